@@ -0,0 +1,50 @@
+package chatlog
+
+import (
+	"fmt"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/anonymize"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(anonymizeCmd)
+	anonymizeCmd.Flags().StringVar(&anonymizeOut, "out", "", "output path, default to the input path with -anon suffix")
+	anonymizeCmd.Flags().StringVar(&anonymizeSalt, "salt", "", "secret for generating pseudonyms, use the same salt to get the same pseudonyms across runs, default to random")
+	anonymizeCmd.Flags().BoolVar(&anonymizeKeepMedia, "keep-media", false, "keep media data, links and cdn urls")
+}
+
+var (
+	anonymizeOut       string
+	anonymizeSalt      string
+	anonymizeKeepMedia bool
+)
+
+var anonymizeCmd = &cobra.Command{
+	Use:   "anonymize <work-dir|export-file>",
+	Short: "Create a copy of work dir or export with identities replaced by pseudonyms",
+	Long: `Create a copy of a decrypted work dir or an exported file (.csv, .json, .txt)
+with wxids, nicknames, remarks and phone numbers replaced by consistent pseudonyms,
+and media data stripped, so it can be shared for bug reports or research.
+
+The input is never modified.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ret, err := anonymize.Run(anonymize.Options{
+			Input:     args[0],
+			Output:    anonymizeOut,
+			Salt:      anonymizeSalt,
+			KeepMedia: anonymizeKeepMedia,
+		})
+		if err != nil {
+			printError(err, "failed to anonymize")
+			return
+		}
+		if jsonOutput() {
+			printJSON(ret)
+			return
+		}
+		fmt.Printf("anonymized copy written to %s (%d files, %d identities replaced)\n", ret.Output, ret.Files, ret.Identities)
+	},
+}
@@ -0,0 +1,159 @@
+package chatlog
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog"
+	"github.com/DanielMao1/chatlog/internal/chatlog/backup"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// EnvBackupPassword 备份密码的环境变量，避免密码出现在命令行历史中
+const EnvBackupPassword = backup.EnvPassword
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+	backupCmd.AddCommand(backupDecryptCmd)
+	backupCmd.Flags().StringVarP(&backupPlatform, "platform", "p", "", "platform")
+	backupCmd.Flags().IntVarP(&backupVer, "version", "v", 0, "version")
+	backupCmd.Flags().StringVarP(&backupDataDir, "data-dir", "d", "", "data dir")
+	backupCmd.Flags().StringVarP(&backupDataKey, "data-key", "k", "", "data key")
+	backupCmd.Flags().StringVarP(&backupWorkDir, "work-dir", "w", "", "work dir")
+	backupCmd.Flags().StringVarP(&backupDir, "dir", "D", "", "backup dir, default to <work dir>/../backup")
+	backupCmd.Flags().IntVarP(&backupKeep, "keep", "n", 0, "keep the last N backups of the account, 0 to keep all")
+	backupCmd.PersistentFlags().StringVarP(&backupPassword, "password", "P", "", "encryption password, or set "+EnvBackupPassword)
+	backupDecryptCmd.Flags().StringVar(&backupDecryptOut, "out", "", "output zip file, default to the input file without .enc")
+}
+
+var (
+	backupPlatform   string
+	backupVer        int
+	backupDataDir    string
+	backupDataKey    string
+	backupWorkDir    string
+	backupDir        string
+	backupKeep       int
+	backupPassword   string
+	backupDecryptOut string
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Create a snapshot archive of work dir, config and keys",
+	Run: func(cmd *cobra.Command, args []string) {
+
+		cmdConf := getBackupConfig()
+
+		password := getBackupPassword()
+		if len(password) == 0 {
+			log.Warn().Msg("backup is not encrypted, the archive contains decrypted chat history and keys")
+		}
+
+		m := chatlog.New(cmd.Context())
+		ret, err := m.CommandBackup("", cmdConf, backup.Options{
+			OutputDir: backupDir,
+			Password:  password,
+			Keep:      backupKeep,
+		})
+		if err != nil {
+			printError(err, "failed to backup")
+			return
+		}
+
+		if jsonOutput() {
+			printJSON(ret)
+			return
+		}
+		fmt.Printf("backup created: %s (%d bytes)\n", ret.File, ret.Size)
+		for _, f := range ret.Removed {
+			fmt.Printf("removed: %s\n", f)
+		}
+		if len(ret.Uploaded) != 0 {
+			fmt.Printf("uploaded: %s\n", ret.Uploaded)
+		}
+		for _, f := range ret.RemoteRemoved {
+			fmt.Printf("removed remote: %s\n", f)
+		}
+	},
+}
+
+var backupDecryptCmd = &cobra.Command{
+	Use:   "decrypt <file>",
+	Short: "Decrypt an encrypted backup to a zip file",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		password := getBackupPassword()
+		if len(password) == 0 {
+			printError(fmt.Errorf("password is required"), "failed to decrypt backup")
+			return
+		}
+
+		out := backupDecryptOut
+		if len(out) == 0 {
+			out = strings.TrimSuffix(args[0], ".enc")
+			if out == args[0] {
+				out += ".zip"
+			}
+		}
+
+		in, err := os.Open(args[0])
+		if err != nil {
+			printError(err, "failed to decrypt backup")
+			return
+		}
+		defer in.Close()
+
+		f, err := os.Create(out)
+		if err != nil {
+			printError(err, "failed to decrypt backup")
+			return
+		}
+		if err := backup.Decrypt(in, f, password); err != nil {
+			f.Close()
+			os.Remove(out)
+			printError(err, "failed to decrypt backup")
+			return
+		}
+		if err := f.Close(); err != nil {
+			printError(err, "failed to decrypt backup")
+			return
+		}
+
+		if jsonOutput() {
+			printJSON(map[string]string{"file": out})
+			return
+		}
+		fmt.Printf("backup decrypted: %s\n", out)
+	},
+}
+
+func getBackupPassword() string {
+	if len(backupPassword) != 0 {
+		return backupPassword
+	}
+	return os.Getenv(EnvBackupPassword)
+}
+
+func getBackupConfig() map[string]any {
+	cmdConf := newCmdConf()
+	if len(backupDataDir) != 0 {
+		cmdConf["data_dir"] = backupDataDir
+	}
+	if len(backupDataKey) != 0 {
+		cmdConf["data_key"] = backupDataKey
+	}
+	if len(backupWorkDir) != 0 {
+		cmdConf["work_dir"] = backupWorkDir
+	}
+	if len(backupPlatform) != 0 {
+		cmdConf["platform"] = backupPlatform
+	}
+	if backupVer != 0 {
+		cmdConf["version"] = backupVer
+	}
+	return cmdConf
+}
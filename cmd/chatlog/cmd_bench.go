@@ -0,0 +1,101 @@
+package chatlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/DanielMao1/chatlog/internal/wechat/decrypt/bench"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(benchCmd)
+	benchCmd.Flags().StringVar(&benchFilter, "filter", "", "only run benchmarks whose name matches this regexp")
+	benchCmd.Flags().StringVar(&benchSave, "save", "", "save the results to this JSON file, for use as a baseline")
+	benchCmd.Flags().StringVar(&benchBaseline, "baseline", "", "compare with the results saved by --save and fail on regressions")
+	benchCmd.Flags().Float64Var(&benchMaxRegression, "max-regression", 0.2, "allowed slowdown against the baseline, 0.2 means 20%")
+}
+
+var (
+	benchFilter        string
+	benchSave          string
+	benchBaseline      string
+	benchMaxRegression float64
+)
+
+// benchOutput --output json 时的输出
+type benchOutput struct {
+	Results     []bench.Result     `json:"results"`
+	Regressions []bench.Regression `json:"regressions,omitempty"`
+}
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Benchmark page decryption, key validation and image decoding",
+	Long: `Benchmark the hot paths of decryption on generated data: V3/V4 page decryption,
+HMAC validation, derived key validation, concurrent deduplication of candidate
+keys during key search and dat image decoding. No WeChat data
+is needed, so the results can be compared between versions and machines.`,
+	Example: `chatlog bench --save baseline.json
+chatlog bench --baseline baseline.json --max-regression 0.1
+chatlog bench --filter decrypt_page`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var baseline []bench.Result
+		if len(benchBaseline) != 0 {
+			data, err := os.ReadFile(benchBaseline)
+			if err == nil {
+				err = json.Unmarshal(data, &baseline)
+			}
+			if err != nil {
+				printError(err, "failed to read baseline")
+				return
+			}
+		}
+
+		results, err := bench.Run(benchFilter)
+		if err != nil {
+			printError(err, "failed to run benchmarks")
+			return
+		}
+
+		if len(benchSave) != 0 {
+			data, _ := json.MarshalIndent(results, "", "  ")
+			if err := os.WriteFile(benchSave, data, 0644); err != nil {
+				printError(err, "failed to save results")
+				return
+			}
+			log.Info().Msgf("results saved to %s", benchSave)
+		}
+
+		var regressions []bench.Regression
+		if baseline != nil {
+			regressions = bench.Compare(baseline, results, benchMaxRegression)
+		}
+
+		if jsonOutput() {
+			printJSON(benchOutput{Results: results, Regressions: regressions})
+		} else {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tN\tNS/OP\tMB/S\tB/OP\tALLOCS/OP")
+			for _, r := range results {
+				mbps := "-"
+				if r.MBPerSec > 0 {
+					mbps = fmt.Sprintf("%.2f", r.MBPerSec)
+				}
+				fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%d\t%d\n", r.Name, r.N, r.NsPerOp, mbps, r.BytesPerOp, r.AllocsPerOp)
+			}
+			w.Flush()
+			for _, r := range regressions {
+				fmt.Printf("regression: %s %d ns/op -> %d ns/op (+%.0f%%)\n", r.Name, r.Baseline, r.Current, (r.Ratio-1)*100)
+			}
+		}
+
+		if len(regressions) != 0 {
+			os.Exit(1)
+		}
+	},
+}
@@ -0,0 +1,227 @@
+package chatlog
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+}
+
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish|powershell]",
+	Short: "Generate the autocompletion script for the specified shell",
+	Long: `Generate the autocompletion script for chatlog for the specified shell.
+
+bash:
+  source <(chatlog completion bash)
+
+zsh:
+  chatlog completion zsh > "${fpath[1]}/_chatlog"
+
+fish:
+  chatlog completion fish | source
+
+powershell:
+  chatlog completion powershell | Out-String | Invoke-Expression`,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+		switch args[0] {
+		case "bash":
+			err = rootCmd.GenBashCompletionV2(os.Stdout, true)
+		case "zsh":
+			err = rootCmd.GenZshCompletion(os.Stdout)
+		case "fish":
+			err = rootCmd.GenFishCompletion(os.Stdout, true)
+		case "powershell":
+			err = rootCmd.GenPowerShellCompletionWithDesc(os.Stdout)
+		}
+		if err != nil {
+			printError(err, "failed to generate completion script")
+		}
+	},
+}
+
+// registerCompletions 为各子命令的参数注册动态补全
+// 需要在所有子命令的 flag 定义完成后调用
+func registerCompletions() {
+	rootCmd.RegisterFlagCompletionFunc("account", completeAccount)
+	for _, cmd := range rootCmd.Commands() {
+		if cmd.Flags().Lookup("data-dir") != nil {
+			cmd.RegisterFlagCompletionFunc("data-dir", completeHistory(func(dataDir, workDir string) string { return dataDir }))
+		}
+		if cmd.Flags().Lookup("work-dir") != nil {
+			cmd.RegisterFlagCompletionFunc("work-dir", completeHistory(func(dataDir, workDir string) string { return workDir }))
+		}
+		if cmd.Flags().Lookup("talker") != nil {
+			cmd.RegisterFlagCompletionFunc("talker", completeTalker)
+		}
+	}
+}
+
+// completeHistory 使用历史账号中记录的目录进行补全
+func completeHistory(pick func(dataDir, workDir string) string) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		history, err := chatlog.New(cmd.Context()).History("")
+		if err != nil || len(history) == 0 {
+			return nil, cobra.ShellCompDirectiveFilterDirs
+		}
+		ret := make([]cobra.Completion, 0, len(history))
+		for _, h := range history {
+			dir := pick(h.DataDir, h.WorkDir)
+			if dir == "" || !strings.HasPrefix(dir, toComplete) {
+				continue
+			}
+			ret = append(ret, cobra.CompletionWithDesc(dir, h.Account))
+		}
+		return ret, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeAccount 使用历史账号名称补全 --account
+func completeAccount(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	history, err := chatlog.New(cmd.Context()).History("")
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	ret := make([]cobra.Completion, 0, len(history))
+	for _, h := range history {
+		if strings.HasPrefix(h.Account, toComplete) {
+			ret = append(ret, cobra.CompletionWithDesc(h.Account, h.DataDir))
+		}
+	}
+	return ret, cobra.ShellCompDirectiveNoFileComp
+}
+
+const (
+	// talkerCacheTTL 补全使用的联系人缓存有效期
+	talkerCacheTTL = 10 * time.Minute
+	// talkerLookupTimeout 缓存失效时读取联系人的最长时间，超时则不补全
+	talkerLookupTimeout = 2 * time.Second
+)
+
+// completeTalker 使用工作目录中的联系人和群聊补全 talker
+// 联系人列表缓存在用户缓存目录中，避免每次按 TAB 都打开数据库
+func completeTalker(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	cmdConf := newCmdConf()
+	for _, key := range []string{"work-dir", "data-dir", "platform"} {
+		if v, err := cmd.Flags().GetString(key); err == nil && v != "" {
+			cmdConf[strings.ReplaceAll(key, "-", "_")] = v
+		}
+	}
+	if v, err := cmd.Flags().GetInt("version"); err == nil && v != 0 {
+		cmdConf["version"] = v
+	}
+
+	// 未指定工作目录和账号时，使用最近一次使用的账号
+	if _, ok := cmdConf["work_dir"]; !ok && len(Account) == 0 {
+		history, err := chatlog.New(cmd.Context()).History("")
+		if err != nil || len(history) == 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		last := history[len(history)-1]
+		cmdConf["work_dir"] = last.WorkDir
+		cmdConf["platform"] = last.Platform
+		cmdConf["version"] = last.Version
+	}
+
+	talkers, err := lookupTalkers(cmd.Context(), cmdConf)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	ret := make([]cobra.Completion, 0)
+	for _, t := range talkers {
+		if strings.HasPrefix(t.Name, toComplete) {
+			ret = append(ret, cobra.CompletionWithDesc(t.Name, t.Desc))
+		}
+	}
+	return ret, cobra.ShellCompDirectiveNoFileComp
+}
+
+// talkerEntry 补全缓存中的联系人或群聊
+type talkerEntry struct {
+	Name string `json:"name"`
+	Desc string `json:"desc"`
+}
+
+// lookupTalkers 优先使用未过期的缓存，否则在超时时间内读取联系人和群聊并写入缓存
+func lookupTalkers(ctx context.Context, cmdConf map[string]any) ([]talkerEntry, error) {
+	path := talkerCachePath(cmdConf)
+	if path != "" {
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) < talkerCacheTTL {
+			if b, err := os.ReadFile(path); err == nil {
+				var talkers []talkerEntry
+				if err := json.Unmarshal(b, &talkers); err == nil {
+					return talkers, nil
+				}
+			}
+		}
+	}
+
+	type result struct {
+		talkers []talkerEntry
+		err     error
+	}
+	ctx, cancel := context.WithTimeout(ctx, talkerLookupTimeout)
+	defer cancel()
+	done := make(chan result, 1)
+	go func() {
+		contacts, chatRooms, err := chatlog.New(ctx).CommandContacts("", cmdConf, "")
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		talkers := make([]talkerEntry, 0, len(contacts.Items)+len(chatRooms.Items))
+		for _, c := range contacts.Items {
+			talkers = append(talkers, talkerEntry{Name: c.UserName, Desc: c.DisplayName()})
+		}
+		for _, c := range chatRooms.Items {
+			talkers = append(talkers, talkerEntry{Name: c.Name, Desc: c.DisplayName()})
+		}
+		done <- result{talkers: talkers}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			return nil, r.err
+		}
+		if path != "" {
+			if b, err := json.Marshal(r.talkers); err == nil {
+				if err := os.MkdirAll(filepath.Dir(path), 0700); err == nil {
+					os.WriteFile(path, b, 0600)
+				}
+			}
+		}
+		return r.talkers, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("lookup talkers timeout")
+	}
+}
+
+// talkerCachePath 返回补全缓存文件路径，不同账号和工作目录使用不同的缓存
+func talkerCachePath(cmdConf map[string]any) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	key := fmt.Sprintf("%s|%v|%v|%v", Account, cmdConf["work_dir"], cmdConf["data_dir"], Profile)
+	sum := md5.Sum([]byte(key))
+	return filepath.Join(dir, "chatlog", "completion", "talkers-"+hex.EncodeToString(sum[:])+".json")
+}
@@ -0,0 +1,121 @@
+package chatlog
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(contactsCmd)
+	contactsCmd.Flags().StringVarP(&contactsPlatform, "platform", "p", "", "platform")
+	contactsCmd.Flags().IntVarP(&contactsVer, "version", "v", 0, "version")
+	contactsCmd.Flags().StringVarP(&contactsDataDir, "data-dir", "d", "", "data dir")
+	contactsCmd.Flags().StringVarP(&contactsWorkDir, "work-dir", "w", "", "work dir")
+	contactsCmd.Flags().StringVarP(&contactsKeyword, "keyword", "q", "", "filter by wxid, alias, remark or nickname")
+	contactsCmd.Flags().StringVarP(&contactsType, "type", "t", "all", "entry type, all, friend, contact or chatroom")
+	contactsCmd.Flags().StringVarP(&contactsFormat, "format", "f", "table", "output format, table, csv or json")
+}
+
+var (
+	contactsPlatform string
+	contactsVer      int
+	contactsDataDir  string
+	contactsWorkDir  string
+	contactsKeyword  string
+	contactsType     string
+	contactsFormat   string
+)
+
+// contactRow 联系人与群聊的统一输出格式
+type contactRow struct {
+	UserName    string `json:"userName"`
+	Alias       string `json:"alias"`
+	Remark      string `json:"remark"`
+	NickName    string `json:"nickName"`
+	Type        string `json:"type"`
+	MemberCount int    `json:"memberCount"`
+}
+
+var contactsCmd = &cobra.Command{
+	Use:   "contacts",
+	Short: "List contacts and chatrooms",
+	Run: func(cmd *cobra.Command, args []string) {
+
+		cmdConf := getContactsConfig()
+
+		m := chatlog.New(cmd.Context())
+		contacts, chatRooms, err := m.CommandContacts("", cmdConf, contactsKeyword)
+		if err != nil {
+			printError(err, "failed to get contacts")
+			return
+		}
+
+		_type := strings.ToLower(contactsType)
+		rows := make([]contactRow, 0)
+		if _type != "chatroom" {
+			for _, c := range contacts.Items {
+				// 群聊同时存在于联系人表中，统一在群聊部分输出
+				if strings.HasSuffix(c.UserName, "@chatroom") {
+					continue
+				}
+				t := "contact"
+				if c.IsFriend {
+					t = "friend"
+				}
+				if _type == "friend" && t != "friend" {
+					continue
+				}
+				rows = append(rows, contactRow{UserName: c.UserName, Alias: c.Alias, Remark: c.Remark, NickName: c.NickName, Type: t})
+			}
+		}
+		if _type == "all" || _type == "chatroom" {
+			for _, c := range chatRooms.Items {
+				rows = append(rows, contactRow{UserName: c.Name, Remark: c.Remark, NickName: c.NickName, Type: "chatroom", MemberCount: len(c.Users)})
+			}
+		}
+
+		switch outputFormat(contactsFormat) {
+		case OutputJSON:
+			printJSON(rows)
+		case "csv":
+			w := csv.NewWriter(os.Stdout)
+			w.Write([]string{"UserName", "Alias", "Remark", "NickName", "Type", "MemberCount"})
+			for _, r := range rows {
+				w.Write([]string{r.UserName, r.Alias, r.Remark, r.NickName, r.Type, strconv.Itoa(r.MemberCount)})
+			}
+			w.Flush()
+		default:
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "UserName\tAlias\tRemark\tNickName\tType\tMembers")
+			for _, r := range rows {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\n", r.UserName, r.Alias, r.Remark, r.NickName, r.Type, r.MemberCount)
+			}
+			w.Flush()
+		}
+	},
+}
+
+func getContactsConfig() map[string]any {
+	cmdConf := newCmdConf()
+	if len(contactsDataDir) != 0 {
+		cmdConf["data_dir"] = contactsDataDir
+	}
+	if len(contactsWorkDir) != 0 {
+		cmdConf["work_dir"] = contactsWorkDir
+	}
+	if len(contactsPlatform) != 0 {
+		cmdConf["platform"] = contactsPlatform
+	}
+	if contactsVer != 0 {
+		cmdConf["version"] = contactsVer
+	}
+	return cmdConf
+}
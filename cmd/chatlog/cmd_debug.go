@@ -0,0 +1,162 @@
+package chatlog
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// EnvAuthToken 访问服务接口的令牌，与配置 auth_token 的环境变量相同
+const EnvAuthToken = "CHATLOG_AUTH_TOKEN"
+
+func init() {
+	rootCmd.AddCommand(debugCmd)
+	debugCmd.AddCommand(debugDumpCmd)
+	debugDumpCmd.Flags().StringVarP(&debugAddr, "addr", "a", "127.0.0.1:5030", "address of the running server")
+	debugDumpCmd.Flags().StringVar(&debugToken, "token", "", "auth token of the server, or set "+EnvAuthToken)
+	debugDumpCmd.Flags().StringVar(&debugOut, "out", "", "output zip file, default to chatlog-debug-<time>.zip")
+	debugDumpCmd.Flags().DurationVar(&debugCPU, "cpu", 0, "also record a CPU profile for this long, e.g. 30s while decrypting")
+}
+
+var (
+	debugAddr  string
+	debugToken string
+	debugOut   string
+	debugCPU   time.Duration
+)
+
+// debugProfile 写入压缩包的一项诊断信息
+type debugProfile struct {
+	file string
+	path string
+}
+
+var debugCmd = &cobra.Command{
+	Use:   "debug",
+	Short: "Diagnose a running chatlog server",
+}
+
+var debugDumpCmd = &cobra.Command{
+	Use:   "dump",
+	Short: "Capture goroutine and heap profiles from a server started with --pprof into a zip file",
+	Run: func(cmd *cobra.Command, args []string) {
+		base, err := debugBaseURL(debugAddr)
+		if err != nil {
+			printError(err, "failed to dump profiles")
+			return
+		}
+		token := debugToken
+		if len(token) == 0 {
+			token = os.Getenv(EnvAuthToken)
+		}
+
+		profiles := []debugProfile{
+			{"runtime.json", "/debug/runtime"},
+			{"goroutine.txt", "/debug/pprof/goroutine?debug=2"},
+			{"goroutine.pb.gz", "/debug/pprof/goroutine"},
+			{"heap.pb.gz", "/debug/pprof/heap?gc=1"},
+			{"allocs.pb.gz", "/debug/pprof/allocs"},
+		}
+		if debugCPU > 0 {
+			seconds := max(int(debugCPU.Round(time.Second).Seconds()), 1)
+			profiles = append(profiles, debugProfile{"cpu.pb.gz", "/debug/pprof/profile?seconds=" + strconv.Itoa(seconds)})
+			log.Info().Msgf("recording CPU profile for %ds", seconds)
+		}
+
+		out := debugOut
+		if len(out) == 0 {
+			out = "chatlog-debug-" + time.Now().Format("20060102150405") + ".zip"
+		}
+		f, err := os.Create(out)
+		if err != nil {
+			printError(err, "failed to dump profiles")
+			return
+		}
+		zw := zip.NewWriter(f)
+		client := &http.Client{
+			Timeout: debugCPU + time.Minute,
+			// 未开启 pprof 时 /debug 会被重定向到首页
+			CheckRedirect: func(req *http.Request, via []*http.Request) error { return http.ErrUseLastResponse },
+		}
+		files := make([]string, 0, len(profiles))
+		for _, p := range profiles {
+			if err = debugFetch(client, base+p.path, token, zw, p.file); err != nil {
+				break
+			}
+			files = append(files, p.file)
+		}
+		if err == nil {
+			err = zw.Close()
+		}
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(out)
+			printError(err, "failed to dump profiles")
+			return
+		}
+
+		if jsonOutput() {
+			printJSON(map[string]any{"file": out, "profiles": files})
+			return
+		}
+		fmt.Printf("profiles saved: %s (%s)\n", out, strings.Join(files, ", "))
+	},
+}
+
+// debugBaseURL 返回服务地址，监听所有地址时连接本机
+func debugBaseURL(addr string) (string, error) {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	u, err := url.Parse(addr)
+	if err != nil || len(u.Host) == 0 {
+		return "", fmt.Errorf("invalid server address: %s", addr)
+	}
+	switch u.Hostname() {
+	case "", "0.0.0.0", "::":
+		u.Host = "127.0.0.1:" + u.Port()
+	}
+	return strings.TrimSuffix(u.Scheme+"://"+u.Host+u.Path, "/"), nil
+}
+
+// debugFetch 下载一项诊断信息写入压缩包
+func debugFetch(client *http.Client, u, token string, zw *zip.Writer, name string) error {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	if len(token) != 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return fmt.Errorf("unauthorized, set --token or %s", EnvAuthToken)
+	case resp.StatusCode == http.StatusNotFound || (resp.StatusCode >= 300 && resp.StatusCode < 400):
+		return fmt.Errorf("pprof is not enabled, start the server with --pprof or set pprof to true in config")
+	case resp.StatusCode != http.StatusOK:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s %s", name, resp.Status, strings.TrimSpace(string(body)))
+	}
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
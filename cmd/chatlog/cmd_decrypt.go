@@ -2,10 +2,13 @@ package chatlog
 
 import (
 	"fmt"
+	"os"
+	"text/tabwriter"
 
 	"github.com/DanielMao1/chatlog/internal/chatlog"
+	"github.com/DanielMao1/chatlog/internal/chatlog/wechat"
+	"github.com/DanielMao1/chatlog/pkg/util"
 
-	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
 
@@ -16,6 +19,9 @@ func init() {
 	decryptCmd.Flags().StringVarP(&decryptDataDir, "data-dir", "d", "", "data dir")
 	decryptCmd.Flags().StringVarP(&decryptDatakey, "data-key", "k", "", "data key")
 	decryptCmd.Flags().StringVarP(&decryptWorkDir, "work-dir", "w", "", "work dir")
+	decryptCmd.Flags().BoolVarP(&decryptForce, "force", "f", false, "decrypt all databases, including unchanged ones")
+	decryptCmd.Flags().BoolVar(&decryptDryRun, "dry-run", false, "list the databases to decrypt and the keys to use without writing anything")
+	decryptCmd.Flags().StringSliceVar(&decryptOnly, "only", nil, "only decrypt databases whose path under the data dir contains one of these strings, e.g. message,contact")
 }
 
 var (
@@ -24,18 +30,65 @@ var (
 	decryptDataDir  string
 	decryptDatakey  string
 	decryptWorkDir  string
+	decryptForce    bool
+	decryptDryRun   bool
+	decryptOnly     []string
 )
 
 var decryptCmd = &cobra.Command{
 	Use:   "decrypt",
 	Short: "decrypt",
+	Long: `Decrypt the databases in the data dir into the work dir.
+
+Databases whose decrypted copy is newer than the source are skipped, use
+--force to decrypt them again. --dry-run lists every database with its size,
+target path, the key that matches it (raw, or which derived key) and whether
+it would be skipped.
+
+Free space in the work dir is checked before anything is written. When it is
+not enough, decrypt part of the databases with --only, e.g. --only message
+for chat history only.`,
+	Example: `chatlog decrypt
+chatlog decrypt --dry-run
+chatlog decrypt --only message,contact,session`,
 	Run: func(cmd *cobra.Command, args []string) {
 
 		cmdConf := getDecryptConfig()
 
-		m := chatlog.New()
-		if err := m.CommandDecrypt("", cmdConf); err != nil {
-			log.Err(err).Msg("failed to decrypt")
+		m := chatlog.New(cmd.Context())
+		if decryptDryRun {
+			items, err := m.CommandDecryptPlan("", cmdConf, decryptForce)
+			if err != nil {
+				printError(err, "failed to plan decryption")
+				return
+			}
+			if jsonOutput() {
+				printJSON(items)
+				return
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ACTION\tSIZE\tKEY\tSOURCE\tTARGET\tREASON")
+			counts := make(map[string]int)
+			for _, item := range items {
+				counts[item.Action]++
+				key := item.Key
+				if len(key) == 0 {
+					key = "-"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", item.Action, util.ByteCountSI(item.Size), key, item.Source, item.Target, item.Reason)
+			}
+			w.Flush()
+			fmt.Printf("\n%d databases: %d to decrypt, %d to copy, %d unchanged, %d failed\n",
+				len(items), counts[wechat.ActionDecrypt], counts[wechat.ActionCopy], counts[wechat.ActionSkip], counts[wechat.ActionFail])
+			return
+		}
+
+		if err := m.CommandDecrypt("", cmdConf, decryptForce); err != nil {
+			printError(err, "failed to decrypt")
+			return
+		}
+		if jsonOutput() {
+			printJSON(map[string]any{"success": true})
 			return
 		}
 		fmt.Println("decrypt success")
@@ -43,7 +96,7 @@ var decryptCmd = &cobra.Command{
 }
 
 func getDecryptConfig() map[string]any {
-	cmdConf := make(map[string]any)
+	cmdConf := newCmdConf()
 	if len(decryptDataDir) != 0 {
 		cmdConf["data_dir"] = decryptDataDir
 	}
@@ -59,5 +112,8 @@ func getDecryptConfig() map[string]any {
 	if decryptVer != 0 {
 		cmdConf["version"] = decryptVer
 	}
+	if len(decryptOnly) != 0 {
+		cmdConf["decrypt_only"] = decryptOnly
+	}
 	return cmdConf
 }
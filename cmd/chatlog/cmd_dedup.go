@@ -0,0 +1,110 @@
+package chatlog
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog"
+	"github.com/DanielMao1/chatlog/internal/chatlog/dedup"
+	"github.com/DanielMao1/chatlog/pkg/util"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(dedupCmd)
+	dedupCmd.Flags().StringVarP(&dedupPlatform, "platform", "p", "", "platform")
+	dedupCmd.Flags().IntVarP(&dedupVer, "version", "v", 0, "version")
+	dedupCmd.Flags().StringVarP(&dedupDataDir, "data-dir", "d", "", "data dir")
+	dedupCmd.Flags().StringVarP(&dedupWorkDir, "work-dir", "w", "", "work dir")
+	dedupCmd.Flags().StringVar(&dedupDir, "dir", "", "directory to scan, default to the data dir")
+	dedupCmd.Flags().Int64Var(&dedupMinSize, "min-size", dedup.DefaultMinSize, "skip files smaller than this many bytes")
+	dedupCmd.Flags().IntVarP(&dedupTop, "top", "n", 20, "number of duplicate groups to list")
+	dedupCmd.Flags().BoolVar(&dedupLink, "link", false, "replace identical files in --dir with hard links, never applied to the data dir")
+	dedupCmd.Flags().BoolVar(&dedupDryRun, "dry-run", false, "with --link, only count the files that would be linked")
+}
+
+var (
+	dedupPlatform string
+	dedupVer      int
+	dedupDataDir  string
+	dedupWorkDir  string
+	dedupDir      string
+	dedupMinSize  int64
+	dedupTop      int
+	dedupLink     bool
+	dedupDryRun   bool
+)
+
+var dedupCmd = &cobra.Command{
+	Use:   "dedup",
+	Short: "Find duplicate media files and the space they waste",
+	Long: `Hash the media files in the data dir (or --dir) and report files with the
+same content, the conversations they appear in and the disk space that
+merging them would save. Image .dat files are compared after decryption, so
+the same picture forwarded to several conversations is found even when the
+encrypted files differ.
+
+Files are only read. With --link, files in --dir with exactly the same bytes
+are replaced by hard links to one copy, e.g. for a directory of exported or
+converted media. The WeChat data dir is never modified.`,
+	Example: `chatlog dedup
+chatlog dedup -n 50 -o json
+chatlog dedup --dir ~/chatlog-media --link --dry-run`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		cmdConf := newCmdConf()
+		if len(dedupDataDir) != 0 {
+			cmdConf["data_dir"] = dedupDataDir
+		}
+		if len(dedupWorkDir) != 0 {
+			cmdConf["work_dir"] = dedupWorkDir
+		}
+		if len(dedupPlatform) != 0 {
+			cmdConf["platform"] = dedupPlatform
+		}
+		if dedupVer != 0 {
+			cmdConf["version"] = dedupVer
+		}
+
+		m := chatlog.New(cmd.Context())
+		ret, err := m.CommandDedup("", cmdConf, dedup.Options{
+			Dir:     dedupDir,
+			MinSize: dedupMinSize,
+			Link:    dedupLink,
+			DryRun:  dedupDryRun,
+		})
+		if err != nil {
+			printError(err, "failed to find duplicate media")
+			return
+		}
+
+		if jsonOutput() {
+			printJSON(ret)
+			return
+		}
+		for i, g := range ret.Groups {
+			if i >= dedupTop {
+				fmt.Printf("... %d more groups\n", len(ret.Groups)-dedupTop)
+				break
+			}
+			fmt.Printf("%d copies, %s wasted", len(g.Files), util.ByteCountSI(g.Wasted))
+			if len(g.Talkers) != 0 {
+				fmt.Printf(" [%s]", strings.Join(g.Talkers, ", "))
+			}
+			fmt.Println()
+			for _, f := range g.Files {
+				fmt.Printf("  %s (%s)\n", f.Path, util.ByteCountSI(f.Size))
+			}
+		}
+		fmt.Printf("%d files (%s) scanned, %d duplicate groups, %d across conversations, %s could be saved\n",
+			ret.Files, util.ByteCountSI(ret.Size), len(ret.Groups), ret.Cross, util.ByteCountSI(ret.Wasted))
+		if dedupLink {
+			if ret.DryRun {
+				fmt.Printf("%d files would be hard linked, %s reclaimed\n", ret.Linked, util.ByteCountSI(ret.Reclaimed))
+			} else {
+				fmt.Printf("%d files hard linked, %s reclaimed\n", ret.Linked, util.ByteCountSI(ret.Reclaimed))
+			}
+		}
+	},
+}
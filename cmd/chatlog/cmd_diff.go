@@ -0,0 +1,103 @@
+package chatlog
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog"
+	"github.com/DanielMao1/chatlog/internal/model"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().StringVarP(&diffPlatform, "platform", "p", "", "platform, used when it can not be detected from the snapshot")
+	diffCmd.Flags().StringVarP(&diffWorkDir, "work-dir", "w", "", "work dir, compared when only one snapshot is given")
+	diffCmd.Flags().StringVarP(&diffTalker, "talker", "t", "", "only compare these talkers, separated by comma")
+	diffCmd.Flags().StringVarP(&diffPassword, "password", "P", "", "password of encrypted backups, or set "+EnvBackupPassword)
+	diffCmd.Flags().BoolVarP(&diffVerbose, "verbose", "V", false, "print deleted and recalled messages")
+}
+
+var (
+	diffPlatform string
+	diffWorkDir  string
+	diffTalker   string
+	diffPassword string
+	diffVerbose  bool
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <old> [new]",
+	Short: "Compare messages between two snapshots",
+	Long: `Compare messages between two snapshots and report added, deleted and recalled
+messages per talker. A snapshot is a work dir or a backup file created by
+"chatlog backup". When [new] is omitted, the current work dir is used.`,
+	Example: `chatlog diff ~/Documents/backup/chatlog-wxid_xxx-20250101-000000.zip
+chatlog diff ./snapshot-a ./snapshot-b --talker 123@chatroom -V`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		cmdConf := newCmdConf()
+		if len(diffPlatform) != 0 {
+			cmdConf["platform"] = diffPlatform
+		}
+		if len(diffWorkDir) != 0 {
+			cmdConf["work_dir"] = diffWorkDir
+		}
+
+		password := diffPassword
+		if len(password) == 0 {
+			password = os.Getenv(EnvBackupPassword)
+		}
+
+		newPath := ""
+		if len(args) == 2 {
+			newPath = args[1]
+		}
+
+		m := chatlog.New(cmd.Context())
+		ret, err := m.CommandDiff("", cmdConf, args[0], newPath, diffTalker, password)
+		if err != nil {
+			printError(err, "failed to diff")
+			return
+		}
+
+		if jsonOutput() {
+			printJSON(ret)
+			return
+		}
+
+		fmt.Printf("--- %s\n+++ %s\n", ret.Old, ret.New)
+		if len(ret.Talkers) == 0 {
+			fmt.Println("no difference")
+			return
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TALKER\tNAME\tADDED\tDELETED\tRECALLED")
+		for _, t := range ret.Talkers {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\n", t.Talker, t.TalkerName, len(t.Added), len(t.Deleted), len(t.Recalled))
+		}
+		fmt.Fprintf(w, "total\t\t%d\t%d\t%d\n", ret.Added, ret.Deleted, ret.Recalled)
+		w.Flush()
+
+		if !diffVerbose {
+			return
+		}
+		for _, t := range ret.Talkers {
+			printDiffMessages(t.Talker, "deleted", t.Deleted)
+			printDiffMessages(t.Talker, "recalled", t.Recalled)
+		}
+	},
+}
+
+func printDiffMessages(talker, kind string, msgs []*model.Message) {
+	if len(msgs) == 0 {
+		return
+	}
+	fmt.Printf("\n# %s %s\n", talker, kind)
+	for _, msg := range msgs {
+		fmt.Println(msg.PlainText(true, "2006-01-02 15:04:05", ""))
+	}
+}
@@ -0,0 +1,82 @@
+package chatlog
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog"
+	"github.com/DanielMao1/chatlog/internal/chatlog/doctor"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().StringVarP(&doctorPlatform, "platform", "p", "", "platform")
+	doctorCmd.Flags().IntVarP(&doctorVer, "version", "v", 0, "version")
+	doctorCmd.Flags().StringVarP(&doctorDataDir, "data-dir", "d", "", "data dir")
+	doctorCmd.Flags().StringVarP(&doctorDataKey, "data-key", "k", "", "data key")
+	doctorCmd.Flags().StringVarP(&doctorWorkDir, "work-dir", "w", "", "work dir")
+	doctorCmd.Flags().StringVarP(&doctorFormat, "format", "f", "table", "output format, table or json")
+}
+
+var (
+	doctorPlatform string
+	doctorVer      int
+	doctorDataDir  string
+	doctorDataKey  string
+	doctorWorkDir  string
+	doctorFormat   string
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose common environment problems",
+	Run: func(cmd *cobra.Command, args []string) {
+
+		cmdConf := getDoctorConfig()
+
+		m := chatlog.New(cmd.Context())
+		results := m.CommandDoctor("", cmdConf)
+
+		switch outputFormat(doctorFormat) {
+		case OutputJSON:
+			printJSON(results)
+		default:
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, r := range results {
+				fmt.Fprintf(w, "[%s]\t%s\t%s\n", strings.ToUpper(r.Status), r.Name, r.Message)
+				if r.Fix != "" {
+					fmt.Fprintf(w, "\t\tfix: %s\n", r.Fix)
+				}
+			}
+			w.Flush()
+		}
+
+		if doctor.Failed(results) {
+			os.Exit(1)
+		}
+	},
+}
+
+func getDoctorConfig() map[string]any {
+	cmdConf := newCmdConf()
+	if len(doctorDataDir) != 0 {
+		cmdConf["data_dir"] = doctorDataDir
+	}
+	if len(doctorDataKey) != 0 {
+		cmdConf["data_key"] = doctorDataKey
+	}
+	if len(doctorWorkDir) != 0 {
+		cmdConf["work_dir"] = doctorWorkDir
+	}
+	if len(doctorPlatform) != 0 {
+		cmdConf["platform"] = doctorPlatform
+	}
+	if doctorVer != 0 {
+		cmdConf["version"] = doctorVer
+	}
+	return cmdConf
+}
@@ -142,5 +142,8 @@ var dumpmemoryCmd = &cobra.Command{
 		}
 
 		log.Info().Msgf("package success, please send %s to developer", zipPath)
+		if jsonOutput() {
+			printJSON(map[string]string{"file": zipPath})
+		}
 	},
 }
@@ -0,0 +1,78 @@
+package chatlog
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog"
+	"github.com/DanielMao1/chatlog/internal/chatlog/importer"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	importCmd.Flags().StringVarP(&importWorkDir, "work-dir", "w", "", "destination work dir")
+	importCmd.Flags().StringVarP(&importFormat, "format", "f", "", "export format, one of "+strings.Join(importer.Formats, ", ")+", detected if not set")
+	importCmd.Flags().StringVarP(&importTalker, "talker", "t", "", "talker id, for exports without it such as group chats exported by MemoTrace")
+	importCmd.Flags().StringVar(&importSelf, "self", "", "your wechat id, used as the sender of your own messages")
+}
+
+var (
+	importWorkDir string
+	importFormat  string
+	importTalker  string
+	importSelf    string
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <file|dir>",
+	Short: "Import chat history exported by other tools",
+	Long: `Import chat history into the work dir, so the HTTP API, MCP and exports work over it.
+
+Supported formats:
+
+  chatlog    csv / json exported by chatlog
+  memotrace  csv exported by MemoTrace (WeChatMsg)
+  ios        MM.sqlite in an iOS backup, pass the DB dir containing it
+
+Imported data is stored with the 4.0 layout. An empty work dir is written to
+directly. Importing into a 4.0 work dir goes through the merge archive next to it
+(<work dir>-merged), like "chatlog merge": duplicated messages are skipped, and the
+imported messages are merged again after "chatlog decrypt" or auto decrypt
+overwrites the work dir. Serve it with --version 4.
+
+Backups made by WeChat's own "Backup and Migrate" feature are not supported,
+restore them to WeChat first, then run "chatlog decrypt".`,
+	Example: `chatlog import ./exports -w ~/Documents/chatlog/imported
+chatlog import ./MemoTrace/data/123@chatroom/123@chatroom.csv --talker 123@chatroom
+chatlog import ./Documents/0123456789abcdef/DB --format ios --self wxid_xxx`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		cmdConf := newCmdConf()
+		if len(importWorkDir) != 0 {
+			cmdConf["work_dir"] = importWorkDir
+		}
+
+		m := chatlog.New(cmd.Context())
+		ret, err := m.CommandImport("", cmdConf, importer.Options{
+			Input:  args[0],
+			Format: importFormat,
+			Talker: importTalker,
+			Self:   importSelf,
+		})
+		if err != nil {
+			printError(err, "failed to import")
+			return
+		}
+
+		if jsonOutput() {
+			printJSON(ret)
+			return
+		}
+		fmt.Printf("import success, %d messages added from %d %s files, %d talkers, %d contacts\n",
+			ret.Messages, ret.Files, ret.Format, ret.Talkers, ret.Contacts)
+		fmt.Printf("work dir: %s\n", ret.WorkDir)
+	},
+}
@@ -0,0 +1,275 @@
+package chatlog
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog"
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+	"github.com/DanielMao1/chatlog/internal/chatlog/doctor"
+	"github.com/DanielMao1/chatlog/pkg/util"
+
+	"github.com/rs/zerolog"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(initCmd)
+	initCmd.Flags().BoolVarP(&initYes, "yes", "y", false, "accept the default answer for every question")
+	initCmd.Flags().BoolVarP(&initForce, "force", "f", false, "extract the key again even if one is saved")
+}
+
+var (
+	initYes   bool
+	initForce bool
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Interactive first-run setup",
+	Long: `Walk through account detection, permission checks, key extraction,
+work dir selection and HTTP server options, then write chatlog-server.json.
+
+Use the global --profile flag to save the result as a named profile.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		// 首次运行时配置文件不存在等日志会干扰问答，错误由向导自行输出
+		if !Debug {
+			zerolog.SetGlobalLevel(zerolog.Disabled)
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		p := &prompter{in: bufio.NewReader(os.Stdin), out: os.Stderr, yes: initYes}
+		values, err := runInit(cmd.Context(), p)
+		if err != nil {
+			printError(err, "init failed")
+			return
+		}
+
+		profile := Profile
+		if len(profile) == 0 {
+			profile = os.Getenv(conf.EnvPrefix + "_PROFILE")
+		}
+		path, err := conf.SaveServiceConfig("", profile, values)
+		if err != nil {
+			printError(err, "failed to save config")
+			return
+		}
+
+		if jsonOutput() {
+			printJSON(map[string]any{"config": path, "profile": profile, "values": values})
+			return
+		}
+		fmt.Printf("\nconfig saved: %s\n", path)
+		if len(profile) != 0 {
+			fmt.Printf("profile: %s, use it with --profile %s\n", profile, profile)
+		}
+		if values["http_addr"] != nil {
+			fmt.Println("next: run `chatlog server` to start the HTTP server")
+		} else {
+			fmt.Println("next: run `chatlog decrypt` whenever you want to refresh the decrypted data")
+		}
+	},
+}
+
+// runInit 依次完成各个步骤，返回需要写入配置文件的内容
+func runInit(ctx context.Context, p *prompter) (map[string]any, error) {
+
+	// step 1. 环境检查
+	p.step("Checking environment")
+	for _, r := range []*doctor.Result{doctor.CheckSIP(), doctor.CheckFullDiskAccess()} {
+		if r.Status == doctor.StatusSkip {
+			continue
+		}
+		p.printf("[%s] %s: %s\n", strings.ToUpper(r.Status), r.Name, r.Message)
+		if r.Status != doctor.StatusOK && len(r.Fix) != 0 {
+			p.printf("       fix: %s\n", r.Fix)
+		}
+	}
+
+	// step 2. 账号检测与密钥获取
+	p.step("Detecting WeChat account and extracting key")
+	account, err := initAccount(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]any{
+		"type":         "wechat",
+		"platform":     account.Platform,
+		"version":      account.Version,
+		"full_version": account.FullVersion,
+		"data_dir":     account.DataDir,
+		"data_key":     account.DataKey,
+		"img_key":      account.ImgKey,
+	}
+	if r := doctor.CheckKey(account.Platform, account.Version, account.DataDir, account.DataKey); r.Status == doctor.StatusFail {
+		p.printf("[%s] %s: %s\n", strings.ToUpper(r.Status), r.Name, r.Message)
+		if !p.confirm("Save the key anyway?", false) {
+			return nil, fmt.Errorf("invalid data key")
+		}
+	}
+
+	// step 3. 工作目录
+	p.step("Choosing work dir")
+	workDir := p.ask("Work dir for decrypted data", util.DefaultWorkDir(account.Account))
+	values["work_dir"] = workDir
+
+	// step 4. HTTP 服务与自动解密
+	p.step("Configuring HTTP server")
+	if p.confirm("Enable HTTP server (API / MCP)?", true) {
+		addr := p.ask("Listen address", "127.0.0.1:5030")
+		values["http_addr"] = addr
+		if !isLoopback(addr) {
+			p.printf("the server will be reachable from other machines, an auth token is recommended\n")
+		}
+		if p.confirm("Require an auth token?", !isLoopback(addr)) {
+			token := newToken()
+			values["auth_token"] = token
+			p.printf("auth token: %s\n", token)
+		}
+		values["auto_decrypt"] = p.confirm("Decrypt new messages automatically while the server is running?", true)
+	}
+
+	// step 5. 首次解密
+	if p.confirm("Decrypt the database now? This may take a while", true) {
+		cmdConf := newCmdConf()
+		for k, v := range values {
+			cmdConf[k] = v
+		}
+		m := chatlog.New(ctx)
+		if err := m.CommandDecrypt("", cmdConf, initForce); err != nil {
+			return nil, fmt.Errorf("decrypt failed: %w", err)
+		}
+		p.printf("decrypted to %s\n", workDir)
+	}
+
+	return values, nil
+}
+
+// initAccount 检测微信进程并获取密钥，未检测到进程时改为手动输入
+func initAccount(ctx context.Context, p *prompter) (*chatlog.KeyResult, error) {
+	m := chatlog.New(ctx)
+	ret, err := m.CommandKey("", 0, initForce, false, Account)
+	if err != nil {
+		p.printf("%s\n", err)
+		p.printf("start WeChat and log in to detect the account automatically, or enter the data dir and key manually\n")
+		account := &chatlog.KeyResult{}
+		if account.DataDir = p.ask("Data dir", ""); len(account.DataDir) == 0 {
+			return nil, err
+		}
+		if account.DataKey = p.ask("Data key", ""); len(account.DataKey) == 0 {
+			return nil, fmt.Errorf("data key is required")
+		}
+		account.ImgKey = p.ask("Image key (optional)", "")
+		account.Platform = p.ask("Platform", runtime.GOOS)
+		account.Version, _ = strconv.Atoi(p.ask("Version", "4"))
+		return account, nil
+	}
+
+	if len(ret) > 1 {
+		for i, r := range ret {
+			p.printf("%d. %s\n", i+1, r)
+		}
+		n, _ := strconv.Atoi(p.ask("Select an account", "1"))
+		if n < 1 || n > len(ret) {
+			return nil, fmt.Errorf("invalid selection: %d", n)
+		}
+		p.printf("extracting key for %s ...\n", ret[n-1].Account)
+		if ret, err = m.CommandKey("", int(ret[n-1].PID), initForce, false, Account); err != nil {
+			return nil, err
+		}
+	}
+
+	account := ret[0]
+	p.printf("account: %s (%s v%s)\ndata dir: %s\n", account.Account, account.Platform, account.FullVersion, account.DataDir)
+	return account, nil
+}
+
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func newToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// interactive 标准输入是否为终端，在 cron、管道等非交互环境中不询问
+func interactive() bool {
+	return util.IsTerminal(os.Stdin)
+}
+
+// prompter 向导的问答，提示写入 stderr，便于 -o json 时 stdout 只包含结果
+type prompter struct {
+	in    *bufio.Reader
+	out   io.Writer
+	yes   bool
+	stepN int
+}
+
+func (p *prompter) printf(format string, args ...any) {
+	fmt.Fprintf(p.out, format, args...)
+}
+
+func (p *prompter) step(title string) {
+	p.stepN++
+	p.printf("\n[%d] %s\n", p.stepN, title)
+}
+
+// ask 读取一行输入，为空时使用默认值
+func (p *prompter) ask(label, def string) string {
+	if len(def) != 0 {
+		p.printf("%s [%s]: ", label, def)
+	} else {
+		p.printf("%s: ", label)
+	}
+	if p.yes {
+		p.printf("%s\n", def)
+		return def
+	}
+	line, err := p.in.ReadString('\n')
+	line = strings.TrimSpace(line)
+	if err != nil && len(line) == 0 {
+		p.printf("\n")
+		return def
+	}
+	if len(line) == 0 {
+		return def
+	}
+	return line
+}
+
+func (p *prompter) confirm(label string, def bool) bool {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	for {
+		switch strings.ToLower(p.ask(label+" ("+hint+")", "")) {
+		case "":
+			return def
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		}
+	}
+}
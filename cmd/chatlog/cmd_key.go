@@ -5,7 +5,6 @@ import (
 
 	"github.com/DanielMao1/chatlog/internal/chatlog"
 
-	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
 
@@ -25,12 +24,23 @@ var keyCmd = &cobra.Command{
 	Use:   "key",
 	Short: "key",
 	Run: func(cmd *cobra.Command, args []string) {
-		m := chatlog.New()
-		ret, err := m.CommandKey("", keyPID, keyForce, keyShowXorKey)
+		m := chatlog.New(cmd.Context())
+		ret, err := m.CommandKey("", keyPID, keyForce, keyShowXorKey, Account)
 		if err != nil {
-			log.Err(err).Msg("failed to get key")
+			printError(err, "failed to get key")
 			return
 		}
-		fmt.Println(ret)
+		if jsonOutput() {
+			printJSON(ret)
+			return
+		}
+		if len(ret) == 1 && len(ret[0].DataKey) != 0 {
+			fmt.Println(ret[0])
+			return
+		}
+		fmt.Println("Select a process:")
+		for _, r := range ret {
+			fmt.Println(r)
+		}
 	},
 }
@@ -0,0 +1,93 @@
+package chatlog
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(matrixCmd)
+	matrixCmd.Flags().StringVarP(&matrixPlatform, "platform", "p", "", "platform")
+	matrixCmd.Flags().IntVarP(&matrixVer, "version", "v", 0, "version")
+	matrixCmd.Flags().StringVarP(&matrixDataDir, "data-dir", "d", "", "data dir")
+	matrixCmd.Flags().StringVarP(&matrixWorkDir, "work-dir", "w", "", "work dir")
+	matrixCmd.Flags().StringVarP(&matrixTalker, "talker", "t", "", "talker id or name")
+	matrixCmd.Flags().StringVar(&matrixTime, "time", "all", "time range, e.g. all, last-30d, 2024-01-01~2024-06-30")
+	matrixCmd.Flags().StringVar(&matrixRoom, "room", "", "room id (!xxx:server) or alias (#xxx:server), overriding matrix.room in config")
+	matrixCmd.Flags().StringVarP(&matrixOutput, "file", "f", "", "export Matrix event JSON to this file instead of replaying")
+	matrixCmd.MarkFlagRequired("talker")
+}
+
+var (
+	matrixPlatform string
+	matrixVer      int
+	matrixDataDir  string
+	matrixWorkDir  string
+	matrixTalker   string
+	matrixTime     string
+	matrixRoom     string
+	matrixOutput   string
+)
+
+var matrixCmd = &cobra.Command{
+	Use:   "matrix",
+	Short: "Export a conversation as Matrix events or replay it into a Matrix room",
+	Long: `Move the history of a conversation to Matrix.
+
+With --file the messages are written as Matrix room events (the JSON layout
+of Element's chat export): one m.room.member event per sender with the
+display name, then m.room.message events with the original timestamps.
+
+Without --file the messages are sent in order to a Matrix room with the
+account in matrix.homeserver and matrix.access_token (or the environment
+variable CHATLOG_MATRIX_ACCESS_TOKEN). Each message is prefixed with the
+sender's name and the original time. Every message has a stable transaction
+id, so an interrupted replay can be run again without duplicates.`,
+	Example: `chatlog matrix --talker "Project Group" -f project.json
+chatlog matrix --talker 12345@chatroom --room '#project:example.org'
+chatlog matrix --talker filehelper --time last-30d --room '!abc:example.org'`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		cmdConf := newCmdConf()
+		if len(matrixDataDir) != 0 {
+			cmdConf["data_dir"] = matrixDataDir
+		}
+		if len(matrixWorkDir) != 0 {
+			cmdConf["work_dir"] = matrixWorkDir
+		}
+		if len(matrixPlatform) != 0 {
+			cmdConf["platform"] = matrixPlatform
+		}
+		if matrixVer != 0 {
+			cmdConf["version"] = matrixVer
+		}
+
+		m := chatlog.New(cmd.Context())
+		count, err := m.CommandMatrix("", cmdConf, matrixTalker, matrixTime, matrixRoom, matrixOutput, func(sent, total int) {
+			if !jsonOutput() {
+				fmt.Fprintf(os.Stderr, "\rreplayed %d/%d", sent, total)
+			}
+		})
+		if err != nil {
+			printError(err, "failed to move messages to matrix")
+			return
+		}
+
+		if jsonOutput() {
+			printJSON(map[string]any{"talker": matrixTalker, "count": count, "file": matrixOutput, "room": matrixRoom})
+			return
+		}
+		if len(matrixOutput) != 0 {
+			fmt.Printf("exported %d messages to %s\n", count, matrixOutput)
+			return
+		}
+		if count != 0 {
+			fmt.Fprintln(os.Stderr)
+		}
+		fmt.Printf("replayed %d messages\n", count)
+	},
+}
@@ -0,0 +1,69 @@
+package chatlog
+
+import (
+	"fmt"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(mergeCmd)
+	mergeCmd.Flags().StringVarP(&mergePlatform, "platform", "p", "", "platform, detected from source dir if not set")
+	mergeCmd.Flags().IntVarP(&mergeVer, "version", "v", 0, "version, detected from source dir if not set")
+	mergeCmd.Flags().StringVarP(&mergeWorkDir, "work-dir", "w", "", "destination work dir")
+}
+
+var (
+	mergePlatform string
+	mergeVer      int
+	mergeWorkDir  string
+)
+
+var mergeCmd = &cobra.Command{
+	Use:   "merge <src-dir>...",
+	Short: "Merge decrypted work dirs into one, deduplicating messages",
+	Long: `Merge decrypted work dirs from other machines or earlier snapshots into the work dir.
+
+Messages are deduplicated, contacts and sessions already in the work dir are kept,
+and missing records are added. Stop any chatlog server using the work dir first.
+
+The sources are first merged into an archive dir next to the work dir
+(<work dir>-merged), then the archive is merged into the work dir. Decryption
+overwrites work dir databases with the ones in the data dir, so "chatlog decrypt"
+and auto decrypt merge the archive again afterwards; keep the archive dir as long
+as the merged history is needed.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		cmdConf := getMergeConfig()
+
+		m := chatlog.New(cmd.Context())
+		ret, err := m.CommandMerge("", cmdConf, args)
+		if err != nil {
+			printError(err, "failed to merge")
+			return
+		}
+
+		if jsonOutput() {
+			printJSON(ret)
+			return
+		}
+		fmt.Printf("merge success, %d messages added, %d records added, %d files copied\n", ret.Messages, ret.Records, ret.Files)
+	},
+}
+
+func getMergeConfig() map[string]any {
+	cmdConf := newCmdConf()
+	if len(mergeWorkDir) != 0 {
+		cmdConf["work_dir"] = mergeWorkDir
+	}
+	if len(mergePlatform) != 0 {
+		cmdConf["platform"] = mergePlatform
+	}
+	if mergeVer != 0 {
+		cmdConf["version"] = mergeVer
+	}
+	return cmdConf
+}
@@ -0,0 +1,92 @@
+package chatlog
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(notionCmd)
+	notionCmd.Flags().StringVarP(&notionPlatform, "platform", "p", "", "platform")
+	notionCmd.Flags().IntVarP(&notionVer, "version", "v", 0, "version")
+	notionCmd.Flags().StringVarP(&notionDataDir, "data-dir", "d", "", "data dir")
+	notionCmd.Flags().StringVarP(&notionWorkDir, "work-dir", "w", "", "work dir")
+	notionCmd.Flags().StringVarP(&notionTalker, "talker", "t", "", "talker id or name")
+	notionCmd.Flags().StringVar(&notionTime, "time", "", "time range, e.g. all, yesterday, last-7d; defaults to all, or yesterday with --digest")
+	notionCmd.Flags().BoolVar(&notionDigest, "digest", false, "write one summary page per day instead of one page per message")
+	notionCmd.MarkFlagRequired("talker")
+}
+
+var (
+	notionPlatform string
+	notionVer      int
+	notionDataDir  string
+	notionWorkDir  string
+	notionTalker   string
+	notionTime     string
+	notionDigest   bool
+)
+
+var notionCmd = &cobra.Command{
+	Use:   "notion",
+	Short: "Write messages or daily digests to a Notion database",
+	Long: `Write the messages of a conversation to a Notion database with the
+integration in notion.token (or the environment variable CHATLOG_NOTION_TOKEN)
+and notion.database_id. Add the integration to the database's connections
+first.
+
+Each message becomes a page: the content goes to the title property, and
+the sender, time, talker and media link go to the properties named in
+notion.properties (Sender, Time, Talker and Media by default). Properties
+missing from the database are skipped; rich text, select, date and url
+properties are supported.
+
+With --digest each day becomes one page with the summary and highlights,
+generated by the model in llm when configured.
+
+Written messages and days are recorded in the work dir, so running the
+command again, e.g. from cron, only adds what is new.`,
+	Example: `chatlog notion --talker "Project Group"
+chatlog notion --talker 12345@chatroom --time last-7d
+chatlog notion --talker 12345@chatroom --digest`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		cmdConf := newCmdConf()
+		if len(notionDataDir) != 0 {
+			cmdConf["data_dir"] = notionDataDir
+		}
+		if len(notionWorkDir) != 0 {
+			cmdConf["work_dir"] = notionWorkDir
+		}
+		if len(notionPlatform) != 0 {
+			cmdConf["platform"] = notionPlatform
+		}
+		if notionVer != 0 {
+			cmdConf["version"] = notionVer
+		}
+
+		m := chatlog.New(cmd.Context())
+		count, err := m.CommandNotion("", cmdConf, notionTalker, notionTime, notionDigest, func(written, total int) {
+			if !jsonOutput() {
+				fmt.Fprintf(os.Stderr, "\rwritten %d/%d", written, total)
+			}
+		})
+		if count != 0 && !jsonOutput() {
+			fmt.Fprintln(os.Stderr)
+		}
+		if err != nil {
+			printError(err, "failed to write to notion")
+			return
+		}
+
+		if jsonOutput() {
+			printJSON(map[string]any{"talker": notionTalker, "count": count, "digest": notionDigest})
+			return
+		}
+		fmt.Printf("wrote %d pages to notion\n", count)
+	},
+}
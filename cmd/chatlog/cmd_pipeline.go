@@ -0,0 +1,98 @@
+package chatlog
+
+import (
+	"context"
+	"os"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog"
+)
+
+func init() {
+	rootCmd.Flags().BoolVar(&noTUI, "no-tui", false, "run key → decrypt → export/server without the terminal UI, progress is written to stderr as JSON lines")
+	rootCmd.Flags().IntVar(&pipelinePID, "pid", 0, "wechat process id, default to the first one found")
+	rootCmd.Flags().BoolVar(&pipelineForce, "force", false, "extract the key again even if one is configured, and decrypt all databases again")
+	rootCmd.Flags().StringVarP(&pipelinePlatform, "platform", "p", "", "platform")
+	rootCmd.Flags().IntVarP(&pipelineVer, "version", "v", 0, "version")
+	rootCmd.Flags().StringVarP(&pipelineDataDir, "data-dir", "d", "", "data dir")
+	rootCmd.Flags().StringVarP(&pipelineDataKey, "data-key", "k", "", "data key")
+	rootCmd.Flags().StringVarP(&pipelineImgKey, "img-key", "i", "", "img key")
+	rootCmd.Flags().StringVarP(&pipelineWorkDir, "work-dir", "w", "", "work dir")
+	rootCmd.Flags().StringVar(&pipelineExport, "export", "", "export messages to file, format is chosen by extension (.csv, .json, .txt)")
+	rootCmd.Flags().StringVarP(&pipelineTalker, "talker", "t", "", "talker to export")
+	rootCmd.Flags().StringVar(&pipelineTime, "time", "all", "time range to export")
+	rootCmd.Flags().StringVar(&pipelineTranslate, "translate", "", "add translations of text messages in this language to the export, e.g. en")
+	rootCmd.Flags().StringVar(&pipelineScript, "export-script", "", "script file run on each message before export, to drop or rewrite it")
+	rootCmd.Flags().BoolVar(&pipelineServe, "serve", false, "start http server after decryption")
+	rootCmd.Flags().StringVarP(&pipelineAddr, "addr", "a", "", "http address")
+	rootCmd.Flags().BoolVar(&pipelineAutoDecrypt, "auto-decrypt", false, "enable auto decrypt while serving")
+}
+
+var (
+	noTUI               bool
+	pipelinePID         int
+	pipelineForce       bool
+	pipelinePlatform    string
+	pipelineVer         int
+	pipelineDataDir     string
+	pipelineDataKey     string
+	pipelineImgKey      string
+	pipelineWorkDir     string
+	pipelineExport      string
+	pipelineTalker      string
+	pipelineTime        string
+	pipelineTranslate   string
+	pipelineScript      string
+	pipelineServe       bool
+	pipelineAddr        string
+	pipelineAutoDecrypt bool
+)
+
+// runPipeline 非交互模式入口，适用于 cron / CI 等无终端环境
+func runPipeline(ctx context.Context) {
+	cmdConf := getPipelineConfig()
+
+	m := chatlog.New(ctx)
+	err := m.CommandPipeline("", cmdConf, chatlog.PipelineOptions{
+		PID:          pipelinePID,
+		Force:        pipelineForce,
+		Export:       pipelineExport,
+		ExportTalker: pipelineTalker,
+		ExportTime:   pipelineTime,
+		Translate:    pipelineTranslate,
+		ExportScript: pipelineScript,
+		Serve:        pipelineServe,
+	})
+	if err != nil {
+		printError(err, "pipeline failed")
+		os.Exit(exitCode)
+	}
+}
+
+func getPipelineConfig() map[string]any {
+	cmdConf := newCmdConf()
+	if len(pipelineDataDir) != 0 {
+		cmdConf["data_dir"] = pipelineDataDir
+	}
+	if len(pipelineDataKey) != 0 {
+		cmdConf["data_key"] = pipelineDataKey
+	}
+	if len(pipelineImgKey) != 0 {
+		cmdConf["img_key"] = pipelineImgKey
+	}
+	if len(pipelineWorkDir) != 0 {
+		cmdConf["work_dir"] = pipelineWorkDir
+	}
+	if len(pipelinePlatform) != 0 {
+		cmdConf["platform"] = pipelinePlatform
+	}
+	if pipelineVer != 0 {
+		cmdConf["version"] = pipelineVer
+	}
+	if len(pipelineAddr) != 0 {
+		cmdConf["http_addr"] = pipelineAddr
+	}
+	if pipelineAutoDecrypt {
+		cmdConf["auto_decrypt"] = true
+	}
+	return cmdConf
+}
@@ -0,0 +1,151 @@
+package chatlog
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog"
+	"github.com/DanielMao1/chatlog/internal/chatlog/prune"
+	"github.com/DanielMao1/chatlog/pkg/util"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(pruneCmd)
+	pruneCmd.Flags().StringVarP(&prunePlatform, "platform", "p", "", "platform")
+	pruneCmd.Flags().IntVarP(&pruneVer, "version", "v", 0, "version")
+	pruneCmd.Flags().StringVarP(&pruneDataDir, "data-dir", "d", "", "data dir")
+	pruneCmd.Flags().StringVarP(&pruneWorkDir, "work-dir", "w", "", "work dir")
+	pruneCmd.Flags().StringVarP(&pruneBackupDir, "backup-dir", "D", "", "backup dir, default to <work dir>/../backup")
+	pruneCmd.Flags().IntVarP(&pruneKeepBackups, "keep-backups", "n", 0, "keep the last N backups of the account, default to prune.keep_backups in config, 0 to keep all")
+	pruneCmd.Flags().DurationVar(&pruneTempMaxAge, "temp-max-age", 0, "remove temp files older than this, default to prune.temp_max_age in config or 1h")
+	pruneCmd.Flags().DurationVar(&pruneCacheMaxAge, "cache-max-age", 0, "remove cached copies older than this, default to prune.cache_max_age in config or 24h")
+	pruneCmd.Flags().BoolVar(&pruneStale, "stale", false, "also remove decrypted databases whose source no longer exists in the data dir, default to prune.stale in config")
+	pruneCmd.Flags().BoolVar(&pruneDryRun, "dry-run", false, "only list the files that would be removed")
+	pruneCmd.Flags().BoolVarP(&pruneYes, "yes", "y", false, "remove files without asking for confirmation")
+}
+
+var (
+	prunePlatform    string
+	pruneVer         int
+	pruneDataDir     string
+	pruneWorkDir     string
+	pruneBackupDir   string
+	pruneKeepBackups int
+	pruneTempMaxAge  time.Duration
+	pruneCacheMaxAge time.Duration
+	pruneStale       bool
+	pruneDryRun      bool
+	pruneYes         bool
+)
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove stale shards, temp files, cached copies and old backups",
+	Long: `Remove files that are no longer needed and report the reclaimed space:
+
+  temp    leftovers of interrupted decryption
+  cache   temporary copies of media and database files
+  backup  backups beyond the retention count
+  stale   with --stale, databases decrypted by chatlog whose source no longer
+          exists in the data dir; databases added by "chatlog merge" or
+          "chatlog import" are never removed
+
+When run in a terminal, the files are listed and removed only after
+confirmation. Use --yes to skip the confirmation.`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		cmdConf := getPruneConfig(cmd)
+
+		// 确认时已列出将要删除的文件
+		ask := !pruneDryRun && !pruneYes && !jsonOutput() && interactive()
+		if ask && !confirmPrune(cmd.Context(), cmdConf) {
+			return
+		}
+
+		m := chatlog.New(cmd.Context())
+		ret, err := m.CommandPrune("", cmdConf, prune.Options{
+			BackupDir: pruneBackupDir,
+			DryRun:    pruneDryRun,
+		})
+		if err != nil {
+			printError(err, "failed to prune")
+			return
+		}
+
+		if jsonOutput() {
+			printJSON(ret)
+			return
+		}
+		if !ask {
+			for _, item := range ret.Items {
+				fmt.Printf("[%s] %s (%s)\n", item.Reason, item.Path, util.ByteCountSI(item.Size))
+			}
+		}
+		if ret.DryRun {
+			fmt.Printf("%d files, %s would be reclaimed\n", len(ret.Items), util.ByteCountSI(ret.Reclaimed))
+			return
+		}
+		fmt.Printf("%d files removed, %s reclaimed\n", len(ret.Items), util.ByteCountSI(ret.Reclaimed))
+	},
+}
+
+// confirmPrune 列出将要删除的文件，确认后才执行清理
+func confirmPrune(ctx context.Context, cmdConf map[string]any) bool {
+	m := chatlog.New(ctx)
+	ret, err := m.CommandPrune("", cmdConf, prune.Options{
+		BackupDir: pruneBackupDir,
+		DryRun:    true,
+	})
+	if err != nil {
+		printError(err, "failed to prune")
+		return false
+	}
+	if len(ret.Items) == 0 {
+		fmt.Println("nothing to prune")
+		return false
+	}
+	for _, item := range ret.Items {
+		fmt.Printf("[%s] %s (%s)\n", item.Reason, item.Path, util.ByteCountSI(item.Size))
+	}
+	p := &prompter{in: bufio.NewReader(os.Stdin), out: os.Stderr}
+	if !p.confirm(fmt.Sprintf("Permanently remove %d files and reclaim %s?", len(ret.Items), util.ByteCountSI(ret.Reclaimed)), false) {
+		fmt.Println("aborted, nothing removed")
+		return false
+	}
+	return true
+}
+
+func getPruneConfig(cmd *cobra.Command) map[string]any {
+	cmdConf := newCmdConf()
+	if len(pruneDataDir) != 0 {
+		cmdConf["data_dir"] = pruneDataDir
+	}
+	if len(pruneWorkDir) != 0 {
+		cmdConf["work_dir"] = pruneWorkDir
+	}
+	if len(prunePlatform) != 0 {
+		cmdConf["platform"] = prunePlatform
+	}
+	if pruneVer != 0 {
+		cmdConf["version"] = pruneVer
+	}
+	// 0 是 keep-backups 的有效值，以是否显式指定为准
+	if cmd.Flags().Changed("keep-backups") {
+		cmdConf["prune.keep_backups"] = pruneKeepBackups
+	}
+	if pruneTempMaxAge != 0 {
+		cmdConf["prune.temp_max_age"] = pruneTempMaxAge
+	}
+	if pruneCacheMaxAge != 0 {
+		cmdConf["prune.cache_max_age"] = pruneCacheMaxAge
+	}
+	if pruneStale {
+		cmdConf["prune.stale"] = true
+	}
+	return cmdConf
+}
@@ -0,0 +1,82 @@
+package chatlog
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog"
+	"github.com/DanielMao1/chatlog/internal/chatlog/report"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+	reportCmd.Flags().StringVarP(&reportPlatform, "platform", "p", "", "platform")
+	reportCmd.Flags().IntVarP(&reportVer, "version", "v", 0, "version")
+	reportCmd.Flags().StringVarP(&reportDataDir, "data-dir", "d", "", "data dir")
+	reportCmd.Flags().StringVarP(&reportWorkDir, "work-dir", "w", "", "work dir")
+	reportCmd.Flags().IntVarP(&reportYear, "year", "y", time.Now().Year(), "year of the report")
+	reportCmd.Flags().IntVarP(&reportTop, "top", "n", report.DefaultTop, "number of top contacts and chatrooms")
+	reportCmd.Flags().StringVarP(&reportFile, "file", "f", "", "output HTML file, defaults to report-<year>.html")
+}
+
+var (
+	reportPlatform string
+	reportVer      int
+	reportDataDir  string
+	reportWorkDir  string
+	reportYear     int
+	reportTop      int
+	reportFile     string
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Generate an annual report as a self-contained HTML page",
+	Long: `Generate an annual report (年度报告) from the local messages of a year:
+message totals, the busiest day, the longest streak of days with messages,
+top contacts and chatrooms, hours of the day, the emoji you used most and
+media counts.
+
+The report is a single HTML file with no external resources, so it can be
+opened offline or shared as is. Nothing is sent to any online service.`,
+	Example: `chatlog report --year 2024
+chatlog report --year 2024 -f ~/Desktop/2024.html --top 5
+chatlog report --year 2024 -o json`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		cmdConf := newCmdConf()
+		if len(reportDataDir) != 0 {
+			cmdConf["data_dir"] = reportDataDir
+		}
+		if len(reportWorkDir) != 0 {
+			cmdConf["work_dir"] = reportWorkDir
+		}
+		if len(reportPlatform) != 0 {
+			cmdConf["platform"] = reportPlatform
+		}
+		if reportVer != 0 {
+			cmdConf["version"] = reportVer
+		}
+
+		// json 模式只输出统计结果，指定 --file 时同时写入 HTML
+		file := reportFile
+		if len(file) == 0 && !jsonOutput() {
+			file = fmt.Sprintf("report-%d.html", reportYear)
+		}
+
+		m := chatlog.New(cmd.Context())
+		r, err := m.CommandReport("", cmdConf, reportYear, reportTop, file)
+		if err != nil {
+			printError(err, "failed to generate report")
+			return
+		}
+
+		if jsonOutput() {
+			printJSON(r)
+			return
+		}
+		fmt.Printf("%d report (%d messages) written to %s\n", r.Year, r.Total, file)
+	},
+}
@@ -1,10 +1,15 @@
 package chatlog
 
 import (
+	"fmt"
+	"os"
+	"strings"
+
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 
 	"github.com/DanielMao1/chatlog/internal/chatlog"
+	"github.com/DanielMao1/chatlog/pkg/daemon"
 )
 
 func init() {
@@ -19,6 +24,8 @@ func init() {
 	serverCmd.Flags().StringVarP(&serverImgKey, "img-key", "i", "", "img key")
 	serverCmd.Flags().StringVarP(&serverWorkDir, "work-dir", "w", "", "work dir")
 	serverCmd.Flags().BoolVarP(&serverAutoDecrypt, "auto-decrypt", "", false, "auto decrypt")
+	serverCmd.Flags().BoolVarP(&serverDaemon, "daemon", "", false, "run server in background")
+	serverCmd.Flags().BoolVar(&serverPprof, "pprof", false, "enable /debug/pprof and /debug/runtime for chatlog debug dump")
 }
 
 var (
@@ -30,6 +37,8 @@ var (
 	serverPlatform    string
 	serverVer         int
 	serverAutoDecrypt bool
+	serverDaemon      bool
+	serverPprof       bool
 )
 
 var serverCmd = &cobra.Command{
@@ -37,19 +46,62 @@ var serverCmd = &cobra.Command{
 	Short: "Start HTTP server",
 	Run: func(cmd *cobra.Command, args []string) {
 
+		if serverDaemon {
+			startServerDaemon()
+			return
+		}
+
 		cmdConf := getServerConfig()
 		log.Info().Msgf("server cmd config: %+v", cmdConf)
 
-		m := chatlog.New()
-		if err := m.CommandHTTPServer("", cmdConf); err != nil {
-			log.Err(err).Msg("failed to start server")
+		m := chatlog.New(cmd.Context())
+		run := func() error { return m.CommandHTTPServer("", cmdConf) }
+
+		// 由 Windows 服务管理器启动时，需要响应服务控制请求
+		isService, err := daemon.RunService(serviceName, run)
+		if !isService {
+			err = run()
+		}
+		if err != nil {
+			printError(err, "failed to start server")
 			return
 		}
 	},
 }
 
+// startServerDaemon 去掉 --daemon 参数后在后台重新启动 server
+func startServerDaemon() {
+	logFile := LogFile
+	if logFile == "" {
+		logFile = defaultServerLogFile()
+	}
+
+	args := make([]string, 0, len(os.Args))
+	for _, arg := range os.Args[1:] {
+		if arg == "--daemon" || strings.HasPrefix(arg, "--daemon=") {
+			continue
+		}
+		args = append(args, arg)
+	}
+	if len(LogFile) == 0 {
+		args = append(args, "--log-file", logFile)
+	}
+
+	pid, err := daemon.Start(args, logFile)
+	if err != nil {
+		printError(err, "failed to start daemon")
+		return
+	}
+
+	if jsonOutput() {
+		printJSON(map[string]any{"pid": pid, "logFile": logFile})
+		return
+	}
+	fmt.Printf("server started in background, pid: %d, log file: %s\n", pid, logFile)
+}
+
 func getServerConfig() map[string]any {
-	cmdConf := make(map[string]any)
+	cmdConf := newCmdConf()
 	if len(serverAddr) != 0 {
 		cmdConf["http_addr"] = serverAddr
 	}
@@ -74,5 +126,8 @@ func getServerConfig() map[string]any {
 	if serverAutoDecrypt {
 		cmdConf["auto_decrypt"] = true
 	}
+	if serverPprof {
+		cmdConf["pprof"] = true
+	}
 	return cmdConf
 }
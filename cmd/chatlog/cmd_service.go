@@ -0,0 +1,163 @@
+package chatlog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+	"github.com/DanielMao1/chatlog/pkg/daemon"
+
+	"github.com/spf13/cobra"
+)
+
+const serviceName = "chatlog"
+
+func init() {
+	rootCmd.AddCommand(serviceCmd)
+	serviceCmd.AddCommand(serviceInstallCmd)
+	serviceCmd.AddCommand(serviceUninstallCmd)
+	serviceInstallCmd.Flags().StringVarP(&serviceAddr, "addr", "a", "", "server address")
+	serviceInstallCmd.Flags().StringVarP(&servicePlatform, "platform", "p", "", "platform")
+	serviceInstallCmd.Flags().IntVarP(&serviceVer, "version", "v", 0, "version")
+	serviceInstallCmd.Flags().StringVarP(&serviceDataDir, "data-dir", "d", "", "data dir")
+	serviceInstallCmd.Flags().StringVarP(&serviceDataKey, "data-key", "k", "", "data key")
+	serviceInstallCmd.Flags().StringVarP(&serviceImgKey, "img-key", "i", "", "img key")
+	serviceInstallCmd.Flags().StringVarP(&serviceWorkDir, "work-dir", "w", "", "work dir")
+	serviceInstallCmd.Flags().BoolVarP(&serviceAutoDecrypt, "auto-decrypt", "", false, "auto decrypt")
+	serviceInstallCmd.Flags().StringVarP(&serviceLogFile, "log-file", "l", "", "log file, default to <config dir>/logs/server.log")
+}
+
+var (
+	serviceAddr        string
+	servicePlatform    string
+	serviceVer         int
+	serviceDataDir     string
+	serviceDataKey     string
+	serviceImgKey      string
+	serviceWorkDir     string
+	serviceAutoDecrypt bool
+	serviceLogFile     string
+)
+
+var serviceCmd = &cobra.Command{
+	Use:   "service",
+	Short: "Manage chatlog server as a system service",
+	Long: `Manage chatlog server as a system service.
+
+macOS:   launchd agent in ~/Library/LaunchAgents
+Linux:   systemd user unit in ~/.config/systemd/user, run "loginctl enable-linger" to start before login
+Windows: Windows service, requires administrator privileges`,
+}
+
+var serviceInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install and start chatlog server as a system service",
+	Run: func(cmd *cobra.Command, args []string) {
+		exe, err := os.Executable()
+		if err != nil {
+			printError(err, "failed to get executable path")
+			return
+		}
+		if exe, err = filepath.EvalSymlinks(exe); err != nil {
+			printError(err, "failed to get executable path")
+			return
+		}
+
+		logFile := serviceLogFile
+		if logFile == "" {
+			logFile = defaultServerLogFile()
+		}
+
+		path, err := daemon.Install(&daemon.Config{
+			Name:        serviceName,
+			DisplayName: "Chatlog",
+			Description: "Chatlog HTTP server",
+			Exec:        exe,
+			Args:        getServiceArgs(logFile),
+			LogFile:     logFile,
+		})
+		if err != nil {
+			printError(err, "failed to install service")
+			return
+		}
+
+		if jsonOutput() {
+			printJSON(map[string]string{"service": serviceName, "path": path, "logFile": logFile})
+			return
+		}
+		fmt.Printf("service installed: %s\nlog file: %s\n", path, logFile)
+	},
+}
+
+var serviceUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Stop and remove chatlog system service",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := daemon.Uninstall(serviceName); err != nil {
+			printError(err, "failed to uninstall service")
+			return
+		}
+		if jsonOutput() {
+			printJSON(map[string]any{"success": true})
+			return
+		}
+		fmt.Println("service uninstalled")
+	},
+}
+
+// getServiceArgs 生成服务的启动参数，未指定的参数由服务运行时从配置文件读取
+func getServiceArgs(logFile string) []string {
+	args := []string{"server", "--log-file", logFile}
+	if len(Profile) != 0 {
+		args = append(args, "--profile", Profile)
+	}
+	if len(Account) != 0 {
+		args = append(args, "--account", Account)
+	}
+	if Jobs != 0 {
+		args = append(args, "--jobs", strconv.Itoa(Jobs))
+	}
+	if KeyWorkers != 0 {
+		args = append(args, "--key-workers", strconv.Itoa(KeyWorkers))
+	}
+	if KeyQueueSize != 0 {
+		args = append(args, "--key-queue-size", strconv.Itoa(KeyQueueSize))
+	}
+	if KeyChunkSize != 0 {
+		args = append(args, "--key-chunk-size", strconv.Itoa(KeyChunkSize))
+	}
+	if KeyOverlap != 0 {
+		args = append(args, "--key-overlap", strconv.Itoa(KeyOverlap))
+	}
+	if len(serviceAddr) != 0 {
+		args = append(args, "--addr", serviceAddr)
+	}
+	if len(serviceDataDir) != 0 {
+		args = append(args, "--data-dir", serviceDataDir)
+	}
+	if len(serviceDataKey) != 0 {
+		args = append(args, "--data-key", serviceDataKey)
+	}
+	if len(serviceImgKey) != 0 {
+		args = append(args, "--img-key", serviceImgKey)
+	}
+	if len(serviceWorkDir) != 0 {
+		args = append(args, "--work-dir", serviceWorkDir)
+	}
+	if len(servicePlatform) != 0 {
+		args = append(args, "--platform", servicePlatform)
+	}
+	if serviceVer != 0 {
+		args = append(args, "--version", strconv.Itoa(serviceVer))
+	}
+	if serviceAutoDecrypt {
+		args = append(args, "--auto-decrypt")
+	}
+	return args
+}
+
+func defaultServerLogFile() string {
+	return filepath.Join(conf.ConfigDir(), "logs", "server.log")
+}
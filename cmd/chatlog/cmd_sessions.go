@@ -0,0 +1,139 @@
+package chatlog
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog"
+	"github.com/DanielMao1/chatlog/internal/chatlog/sessions"
+	"github.com/DanielMao1/chatlog/internal/chatlog/summarize"
+	"github.com/DanielMao1/chatlog/pkg/util"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(sessionsCmd)
+	sessionsCmd.Flags().StringVarP(&sessionsPlatform, "platform", "p", "", "platform")
+	sessionsCmd.Flags().IntVarP(&sessionsVer, "version", "v", 0, "version")
+	sessionsCmd.Flags().StringVarP(&sessionsDataDir, "data-dir", "d", "", "data dir")
+	sessionsCmd.Flags().StringVarP(&sessionsWorkDir, "work-dir", "w", "", "work dir")
+	sessionsCmd.Flags().StringVarP(&sessionsKeyword, "keyword", "q", "", "filter by wxid or name")
+	sessionsCmd.Flags().IntVarP(&sessionsLimit, "limit", "n", 20, "number of sessions, 0 for all")
+	sessionsCmd.Flags().StringVarP(&sessionsSince, "since", "s", "", "count new messages since a duration ago or a time, e.g. 24h, 7d, 2025-01-01, default the previous run")
+	sessionsCmd.Flags().BoolVarP(&sessionsChanged, "changed", "c", false, "only list sessions with new messages")
+	sessionsCmd.Flags().BoolVar(&sessionsNoSave, "no-save", false, "do not record this run as the start of the next count")
+}
+
+var (
+	sessionsPlatform string
+	sessionsVer      int
+	sessionsDataDir  string
+	sessionsWorkDir  string
+	sessionsKeyword  string
+	sessionsLimit    int
+	sessionsSince    string
+	sessionsChanged  bool
+	sessionsNoSave   bool
+)
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "List sessions by recent activity with new message counts",
+	Long: `List sessions ordered by last activity, with the number of messages received
+from others since the previous run of this command, so you can see which
+conversations changed since the last export or backup.
+
+The time of each run is recorded in ` + sessions.StateFile + ` in the work dir,
+use --no-save to leave it unchanged, or --since to count from another time.`,
+	Example: `chatlog sessions
+chatlog sessions --changed
+chatlog sessions --since 7d -n 0 -o json`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		var since time.Time
+		if len(sessionsSince) != 0 {
+			var err error
+			if since, err = parseSinceTime(sessionsSince); err != nil {
+				printError(err, "failed to list sessions")
+				return
+			}
+		}
+
+		cmdConf := newCmdConf()
+		if len(sessionsDataDir) != 0 {
+			cmdConf["data_dir"] = sessionsDataDir
+		}
+		if len(sessionsWorkDir) != 0 {
+			cmdConf["work_dir"] = sessionsWorkDir
+		}
+		if len(sessionsPlatform) != 0 {
+			cmdConf["platform"] = sessionsPlatform
+		}
+		if sessionsVer != 0 {
+			cmdConf["version"] = sessionsVer
+		}
+
+		m := chatlog.New(cmd.Context())
+		ret, err := m.CommandSessions("", cmdConf, sessionsKeyword, sessionsLimit, since, !sessionsNoSave)
+		if err != nil {
+			printError(err, "failed to list sessions")
+			return
+		}
+
+		if sessionsChanged {
+			items := make([]*sessions.Item, 0)
+			for _, item := range ret.Items {
+				if item.New > 0 {
+					items = append(items, item)
+				}
+			}
+			ret.Items = items
+		}
+
+		if jsonOutput() {
+			printJSON(ret)
+			return
+		}
+
+		if ret.Since.IsZero() {
+			fmt.Println("first run, new messages will be counted from now on")
+		} else {
+			fmt.Printf("new messages since %s\n", ret.Since.Format(time.DateTime))
+		}
+		fmt.Println()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NEW\tLAST ACTIVITY\tNAME\tTALKER\tLAST MESSAGE")
+		total := 0
+		for _, item := range ret.Items {
+			total += item.New
+			n := "-"
+			if item.New > 0 {
+				n = fmt.Sprintf("%d", item.New)
+			}
+			content := strings.Join(strings.Fields(item.Content), " ")
+			if r := []rune(content); len(r) > 40 {
+				content = string(r[:40]) + "..."
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", n, item.LastTime.Format("2006-01-02 15:04"), item.Name, item.UserName, content)
+		}
+		w.Flush()
+		if !ret.Since.IsZero() {
+			fmt.Printf("\n%d new messages\n", total)
+		}
+	},
+}
+
+// parseSinceTime 解析 24h、7d 形式的时长或 2025-01-01 等时间
+func parseSinceTime(s string) (time.Time, error) {
+	if d, err := summarize.ParseSince(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if start, _, ok := util.TimeRangeOf(s); ok {
+		return start, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time: %s", s)
+}
@@ -0,0 +1,116 @@
+package chatlog
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog"
+	"github.com/DanielMao1/chatlog/internal/model"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+	statsCmd.Flags().StringVarP(&statsPlatform, "platform", "p", "", "platform")
+	statsCmd.Flags().IntVarP(&statsVer, "version", "v", 0, "version")
+	statsCmd.Flags().StringVarP(&statsDataDir, "data-dir", "d", "", "data dir")
+	statsCmd.Flags().StringVarP(&statsWorkDir, "work-dir", "w", "", "work dir")
+	statsCmd.Flags().StringVarP(&statsTime, "time", "t", "all", "time range, e.g. 2024, 2024-01~2024-06, last-30d")
+	statsCmd.Flags().IntVarP(&statsTop, "top", "n", 10, "number of top contacts and chatrooms")
+	statsCmd.Flags().StringVarP(&statsFormat, "format", "f", "table", "output format, table or json")
+}
+
+var (
+	statsPlatform string
+	statsVer      int
+	statsDataDir  string
+	statsWorkDir  string
+	statsTime     string
+	statsTop      int
+	statsFormat   string
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show account statistics",
+	Run: func(cmd *cobra.Command, args []string) {
+
+		cmdConf := getStatsConfig()
+
+		m := chatlog.New(cmd.Context())
+		stats, err := m.CommandStats("", cmdConf, statsTime, statsTop)
+		if err != nil {
+			printError(err, "failed to get stats")
+			return
+		}
+
+		switch outputFormat(statsFormat) {
+		case OutputJSON:
+			printJSON(stats)
+		default:
+			printStats(stats)
+		}
+	},
+}
+
+func getStatsConfig() map[string]any {
+	cmdConf := newCmdConf()
+	if len(statsDataDir) != 0 {
+		cmdConf["data_dir"] = statsDataDir
+	}
+	if len(statsWorkDir) != 0 {
+		cmdConf["work_dir"] = statsWorkDir
+	}
+	if len(statsPlatform) != 0 {
+		cmdConf["platform"] = statsPlatform
+	}
+	if statsVer != 0 {
+		cmdConf["version"] = statsVer
+	}
+	return cmdConf
+}
+
+func printStats(stats *model.Stats) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintf(w, "Messages\t%d\n", stats.Total)
+	fmt.Fprintf(w, "Sessions\t%d\n", stats.Sessions)
+
+	fmt.Fprintln(w, "\nYear\tMessages")
+	for _, key := range sortedKeys(stats.ByYear) {
+		fmt.Fprintf(w, "%s\t%d\n", key, stats.ByYear[key])
+	}
+
+	fmt.Fprintln(w, "\nMonth\tMessages")
+	for _, key := range sortedKeys(stats.ByMonth) {
+		fmt.Fprintf(w, "%s\t%d\n", key, stats.ByMonth[key])
+	}
+
+	fmt.Fprintln(w, "\nMedia\tMessages")
+	for _, key := range sortedKeys(stats.Media) {
+		fmt.Fprintf(w, "%s\t%d\n", key, stats.Media[key])
+	}
+
+	fmt.Fprintln(w, "\nTop Contacts\tUserName\tMessages")
+	for _, item := range stats.TopContact {
+		fmt.Fprintf(w, "%s\t%s\t%d\n", item.Name, item.UserName, item.Count)
+	}
+
+	fmt.Fprintln(w, "\nTop ChatRooms\tUserName\tMessages")
+	for _, item := range stats.TopRoom {
+		fmt.Fprintf(w, "%s\t%s\t%d\n", item.Name, item.UserName, item.Count)
+	}
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,104 @@
+package chatlog
+
+import (
+	"fmt"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog"
+	"github.com/DanielMao1/chatlog/internal/chatlog/summarize"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(summarizeCmd)
+	summarizeCmd.Flags().StringVarP(&summarizePlatform, "platform", "p", "", "platform")
+	summarizeCmd.Flags().IntVarP(&summarizeVer, "version", "v", 0, "version")
+	summarizeCmd.Flags().StringVarP(&summarizeDataDir, "data-dir", "d", "", "data dir")
+	summarizeCmd.Flags().StringVarP(&summarizeWorkDir, "work-dir", "w", "", "work dir")
+	summarizeCmd.Flags().StringVarP(&summarizeTalker, "talker", "t", "", "talker id or name")
+	summarizeCmd.Flags().StringVarP(&summarizeSince, "since", "s", "24h", "summarize messages in this period, e.g. 30m, 24h, 7d")
+	summarizeCmd.Flags().StringVar(&summarizeTo, "to", summarize.ToStdout, "stdout, webhook (summarize in config), a destination name or a URL")
+	summarizeCmd.Flags().StringVar(&summarizeProvider, "provider", "", "llm provider overriding llm.provider in config: openai, ollama or none")
+	summarizeCmd.MarkFlagRequired("talker")
+}
+
+var (
+	summarizePlatform string
+	summarizeVer      int
+	summarizeDataDir  string
+	summarizeWorkDir  string
+	summarizeTalker   string
+	summarizeSince    string
+	summarizeTo       string
+	summarizeProvider string
+)
+
+var summarizeCmd = &cobra.Command{
+	Use:   "summarize",
+	Short: "Summarize recent messages of a talker and push or print it",
+	Long: `Collect the messages of a talker in a recent period into a summary with
+highlights (titles of shared links and files), then print it or POST it as JSON.
+
+With --to webhook the summary is posted to summarize.url in the config file,
+along with summarize.headers, e.g. an auth token required by the receiver,
+or to the destination named by summarize.destination. --to also accepts the
+name of any destination configured under destinations.
+
+When llm is configured (provider openai for any OpenAI-compatible API, or
+ollama for a local model), the messages are sent to the model, which writes
+the summary and highlights; titles of shared links and files are appended to
+the highlights. Use --provider none to skip the model for one run.`,
+	Example: `chatlog summarize --talker filehelper --since 24h --to webhook
+chatlog summarize --talker "Project Group" --since 7d -o json
+chatlog summarize --talker filehelper --to relay
+chatlog summarize --talker "Project Group" --provider ollama`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		since, err := summarize.ParseSince(summarizeSince)
+		if err != nil {
+			printError(err, "failed to summarize")
+			return
+		}
+
+		cmdConf := newCmdConf()
+		if len(summarizeDataDir) != 0 {
+			cmdConf["data_dir"] = summarizeDataDir
+		}
+		if len(summarizeWorkDir) != 0 {
+			cmdConf["work_dir"] = summarizeWorkDir
+		}
+		if len(summarizePlatform) != 0 {
+			cmdConf["platform"] = summarizePlatform
+		}
+		if summarizeVer != 0 {
+			cmdConf["version"] = summarizeVer
+		}
+		if len(summarizeProvider) != 0 {
+			cmdConf["llm.provider"] = summarizeProvider
+		}
+
+		m := chatlog.New(cmd.Context())
+		payload, err := m.CommandSummarize("", cmdConf, summarizeTalker, since, summarizeTo)
+		if err != nil {
+			printError(err, "failed to summarize")
+			return
+		}
+
+		if jsonOutput() {
+			printJSON(payload)
+			return
+		}
+		if summarizeTo != summarize.ToStdout {
+			fmt.Printf("summary of %s (%d messages) pushed to %s\n", payload.Group, payload.MessageCount, summarizeTo)
+			return
+		}
+		fmt.Printf("# %s (%s), %d messages since %s\n\n", payload.Group, payload.Talker, payload.MessageCount, payload.Since)
+		fmt.Println(payload.Summary)
+		if len(payload.Highlights) != 0 {
+			fmt.Println("\n## Highlights")
+			for _, h := range payload.Highlights {
+				fmt.Printf("- %s\n", h)
+			}
+		}
+	},
+}
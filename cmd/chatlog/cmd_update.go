@@ -0,0 +1,109 @@
+package chatlog
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/update"
+	"github.com/DanielMao1/chatlog/pkg/version"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(updateCmd)
+	updateCmd.Flags().BoolVarP(&updateCheck, "check", "c", false, "only check for a new version")
+	updateCmd.Flags().BoolVarP(&updateForce, "force", "f", false, "install the latest release even if it is not newer, e.g. on development builds")
+}
+
+var (
+	updateCheck bool
+	updateForce bool
+)
+
+// UpdateResult update 命令的结果
+type UpdateResult struct {
+	Current   string `json:"current"`
+	Latest    string `json:"latest"`
+	URL       string `json:"url"`
+	Available bool   `json:"available"`
+	Updated   bool   `json:"updated"`
+	Path      string `json:"path,omitempty"`
+}
+
+var updateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Update chatlog to the latest release",
+	Long: `Check the latest GitHub release and replace the running binary with it.
+
+The package for the current platform is verified against the sha256 in the
+release's checksums.txt before the binary is replaced. Set GITHUB_TOKEN to
+avoid the rate limit of anonymous GitHub API requests.`,
+	Example: `chatlog update --check
+chatlog update`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+
+		rel, err := update.Latest(ctx)
+		if err != nil {
+			printError(err, "failed to check for updates")
+			return
+		}
+		ret := &UpdateResult{
+			Current:   version.Version,
+			Latest:    rel.Version(),
+			URL:       rel.HTMLURL,
+			Available: update.Newer(version.Version, rel.Version()),
+		}
+
+		if updateCheck || (!ret.Available && !updateForce) {
+			if jsonOutput() {
+				printJSON(ret)
+				return
+			}
+			switch {
+			case ret.Available:
+				fmt.Printf("new version %s is available (current %s), run `chatlog update` to install\n%s\n", ret.Latest, ret.Current, ret.URL)
+			case !update.Valid(ret.Current):
+				fmt.Printf("current version %s can not be compared with %s, use --force to install it\n", ret.Current, ret.Latest)
+			default:
+				fmt.Printf("chatlog %s is up to date\n", ret.Current)
+			}
+			return
+		}
+
+		exe, err := os.Executable()
+		if err == nil {
+			exe, err = filepath.EvalSymlinks(exe)
+		}
+		if err != nil {
+			printError(err, "failed to locate the chatlog binary")
+			return
+		}
+
+		if !jsonOutput() {
+			fmt.Printf("downloading chatlog %s ...\n", ret.Latest)
+		}
+		binary, err := update.Download(ctx, rel)
+		if err != nil {
+			printError(err, "failed to download update")
+			return
+		}
+		if err := update.Replace(exe, binary); err != nil {
+			printError(err, "failed to replace binary")
+			return
+		}
+		ret.Updated = true
+		ret.Path = exe
+
+		if jsonOutput() {
+			printJSON(ret)
+			return
+		}
+		fmt.Printf("updated %s from %s to %s\n", exe, ret.Current, ret.Latest)
+	},
+}
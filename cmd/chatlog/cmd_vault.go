@@ -0,0 +1,82 @@
+package chatlog
+
+import (
+	"fmt"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(vaultCmd)
+	vaultCmd.Flags().StringVarP(&vaultPlatform, "platform", "p", "", "platform")
+	vaultCmd.Flags().IntVarP(&vaultVer, "version", "v", 0, "version")
+	vaultCmd.Flags().StringVarP(&vaultDataDir, "data-dir", "d", "", "data dir")
+	vaultCmd.Flags().StringVarP(&vaultWorkDir, "work-dir", "w", "", "work dir")
+	vaultCmd.Flags().StringSliceVarP(&vaultTalkers, "talker", "t", nil, "talker ids or names, repeat or separate with commas")
+	vaultCmd.Flags().StringVar(&vaultDir, "dir", "", "vault dir, defaults to vault next to the work dir")
+	vaultCmd.Flags().BoolVar(&vaultGit, "git", false, "write text only and commit the vault to a git repository")
+	vaultCmd.MarkFlagRequired("talker")
+}
+
+var (
+	vaultPlatform string
+	vaultVer      int
+	vaultDataDir  string
+	vaultWorkDir  string
+	vaultTalkers  []string
+	vaultDir      string
+	vaultGit      bool
+)
+
+var vaultCmd = &cobra.Command{
+	Use:   "vault",
+	Short: "Append new messages to a Markdown vault, optionally committed to git",
+	Long: `Append the new messages of each talker to a Markdown vault (Obsidian,
+Logseq or any folder): one folder per talker and one note per day. The first
+run writes the whole history, later runs only append new messages. Images
+and files are copied next to the notes.
+
+With --git only text is written and the vault is committed to a git
+repository after each run (initialized when the dir is not a repository
+yet). Run it daily, e.g. from cron, to get a diffable history of the
+archive: every commit records the hash of the previous one, so any later
+edit to the history shows up in git log, git diff and git fsck.
+
+The same sync runs in the TUI and server as the scheduled vault job.`,
+	Example: `chatlog vault --talker 12345@chatroom --dir ~/Obsidian/WeChat
+chatlog vault --talker "Project Group",filehelper --dir ~/chat-archive --git`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		cmdConf := newCmdConf()
+		if len(vaultDataDir) != 0 {
+			cmdConf["data_dir"] = vaultDataDir
+		}
+		if len(vaultWorkDir) != 0 {
+			cmdConf["work_dir"] = vaultWorkDir
+		}
+		if len(vaultPlatform) != 0 {
+			cmdConf["platform"] = vaultPlatform
+		}
+		if vaultVer != 0 {
+			cmdConf["version"] = vaultVer
+		}
+
+		m := chatlog.New(cmd.Context())
+		result, commit, err := m.CommandVault("", cmdConf, vaultDir, vaultTalkers, vaultGit)
+		if err != nil {
+			printError(err, "failed to sync vault")
+			return
+		}
+
+		if jsonOutput() {
+			printJSON(map[string]any{"files": result.Files, "messages": result.Messages, "media": result.Media, "commit": commit})
+			return
+		}
+		fmt.Printf("wrote %d messages to %d notes, copied %d media files\n", result.Messages, result.Files, result.Media)
+		if len(commit) != 0 {
+			fmt.Printf("committed %s\n", commit)
+		}
+	},
+}
@@ -18,6 +18,10 @@ var versionCmd = &cobra.Command{
 	Use:   "version [-m]",
 	Short: "Show the version of chatlog",
 	Run: func(cmd *cobra.Command, args []string) {
+		if jsonOutput() {
+			printJSON(version.Info(versionM))
+			return
+		}
 		if versionM {
 			fmt.Println(version.GetMore(true))
 		} else {
@@ -0,0 +1,82 @@
+package chatlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog"
+	"github.com/DanielMao1/chatlog/internal/model"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+	watchCmd.Flags().StringVarP(&watchPlatform, "platform", "p", "", "platform")
+	watchCmd.Flags().IntVarP(&watchVer, "version", "v", 0, "version")
+	watchCmd.Flags().StringVarP(&watchDataDir, "data-dir", "d", "", "data dir")
+	watchCmd.Flags().StringVarP(&watchDataKey, "data-key", "k", "", "data key")
+	watchCmd.Flags().StringVarP(&watchWorkDir, "work-dir", "w", "", "work dir")
+	watchCmd.Flags().StringVarP(&watchTalker, "talker", "t", "", "talker, empty for all sessions")
+	watchCmd.Flags().StringVarP(&watchFormat, "format", "f", "text", "output format, text or json")
+}
+
+var (
+	watchPlatform string
+	watchVer      int
+	watchDataDir  string
+	watchDataKey  string
+	watchWorkDir  string
+	watchTalker   string
+	watchFormat   string
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Print new messages in real time",
+	Run: func(cmd *cobra.Command, args []string) {
+
+		cmdConf := getWatchConfig()
+
+		showChatRoom := watchTalker == "" || strings.Contains(watchTalker, ",")
+		asJSON := outputFormat(watchFormat) == OutputJSON
+
+		m := chatlog.New(cmd.Context())
+		err := m.CommandWatch("", cmdConf, watchTalker, func(msg *model.Message) {
+			if asJSON {
+				b, err := json.Marshal(msg)
+				if err != nil {
+					return
+				}
+				fmt.Println(string(b))
+				return
+			}
+			fmt.Println(msg.PlainText(showChatRoom, "2006-01-02 15:04:05", ""))
+		})
+		if err != nil {
+			printError(err, "failed to watch messages")
+			return
+		}
+	},
+}
+
+func getWatchConfig() map[string]any {
+	cmdConf := newCmdConf()
+	if len(watchDataDir) != 0 {
+		cmdConf["data_dir"] = watchDataDir
+	}
+	if len(watchDataKey) != 0 {
+		cmdConf["data_key"] = watchDataKey
+	}
+	if len(watchWorkDir) != 0 {
+		cmdConf["work_dir"] = watchWorkDir
+	}
+	if len(watchPlatform) != 0 {
+		cmdConf["platform"] = watchPlatform
+	}
+	if watchVer != 0 {
+		cmdConf["version"] = watchVer
+	}
+	return cmdConf
+}
@@ -2,11 +2,13 @@ package chatlog
 
 import (
 	"io"
+	"maps"
 	"os"
 	"path/filepath"
-	"time"
 
-	"github.com/DanielMao1/chatlog/pkg/util"
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+	"github.com/DanielMao1/chatlog/pkg/logbuf"
+	"github.com/DanielMao1/chatlog/pkg/logging"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -16,30 +18,101 @@ import (
 
 var Debug bool
 
-func initLog(cmd *cobra.Command, args []string) {
-	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+// LogLevel、LogFormat 与 LogFile 覆盖配置文件中的 log.level、log.format 与 log.file
+var LogLevel, LogFormat, LogFile string
+
+// logConfig 合并配置文件中的 log 配置与命令行参数，--debug 将默认级别改为 debug，模块的级别不变
+func logConfig(format string) (logging.Config, error) {
+	l := conf.LoadLog()
+	if len(LogLevel) != 0 {
+		l.Level = LogLevel
+	}
+	if len(LogFormat) != 0 {
+		l.Format = LogFormat
+	}
+	if len(LogFile) != 0 {
+		l.File = LogFile
+	}
+	if len(l.Format) == 0 {
+		l.Format = format
+	}
 
+	level, modules, err := logging.ParseLevels(l.Level)
+	if err != nil {
+		return logging.Config{}, err
+	}
 	if Debug {
-		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+		level = zerolog.DebugLevel.String()
+	}
+	// level 中的模块级别优先于 modules
+	if len(l.Modules) != 0 {
+		merged := maps.Clone(l.Modules)
+		maps.Copy(merged, modules)
+		modules = merged
+	}
+	return logging.Config{
+		Level:      level,
+		Modules:    modules,
+		Format:     l.Format,
+		File:       l.File,
+		MaxSize:    l.MaxSize,
+		MaxBackups: l.MaxBackups,
+	}, nil
+}
+
+func initLog(cmd *cobra.Command, args []string) {
+	format := logging.FormatConsole
+	if jsonOutput() {
+		format = logging.FormatJSON
 	}
+	setupLog(format)
+}
+
+// initPipelineLog 非交互模式下默认以 JSON 行的形式向 stderr 输出日志，便于脚本解析
+func initPipelineLog(cmd *cobra.Command, args []string) {
+	setupLog(logging.FormatJSON)
+}
 
-	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339})
+// setupLog 按配置初始化日志，配置无效时使用默认配置并提示
+func setupLog(format string) {
+	c, err := logConfig(format)
+	if err == nil {
+		err = logging.Setup(c, os.Stderr)
+	}
+	if err != nil {
+		logging.Setup(logging.Config{Format: format}, os.Stderr)
+		log.Warn().Err(err).Msg("invalid log config, using defaults")
+	}
 }
 
 func initTuiLog(cmd *cobra.Command, args []string) {
-	logOutput := io.Discard
-
-	debug, _ := cmd.Flags().GetBool("debug")
-	if debug {
-		logpath := "/Users/danielmao/chatlog/log"
-		util.PrepareDir(logpath)
-		logFD, err := os.OpenFile(filepath.Join(logpath, "chatlog.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, os.ModePerm)
-		if err != nil {
-			panic(err)
-		}
-		logOutput = logFD
+	c, cerr := logConfig(logging.FormatConsole)
+	// 日志写入内存缓冲区，在 TUI 的日志面板中按级别过滤显示，未指定 --log-level 时记录 debug 及以上的日志
+	if len(LogLevel) == 0 {
+		c.Level = zerolog.DebugLevel.String()
 	}
 
-	log.Logger = log.Output(zerolog.ConsoleWriter{Out: logOutput, NoColor: true, TimeFormat: time.RFC3339})
-	logrus.SetOutput(logOutput)
+	// --debug 或配置了日志文件时同时写入文件
+	var w io.Writer = logbuf.Default
+	var werr error
+	if Debug || len(c.File) != 0 {
+		if len(c.File) == 0 {
+			c.File = filepath.Join(conf.ConfigDir(), "logs", "chatlog.log")
+		}
+		var out io.Writer
+		if out, werr = logging.NewWriter(c, io.Discard); werr == nil {
+			w = zerolog.MultiLevelWriter(out, logbuf.Default)
+		}
+	}
+	if err := logging.SetupWriter(c, w); err != nil {
+		logging.SetupWriter(logging.Config{Level: zerolog.DebugLevel.String()}, w)
+	}
+	if cerr != nil {
+		log.Warn().Err(cerr).Msg("invalid log config, using defaults")
+	}
+	if werr != nil {
+		log.Warn().Err(werr).Msg("failed to open log file")
+	}
+	// 依赖库通过 logrus 输出的日志同样写入日志面板，不直接输出到终端
+	logrus.SetOutput(log.Logger)
 }
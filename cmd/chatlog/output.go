@@ -0,0 +1,63 @@
+package chatlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/DanielMao1/chatlog/internal/errors"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// Output 全局输出格式，为 json 时所有命令向 stdout 输出结构化结果
+var Output string
+
+func jsonOutput() bool {
+	return strings.ToLower(Output) == OutputJSON
+}
+
+// outputFormat 返回命令实际使用的输出格式，全局 --output json 优先于命令自身的 --format
+func outputFormat(format string) string {
+	if jsonOutput() {
+		return OutputJSON
+	}
+	return strings.ToLower(format)
+}
+
+// printJSON 以缩进的 JSON 格式输出到 stdout
+func printJSON(v any) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		log.Err(err).Msg("failed to marshal output")
+		return
+	}
+	fmt.Println(string(b))
+}
+
+// exitCode 命令结束时的退出码，由 printError 按错误类型设置
+var exitCode = errors.ExitOK
+
+// printError 输出命令执行失败的信息，并记录退出码
+// stderr 日志带有 code 和 exit_code 字段，json 模式下同时向 stdout 输出 {"error": "...", "code": "...", "exit_code": n}
+func printError(err error, msg string) {
+	code := errors.ExitCode(err)
+	if exitCode == errors.ExitOK {
+		exitCode = code
+	}
+	reason := errors.ReasonOf(err)
+	log.Err(err).Str("code", errors.ExitName(code)).Int("exit_code", code).Str("reason", reason).Msg(msg)
+	if jsonOutput() {
+		printJSON(map[string]any{
+			"error":     fmt.Sprintf("%s: %v", msg, err),
+			"code":      errors.ExitName(code),
+			"exit_code": code,
+			"reason":    reason,
+		})
+	}
+}
@@ -1,7 +1,15 @@
 package chatlog
 
 import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
 	"github.com/DanielMao1/chatlog/internal/chatlog"
+	"github.com/DanielMao1/chatlog/internal/errors"
+	"github.com/DanielMao1/chatlog/internal/wechat/key/scan"
+	"github.com/DanielMao1/chatlog/pkg/util"
 
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -12,30 +20,113 @@ func init() {
 	cobra.MousetrapHelpText = ""
 
 	rootCmd.PersistentFlags().BoolVar(&Debug, "debug", false, "debug")
+	rootCmd.PersistentFlags().StringVar(&LogLevel, "log-level", "", "log level, with optional levels per module such as info,wechat=debug, or set log.level in config")
+	rootCmd.PersistentFlags().StringVar(&LogFormat, "log-format", "", "log format, console or json, or set log.format in config")
+	rootCmd.PersistentFlags().StringVarP(&LogFile, "log-file", "l", "", "write log to this file, rotated by size, or set log.file in config")
+	rootCmd.PersistentFlags().StringVarP(&Output, "output", "o", OutputText, "output format, text or json")
+	rootCmd.PersistentFlags().StringVar(&Profile, "profile", "", "config profile in chatlog-server.json, or set CHATLOG_PROFILE")
+	rootCmd.PersistentFlags().StringVar(&Account, "account", "", "history account name or wxid in chatlog.json, or set CHATLOG_ACCOUNT")
+	rootCmd.PersistentFlags().IntVarP(&Jobs, "jobs", "j", 0, "number of parallel jobs for heavy work such as decryption, 0 for the number of CPUs (max 16), or set CHATLOG_JOBS")
+	rootCmd.PersistentFlags().IntVar(&KeyWorkers, "key-workers", 0, "number of workers searching process memory for keys, 0 for the number of CPUs (max 16), or set key_scan.workers in config")
+	rootCmd.PersistentFlags().IntVar(&KeyQueueSize, "key-queue-size", 0, "number of memory chunks waiting to be searched for keys, or set key_scan.queue_size in config")
+	rootCmd.PersistentFlags().IntVar(&KeyChunkSize, "key-chunk-size", 0, "minimum size in MB of the chunks large memory regions are split into when searching for keys (default 4), or set key_scan.chunk_size in config")
+	rootCmd.PersistentFlags().IntVar(&KeyOverlap, "key-overlap", 0, "number of bytes adjacent memory chunks and regions overlap when searching for keys (default 1024), or set key_scan.overlap in config")
 	rootCmd.PersistentPreRun = initLog
+	cobra.OnInitialize(initKeyScan)
 }
 
+// Profile 服务配置中使用的 profile 名称
+var Profile string
+
+// Account 使用的历史账号名称或微信 ID
+var Account string
+
+// Jobs 解密等耗时任务的并发数
+var Jobs int
+
+// KeyWorkers、KeyQueueSize、KeyChunkSize 与 KeyOverlap 搜索密钥时的并发数、等待搜索的内存块数量、内存块大小（MB）与重叠字节数
+var KeyWorkers, KeyQueueSize, KeyChunkSize, KeyOverlap int
+
+// initKeyScan 命令行参数中的密钥扫描参数覆盖配置
+func initKeyScan() {
+	scan.Override(scan.Options{Workers: KeyWorkers, QueueSize: KeyQueueSize, ChunkSize: KeyChunkSize * 1024 * 1024, Overlap: KeyOverlap})
+}
+
+// newCmdConf 创建命令行参数配置，并带上全局的 --profile、--account 和 --jobs
+func newCmdConf() map[string]any {
+	cmdConf := make(map[string]any)
+	if len(Profile) != 0 {
+		cmdConf["profile"] = Profile
+	}
+	if len(Account) != 0 {
+		cmdConf["account"] = Account
+	}
+	if Jobs != 0 {
+		cmdConf["jobs"] = Jobs
+	}
+	return cmdConf
+}
+
+// Execute 执行命令，失败时按错误类型以不同的退出码退出，见 errors.ExitCode
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		log.Err(err).Msg("command execution failed")
+	registerCompletions()
+	ctx, stop := interruptContext()
+	defer stop()
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
+		log.Err(err).Str("code", errors.ExitName(errors.ExitUsage)).Int("exit_code", errors.ExitUsage).Msg("command execution failed")
+		os.Exit(errors.ExitUsage)
+	}
+	if exitCode != errors.ExitOK {
+		os.Exit(exitCode)
 	}
 }
 
+// interruptContext 返回收到 Ctrl-C 或 SIGTERM 时取消的上下文，进行中的解密、查询、导出与媒体转换随之停止
+// 取消后恢复默认的信号处理，停止前再次 Ctrl-C 时立即退出
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		defer signal.Stop(ch)
+		select {
+		case sig := <-ch:
+			log.Warn().Str("signal", sig.String()).Msg("stopping, press Ctrl-C again to force quit")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
 var rootCmd = &cobra.Command{
-	Use:     "chatlog",
-	Short:   "chatlog",
-	Long:    `chatlog`,
-	Example: `chatlog`,
-	Args:    cobra.MinimumNArgs(0),
+	Use:   "chatlog",
+	Short: "chatlog",
+	Long:  `chatlog`,
+	Example: `chatlog
+chatlog --no-tui --export chat.csv --talker wxid_xxx
+chatlog --no-tui --serve --auto-decrypt`,
+	Args: cobra.MinimumNArgs(0),
 	CompletionOptions: cobra.CompletionOptions{
-		HiddenDefaultCmd: true,
+		DisableDefaultCmd: true,
 	},
-	PreRun: initTuiLog,
-	Run:    Root,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		// 标准输出不是终端时 TUI 会自动退化为无界面运行，日志同样写入 stderr
+		if noTUI || !util.IsTerminal(os.Stdout) {
+			initPipelineLog(cmd, args)
+			return
+		}
+		initTuiLog(cmd, args)
+	},
+	Run: Root,
 }
 
 func Root(cmd *cobra.Command, args []string) {
-	m := chatlog.New()
+	if noTUI {
+		runPipeline(cmd.Context())
+		return
+	}
+	m := chatlog.New(cmd.Context())
 	if err := m.Run(""); err != nil {
 		log.Err(err).Msg("failed to run chatlog instance")
 	}
@@ -0,0 +1,144 @@
+package chatlog
+
+import (
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/doctor"
+	iwechat "github.com/DanielMao1/chatlog/internal/wechat"
+)
+
+// addrProbeTimeout 检测 HTTP 地址是否被占用的超时时间
+const addrProbeTimeout = 200 * time.Millisecond
+
+// AccountStatus 账号的状态，用于切换账号时显示
+type AccountStatus struct {
+	Account string
+	// Instance 正在运行的微信进程，未运行时为 nil
+	Instance *iwechat.Account
+	// Saved 账号保存在历史账号中
+	Saved   bool
+	Current bool
+
+	DataDir string
+	WorkDir string
+
+	KeyCached     bool
+	KeyValid      bool
+	WorkDirExists bool
+	// LastDecrypt 工作目录中最近一次解密写入数据库的时间
+	LastDecrypt time.Time
+
+	HTTPAddr string
+	// HTTPBound HTTP 地址正在监听，可能是当前程序，也可能是为该账号提供服务的其他 chatlog 进程
+	HTTPBound bool
+}
+
+// AccountStatuses 返回微信进程与历史账号的状态，同一账号只返回一项，当前账号排在最前
+// 会读取数据库文件验证密钥，应在后台调用
+func (m *Manager) AccountStatuses() []*AccountStatus {
+	statuses := make(map[string]*AccountStatus)
+	get := func(account string) *AccountStatus {
+		if s, ok := statuses[account]; ok {
+			return s
+		}
+		s := &AccountStatus{Account: account}
+		statuses[account] = s
+		return s
+	}
+
+	keys := make(map[string]*iwechat.Account)
+	for account, hist := range m.ctx.History {
+		s := get(account)
+		s.Saved = true
+		keys[account] = &iwechat.Account{
+			Name:        hist.Account,
+			Platform:    hist.Platform,
+			Version:     hist.Version,
+			FullVersion: hist.FullVersion,
+			DataDir:     hist.DataDir,
+			Key:         hist.DataKey,
+			ImgKey:      hist.ImgKey,
+		}
+		s.DataDir, s.WorkDir, s.HTTPAddr = hist.DataDir, hist.WorkDir, hist.HTTPAddr
+	}
+	for _, instance := range m.wechat.GetWeChatInstances() {
+		s := get(instance.Name)
+		s.Instance = instance
+		if len(instance.DataDir) != 0 {
+			s.DataDir = instance.DataDir
+		}
+		if len(instance.Key) != 0 || keys[instance.Name] == nil {
+			keys[instance.Name] = instance
+		}
+	}
+
+	ret := make([]*AccountStatus, 0, len(statuses))
+	for account, s := range statuses {
+		s.Current = len(m.ctx.DataDir) != 0 && s.DataDir == m.ctx.DataDir
+		if s.Current {
+			s.WorkDir = m.ctx.WorkDir
+			s.HTTPAddr = m.ctx.HTTPAddr
+		}
+		if len(s.HTTPAddr) == 0 {
+			s.HTTPAddr = m.ctx.GetHTTPAddr()
+		}
+
+		if key := keys[account]; key != nil && len(key.Key) != 0 {
+			s.KeyCached = true
+			s.KeyValid = doctor.CheckKey(key.Platform, key.Version, s.DataDir, key.Key).Status == doctor.StatusOK
+		}
+		if len(s.WorkDir) != 0 {
+			if fi, err := os.Stat(s.WorkDir); err == nil && fi.IsDir() {
+				s.WorkDirExists = true
+				s.LastDecrypt = lastDecrypt(s.WorkDir)
+			}
+		}
+		s.HTTPBound = (s.Current && m.ctx.HTTPEnabled) || addrInUse(s.HTTPAddr)
+		ret = append(ret, s)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Current != ret[j].Current {
+			return ret[i].Current
+		}
+		if (ret[i].Instance != nil) != (ret[j].Instance != nil) {
+			return ret[i].Instance != nil
+		}
+		return ret[i].Account < ret[j].Account
+	})
+	return ret
+}
+
+// lastDecrypt 返回工作目录中数据库文件最近的修改时间
+func lastDecrypt(workDir string) time.Time {
+	var last time.Time
+	filepath.WalkDir(workDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(d.Name(), ".db") {
+			return nil
+		}
+		if fi, err := d.Info(); err == nil && fi.ModTime().After(last) {
+			last = fi.ModTime()
+		}
+		return nil
+	})
+	return last
+}
+
+// addrInUse 判断地址是否已有程序在监听
+func addrInUse(addr string) bool {
+	if len(addr) == 0 {
+		return false
+	}
+	conn, err := net.DialTimeout("tcp", addr, addrProbeTimeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
@@ -0,0 +1,102 @@
+package analytics
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	// Positive、Negative 情感分数超过该值的消息计为积极或消极
+	Positive = 0.2
+	Negative = -0.2
+)
+
+// Message 参与分析的一条文本消息
+type Message struct {
+	Time   time.Time
+	Sender string // 发送人名称
+	Text   string
+}
+
+// Month 聊天对象一个月的情感与话题
+type Month struct {
+	Month     string           `json:"month"`     // 格式为 2006-01
+	Messages  int              `json:"messages"`  // 文本消息数量
+	Scored    int              `json:"scored"`    // 含有情感词的消息数量
+	Sentiment float64          `json:"sentiment"` // 含有情感词的消息的平均情感分数，-1 ~ 1
+	Positive  int              `json:"positive"`  // 积极的消息数量
+	Negative  int              `json:"negative"`  // 消极的消息数量
+	Senders   map[string]*Tone `json:"senders"`   // 每个发送人的情感
+	Topics    []*Topic         `json:"topics"`
+}
+
+// Tone 一个发送人在一个月中的情感
+type Tone struct {
+	Messages  int     `json:"messages"`
+	Sentiment float64 `json:"sentiment"` // 含有情感词的消息的平均情感分数
+}
+
+// Analyze 按月统计一个聊天对象的消息的情感并聚类话题，结果按月份排列
+func Analyze(messages []*Message) []*Month {
+	months := make(map[string][]*Message)
+	for _, m := range messages {
+		key := m.Time.Format("2006-01")
+		months[key] = append(months[key], m)
+	}
+
+	// 关键词的文档频率按所有消息统计，每月突出当月特有的关键词
+	docs := make(map[*Message]*doc, len(messages))
+	df := make(map[string]int)
+	for _, m := range messages {
+		d := &doc{terms: make(map[string]bool)}
+		for _, t := range Tokens(m.Text) {
+			d.terms[t] = true
+		}
+		for t := range d.terms {
+			df[t]++
+		}
+		d.sentiment, d.scored = Score(m.Text)
+		docs[m] = d
+	}
+
+	ret := make([]*Month, 0, len(months))
+	for key, list := range months {
+		month := &Month{Month: key, Messages: len(list), Senders: make(map[string]*Tone)}
+		sum := 0.0
+		senderSum, senderScored := make(map[string]float64), make(map[string]int)
+		monthDocs := make([]*doc, 0, len(list))
+		for _, m := range list {
+			d := docs[m]
+			monthDocs = append(monthDocs, d)
+			tone, ok := month.Senders[m.Sender]
+			if !ok {
+				tone = &Tone{}
+				month.Senders[m.Sender] = tone
+			}
+			tone.Messages++
+			if !d.scored {
+				continue
+			}
+			month.Scored++
+			sum += d.sentiment
+			senderSum[m.Sender] += d.sentiment
+			senderScored[m.Sender]++
+			switch {
+			case d.sentiment >= Positive:
+				month.Positive++
+			case d.sentiment <= Negative:
+				month.Negative++
+			}
+		}
+		if month.Scored > 0 {
+			month.Sentiment = round(sum / float64(month.Scored))
+		}
+		for sender, n := range senderScored {
+			month.Senders[sender].Sentiment = round(senderSum[sender] / float64(n))
+		}
+		month.Topics = topics(monthDocs, df, len(messages))
+		ret = append(ret, month)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Month < ret[j].Month })
+	return ret
+}
@@ -0,0 +1,151 @@
+package analytics
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		text string
+		sign int
+	}{
+		{"好的，谢谢", 1},
+		{"非常满意[强]", 1},
+		{"这个价格太贵了，不能接受", -1},
+		{"不喜欢", -1},
+		{"不是很好", -1},
+		{"好贵啊", -1},
+		{"没问题", 1},
+		{"not bad at all", 1},
+		{"this is unacceptable 😡", -1},
+		{"好像明天要下雨", 0},
+		{"明天下午三点开会", 0},
+	}
+	for _, tt := range tests {
+		score, ok := Score(tt.text)
+		sign := 0
+		if score > 0 {
+			sign = 1
+		} else if score < 0 {
+			sign = -1
+		}
+		if sign != tt.sign || ok != (tt.sign != 0) || score < -1 || score > 1 {
+			t.Errorf("Score(%q) = %v, %v, want sign %d", tt.text, score, ok, tt.sign)
+		}
+	}
+}
+
+func TestTokens(t *testing.T) {
+	got := Tokens("合同的付款方式 https://example.com/a [微笑] the Contract payment ok")
+	want := []string{"合同", "付款", "款方", "方式", "contract", "payment"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokens = %q, want %q", got, want)
+	}
+}
+
+func TestWords(t *testing.T) {
+	texts := []string{
+		"人工智能的项目进度怎么样",
+		"项目进度延迟了，人工智能模型还在训练",
+		"项目进展顺利 https://example.com [微笑]",
+		"人工智能 model training, the model is ready",
+		"项目进展如何",
+		"项目呢",
+	}
+	got := Words(texts, 5)
+	want := []*Word{
+		{Text: "人工智能", Count: 3, Weight: 1},
+		{Text: "model", Count: 2, Weight: 0.667},
+		{Text: "项目进展", Count: 2, Weight: 0.667},
+		{Text: "项目进度", Count: 2, Weight: 0.667},
+		{Text: "ready", Count: 1, Weight: 0.333},
+	}
+	if !reflect.DeepEqual(got, want) {
+		for _, w := range got {
+			t.Logf("%+v", *w)
+		}
+		t.Errorf("Words returned unexpected result")
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	jan := time.Date(2024, 1, 10, 10, 0, 0, 0, time.Local)
+	feb := time.Date(2024, 2, 10, 10, 0, 0, 0, time.Local)
+	messages := []*Message{
+		{Time: jan, Sender: "A", Text: "合同价格太贵了"},
+		{Time: jan, Sender: "B", Text: "合同价格可以再谈"},
+		{Time: jan, Sender: "A", Text: "付款方式呢"},
+		{Time: jan, Sender: "B", Text: "付款方式按季度"},
+		{Time: feb, Sender: "A", Text: "合作愉快，谢谢"},
+		{Time: feb, Sender: "B", Text: "感谢支持"},
+		{Time: feb, Sender: "B", Text: "发票已经寄出"},
+	}
+	months := Analyze(messages)
+	if len(months) != 2 || months[0].Month != "2024-01" || months[1].Month != "2024-02" {
+		t.Fatalf("months = %+v", months)
+	}
+	m := months[0]
+	if m.Messages != 4 || m.Senders["A"].Messages != 2 || m.Sentiment >= months[1].Sentiment || months[1].Positive != 2 {
+		t.Errorf("sentiment = %+v, %+v", m, months[1])
+	}
+	if len(m.Topics) != 2 || m.Topics[0].Messages != 2 {
+		t.Fatalf("topics = %+v", m.Topics)
+	}
+	for _, topic := range m.Topics {
+		if len(topic.Keywords) < 2 {
+			t.Errorf("topic %v should group co-occurring keywords", topic.Keywords)
+		}
+	}
+	if len(months[1].Topics) != 0 {
+		t.Errorf("keywords mentioned once should not form topics: %+v", months[1].Topics[0])
+	}
+}
+
+// fakeSource 返回时间范围内的消息
+type fakeSource []*Message
+
+func (f fakeSource) Messages(ctx context.Context, talker string, start, end time.Time) ([]*Message, error) {
+	var ret []*Message
+	for _, m := range f {
+		if !m.Time.Before(start) && !m.Time.After(end) {
+			ret = append(ret, m)
+		}
+	}
+	return ret, nil
+}
+
+func TestService(t *testing.T) {
+	path := filepath.Join(t.TempDir(), StoreFile)
+	store, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	source := fakeSource{
+		{Time: now.AddDate(0, -2, 0), Sender: "A", Text: "谢谢"},
+		{Time: now, Sender: "A", Text: "失望"},
+	}
+	s := NewService(store, source)
+	if err := s.Update(context.Background(), "a"); err != nil {
+		t.Fatal(err)
+	}
+	if months := store.Months("a", now.AddDate(0, -1, 0), now); len(months) != 1 || months[0].Negative != 1 {
+		t.Errorf("Months = %+v", months)
+	}
+
+	// 重新打开后保留结果
+	store, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if months := store.Months("a", now.AddDate(-1, 0, 0), now); len(months) != 2 || store.Updated("a").IsZero() {
+		t.Errorf("reopened Months = %d", len(months))
+	}
+	if months := store.Months("b", now.AddDate(-1, 0, 0), now); months != nil {
+		t.Errorf("Months of unknown talker = %v", months)
+	}
+}
@@ -0,0 +1,77 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultInterval 默认更新分析结果的间隔，情感与话题按月统计，不需要频繁更新
+const DefaultInterval = 6 * time.Hour
+
+// Source 提供聊天对象的文本消息
+type Source interface {
+	// Messages 返回聊天对象在 [start, end] 内按时间排列的文本消息
+	Messages(ctx context.Context, talker string, start, end time.Time) ([]*Message, error)
+}
+
+// Service 定期分析聊天对象的消息并保存结果
+type Service struct {
+	store  *Store
+	source Source
+}
+
+// NewService 创建分析服务
+func NewService(store *Store, source Source) *Service {
+	return &Service{store: store, source: source}
+}
+
+// Store 返回保存分析结果的文件
+func (s *Service) Store() *Store {
+	return s.store
+}
+
+// Run 每隔 interval 更新聊天对象的分析结果，直到 ctx 结束
+func (s *Service) Run(ctx context.Context, talkers []string, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, talker := range talkers {
+			if ctx.Err() != nil {
+				return
+			}
+			if err := s.Update(ctx, talker); err != nil {
+				log.Debug().Err(err).Str("talker", talker).Msg("update analytics failed")
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Update 重新分析聊天对象上次分析时所在月份及之后的消息，首次分析时分析所有消息
+func (s *Service) Update(ctx context.Context, talker string) error {
+	start := time.Unix(0, 0)
+	if updated := s.store.Updated(talker); !updated.IsZero() {
+		start = updated
+	}
+	return s.Analyze(ctx, talker, start, time.Now())
+}
+
+// Analyze 分析聊天对象从 start 所在月份开始到 end 的消息并保存结果，每月的结果都包含整月的消息
+func (s *Service) Analyze(ctx context.Context, talker string, start, end time.Time) error {
+	start = time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, start.Location())
+	messages, err := s.source.Messages(ctx, talker, start, end)
+	if err != nil {
+		return fmt.Errorf("%s: %w", talker, err)
+	}
+	return s.store.Put(talker, Analyze(messages), time.Now())
+}
@@ -0,0 +1,156 @@
+package analytics
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// 情感词典，权重为正表示积极，为负表示消极，为 0 的词用于避免误匹配（如“好像”中的“好”）
+var lexicon = map[string]float64{
+	// 积极
+	"好": 1, "好的": 1, "很好": 1, "好啊": 1, "不错": 1, "挺好": 1, "太好了": 1.5, "棒": 1, "赞": 1, "优秀": 1, "完美": 1.5,
+	"喜欢": 1, "爱": 1, "满意": 1, "开心": 1, "高兴": 1, "愉快": 1, "快乐": 1, "哈哈": 0.8, "嘻嘻": 0.8,
+	"谢谢": 1, "感谢": 1, "多谢": 1, "辛苦了": 0.8, "同意": 1, "可以": 0.5, "没问题": 1, "行": 0.5, "好吧": 0.3, "认可": 1,
+	"接受": 0.8, "支持": 1, "成功": 1, "顺利": 1, "放心": 0.8, "期待": 0.8, "合作愉快": 1.5, "恭喜": 1, "优惠": 0.5, "划算": 0.8,
+	// 消极
+	"不行": -1, "不好": -1, "差": -1, "很差": -1.5, "失望": -1.5, "生气": -1.5, "讨厌": -1.5, "难过": -1, "伤心": -1, "烦": -1,
+	"问题": -0.5, "麻烦": -0.8, "拒绝": -1.5, "不满": -1.5, "投诉": -1.5, "糟糕": -1.5, "担心": -0.8, "抱歉": -0.5, "对不起": -0.5,
+	"遗憾": -1, "太贵": -1.5, "贵": -0.8, "无法": -0.8, "取消": -1, "延迟": -1, "拖": -0.8, "催": -0.5, "错": -0.8, "坑": -1.5,
+	"骗": -1.5, "垃圾": -1.5, "算了": -1, "过分": -1.5, "离谱": -1.5, "违约": -1.5, "赔偿": -1, "退款": -0.8, "不同意": -1.5,
+	"没法": -0.8, "不接受": -1.5, "不可能": -1.5, "崩溃": -1.5, "郁闷": -1, "无语": -1,
+	// 避免误匹配
+	"好像": 0, "好几": 0, "好多": 0, "只好": 0, "正好": 0, "刚好": 0, "行李": 0, "银行": 0, "进行": 0, "执行": 0, "行程": 0,
+	"差不多": 0, "出差": 0, "时差": 0, "可以吗": 0, "爱好": 0, "不错过": 0, "没问题吧": 0,
+
+	// 英文
+	"good": 1, "great": 1.5, "nice": 1, "excellent": 1.5, "perfect": 1.5, "awesome": 1.5, "love": 1, "like": 0.5,
+	"happy": 1, "glad": 1, "thanks": 1, "thank": 1, "agree": 1, "agreed": 1, "deal": 0.8, "ok": 0.5, "okay": 0.5,
+	"sure": 0.5, "yes": 0.5, "fine": 0.5, "cool": 0.8, "congrats": 1, "appreciate": 1, "pleased": 1, "lol": 0.8,
+	"bad": -1, "terrible": -1.5, "awful": -1.5, "hate": -1.5, "angry": -1.5, "sad": -1, "sorry": -0.5, "problem": -0.5,
+	"issue": -0.5, "expensive": -1, "cancel": -1, "cancelled": -1, "delay": -1, "delayed": -1, "reject": -1.5,
+	"rejected": -1.5, "disappointed": -1.5, "unfortunately": -1, "worse": -1, "worst": -1.5, "refund": -0.8,
+	"unacceptable": -1.5, "annoying": -1, "wrong": -0.8, "fail": -1, "failed": -1,
+}
+
+// emoticons 微信表情与常见 emoji 的情感
+var emoticons = map[string]float64{
+	"[微笑]": 0.5, "[呲牙]": 1, "[偷笑]": 0.8, "[愉快]": 1, "[强]": 1, "[OK]": 0.8, "[玫瑰]": 1, "[爱心]": 1, "[拥抱]": 1,
+	"[鼓掌]": 1, "[胜利]": 1, "[握手]": 1, "[抱拳]": 0.8, "[庆祝]": 1, "[耶]": 1, "[笑脸]": 1, "[破涕为笑]": 0.8,
+	"[流泪]": -1, "[大哭]": -1, "[发怒]": -1.5, "[弱]": -1, "[衰]": -1, "[难过]": -1, "[抓狂]": -1.5, "[撇嘴]": -0.8,
+	"[白眼]": -1, "[鄙视]": -1.5, "[委屈]": -1, "[心碎]": -1.5, "[叹气]": -0.8, "[捂脸]": -0.5, "[汗]": -0.5,
+	"😀": 1, "😃": 1, "😄": 1, "😁": 1, "😂": 0.8, "😊": 1, "😍": 1.5, "👍": 1, "❤️": 1, "🎉": 1, "🙏": 0.8,
+	"😢": -1, "😭": -1, "😡": -1.5, "😠": -1.5, "👎": -1, "💔": -1.5, "😞": -1, "😤": -1,
+}
+
+// negators 否定词，出现在情感词前时情感反转
+var negators = map[string]bool{
+	"不": true, "没": true, "没有": true, "别": true, "未": true, "无": true, "不太": true, "不是": true,
+	"not": true, "no": true, "never": true, "don't": true, "dont": true, "didn't": true, "isn't": true,
+	"won't": true, "can't": true, "cannot": true, "doesn't": true, "wasn't": true,
+}
+
+// intensifiers 程度副词，出现在情感词前时加强情感
+var intensifiers = map[string]bool{
+	"很": true, "非常": true, "太": true, "特别": true, "超": true, "真": true, "好": true, "十分": true, "极其": true, "最": true,
+	"very": true, "really": true, "so": true, "too": true, "extremely": true, "super": true,
+}
+
+// maxWordLen 词典中中文词的最大字数
+const maxWordLen = 4
+
+// Score 返回文本的情感分数，范围为 -1（消极）到 1（积极），没有情感词时返回 0 与 false
+func Score(text string) (float64, bool) {
+	sum, hits := 0.0, 0
+	add := func(w float64) {
+		sum += w
+		hits++
+	}
+
+	for e, w := range emoticons {
+		if n := strings.Count(text, e); n > 0 {
+			text = strings.ReplaceAll(text, e, " ")
+			for i := 0; i < n; i++ {
+				add(w)
+			}
+		}
+	}
+
+	// 中文按最长匹配查找情感词，英文按单词查找，前两个词中的否定词与程度副词影响情感
+	var prev []string
+	runes := []rune(strings.ToLower(text))
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || r == '\'') {
+			j := i
+			for j < len(runes) && runes[j] < unicode.MaxASCII && (unicode.IsLetter(runes[j]) || runes[j] == '\'') {
+				j++
+			}
+			word := string(runes[i:j])
+			if w, ok := lexicon[word]; ok && w != 0 {
+				add(modify(w, prev))
+			}
+			prev = append(prev, word)
+			i = j
+			continue
+		}
+		if !unicode.Is(unicode.Han, r) {
+			if !unicode.IsSpace(r) {
+				prev = nil
+			}
+			i++
+			continue
+		}
+		word := string(r)
+		for n := min(maxWordLen, len(runes)-i); n > 1; n-- {
+			if _, ok := lexicon[string(runes[i:i+n])]; ok {
+				word = string(runes[i : i+n])
+				break
+			}
+			if negators[string(runes[i:i+n])] || intensifiers[string(runes[i:i+n])] {
+				word = string(runes[i : i+n])
+				break
+			}
+		}
+		if w, ok := lexicon[word]; ok && w != 0 && !isModifier(word, runes, i+len([]rune(word))) {
+			add(modify(w, prev))
+		}
+		prev = append(prev, word)
+		i += len([]rune(word))
+	}
+
+	if hits == 0 {
+		return 0, false
+	}
+	score := sum / math.Sqrt(float64(hits)+1)
+	return math.Max(-1, math.Min(1, score)), true
+}
+
+// isModifier 判断情感词是否用作程度副词，如“好贵”中的“好”
+func isModifier(word string, runes []rune, next int) bool {
+	if !intensifiers[word] || next >= len(runes) {
+		return false
+	}
+	for n := min(maxWordLen, len(runes)-next); n > 0; n-- {
+		if w, ok := lexicon[string(runes[next:next+n])]; ok && w != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// modify 按前两个词中的否定词与程度副词调整情感权重
+func modify(w float64, prev []string) float64 {
+	if len(prev) > 2 {
+		prev = prev[len(prev)-2:]
+	}
+	for _, p := range prev {
+		switch {
+		case negators[p]:
+			w = -w * 0.8
+		case intensifiers[p]:
+			w *= 1.5
+		}
+	}
+	return w
+}
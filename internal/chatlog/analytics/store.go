@@ -0,0 +1,96 @@
+package analytics
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// StoreFile 工作目录中保存分析结果的文件
+const StoreFile = "analytics.json"
+
+// Store 保存每个聊天对象按月的分析结果
+type Store struct {
+	path string
+
+	mu      sync.Mutex
+	talkers map[string]*talkerResult
+}
+
+// talkerResult 一个聊天对象的分析结果
+type talkerResult struct {
+	Updated time.Time         `json:"updated"`
+	Months  map[string]*Month `json:"months"`
+}
+
+// Open 读取分析结果，文件不存在时返回空的结果
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, talkers: make(map[string]*talkerResult)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.talkers); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Months 返回聊天对象在 [start, end] 内各月的分析结果，按月份排列，没有结果时返回 nil
+func (s *Store) Months(talker string, start, end time.Time) []*Month {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.talkers[talker]
+	if !ok {
+		return nil
+	}
+	from, to := start.Format("2006-01"), end.Format("2006-01")
+	ret := make([]*Month, 0)
+	for key, m := range r.Months {
+		if key >= from && key <= to {
+			ret = append(ret, m)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Month < ret[j].Month })
+	return ret
+}
+
+// Updated 返回聊天对象上次分析的时间，没有分析过时返回零值
+func (s *Store) Updated(talker string) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.talkers[talker]; ok {
+		return r.Updated
+	}
+	return time.Time{}
+}
+
+// Put 保存聊天对象的分析结果，覆盖相同月份的结果并写入文件
+func (s *Store) Put(talker string, months []*Month, updated time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.talkers[talker]
+	if !ok {
+		r = &talkerResult{Months: make(map[string]*Month)}
+		s.talkers[talker] = r
+	}
+	for _, m := range months {
+		r.Months[m.Month] = m
+	}
+	r.Updated = updated
+
+	data, err := json.Marshal(s.talkers)
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,195 @@
+package analytics
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// TopicTerms 每月参与聚类的关键词数量
+	TopicTerms = 24
+
+	// MaxTopics、TopicKeywords 每月最多的话题数量与每个话题的关键词数量
+	MaxTopics     = 5
+	TopicKeywords = 5
+
+	// TopicMinCount 关键词在当月至少出现的消息数量
+	TopicMinCount = 2
+
+	// topicSimilarity 关键词与话题共同出现的比例达到该值时归入该话题
+	topicSimilarity = 0.25
+)
+
+// Topic 一个月中相关联的一组关键词
+type Topic struct {
+	Keywords  []string `json:"keywords"`  // 按重要程度排列
+	Messages  int      `json:"messages"`  // 提到任一关键词的消息数量
+	Sentiment float64  `json:"sentiment"` // 这些消息的平均情感分数
+}
+
+var (
+	urlRegex   = regexp.MustCompile(`https?://\S+`)
+	emojiRegex = regexp.MustCompile(`\[[^\[\]\s]{1,6}\]`)
+)
+
+// stopChars 不作为关键词的常见虚词、代词，中文关键词中含有这些字时忽略
+var stopChars = map[rune]bool{}
+
+// stopWords 不作为关键词的中英文常用词
+var stopWords = map[string]bool{}
+
+func init() {
+	for _, r := range "的了是我你他她它们在有和就都也还这那个吗呢吧啊么什怎样嗯哦哈呀嘛啦不没一二三到说要给把被让对从与及而或但很太好得着过地为以之其所" {
+		stopChars[r] = true
+	}
+	for _, w := range strings.Fields(`今天 明天 昨天 后天 上午 下午 晚上 早上 现在 刚才 已经 如果 因此 因为 然后 时候 知道 觉得 一下 可能 能够 应该 需要
+		看看 看到 想想 出来 起来 下来 回来 过来 过去 上去 下去 进去 出去 会儿 不会 不能 一起 自己 东西 事情`) {
+		stopWords[w] = true
+	}
+	for _, w := range strings.Fields(`the and for are but not you your yours all any can had has have her his him how its let may our out she that
+		this was were what when where which who why will with would about after again also been before being both could did does doing
+		each from further here into just more most other over own same should some such than then there these they those through under
+		until very while them their ours ok okay yes yeah lol haha thanks thank please https http www com`) {
+		stopWords[w] = true
+	}
+}
+
+// Tokens 返回文本中的候选关键词：中文为相邻两字，英文为三个字母以上的单词，忽略链接、表情与常用词
+func Tokens(text string) []string {
+	text = urlRegex.ReplaceAllString(text, " ")
+	text = emojiRegex.ReplaceAllString(text, " ")
+	runes := []rune(strings.ToLower(text))
+	var ret []string
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.Is(unicode.Han, r):
+			if i+1 < len(runes) && unicode.Is(unicode.Han, runes[i+1]) && !stopChars[r] && !stopChars[runes[i+1]] && !stopWords[string(runes[i:i+2])] {
+				ret = append(ret, string(runes[i:i+2]))
+			}
+			i++
+		case r < unicode.MaxASCII && unicode.IsLetter(r):
+			j := i
+			for j < len(runes) && runes[j] < unicode.MaxASCII && unicode.IsLetter(runes[j]) {
+				j++
+			}
+			if word := string(runes[i:j]); j-i >= 3 && !stopWords[word] {
+				ret = append(ret, word)
+			}
+			i = j
+		default:
+			i++
+		}
+	}
+	return ret
+}
+
+// doc 参与话题聚类的一条消息
+type doc struct {
+	terms     map[string]bool
+	sentiment float64
+	scored    bool
+}
+
+// topics 按关键词在消息中共同出现的情况聚类话题
+// df 为关键词在所有消息中出现的消息数，total 为所有消息数，用于计算 TF-IDF
+func topics(docs []*doc, df map[string]int, total int) []*Topic {
+	tf := make(map[string]int)
+	for _, d := range docs {
+		for t := range d.terms {
+			tf[t]++
+		}
+	}
+	type term struct {
+		text  string
+		score float64
+	}
+	terms := make([]term, 0, len(tf))
+	for t, n := range tf {
+		if n < TopicMinCount {
+			continue
+		}
+		terms = append(terms, term{t, float64(n) * math.Log(float64(total+1)/float64(df[t]))})
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].score != terms[j].score {
+			return terms[i].score > terms[j].score
+		}
+		return terms[i].text < terms[j].text
+	})
+	if len(terms) > TopicTerms {
+		terms = terms[:TopicTerms]
+	}
+
+	// 按重要程度依次归入共同出现比例最高的话题，都不满足时作为新话题
+	var clusters [][]string
+	for _, t := range terms {
+		best, bestSim := -1, topicSimilarity
+		for i, c := range clusters {
+			if len(c) >= TopicKeywords {
+				continue
+			}
+			if sim := cooccurrence(docs, t.text, c); sim >= bestSim {
+				best, bestSim = i, sim
+			}
+		}
+		if best >= 0 {
+			clusters[best] = append(clusters[best], t.text)
+		} else if len(clusters) < MaxTopics {
+			clusters = append(clusters, []string{t.text})
+		}
+	}
+
+	ret := make([]*Topic, 0, len(clusters))
+	for _, c := range clusters {
+		topic := &Topic{Keywords: c}
+		sum, scored := 0.0, 0
+		for _, d := range docs {
+			for _, k := range c {
+				if d.terms[k] {
+					topic.Messages++
+					if d.scored {
+						sum += d.sentiment
+						scored++
+					}
+					break
+				}
+			}
+		}
+		if scored > 0 {
+			topic.Sentiment = round(sum / float64(scored))
+		}
+		ret = append(ret, topic)
+	}
+	sort.SliceStable(ret, func(i, j int) bool { return ret[i].Messages > ret[j].Messages })
+	return ret
+}
+
+// cooccurrence 返回含有关键词的消息中同时含有话题中任一关键词的比例
+func cooccurrence(docs []*doc, term string, cluster []string) float64 {
+	with, both := 0, 0
+	for _, d := range docs {
+		if !d.terms[term] {
+			continue
+		}
+		with++
+		for _, k := range cluster {
+			if d.terms[k] {
+				both++
+				break
+			}
+		}
+	}
+	if with == 0 {
+		return 0
+	}
+	return float64(both) / float64(with)
+}
+
+// round 保留三位小数
+func round(f float64) float64 {
+	return math.Round(f*1000) / 1000
+}
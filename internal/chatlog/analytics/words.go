@@ -0,0 +1,142 @@
+package analytics
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// WordMaxLen 识别的中文词的最大字数
+	WordMaxLen = 4
+
+	// WordMinCount 三字以上的词至少出现的次数
+	WordMinCount = 2
+
+	// wordCoverage 长词的出现次数达到其前缀、后缀出现次数的该比例时作为一个词
+	wordCoverage = 0.8
+)
+
+// Word 词频统计中的一个词
+type Word struct {
+	Text   string  `json:"text"`
+	Count  int     `json:"count"`
+	Weight float64 `json:"weight"` // 出现次数与最多的词的比值，0 ~ 1，可直接用作词云的字号比例
+}
+
+// Words 统计文本中出现最多的 top 个词，忽略链接、表情与常用词
+// 中文不依赖词典：统计两到四字的片段，从长到短选出出现次数接近其前缀、后缀的片段作为词，并从其包含的短片段中扣除
+func Words(texts []string, top int) []*Word {
+	counts := make(map[string]int)
+	for _, text := range texts {
+		text = urlRegex.ReplaceAllString(text, " ")
+		text = emojiRegex.ReplaceAllString(text, " ")
+		runes := []rune(strings.ToLower(text))
+		for i := 0; i < len(runes); {
+			r := runes[i]
+			switch {
+			case unicode.Is(unicode.Han, r):
+				j := i
+				for j < len(runes) && unicode.Is(unicode.Han, runes[j]) {
+					j++
+				}
+				countGrams(counts, runes[i:j])
+				i = j
+			case r < unicode.MaxASCII && unicode.IsLetter(r):
+				j := i
+				for j < len(runes) && runes[j] < unicode.MaxASCII && unicode.IsLetter(runes[j]) {
+					j++
+				}
+				if word := string(runes[i:j]); j-i >= 3 && !stopWords[word] {
+					counts[word]++
+				}
+				i = j
+			default:
+				i++
+			}
+		}
+	}
+
+	words := make(map[string]int)
+	for n := WordMaxLen; n >= 2; n-- {
+		grams := make([]string, 0)
+		for g := range counts {
+			if len([]rune(g)) == n && isHan(g) {
+				grams = append(grams, g)
+			}
+		}
+		// 出现次数多的片段先选，避免被相互重叠的片段扣除
+		sort.Slice(grams, func(i, j int) bool {
+			if counts[grams[i]] != counts[grams[j]] {
+				return counts[grams[i]] > counts[grams[j]]
+			}
+			return grams[i] < grams[j]
+		})
+		for _, g := range grams {
+			c := counts[g]
+			if c <= 0 || stopWords[g] {
+				continue
+			}
+			runes := []rune(g)
+			if n > 2 {
+				prefix, suffix := counts[string(runes[:n-1])], counts[string(runes[1:])]
+				if c < WordMinCount || float64(c) < wordCoverage*float64(min(prefix, suffix)) {
+					continue
+				}
+			}
+			words[g] = c
+			for l := 2; l < n; l++ {
+				for i := 0; i+l <= n; i++ {
+					counts[string(runes[i:i+l])] -= c
+				}
+			}
+		}
+	}
+	for w, c := range counts {
+		if !isHan(w) {
+			words[w] = c
+		}
+	}
+
+	ret := make([]*Word, 0, len(words))
+	for w, c := range words {
+		if c > 0 {
+			ret = append(ret, &Word{Text: w, Count: c})
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Count != ret[j].Count {
+			return ret[i].Count > ret[j].Count
+		}
+		return ret[i].Text < ret[j].Text
+	})
+	if top > 0 && len(ret) > top {
+		ret = ret[:top]
+	}
+	for _, w := range ret {
+		w.Weight = round(float64(w.Count) / float64(ret[0].Count))
+	}
+	return ret
+}
+
+// countGrams 统计一段连续汉字中两到 WordMaxLen 字的片段，片段中不能含有常用虚词
+func countGrams(counts map[string]int, runes []rune) {
+	for i := range runes {
+		for n := 1; n <= WordMaxLen && i+n <= len(runes); n++ {
+			if stopChars[runes[i+n-1]] {
+				break
+			}
+			if n > 1 {
+				counts[string(runes[i:i+n])]++
+			}
+		}
+	}
+}
+
+// isHan 判断文本是否以汉字开头
+func isHan(s string) bool {
+	for _, r := range s {
+		return unicode.Is(unicode.Han, r)
+	}
+	return false
+}
@@ -0,0 +1,232 @@
+package anonymize
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Options 脱敏参数
+type Options struct {
+	Input     string // 工作目录或导出文件（.csv / .json / .txt）
+	Output    string // 输出路径，为空时在输入路径后追加 -anon
+	Salt      string // 生成化名的密钥，相同的 salt 对同一身份生成相同的化名，为空时随机生成
+	KeepMedia bool   // 保留媒体数据，默认清除语音、缩略图等二进制数据以及媒体链接和 CDN 地址
+}
+
+// Result 脱敏结果
+type Result struct {
+	Output     string `json:"output"`
+	Files      int    `json:"files"`
+	Identities int    `json:"identities"`
+}
+
+var (
+	// 未出现在联系人中的微信 ID、群聊 ID、公众号 ID 和手机号
+	idPattern    = `wxid_[a-zA-Z0-9_-]{4,}|\d{5,}@chatroom|gh_[0-9a-f]{12}`
+	phonePattern = `\+\d{8,15}\b|\b1[3-9]\d{9}\b`
+
+	phoneRe = regexp.MustCompile(`^(?:` + phonePattern + `)$`)
+
+	// 媒体消息 XML 中的下载地址、解密密钥和文件摘要
+	mediaAttrRe = regexp.MustCompile(`\b(aeskey|[a-z]*cdn[a-z]*url|[a-z]*md5)="[^"]*"`)
+	mediaElemRe = regexp.MustCompile(`<(aeskey|[a-z]*cdn[a-z]*url|[a-z]*md5)>[^<]*</`)
+	// 导出文件中指向 chatlog 服务的媒体链接
+	mediaLinkRe = regexp.MustCompile(`(!?\[[^\]]*\])\(http://[^/\s)]*/(?:image|video|voice|file)/[^)\s]*\)`)
+)
+
+// Anonymizer 将微信 ID、昵称、备注和手机号替换为一致的化名
+// 化名由 salt 与原始值的 HMAC 生成，不保存映射关系，无法通过枚举微信 ID 反推
+type Anonymizer struct {
+	salt       []byte
+	stripMedia bool
+
+	known map[string]string
+	// 消息表名中使用的会话 ID md5
+	md5s map[string]string
+
+	re *regexp.Regexp
+}
+
+// New 创建 Anonymizer，salt 为空时随机生成
+func New(salt string, stripMedia bool) *Anonymizer {
+	a := &Anonymizer{
+		salt:       []byte(salt),
+		stripMedia: stripMedia,
+		known:      make(map[string]string),
+		md5s:       make(map[string]string),
+	}
+	if len(a.salt) == 0 {
+		a.salt = make([]byte, 16)
+		rand.Read(a.salt)
+	}
+	return a
+}
+
+// AddID 记录一个微信 ID，之后出现在任意文本中都会被替换
+func (a *Anonymizer) AddID(id string) {
+	id = strings.TrimSpace(id)
+	if utf8.RuneCountInString(id) < 2 {
+		return
+	}
+	if _, ok := a.known[id]; ok {
+		return
+	}
+	a.known[id] = a.id(id)
+	sum := md5.Sum([]byte(id))
+	a.md5s[hex.EncodeToString(sum[:])] = id
+	a.re = nil
+}
+
+// AddName 记录一个昵称或备注，单字的名称容易误伤正文，不做替换
+func (a *Anonymizer) AddName(name string, chatRoom bool) {
+	name = strings.TrimSpace(name)
+	if utf8.RuneCountInString(name) < 2 {
+		return
+	}
+	if _, ok := a.known[name]; ok {
+		return
+	}
+	prefix := "User_"
+	if chatRoom {
+		prefix = "Group_"
+	}
+	a.known[name] = prefix + a.hash(name)[:6]
+	a.re = nil
+}
+
+// Identities 返回已记录的身份数量
+func (a *Anonymizer) Identities() int {
+	return len(a.known)
+}
+
+// Text 替换文本中的身份信息
+func (a *Anonymizer) Text(s string) string {
+	if len(s) == 0 {
+		return s
+	}
+	if a.stripMedia {
+		s = mediaAttrRe.ReplaceAllString(s, `$1=""`)
+		s = mediaElemRe.ReplaceAllString(s, `<$1></`)
+		s = mediaLinkRe.ReplaceAllString(s, `$1`)
+	}
+	// 单次扫描完成替换，避免化名被再次匹配
+	return a.regexp().ReplaceAllStringFunc(s, func(m string) string {
+		if v, ok := a.known[m]; ok {
+			return v
+		}
+		if phoneRe.MatchString(m) {
+			return a.phone(m)
+		}
+		return a.id(m)
+	})
+}
+
+// TableName 将以会话 ID md5 命名的消息表重命名为化名的 md5，保持表名与会话的对应关系
+func (a *Anonymizer) TableName(name string) string {
+	i := strings.LastIndexByte(name, '_')
+	if i < 0 {
+		return name
+	}
+	id, ok := a.md5s[strings.ToLower(name[i+1:])]
+	if !ok {
+		return name
+	}
+	sum := md5.Sum([]byte(a.known[id]))
+	return name[:i+1] + hex.EncodeToString(sum[:])
+}
+
+func (a *Anonymizer) regexp() *regexp.Regexp {
+	if a.re != nil {
+		return a.re
+	}
+	// 按长度倒序排列，保证优先匹配较长的名称
+	keys := make([]string, 0, len(a.known))
+	for k := range a.known {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	alts := make([]string, 0, len(keys)+2)
+	for _, k := range keys {
+		alts = append(alts, regexp.QuoteMeta(k))
+	}
+	alts = append(alts, idPattern, phonePattern)
+	a.re = regexp.MustCompile(strings.Join(alts, "|"))
+	return a.re
+}
+
+func (a *Anonymizer) id(id string) string {
+	switch {
+	case strings.HasSuffix(id, "@chatroom"):
+		return "anon" + a.hash(id)[:10] + "@chatroom"
+	case strings.HasPrefix(id, "gh_"):
+		return "gh_anon" + a.hash(id)[:8]
+	default:
+		return "wxid_anon" + a.hash(id)[:10]
+	}
+}
+
+func (a *Anonymizer) phone(p string) string {
+	h, _ := hex.DecodeString(a.hash(p)[:16])
+	return fmt.Sprintf("199%08d", binary.BigEndian.Uint64(h)%100000000)
+}
+
+func (a *Anonymizer) hash(s string) string {
+	mac := hmac.New(sha256.New, a.salt)
+	mac.Write([]byte(s))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Run 生成输入的脱敏副本，输入为目录时按工作目录处理，否则按导出文件处理
+func Run(opts Options) (*Result, error) {
+	if len(opts.Input) == 0 {
+		return nil, fmt.Errorf("input is required")
+	}
+	info, err := os.Stat(opts.Input)
+	if err != nil {
+		return nil, err
+	}
+
+	output := opts.Output
+	if len(output) == 0 {
+		input := strings.TrimRight(opts.Input, `/\`)
+		ext := ""
+		if !info.IsDir() {
+			ext = filepath.Ext(input)
+		}
+		output = strings.TrimSuffix(input, ext) + "-anon" + ext
+	}
+	if abs, _ := filepath.Abs(output); abs != "" {
+		if in, _ := filepath.Abs(opts.Input); in == abs {
+			return nil, fmt.Errorf("output must be different from input")
+		}
+	}
+
+	a := New(opts.Salt, !opts.KeepMedia)
+	ret := &Result{Output: output}
+	if info.IsDir() {
+		ret.Files, err = WorkDir(a, opts.Input, output)
+	} else {
+		ret.Files, err = File(a, opts.Input, output)
+	}
+	if err != nil {
+		return nil, err
+	}
+	ret.Identities = a.Identities()
+	return ret, nil
+}
@@ -0,0 +1,52 @@
+package anonymize
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestText(t *testing.T) {
+	a := New("salt", true)
+	a.AddID("alice_custom")
+	a.AddName("爱丽丝", false)
+
+	in := "alice_custom 爱丽丝 wxid_unknown1 13812345678 <img aeskey=\"k\"/>"
+	out := a.Text(in)
+	for _, s := range []string{"alice_custom", "爱丽丝", "wxid_unknown1", "13812345678", `aeskey="k"`} {
+		if strings.Contains(out, s) {
+			t.Errorf("%q not replaced: %s", s, out)
+		}
+	}
+	if out != a.Text(in) {
+		t.Error("pseudonyms are not consistent")
+	}
+	if New("salt", true).Text("wxid_unknown1") != a.Text("wxid_unknown1") {
+		t.Error("pseudonyms differ with the same salt")
+	}
+}
+
+func TestProtobuf(t *testing.T) {
+	a := New("salt", true)
+	a.AddID("wxid_alice")
+
+	inner := protowire.AppendBytes(protowire.AppendTag(nil, 1, protowire.BytesType), []byte("wxid_alice"))
+	b := protowire.AppendBytes(protowire.AppendTag(nil, 2, protowire.BytesType), inner)
+	b = protowire.AppendVarint(protowire.AppendTag(b, 3, protowire.VarintType), 42)
+
+	out, ok := a.blob(b, 0)
+	if !ok {
+		t.Fatal("failed to parse protobuf")
+	}
+	_, _, n := protowire.ConsumeTag(out)
+	nested, m := protowire.ConsumeBytes(out[n:])
+	if m < 0 {
+		t.Fatal("invalid outer message")
+	}
+	_, _, n = protowire.ConsumeTag(nested)
+	v, m := protowire.ConsumeBytes(nested[n:])
+	if m < 0 || string(v) != a.Text("wxid_alice") {
+		t.Errorf("nested field not replaced: %q", v)
+	}
+}
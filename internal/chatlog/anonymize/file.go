@@ -0,0 +1,215 @@
+package anonymize
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/DanielMao1/chatlog/pkg/util"
+)
+
+// 纯文本导出中每条消息的标题行：发送人(ID) [群名(群ID)] 时间
+var headerRe = regexp.MustCompile(`^(.*?)\(([^()\s]+)\) (?:\[(.*?)\(([^()\s]+)\)\] )?\d`)
+
+// 导出 JSON 中与媒体文件对应的字段
+var mediaKeys = map[string]bool{
+	"path":      true,
+	"thumbpath": true,
+	"md5":       true,
+	"rawmd5":    true,
+	"cdnurl":    true,
+	"voice":     true,
+	"mediaMsg":  true,
+}
+
+// File 脱敏导出文件，按扩展名选择 csv / json / 纯文本格式
+func File(a *Anonymizer, src, dst string) (int, error) {
+	if err := util.PrepareDir(filepath.Dir(dst)); err != nil {
+		return 0, err
+	}
+
+	var err error
+	switch strings.ToLower(filepath.Ext(src)) {
+	case ".csv":
+		err = csvFile(a, src, dst)
+	case ".json":
+		err = jsonFile(a, src, dst)
+	default:
+		err = textFile(a, src, dst)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+func csvFile(a *Anonymizer, src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	r := csv.NewReader(in)
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return err
+	}
+
+	if len(records) > 0 {
+		cols := make(map[string]int)
+		for i, name := range records[0] {
+			cols[strings.ToLower(name)] = i
+		}
+		get := func(record []string, name string) string {
+			if i, ok := cols[name]; ok && i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+		for _, record := range records[1:] {
+			talker := get(record, "talker")
+			a.AddID(talker)
+			a.AddID(get(record, "sender"))
+			a.AddName(get(record, "sendername"), false)
+			a.AddName(get(record, "talkername"), strings.HasSuffix(talker, "@chatroom"))
+		}
+		for _, record := range records[1:] {
+			for i := range record {
+				record[i] = a.Text(record[i])
+			}
+		}
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	w := csv.NewWriter(out)
+	if err := w.WriteAll(records); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+func jsonFile(a *Anonymizer, src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	var v any
+	dec := json.NewDecoder(in)
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+
+	collectJSON(a, v)
+	v = scrubJSON(a, v)
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// collectJSON 从导出的消息中收集发送人和聊天对象
+func collectJSON(a *Anonymizer, v any) {
+	switch v := v.(type) {
+	case []any:
+		for _, e := range v {
+			collectJSON(a, e)
+		}
+	case map[string]any:
+		talker, _ := v["talker"].(string)
+		a.AddID(talker)
+		if s, ok := v["sender"].(string); ok {
+			a.AddID(s)
+		}
+		if s, ok := v["senderName"].(string); ok {
+			a.AddName(s, false)
+		}
+		if s, ok := v["talkerName"].(string); ok {
+			a.AddName(s, strings.HasSuffix(talker, "@chatroom"))
+		}
+		for _, e := range v {
+			collectJSON(a, e)
+		}
+	}
+}
+
+func scrubJSON(a *Anonymizer, v any) any {
+	switch v := v.(type) {
+	case string:
+		return a.Text(v)
+	case []any:
+		for i := range v {
+			v[i] = scrubJSON(a, v[i])
+		}
+	case map[string]any:
+		for k, e := range v {
+			if a.stripMedia && mediaKeys[k] {
+				delete(v, k)
+				continue
+			}
+			v[k] = scrubJSON(a, e)
+		}
+	}
+	return v
+}
+
+func textFile(a *Anonymizer, src, dst string) error {
+	b, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(string(b), "\n")
+
+	for _, line := range lines {
+		m := headerRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		// 自己发送的消息以“我”代替 ID
+		if m[2] != "我" {
+			a.AddID(m[2])
+		}
+		a.AddName(m[1], false)
+		if len(m[4]) != 0 {
+			a.AddID(m[4])
+			a.AddName(m[3], true)
+		}
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	w := bufio.NewWriter(out)
+	for i, line := range lines {
+		if i > 0 {
+			w.WriteString("\n")
+		}
+		w.WriteString(a.Text(line))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return out.Close()
+}
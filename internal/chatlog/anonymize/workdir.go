@@ -0,0 +1,486 @@
+package anonymize
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/DanielMao1/chatlog/pkg/util"
+	"github.com/DanielMao1/chatlog/pkg/util/zstd"
+)
+
+const batchSize = 1000
+
+var (
+	// 记录微信 ID 的字段
+	idColumns = map[string]bool{
+		"username":         true,
+		"user_name":        true,
+		"usrname":          true,
+		"strtalker":        true,
+		"talker":           true,
+		"alias":            true,
+		"encryptusername":  true,
+		"encrypt_username": true,
+		"chatroomname":     true,
+		"chat_room_name":   true,
+		"m_nsusrname":      true,
+		"m_nsaliasname":    true,
+	}
+
+	// 记录昵称、备注的字段
+	nameColumnRe = regexp.MustCompile(`(?i)(nickname|nick_name|remark|display_?name|googlecontactname)$`)
+
+	// 拼音和头像字段直接清空，拼音可以还原出姓名
+	clearColumnRe = regexp.MustCompile(`(?i)(py|pin_?yin|quan_?pin|head.*(url|md5))`)
+
+	// 以会话 ID md5 命名的消息表
+	hashTableRe = regexp.MustCompile(`^(Msg|Chat)_[0-9a-fA-F]{32}$`)
+)
+
+// WorkDir 生成解密工作目录的脱敏副本
+// 先从所有数据库中收集联系人和群聊信息，再替换所有文本和 protobuf 字段中的身份信息
+func WorkDir(a *Anonymizer, src, dst string) (int, error) {
+	files := make([]string, 0)
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			// 全文索引包含消息原文且无法逐条替换，不复制
+			if info.Name() == "fts" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isTempFile(path) {
+			return nil
+		}
+		isDB := strings.HasSuffix(path, ".db")
+		if !isDB && a.stripMedia {
+			return nil
+		}
+		if err := copyFile(path, filepath.Join(dst, rel)); err != nil {
+			return err
+		}
+		if isDB {
+			files = append(files, filepath.Join(dst, rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(files) == 0 {
+		return 0, fmt.Errorf("no database found in %s", src)
+	}
+
+	for _, file := range files {
+		if err := walkDB(file, func(db *sql.DB, table string, cols []string) error {
+			return collectTable(a, db, table, cols)
+		}); err != nil {
+			return 0, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	for _, file := range files {
+		if err := walkDB(file, func(db *sql.DB, table string, cols []string) error {
+			return scrubTable(a, db, table, cols)
+		}); err != nil {
+			return 0, fmt.Errorf("%s: %w", file, err)
+		}
+		if err := vacuum(file); err != nil {
+			return 0, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	return len(files), nil
+}
+
+func walkDB(path string, fn func(db *sql.DB, table string, cols []string) error) error {
+	db, err := openDB(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tables, err := listTables(db)
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		cols, err := listColumns(db, table)
+		if err != nil {
+			return err
+		}
+		if err := fn(db, table, cols); err != nil {
+			return fmt.Errorf("%s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// collectTable 收集表中的微信 ID 与昵称，同一行中 ID 为群聊时昵称视为群名
+func collectTable(a *Anonymizer, db *sql.DB, table string, cols []string) error {
+	ids := make([]int, 0)
+	names := make([]int, 0)
+	for i, c := range cols {
+		switch {
+		case idColumns[strings.ToLower(c)]:
+			ids = append(ids, i)
+		case nameColumnRe.MatchString(c):
+			names = append(names, i)
+		}
+	}
+	if len(ids) == 0 && len(names) == 0 {
+		return nil
+	}
+
+	rows, err := db.Query(fmt.Sprintf("SELECT %s FROM %s", columnList(cols), quote(table)))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	values := make([]any, len(cols))
+	ptrs := make([]any, len(cols))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		chatRoom := false
+		for _, i := range ids {
+			id := toString(values[i])
+			chatRoom = chatRoom || strings.HasSuffix(id, "@chatroom")
+			a.AddID(id)
+		}
+		for _, i := range names {
+			a.AddName(toString(values[i]), chatRoom)
+		}
+	}
+	return rows.Err()
+}
+
+// scrubTable 替换表中所有文本和二进制字段，并按化名重命名消息表
+func scrubTable(a *Anonymizer, db *sql.DB, table string, cols []string) error {
+	if hashTableRe.MatchString(table) {
+		if name := a.TableName(table); name != table {
+			if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s RENAME TO %s", quote(table), quote(name))); err != nil {
+				return err
+			}
+			table = name
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	sets := make([]string, len(cols))
+	for i, c := range cols {
+		sets[i] = quote(c) + " = ?"
+	}
+	update, err := tx.Prepare(fmt.Sprintf("UPDATE %s SET %s WHERE rowid = ?", quote(table), strings.Join(sets, ", ")))
+	if err != nil {
+		return err
+	}
+	defer update.Close()
+
+	var last int64
+	for {
+		rowids, rows, err := readBatch(tx, table, cols, last)
+		if err != nil {
+			// WITHOUT ROWID 表，通常为索引类的小表，直接清空
+			if strings.Contains(err.Error(), "no such column: rowid") {
+				log.Warn().Str("table", table).Msg("table without rowid is cleared")
+				if _, err := tx.Exec(fmt.Sprintf("DELETE FROM %s", quote(table))); err != nil {
+					return err
+				}
+				return tx.Commit()
+			}
+			return err
+		}
+		for i, values := range rows {
+			changed := false
+			for j, v := range values {
+				nv := a.value(cols[j], v)
+				if !equal(nv, v) {
+					values[j] = nv
+					changed = true
+				}
+			}
+			if !changed {
+				continue
+			}
+			if _, err := update.Exec(append(values, rowids[i])...); err != nil {
+				return err
+			}
+		}
+		if len(rowids) < batchSize {
+			break
+		}
+		last = rowids[len(rowids)-1]
+	}
+	return tx.Commit()
+}
+
+func readBatch(tx *sql.Tx, table string, cols []string, after int64) ([]int64, [][]any, error) {
+	rows, err := tx.Query(fmt.Sprintf("SELECT rowid, %s FROM %s WHERE rowid > ? ORDER BY rowid LIMIT %d", columnList(cols), quote(table), batchSize), after)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	rowids := make([]int64, 0, batchSize)
+	ret := make([][]any, 0, batchSize)
+	for rows.Next() {
+		var rowid int64
+		values := make([]any, len(cols))
+		ptrs := make([]any, len(cols)+1)
+		ptrs[0] = &rowid
+		for i := range values {
+			ptrs[i+1] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, nil, err
+		}
+		rowids = append(rowids, rowid)
+		ret = append(ret, values)
+	}
+	return rowids, ret, rows.Err()
+}
+
+// value 返回字段脱敏后的值
+func (a *Anonymizer) value(col string, v any) any {
+	switch v := v.(type) {
+	case string:
+		if clearColumnRe.MatchString(col) {
+			return ""
+		}
+		return a.Text(v)
+	case []byte:
+		if len(v) == 0 {
+			return v
+		}
+		if clearColumnRe.MatchString(col) {
+			return []byte{}
+		}
+		// 4.0 的消息内容可能为 zstd 压缩，脱敏后以明文保存，读取时两种格式均支持
+		if bytes.HasPrefix(v, []byte{0x28, 0xb5, 0x2f, 0xfd}) {
+			if b, err := zstd.Decompress(v); err == nil {
+				return []byte(a.Text(string(b)))
+			}
+		}
+		if b, ok := a.blob(v, 0); ok {
+			return b
+		}
+		// 无法解析的二进制数据，通常为语音、缩略图等媒体
+		if a.stripMedia {
+			return []byte{}
+		}
+	}
+	return v
+}
+
+// blob 脱敏 protobuf 或文本格式的二进制数据，两者都无法解析时返回 false
+// 嵌套消息也可能是合法的 UTF-8，以长度分隔字段的 tag 开头且能完整解析时优先按 protobuf 处理，避免改变嵌套消息的长度
+func (a *Anonymizer) blob(v []byte, depth int) ([]byte, bool) {
+	if len(v) == 0 {
+		return v, true
+	}
+	if b, ok := a.protobuf(v, depth); ok && (!isText(v) || protowire.Type(v[0]&7) == protowire.BytesType) {
+		return b, true
+	}
+	if utf8.Valid(v) {
+		return []byte(a.Text(string(v))), true
+	}
+	return nil, false
+}
+
+// protobuf 按 protobuf 编码遍历二进制数据，替换其中的字符串字段
+func (a *Anonymizer) protobuf(b []byte, depth int) ([]byte, bool) {
+	if depth > 8 {
+		return nil, false
+	}
+	ret := make([]byte, 0, len(b))
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 || num == 0 {
+			return nil, false
+		}
+		b = b[n:]
+		ret = protowire.AppendTag(ret, num, typ)
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return nil, false
+			}
+			ret = append(ret, b[:n]...)
+			b = b[n:]
+			continue
+		}
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return nil, false
+		}
+		b = b[n:]
+		if nv, ok := a.blob(v, depth+1); ok {
+			v = nv
+		}
+		ret = protowire.AppendBytes(ret, v)
+	}
+	return ret, true
+}
+
+// vacuum 清理被替换的旧数据所在的空闲页
+func vacuum(path string) error {
+	db, err := openDB(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec("VACUUM")
+	return err
+}
+
+func listTables(db *sql.DB) ([]string, error) {
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type='table' AND sql NOT LIKE 'CREATE VIRTUAL%' AND name NOT LIKE 'sqlite_%'")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ret := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		ret = append(ret, name)
+	}
+	return ret, rows.Err()
+}
+
+func listColumns(db *sql.DB, table string) ([]string, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT name FROM pragma_table_info(%s)", quoteString(table)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ret := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		ret = append(ret, name)
+	}
+	return ret, rows.Err()
+}
+
+func openDB(path string) (*sql.DB, error) {
+	// 压缩的工作目录中的数据库需要先解压才能写入，下次解密时重新压缩
+	if err := zstd.InflateFile(path); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	// 事务中的读写需要使用同一连接
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+func columnList(cols []string) string {
+	quoted := make([]string, len(cols))
+	for i, c := range cols {
+		quoted[i] = quote(c)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func quote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func quoteString(s string) string {
+	return `'` + strings.ReplaceAll(s, `'`, `''`) + `'`
+}
+
+// isText 判断是否为不含控制字符的 UTF-8 文本
+func isText(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, c := range b {
+		if c < 0x20 && c != '\t' && c != '\n' && c != '\r' {
+			return false
+		}
+	}
+	return true
+}
+
+func toString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return ""
+}
+
+func equal(a, b any) bool {
+	switch a := a.(type) {
+	case string:
+		b, ok := b.(string)
+		return ok && a == b
+	case []byte:
+		b, ok := b.([]byte)
+		return ok && bytes.Equal(a, b)
+	}
+	return true
+}
+
+func isTempFile(path string) bool {
+	return strings.HasSuffix(path, "-wal") || strings.HasSuffix(path, "-shm") || strings.HasSuffix(path, "-journal")
+}
+
+func copyFile(src, dst string) error {
+	if err := util.PrepareDir(filepath.Dir(dst)); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
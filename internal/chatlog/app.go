@@ -1,25 +1,55 @@
 package chatlog
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"net/http"
+	"os"
 	"path/filepath"
 	"runtime"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
 	"github.com/DanielMao1/chatlog/internal/chatlog/ctx"
+	"github.com/DanielMao1/chatlog/internal/chatlog/summarize"
+	"github.com/DanielMao1/chatlog/internal/chatlog/update"
+	"github.com/DanielMao1/chatlog/internal/errors"
+	"github.com/DanielMao1/chatlog/internal/i18n"
+	"github.com/DanielMao1/chatlog/internal/model"
+	"github.com/DanielMao1/chatlog/internal/ui/chat"
 	"github.com/DanielMao1/chatlog/internal/ui/footer"
 	"github.com/DanielMao1/chatlog/internal/ui/form"
 	"github.com/DanielMao1/chatlog/internal/ui/help"
 	"github.com/DanielMao1/chatlog/internal/ui/infobar"
+	"github.com/DanielMao1/chatlog/internal/ui/keymap"
+	"github.com/DanielMao1/chatlog/internal/ui/logview"
 	"github.com/DanielMao1/chatlog/internal/ui/menu"
-	"github.com/DanielMao1/chatlog/internal/wechat"
+	"github.com/DanielMao1/chatlog/internal/ui/picker"
+	"github.com/DanielMao1/chatlog/internal/ui/progressbar"
+	"github.com/DanielMao1/chatlog/internal/ui/statusbar"
+	"github.com/DanielMao1/chatlog/internal/ui/style"
+	"github.com/DanielMao1/chatlog/pkg/logbuf"
+	"github.com/DanielMao1/chatlog/pkg/progress"
+	"github.com/DanielMao1/chatlog/pkg/termimg"
+	"github.com/DanielMao1/chatlog/pkg/util"
+	"github.com/DanielMao1/chatlog/pkg/version"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
+	"github.com/rs/zerolog/log"
 )
 
 const (
 	RefreshInterval = 1000 * time.Millisecond
+
+	// WorkUsageInterval 重新计算工作目录大小的间隔
+	WorkUsageInterval = time.Minute
 )
 
 type App struct {
@@ -31,9 +61,22 @@ type App struct {
 
 	// page
 	mainPages *tview.Pages
+	layout    *tview.Flex
 	infoBar   *infobar.InfoBar
 	tabPages  *tview.Pages
+	logView   *logview.LogView
 	footer    *footer.Footer
+	statusBar *statusbar.StatusBar
+	showLog   bool
+
+	// usageAt 最近一次计算工作目录大小的时间
+	usageAt time.Time
+
+	// bell 下次绘制时终端响铃
+	bell atomic.Bool
+
+	// browser 打开的消息浏览页面，退出时保存其状态
+	browser *chat.Browser
 
 	// tab
 	menu      *menu.Menu
@@ -43,6 +86,23 @@ type App struct {
 }
 
 func NewApp(ctx *ctx.Context, m *Manager) *App {
+	// 配色需在创建界面组件之前应用
+	theme, palette, err := style.Load(ctx.GetTheme(), ctx.GetColors())
+	if err != nil {
+		log.Warn().Err(err).Msg("load theme failed, use default theme")
+		theme, palette = style.ThemeDark, style.Themes[style.ThemeDark]
+	}
+	log.Debug().Str("theme", theme).Msg("apply theme")
+	style.Apply(palette)
+	if err := keymap.Load(ctx.GetKeys()); err != nil {
+		log.Warn().Err(err).Msg("load key bindings failed, use default keys")
+	}
+	// 界面语言同样需在创建界面组件之前设置
+	if err := i18n.Set(ctx.GetLanguage()); err != nil {
+		log.Warn().Err(err).Msg("set language failed, use detected language")
+	}
+	log.Debug().Str("language", i18n.Lang()).Msg("apply language")
+
 	app := &App{
 		ctx:         ctx,
 		m:           m,
@@ -50,8 +110,10 @@ func NewApp(ctx *ctx.Context, m *Manager) *App {
 		mainPages:   tview.NewPages(),
 		infoBar:     infobar.New(),
 		tabPages:    tview.NewPages(),
+		logView:     logview.New(logbuf.Default),
 		footer:      footer.New(),
-		menu:        menu.New("主菜单"),
+		statusBar:   statusbar.New(),
+		menu:        menu.New(i18n.T("主菜单")),
 		help:        help.New(),
 	}
 
@@ -64,13 +126,20 @@ func NewApp(ctx *ctx.Context, m *Manager) *App {
 
 func (a *App) Run() error {
 
-	flex := tview.NewFlex().
+	a.layout = tview.NewFlex().
 		SetDirection(tview.FlexRow).
 		AddItem(a.infoBar, infobar.InfoBarViewHeight, 0, false).
 		AddItem(a.tabPages, 0, 1, true).
+		AddItem(a.logView, 0, 0, false).
 		AddItem(a.footer, 1, 1, false)
 
-	a.mainPages.AddPage("main", flex, true, true)
+	a.mainPages.AddPage("main", a.layout, true, true)
+
+	// 状态栏显示在所有页面的底部
+	root := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(a.mainPages, 0, 1, true).
+		AddItem(a.statusBar, statusbar.Height, 0, false)
 
 	a.tabPages.
 		AddPage("0", a.menu, true, true).
@@ -78,14 +147,76 @@ func (a *App) Run() error {
 	a.tabCount = 2
 
 	a.SetInputCapture(a.inputCapture)
+	a.SetMouseCapture(a.mouseCapture)
+	a.SetBeforeDrawFunc(func(screen tcell.Screen) bool {
+		if a.bell.Swap(false) {
+			screen.Beep()
+		}
+		return false
+	})
+
+	// 首次运行且尚未获取密钥时显示引导，否则恢复上次退出时打开的消息浏览页面
+	if !a.ctx.GetTourDone() && len(a.ctx.DataKey) == 0 {
+		a.showTour(0)
+	} else {
+		a.restoreState()
+	}
+	defer a.saveState()
 
 	go a.refresh()
+	go a.checkUpdate()
+
+	return a.run(root)
+}
 
-	if err := a.SetRoot(a.mainPages, true).EnableMouse(false).Run(); err != nil {
-		return err
+// run 运行界面，界面代码 panic 时 tview 会先恢复终端，这里将 panic 转为错误返回，由调用方退回无界面运行
+func (a *App) run(root tview.Primitive) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Newf(nil, http.StatusInternalServerError, "terminal UI panic: %v", r)
+			log.Err(err).Msgf("PANIC RECOVERED\n%s", string(debug.Stack()))
+		}
+	}()
+	return a.SetRoot(root, true).EnableMouse(!a.ctx.GetNoMouse()).Run()
+}
+
+// restoreState 同一账号已解密时，恢复上次退出时打开的会话、筛选条件与滚动位置
+func (a *App) restoreState() {
+	s := LoadUIState(a.ctx.GetConfigDir())
+	if s == nil || s.Browser == nil || s.Account != a.ctx.Account || len(a.ctx.WorkDir) == 0 {
+		return
+	}
+	a.openBrowser(func() {
+		a.mainPages.SwitchToPage("main")
+	}).Restore(s.Browser)
+}
+
+// saveState 退出时保存当前账号与消息浏览页面的状态
+func (a *App) saveState() {
+	s := &UIState{Account: a.ctx.Account}
+	if a.browser != nil {
+		s.Browser = a.browser.State()
+	}
+	if err := SaveUIState(a.ctx.GetConfigDir(), s); err != nil {
+		log.Debug().Err(err).Msg("save tui state failed")
 	}
+}
 
-	return nil
+// checkUpdate 在后台检查新版本，有新版本时在底栏提示
+func (a *App) checkUpdate() {
+	c, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	latest, err := update.Check(c, a.ctx.GetConfigDir(), version.Version)
+	if err != nil {
+		log.Debug().Err(err).Msg("check update failed")
+		return
+	}
+	if len(latest) == 0 {
+		return
+	}
+	a.QueueUpdateDraw(func() {
+		a.footer.SetUpdate(latest)
+	})
 }
 
 func (a *App) Stop() {
@@ -102,22 +233,22 @@ func (a *App) updateMenuItemsState() {
 		// 更新自动解密菜单项
 		if item.Index == 5 {
 			if a.ctx.AutoDecrypt {
-				item.Name = "停止自动解密"
-				item.Description = "停止监控数据目录更新，不再自动解密新增数据"
+				item.Name = i18n.T("停止自动解密")
+				item.Description = i18n.T("停止监控数据目录更新，不再自动解密新增数据")
 			} else {
-				item.Name = "开启自动解密"
-				item.Description = "监控数据目录更新，自动解密新增数据"
+				item.Name = i18n.T("开启自动解密")
+				item.Description = i18n.T("监控数据目录更新，自动解密新增数据")
 			}
 		}
 
 		// 更新HTTP服务菜单项
 		if item.Index == 4 {
 			if a.ctx.HTTPEnabled {
-				item.Name = "停止 HTTP 服务"
-				item.Description = "停止本地 HTTP & MCP 服务器"
+				item.Name = i18n.T("停止 HTTP 服务")
+				item.Description = i18n.T("停止本地 HTTP & MCP 服务器")
 			} else {
-				item.Name = "启动 HTTP 服务"
-				item.Description = "启动本地 HTTP & MCP 服务器"
+				item.Name = i18n.T("启动 HTTP 服务")
+				item.Description = i18n.T("启动本地 HTTP & MCP 服务器")
 			}
 		}
 	}
@@ -156,22 +287,102 @@ func (a *App) refresh() {
 				a.infoBar.UpdateSession(a.ctx.LastSession.Format("2006-01-02 15:04:05"))
 			}
 			if a.ctx.HTTPEnabled {
-				a.infoBar.UpdateHTTPServer(fmt.Sprintf("[green][已启动][white] [%s]", a.ctx.HTTPAddr))
+				a.infoBar.UpdateHTTPServer(i18n.Tf("%s[已启动][-] [%s]", style.Tag(style.SuccessColor), a.ctx.HTTPAddr))
 			} else {
-				a.infoBar.UpdateHTTPServer("[未启动]")
+				a.infoBar.UpdateHTTPServer(i18n.T("[未启动]"))
 			}
 			if a.ctx.AutoDecrypt {
-				a.infoBar.UpdateAutoDecrypt("[green][已开启][white]")
+				a.infoBar.UpdateAutoDecrypt(style.Tag(style.SuccessColor) + i18n.T("[已开启][-]"))
 			} else {
-				a.infoBar.UpdateAutoDecrypt("[未开启]")
+				a.infoBar.UpdateAutoDecrypt(i18n.T("[未开启]"))
 			}
+			a.updateStatusBar()
+			if a.ctx.AutoDecrypt {
+				a.notify()
+			}
+			a.QueueUpdate(func() {
+				if a.showLog {
+					a.logView.Refresh(false)
+				}
+			})
 
 			a.Draw()
 		}
 	}
 }
 
+// notify 自动解密发现关注的聊天对象的新消息时响铃、发送桌面通知、Telegram 通知或推送
+func (a *App) notify() {
+	for _, n := range a.m.CheckNotify() {
+		if n.Rule.Bell {
+			a.bell.Store(true)
+		}
+		if n.Rule.Desktop {
+			go func(n *Notification) {
+				if err := util.DesktopNotify(n.Title, n.Snippet); err != nil {
+					log.Debug().Err(err).Msg("send desktop notification failed")
+				}
+			}(n)
+		}
+		if n.Rule.Telegram || len(n.Rule.Destination) != 0 {
+			go a.m.deliverNotify(n)
+		}
+	}
+}
+
+// updateStatusBar 更新状态栏，工作目录大小每隔 WorkUsageInterval 在后台重新计算
+func (a *App) updateStatusBar() {
+	now := time.Now()
+	if len(a.ctx.WorkDir) != 0 && now.Sub(a.usageAt) >= WorkUsageInterval {
+		a.usageAt = now
+		go a.ctx.RefreshWorkUsage()
+	}
+	status := statusbar.Status{
+		Account:     a.ctx.Account,
+		Version:     a.ctx.FullVersion,
+		WorkUsage:   a.ctx.WorkUsage,
+		AutoDecrypt: a.ctx.AutoDecrypt,
+		LastDecrypt: a.m.LastAutoDecrypt(),
+		LastSession: a.ctx.LastSession,
+		HTTPEnabled: a.ctx.HTTPEnabled,
+		HTTPAddr:    a.ctx.GetHTTPAddr(),
+		Requests:    a.m.HTTPRequestsPerMinute(),
+	}
+	a.QueueUpdate(func() {
+		a.statusBar.Update(status, now)
+	})
+}
+
+// mouseCapture 弹出对话框时忽略对话框外的鼠标事件，避免点击到下层的菜单
+func (a *App) mouseCapture(event *tcell.EventMouse, action tview.MouseAction) (*tcell.EventMouse, tview.MouseAction) {
+	name, front := a.mainPages.GetFrontPage()
+	if front == nil || name == "main" {
+		return event, action
+	}
+	x, y := event.Position()
+	rx, ry, width, height := front.GetRect()
+	if x < rx || x >= rx+width || y < ry || y >= ry+height {
+		return nil, action
+	}
+	return event, action
+}
+
 func (a *App) inputCapture(event *tcell.EventKey) *tcell.EventKey {
+	typing := a.typing()
+	event = keymap.Translate(event, typing)
+	// 输入文字时字符按键不触发快捷操作
+	shortcut := func(action string) bool {
+		return !(typing && event.Key() == tcell.KeyRune) && keymap.Match(event, action)
+	}
+
+	// 按键说明窗口自行处理关闭的按键
+	if name, _ := a.mainPages.GetFrontPage(); name == help.KeysTitle {
+		if shortcut(keymap.Quit) {
+			a.Stop()
+			return nil
+		}
+		return event
+	}
 
 	// 如果当前页面不是主页面，ESC 键返回主页面
 	if a.mainPages.HasPage("submenu") && event.Key() == tcell.KeyEscape {
@@ -191,74 +402,152 @@ func (a *App) inputCapture(event *tcell.EventKey) *tcell.EventKey {
 		}
 	}
 
-	switch event.Key() {
-	case tcell.KeyCtrlC:
+	switch {
+	case shortcut(keymap.Quit):
 		a.Stop()
+		return nil
+	case shortcut(keymap.Log):
+		a.toggleLog()
+		return nil
+	case a.showLog && shortcut(keymap.LogLevel):
+		a.logView.NextLevel()
+		return nil
+	case shortcut(keymap.Help):
+		if screen := a.screen(); len(screen) != 0 {
+			a.showKeys(screen)
+			return nil
+		}
+	case shortcut(keymap.CopyDataKey):
+		a.copyKey(i18n.T("数据密钥"), a.ctx.DataKey)
+		return nil
+	case shortcut(keymap.CopyImgKey):
+		a.copyKey(i18n.T("图片密钥"), a.ctx.ImgKey)
+		return nil
+	case event.Key() == tcell.KeyCtrlC:
+		// 退出键已自定义，不交给 tview 退出
+		return nil
 	}
 
 	return event
 }
 
-func (a *App) initMenu() {
+// screen 返回最上层页面对应的按键说明界面，对话框与图片预览返回空
+func (a *App) screen() string {
+	name, front := a.mainPages.GetFrontPage()
+	switch name {
+	case "main":
+		if a.activeTab == 1 {
+			return help.ScreenHelp
+		}
+		return help.ScreenMenu
+	case picker.Title:
+		return help.ScreenPicker
+	case chat.Title:
+		return help.ScreenBrowser
+	case chat.SearchTitle:
+		return help.ScreenSearch
+	}
+	switch front.(type) {
+	case *menu.SubMenu:
+		return help.ScreenSubmenu
+	case *form.Form:
+		return help.ScreenForm
+	}
+	return ""
+}
 
-	summarizeFileHelper := &menu.Item{
-		Index:       1,
-		Name:        "总结文件传输助手",
-		Description: "总结过去一天内容并推送到服务器",
-		Selected: func(i *menu.Item) {
-			modal := tview.NewModal().SetText("正在总结文件传输助手...")
-			a.mainPages.AddPage("modal", modal, true, true)
-			a.SetFocus(modal)
+// showKeys 显示当前界面可用的按键，关闭后恢复之前的焦点
+func (a *App) showKeys(screen string) {
+	prev := a.GetFocus()
+	keys := help.NewKeys(help.Hints(screen), func() {
+		a.mainPages.RemovePage(help.KeysTitle)
+		a.SetFocus(prev)
+	})
+	a.mainPages.AddPage(help.KeysTitle, keys, true, true)
+	a.SetFocus(keys)
+}
 
-			go func() {
-				summary, err := a.m.SummarizeFileHelper()
+// showTour 显示首次运行引导的第 step 步，完成或跳过后不再显示
+func (a *App) showTour(step int) {
+	steps := help.Tour()
+	last := step == len(steps)-1
+	buttons := []string{i18n.T("下一步"), i18n.T("跳过")}
+	if last {
+		buttons = []string{i18n.T("开始使用")}
+	}
+	text := fmt.Sprintf("%s%s[-]\n\n%s\n\n%s%d/%d[-]",
+		style.Tag(style.PageHeaderFgColor), steps[step].Title, steps[step].Text,
+		style.Tag(style.MutedColor), step+1, len(steps))
+	a.showModal(text, buttons, func(buttonIndex int, buttonLabel string) {
+		a.mainPages.RemovePage("modal")
+		if buttonIndex == 0 && !last {
+			a.showTour(step + 1)
+			return
+		}
+		if err := a.ctx.SetTourDone(); err != nil {
+			log.Debug().Err(err).Msg("save tour state failed")
+		}
+	})
+}
 
-				a.QueueUpdateDraw(func() {
-					if err != nil {
-						modal.SetText("推送失败: " + err.Error())
-					} else {
-						display := summary
-						if len(display) > 200 {
-							display = display[:200] + "..."
-						}
-						modal.SetText("推送成功\n\n" + display)
-					}
+// typing 当前焦点是否在输入框中
+func (a *App) typing() bool {
+	switch a.GetFocus().(type) {
+	case *tview.InputField, *tview.TextArea:
+		return true
+	}
+	return false
+}
 
-					modal.AddButtons([]string{"OK"})
-					modal.SetDoneFunc(func(buttonIndex int, buttonLabel string) {
-						a.mainPages.RemovePage("modal")
-					})
-					a.SetFocus(modal)
-				})
-			}()
+// toggleLog 展开或收起日志面板
+func (a *App) toggleLog() {
+	a.showLog = !a.showLog
+	if a.showLog {
+		a.logView.Refresh(true)
+		a.layout.ResizeItem(a.logView, logview.Height, 0)
+		return
+	}
+	a.layout.ResizeItem(a.logView, 0, 0)
+}
+
+func (a *App) initMenu() {
+
+	summarizeTalker := &menu.Item{
+		Index:       1,
+		Name:        i18n.T("总结聊天记录"),
+		Description: i18n.T("选择联系人或群聊，总结过去一天内容并推送到 summarize.url"),
+		Selected: func(i *menu.Item) {
+			a.pickTalker(i18n.T("选择要总结的联系人或群聊"), a.summarizeTalker)
 		},
 	}
 
 	getDataKey := &menu.Item{
 		Index:       2,
-		Name:        "获取密钥",
-		Description: "从进程获取数据密钥 & 图片密钥",
+		Name:        i18n.T("获取密钥"),
+		Description: i18n.T("从进程获取数据密钥 & 图片密钥"),
 		Selected: func(i *menu.Item) {
 			modal := tview.NewModal()
+			title := i18n.T("获取密钥中...")
 			if runtime.GOOS == "darwin" {
-				modal.SetText("获取密钥中...\n预计需要 20 秒左右的时间，期间微信会卡住，请耐心等待")
-			} else {
-				modal.SetText("获取密钥中...")
+				title = i18n.T("获取密钥中...\n预计需要 20 秒左右的时间，期间微信会卡住，请耐心等待")
 			}
+			modal.SetText(title)
 			a.mainPages.AddPage("modal", modal, true, true)
 			a.SetFocus(modal)
 
+			stop := a.watchProgress(modal, title)
 			go func() {
 				err := a.m.GetDataKey()
+				stop()
 
 				// 在主线程中更新UI
 				a.QueueUpdateDraw(func() {
 					if err != nil {
 						// 解密失败
-						modal.SetText("获取密钥失败: " + err.Error())
+						modal.SetText(i18n.T("获取密钥失败: ") + err.Error())
 					} else {
 						// 解密成功
-						modal.SetText("获取密钥成功")
+						modal.SetText(i18n.T("获取密钥成功"))
 					}
 
 					// 添加确认按钮
@@ -274,53 +563,42 @@ func (a *App) initMenu() {
 
 	decryptData := &menu.Item{
 		Index:       3,
-		Name:        "解密数据",
-		Description: "解密数据文件",
+		Name:        i18n.T("解密数据"),
+		Description: i18n.T("解密数据文件"),
 		Selected: func(i *menu.Item) {
-			// 创建一个没有按钮的模态框，显示"解密中..."
+			// 工作目录中已有数据时选择只解密有变化的数据库还是全部重新解密
+			if !dirHasFiles(a.ctx.WorkDir) {
+				a.decryptData(false)
+				return
+			}
 			modal := tview.NewModal().
-				SetText("解密中...")
-
-			a.mainPages.AddPage("modal", modal, true, true)
-			a.SetFocus(modal)
-
-			// 在后台执行解密操作
-			go func() {
-				// 执行解密
-				err := a.m.DecryptDBFiles()
-
-				// 在主线程中更新UI
-				a.QueueUpdateDraw(func() {
-					if err != nil {
-						// 解密失败
-						modal.SetText("解密失败: " + err.Error())
-					} else {
-						// 解密成功
-						modal.SetText("解密数据成功")
+				SetText(i18n.Tf("工作目录中已有解密数据\n%s\n\n解密更新：只解密有变化的数据库，已有数据保持不变\n\n全部重新解密：覆盖工作目录中的所有数据库，耗时较长，期间浏览与 HTTP 服务可能读取到不完整的数据", a.ctx.WorkDir)).
+				AddButtons([]string{i18n.T("解密更新"), i18n.T("全部重新解密"), i18n.T("取消")}).
+				SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+					a.mainPages.RemovePage("modal")
+					switch buttonIndex {
+					case 0:
+						a.decryptData(false)
+					case 1:
+						a.decryptData(true)
 					}
-
-					// 添加确认按钮
-					modal.AddButtons([]string{"OK"})
-					modal.SetDoneFunc(func(buttonIndex int, buttonLabel string) {
-						a.mainPages.RemovePage("modal")
-					})
-					a.SetFocus(modal)
 				})
-			}()
+			a.mainPages.AddPage("modal", modal, true, true)
+			a.SetFocus(modal)
 		},
 	}
 
 	httpServer := &menu.Item{
 		Index:       4,
-		Name:        "启动 HTTP 服务",
-		Description: "启动本地 HTTP & MCP 服务器",
+		Name:        i18n.T("启动 HTTP 服务"),
+		Description: i18n.T("启动本地 HTTP & MCP 服务器"),
 		Selected: func(i *menu.Item) {
 			modal := tview.NewModal()
 
 			// 根据当前服务状态执行不同操作
 			if !a.ctx.HTTPEnabled {
 				// HTTP 服务未启动，启动服务
-				modal.SetText("正在启动 HTTP 服务...")
+				modal.SetText(i18n.T("正在启动 HTTP 服务..."))
 				a.mainPages.AddPage("modal", modal, true, true)
 				a.SetFocus(modal)
 
@@ -332,10 +610,10 @@ func (a *App) initMenu() {
 					a.QueueUpdateDraw(func() {
 						if err != nil {
 							// 启动失败
-							modal.SetText("启动 HTTP 服务失败: " + err.Error())
+							modal.SetText(i18n.T("启动 HTTP 服务失败: ") + err.Error())
 						} else {
 							// 启动成功
-							modal.SetText("已启动 HTTP 服务")
+							modal.SetText(i18n.T("已启动 HTTP 服务"))
 						}
 
 						// 更改菜单项名称
@@ -351,7 +629,7 @@ func (a *App) initMenu() {
 				}()
 			} else {
 				// HTTP 服务已启动，停止服务
-				modal.SetText("正在停止 HTTP 服务...")
+				modal.SetText(i18n.T("正在停止 HTTP 服务..."))
 				a.mainPages.AddPage("modal", modal, true, true)
 				a.SetFocus(modal)
 
@@ -363,10 +641,10 @@ func (a *App) initMenu() {
 					a.QueueUpdateDraw(func() {
 						if err != nil {
 							// 停止失败
-							modal.SetText("停止 HTTP 服务失败: " + err.Error())
+							modal.SetText(i18n.T("停止 HTTP 服务失败: ") + err.Error())
 						} else {
 							// 停止成功
-							modal.SetText("已停止 HTTP 服务")
+							modal.SetText(i18n.T("已停止 HTTP 服务"))
 						}
 
 						// 更改菜单项名称
@@ -386,15 +664,15 @@ func (a *App) initMenu() {
 
 	autoDecrypt := &menu.Item{
 		Index:       5,
-		Name:        "开启自动解密",
-		Description: "自动解密新增的数据文件",
+		Name:        i18n.T("开启自动解密"),
+		Description: i18n.T("自动解密新增的数据文件"),
 		Selected: func(i *menu.Item) {
 			modal := tview.NewModal()
 
 			// 根据当前自动解密状态执行不同操作
 			if !a.ctx.AutoDecrypt {
 				// 自动解密未开启，开启自动解密
-				modal.SetText("正在开启自动解密...")
+				modal.SetText(i18n.T("正在开启自动解密..."))
 				a.mainPages.AddPage("modal", modal, true, true)
 				a.SetFocus(modal)
 
@@ -406,13 +684,13 @@ func (a *App) initMenu() {
 					a.QueueUpdateDraw(func() {
 						if err != nil {
 							// 开启失败
-							modal.SetText("开启自动解密失败: " + err.Error())
+							modal.SetText(i18n.T("开启自动解密失败: ") + err.Error())
 						} else {
 							// 开启成功
 							if a.ctx.Version == 3 {
-								modal.SetText("已开启自动解密\n3.x版本数据文件更新不及时，有低延迟需求请使用4.0版本")
+								modal.SetText(i18n.T("已开启自动解密\n3.x版本数据文件更新不及时，有低延迟需求请使用4.0版本"))
 							} else {
-								modal.SetText("已开启自动解密")
+								modal.SetText(i18n.T("已开启自动解密"))
 							}
 						}
 
@@ -429,7 +707,7 @@ func (a *App) initMenu() {
 				}()
 			} else {
 				// 自动解密已开启，停止自动解密
-				modal.SetText("正在停止自动解密...")
+				modal.SetText(i18n.T("正在停止自动解密..."))
 				a.mainPages.AddPage("modal", modal, true, true)
 				a.SetFocus(modal)
 
@@ -441,10 +719,10 @@ func (a *App) initMenu() {
 					a.QueueUpdateDraw(func() {
 						if err != nil {
 							// 停止失败
-							modal.SetText("停止自动解密失败: " + err.Error())
+							modal.SetText(i18n.T("停止自动解密失败: ") + err.Error())
 						} else {
 							// 停止成功
-							modal.SetText("已停止自动解密")
+							modal.SetText(i18n.T("已停止自动解密"))
 						}
 
 						// 更改菜单项名称
@@ -464,19 +742,19 @@ func (a *App) initMenu() {
 
 	setting := &menu.Item{
 		Index:       6,
-		Name:        "设置",
-		Description: "设置应用程序选项",
+		Name:        i18n.T("设置"),
+		Description: i18n.T("设置应用程序选项"),
 		Selected:    a.settingSelected,
 	}
 
 	selectAccount := &menu.Item{
 		Index:       7,
-		Name:        "切换账号",
-		Description: "切换当前操作的账号，可以选择进程或历史账号",
+		Name:        i18n.T("切换账号"),
+		Description: i18n.T("切换当前操作的账号，可以选择进程或历史账号"),
 		Selected:    a.selectAccountSelected,
 	}
 
-	a.menu.AddItem(summarizeFileHelper)
+	a.menu.AddItem(summarizeTalker)
 	a.menu.AddItem(getDataKey)
 	a.menu.AddItem(decryptData)
 	a.menu.AddItem(httpServer)
@@ -486,14 +764,380 @@ func (a *App) initMenu() {
 
 	a.menu.AddItem(&menu.Item{
 		Index:       8,
-		Name:        "退出",
-		Description: "退出程序",
+		Name:        i18n.T("浏览聊天记录"),
+		Description: i18n.T("选择会话，按时间顺序阅读消息"),
+		Selected:    a.browseSelected,
+	})
+
+	a.menu.AddItem(&menu.Item{
+		Index:       9,
+		Name:        i18n.T("搜索聊天记录"),
+		Description: i18n.T("在所有会话中搜索消息，打开所在会话查看上下文"),
+		Selected:    a.searchSelected,
+	})
+
+	a.menu.AddItem(&menu.Item{
+		Index:       10,
+		Name:        i18n.T("导出聊天记录"),
+		Description: i18n.T("选择会话、时间范围与格式，导出消息到文件"),
+		Selected:    a.exportSelected,
+	})
+
+	a.menu.AddItem(&menu.Item{
+		Index:       11,
+		Name:        i18n.T("退出"),
+		Description: i18n.T("退出程序"),
 		Selected: func(i *menu.Item) {
 			a.Stop()
 		},
 	})
 }
 
+// pickTalker 显示联系人与群聊选择列表，选中后关闭列表并调用 selected
+func (a *App) pickTalker(title string, selected func(item *picker.Item)) {
+	p := picker.New(title, func(item *picker.Item) {
+		a.mainPages.RemovePage(picker.Title)
+		selected(item)
+	}, func() {
+		a.mainPages.RemovePage(picker.Title)
+	})
+	a.mainPages.AddPage(picker.Title, p, true, true)
+	a.SetFocus(p)
+
+	go func() {
+		contacts, chatRooms, err := a.m.BrowseTalkers()
+		a.QueueUpdateDraw(func() {
+			if err != nil {
+				p.SetError(err)
+				return
+			}
+			p.SetItems(picker.PinFirst(talkerItems(contacts, chatRooms), a.m.Pinned()))
+		})
+	}()
+}
+
+// talkerItems 按好友、群聊、其他联系人的顺序生成选择项
+func talkerItems(contacts []*model.Contact, chatRooms []*model.ChatRoom) []*picker.Item {
+	items := make([]*picker.Item, 0, len(contacts)+len(chatRooms))
+	others := make([]*picker.Item, 0)
+	rooms := make(map[string]bool, len(chatRooms))
+	for _, c := range chatRooms {
+		rooms[c.Name] = true
+	}
+	for _, c := range contacts {
+		if rooms[c.UserName] {
+			continue
+		}
+		if c.IsFriend {
+			items = append(items, picker.ContactItem(c))
+		} else {
+			others = append(others, picker.ContactItem(c))
+		}
+	}
+	for _, c := range chatRooms {
+		items = append(items, picker.ChatRoomItem(c))
+	}
+	return append(items, others...)
+}
+
+// summarizeTalker 总结选中的联系人或群聊过去一天的消息并推送
+func (a *App) summarizeTalker(item *picker.Item) {
+	modal := tview.NewModal().SetText(i18n.Tf("正在总结 %s...", item.Name))
+	a.mainPages.AddPage("modal", modal, true, true)
+	a.SetFocus(modal)
+
+	go func() {
+		payload, err := a.m.Summarize(item.UserName, 24*time.Hour, summarize.ToWebhook)
+
+		a.QueueUpdateDraw(func() {
+			if err != nil {
+				modal.SetText(i18n.T("推送失败: ") + err.Error())
+			} else {
+				display := payload.Summary
+				if r := []rune(display); len(r) > 200 {
+					display = string(r[:200]) + "..."
+				}
+				modal.SetText(i18n.T("推送成功\n\n") + display)
+			}
+
+			modal.AddButtons([]string{"OK"})
+			modal.SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+				a.mainPages.RemovePage("modal")
+			})
+			a.SetFocus(modal)
+		})
+	}()
+}
+
+// browseSelected 打开消息浏览页面
+func (a *App) browseSelected(i *menu.Item) {
+	if len(a.ctx.WorkDir) == 0 {
+		a.showError(i18n.Errorf("请先执行解密数据"))
+		return
+	}
+	a.openBrowser(func() {
+		a.mainPages.SwitchToPage("main")
+	})
+}
+
+// openBrowser 打开消息浏览页面，按 ESC 关闭页面后调用 back
+func (a *App) openBrowser(back func()) *chat.Browser {
+	browser := chat.New(a.m, func(f func()) { a.QueueUpdateDraw(f) }, func(p tview.Primitive) { a.SetFocus(p) }, func() {
+		a.mainPages.RemovePage(chat.Title)
+		a.browser = nil
+		back()
+	})
+	a.browser = browser
+	browser.SetPickFunc(func(open func(talker, name string)) {
+		a.pickTalker(i18n.T("选择联系人或群聊"), func(item *picker.Item) {
+			a.SetFocus(browser)
+			open(item.UserName, item.Name)
+		})
+	})
+	browser.SetPreviewFunc(a.previewImage)
+	browser.SetCopyFunc(a.copyMessage)
+	browser.SetPathFunc(a.m.MediaPath)
+	browser.SetMentionFunc(a.m.MentionNames)
+	browser.SetPinFunc(a.m.Pinned, a.m.Pin)
+	a.mainPages.AddPage(chat.Title, browser, true, true)
+	a.SetFocus(browser)
+	browser.LoadSessions()
+	return browser
+}
+
+// searchSelected 打开全文搜索页面，选中结果后在消息浏览中打开所在会话并定位到该消息
+func (a *App) searchSelected(i *menu.Item) {
+	if len(a.ctx.WorkDir) == 0 {
+		a.showError(i18n.Errorf("请先执行解密数据"))
+		return
+	}
+	var search *chat.Search
+	search = chat.NewSearch(a.m, func(f func()) { a.QueueUpdateDraw(f) }, func(p tview.Primitive) { a.SetFocus(p) }, func(msg *model.Message) {
+		browser := a.openBrowser(func() {
+			a.SetFocus(search)
+		})
+		browser.OpenMessage(msg)
+	}, func() {
+		a.mainPages.RemovePage(chat.SearchTitle)
+		a.mainPages.SwitchToPage("main")
+	})
+	a.mainPages.AddPage(chat.SearchTitle, search, true, true)
+	a.SetFocus(search)
+}
+
+// copyKey 复制数据密钥或图片密钥到剪贴板
+func (a *App) copyKey(name, key string) {
+	if len(key) == 0 {
+		a.showError(i18n.Errorf("%s未设置，请先获取密钥", name))
+		return
+	}
+	a.copyText(name, key)
+}
+
+// copyMessage 复制消息的文本，path 为 true 时复制图片、视频或文件消息的文件路径
+func (a *App) copyMessage(msg *model.Message, path bool) {
+	if !path {
+		a.copyText(i18n.T("消息"), chat.Content(msg))
+		return
+	}
+	go func() {
+		p, err := a.m.MediaPath(msg)
+		a.QueueUpdateDraw(func() {
+			if err != nil {
+				a.showError(i18n.Errorf("无法获取文件路径: %v", err))
+				return
+			}
+			a.copyText(i18n.T("文件路径"), p)
+		})
+	}()
+}
+
+// copyText 复制文本到系统剪贴板，在状态栏提示复制结果
+func (a *App) copyText(name, text string) {
+	if err := util.CopyToClipboard(text); err != nil {
+		a.showError(i18n.Errorf("复制失败: %v", err))
+		return
+	}
+	a.statusBar.SetMessage(i18n.Tf("已复制%s到剪贴板", name))
+}
+
+// previewImage 预览图片消息，终端支持图形协议时暂停界面显示原图，否则在界面中以字符块显示
+func (a *App) previewImage(msg *model.Message) {
+	go func() {
+		data, err := a.m.BrowseImage(msg)
+		a.QueueUpdateDraw(func() {
+			if err != nil {
+				a.showError(i18n.Errorf("无法预览图片: %v", err))
+				return
+			}
+			protocol := termimg.Detect()
+			if protocol == termimg.ProtocolNone {
+				a.showImage(data)
+				return
+			}
+			_, _, width, _ := a.mainPages.GetRect()
+			a.Suspend(func() {
+				fmt.Print("\x1b[2J\x1b[H")
+				if err := termimg.Write(os.Stdout, protocol, data, max(width-2, 10)); err != nil {
+					fmt.Printf(i18n.T("预览失败: %v"), err)
+				}
+				fmt.Print(i18n.T("\n\n按 Enter 返回"))
+				bufio.NewReader(os.Stdin).ReadString('\n')
+			})
+		})
+	}()
+}
+
+// showImage 在不支持图形协议的终端中以字符块显示图片
+func (a *App) showImage(data []byte) {
+	img, err := termimg.Decode(data)
+	if err != nil {
+		a.showError(i18n.Errorf("无法预览图片: %v", err))
+		return
+	}
+	prev := a.GetFocus()
+	view := tview.NewImage().SetImage(img)
+	view.SetBorder(true).
+		SetBorderColor(style.BorderColor).
+		SetTitle(i18n.Tf(" 图片预览 (%s 返回) ", keymap.Label(keymap.Back)))
+	view.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape {
+			a.mainPages.RemovePage("image")
+			a.SetFocus(prev)
+			return nil
+		}
+		return event
+	})
+	a.mainPages.AddPage("image", view, true, true)
+	a.SetFocus(view)
+}
+
+// decryptData 解密数据文件，force 为 true 时全部重新解密
+func (a *App) decryptData(force bool) {
+	// 创建一个没有按钮的模态框，显示"解密中..."
+	modal := tview.NewModal().
+		SetText(i18n.T("解密中..."))
+
+	a.mainPages.AddPage("modal", modal, true, true)
+	a.SetFocus(modal)
+
+	// 在后台执行解密操作
+	stop := a.watchProgress(modal, i18n.T("解密中..."))
+	go func() {
+		// 执行解密
+		err := a.m.DecryptDBFiles(force)
+		stop()
+
+		// 在主线程中更新UI
+		a.QueueUpdateDraw(func() {
+			if err != nil {
+				// 解密失败
+				modal.SetText(i18n.T("解密失败: ") + err.Error())
+			} else {
+				// 解密成功
+				modal.SetText(i18n.T("解密数据成功"))
+			}
+
+			// 添加确认按钮
+			modal.AddButtons([]string{"OK"})
+			modal.SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+				a.mainPages.RemovePage("modal")
+			})
+			a.SetFocus(modal)
+		})
+	}()
+}
+
+// exportSelected 选择联系人或群聊后打开导出表单
+func (a *App) exportSelected(i *menu.Item) {
+	if len(a.ctx.WorkDir) == 0 {
+		a.showError(i18n.Errorf("请先执行解密数据"))
+		return
+	}
+	a.pickTalker(i18n.T("选择要导出的联系人或群聊"), a.exportTalker)
+}
+
+// exportTalker 设置时间范围、格式与文件后导出选中会话的消息
+func (a *App) exportTalker(item *picker.Item) {
+	formView := form.NewForm(i18n.T("导出 ") + item.Name)
+
+	timeRange := "last-7d"
+	format := ExportFormats[0]
+	path := exportFileName(item)
+	translate := ""
+
+	formView.AddInputField(i18n.T("时间范围"), timeRange, 24, nil, func(text string) {
+		timeRange = text
+	})
+	formView.AddDropDown(i18n.T("格式"), ExportFormats, 0, func(option string, optionIndex int) {
+		format = option
+	})
+	formView.AddInputField(i18n.T("文件"), path, 0, nil, func(text string) {
+		path = text
+	})
+	formView.AddInputField(i18n.T("翻译为"), translate, 8, nil, func(text string) {
+		translate = strings.TrimSpace(text)
+	})
+
+	formView.AddButton(i18n.T("导出"), func() {
+		a.mainPages.RemovePage("export")
+		// 文件扩展名与格式保持一致
+		if ext := filepath.Ext(path); ext != "."+ExportExt(format) {
+			path = strings.TrimSuffix(path, ext) + "." + ExportExt(format)
+		}
+		a.exportMessages(item, timeRange, format, path, translate)
+	})
+	formView.AddButton(i18n.T("取消"), func() {
+		a.mainPages.RemovePage("export")
+	})
+	formView.SetCancelFunc(func() {
+		a.mainPages.RemovePage("export")
+	})
+
+	a.mainPages.AddPage("export", formView, true, true)
+	a.SetFocus(formView)
+}
+
+// exportMessages 在后台导出消息，模态框中显示导出进度
+func (a *App) exportMessages(item *picker.Item, timeRange, format, path, translate string) {
+	title := i18n.Tf("正在导出 %s...", item.Name)
+	modal := tview.NewModal().SetText(title)
+	a.mainPages.AddPage("modal", modal, true, true)
+	a.SetFocus(modal)
+
+	stop := a.watchProgress(modal, title)
+	go func() {
+		count, err := a.m.Export(item.UserName, timeRange, format, path, translate, "")
+		stop()
+
+		a.QueueUpdateDraw(func() {
+			if err != nil {
+				modal.SetText(i18n.Tf("导出失败: %v\n\n按 %s 查看日志", err, keymap.Label(keymap.Log)))
+			} else {
+				if abs, err := filepath.Abs(path); err == nil {
+					path = abs
+				}
+				modal.SetText(i18n.Tf("已导出 %d 条消息到\n%s", count, path))
+			}
+
+			modal.AddButtons([]string{"OK"})
+			modal.SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+				a.mainPages.RemovePage("modal")
+			})
+			a.SetFocus(modal)
+		})
+	}()
+}
+
+// exportFileName 返回默认的导出文件名，使用会话名称并替换文件名中不允许的字符
+func exportFileName(item *picker.Item) string {
+	name := item.Name
+	if len(name) == 0 {
+		name = item.UserName
+	}
+	return fmt.Sprintf("%s_%s.%s", safeFileName(name), time.Now().Format("20060102"), ExportFormats[0])
+}
+
 // settingItem 表示一个设置项
 type settingItem struct {
 	name        string
@@ -505,33 +1149,58 @@ func (a *App) settingSelected(i *menu.Item) {
 
 	settings := []settingItem{
 		{
-			name:        "设置 HTTP 服务地址",
-			description: "配置 HTTP 服务监听的地址",
+			name:        i18n.T("设置 HTTP 服务地址"),
+			description: i18n.T("配置 HTTP 服务监听的地址"),
 			action:      a.settingHTTPPort,
 		},
 		{
-			name:        "设置工作目录",
-			description: "配置数据解密后的存储目录",
+			name:        i18n.T("设置工作目录"),
+			description: i18n.T("配置数据解密后的存储目录"),
 			action:      a.settingWorkDir,
 		},
 		{
-			name:        "设置数据密钥",
-			description: "配置数据解密密钥",
+			name:        i18n.T("设置数据密钥"),
+			description: i18n.T("配置数据解密密钥"),
 			action:      a.settingDataKey,
 		},
 		{
-			name:        "设置图片密钥",
-			description: "配置图片解密密钥",
+			name:        i18n.T("设置图片密钥"),
+			description: i18n.T("配置图片解密密钥"),
 			action:      a.settingImgKey,
 		},
 		{
-			name:        "设置数据目录",
-			description: "配置微信数据文件所在目录",
+			name:        i18n.T("设置数据目录"),
+			description: i18n.T("配置微信数据文件所在目录"),
 			action:      a.settingDataDir,
 		},
+		{
+			name:        i18n.T("设置访问令牌"),
+			description: i18n.T("配置 HTTP 接口的访问令牌，为空时不校验"),
+			action:      a.settingAuthToken,
+		},
+		{
+			name:        i18n.T("设置自动解密间隔"),
+			description: i18n.T("配置自动解密时等待数据库停止写入的时间"),
+			action:      a.settingAutoDecryptInterval,
+		},
+		{
+			name:        i18n.T("设置推送目标"),
+			description: i18n.T("添加、修改或删除 webhook 与总结使用的推送目标"),
+			action:      a.settingDestinations,
+		},
+		{
+			name:        i18n.T("定时任务"),
+			description: i18n.T("配置每日导出、每周备份、总结推送、邮件摘要与笔记库同步"),
+			action:      a.settingSchedule,
+		},
+		{
+			name:        i18n.T("消息通知"),
+			description: i18n.T("自动解密发现关注的聊天对象的新消息时响铃或发送桌面通知"),
+			action:      a.settingNotify,
+		},
 	}
 
-	subMenu := menu.NewSubMenu("设置")
+	subMenu := menu.NewSubMenu(i18n.T("设置"))
 	for idx, setting := range settings {
 		item := &menu.Item{
 			Index:       idx + 1,
@@ -553,24 +1222,28 @@ func (a *App) settingSelected(i *menu.Item) {
 // settingHTTPPort 设置 HTTP 端口
 func (a *App) settingHTTPPort() {
 	// 使用我们的自定义表单组件
-	formView := form.NewForm("设置 HTTP 地址")
+	formView := form.NewForm(i18n.T("设置 HTTP 地址"))
 
 	// 临时存储用户输入的值
 	tempHTTPAddr := a.ctx.HTTPAddr
 
 	// 添加输入字段 - 不再直接设置HTTP地址，而是更新临时变量
-	formView.AddInputField("地址", tempHTTPAddr, 0, nil, func(text string) {
+	formView.AddInputField(i18n.T("地址"), tempHTTPAddr, 0, nil, func(text string) {
 		tempHTTPAddr = text // 只更新临时变量
 	})
 
 	// 添加按钮 - 点击保存时才设置HTTP地址
-	formView.AddButton("保存", func() {
-		a.m.SetHTTPAddr(tempHTTPAddr) // 在这里设置HTTP地址
+	formView.AddButton(i18n.T("保存"), func() {
+		// 在这里设置HTTP地址，地址无效时保留表单
+		if err := a.m.SetHTTPAddr(tempHTTPAddr); err != nil {
+			a.showError(err)
+			return
+		}
 		a.mainPages.RemovePage("submenu2")
-		a.showInfo("HTTP 地址已设置为 " + a.ctx.HTTPAddr)
+		a.showInfo(i18n.T("HTTP 地址已设置为 ") + a.ctx.HTTPAddr)
 	})
 
-	formView.AddButton("取消", func() {
+	formView.AddButton(i18n.T("取消"), func() {
 		a.mainPages.RemovePage("submenu2")
 	})
 
@@ -581,24 +1254,37 @@ func (a *App) settingHTTPPort() {
 // settingWorkDir 设置工作目录
 func (a *App) settingWorkDir() {
 	// 使用我们的自定义表单组件
-	formView := form.NewForm("设置工作目录")
+	formView := form.NewForm(i18n.T("设置工作目录"))
 
 	// 临时存储用户输入的值
 	tempWorkDir := a.ctx.WorkDir
 
 	// 添加输入字段 - 不再直接设置工作目录，而是更新临时变量
-	formView.AddInputField("工作目录", tempWorkDir, 0, nil, func(text string) {
+	formView.AddInputField(i18n.T("工作目录"), tempWorkDir, 0, nil, func(text string) {
 		tempWorkDir = text // 只更新临时变量
 	})
 
 	// 添加按钮 - 点击保存时才设置工作目录
-	formView.AddButton("保存", func() {
-		a.ctx.SetWorkDir(tempWorkDir) // 在这里设置工作目录
-		a.mainPages.RemovePage("submenu2")
-		a.showInfo("工作目录已设置为 " + a.ctx.WorkDir)
+	formView.AddButton(i18n.T("保存"), func() {
+		save := func() {
+			// 在这里设置工作目录，目录无效时保留表单
+			if err := a.m.SetWorkDir(tempWorkDir); err != nil {
+				a.showError(err)
+				return
+			}
+			a.mainPages.RemovePage("submenu2")
+			a.showInfo(i18n.T("工作目录已设置为 ") + a.ctx.WorkDir)
+		}
+		// 目录中已有其他文件时，解密会覆盖其中的同名数据库
+		dir, err := filepath.Abs(strings.TrimSpace(tempWorkDir))
+		if err != nil || dir == a.ctx.WorkDir || !dirHasFiles(dir) {
+			save()
+			return
+		}
+		a.confirm(i18n.Tf("目录 %s 中已有文件\n\n设为工作目录后，解密会覆盖其中同名的数据库文件，清理时可能删除其中不属于当前账号的数据库\n\n确定使用该目录吗？", dir), i18n.T("使用该目录"), save)
 	})
 
-	formView.AddButton("取消", func() {
+	formView.AddButton(i18n.T("取消"), func() {
 		a.mainPages.RemovePage("submenu2")
 	})
 
@@ -609,24 +1295,24 @@ func (a *App) settingWorkDir() {
 // settingDataKey 设置数据密钥
 func (a *App) settingDataKey() {
 	// 使用我们的自定义表单组件
-	formView := form.NewForm("设置数据密钥")
+	formView := form.NewForm(i18n.T("设置数据密钥"))
 
 	// 临时存储用户输入的值
 	tempDataKey := a.ctx.DataKey
 
 	// 添加输入字段 - 不直接设置数据密钥，而是更新临时变量
-	formView.AddInputField("数据密钥", tempDataKey, 0, nil, func(text string) {
+	formView.AddInputField(i18n.T("数据密钥"), tempDataKey, 0, nil, func(text string) {
 		tempDataKey = text // 只更新临时变量
 	})
 
 	// 添加按钮 - 点击保存时才设置数据密钥
-	formView.AddButton("保存", func() {
+	formView.AddButton(i18n.T("保存"), func() {
 		a.ctx.DataKey = tempDataKey // 设置数据密钥
 		a.mainPages.RemovePage("submenu2")
-		a.showInfo("数据密钥已设置")
+		a.showInfo(i18n.T("数据密钥已设置"))
 	})
 
-	formView.AddButton("取消", func() {
+	formView.AddButton(i18n.T("取消"), func() {
 		a.mainPages.RemovePage("submenu2")
 	})
 
@@ -636,21 +1322,21 @@ func (a *App) settingDataKey() {
 
 // settingImgKey 设置图片密钥 (ImgKey)
 func (a *App) settingImgKey() {
-	formView := form.NewForm("设置图片密钥")
+	formView := form.NewForm(i18n.T("设置图片密钥"))
 
 	tempImgKey := a.ctx.ImgKey
 
-	formView.AddInputField("图片密钥", tempImgKey, 0, nil, func(text string) {
+	formView.AddInputField(i18n.T("图片密钥"), tempImgKey, 0, nil, func(text string) {
 		tempImgKey = text
 	})
 
-	formView.AddButton("保存", func() {
+	formView.AddButton(i18n.T("保存"), func() {
 		a.ctx.SetImgKey(tempImgKey)
 		a.mainPages.RemovePage("submenu2")
-		a.showInfo("图片密钥已设置")
+		a.showInfo(i18n.T("图片密钥已设置"))
 	})
 
-	formView.AddButton("取消", func() {
+	formView.AddButton(i18n.T("取消"), func() {
 		a.mainPages.RemovePage("submenu2")
 	})
 
@@ -661,24 +1347,24 @@ func (a *App) settingImgKey() {
 // settingDataDir 设置数据目录
 func (a *App) settingDataDir() {
 	// 使用我们的自定义表单组件
-	formView := form.NewForm("设置数据目录")
+	formView := form.NewForm(i18n.T("设置数据目录"))
 
 	// 临时存储用户输入的值
 	tempDataDir := a.ctx.DataDir
 
 	// 添加输入字段 - 不直接设置数据目录，而是更新临时变量
-	formView.AddInputField("数据目录", tempDataDir, 0, nil, func(text string) {
+	formView.AddInputField(i18n.T("数据目录"), tempDataDir, 0, nil, func(text string) {
 		tempDataDir = text // 只更新临时变量
 	})
 
 	// 添加按钮 - 点击保存时才设置数据目录
-	formView.AddButton("保存", func() {
+	formView.AddButton(i18n.T("保存"), func() {
 		a.ctx.DataDir = tempDataDir // 设置数据目录
 		a.mainPages.RemovePage("submenu2")
-		a.showInfo("数据目录已设置为 " + a.ctx.DataDir)
+		a.showInfo(i18n.T("数据目录已设置为 ") + a.ctx.DataDir)
 	})
 
-	formView.AddButton("取消", func() {
+	formView.AddButton(i18n.T("取消"), func() {
 		a.mainPages.RemovePage("submenu2")
 	})
 
@@ -686,169 +1372,692 @@ func (a *App) settingDataDir() {
 	a.SetFocus(formView)
 }
 
-// selectAccountSelected 处理切换账号菜单项的选择事件
-func (a *App) selectAccountSelected(i *menu.Item) {
-	// 创建子菜单
-	subMenu := menu.NewSubMenu("切换账号")
+// settingAuthToken 设置 HTTP 接口的访问令牌
+func (a *App) settingAuthToken() {
+	formView := form.NewForm(i18n.T("设置访问令牌"))
+
+	tempToken := a.ctx.GetAuthToken()
+	formView.AddInputField(i18n.T("访问令牌"), tempToken, 0, nil, func(text string) {
+		tempToken = text
+	})
+
+	formView.AddButton(i18n.T("保存"), func() {
+		if err := a.m.SetAuthToken(tempToken); err != nil {
+			a.showError(err)
+			return
+		}
+		a.mainPages.RemovePage("submenu2")
+		if len(a.ctx.GetAuthToken()) == 0 {
+			a.showInfo(i18n.T("已关闭访问令牌校验"))
+		} else {
+			a.showInfo(i18n.T("访问令牌已设置"))
+		}
+	})
+
+	formView.AddButton(i18n.T("取消"), func() {
+		a.mainPages.RemovePage("submenu2")
+	})
+
+	a.mainPages.AddPage("submenu2", formView, true, true)
+	a.SetFocus(formView)
+}
+
+// settingAutoDecryptInterval 设置自动解密等待数据库停止写入的时间
+func (a *App) settingAutoDecryptInterval() {
+	a.autoDecryptIntervalForm("submenu2", func() {
+		a.mainPages.RemovePage("submenu2")
+	})
+}
+
+// autoDecryptIntervalForm 在 page 页面中显示自动解密间隔的表单，保存或取消后调用 done
+func (a *App) autoDecryptIntervalForm(page string, done func()) {
+	formView := form.NewForm(i18n.T("设置自动解密间隔"))
+
+	tempInterval := "0s"
+	if d := a.ctx.GetAutoDecryptInterval(); d > 0 {
+		tempInterval = d.String()
+	}
+	formView.AddInputField(i18n.T("间隔 (如 2s，0 为默认)"), tempInterval, 12, nil, func(text string) {
+		tempInterval = text
+	})
+
+	formView.AddButton(i18n.T("保存"), func() {
+		if err := a.m.SetAutoDecryptInterval(tempInterval); err != nil {
+			a.showError(err)
+			return
+		}
+		done()
+		a.showInfo(i18n.T("自动解密间隔已设置"))
+	})
+
+	formView.AddButton(i18n.T("取消"), done)
+	formView.SetCancelFunc(done)
+
+	a.mainPages.AddPage(page, formView, true, true)
+	a.SetFocus(formView)
+}
+
+// settingDestinations 显示推送目标列表，选择后修改，或添加新的推送目标
+func (a *App) settingDestinations() {
+	subMenu := menu.NewSubMenu(i18n.T("设置推送目标"))
+	subMenu.SetCancelFunc(func() {
+		a.mainPages.RemovePage("submenu2")
+	})
+
+	subMenu.AddItem(&menu.Item{
+		Index:       0,
+		Name:        i18n.T("添加推送目标"),
+		Description: i18n.T("添加新的推送目标"),
+		Selected: func(*menu.Item) {
+			a.settingDestination("", nil)
+		},
+	})
 
-	// 添加微信进程
-	instances := a.m.wechat.GetWeChatInstances()
-	if len(instances) > 0 {
-		// 添加实例标题
+	dests := a.ctx.GetDestinations()
+	names := make([]string, 0, len(dests))
+	for name := range dests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for idx, name := range names {
+		dest := dests[name]
+		if dest == nil {
+			continue
+		}
 		subMenu.AddItem(&menu.Item{
-			Index:       0,
-			Name:        "--- 微信进程 ---",
-			Description: "",
-			Hidden:      false,
-			Selected:    nil,
+			Index:       idx + 1,
+			Name:        tview.Escape(name),
+			Description: tview.Escape(dest.URL),
+			Selected: func(name string, dest *conf.Destination) func(*menu.Item) {
+				return func(*menu.Item) {
+					a.settingDestination(name, dest)
+				}
+			}(name, dest),
 		})
+	}
 
-		// 添加实例列表
-		for idx, instance := range instances {
-			// 创建一个实例描述
-			description := fmt.Sprintf("版本: %s 目录: %s", instance.FullVersion, instance.DataDir)
-
-			// 标记当前选中的实例
-			name := fmt.Sprintf("%s [%d]", instance.Name, instance.PID)
-			if a.ctx.Current != nil && a.ctx.Current.PID == instance.PID {
-				name = name + " [当前]"
-			}
+	a.mainPages.AddPage("submenu2", subMenu, true, true)
+	a.SetFocus(subMenu)
+}
 
-			// 创建菜单项
-			instanceItem := &menu.Item{
-				Index:       idx + 1,
-				Name:        name,
-				Description: description,
-				Hidden:      false,
-				Selected: func(instance *wechat.Account) func(*menu.Item) {
-					return func(*menu.Item) {
-						// 如果是当前账号，则无需切换
-						if a.ctx.Current != nil && a.ctx.Current.PID == instance.PID {
-							a.mainPages.RemovePage("submenu")
-							a.showInfo("已经是当前账号")
-							return
-						}
+// settingDestination 添加或修改推送目标，name 为空时添加
+func (a *App) settingDestination(name string, dest *conf.Destination) {
+	title := i18n.T("添加推送目标")
+	tempName, tempURL, tempHeaders, tempTimeout := name, "", "", ""
+	if dest != nil {
+		title = i18n.T("修改推送目标 ") + name
+		tempURL, tempHeaders = dest.URL, FormatHeaders(dest.Headers)
+		if dest.Timeout > 0 {
+			tempTimeout = dest.Timeout.String()
+		}
+	}
+	formView := form.NewForm(title)
 
-						// 显示切换中的模态框
-						modal := tview.NewModal().SetText("正在切换账号...")
-						a.mainPages.AddPage("modal", modal, true, true)
-						a.SetFocus(modal)
+	formView.AddInputField(i18n.T("名称"), tempName, 20, nil, func(text string) {
+		tempName = text
+	})
+	formView.AddInputField("URL", tempURL, 40, nil, func(text string) {
+		tempURL = text
+	})
+	formView.AddInputField(i18n.T("请求头 (Key: Value; ...)"), tempHeaders, 40, nil, func(text string) {
+		tempHeaders = text
+	})
+	formView.AddInputField(i18n.T("超时 (如 10s)"), tempTimeout, 12, nil, func(text string) {
+		tempTimeout = text
+	})
 
-						// 在后台执行切换操作
-						go func() {
-							err := a.m.Switch(instance, "")
-
-							// 在主线程中更新UI
-							a.QueueUpdateDraw(func() {
-								a.mainPages.RemovePage("modal")
-								a.mainPages.RemovePage("submenu")
-
-								if err != nil {
-									// 切换失败
-									a.showError(fmt.Errorf("切换账号失败: %v", err))
-								} else {
-									// 切换成功
-									a.showInfo("切换账号成功")
-									// 更新菜单状态
-									a.updateMenuItemsState()
-								}
-							})
-						}()
-					}
-				}(instance),
+	closeForm := func() {
+		a.mainPages.RemovePage("destination")
+		a.mainPages.RemovePage("submenu2")
+		a.settingDestinations()
+	}
+	formView.AddButton(i18n.T("保存"), func() {
+		if err := a.m.SetDestination(tempName, tempURL, tempHeaders, tempTimeout); err != nil {
+			a.showError(err)
+			return
+		}
+		// 修改名称时删除原来的推送目标
+		if len(name) != 0 && strings.TrimSpace(tempName) != name {
+			if err := a.m.RemoveDestination(name); err != nil {
+				a.showError(err)
+				return
 			}
-			subMenu.AddItem(instanceItem)
 		}
+		closeForm()
+		a.showInfo(i18n.T("推送目标已保存，webhook 在下次启动 HTTP 服务时使用"))
+	})
+	if dest != nil {
+		formView.AddButton(i18n.T("删除"), func() {
+			if err := a.m.RemoveDestination(name); err != nil {
+				a.showError(err)
+				return
+			}
+			closeForm()
+			a.showInfo(i18n.T("推送目标 ") + name + i18n.T(" 已删除"))
+		})
 	}
+	formView.AddButton(i18n.T("取消"), func() {
+		a.mainPages.RemovePage("destination")
+	})
+	formView.SetCancelFunc(func() {
+		a.mainPages.RemovePage("destination")
+	})
+
+	a.mainPages.AddPage("destination", formView, true, true)
+	a.SetFocus(formView)
+}
+
+// settingNotify 显示关注的聊天对象，选择后修改通知方式
+func (a *App) settingNotify() {
+	subMenu := menu.NewSubMenu(i18n.T("消息通知"))
+	subMenu.SetCancelFunc(func() {
+		a.mainPages.RemovePage("submenu2")
+	})
+
+	subMenu.AddItem(&menu.Item{
+		Index:       0,
+		Name:        i18n.T("添加关注"),
+		Description: i18n.T("选择聊天对象，收到新消息时通知"),
+		Selected: func(*menu.Item) {
+			a.pickTalker(i18n.T("选择关注的聊天对象"), func(item *picker.Item) {
+				a.notifyForm(conf.NotifyRule{Talker: item.UserName, Bell: true})
+			})
+		},
+	})
 
-	// 添加历史账号
-	if len(a.ctx.History) > 0 {
-		// 添加历史账号标题
+	for idx, rule := range a.ctx.GetNotify() {
+		var ways []string
+		if rule.Bell {
+			ways = append(ways, i18n.T("响铃"))
+		}
+		if rule.Desktop {
+			ways = append(ways, i18n.T("桌面通知"))
+		}
+		if rule.Telegram {
+			ways = append(ways, "Telegram")
+		}
+		if len(rule.Destination) != 0 {
+			ways = append(ways, i18n.T("推送到 ")+rule.Destination)
+		}
 		subMenu.AddItem(&menu.Item{
-			Index:       100,
-			Name:        "--- 历史账号 ---",
-			Description: "",
-			Hidden:      false,
-			Selected:    nil,
+			Index:       idx + 1,
+			Name:        tview.Escape(rule.Talker),
+			Description: strings.Join(ways, ", "),
+			Selected: func(rule conf.NotifyRule) func(*menu.Item) {
+				return func(*menu.Item) {
+					a.notifyForm(rule)
+				}
+			}(rule),
 		})
+	}
 
-		// 添加历史账号列表
-		idx := 101
-		for account, hist := range a.ctx.History {
-			// 创建一个账号描述
-			description := fmt.Sprintf("版本: %s 目录: %s", hist.FullVersion, hist.DataDir)
+	a.mainPages.AddPage("submenu2", subMenu, true, true)
+	a.SetFocus(subMenu)
+}
 
-			// 标记当前选中的账号
-			name := account
-			if name == "" {
-				name = filepath.Base(hist.DataDir)
-			}
-			if a.ctx.DataDir == hist.DataDir {
-				name = name + " [当前]"
-			}
+// notifyForm 修改聊天对象的通知方式，都不勾选时取消关注
+func (a *App) notifyForm(rule conf.NotifyRule) {
+	tempRule := rule
+	formView := form.NewForm(i18n.T("消息通知 ") + rule.Talker)
+	formView.AddCheckbox(i18n.T("终端响铃"), tempRule.Bell, func(checked bool) {
+		tempRule.Bell = checked
+	})
+	formView.AddCheckbox(i18n.T("桌面通知"), tempRule.Desktop, func(checked bool) {
+		tempRule.Desktop = checked
+	})
+	formView.AddCheckbox(i18n.T("Telegram 通知"), tempRule.Telegram, func(checked bool) {
+		tempRule.Telegram = checked
+	})
+	// 推送目标，第一项为不推送
+	options := []string{i18n.T("不推送")}
+	for name := range a.ctx.GetDestinations() {
+		options = append(options, name)
+	}
+	sort.Strings(options[1:])
+	formView.AddDropDown(i18n.T("推送目标"), options, max(slices.Index(options, rule.Destination), 0), func(option string, optionIndex int) {
+		tempRule.Destination = ""
+		if optionIndex > 0 {
+			tempRule.Destination = option
+		}
+	})
 
-			// 创建菜单项
-			histItem := &menu.Item{
-				Index:       idx,
-				Name:        name,
-				Description: description,
-				Hidden:      false,
-				Selected: func(account string) func(*menu.Item) {
-					return func(*menu.Item) {
-						// 如果是当前账号，则无需切换
-						if a.ctx.Current != nil && a.ctx.DataDir == a.ctx.History[account].DataDir {
-							a.mainPages.RemovePage("submenu")
-							a.showInfo("已经是当前账号")
-							return
-						}
+	closeForm := func() {
+		a.mainPages.RemovePage("notify")
+		a.mainPages.RemovePage("submenu2")
+		a.settingNotify()
+	}
+	formView.AddButton(i18n.T("保存"), func() {
+		if err := a.m.SetNotifyRule(tempRule); err != nil {
+			a.showError(err)
+			return
+		}
+		closeForm()
+		if !a.ctx.AutoDecrypt {
+			a.showInfo(i18n.T("通知已保存，开启自动解密后生效"))
+		}
+	})
+	formView.AddButton(i18n.T("取消关注"), func() {
+		if err := a.m.RemoveNotifyRule(rule.Talker); err != nil {
+			a.showError(err)
+			return
+		}
+		closeForm()
+	})
+	formView.AddButton(i18n.T("取消"), func() {
+		a.mainPages.RemovePage("notify")
+	})
+	formView.SetCancelFunc(func() {
+		a.mainPages.RemovePage("notify")
+	})
 
-						// 显示切换中的模态框
-						modal := tview.NewModal().SetText("正在切换账号...")
-						a.mainPages.AddPage("modal", modal, true, true)
-						a.SetFocus(modal)
+	a.mainPages.AddPage("notify", formView, true, true)
+	a.SetFocus(formView)
+}
 
-						// 在后台执行切换操作
-						go func() {
-							err := a.m.Switch(nil, account)
-
-							// 在主线程中更新UI
-							a.QueueUpdateDraw(func() {
-								a.mainPages.RemovePage("modal")
-								a.mainPages.RemovePage("submenu")
-
-								if err != nil {
-									// 切换失败
-									a.showError(fmt.Errorf("切换账号失败: %v", err))
-								} else {
-									// 切换成功
-									a.showInfo("切换账号成功")
-									// 更新菜单状态
-									a.updateMenuItemsState()
-								}
-							})
-						}()
-					}
-				}(account),
-			}
-			idx++
-			subMenu.AddItem(histItem)
+// settingSchedule 显示定时任务列表，选择后修改任务
+func (a *App) settingSchedule() {
+	subMenu := menu.NewSubMenu(i18n.T("定时任务"))
+	subMenu.SetCancelFunc(func() {
+		a.mainPages.RemovePage("submenu2")
+	})
+
+	s := a.ctx.GetSchedule()
+	if s == nil {
+		s = &conf.Schedule{}
+	}
+	interval := i18n.T("默认 (1s)")
+	if d := a.ctx.GetAutoDecryptInterval(); d > 0 {
+		interval = d.String()
+	}
+
+	// 各任务的执行时间，为空时未开启
+	var exportAt, backupAt, summaryAt, emailAt, vaultAt string
+	if s.Export != nil && len(s.Export.At) != 0 {
+		exportAt = i18n.Tf("每天 %s", s.Export.At)
+	}
+	if s.Backup != nil && len(s.Backup.At) != 0 && s.Backup.Weekday >= 0 && s.Backup.Weekday <= 6 {
+		backupAt = i18n.Tf("每周%s %s", weekdayNames()[s.Backup.Weekday], s.Backup.At)
+	}
+	if s.Summary != nil && len(s.Summary.At) != 0 {
+		summaryAt = i18n.Tf("每天 %s", s.Summary.At)
+	}
+	if s.Email != nil && len(s.Email.At) != 0 {
+		emailAt = i18n.Tf("每天 %s", s.Email.At)
+	}
+	if s.Vault != nil && len(s.Vault.At) != 0 {
+		vaultAt = i18n.Tf("每天 %s", s.Vault.At)
+	}
+
+	closeForm := func() {
+		a.mainPages.RemovePage("job")
+		a.mainPages.RemovePage("submenu2")
+		a.settingSchedule()
+	}
+	items := []*menu.Item{
+		{
+			Name:        i18n.T("自动解密间隔"),
+			Description: interval,
+			Selected: func(*menu.Item) {
+				a.autoDecryptIntervalForm("job", closeForm)
+			},
+		},
+		{
+			Name:        i18n.T("每日导出"),
+			Description: a.jobDescription(JobExport, exportAt),
+			Selected: func(*menu.Item) {
+				a.exportJobForm(s.Export, closeForm)
+			},
+		},
+		{
+			Name:        i18n.T("每周备份"),
+			Description: a.jobDescription(JobBackup, backupAt),
+			Selected: func(*menu.Item) {
+				a.backupJobForm(s.Backup, closeForm)
+			},
+		},
+		{
+			Name:        i18n.T("每日总结推送"),
+			Description: a.jobDescription(JobSummary, summaryAt),
+			Selected: func(*menu.Item) {
+				a.summaryJobForm(s.Summary, closeForm)
+			},
+		},
+		{
+			Name:        i18n.T("每日邮件摘要"),
+			Description: a.jobDescription(JobEmail, emailAt),
+			Selected: func(*menu.Item) {
+				a.emailJobForm(s.Email, closeForm)
+			},
+		},
+		{
+			Name:        i18n.T("每日同步笔记库"),
+			Description: a.jobDescription(JobVault, vaultAt),
+			Selected: func(*menu.Item) {
+				a.vaultJobForm(s.Vault, closeForm)
+			},
+		},
+	}
+	for idx, item := range items {
+		item.Index = idx
+		subMenu.AddItem(item)
+	}
+
+	a.mainPages.AddPage("submenu2", subMenu, true, true)
+	a.SetFocus(subMenu)
+}
+
+// jobDescription 在执行时间后附加下一次执行的时间与最近一次的结果
+func (a *App) jobDescription(name, when string) string {
+	if len(when) == 0 {
+		return i18n.T("未开启")
+	}
+	text := when
+	for _, st := range a.m.ScheduleStatus() {
+		if st.Name != name {
+			continue
+		}
+		text += i18n.Tf("，下次 %s", st.Next.Format("01-02 15:04"))
+		switch {
+		case st.LastRun.IsZero():
+		case st.LastErr != nil:
+			text += i18n.Tf("，%s 失败", st.LastRun.Format("01-02 15:04"))
+		default:
+			text += i18n.Tf("，%s 成功", st.LastRun.Format("01-02 15:04"))
 		}
 	}
+	return tview.Escape(text)
+}
 
-	// 如果没有账号可选择
-	if len(a.ctx.History) == 0 && len(instances) == 0 {
-		subMenu.AddItem(&menu.Item{
-			Index:       1,
-			Name:        "无可用账号",
-			Description: "未检测到微信进程或历史账号",
-			Hidden:      false,
-			Selected:    nil,
-		})
+// weekdayNames 返回星期日到星期六的名称
+func weekdayNames() []string {
+	return []string{i18n.T("日"), i18n.T("一"), i18n.T("二"), i18n.T("三"), i18n.T("四"), i18n.T("五"), i18n.T("六")}
+}
+
+// exportJobForm 修改每日导出任务，时间为空时关闭
+func (a *App) exportJobForm(job *conf.ExportJob, done func()) {
+	tempAt, tempTalkers, tempFormat, tempDir := "", "", ExportText, ""
+	if job != nil {
+		tempAt, tempTalkers, tempFormat, tempDir = job.At, strings.Join(job.Talkers, ", "), job.Format, job.Dir
+	}
+	formView := form.NewForm(i18n.T("每日导出"))
+	formView.AddInputField(i18n.T("时间 (HH:MM，留空关闭)"), tempAt, 8, nil, func(text string) {
+		tempAt = text
+	})
+	formView.AddInputField(i18n.T("聊天对象 (逗号分隔)"), tempTalkers, 40, nil, func(text string) {
+		tempTalkers = text
+	})
+	formView.AddDropDown(i18n.T("格式"), ExportFormats, max(slices.Index(ExportFormats, tempFormat), 0), func(option string, optionIndex int) {
+		tempFormat = option
+	})
+	formView.AddInputField(i18n.T("目录 (留空为工作目录旁的 export)"), tempDir, 40, nil, func(text string) {
+		tempDir = text
+	})
+	a.jobFormButtons(formView, done, func() error {
+		return a.m.SetExportJob(tempAt, tempTalkers, tempFormat, tempDir)
+	})
+}
+
+// backupJobForm 修改每周备份任务，时间为空时关闭
+func (a *App) backupJobForm(job *conf.BackupJob, done func()) {
+	tempAt, tempWeekday, tempDir, tempKeep := "", 0, "", ""
+	if job != nil {
+		tempAt, tempWeekday, tempDir = job.At, min(max(job.Weekday, 0), 6), job.Dir
+		if job.Keep > 0 {
+			tempKeep = fmt.Sprint(job.Keep)
+		}
+	}
+	formView := form.NewForm(i18n.T("每周备份"))
+	formView.AddInputField(i18n.T("时间 (HH:MM，留空关闭)"), tempAt, 8, nil, func(text string) {
+		tempAt = text
+	})
+	formView.AddDropDown(i18n.T("星期"), weekdayNames(), tempWeekday, func(option string, optionIndex int) {
+		tempWeekday = optionIndex
+	})
+	formView.AddInputField(i18n.T("目录 (留空为工作目录旁的 backup)"), tempDir, 40, nil, func(text string) {
+		tempDir = text
+	})
+	formView.AddInputField(i18n.T("保留数量 (0 为全部保留)"), tempKeep, 8, nil, func(text string) {
+		tempKeep = text
+	})
+	a.jobFormButtons(formView, done, func() error {
+		return a.m.SetBackupJob(tempAt, tempWeekday, tempDir, tempKeep)
+	})
+}
+
+// summaryJobForm 修改每日总结推送任务，时间为空时关闭
+func (a *App) summaryJobForm(job *conf.SummaryJob, done func()) {
+	tempAt, tempTalkers, tempTo := "", "", summarize.ToWebhook
+	if job != nil {
+		tempAt, tempTalkers, tempTo = job.At, strings.Join(job.Talkers, ", "), job.To
+	}
+	formView := form.NewForm(i18n.T("每日总结推送"))
+	formView.AddInputField(i18n.T("时间 (HH:MM，留空关闭)"), tempAt, 8, nil, func(text string) {
+		tempAt = text
+	})
+	formView.AddInputField(i18n.T("聊天对象 (逗号分隔)"), tempTalkers, 40, nil, func(text string) {
+		tempTalkers = text
+	})
+	formView.AddInputField(i18n.T("推送到 (webhook、推送目标名称或 URL)"), tempTo, 40, nil, func(text string) {
+		tempTo = text
+	})
+	a.jobFormButtons(formView, done, func() error {
+		return a.m.SetSummaryJob(tempAt, tempTalkers, tempTo)
+	})
+}
+
+// emailJobForm 修改每日邮件摘要任务，时间为空时关闭
+func (a *App) emailJobForm(job *conf.EmailJob, done func()) {
+	tempAt, tempTalkers, tempTo := "", "", ""
+	if job != nil {
+		tempAt, tempTalkers, tempTo = job.At, strings.Join(job.Talkers, ", "), strings.Join(job.To, ", ")
 	}
+	formView := form.NewForm(i18n.T("每日邮件摘要"))
+	formView.AddInputField(i18n.T("时间 (HH:MM，留空关闭)"), tempAt, 8, nil, func(text string) {
+		tempAt = text
+	})
+	formView.AddInputField(i18n.T("聊天对象 (逗号分隔)"), tempTalkers, 40, nil, func(text string) {
+		tempTalkers = text
+	})
+	formView.AddInputField(i18n.T("收件人 (逗号分隔，留空使用 smtp.to)"), tempTo, 40, nil, func(text string) {
+		tempTo = text
+	})
+	a.jobFormButtons(formView, done, func() error {
+		return a.m.SetEmailJob(tempAt, tempTalkers, tempTo)
+	})
+}
 
-	// 显示子菜单
+// vaultJobForm 修改每日同步笔记库任务，时间为空时关闭
+func (a *App) vaultJobForm(job *conf.VaultJob, done func()) {
+	tempAt, tempTalkers, tempDir, tempGit := "", "", "", false
+	if job != nil {
+		tempAt, tempTalkers, tempDir, tempGit = job.At, strings.Join(job.Talkers, ", "), job.Dir, job.Git
+	}
+	formView := form.NewForm(i18n.T("每日同步笔记库"))
+	formView.AddInputField(i18n.T("时间 (HH:MM，留空关闭)"), tempAt, 8, nil, func(text string) {
+		tempAt = text
+	})
+	formView.AddInputField(i18n.T("聊天对象 (逗号分隔)"), tempTalkers, 40, nil, func(text string) {
+		tempTalkers = text
+	})
+	formView.AddInputField(i18n.T("笔记库目录 (留空为工作目录旁的 vault)"), tempDir, 40, nil, func(text string) {
+		tempDir = text
+	})
+	formView.AddCheckbox(i18n.T("提交到 git 仓库 (只同步文字)"), tempGit, func(checked bool) {
+		tempGit = checked
+	})
+	a.jobFormButtons(formView, done, func() error {
+		return a.m.SetVaultJob(tempAt, tempTalkers, tempDir, tempGit)
+	})
+}
+
+// jobFormButtons 为定时任务表单添加保存与取消按钮，保存失败时保留表单
+func (a *App) jobFormButtons(formView *form.Form, done func(), save func() error) {
+	formView.AddButton(i18n.T("保存"), func() {
+		if err := save(); err != nil {
+			a.showError(err)
+			return
+		}
+		done()
+		a.showInfo(i18n.T("定时任务已保存"))
+	})
+	formView.AddButton(i18n.T("取消"), done)
+	formView.SetCancelFunc(done)
+
+	a.mainPages.AddPage("job", formView, true, true)
+	a.SetFocus(formView)
+}
+
+// selectAccountSelected 处理切换账号菜单项的选择事件
+// 账号状态需要读取数据库验证密钥，先显示加载中，在后台获取后更新列表
+func (a *App) selectAccountSelected(i *menu.Item) {
+	subMenu := menu.NewSubMenu(i18n.T("切换账号"))
+	subMenu.AddItem(&menu.Item{
+		Index:       1,
+		Name:        i18n.T("加载中..."),
+		Description: i18n.T("正在检查微信进程与历史账号"),
+	})
 	a.mainPages.AddPage("submenu", subMenu, true, true)
 	a.SetFocus(subMenu)
+
+	go func() {
+		statuses := a.m.AccountStatuses()
+		a.QueueUpdateDraw(func() {
+			items := make([]*menu.Item, 0, len(statuses))
+			for idx, status := range statuses {
+				name := status.Account
+				if len(name) == 0 {
+					name = filepath.Base(status.DataDir)
+				}
+				if status.Current {
+					name = name + i18n.T(" [当前]")
+				}
+				items = append(items, &menu.Item{
+					Index:       idx + 1,
+					Name:        name,
+					Description: accountDescription(status),
+					Selected: func(status *AccountStatus) func(*menu.Item) {
+						return func(*menu.Item) {
+							a.switchAccount(status)
+						}
+					}(status),
+				})
+			}
+
+			// 如果没有账号可选择
+			if len(items) == 0 {
+				items = append(items, &menu.Item{
+					Index:       1,
+					Name:        i18n.T("无可用账号"),
+					Description: i18n.T("未检测到微信进程或历史账号"),
+				})
+			}
+			subMenu.SetItems(items)
+		})
+	}()
+}
+
+// switchAccount 切换到选中的账号，正在运行的账号切换到微信进程，否则切换到历史账号
+func (a *App) switchAccount(status *AccountStatus) {
+	// 如果是当前账号，则无需切换
+	if status.Current {
+		a.mainPages.RemovePage("submenu")
+		a.showInfo(i18n.T("已经是当前账号"))
+		return
+	}
+
+	if a.ctx.AutoDecrypt {
+		a.confirm(i18n.Tf("当前账号 %s 正在自动解密\n\n切换后将停止监控当前账号的数据目录，之后收到的新消息不会被解密；新账号已解密过时会在新账号上继续自动解密\n\n确定切换吗？", a.ctx.Account), i18n.T("切换"), func() {
+			a.doSwitchAccount(status)
+		})
+		return
+	}
+	a.doSwitchAccount(status)
+}
+
+// doSwitchAccount 在后台切换账号
+func (a *App) doSwitchAccount(status *AccountStatus) {
+
+	// 显示切换中的模态框
+	modal := tview.NewModal().SetText(i18n.T("正在切换账号..."))
+	a.mainPages.AddPage("modal", modal, true, true)
+	a.SetFocus(modal)
+
+	// 在后台执行切换操作
+	go func() {
+		err := a.m.Switch(status.Instance, status.Account)
+
+		// 在主线程中更新UI
+		a.QueueUpdateDraw(func() {
+			a.mainPages.RemovePage("modal")
+			a.mainPages.RemovePage("submenu")
+
+			if err != nil {
+				a.showError(i18n.Errorf("切换账号失败: %v", err))
+			} else {
+				a.showInfo(i18n.T("切换账号成功"))
+				// 更新菜单状态
+				a.updateMenuItemsState()
+			}
+		})
+	}()
+}
+
+// accountDescription 返回账号的进程、密钥、工作目录、解密时间与 HTTP 服务状态
+func accountDescription(s *AccountStatus) string {
+	ok := func(text string) string { return style.Tag(style.SuccessColor) + "● " + text + "[-]" }
+	warn := func(text string) string { return style.Tag(style.PausedStatusFgColor) + "● " + text + "[-]" }
+	off := func(text string) string { return style.Tag(style.MutedColor) + "○ " + text + "[-]" }
+
+	parts := make([]string, 0, 5)
+	if s.Instance != nil {
+		parts = append(parts, ok(i18n.Tf("进程 %d", s.Instance.PID)))
+	} else {
+		parts = append(parts, off(i18n.T("未运行")))
+	}
+	switch {
+	case s.KeyValid:
+		parts = append(parts, ok(i18n.T("密钥")))
+	case s.KeyCached:
+		parts = append(parts, warn(i18n.T("密钥无效")))
+	default:
+		parts = append(parts, off(i18n.T("无密钥")))
+	}
+	switch {
+	case s.WorkDirExists && !s.LastDecrypt.IsZero():
+		parts = append(parts, ok(i18n.T("解密于 ")+s.LastDecrypt.Format("01-02 15:04")))
+	case s.WorkDirExists:
+		parts = append(parts, warn(i18n.T("未解密")))
+	default:
+		parts = append(parts, off(i18n.T("无工作目录")))
+	}
+	if s.HTTPBound {
+		parts = append(parts, ok("HTTP "+tview.Escape(s.HTTPAddr)))
+	} else {
+		parts = append(parts, off("HTTP"))
+	}
+	return strings.Join(parts, "  ")
+}
+
+// watchProgress 在模态框中显示解密与获取密钥的进度，返回的函数停止更新，需在设置最终结果前调用
+func (a *App) watchProgress(modal *tview.Modal, title string) func() {
+	events, cancel := progress.Subscribe()
+	var stopped atomic.Bool
+	go func() {
+		view := progressbar.New()
+		for e := range events {
+			view.Update(e)
+			text := view.Text(title)
+			a.QueueUpdateDraw(func() {
+				if !stopped.Load() {
+					modal.SetText(text)
+				}
+			})
+		}
+	}()
+	return func() {
+		stopped.Store(true)
+		cancel()
+	}
 }
 
 // showModal 显示一个模态对话框
@@ -862,9 +2071,35 @@ func (a *App) showModal(text string, buttons []string, doneFunc func(buttonIndex
 	a.SetFocus(modal)
 }
 
+// confirm 显示确认对话框说明操作的后果，选择 label 后执行 action，默认选中取消
+func (a *App) confirm(text, label string, action func()) {
+	modal := tview.NewModal().
+		SetText(text).
+		AddButtons([]string{label, i18n.T("取消")}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			a.mainPages.RemovePage("modal")
+			if buttonIndex == 0 {
+				action()
+			}
+		}).
+		SetFocus(1)
+
+	a.mainPages.AddPage("modal", modal, true, true)
+	a.SetFocus(modal)
+}
+
+// dirHasFiles 目录存在且不为空
+func dirHasFiles(dir string) bool {
+	if len(dir) == 0 {
+		return false
+	}
+	entries, err := os.ReadDir(dir)
+	return err == nil && len(entries) != 0
+}
+
 // showError 显示错误对话框
 func (a *App) showError(err error) {
-	a.showModal(err.Error(), []string{"OK"}, func(buttonIndex int, buttonLabel string) {
+	a.showModal(i18n.Tf("%s\n\n按 %s 查看日志", err.Error(), keymap.Label(keymap.Log)), []string{"OK"}, func(buttonIndex int, buttonLabel string) {
 		a.mainPages.RemovePage("modal")
 	})
 }
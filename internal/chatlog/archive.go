@@ -0,0 +1,51 @@
+package chatlog
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+	"github.com/DanielMao1/chatlog/internal/chatlog/database"
+	"github.com/DanielMao1/chatlog/internal/chatlog/vault"
+	"github.com/DanielMao1/chatlog/internal/errors"
+)
+
+// CommandVault 将聊天对象的新消息同步到笔记库，git 为 true 时只写入文字并在同步后提交到 git 仓库
+// dir 为空时同步到工作目录旁的 vault 目录，返回同步结果与新提交的 ID，没有新提交时为空
+func (m *Manager) CommandVault(configPath string, cmdConf map[string]any, dir string, talkers []string, git bool) (*vault.Result, string, error) {
+
+	var err error
+	m.sc, m.scm, err = conf.LoadServiceConfig(configPath, cmdConf)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(m.sc.GetWorkDir()) == 0 {
+		return nil, "", errors.ConfigRequired("workDir")
+	}
+	if len(talkers) == 0 {
+		return nil, "", fmt.Errorf("at least one talker is required")
+	}
+	if len(dir) == 0 {
+		dir = filepath.Join(filepath.Dir(m.sc.GetWorkDir()), "vault")
+	}
+
+	m.db = database.NewService(m.sc)
+	if err := m.db.Start(); err != nil {
+		return nil, "", err
+	}
+	defer m.db.Stop()
+
+	result, err := m.SyncVault(dir, talkers, !git)
+	if err != nil || !git {
+		return result, "", err
+	}
+	commit, err := vault.Commit(dir, VaultCommitMessage(result, time.Now()))
+	return result, commit, err
+}
+
+// VaultCommitMessage 返回提交笔记库时的提交说明
+func VaultCommitMessage(result *vault.Result, now time.Time) string {
+	return fmt.Sprintf("chatlog %s: %d messages in %d notes", now.Format("2006-01-02"), result.Messages, result.Files)
+}
@@ -0,0 +1,369 @@
+package backup
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+	"github.com/DanielMao1/chatlog/pkg/util"
+	"github.com/DanielMao1/chatlog/pkg/version"
+)
+
+const (
+	ManifestName = "manifest.json"
+	Ext          = ".zip"
+	EncryptedExt = ".zip.enc"
+
+	timeFormat = "20060102-150405"
+
+	// EnvPassword 备份密码的环境变量，避免密码出现在命令行历史与配置文件中
+	EnvPassword = "CHATLOG_BACKUP_PASSWORD"
+)
+
+// Manifest 备份中记录的账号与密钥信息，用于恢复时重新配置
+type Manifest struct {
+	Account     string    `json:"account"`
+	Platform    string    `json:"platform"`
+	Version     int       `json:"version"`
+	FullVersion string    `json:"full_version"`
+	DataDir     string    `json:"data_dir"`
+	DataKey     string    `json:"data_key"`
+	ImgKey      string    `json:"img_key"`
+	WorkDir     string    `json:"work_dir"`
+	Chatlog     string    `json:"chatlog"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Options 备份参数
+type Options struct {
+	WorkDir   string // 解密后的工作目录
+	ConfigDir string // chatlog 配置目录，为空时不备份配置
+	OutputDir string // 备份文件存放目录
+	Password  string // 非空时对备份文件加密
+	Keep      int    // 保留最近的备份数量，0 表示全部保留
+
+	// Remote 非空时将备份上传到远程存储，备份必须加密
+	Remote *conf.BackupRemote
+}
+
+// Result 备份结果
+type Result struct {
+	File    string   `json:"file"`
+	Size    int64    `json:"size"`
+	Removed []string `json:"removed,omitempty"`
+
+	Uploaded      string   `json:"uploaded,omitempty"` // 备份在远程存储中的位置
+	RemoteRemoved []string `json:"remote_removed,omitempty"`
+}
+
+// Create 将工作目录、配置文件和密钥清单打包为带时间戳的备份文件
+func Create(opts Options, manifest *Manifest) (*Result, error) {
+	if len(opts.WorkDir) == 0 {
+		return nil, fmt.Errorf("workDir is required")
+	}
+	if len(opts.OutputDir) == 0 {
+		return nil, fmt.Errorf("output dir is required")
+	}
+	if opts.Remote != nil && len(opts.Password) == 0 {
+		// 备份包含解密后的聊天记录与密钥，不上传未加密的备份
+		return nil, fmt.Errorf("backup_remote requires an encrypted backup, set a password or %s", EnvPassword)
+	}
+	if err := util.PrepareDir(opts.OutputDir); err != nil {
+		return nil, err
+	}
+
+	// 输出目录位于工作目录内时，避免把历史备份打包进去
+	outputDir, _ := filepath.Abs(opts.OutputDir)
+
+	manifest.Chatlog = version.Version
+	manifest.CreatedAt = time.Now()
+
+	prefix := filePrefix(manifest.Account)
+	name := prefix + manifest.CreatedAt.Format(timeFormat) + Ext
+	if len(opts.Password) != 0 {
+		name = prefix + manifest.CreatedAt.Format(timeFormat) + EncryptedExt
+	}
+	path := filepath.Join(opts.OutputDir, name)
+
+	// 先写入临时文件，完成后再重命名，避免留下不完整的备份
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp)
+
+	var w io.WriteCloser = nopCloser{f}
+	if len(opts.Password) != 0 {
+		if w, err = NewEncryptWriter(f, opts.Password); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+
+	if err := writeArchive(w, opts, outputDir, manifest); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return nil, err
+	}
+
+	ret := &Result{File: path}
+	if info, err := os.Stat(path); err == nil {
+		ret.Size = info.Size()
+	}
+
+	if opts.Keep > 0 {
+		ret.Removed, err = Prune(opts.OutputDir, manifest.Account, opts.Keep)
+		if err != nil {
+			return ret, err
+		}
+	}
+
+	if opts.Remote != nil {
+		ret.Uploaded, ret.RemoteRemoved, err = Upload(context.Background(), opts.Remote, path, manifest.Account)
+		if err != nil {
+			return ret, err
+		}
+	}
+
+	return ret, nil
+}
+
+func writeArchive(w io.Writer, opts Options, outputDir string, manifest *Manifest) error {
+	zw := zip.NewWriter(w)
+
+	mw, err := zw.CreateHeader(&zip.FileHeader{Name: ManifestName, Method: zip.Deflate, Modified: manifest.CreatedAt})
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(mw)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return err
+	}
+
+	if err := addDir(zw, opts.WorkDir, "workdir", outputDir); err != nil {
+		return err
+	}
+
+	if len(opts.ConfigDir) != 0 {
+		// 只备份配置文件，不包括日志等目录
+		entries, err := os.ReadDir(opts.ConfigDir)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		for _, e := range entries {
+			if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+				continue
+			}
+			if err := addFile(zw, filepath.Join(opts.ConfigDir, e.Name()), "config/"+e.Name()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return zw.Close()
+}
+
+func addDir(zw *zip.Writer, root, prefix, skip string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if abs, _ := filepath.Abs(path); abs == skip {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// 跳过 sqlite 的临时文件
+		if strings.HasSuffix(path, "-wal") || strings.HasSuffix(path, "-shm") {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		return addFile(zw, path, prefix+"/"+filepath.ToSlash(rel))
+	})
+}
+
+func addFile(zw *zip.Writer, path, name string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	header.Method = zip.Deflate
+
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// ExtractWorkDir 将备份中的工作目录解压到 dst，加密的备份需要提供密码
+func ExtractWorkDir(file, password, dst string) error {
+	path := file
+	if strings.HasSuffix(file, EncryptedExt) {
+		if len(password) == 0 {
+			return fmt.Errorf("password is required for encrypted backup")
+		}
+		tmp, err := os.CreateTemp("", "chatlog-backup-*"+Ext)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+
+		in, err := os.Open(file)
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		err = Decrypt(in, tmp, password)
+		in.Close()
+		if cerr := tmp.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+		path = tmp.Name()
+	}
+
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	root := filepath.Clean(dst) + string(os.PathSeparator)
+	for _, f := range zr.File {
+		name, ok := strings.CutPrefix(f.Name, "workdir/")
+		if !ok || len(name) == 0 || strings.HasSuffix(name, "/") {
+			continue
+		}
+		target := filepath.Join(dst, filepath.FromSlash(name))
+		if !strings.HasPrefix(target, root) {
+			return fmt.Errorf("invalid file name in backup: %s", f.Name)
+		}
+		if err := extractFile(f, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractFile(f *zip.File, target string) error {
+	if err := util.PrepareDir(filepath.Dir(target)); err != nil {
+		return err
+	}
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// Prune 删除同一账号较早的备份，只保留最近 keep 个
+func Prune(dir, account string, keep int) ([]string, error) {
+	files, err := List(dir, account)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) <= keep {
+		return nil, nil
+	}
+
+	removed := make([]string, 0)
+	for _, file := range files[:len(files)-keep] {
+		if err := os.Remove(file); err != nil {
+			return removed, err
+		}
+		log.Info().Msgf("removed old backup %s", file)
+		removed = append(removed, file)
+	}
+	return removed, nil
+}
+
+// List 返回指定账号的备份文件，按时间从旧到新排序
+func List(dir, account string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	prefix := filePrefix(account)
+	files := make([]string, 0)
+	for _, e := range entries {
+		if e.IsDir() || !isBackupName(prefix, e.Name()) {
+			continue
+		}
+		files = append(files, filepath.Join(dir, e.Name()))
+	}
+	// 文件名中的时间戳可以直接按字典序排序
+	sort.Strings(files)
+	return files, nil
+}
+
+// isBackupName 判断文件名是否为该账号的备份，文件名剩余部分必须是时间戳，避免误匹配名称相近的其他账号
+func isBackupName(prefix, name string) bool {
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	ts := strings.TrimSuffix(strings.TrimSuffix(name[len(prefix):], EncryptedExt), Ext)
+	_, err := time.Parse(timeFormat, ts)
+	return err == nil
+}
+
+func filePrefix(account string) string {
+	if len(account) == 0 {
+		return "chatlog-"
+	}
+	return "chatlog-" + account + "-"
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
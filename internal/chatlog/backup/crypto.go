@@ -0,0 +1,182 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// 加密文件格式：
+//
+//	magic(8) | salt(16) | nonce(12) | chunk...
+//	chunk: length(4) | ciphertext(length)
+//
+// 每个分块使用 AES-256-GCM 独立加密，nonce 为基础 nonce 与分块序号异或，
+// 附加数据中标记是否为最后一块，防止分块被重排或截断
+const (
+	magic      = "CLBAK001"
+	saltSize   = 16
+	chunkSize  = 1 << 20
+	iterations = 100000
+)
+
+var ErrInvalidPassword = errors.New("invalid password or corrupted backup")
+
+func deriveKey(password string, salt []byte) (cipher.AEAD, error) {
+	key := pbkdf2.Key([]byte(password), salt, iterations, 32, sha256.New)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(base []byte, seq uint64) []byte {
+	nonce := make([]byte, len(base))
+	copy(nonce, base)
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], seq)
+	for i := 0; i < 8; i++ {
+		nonce[len(nonce)-8+i] ^= b[i]
+	}
+	return nonce
+}
+
+func chunkAD(last bool) []byte {
+	if last {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+// EncryptWriter 分块加密写入，Close 时写入最后一块
+type EncryptWriter struct {
+	w     io.Writer
+	aead  cipher.AEAD
+	nonce []byte
+	seq   uint64
+	buf   []byte
+}
+
+func NewEncryptWriter(w io.Writer, password string) (*EncryptWriter, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := deriveKey(password, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	header := append(append([]byte(magic), salt...), nonce...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &EncryptWriter{
+		w:     w,
+		aead:  aead,
+		nonce: nonce,
+		buf:   make([]byte, 0, chunkSize),
+	}, nil
+}
+
+func (e *EncryptWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		// 缓冲区已满且仍有数据时才写出，保证最后一块在 Close 时写出
+		if len(e.buf) == chunkSize {
+			if err := e.flush(false); err != nil {
+				return n, err
+			}
+		}
+		m := copy(e.buf[len(e.buf):chunkSize], p)
+		e.buf = e.buf[:len(e.buf)+m]
+		p = p[m:]
+		n += m
+	}
+	return n, nil
+}
+
+func (e *EncryptWriter) Close() error {
+	return e.flush(true)
+}
+
+func (e *EncryptWriter) flush(last bool) error {
+	ct := e.aead.Seal(nil, chunkNonce(e.nonce, e.seq), e.buf, chunkAD(last))
+	var l [4]byte
+	binary.BigEndian.PutUint32(l[:], uint32(len(ct)))
+	if _, err := e.w.Write(l[:]); err != nil {
+		return err
+	}
+	if _, err := e.w.Write(ct); err != nil {
+		return err
+	}
+	e.seq++
+	e.buf = e.buf[:0]
+	return nil
+}
+
+// Decrypt 解密备份文件，输出原始 zip 内容
+func Decrypt(r io.Reader, w io.Writer, password string) error {
+	br := bufio.NewReader(r)
+
+	header := make([]byte, len(magic)+saltSize)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return fmt.Errorf("read header failed: %w", err)
+	}
+	if !bytes.Equal(header[:len(magic)], []byte(magic)) {
+		return fmt.Errorf("not an encrypted chatlog backup")
+	}
+	aead, err := deriveKey(password, header[len(magic):])
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(br, nonce); err != nil {
+		return fmt.Errorf("read header failed: %w", err)
+	}
+
+	maxChunk := uint32(chunkSize + aead.Overhead())
+	for seq := uint64(0); ; seq++ {
+		var l [4]byte
+		if _, err := io.ReadFull(br, l[:]); err != nil {
+			// 没有读到最后一块，文件被截断
+			return ErrInvalidPassword
+		}
+		n := binary.BigEndian.Uint32(l[:])
+		if n > maxChunk {
+			return ErrInvalidPassword
+		}
+		ct := make([]byte, n)
+		if _, err := io.ReadFull(br, ct); err != nil {
+			return ErrInvalidPassword
+		}
+
+		_, err := br.Peek(1)
+		last := err == io.EOF
+		pt, err := aead.Open(nil, chunkNonce(nonce, seq), ct, chunkAD(last))
+		if err != nil {
+			return ErrInvalidPassword
+		}
+		if _, err := w.Write(pt); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	for _, size := range []int{0, 100, chunkSize, chunkSize*2 + 7} {
+		data := make([]byte, size)
+		rand.Read(data)
+
+		var enc bytes.Buffer
+		w, err := NewEncryptWriter(&enc, "secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		var dec bytes.Buffer
+		if err := Decrypt(bytes.NewReader(enc.Bytes()), &dec, "secret"); err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if !bytes.Equal(dec.Bytes(), data) {
+			t.Fatalf("size %d: decrypted data mismatch", size)
+		}
+
+		if err := Decrypt(bytes.NewReader(enc.Bytes()), &bytes.Buffer{}, "wrong"); err != ErrInvalidPassword {
+			t.Fatalf("size %d: expected ErrInvalidPassword with wrong password, got %v", size, err)
+		}
+
+		// 截断到分块边界时也应当报错
+		if size > chunkSize {
+			truncated := enc.Bytes()[:len(magic)+saltSize+12+4+chunkSize+16]
+			if err := Decrypt(bytes.NewReader(truncated), &bytes.Buffer{}, "secret"); err != ErrInvalidPassword {
+				t.Fatalf("size %d: expected error for truncated backup, got %v", size, err)
+			}
+		}
+	}
+}
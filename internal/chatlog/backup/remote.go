@@ -0,0 +1,81 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+)
+
+// Remote 存放备份的远程存储
+type Remote interface {
+	// Upload 将本地文件上传为 name
+	Upload(ctx context.Context, name, path string) error
+	// List 返回远程存储中的文件名
+	List(ctx context.Context) ([]string, error)
+	// Delete 删除远程文件，文件不存在时不报错
+	Delete(ctx context.Context, name string) error
+	// Location 返回文件在远程存储中的位置，用于显示
+	Location(name string) string
+}
+
+// NewRemote 按 backup_remote 配置创建远程存储
+func NewRemote(c *conf.BackupRemote) (Remote, error) {
+	if c == nil {
+		return nil, fmt.Errorf("backup_remote is not configured")
+	}
+	switch strings.ToLower(c.Type) {
+	case "s3":
+		return newS3(c)
+	case "webdav":
+		return newWebDAV(c)
+	}
+	return nil, fmt.Errorf("unsupported backup_remote.type: %q, use s3 or webdav", c.Type)
+}
+
+// Upload 上传备份文件，并按 backup_remote.keep 删除远程较早的同账号备份，返回上传的位置与删除的文件
+func Upload(ctx context.Context, c *conf.BackupRemote, file, account string) (string, []string, error) {
+	remote, err := NewRemote(c)
+	if err != nil {
+		return "", nil, err
+	}
+	name := filepath.Base(file)
+	if err := remote.Upload(ctx, name, file); err != nil {
+		return "", nil, fmt.Errorf("upload backup: %w", err)
+	}
+	location := remote.Location(name)
+	log.Info().Str("location", location).Msg("backup uploaded")
+	if c.Keep <= 0 {
+		return location, nil, nil
+	}
+
+	names, err := remote.List(ctx)
+	if err != nil {
+		return location, nil, fmt.Errorf("list remote backups: %w", err)
+	}
+	prefix := filePrefix(account)
+	files := make([]string, 0, len(names))
+	for _, n := range names {
+		if isBackupName(prefix, n) {
+			files = append(files, n)
+		}
+	}
+	sort.Strings(files)
+	if len(files) <= c.Keep {
+		return location, nil, nil
+	}
+	removed := make([]string, 0)
+	for _, n := range files[:len(files)-c.Keep] {
+		if err := remote.Delete(ctx, n); err != nil {
+			return location, removed, fmt.Errorf("delete remote backup %s: %w", n, err)
+		}
+		log.Info().Msgf("removed old remote backup %s", remote.Location(n))
+		removed = append(removed, remote.Location(n))
+	}
+	return location, removed, nil
+}
@@ -0,0 +1,282 @@
+package backup
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+)
+
+// s3DefaultRegion 未配置区域时使用的区域
+const s3DefaultRegion = "us-east-1"
+
+// s3Remote S3 兼容的对象存储，使用 path-style 地址与 Signature V4 签名
+type s3Remote struct {
+	endpoint  *url.URL
+	bucket    string
+	region    string
+	prefix    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+}
+
+func newS3(c *conf.BackupRemote) (*s3Remote, error) {
+	if len(c.Bucket) == 0 {
+		return nil, fmt.Errorf("backup_remote.bucket is required")
+	}
+	if len(c.AccessKey) == 0 || len(c.SecretKey) == 0 {
+		return nil, fmt.Errorf("backup_remote.access_key and secret_key are required")
+	}
+	region := c.Region
+	if len(region) == 0 {
+		region = s3DefaultRegion
+	}
+	endpoint := c.URL
+	if len(endpoint) == 0 {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	u, err := url.Parse(strings.TrimRight(endpoint, "/"))
+	if err != nil || len(u.Host) == 0 {
+		return nil, fmt.Errorf("invalid backup_remote.url: %s", endpoint)
+	}
+	return &s3Remote{
+		endpoint:  u,
+		bucket:    c.Bucket,
+		region:    region,
+		prefix:    c.Prefix,
+		accessKey: c.AccessKey,
+		secretKey: c.SecretKey,
+		client:    &http.Client{},
+	}, nil
+}
+
+func (r *s3Remote) Location(name string) string {
+	return "s3://" + r.bucket + "/" + r.prefix + name
+}
+
+// objectURL 返回对象的地址，key 为空时为存储桶的地址
+func (r *s3Remote) objectURL(key string, query url.Values) *url.URL {
+	u := *r.endpoint
+	u.Path = u.Path + "/" + r.bucket
+	if len(key) != 0 {
+		u.Path += "/" + key
+	}
+	u.RawPath = awsEscapePath(u.Path)
+	u.RawQuery = canonicalQuery(query)
+	return &u
+}
+
+func (r *s3Remote) Upload(ctx context.Context, name, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, r.objectURL(r.prefix+name, nil).String(), f)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/octet-stream")
+	_, err = r.do(req, hex.EncodeToString(h.Sum(nil)))
+	return err
+}
+
+func (r *s3Remote) List(ctx context.Context) ([]string, error) {
+	var names []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {r.prefix}}
+		if len(token) != 0 {
+			query.Set("continuation-token", token)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.objectURL("", query).String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		body, err := r.do(req, emptySHA256)
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Contents []struct {
+				Key string
+			}
+			IsTruncated           bool
+			NextContinuationToken string
+		}
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("invalid list response: %v", err)
+		}
+		for _, c := range result.Contents {
+			name := strings.TrimPrefix(c.Key, r.prefix)
+			if len(name) != 0 && !strings.Contains(name, "/") {
+				names = append(names, name)
+			}
+		}
+		if !result.IsTruncated || len(result.NextContinuationToken) == 0 {
+			return names, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+func (r *s3Remote) Delete(ctx context.Context, name string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, r.objectURL(r.prefix+name, nil).String(), nil)
+	if err != nil {
+		return err
+	}
+	_, err = r.do(req, emptySHA256)
+	return err
+}
+
+// do 签名并发送请求，返回响应内容，状态码不是 2xx 时返回 S3 的错误信息
+func (r *s3Remote) do(req *http.Request, payloadHash string) ([]byte, error) {
+	signV4(req, payloadHash, r.accessKey, r.secretKey, r.region, "s3", time.Now())
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Code    string
+			Message string
+		}
+		if xml.Unmarshal(body, &e) == nil && len(e.Code) != 0 {
+			return nil, fmt.Errorf("s3 %s: %s", e.Code, e.Message)
+		}
+		return nil, fmt.Errorf("s3 status %s", resp.Status)
+	}
+	return body, nil
+}
+
+// emptySHA256 空请求体的 SHA256
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// signV4 按 AWS Signature Version 4 为请求签名，签名包括 host 与请求中已设置的所有请求头
+func signV4(req *http.Request, payloadHash, accessKey, secretKey, region, service string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	host := req.Host
+	if len(host) == 0 {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var canonicalHeaders strings.Builder
+	for _, k := range keys {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(keys, ";")
+
+	path := req.URL.EscapedPath()
+	if len(path) == 0 {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	sum := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKey+"/"+scope+
+		",SignedHeaders="+signedHeaders+",Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// canonicalQuery 按参数名排序并编码查询参数
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, awsEscape(k)+"="+awsEscape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsEscapePath 编码路径，保留 /
+func awsEscapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		segments[i] = awsEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// awsEscape 除字母、数字与 -._~ 外都按 UTF-8 编码为 %XX
+func awsEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
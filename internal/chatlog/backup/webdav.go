@@ -0,0 +1,138 @@
+package backup
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+)
+
+// webdavRemote WebDAV 服务器上的目录，如 NAS、Nextcloud
+type webdavRemote struct {
+	base     *url.URL // 以 / 结尾
+	username string
+	password string
+	client   *http.Client
+}
+
+func newWebDAV(c *conf.BackupRemote) (*webdavRemote, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil || len(u.Host) == 0 {
+		return nil, fmt.Errorf("invalid backup_remote.url: %s", c.URL)
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+		u.RawPath = ""
+	}
+	return &webdavRemote{base: u, username: c.Username, password: c.Password, client: &http.Client{}}, nil
+}
+
+func (r *webdavRemote) Location(name string) string {
+	return r.fileURL(name)
+}
+
+func (r *webdavRemote) fileURL(name string) string {
+	return r.base.JoinPath(name).String()
+}
+
+func (r *webdavRemote) Upload(ctx context.Context, name, file string) error {
+	// 目录不存在时创建，已存在时服务器返回 405，忽略
+	if resp, err := r.do(ctx, "MKCOL", r.base.String(), nil, 0, nil); err == nil {
+		resp.Body.Close()
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	resp, err := r.do(ctx, http.MethodPut, r.fileURL(name), f, info.Size(), map[string]string{"Content-Type": "application/octet-stream"})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webdav status %s", resp.Status)
+	}
+	return nil
+}
+
+func (r *webdavRemote) List(ctx context.Context) ([]string, error) {
+	body := `<?xml version="1.0" encoding="utf-8"?><propfind xmlns="DAV:"><prop><resourcetype/></prop></propfind>`
+	resp, err := r.do(ctx, "PROPFIND", r.base.String(), strings.NewReader(body), int64(len(body)),
+		map[string]string{"Depth": "1", "Content-Type": "application/xml; charset=utf-8"})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, fmt.Errorf("webdav status %s", resp.Status)
+	}
+	var result struct {
+		Responses []struct {
+			Href string `xml:"href"`
+		} `xml:"response"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid propfind response: %v", err)
+	}
+	names := make([]string, 0, len(result.Responses))
+	for _, res := range result.Responses {
+		href := res.Href
+		if u, err := url.Parse(href); err == nil {
+			href = u.Path
+		}
+		// 第一条为目录本身
+		if strings.HasSuffix(href, "/") {
+			continue
+		}
+		names = append(names, path.Base(href))
+	}
+	return names, nil
+}
+
+func (r *webdavRemote) Delete(ctx context.Context, name string) error {
+	resp, err := r.do(ctx, http.MethodDelete, r.fileURL(name), nil, 0, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("webdav status %s", resp.Status)
+	}
+	return nil
+}
+
+func (r *webdavRemote) do(ctx context.Context, method, u string, body io.Reader, size int64, headers map[string]string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if len(r.username) != 0 {
+		req.SetBasicAuth(r.username, r.password)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		return nil, fmt.Errorf("webdav authentication failed")
+	}
+	return resp, nil
+}
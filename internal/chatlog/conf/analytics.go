@@ -0,0 +1,11 @@
+package conf
+
+import "time"
+
+// Analytics 按月分析聊天对象的情感与话题
+type Analytics struct {
+	// Talkers 在后台定期分析并保存结果的聊天对象，其他聊天对象在请求时分析
+	Talkers []string `mapstructure:"talkers" json:"talkers"`
+	// Interval 更新分析结果的间隔，默认 6h
+	Interval time.Duration `mapstructure:"interval" json:"interval"`
+}
@@ -0,0 +1,20 @@
+package conf
+
+// BackupRemote 加密备份完成后上传的远程存储
+type BackupRemote struct {
+	// Type 存储类型：s3（S3 兼容的对象存储，如 AWS S3、MinIO、Cloudflare R2）或 webdav
+	Type string `mapstructure:"type" json:"type"`
+	// URL S3 为服务地址，默认 https://s3.<region>.amazonaws.com；WebDAV 为存放备份的目录地址
+	URL string `mapstructure:"url" json:"url"`
+	// Bucket、Region、Prefix S3 的存储桶、区域（默认 us-east-1）与对象名前缀，如 chatlog/
+	Bucket    string `mapstructure:"bucket" json:"bucket"`
+	Region    string `mapstructure:"region" json:"region"`
+	Prefix    string `mapstructure:"prefix" json:"prefix"`
+	AccessKey string `mapstructure:"access_key" json:"access_key"`
+	SecretKey string `mapstructure:"secret_key" json:"-"` // 不写入日志
+	// Username、Password WebDAV 的用户名与密码
+	Username string `mapstructure:"username" json:"username"`
+	Password string `mapstructure:"password" json:"-"` // 不写入日志
+	// Keep 远程保留最近的备份数量，0 表示全部保留
+	Keep int `mapstructure:"keep" json:"keep"`
+}
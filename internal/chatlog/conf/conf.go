@@ -2,10 +2,13 @@ package conf
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/rs/zerolog/log"
+	"github.com/DanielMao1/chatlog/internal/errors"
 	"github.com/DanielMao1/chatlog/pkg/config"
 )
 
@@ -16,6 +19,18 @@ const (
 	EnvConfigDir     = "CHATLOG_DIR"
 )
 
+// ConfigDir 返回默认的配置目录，优先使用环境变量 CHATLOG_DIR
+func ConfigDir() string {
+	if dir := os.Getenv(EnvConfigDir); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = os.TempDir()
+	}
+	return filepath.Join(home, "."+AppName)
+}
+
 // LoadTUIConfig 加载 TUI 配置
 func LoadTUIConfig(configPath string) (*TUIConfig, *config.Manager, error) {
 
@@ -54,7 +69,7 @@ func LoadServiceConfig(configPath string, cmdConf map[string]any) (*ServerConfig
 	scm, err := config.New(AppName, configPath, ServerConfigName, EnvPrefix, false)
 	if err != nil {
 		log.Error().Err(err).Msg("load server config failed")
-		return nil, nil, err
+		return nil, nil, errors.ConfigInvalid(err)
 	}
 
 	conf := &ServerConfig{}
@@ -67,7 +82,29 @@ func LoadServiceConfig(configPath string, cmdConf map[string]any) (*ServerConfig
 
 	if err := scm.Load(conf); err != nil {
 		log.Error().Err(err).Msg("load server config failed")
-		return nil, nil, err
+		return nil, nil, errors.ConfigInvalid(err)
+	}
+
+	// Load Profile config
+	if len(conf.Profile) != 0 {
+		if err := applyProfile(scm, conf.Profile, cmdConf); err != nil {
+			return nil, nil, errors.ConfigInvalid(err)
+		}
+		if err := scm.Load(conf); err != nil {
+			log.Error().Err(err).Msg("reload server config failed")
+			return nil, nil, errors.ConfigInvalid(err)
+		}
+	}
+
+	// Load Account config
+	if len(conf.Account) != 0 {
+		if err := applyAccount(scm, configPath, conf.Account, cmdConf); err != nil {
+			return nil, nil, errors.ConfigInvalid(err)
+		}
+		if err := scm.Load(conf); err != nil {
+			log.Error().Err(err).Msg("reload server config failed")
+			return nil, nil, errors.ConfigInvalid(err)
+		}
 	}
 
 	// Load Data Dir config
@@ -76,7 +113,7 @@ func LoadServiceConfig(configPath string, cmdConf map[string]any) (*ServerConfig
 			var pconf map[string]any
 			if err := json.Unmarshal(b, &pconf); err == nil {
 				for key, value := range pconf {
-					if !DataDirConfigs[key] {
+					if !DataDirConfigs[key] || envSet(key) {
 						continue
 					}
 					scm.SetConfig(key, value)
@@ -85,7 +122,7 @@ func LoadServiceConfig(configPath string, cmdConf map[string]any) (*ServerConfig
 		}
 		if err := scm.Load(conf); err != nil {
 			log.Error().Err(err).Msg("reload server config failed")
-			return nil, nil, err
+			return nil, nil, errors.ConfigInvalid(err)
 		}
 	}
 
@@ -95,6 +132,67 @@ func LoadServiceConfig(configPath string, cmdConf map[string]any) (*ServerConfig
 	return conf, scm, nil
 }
 
+// applyProfile 使用 profiles.<name> 中的配置覆盖顶层配置
+// 优先级：命令行参数 > 环境变量 > profile > 顶层配置
+func applyProfile(scm *config.Manager, name string, cmdConf map[string]any) error {
+	key := "profiles." + name
+	if !scm.Viper.IsSet(key) {
+		return fmt.Errorf("profile not found: %s", name)
+	}
+	for k, v := range scm.Viper.GetStringMap(key) {
+		if _, ok := cmdConf[k]; ok || envSet(k) {
+			continue
+		}
+		scm.SetConfig(k, v)
+	}
+	return nil
+}
+
+// applyAccount 使用历史账号中记录的平台、目录和密钥覆盖当前配置
+// 优先级：命令行参数 > 环境变量 > 历史账号 > profile > 顶层配置
+func applyAccount(scm *config.Manager, configPath string, account string, cmdConf map[string]any) error {
+	tc, _, err := LoadTUIConfig(configPath)
+	if err != nil {
+		return err
+	}
+	h, err := tc.FindHistory(account)
+	if err != nil {
+		return err
+	}
+	values := map[string]any{
+		"platform":     h.Platform,
+		"version":      h.Version,
+		"full_version": h.FullVersion,
+		"data_dir":     h.DataDir,
+		"data_key":     h.DataKey,
+		"img_key":      h.ImgKey,
+		"work_dir":     h.WorkDir,
+	}
+	for k, v := range values {
+		if _, ok := cmdConf[k]; ok || envSet(k) {
+			continue
+		}
+		if v == "" || v == 0 {
+			continue
+		}
+		scm.SetConfig(k, v)
+	}
+	return nil
+}
+
+// EnvName 返回配置项对应的环境变量名，嵌套配置项的 . 替换为 _，如 prune.stale 对应 CHATLOG_PRUNE_STALE
+// 与 config.New 中 viper 的 AutomaticEnv 规则一致
+func EnvName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// envSet 判断配置项是否已通过 CHATLOG_* 环境变量设置
+// SetConfig 的优先级高于环境变量，写入 profile 等配置前需要先检查
+func envSet(key string) bool {
+	_, ok := os.LookupEnv(EnvName(key))
+	return ok
+}
+
 var DataDirConfigs = map[string]bool{
 	"type":         true,
 	"platform":     true,
@@ -103,3 +201,42 @@ var DataDirConfigs = map[string]bool{
 	"data_key":     true,
 	"img_key":      true,
 }
+
+// SaveServiceConfig 将配置写入 chatlog-server.json 并返回文件路径，profile 非空时写入 profiles.<profile>
+// 文件中已有的其他配置（webhook、其他 profile 等）保持不变
+func SaveServiceConfig(configPath string, profile string, values map[string]any) (string, error) {
+
+	if configPath == "" {
+		configPath = os.Getenv(EnvConfigDir)
+	}
+
+	// 不读取环境变量，避免把 CHATLOG_* 的值写入文件
+	scm, err := config.New(AppName, configPath, ServerConfigName, "", false)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(scm.Path, ServerConfigName+"."+config.DefaultConfigType)
+	if _, err := os.Stat(path); err == nil {
+		if err := scm.Viper.ReadInConfig(); err != nil {
+			return "", err
+		}
+	}
+
+	prefix := ""
+	if len(profile) != 0 {
+		prefix = "profiles." + profile + "."
+	}
+	for key, value := range values {
+		scm.Viper.Set(prefix+key, value)
+	}
+
+	if err := scm.Viper.WriteConfigAs(path); err != nil {
+		return "", err
+	}
+	// 配置中包含密钥
+	if err := os.Chmod(path, 0600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
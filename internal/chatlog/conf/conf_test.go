@@ -0,0 +1,179 @@
+package conf
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeServerConfig 在 dir 中写入 chatlog-server.json
+func writeServerConfig(t *testing.T, dir string, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, ServerConfigName+".json"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	cases := map[string]string{
+		"data_dir":              "CHATLOG_DATA_DIR",
+		"auth_token":            "CHATLOG_AUTH_TOKEN",
+		"auto_decrypt_interval": "CHATLOG_AUTO_DECRYPT_INTERVAL",
+		"prune.stale":           "CHATLOG_PRUNE_STALE",
+		"summarize.url":         "CHATLOG_SUMMARIZE_URL",
+	}
+	for key, want := range cases {
+		if got := EnvName(key); got != want {
+			t.Errorf("EnvName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestEnvMapping(t *testing.T) {
+	dir := t.TempDir()
+	envs := map[string]string{
+		"CHATLOG_TYPE":                  "wechat",
+		"CHATLOG_PLATFORM":              "darwin",
+		"CHATLOG_VERSION":               "4",
+		"CHATLOG_FULL_VERSION":          "4.0.3.22",
+		"CHATLOG_DATA_DIR":              "/data",
+		"CHATLOG_DATA_KEY":              "datakey",
+		"CHATLOG_IMG_KEY":               "imgkey",
+		"CHATLOG_WORK_DIR":              "/work",
+		"CHATLOG_HTTP_ADDR":             "0.0.0.0:8080",
+		"CHATLOG_AUTO_DECRYPT":          "true",
+		"CHATLOG_JOBS":                  "3",
+		"CHATLOG_AUTH_TOKEN":            "secret",
+		"CHATLOG_AUTO_DECRYPT_INTERVAL": "5s",
+		"CHATLOG_PRUNE_KEEP_BACKUPS":    "2",
+		"CHATLOG_PRUNE_STALE":           "true",
+		"CHATLOG_LLM_PROVIDER":          "ollama",
+		"CHATLOG_LLM_MODEL":             "qwen2.5",
+	}
+	for k, v := range envs {
+		t.Setenv(k, v)
+	}
+
+	c, _, err := LoadServiceConfig(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Type != "wechat" || c.Platform != "darwin" || c.Version != 4 || c.FullVersion != "4.0.3.22" {
+		t.Errorf("unexpected account fields: %+v", c)
+	}
+	if c.DataDir != "/data" || c.WorkDir != "/work" || c.DataKey != "datakey" || c.ImgKey != "imgkey" {
+		t.Errorf("unexpected dirs or keys: %+v", c)
+	}
+	if c.HTTPAddr != "0.0.0.0:8080" || !c.AutoDecrypt || c.Jobs != 3 || c.AuthToken != "secret" {
+		t.Errorf("unexpected server fields: %+v", c)
+	}
+	if c.AutoDecryptInterval != 5*time.Second {
+		t.Errorf("AutoDecryptInterval = %v, want 5s", c.AutoDecryptInterval)
+	}
+	if p := c.GetPrune(); p.KeepBackups != 2 || !p.Stale {
+		t.Errorf("unexpected prune config: %+v", p)
+	}
+	if l := c.GetLLM(); l == nil || l.Provider != "ollama" || l.Model != "qwen2.5" {
+		t.Errorf("unexpected llm config: %+v", l)
+	}
+}
+
+func TestEnvPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	writeServerConfig(t, dir, `{
+		"data_dir": "/file/data",
+		"work_dir": "/file/work",
+		"http_addr": "127.0.0.1:5030",
+		"data_key": "filekey",
+		"profiles": {
+			"nas": { "data_key": "profilekey", "img_key": "profileimg", "auth_token": "profiletoken" }
+		}
+	}`)
+	t.Setenv("CHATLOG_DATA_DIR", "/env/data")
+	t.Setenv("CHATLOG_WORK_DIR", "/env/work")
+	t.Setenv("CHATLOG_DATA_KEY", "envkey")
+	t.Setenv("CHATLOG_AUTH_TOKEN", "envtoken")
+
+	c, _, err := LoadServiceConfig(dir, map[string]any{
+		"work_dir":   "/cmd/work",
+		"auth_token": "cmdtoken",
+		"profile":    "nas",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 命令行参数 > 环境变量
+	if c.WorkDir != "/cmd/work" || c.AuthToken != "cmdtoken" {
+		t.Errorf("command line should override env, got work_dir=%q auth_token=%q", c.WorkDir, c.AuthToken)
+	}
+	// 环境变量 > 配置文件
+	if c.DataDir != "/env/data" {
+		t.Errorf("env should override file, got data_dir=%q", c.DataDir)
+	}
+	// 环境变量 > profile
+	if c.DataKey != "envkey" {
+		t.Errorf("env should override profile, got data_key=%q", c.DataKey)
+	}
+	// profile > 配置文件
+	if c.ImgKey != "profileimg" {
+		t.Errorf("profile should apply when env is unset, got img_key=%q", c.ImgKey)
+	}
+	// 未覆盖的配置项保持文件中的值
+	if c.HTTPAddr != "127.0.0.1:5030" {
+		t.Errorf("file value lost, got http_addr=%q", c.HTTPAddr)
+	}
+}
+
+func TestEnvOverridesDataDirConfig(t *testing.T) {
+	dir, dataDir := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(dataDir, "chatlog.json"), []byte(`{"platform": "windows", "version": 3}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	writeServerConfig(t, dir, `{"data_dir": "`+filepath.ToSlash(dataDir)+`"}`)
+	t.Setenv("CHATLOG_PLATFORM", "darwin")
+
+	c, _, err := LoadServiceConfig(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Platform != "darwin" {
+		t.Errorf("env should override chatlog.json in data dir, got platform=%q", c.Platform)
+	}
+	if c.Version != 3 {
+		t.Errorf("chatlog.json in data dir should apply when env is unset, got version=%d", c.Version)
+	}
+}
+
+func TestConfigLogOmitsSecrets(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(EnvConfigDir, "")
+	t.Setenv("CHATLOG_AUTH_TOKEN", "supersecret123")
+	writeServerConfig(t, dir, `{
+		"summarize": {"url": "http://localhost/summary", "headers": {"Authorization": "Bearer summarytoken"}},
+		"destinations": {"bot": {"url": "http://localhost/bot", "headers": {"X-Token": "desttoken"}, "secret": "signsecret"}}
+	}`)
+
+	c, _, err := LoadServiceConfig(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.AuthToken != "supersecret123" {
+		t.Fatalf("AuthToken = %q", c.AuthToken)
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"supersecret123", "summarytoken", "desttoken", "signsecret"} {
+		if strings.Contains(string(b), secret) {
+			t.Errorf("server config log contains %s: %s", secret, b)
+		}
+	}
+	if c.Destinations["bot"].Headers["x-token"] != "desttoken" {
+		t.Errorf("headers not loaded: %+v", c.Destinations["bot"])
+	}
+}
@@ -0,0 +1,18 @@
+package conf
+
+import "time"
+
+// Embedding 语义搜索使用的向量模型，未配置时不开启语义搜索
+type Embedding struct {
+	// Provider 向量模型接口：openai（OpenAI 兼容的 /embeddings 接口）、ollama、none，为空时等同于 none
+	Provider string `mapstructure:"provider" json:"provider"`
+	// URL 接口地址，openai 默认 https://api.openai.com/v1，ollama 默认 http://localhost:11434
+	URL     string        `mapstructure:"url" json:"url"`
+	Model   string        `mapstructure:"model" json:"model"`
+	APIKey  string        `mapstructure:"api_key" json:"-"`       // 不写入日志
+	Timeout time.Duration `mapstructure:"timeout" json:"timeout"` // 请求超时，默认 2m
+	// Talkers 建立索引的聊天对象，为空时为所有会话
+	Talkers []string `mapstructure:"talkers" json:"talkers"`
+	// Interval 更新索引的间隔，默认 10m
+	Interval time.Duration `mapstructure:"interval" json:"interval"`
+}
@@ -0,0 +1,14 @@
+package conf
+
+// KeyScan 扫描微信进程内存搜索密钥时的并发与内存参数，零值表示使用默认值
+// 核数少的电脑可以减少 workers 与 queue_size 降低占用，核数多的电脑可以增加 workers 加快扫描
+type KeyScan struct {
+	// Workers 搜索密钥的并发数，0 表示按 CPU 核数（最多 16）
+	Workers int `mapstructure:"workers" json:"workers"`
+	// QueueSize 读取后等待搜索的内存块数量，越大占用内存越多
+	QueueSize int `mapstructure:"queue_size" json:"queue_size"`
+	// ChunkSize 大内存区域拆分后每块的最小大小（MB），默认 4
+	ChunkSize int `mapstructure:"chunk_size" json:"chunk_size"`
+	// Overlap 相邻内存块与相邻内存区域之间重叠的字节数，默认 1024
+	Overlap int `mapstructure:"overlap" json:"overlap"`
+}
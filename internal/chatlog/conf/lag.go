@@ -0,0 +1,22 @@
+package conf
+
+import "time"
+
+// DefaultDecryptLagThreshold 未配置 threshold 时的告警阈值
+const DefaultDecryptLagThreshold = 10 * time.Minute
+
+// DecryptLagAlert 自动解密落后超过阈值时推送告警，恢复后再推送一次
+type DecryptLagAlert struct {
+	// Threshold 工作目录落后数据目录多久后告警，默认 10m
+	Threshold time.Duration `mapstructure:"threshold" json:"threshold"`
+	// Destination 接收告警的推送目标名称，见 destinations
+	Destination string `mapstructure:"destination" json:"destination"`
+}
+
+// GetThreshold 返回告警阈值，未配置时为 DefaultDecryptLagThreshold
+func (a *DecryptLagAlert) GetThreshold() time.Duration {
+	if a.Threshold <= 0 {
+		return DefaultDecryptLagThreshold
+	}
+	return a.Threshold
+}
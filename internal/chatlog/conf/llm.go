@@ -0,0 +1,18 @@
+package conf
+
+import "time"
+
+// LLM 生成总结等内容使用的大模型
+type LLM struct {
+	// Provider 大模型接口：openai（OpenAI 兼容接口）、ollama、none，为空时等同于 none
+	Provider string `mapstructure:"provider" json:"provider"`
+	// URL 接口地址，openai 默认 https://api.openai.com/v1，ollama 默认 http://localhost:11434
+	URL    string `mapstructure:"url" json:"url"`
+	Model  string `mapstructure:"model" json:"model"`
+	APIKey string `mapstructure:"api_key" json:"-"` // 不写入日志
+	// Prompt 系统提示词，为空时使用默认的总结提示词
+	Prompt  string        `mapstructure:"prompt" json:"prompt"`
+	Timeout time.Duration `mapstructure:"timeout" json:"timeout"` // 请求超时，默认 2m
+	// MaxChars 发送给大模型的聊天记录最大字数，超出时只发送最近的部分，默认 24000
+	MaxChars int `mapstructure:"max_chars" json:"max_chars"`
+}
@@ -0,0 +1,42 @@
+package conf
+
+import (
+	"os"
+
+	"github.com/DanielMao1/chatlog/pkg/config"
+)
+
+// Log 日志输出配置，零值表示以 info 级别输出到 stderr
+type Log struct {
+	// Level 日志级别，可以带各模块的级别，如 info,wechat=debug
+	Level string `mapstructure:"level" json:"level"`
+	// Modules 各模块的日志级别，模块名按包路径匹配，如 wechat、http、chatlog/database
+	Modules map[string]string `mapstructure:"modules" json:"modules"`
+	// Format 日志格式，console 或 json
+	Format string `mapstructure:"format" json:"format"`
+	// File 日志文件，为空时输出到 stderr
+	File string `mapstructure:"file" json:"file"`
+	// MaxSize 日志文件超过多少 MB 后轮转，默认 10
+	MaxSize int `mapstructure:"max_size" json:"max_size"`
+	// MaxBackups 保留的历史日志文件数量，默认 3
+	MaxBackups int `mapstructure:"max_backups" json:"max_backups"`
+}
+
+// LoadLog 读取服务配置文件中的 log 配置，可以用 CHATLOG_LOG_LEVEL 等环境变量覆盖
+// 在命令开始时、其他配置加载前调用，配置文件不存在或无效时返回零值
+func LoadLog() *Log {
+	scm, err := config.New(AppName, os.Getenv(EnvConfigDir), ServerConfigName, EnvPrefix, false)
+	if err != nil {
+		return &Log{}
+	}
+	v := scm.Viper
+	v.ReadInConfig()
+	return &Log{
+		Level:      v.GetString("log.level"),
+		Modules:    v.GetStringMapString("log.modules"),
+		Format:     v.GetString("log.format"),
+		File:       v.GetString("log.file"),
+		MaxSize:    v.GetInt("log.max_size"),
+		MaxBackups: v.GetInt("log.max_backups"),
+	}
+}
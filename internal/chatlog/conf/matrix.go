@@ -0,0 +1,11 @@
+package conf
+
+// Matrix 将聊天记录回放到 Matrix 房间时使用的账号
+type Matrix struct {
+	// Homeserver 服务器地址，如 https://matrix.org
+	Homeserver string `mapstructure:"homeserver" json:"homeserver"`
+	// AccessToken 账号的 access token，不写入日志
+	AccessToken string `mapstructure:"access_token" json:"-"`
+	// Room 默认回放到的房间 ID（!xxx:server）或别名（#xxx:server）
+	Room string `mapstructure:"room" json:"room"`
+}
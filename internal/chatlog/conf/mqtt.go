@@ -0,0 +1,33 @@
+package conf
+
+import "time"
+
+// MQTT 发布状态与事件使用的 MQTT 服务器
+type MQTT struct {
+	// Broker 服务器地址，如 tcp://192.168.1.10:1883，ssl:// 或 mqtts:// 使用 TLS
+	Broker   string `mapstructure:"broker" json:"broker"`
+	Username string `mapstructure:"username" json:"username"`
+	Password string `mapstructure:"password" json:"-"` // 不写入日志
+	// ClientID 客户端 ID，为空时为 chatlog- 加随机后缀
+	ClientID string `mapstructure:"client_id" json:"client_id"`
+	// QoS 发布消息的服务质量等级：0、1、2，默认 0
+	QoS int `mapstructure:"qos" json:"qos"`
+	// Topic 主题前缀，默认 chatlog
+	Topic string `mapstructure:"topic" json:"topic"`
+	// Messages 自动解密发现新消息时发布到 <topic>/messages/<聊天对象>
+	Messages bool `mapstructure:"messages" json:"messages"`
+	// Talkers 发布新消息的聊天对象，为空时发布所有聊天对象的新消息
+	Talkers []string `mapstructure:"talkers" json:"talkers"`
+}
+
+// HomeAssistant 在 Home Assistant 中显示 chatlog 的状态
+type HomeAssistant struct {
+	// Talkers 显示新消息数量与最后一条消息的聊天对象，为空时 HTTP 接口显示最近的会话，不通过 MQTT 发布
+	Talkers []string `mapstructure:"talkers" json:"talkers"`
+	// Discovery 配置了 mqtt 时通过 MQTT Discovery 自动创建传感器
+	Discovery bool `mapstructure:"discovery" json:"discovery"`
+	// DiscoveryPrefix Home Assistant 的 discovery 主题前缀，默认 homeassistant
+	DiscoveryPrefix string `mapstructure:"discovery_prefix" json:"discovery_prefix"`
+	// Interval 通过 MQTT 发布状态的间隔，默认 1m
+	Interval time.Duration `mapstructure:"interval" json:"interval"`
+}
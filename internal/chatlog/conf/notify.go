@@ -0,0 +1,12 @@
+package conf
+
+// NotifyRule 关注的聊天对象，自动解密发现其新消息时发出通知
+type NotifyRule struct {
+	Talker  string `mapstructure:"talker" json:"talker"`
+	Bell    bool   `mapstructure:"bell" json:"bell"`       // 终端响铃
+	Desktop bool   `mapstructure:"desktop" json:"desktop"` // 系统桌面通知，附带消息摘要
+	// Telegram 通过 Telegram 机器人发送通知，需要配置 telegram
+	Telegram bool `mapstructure:"telegram" json:"telegram"`
+	// Destination 推送目标名称，如 Slack、Discord 的 incoming webhook，为空时不推送
+	Destination string `mapstructure:"destination" json:"destination"`
+}
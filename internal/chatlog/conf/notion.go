@@ -0,0 +1,21 @@
+package conf
+
+// Notion 将聊天记录或每日总结写入 Notion 数据库时使用的 integration
+type Notion struct {
+	// Token integration 的 token（secret_xxx 或 ntn_xxx），不写入日志
+	Token string `mapstructure:"token" json:"-"`
+	// DatabaseID 写入的数据库 ID，需要先在数据库的「连接」中添加该 integration
+	DatabaseID string `mapstructure:"database_id" json:"database_id"`
+	// Properties 数据库中对应的属性名称，未配置时使用默认名称，数据库中不存在的属性不写入
+	Properties NotionProperties `mapstructure:"properties" json:"properties"`
+	// URL API 地址，默认 https://api.notion.com，可以使用反向代理
+	URL string `mapstructure:"url" json:"url"`
+}
+
+// NotionProperties 消息字段对应的数据库属性名称，内容或总结标题写入数据库的标题属性
+type NotionProperties struct {
+	Talker string `mapstructure:"talker" json:"talker"` // 聊天对象，默认 Talker
+	Sender string `mapstructure:"sender" json:"sender"` // 发送人，默认 Sender
+	Time   string `mapstructure:"time" json:"time"`     // 消息时间或总结日期，默认 Time
+	Media  string `mapstructure:"media" json:"media"`   // 图片、语音、视频的链接，默认 Media
+}
@@ -0,0 +1,23 @@
+package conf
+
+import "time"
+
+// OCR 识别图片消息中的文字，未配置时不识别
+type OCR struct {
+	// Provider 识别接口：tesseract（本地 tesseract）、openai（OpenAI 兼容的多模态 /chat/completions 接口）、none，为空时等同于 none
+	Provider string `mapstructure:"provider" json:"provider"`
+	// URL 接口地址，openai 默认 https://api.openai.com/v1
+	URL string `mapstructure:"url" json:"url"`
+	// Model openai 的模型名称，需要支持图片输入，如 gpt-4o-mini、qwen-vl-plus
+	Model  string `mapstructure:"model" json:"model"`
+	APIKey string `mapstructure:"api_key" json:"-"` // 不写入日志
+	// Binary tesseract 可执行文件，默认为 PATH 中的 tesseract
+	Binary string `mapstructure:"binary" json:"binary"`
+	// Language tesseract 的语言，默认 chi_sim+eng
+	Language string        `mapstructure:"language" json:"language"`
+	Timeout  time.Duration `mapstructure:"timeout" json:"timeout"` // 单张图片的识别超时，默认 1m
+	// Talkers 在后台批量识别的聊天对象，为空时只在请求时识别
+	Talkers []string `mapstructure:"talkers" json:"talkers"`
+	// Interval 批量识别新图片的间隔，默认 10m
+	Interval time.Duration `mapstructure:"interval" json:"interval"`
+}
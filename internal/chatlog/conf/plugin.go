@@ -0,0 +1,20 @@
+package conf
+
+import "time"
+
+// Plugin 处理新消息的外部程序，程序从 stdin 读取一批消息的 JSON，向 stdout 输出标签、评分与脱敏内容，协议见 plugin 包
+type Plugin struct {
+	// Name 插件名称，保存在标注中区分来源，默认为程序的文件名
+	Name string `mapstructure:"name" json:"name"`
+	// Command 可执行文件，Args 为参数
+	Command string   `mapstructure:"command" json:"command"`
+	Args    []string `mapstructure:"args" json:"args"`
+	// Talkers 交给插件处理的聊天对象
+	Talkers []string `mapstructure:"talkers" json:"talkers"`
+	// Interval 处理新消息的间隔，默认 10m
+	Interval time.Duration `mapstructure:"interval" json:"interval"`
+	// Timeout 处理一批消息的超时，默认 1m
+	Timeout time.Duration `mapstructure:"timeout" json:"timeout"`
+	// BatchSize 每批最多的消息数量，默认 100
+	BatchSize int `mapstructure:"batch_size" json:"batch_size"`
+}
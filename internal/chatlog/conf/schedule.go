@@ -0,0 +1,53 @@
+package conf
+
+// Schedule 定时任务，At 为本地时间 HH:MM，为空时不执行该任务
+type Schedule struct {
+	Export  *ExportJob  `mapstructure:"export" json:"export"`
+	Backup  *BackupJob  `mapstructure:"backup" json:"backup"`
+	Summary *SummaryJob `mapstructure:"summary" json:"summary"`
+	Email   *EmailJob   `mapstructure:"email" json:"email"`
+	Vault   *VaultJob   `mapstructure:"vault" json:"vault"`
+}
+
+// ExportJob 每天导出前一天的聊天记录，每个聊天对象一个文件
+type ExportJob struct {
+	At      string   `mapstructure:"at" json:"at"`
+	Talkers []string `mapstructure:"talkers" json:"talkers"`
+	Format  string   `mapstructure:"format" json:"format"` // txt、csv 或 json，默认 txt
+	Dir     string   `mapstructure:"dir" json:"dir"`       // 为空时导出到工作目录旁的 export 目录
+	// Translate 附上文本消息译文的目标语言，如 en，为空时不翻译
+	Translate string `mapstructure:"translate" json:"translate"`
+	// Script 导出前对每条消息运行的脚本，可以丢弃或改写消息，语法见 script 包
+	Script string `mapstructure:"script" json:"script"`
+}
+
+// BackupJob 每周备份工作目录与配置文件
+type BackupJob struct {
+	At      string `mapstructure:"at" json:"at"`
+	Weekday int    `mapstructure:"weekday" json:"weekday"` // 0 为星期日
+	Dir     string `mapstructure:"dir" json:"dir"`         // 为空时备份到工作目录旁的 backup 目录
+	Keep    int    `mapstructure:"keep" json:"keep"`       // 保留最近的备份数量，0 表示全部保留
+}
+
+// SummaryJob 每天总结聊天对象最近一天的消息并推送
+type SummaryJob struct {
+	At      string   `mapstructure:"at" json:"at"`
+	Talkers []string `mapstructure:"talkers" json:"talkers"`
+	To      string   `mapstructure:"to" json:"to"` // webhook、推送目标名称或 URL，默认 webhook
+}
+
+// EmailJob 每天总结聊天对象最近一天的消息并发送邮件摘要
+type EmailJob struct {
+	At      string   `mapstructure:"at" json:"at"`
+	Talkers []string `mapstructure:"talkers" json:"talkers"`
+	To      []string `mapstructure:"to" json:"to"` // 收件人，为空时使用 smtp.to
+}
+
+// VaultJob 每天将聊天对象的新消息同步到 Obsidian、Logseq 等笔记库
+type VaultJob struct {
+	At      string   `mapstructure:"at" json:"at"`
+	Talkers []string `mapstructure:"talkers" json:"talkers"`
+	Dir     string   `mapstructure:"dir" json:"dir"` // 笔记库目录，为空时同步到工作目录旁的 vault 目录
+	// Git 同步后将笔记库提交到 git 仓库，只写入文字，图片与文件不复制到笔记库
+	Git bool `mapstructure:"git" json:"git"`
+}
@@ -1,21 +1,80 @@
 package conf
 
+import "time"
+
 const (
 	DefalutHTTPAddr = "0.0.0.0:5030"
 )
 
 type ServerConfig struct {
-	Type        string   `mapstructure:"type"`
-	Platform    string   `mapstructure:"platform"`
-	Version     int      `mapstructure:"version"`
-	FullVersion string   `mapstructure:"full_version"`
-	DataDir     string   `mapstructure:"data_dir"`
-	DataKey     string   `mapstructure:"data_key"`
-	ImgKey      string   `mapstructure:"img_key"`
-	WorkDir     string   `mapstructure:"work_dir"`
-	HTTPAddr    string   `mapstructure:"http_addr"`
-	AutoDecrypt bool     `mapstructure:"auto_decrypt"`
-	Webhook     *Webhook `mapstructure:"webhook"`
+	Type        string     `mapstructure:"type"`
+	Platform    string     `mapstructure:"platform"`
+	Version     int        `mapstructure:"version"`
+	FullVersion string     `mapstructure:"full_version"`
+	DataDir     string     `mapstructure:"data_dir"`
+	DataKey     string     `mapstructure:"data_key"`
+	ImgKey      string     `mapstructure:"img_key"`
+	WorkDir     string     `mapstructure:"work_dir"`
+	HTTPAddr    string     `mapstructure:"http_addr"`
+	AutoDecrypt bool       `mapstructure:"auto_decrypt"`
+	Jobs        int        `mapstructure:"jobs"`                // 解密等耗时任务的并发数，0 表示按 CPU 核数
+	AuthToken   string     `mapstructure:"auth_token" json:"-"` // 不写入日志
+	Webhook     *Webhook   `mapstructure:"webhook"`
+	Summarize   *Summarize `mapstructure:"summarize"`
+	// Destinations 具名推送目标
+	Destinations map[string]*Destination `mapstructure:"destinations"`
+	Prune        *Prune                  `mapstructure:"prune"`
+	// BackupRemote 加密备份完成后上传的远程存储
+	BackupRemote *BackupRemote `mapstructure:"backup_remote"`
+	// LLM 生成总结使用的大模型，未配置时总结为按时间排列的消息
+	LLM *LLM `mapstructure:"llm"`
+	// Embedding 语义搜索使用的向量模型，未配置时不开启语义搜索
+	Embedding *Embedding `mapstructure:"embedding"`
+	// MQTT 发布状态与事件使用的 MQTT 服务器
+	MQTT *MQTT `mapstructure:"mqtt"`
+	// Stream 发布新消息与解密事件的 Kafka 或 NATS 服务器
+	Stream *Stream `mapstructure:"stream"`
+	// KeyScan 扫描进程内存搜索密钥的并发与内存参数
+	KeyScan *KeyScan `mapstructure:"key_scan"`
+	// SQLite 打开工作目录数据库的连接池、锁等待与内存映射参数
+	SQLite *SQLite `mapstructure:"sqlite"`
+	// HomeAssistant Home Assistant 传感器
+	HomeAssistant *HomeAssistant `mapstructure:"homeassistant"`
+	// Matrix chatlog matrix 回放聊天记录使用的账号
+	Matrix *Matrix `mapstructure:"matrix"`
+	// Notion chatlog notion 写入聊天记录与每日总结的数据库
+	Notion *Notion `mapstructure:"notion"`
+	// STT 语音消息转文字，未配置时不转写
+	STT *STT `mapstructure:"stt"`
+	// OCR 识别图片消息中的文字，未配置时不识别
+	OCR *OCR `mapstructure:"ocr"`
+	// Translate 翻译消息使用的接口
+	Translate *Translate `mapstructure:"translate"`
+	// Analytics 按月分析聊天对象的情感与话题
+	Analytics *Analytics `mapstructure:"analytics"`
+	// Plugins 处理新消息并返回标注的外部程序
+	Plugins []*Plugin `mapstructure:"plugins"`
+
+	// AutoDecryptInterval 自动解密时等待数据库停止写入的时间，0 表示默认的 1s
+	AutoDecryptInterval time.Duration `mapstructure:"auto_decrypt_interval"`
+
+	// CompressWorkDir 解密后用 zstd 压缩工作目录中的数据库，打开时解压到配置目录
+	CompressWorkDir bool `mapstructure:"compress_workdir"`
+
+	// DecryptOnly 只解密相对数据目录的路径包含其中任一字符串的数据库，为空时解密全部数据库
+	DecryptOnly []string `mapstructure:"decrypt_only"`
+
+	// DecryptLagAlert 自动解密落后超过阈值时推送告警，未配置时不告警
+	DecryptLagAlert *DecryptLagAlert `mapstructure:"decrypt_lag_alert"`
+
+	// Pprof 开启 /debug/pprof 性能分析与 /debug/runtime 运行时信息接口
+	Pprof bool `mapstructure:"pprof"`
+
+	// Profile 当前使用的 profile，对应配置文件中 profiles 下的同名配置
+	Profile string `mapstructure:"profile"`
+
+	// Account 当前使用的历史账号，对应 TUI 配置 chatlog.json 中 history 下的账号名称或微信 ID
+	Account string `mapstructure:"account"`
 }
 
 var ServerDefaults = map[string]any{}
@@ -44,10 +103,18 @@ func (c *ServerConfig) GetImgKey() string {
 	return c.ImgKey
 }
 
+func (c *ServerConfig) GetJobs() int {
+	return c.Jobs
+}
+
 func (c *ServerConfig) GetAutoDecrypt() bool {
 	return c.AutoDecrypt
 }
 
+func (c *ServerConfig) GetAutoDecryptInterval() time.Duration {
+	return c.AutoDecryptInterval
+}
+
 func (c *ServerConfig) GetHTTPAddr() string {
 	if c.HTTPAddr == "" {
 		c.HTTPAddr = DefalutHTTPAddr
@@ -55,6 +122,109 @@ func (c *ServerConfig) GetHTTPAddr() string {
 	return c.HTTPAddr
 }
 
+func (c *ServerConfig) GetAuthToken() string {
+	return c.AuthToken
+}
+
 func (c *ServerConfig) GetWebhook() *Webhook {
 	return c.Webhook
 }
+
+func (c *ServerConfig) GetSummarize() *Summarize {
+	return c.Summarize
+}
+
+func (c *ServerConfig) GetDestinations() map[string]*Destination {
+	return c.Destinations
+}
+
+func (c *ServerConfig) GetLLM() *LLM {
+	return c.LLM
+}
+
+func (c *ServerConfig) GetEmbedding() *Embedding {
+	return c.Embedding
+}
+
+func (c *ServerConfig) GetSTT() *STT {
+	return c.STT
+}
+
+func (c *ServerConfig) GetOCR() *OCR {
+	return c.OCR
+}
+
+func (c *ServerConfig) GetTranslate() *Translate {
+	return c.Translate
+}
+
+func (c *ServerConfig) GetAnalytics() *Analytics {
+	return c.Analytics
+}
+
+func (c *ServerConfig) GetPlugins() []*Plugin {
+	return c.Plugins
+}
+
+func (c *ServerConfig) GetBackupRemote() *BackupRemote {
+	return c.BackupRemote
+}
+
+func (c *ServerConfig) GetMQTT() *MQTT {
+	return c.MQTT
+}
+
+func (c *ServerConfig) GetStream() *Stream {
+	return c.Stream
+}
+
+func (c *ServerConfig) GetKeyScan() *KeyScan {
+	return c.KeyScan
+}
+
+func (c *ServerConfig) GetCompressWorkDir() bool {
+	return c.CompressWorkDir
+}
+
+func (c *ServerConfig) GetDecryptOnly() []string {
+	return c.DecryptOnly
+}
+
+func (c *ServerConfig) GetDecryptLagAlert() *DecryptLagAlert {
+	return c.DecryptLagAlert
+}
+
+func (c *ServerConfig) GetSQLite() *SQLite {
+	return c.SQLite
+}
+
+func (c *ServerConfig) GetPprof() bool {
+	return c.Pprof
+}
+
+func (c *ServerConfig) GetHomeAssistant() *HomeAssistant {
+	return c.HomeAssistant
+}
+
+func (c *ServerConfig) GetMatrix() *Matrix {
+	return c.Matrix
+}
+
+func (c *ServerConfig) GetNotion() *Notion {
+	return c.Notion
+}
+
+// Prune prune 命令的保留策略
+type Prune struct {
+	KeepBackups int           `mapstructure:"keep_backups"`  // 保留最近的备份数量，0 表示全部保留
+	TempMaxAge  time.Duration `mapstructure:"temp_max_age"`  // 临时文件的保留时长，默认 1h
+	CacheMaxAge time.Duration `mapstructure:"cache_max_age"` // 临时副本的保留时长，默认 24h
+	Stale       bool          `mapstructure:"stale"`         // 清理数据目录中已不存在的解密数据库，默认不清理
+}
+
+func (c *ServerConfig) GetPrune() *Prune {
+	if c.Prune == nil {
+		return &Prune{}
+	}
+	return c.Prune
+}
@@ -0,0 +1,14 @@
+package conf
+
+// SMTP 发送邮件摘要的 SMTP 服务器
+type SMTP struct {
+	Host string `mapstructure:"host" json:"host"`
+	// Port 默认 587，465 使用 TLS 直连，其他端口在服务器支持时使用 STARTTLS
+	Port     int    `mapstructure:"port" json:"port"`
+	Username string `mapstructure:"username" json:"username"`
+	Password string `mapstructure:"password" json:"-"` // 不写入日志
+	// From 发件人，为空时使用 username
+	From string `mapstructure:"from" json:"from"`
+	// To 默认的收件人
+	To []string `mapstructure:"to" json:"to"`
+}
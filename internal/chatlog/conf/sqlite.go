@@ -0,0 +1,17 @@
+package conf
+
+import "time"
+
+// SQLite 打开工作目录数据库的参数，零值表示使用默认值
+type SQLite struct {
+	// MaxConns 每个数据库的最大连接数，默认 8，HTTP 并发查询不超过该数量时互不等待
+	MaxConns int `mapstructure:"max_conns" json:"max_conns"`
+	// MaxOpenDBs 同时打开的数据库数量，默认 16，超过时关闭最久未使用的
+	MaxOpenDBs int `mapstructure:"max_open_dbs" json:"max_open_dbs"`
+	// BusyTimeout 数据库被锁定时等待的时间，默认 5s
+	BusyTimeout time.Duration `mapstructure:"busy_timeout" json:"busy_timeout"`
+	// MmapSize 内存映射的大小（MB），默认 256，-1 表示不使用内存映射
+	MmapSize int `mapstructure:"mmap_size" json:"mmap_size"`
+	// JournalMode 日志模式，默认为空，只读打开数据库，不修改日志模式；设置后以读写方式打开并切换到该模式
+	JournalMode string `mapstructure:"journal_mode" json:"journal_mode"`
+}
@@ -0,0 +1,22 @@
+package conf
+
+// Stream 发布新消息与解密事件的 Kafka 或 NATS 服务器
+type Stream struct {
+	// Type kafka 或 nats
+	Type string `mapstructure:"type" json:"type"`
+	// Servers 服务器地址，kafka 为 host:9092，nats 为 nats://host:4222 或 tls://host:4222
+	Servers  []string `mapstructure:"servers" json:"servers"`
+	Username string   `mapstructure:"username" json:"username"`
+	Password string   `mapstructure:"password" json:"-"` // kafka 使用 SASL PLAIN 认证
+	Token    string   `mapstructure:"token" json:"-"`    // nats 的认证 token
+	// TLS 使用 TLS 连接
+	TLS bool `mapstructure:"tls" json:"tls"`
+	// Topic 主题前缀，默认 chatlog
+	Topic string `mapstructure:"topic" json:"topic"`
+	// Messages 发现新消息时发布到 <topic>.messages，nats 为 <topic>.messages.<聊天对象>
+	Messages bool `mapstructure:"messages" json:"messages"`
+	// Talkers 发布新消息的聊天对象，为空时发布所有聊天对象的新消息
+	Talkers []string `mapstructure:"talkers" json:"talkers"`
+	// Decrypt 解密开始、完成与失败时发布到 <topic>.decrypt
+	Decrypt bool `mapstructure:"decrypt" json:"decrypt"`
+}
@@ -0,0 +1,23 @@
+package conf
+
+import "time"
+
+// STT 语音消息转文字，未配置时不转写
+type STT struct {
+	// Provider 转写接口：openai（OpenAI 兼容的 /audio/transcriptions 接口）、whisper（本地 whisper.cpp）、none，为空时等同于 none
+	Provider string `mapstructure:"provider" json:"provider"`
+	// URL 接口地址，openai 默认 https://api.openai.com/v1
+	URL string `mapstructure:"url" json:"url"`
+	// Model openai 为模型名称，默认 whisper-1；whisper 为 ggml 模型文件路径
+	Model  string `mapstructure:"model" json:"model"`
+	APIKey string `mapstructure:"api_key" json:"-"` // 不写入日志
+	// Binary whisper.cpp 可执行文件，默认为 PATH 中的 whisper-cli
+	Binary string `mapstructure:"binary" json:"binary"`
+	// Language 语音的语言，如 zh，为空时自动识别
+	Language string        `mapstructure:"language" json:"language"`
+	Timeout  time.Duration `mapstructure:"timeout" json:"timeout"` // 单条语音的转写超时，默认 2m
+	// Talkers 在后台批量转写的聊天对象，为空时只在请求时转写
+	Talkers []string `mapstructure:"talkers" json:"talkers"`
+	// Interval 批量转写新语音的间隔，默认 10m
+	Interval time.Duration `mapstructure:"interval" json:"interval"`
+}
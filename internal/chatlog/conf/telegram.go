@@ -0,0 +1,14 @@
+package conf
+
+import "time"
+
+// Telegram 通过 Telegram 机器人查询聊天记录与接收新消息通知
+type Telegram struct {
+	Token string `mapstructure:"token" json:"-"` // BotFather 创建机器人后获得的 token，不写入日志
+	// ChatID 机器人主人的会话 ID，只响应该会话的消息，未配置时机器人回复当前会话的 ID
+	ChatID int64 `mapstructure:"chat_id" json:"chat_id"`
+	// URL Bot API 地址，默认 https://api.telegram.org，可以使用反向代理
+	URL string `mapstructure:"url" json:"url"`
+	// Timeout 长轮询等待新消息的时间，默认 30s
+	Timeout time.Duration `mapstructure:"timeout" json:"timeout"`
+}
@@ -0,0 +1,13 @@
+package conf
+
+import "time"
+
+// Translate 翻译消息使用的接口，目标语言在请求或导出时指定
+type Translate struct {
+	// Provider 翻译接口：llm（使用 llm 配置的大模型）、deepl、libretranslate、none，为空时等同于 none
+	Provider string `mapstructure:"provider" json:"provider"`
+	// URL 接口地址，deepl 按密钥自动选择免费版或专业版，libretranslate 默认 http://localhost:5000
+	URL     string        `mapstructure:"url" json:"url"`
+	APIKey  string        `mapstructure:"api_key" json:"-"`       // 不写入日志
+	Timeout time.Duration `mapstructure:"timeout" json:"timeout"` // 请求超时，默认 1m
+}
@@ -1,10 +1,78 @@
 package conf
 
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
 type TUIConfig struct {
 	ConfigDir   string          `mapstructure:"-" json:"config_dir"`
 	LastAccount string          `mapstructure:"last_account" json:"last_account"`
 	History     []ProcessConfig `mapstructure:"history" json:"history"`
 	Webhook     *Webhook        `mapstructure:"webhook" json:"webhook"`
+	Summarize   *Summarize      `mapstructure:"summarize" json:"summarize"`
+	// Destinations 具名推送目标
+	Destinations map[string]*Destination `mapstructure:"destinations" json:"destinations"`
+	AuthToken    string                  `mapstructure:"auth_token" json:"-"` // 不写入日志
+	Jobs         int                     `mapstructure:"jobs" json:"jobs"`    // 解密等耗时任务的并发数，0 表示按 CPU 核数
+	// CompressWorkDir 解密后用 zstd 压缩工作目录中的数据库，打开时解压到配置目录
+	CompressWorkDir bool `mapstructure:"compress_workdir" json:"compress_workdir"`
+	// Pprof 开启 /debug/pprof 性能分析与 /debug/runtime 运行时信息接口
+	Pprof bool `mapstructure:"pprof" json:"pprof"`
+	// AutoDecryptInterval 自动解密时等待数据库停止写入的时间，0 表示默认的 1s
+	AutoDecryptInterval time.Duration `mapstructure:"auto_decrypt_interval" json:"auto_decrypt_interval"`
+	// DecryptLagAlert 自动解密落后超过阈值时推送告警，未配置时不告警
+	DecryptLagAlert *DecryptLagAlert `mapstructure:"decrypt_lag_alert" json:"decrypt_lag_alert"`
+	// Theme 界面配色：dark、light、high-contrast、no-color
+	Theme string `mapstructure:"theme" json:"theme"`
+	// Colors 覆盖配色中的颜色，键为 fg、bg、accent 等，值为颜色名称或 #rrggbb
+	Colors map[string]string `mapstructure:"colors" json:"colors"`
+	// NoMouse 禁用鼠标，便于在终端中直接选择复制文字
+	NoMouse bool `mapstructure:"no_mouse" json:"no_mouse"`
+	// Keys 自定义按键，键为操作名称，值为逗号分隔的按键，如 {"down": "j, down", "quit": "q"}
+	Keys map[string]string `mapstructure:"keys" json:"keys"`
+	// Language 界面语言：zh-CN、en，为空时按 CHATLOG_LANG 与系统区域设置检测
+	Language string `mapstructure:"language" json:"language"`
+	// Schedule 定时导出、备份与总结推送
+	Schedule *Schedule `mapstructure:"schedule" json:"schedule"`
+	// BackupRemote 加密备份完成后上传的远程存储
+	BackupRemote *BackupRemote `mapstructure:"backup_remote" json:"backup_remote"`
+	// TourDone 是否已完成或跳过首次运行引导
+	TourDone bool `mapstructure:"tour_done" json:"tour_done"`
+	// Notify 关注的聊天对象，自动解密发现新消息时响铃或发送桌面通知
+	Notify []NotifyRule `mapstructure:"notify" json:"notify"`
+	// Pinned 置顶的聊天对象，显示在会话列表与联系人选择列表的最前面
+	Pinned []string `mapstructure:"pinned" json:"pinned"`
+	// LLM 生成总结使用的大模型，未配置时总结为按时间排列的消息
+	LLM *LLM `mapstructure:"llm" json:"llm"`
+	// SMTP 发送每日邮件摘要的邮件服务器
+	SMTP *SMTP `mapstructure:"smtp" json:"smtp"`
+	// Telegram 通过 Telegram 机器人查询聊天记录与接收通知
+	Telegram *Telegram `mapstructure:"telegram" json:"telegram"`
+	// Embedding 语义搜索使用的向量模型，未配置时不开启语义搜索
+	Embedding *Embedding `mapstructure:"embedding" json:"embedding"`
+	// MQTT 发布状态与事件使用的 MQTT 服务器
+	MQTT *MQTT `mapstructure:"mqtt" json:"mqtt"`
+	// Stream 发布新消息与解密事件的 Kafka 或 NATS 服务器
+	Stream *Stream `mapstructure:"stream" json:"stream"`
+	// KeyScan 扫描进程内存搜索密钥的并发与内存参数
+	KeyScan *KeyScan `mapstructure:"key_scan" json:"key_scan"`
+	// SQLite 打开工作目录数据库的连接池、锁等待与内存映射参数
+	SQLite *SQLite `mapstructure:"sqlite" json:"sqlite"`
+	// HomeAssistant Home Assistant 传感器
+	HomeAssistant *HomeAssistant `mapstructure:"homeassistant" json:"homeassistant"`
+	// STT 语音消息转文字，未配置时不转写
+	STT *STT `mapstructure:"stt" json:"stt"`
+	// OCR 识别图片消息中的文字，未配置时不识别
+	OCR *OCR `mapstructure:"ocr" json:"ocr"`
+	// Translate 翻译消息使用的接口
+	Translate *Translate `mapstructure:"translate" json:"translate"`
+	// Analytics 按月分析聊天对象的情感与话题
+	Analytics *Analytics `mapstructure:"analytics" json:"analytics"`
+	// Plugins 处理新消息并返回标注的外部程序
+	Plugins []*Plugin `mapstructure:"plugins" json:"plugins"`
 }
 
 var TUIDefaults = map[string]any{}
@@ -38,3 +106,33 @@ func (c *TUIConfig) ParseHistory() map[string]ProcessConfig {
 	}
 	return m
 }
+
+// FindHistory 按账号名称或微信 ID 查找历史账号
+// 4.0 版本的账号名称为 wxid_xxx_1234 形式的目录名，因此也匹配以 "<wxid>_" 开头的账号，匹配到多个时返回错误
+func (c *TUIConfig) FindHistory(account string) (*ProcessConfig, error) {
+	matches := make([]*ProcessConfig, 0)
+	for i := range c.History {
+		h := &c.History[i]
+		name := h.Account
+		if len(name) == 0 && len(h.DataDir) != 0 {
+			name = filepath.Base(h.DataDir)
+		}
+		if name == account {
+			return h, nil
+		}
+		if strings.HasPrefix(name, account+"_") {
+			matches = append(matches, h)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("account not found in history: %s", account)
+	case 1:
+		return matches[0], nil
+	}
+	names := make([]string, 0, len(matches))
+	for _, h := range matches {
+		names = append(names, h.Account)
+	}
+	return nil, fmt.Errorf("account %s matches multiple history accounts: %s", account, strings.Join(names, ", "))
+}
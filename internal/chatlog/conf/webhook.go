@@ -1,5 +1,7 @@
 package conf
 
+import "time"
+
 type Webhook struct {
 	Host    string         `mapstructure:"host"`
 	DelayMs int64          `mapstructure:"delay_ms"`
@@ -7,10 +9,36 @@ type Webhook struct {
 }
 
 type WebhookItem struct {
-	Type     string `mapstructure:"type"`
-	URL      string `mapstructure:"url"`
-	Talker   string `mapstructure:"talker"`
-	Sender   string `mapstructure:"sender"`
-	Keyword  string `mapstructure:"keyword"`
-	Disabled bool   `mapstructure:"disabled"`
+	Type        string `mapstructure:"type"`
+	URL         string `mapstructure:"url"`
+	Destination string `mapstructure:"destination"` // 推送目标名称，设置后忽略 url
+	Talker      string `mapstructure:"talker"`
+	Sender      string `mapstructure:"sender"`
+	Keyword     string `mapstructure:"keyword"`
+	Disabled    bool   `mapstructure:"disabled"`
+}
+
+// Summarize summarize 命令推送总结的地址
+type Summarize struct {
+	Destination string            `mapstructure:"destination"` // --to webhook 使用的推送目标名称，设置后忽略 url 和 headers
+	URL         string            `mapstructure:"url"`
+	Headers     map[string]string `mapstructure:"headers" json:"-"` // 附加的请求头，如接收端要求的鉴权 token，不写入日志
+}
+
+// Destination 推送目标，在 destinations 中按名称配置，webhook 和 summarize 通过名称引用
+type Destination struct {
+	URL      string            `mapstructure:"url"`
+	Headers  map[string]string `mapstructure:"headers" json:"-"` // 附加的请求头，如鉴权 token，不写入日志
+	Template string            `mapstructure:"template"`         // 请求体模板（text/template），为空时直接发送 JSON
+	Timeout  time.Duration     `mapstructure:"timeout"`          // 请求超时，默认 10s
+	// Format 请求体格式：json、slack 或 discord，为空时按 URL 识别 Slack 与 Discord 的 incoming webhook，设置 template 时忽略
+	Format string `mapstructure:"format"`
+	// Script 推送前对每条消息运行的脚本，可以丢弃、改写消息或改为推送到其他目标，语法见 script 包
+	Script string `mapstructure:"script"`
+	// Secret 设置后用 HMAC-SHA256 签名请求体，签名在 X-Chatlog-Signature 请求头中
+	Secret string `mapstructure:"secret" json:"-"` // 不写入日志
+	// Retries 推送失败时的重试次数，默认 3，小于 0 时不重试
+	Retries int `mapstructure:"retries"`
+	// Backoff 第一次重试前的等待时间，之后每次加倍，默认 1s
+	Backoff time.Duration `mapstructure:"backoff"`
 }
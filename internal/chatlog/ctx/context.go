@@ -154,6 +154,13 @@ func (c *Context) Refresh() {
 	}
 }
 
+// RefreshWorkUsage 重新计算工作目录的大小
+func (c *Context) RefreshWorkUsage() {
+	if workDir := c.WorkDir; len(workDir) != 0 {
+		c.WorkUsage = util.GetDirSize(workDir)
+	}
+}
+
 func (c *Context) GetDataDir() string {
 	return c.DataDir
 }
@@ -174,6 +181,61 @@ func (c *Context) GetDataKey() string {
 	return c.DataKey
 }
 
+func (c *Context) GetConfigDir() string {
+	return c.conf.ConfigDir
+}
+
+func (c *Context) GetJobs() int {
+	return c.conf.Jobs
+}
+
+func (c *Context) GetAutoDecryptInterval() time.Duration {
+	return c.conf.AutoDecryptInterval
+}
+
+func (c *Context) GetTheme() string {
+	return c.conf.Theme
+}
+
+func (c *Context) GetColors() map[string]string {
+	return c.conf.Colors
+}
+
+func (c *Context) GetNoMouse() bool {
+	return c.conf.NoMouse
+}
+
+func (c *Context) GetKeys() map[string]string {
+	return c.conf.Keys
+}
+
+func (c *Context) GetLanguage() string {
+	return c.conf.Language
+}
+
+func (c *Context) GetSchedule() *conf.Schedule {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.conf.Schedule
+}
+
+func (c *Context) GetNotify() []conf.NotifyRule {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.conf.Notify
+}
+
+// GetPinned 返回置顶的聊天对象，按置顶的先后顺序
+func (c *Context) GetPinned() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.conf.Pinned
+}
+
+func (c *Context) GetTourDone() bool {
+	return c.conf.TourDone
+}
+
 func (c *Context) GetHTTPAddr() string {
 	if c.HTTPAddr == "" {
 		c.HTTPAddr = DefalutHTTPAddr
@@ -181,10 +243,99 @@ func (c *Context) GetHTTPAddr() string {
 	return c.HTTPAddr
 }
 
+func (c *Context) GetAuthToken() string {
+	return c.conf.AuthToken
+}
+
 func (c *Context) GetWebhook() *conf.Webhook {
 	return c.conf.Webhook
 }
 
+func (c *Context) GetSummarize() *conf.Summarize {
+	return c.conf.Summarize
+}
+
+func (c *Context) GetLLM() *conf.LLM {
+	return c.conf.LLM
+}
+
+func (c *Context) GetEmbedding() *conf.Embedding {
+	return c.conf.Embedding
+}
+
+func (c *Context) GetSTT() *conf.STT {
+	return c.conf.STT
+}
+
+func (c *Context) GetOCR() *conf.OCR {
+	return c.conf.OCR
+}
+
+func (c *Context) GetTranslate() *conf.Translate {
+	return c.conf.Translate
+}
+
+func (c *Context) GetAnalytics() *conf.Analytics {
+	return c.conf.Analytics
+}
+
+func (c *Context) GetPlugins() []*conf.Plugin {
+	return c.conf.Plugins
+}
+
+func (c *Context) GetBackupRemote() *conf.BackupRemote {
+	return c.conf.BackupRemote
+}
+
+func (c *Context) GetMQTT() *conf.MQTT {
+	return c.conf.MQTT
+}
+
+func (c *Context) GetStream() *conf.Stream {
+	return c.conf.Stream
+}
+
+func (c *Context) GetKeyScan() *conf.KeyScan {
+	return c.conf.KeyScan
+}
+
+func (c *Context) GetCompressWorkDir() bool {
+	return c.conf.CompressWorkDir
+}
+
+// GetDecryptOnly TUI 总是解密全部数据库
+func (c *Context) GetDecryptOnly() []string {
+	return nil
+}
+
+func (c *Context) GetSQLite() *conf.SQLite {
+	return c.conf.SQLite
+}
+
+func (c *Context) GetPprof() bool {
+	return c.conf.Pprof
+}
+
+func (c *Context) GetHomeAssistant() *conf.HomeAssistant {
+	return c.conf.HomeAssistant
+}
+
+func (c *Context) GetSMTP() *conf.SMTP {
+	return c.conf.SMTP
+}
+
+func (c *Context) GetTelegram() *conf.Telegram {
+	return c.conf.Telegram
+}
+
+func (c *Context) GetDestinations() map[string]*conf.Destination {
+	return c.conf.Destinations
+}
+
+func (c *Context) GetDecryptLagAlert() *conf.DecryptLagAlert {
+	return c.conf.DecryptLagAlert
+}
+
 func (c *Context) SetHTTPEnabled(enabled bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -237,6 +388,147 @@ func (c *Context) SetImgKey(key string) {
 	c.UpdateConfig()
 }
 
+// SetAuthToken 设置 HTTP 接口的访问令牌并写入配置文件，为空时不校验
+func (c *Context) SetAuthToken(token string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.cm.SetConfig("auth_token", token); err != nil {
+		return err
+	}
+	c.conf.AuthToken = token
+	return nil
+}
+
+// SetTourDone 记录已完成首次运行引导并写入配置文件，之后启动时不再显示
+func (c *Context) SetTourDone() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.cm.SetConfig("tour_done", true); err != nil {
+		return err
+	}
+	c.conf.TourDone = true
+	return nil
+}
+
+// SetAutoDecryptInterval 设置自动解密的等待时间并写入配置文件，对之后的数据库写入生效
+func (c *Context) SetAutoDecryptInterval(interval time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.cm.SetConfig("auto_decrypt_interval", interval.String()); err != nil {
+		return err
+	}
+	c.conf.AutoDecryptInterval = interval
+	return nil
+}
+
+// SetDestination 添加或修改推送目标并写入配置文件，dest 为 nil 时删除
+func (c *Context) SetDestination(name string, dest *conf.Destination) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	dests := make(map[string]*conf.Destination, len(c.conf.Destinations)+1)
+	for k, v := range c.conf.Destinations {
+		dests[k] = v
+	}
+	if dest == nil {
+		delete(dests, name)
+	} else {
+		dests[name] = dest
+	}
+
+	// 按配置文件中的键名写入，避免结构体按字段名序列化
+	values := make(map[string]any, len(dests))
+	for k, d := range dests {
+		v := map[string]any{"url": d.URL}
+		if len(d.Headers) != 0 {
+			v["headers"] = d.Headers
+		}
+		if len(d.Template) != 0 {
+			v["template"] = d.Template
+		}
+		if d.Timeout > 0 {
+			v["timeout"] = d.Timeout.String()
+		}
+		if len(d.Format) != 0 {
+			v["format"] = d.Format
+		}
+		if len(d.Script) != 0 {
+			v["script"] = d.Script
+		}
+		if len(d.Secret) != 0 {
+			v["secret"] = d.Secret
+		}
+		if d.Retries != 0 {
+			v["retries"] = d.Retries
+		}
+		if d.Backoff > 0 {
+			v["backoff"] = d.Backoff.String()
+		}
+		values[k] = v
+	}
+	if err := c.cm.SetConfig("destinations", values); err != nil {
+		return err
+	}
+	c.conf.Destinations = dests
+	return nil
+}
+
+// SetSchedule 设置定时任务并写入配置文件
+func (c *Context) SetSchedule(schedule *conf.Schedule) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// 按配置文件中的键名写入，避免结构体按字段名序列化
+	values := make(map[string]any)
+	if j := schedule.Export; j != nil {
+		values["export"] = map[string]any{"at": j.At, "talkers": j.Talkers, "format": j.Format, "dir": j.Dir, "script": j.Script}
+	}
+	if j := schedule.Backup; j != nil {
+		values["backup"] = map[string]any{"at": j.At, "weekday": j.Weekday, "dir": j.Dir, "keep": j.Keep}
+	}
+	if j := schedule.Summary; j != nil {
+		values["summary"] = map[string]any{"at": j.At, "talkers": j.Talkers, "to": j.To}
+	}
+	if j := schedule.Email; j != nil {
+		values["email"] = map[string]any{"at": j.At, "talkers": j.Talkers, "to": j.To}
+	}
+	if j := schedule.Vault; j != nil {
+		values["vault"] = map[string]any{"at": j.At, "talkers": j.Talkers, "dir": j.Dir, "git": j.Git}
+	}
+	if err := c.cm.SetConfig("schedule", values); err != nil {
+		return err
+	}
+	c.conf.Schedule = schedule
+	return nil
+}
+
+// SetNotify 设置关注的聊天对象并写入配置文件
+func (c *Context) SetNotify(rules []conf.NotifyRule) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// 按配置文件中的键名写入，避免结构体按字段名序列化
+	values := make([]map[string]any, 0, len(rules))
+	for _, r := range rules {
+		values = append(values, map[string]any{"talker": r.Talker, "bell": r.Bell, "desktop": r.Desktop, "telegram": r.Telegram, "destination": r.Destination})
+	}
+	if err := c.cm.SetConfig("notify", values); err != nil {
+		return err
+	}
+	c.conf.Notify = rules
+	return nil
+}
+
+// SetPinned 设置置顶的聊天对象并写入配置文件
+func (c *Context) SetPinned(talkers []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.cm.SetConfig("pinned", talkers); err != nil {
+		return err
+	}
+	c.conf.Pinned = talkers
+	return nil
+}
+
 func (c *Context) SetAutoDecrypt(enabled bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -2,14 +2,17 @@ package database
 
 import (
 	"context"
+	"path/filepath"
 	"time"
 
 	"github.com/rs/zerolog/log"
 
 	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+	"github.com/DanielMao1/chatlog/internal/chatlog/push"
 	"github.com/DanielMao1/chatlog/internal/chatlog/webhook"
 	"github.com/DanielMao1/chatlog/internal/model"
 	"github.com/DanielMao1/chatlog/internal/wechatdb"
+	"github.com/DanielMao1/chatlog/internal/wechatdb/datasource/dbm"
 )
 
 const (
@@ -33,6 +36,10 @@ type Config interface {
 	GetPlatform() string
 	GetVersion() int
 	GetWebhook() *conf.Webhook
+	GetDestinations() map[string]*conf.Destination
+	GetMQTT() *conf.MQTT
+	GetStream() *conf.Stream
+	GetSQLite() *conf.SQLite
 }
 
 func NewService(conf Config) *Service {
@@ -43,12 +50,21 @@ func NewService(conf Config) *Service {
 }
 
 func (s *Service) Start() error {
+	var opts dbm.Options
+	if c := s.conf.GetSQLite(); c != nil {
+		opts = dbm.Options{MaxConns: c.MaxConns, MaxOpenDBs: c.MaxOpenDBs, BusyTimeout: c.BusyTimeout, MmapSize: int64(c.MmapSize) * 1024 * 1024, JournalMode: c.JournalMode}
+	}
+	// 压缩的工作目录中的数据库解压到配置目录，不放在其他用户可以读取的系统临时目录中
+	opts.DecompressDir = filepath.Join(conf.ConfigDir(), dbm.DecompressDir)
+	dbm.SetOptions(opts)
 	db, err := wechatdb.New(s.conf.GetWorkDir(), s.conf.GetPlatform(), s.conf.GetVersion())
 	if err != nil {
 		return err
 	}
 	s.SetReady()
 	s.db = db
+	// 推送失败的请求保存在工作目录中，webhook、通知与总结推送都在数据库启动后进行
+	push.SetSpool(filepath.Join(s.conf.GetWorkDir(), push.SpoolDir))
 	s.initWebhook()
 	return nil
 }
@@ -87,25 +103,40 @@ func (s *Service) GetDB() *wechatdb.DB {
 	return s.db
 }
 
-func (s *Service) GetMessages(start, end time.Time, talker string, sender string, keyword string, limit, offset int) ([]*model.Message, error) {
-	return s.db.GetMessages(start, end, talker, sender, keyword, limit, offset)
+func (s *Service) GetMessages(ctx context.Context, start, end time.Time, talker string, sender string, keyword string, limit, offset int) ([]*model.Message, error) {
+	return s.db.GetMessages(ctx, start, end, talker, sender, keyword, limit, offset)
 }
 
-func (s *Service) GetContacts(key string, limit, offset int) (*wechatdb.GetContactsResp, error) {
-	return s.db.GetContacts(key, limit, offset)
+func (s *Service) GetContacts(ctx context.Context, key string, limit, offset int) (*wechatdb.GetContactsResp, error) {
+	return s.db.GetContacts(ctx, key, limit, offset)
 }
 
-func (s *Service) GetChatRooms(key string, limit, offset int) (*wechatdb.GetChatRoomsResp, error) {
-	return s.db.GetChatRooms(key, limit, offset)
+func (s *Service) GetChatRooms(ctx context.Context, key string, limit, offset int) (*wechatdb.GetChatRoomsResp, error) {
+	return s.db.GetChatRooms(ctx, key, limit, offset)
 }
 
 // GetSession retrieves session information
-func (s *Service) GetSessions(key string, limit, offset int) (*wechatdb.GetSessionsResp, error) {
-	return s.db.GetSessions(key, limit, offset)
+func (s *Service) GetSessions(ctx context.Context, key string, limit, offset int) (*wechatdb.GetSessionsResp, error) {
+	return s.db.GetSessions(ctx, key, limit, offset)
+}
+
+// GetStats retrieves account-level statistics
+func (s *Service) GetStats(ctx context.Context, start, end time.Time, top int) (*model.Stats, error) {
+	return s.db.GetStats(ctx, start, end, top)
+}
+
+// CountMessages retrieves daily message counts grouped by talker and message type
+func (s *Service) CountMessages(ctx context.Context, start, end time.Time, talker string) ([]*model.MessageCount, error) {
+	return s.db.CountMessages(ctx, start, end, talker)
+}
+
+// NewFeed creates an incremental message feed starting at since
+func (s *Service) NewFeed(talker string, since time.Time) *wechatdb.Feed {
+	return s.db.NewFeed(talker, since)
 }
 
-func (s *Service) GetMedia(_type string, key string) (*model.Media, error) {
-	return s.db.GetMedia(_type, key)
+func (s *Service) GetMedia(ctx context.Context, _type string, key string) (*model.Media, error) {
+	return s.db.GetMedia(ctx, _type, key)
 }
 
 func (s *Service) initWebhook() error {
@@ -0,0 +1,116 @@
+package decrypted
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// StateFile 记录 chatlog 从数据目录解密生成的数据库，位于工作目录下
+// merge、import 写入的数据库不在其中，prune 只会把清单中的数据库当作过期分片
+const StateFile = ".chatlog-decrypted.json"
+
+// mu 同一进程中自动解密可能同时完成多个数据库，避免并发读写清单
+var mu sync.Mutex
+
+type state struct {
+	Files []string `json:"files"` // 相对工作目录的路径，使用 / 分隔
+}
+
+// Load 读取工作目录中解密生成的数据库，键为 / 分隔的相对路径，没有记录时返回空集合
+func Load(workDir string) (map[string]bool, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	s, err := load(workDir)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string]bool, len(s.Files))
+	for _, f := range s.Files {
+		ret[f] = true
+	}
+	return ret, nil
+}
+
+// Record 将解密生成的数据库加入清单，rels 为相对工作目录的路径
+func Record(workDir string, rels ...string) error {
+	if len(rels) == 0 {
+		return nil
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	s, err := load(workDir)
+	if err != nil {
+		return err
+	}
+	files := make(map[string]bool, len(s.Files)+len(rels))
+	for _, f := range s.Files {
+		files[f] = true
+	}
+	changed := false
+	for _, rel := range rels {
+		rel = normalize(rel)
+		if !files[rel] {
+			files[rel] = true
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return save(workDir, files)
+}
+
+// Remove 将已删除的数据库移出清单
+func Remove(workDir string, rels ...string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	s, err := load(workDir)
+	if err != nil {
+		return err
+	}
+	files := make(map[string]bool, len(s.Files))
+	for _, f := range s.Files {
+		files[f] = true
+	}
+	for _, rel := range rels {
+		delete(files, normalize(rel))
+	}
+	return save(workDir, files)
+}
+
+// normalize 统一使用 / 分隔且不以 / 开头，数据目录截取出的路径带有前导分隔符
+func normalize(rel string) string {
+	return strings.TrimLeft(filepath.ToSlash(rel), "/")
+}
+
+func load(workDir string) (*state, error) {
+	s := &state{}
+	b, err := os.ReadFile(filepath.Join(workDir, StateFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func save(workDir string, files map[string]bool) error {
+	s := state{Files: make([]string, 0, len(files))}
+	for f := range files {
+		s.Files = append(s.Files, f)
+	}
+	sort.Strings(s.Files)
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(workDir, StateFile), b, 0644)
+}
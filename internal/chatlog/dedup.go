@@ -0,0 +1,66 @@
+package chatlog
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+	"github.com/DanielMao1/chatlog/internal/chatlog/database"
+	"github.com/DanielMao1/chatlog/internal/chatlog/dedup"
+	"github.com/DanielMao1/chatlog/internal/errors"
+	"github.com/DanielMao1/chatlog/pkg/util/dat2img"
+)
+
+// CommandDedup 查找重复的媒体文件，opts.Dir 为空时查找数据目录
+// 数据目录中的文件由微信管理，只统计不合并；合并只用于 --dir 指定的目录
+func (m *Manager) CommandDedup(configPath string, cmdConf map[string]any, opts dedup.Options) (*dedup.Result, error) {
+
+	var err error
+	m.sc, m.scm, err = conf.LoadServiceConfig(configPath, cmdConf)
+	if err != nil {
+		return nil, err
+	}
+
+	dataDir := m.sc.GetDataDir()
+	if len(opts.Dir) == 0 {
+		if len(dataDir) == 0 {
+			return nil, errors.ConfigRequired("dataDir")
+		}
+		opts.Dir = dataDir
+	}
+	if opts.Link && len(dataDir) != 0 && filepath.Clean(opts.Dir) == filepath.Clean(dataDir) {
+		return nil, fmt.Errorf("refusing to hard link files in the WeChat data dir %s", dataDir)
+	}
+
+	// 聊天对象名称只用于展示，工作目录不可用时按 md5 显示
+	if len(m.sc.GetWorkDir()) != 0 {
+		m.db = database.NewService(m.sc)
+		if err := m.db.Start(); err == nil {
+			defer m.db.Stop()
+			if resp, err := m.db.GetSessions(m.runCtx, "", 0, 0); err == nil {
+				opts.Talkers = make(map[string]string, len(resp.Items))
+				for _, s := range resp.Items {
+					sum := md5.Sum([]byte(s.UserName))
+					name := s.NickName
+					if len(name) == 0 {
+						name = s.UserName
+					}
+					opts.Talkers[hex.EncodeToString(sum[:])] = name
+				}
+			}
+		}
+	}
+
+	if m.sc.GetVersion() == 4 && len(dataDir) != 0 {
+		dat2img.SetAesKey(m.sc.GetImgKey())
+		dat2img.ScanAndSetXorKey(dataDir)
+	}
+	opts.Decrypt = func(data []byte) ([]byte, error) {
+		out, _, err := dat2img.Dat2ImageContext(m.runCtx, data)
+		return out, err
+	}
+
+	return dedup.Run(opts)
+}
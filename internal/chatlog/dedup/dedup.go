@@ -0,0 +1,277 @@
+// Package dedup 查找重复的媒体文件，统计可以节省的空间，并可以用硬链接合并重复文件
+package dedup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultMinSize 默认参与查找的最小文件大小，更小的文件节省的空间有限
+const DefaultMinSize = 4 << 10
+
+// skipExts 不属于媒体的文件：数据库、日志与临时文件
+var skipExts = map[string]bool{
+	".db": true, ".db-wal": true, ".db-shm": true, ".db-journal": true,
+	".sqlite": true, ".sqlite-wal": true, ".sqlite-shm": true,
+	".log": true, ".tmp": true, ".json": true,
+}
+
+// talkerDir 媒体路径中以聊天对象 ID 的 md5 命名的目录，如 msg/attach/<md5>/
+var talkerDir = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// Options 查找参数
+type Options struct {
+	Dir     string            // 查找的目录
+	MinSize int64             // 小于该大小的文件不参与查找，0 为 DefaultMinSize
+	Talkers map[string]string // 聊天对象 ID 的 md5 到名称的映射，用于按聊天对象统计
+	Link    bool              // 用硬链接合并内容完全相同的文件
+	DryRun  bool              // 只统计可以合并的文件，不修改文件
+
+	// Decrypt 返回 .dat 图片解密后的内容，为空或失败时按原始内容比较
+	// 解密后比较可以找到同一张图片加密为不同文件的情况
+	Decrypt func([]byte) ([]byte, error)
+}
+
+// File 一个重复的文件
+type File struct {
+	Path   string `json:"path"` // 相对于查找目录的路径
+	Size   int64  `json:"size"`
+	Talker string `json:"talker"` // 所属的聊天对象，无法识别时为空
+}
+
+// Group 内容相同的一组文件
+type Group struct {
+	Hash    string   `json:"hash"`
+	Size    int64    `json:"size"` // 保留的文件的大小
+	Files   []*File  `json:"files"`
+	Talkers []string `json:"talkers"` // 涉及的聊天对象
+	Wasted  int64    `json:"wasted"`  // 除一个文件外其他文件占用的空间
+}
+
+// Result 查找结果
+type Result struct {
+	Dir       string   `json:"dir"`
+	Files     int      `json:"files"`     // 参与查找的文件数
+	Size      int64    `json:"size"`      // 参与查找的文件总大小
+	Groups    []*Group `json:"groups"`    // 按浪费的空间降序排列
+	Wasted    int64    `json:"wasted"`    // 重复文件占用的空间，即合并后可以节省的空间
+	Cross     int      `json:"cross"`     // 出现在多个聊天对象中的重复组数
+	Linked    int      `json:"linked"`    // 已经或将要替换为硬链接的文件数
+	Reclaimed int64    `json:"reclaimed"` // 硬链接合并节省的空间
+	DryRun    bool     `json:"dryRun"`
+}
+
+// candidate 待比较的文件
+type candidate struct {
+	path string
+	size int64
+}
+
+// Run 查找目录中内容相同的文件
+// 先按大小分组，只计算大小相同的文件的哈希，Link 为 true 时将原始内容完全相同的文件替换为硬链接
+func Run(opts Options) (*Result, error) {
+	if len(opts.Dir) == 0 {
+		return nil, fmt.Errorf("dir is required")
+	}
+	if opts.MinSize <= 0 {
+		opts.MinSize = DefaultMinSize
+	}
+
+	ret := &Result{Dir: opts.Dir, Groups: make([]*Group, 0), DryRun: opts.DryRun}
+	bySize := make(map[int64][]*candidate)
+	seen := make(map[uint64]bool)
+	err := filepath.WalkDir(opts.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			log.Debug().Err(err).Str("path", path).Msg("walk media dir failed")
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !d.Type().IsRegular() || skipExts[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() < opts.MinSize {
+			return nil
+		}
+		// 已经是硬链接的文件只计算一次
+		if ino := inode(info); ino != 0 {
+			if seen[ino] {
+				return nil
+			}
+			seen[ino] = true
+		}
+		ret.Files++
+		ret.Size += info.Size()
+		bySize[info.Size()] = append(bySize[info.Size()], &candidate{path: path, size: info.Size()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, list := range bySize {
+		if len(list) < 2 && opts.Decrypt == nil {
+			continue
+		}
+		ret.Groups = append(ret.Groups, groups(opts, list)...)
+	}
+
+	// 解密后比较时，大小不同的 .dat 文件也可能内容相同，合并同一哈希的组
+	merged := make(map[string]*Group)
+	for _, g := range ret.Groups {
+		if m, ok := merged[g.Hash]; ok {
+			m.Files = append(m.Files, g.Files...)
+			continue
+		}
+		merged[g.Hash] = g
+	}
+	ret.Groups = ret.Groups[:0]
+	for _, g := range merged {
+		if len(g.Files) < 2 {
+			continue
+		}
+		// 保留最大的文件，其他文件占用的空间可以节省
+		sort.Slice(g.Files, func(i, j int) bool {
+			if g.Files[i].Size != g.Files[j].Size {
+				return g.Files[i].Size > g.Files[j].Size
+			}
+			return g.Files[i].Path < g.Files[j].Path
+		})
+		g.Size = g.Files[0].Size
+		for _, f := range g.Files[1:] {
+			g.Wasted += f.Size
+		}
+		g.Talkers = talkers(g.Files)
+		ret.Groups = append(ret.Groups, g)
+		ret.Wasted += g.Wasted
+		if len(g.Talkers) > 1 {
+			ret.Cross++
+		}
+	}
+	sort.Slice(ret.Groups, func(i, j int) bool {
+		if ret.Groups[i].Wasted != ret.Groups[j].Wasted {
+			return ret.Groups[i].Wasted > ret.Groups[j].Wasted
+		}
+		return ret.Groups[i].Hash < ret.Groups[j].Hash
+	})
+
+	if opts.Link {
+		for _, g := range ret.Groups {
+			link(opts, ret, g)
+		}
+	}
+	return ret, nil
+}
+
+// groups 计算大小相同的一组文件的哈希，返回每个哈希对应的文件
+func groups(opts Options, list []*candidate) []*Group {
+	byHash := make(map[string]*Group)
+	for _, c := range list {
+		data, err := os.ReadFile(c.path)
+		if err != nil {
+			log.Debug().Err(err).Str("path", c.path).Msg("read media failed")
+			continue
+		}
+		if opts.Decrypt != nil && strings.EqualFold(filepath.Ext(c.path), ".dat") {
+			if out, err := opts.Decrypt(data); err == nil {
+				data = out
+			}
+		} else if len(list) < 2 {
+			continue
+		}
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:])
+		g, ok := byHash[hash]
+		if !ok {
+			g = &Group{Hash: hash}
+			byHash[hash] = g
+		}
+		g.Files = append(g.Files, &File{Path: rel(opts.Dir, c.path), Size: c.size, Talker: talker(opts, c.path)})
+	}
+	ret := make([]*Group, 0, len(byHash))
+	for _, g := range byHash {
+		ret = append(ret, g)
+	}
+	return ret
+}
+
+// link 将组中原始内容完全相同的文件替换为指向其中第一个文件的硬链接
+// 解密后相同但加密内容不同的文件不能互相替换，保持不变
+func link(opts Options, ret *Result, g *Group) {
+	keep := make(map[[sha256.Size]byte]string)
+	for _, f := range g.Files {
+		path := filepath.Join(opts.Dir, f.Path)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		sum := sha256.Sum256(data)
+		first, ok := keep[sum]
+		if !ok {
+			keep[sum] = path
+			continue
+		}
+		if !opts.DryRun {
+			// 先链接到临时文件再替换，失败时原文件不受影响
+			tmp := path + ".dedup"
+			if err := os.Link(first, tmp); err != nil {
+				log.Debug().Err(err).Str("path", path).Msg("hard link failed")
+				continue
+			}
+			if err := os.Rename(tmp, path); err != nil {
+				os.Remove(tmp)
+				log.Debug().Err(err).Str("path", path).Msg("replace with hard link failed")
+				continue
+			}
+		}
+		ret.Linked++
+		ret.Reclaimed += f.Size
+	}
+}
+
+// talker 从媒体路径中以 md5 命名的目录识别聊天对象
+func talker(opts Options, path string) string {
+	for _, part := range strings.Split(filepath.ToSlash(rel(opts.Dir, path)), "/") {
+		if talkerDir.MatchString(part) {
+			if name, ok := opts.Talkers[part]; ok {
+				return name
+			}
+			return part
+		}
+	}
+	return ""
+}
+
+// talkers 返回文件涉及的聊天对象，按名称排列
+func talkers(files []*File) []string {
+	set := make(map[string]bool)
+	for _, f := range files {
+		if len(f.Talker) != 0 {
+			set[f.Talker] = true
+		}
+	}
+	ret := make([]string, 0, len(set))
+	for t := range set {
+		ret = append(ret, t)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func rel(dir, path string) string {
+	if r, err := filepath.Rel(dir, path); err == nil {
+		return r
+	}
+	return path
+}
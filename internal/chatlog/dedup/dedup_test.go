@@ -0,0 +1,85 @@
+package dedup
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	alice := "0123456789abcdef0123456789abcdef"
+	bob := "fedcba9876543210fedcba9876543210"
+	photo := bytes.Repeat([]byte("photo"), 2000)
+	write := func(path string, data []byte) {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join("msg", "attach", alice, "2024-01", "Img", "a.jpg"), photo)
+	write(filepath.Join("msg", "attach", bob, "2024-02", "Img", "b.jpg"), photo)
+	write(filepath.Join("msg", "attach", bob, "2024-02", "Img", "c.jpg"), bytes.Repeat([]byte("other"), 2000))
+	// 加密后内容不同，解密后与 a.jpg 相同
+	write(filepath.Join("msg", "attach", bob, "2024-03", "Img", "d.dat"), append([]byte("enc"), photo...))
+	write(filepath.Join("db_storage", "message", "message_0.db"), photo)
+	write(filepath.Join("msg", "file", "small.txt"), []byte("tiny"))
+
+	decrypt := func(data []byte) ([]byte, error) {
+		if !bytes.HasPrefix(data, []byte("enc")) {
+			return nil, fmt.Errorf("not encrypted")
+		}
+		return data[3:], nil
+	}
+	ret, err := Run(Options{Dir: dir, Talkers: map[string]string{alice: "Alice"}, Decrypt: decrypt, DryRun: true, Link: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.Files != 4 || len(ret.Groups) != 1 || ret.Cross != 1 {
+		t.Fatalf("Files, Groups, Cross = %d, %d, %d", ret.Files, len(ret.Groups), ret.Cross)
+	}
+	g := ret.Groups[0]
+	if len(g.Files) != 3 || g.Files[0].Size != int64(len(photo))+3 || g.Wasted != 2*int64(len(photo)) {
+		t.Errorf("group = %+v", g)
+	}
+	if len(g.Talkers) != 2 || g.Talkers[0] != "Alice" || g.Talkers[1] != bob {
+		t.Errorf("Talkers = %v", g.Talkers)
+	}
+	// 只有原始内容相同的文件可以合并，d.dat 保留
+	if ret.Linked != 1 || ret.Reclaimed != int64(len(photo)) {
+		t.Errorf("Linked, Reclaimed = %d, %d in dry run", ret.Linked, ret.Reclaimed)
+	}
+	same := func() bool {
+		a, _ := os.Stat(filepath.Join(dir, "msg", "attach", alice, "2024-01", "Img", "a.jpg"))
+		b, _ := os.Stat(filepath.Join(dir, "msg", "attach", bob, "2024-02", "Img", "b.jpg"))
+		return os.SameFile(a, b)
+	}
+	if same() {
+		t.Errorf("dry run linked files")
+	}
+
+	ret, err = Run(Options{Dir: dir, Link: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.Linked != 1 || ret.Reclaimed != int64(len(photo)) {
+		t.Fatalf("Linked, Reclaimed = %d, %d", ret.Linked, ret.Reclaimed)
+	}
+	if !same() {
+		t.Errorf("duplicates are not hard linked")
+	}
+
+	// 已经是硬链接的文件只计算一次
+	ret, err = Run(Options{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.Files != 3 || len(ret.Groups) != 0 {
+		t.Errorf("after linking Files, Groups = %d, %d", ret.Files, len(ret.Groups))
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package dedup
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// inode 返回文件的 inode，用于识别已经是硬链接的文件
+func inode(info fs.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Ino)
+	}
+	return 0
+}
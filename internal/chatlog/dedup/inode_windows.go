@@ -0,0 +1,10 @@
+//go:build windows
+
+package dedup
+
+import "io/fs"
+
+// inode Windows 的文件信息中没有 inode，硬链接的文件分别计算
+func inode(info fs.FileInfo) uint64 {
+	return 0
+}
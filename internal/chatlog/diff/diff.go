@@ -0,0 +1,155 @@
+package diff
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/DanielMao1/chatlog/internal/errors"
+	"github.com/DanielMao1/chatlog/internal/model"
+	"github.com/DanielMao1/chatlog/internal/wechatdb"
+	"github.com/DanielMao1/chatlog/pkg/util"
+)
+
+// TalkerDiff 单个聊天对象的差异
+type TalkerDiff struct {
+	Talker     string           `json:"talker"`
+	TalkerName string           `json:"talkerName,omitempty"`
+	Added      []*model.Message `json:"added,omitempty"`
+	Deleted    []*model.Message `json:"deleted,omitempty"`
+	Recalled   []*model.Message `json:"recalled,omitempty"`
+}
+
+// Result 两个快照之间的差异，Recalled 中为旧快照中被撤回的原始消息
+type Result struct {
+	Old      string        `json:"old"`
+	New      string        `json:"new"`
+	Added    int           `json:"added"`
+	Deleted  int           `json:"deleted"`
+	Recalled int           `json:"recalled"`
+	Talkers  []*TalkerDiff `json:"talkers"`
+}
+
+// Compare 逐个聊天对象比较两个快照中的消息，talker 为空时比较所有会话
+func Compare(ctx context.Context, oldDB, newDB *wechatdb.DB, talker string) (*Result, error) {
+	talkers := util.Str2List(talker, ",")
+	names := make(map[string]string)
+	if len(talkers) == 0 {
+		// 会话被整个删除时只存在于旧快照中，需要合并两边的会话列表
+		seen := make(map[string]bool)
+		for _, db := range []*wechatdb.DB{oldDB, newDB} {
+			resp, err := db.GetSessions(ctx, "", 0, 0)
+			if err != nil {
+				return nil, err
+			}
+			for _, s := range resp.Items {
+				if !seen[s.UserName] {
+					seen[s.UserName] = true
+					talkers = append(talkers, s.UserName)
+				}
+				if len(s.NickName) != 0 {
+					names[s.UserName] = s.NickName
+				}
+			}
+		}
+	}
+
+	ret := &Result{Talkers: make([]*TalkerDiff, 0)}
+	for _, t := range talkers {
+		oldMsgs, err := getMessages(ctx, oldDB, t)
+		if err != nil {
+			return nil, err
+		}
+		newMsgs, err := getMessages(ctx, newDB, t)
+		if err != nil {
+			return nil, err
+		}
+
+		d := compareMessages(oldMsgs, newMsgs)
+		if len(d.Added) == 0 && len(d.Deleted) == 0 && len(d.Recalled) == 0 {
+			continue
+		}
+		d.Talker = t
+		d.TalkerName = names[t]
+		for _, msgs := range [][]*model.Message{newMsgs, oldMsgs} {
+			if len(d.TalkerName) == 0 && len(msgs) > 0 {
+				d.TalkerName = msgs[0].TalkerName
+			}
+		}
+		ret.Added += len(d.Added)
+		ret.Deleted += len(d.Deleted)
+		ret.Recalled += len(d.Recalled)
+		ret.Talkers = append(ret.Talkers, d)
+	}
+
+	sort.SliceStable(ret.Talkers, func(i, j int) bool {
+		a, b := ret.Talkers[i], ret.Talkers[j]
+		return len(a.Deleted)+len(a.Recalled) > len(b.Deleted)+len(b.Recalled)
+	})
+	return ret, nil
+}
+
+// getMessages 返回聊天对象的全部消息，快照中不存在该聊天对象时返回空
+func getMessages(ctx context.Context, db *wechatdb.DB, talker string) ([]*model.Message, error) {
+	msgs, err := db.GetMessages(ctx, time.Unix(0, 0), time.Now().AddDate(1, 0, 0), talker, "", "", 0, 0)
+	if err != nil {
+		if errors.GetCode(err) == http.StatusNotFound {
+			log.Debug().Err(err).Str("talker", talker).Msg("no messages in snapshot")
+			return nil, nil
+		}
+		return nil, err
+	}
+	return msgs, nil
+}
+
+// compareMessages 按消息序号比较，同一序号的消息在新快照中变为系统消息视为撤回
+func compareMessages(oldMsgs, newMsgs []*model.Message) *TalkerDiff {
+	d := &TalkerDiff{}
+
+	newIndex := make(map[string]*model.Message, len(newMsgs))
+	for k, m := range keys(newMsgs) {
+		newIndex[k] = m
+	}
+
+	oldKeys := keys(oldMsgs)
+	for k, m := range oldKeys {
+		n, ok := newIndex[k]
+		switch {
+		case !ok:
+			d.Deleted = append(d.Deleted, m)
+		case m.Type != model.MessageTypeSystem && n.Type == model.MessageTypeSystem:
+			d.Recalled = append(d.Recalled, m)
+		}
+	}
+	for k, m := range newIndex {
+		if _, ok := oldKeys[k]; !ok {
+			d.Added = append(d.Added, m)
+		}
+	}
+
+	for _, msgs := range [][]*model.Message{d.Added, d.Deleted, d.Recalled} {
+		sort.Slice(msgs, func(i, j int) bool { return msgs[i].Time.Before(msgs[j].Time) })
+	}
+	return d
+}
+
+// keys 为消息生成比较用的键
+// macOS 3.x 的消息没有序号，使用时间、发送人和同一秒内的顺序代替
+func keys(msgs []*model.Message) map[string]*model.Message {
+	ret := make(map[string]*model.Message, len(msgs))
+	count := make(map[string]int)
+	for _, m := range msgs {
+		if m.Seq != 0 {
+			ret[fmt.Sprint(m.Seq)] = m
+			continue
+		}
+		k := fmt.Sprintf("%d|%s", m.Time.Unix(), m.Sender)
+		ret[fmt.Sprintf("%s|%d", k, count[k])] = m
+		count[k]++
+	}
+	return ret
+}
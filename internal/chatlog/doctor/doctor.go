@@ -0,0 +1,253 @@
+package doctor
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/DanielMao1/chatlog/internal/wechat"
+	"github.com/DanielMao1/chatlog/internal/wechat/decrypt"
+	"github.com/DanielMao1/chatlog/internal/wechat/key/darwin/glance"
+	"github.com/DanielMao1/chatlog/pkg/util"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+	StatusSkip = "skip"
+)
+
+// MinFreeSpace 工作目录所在磁盘的最小剩余空间
+const MinFreeSpace = 1 << 30
+
+// Result 单项检查结果
+type Result struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Fix     string `json:"fix,omitempty"`
+}
+
+type Config interface {
+	GetDataDir() string
+	GetDataKey() string
+	GetWorkDir() string
+	GetPlatform() string
+	GetVersion() int
+}
+
+// Run 依次执行所有检查
+func Run(conf Config) []*Result {
+	return []*Result{
+		CheckSIP(),
+		CheckProcess(),
+		CheckDataDir(conf.GetDataDir()),
+		CheckFullDiskAccess(),
+		CheckKey(conf.GetPlatform(), conf.GetVersion(), conf.GetDataDir(), conf.GetDataKey()),
+		CheckDiskSpace(conf.GetWorkDir(), conf.GetDataDir()),
+	}
+}
+
+// Failed 返回是否存在失败的检查项
+func Failed(results []*Result) bool {
+	for _, r := range results {
+		if r.Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckConfig 将配置加载错误转换为检查结果
+func CheckConfig(err error) *Result {
+	r := &Result{Name: "config"}
+	if err != nil {
+		r.Status = StatusFail
+		r.Message = err.Error()
+		r.Fix = "check the JSON syntax of the config file, or remove it to regenerate defaults"
+		return r
+	}
+	r.Status = StatusOK
+	r.Message = "config loaded"
+	return r
+}
+
+func CheckSIP() *Result {
+	r := &Result{Name: "sip"}
+	if runtime.GOOS != "darwin" {
+		r.Status = StatusSkip
+		r.Message = "only required on macOS"
+		return r
+	}
+	if glance.IsSIPDisabled() {
+		r.Status = StatusOK
+		r.Message = "SIP is disabled"
+		return r
+	}
+	r.Status = StatusWarn
+	r.Message = "SIP is enabled, key extraction will fail"
+	r.Fix = "boot into Recovery Mode and run `csrutil disable`, or provide the key with --data-key"
+	return r
+}
+
+func CheckProcess() *Result {
+	r := &Result{Name: "process"}
+	if err := wechat.Load(); err != nil {
+		r.Status = StatusFail
+		r.Message = err.Error()
+		return r
+	}
+	accounts := wechat.GetAccounts()
+	if len(accounts) == 0 {
+		r.Status = StatusWarn
+		r.Message = "no running WeChat process found"
+		r.Fix = "start WeChat and log in if you need to extract keys"
+		return r
+	}
+	items := make([]string, 0, len(accounts))
+	for _, a := range accounts {
+		items = append(items, fmt.Sprintf("%s(pid %d, %s v%s, %s)", a.Name, a.PID, a.Platform, a.FullVersion, a.Status))
+	}
+	r.Status = StatusOK
+	r.Message = strings.Join(items, "; ")
+	return r
+}
+
+func CheckDataDir(dataDir string) *Result {
+	r := &Result{Name: "data_dir"}
+	if dataDir == "" {
+		r.Status = StatusWarn
+		r.Message = "data dir is not configured"
+		r.Fix = "pass --data-dir or run `chatlog key` with WeChat running"
+		return r
+	}
+	if _, err := os.ReadDir(dataDir); err != nil {
+		r.Status = StatusFail
+		r.Message = err.Error()
+		if os.IsPermission(err) && runtime.GOOS == "darwin" {
+			r.Fix = "grant Full Disk Access to your terminal in System Settings > Privacy & Security"
+		} else {
+			r.Fix = "check that the data dir exists and is readable"
+		}
+		return r
+	}
+	r.Status = StatusOK
+	r.Message = dataDir
+	return r
+}
+
+func CheckFullDiskAccess() *Result {
+	r := &Result{Name: "full_disk_access"}
+	if runtime.GOOS != "darwin" {
+		r.Status = StatusSkip
+		r.Message = "only required on macOS"
+		return r
+	}
+	// TCC.db 只有在授予完全磁盘访问权限后才能读取
+	f, err := os.Open("/Library/Application Support/com.apple.TCC/TCC.db")
+	if err != nil {
+		r.Status = StatusWarn
+		r.Message = "terminal does not have Full Disk Access"
+		r.Fix = "grant Full Disk Access to your terminal in System Settings > Privacy & Security"
+		return r
+	}
+	f.Close()
+	r.Status = StatusOK
+	r.Message = "granted"
+	return r
+}
+
+func CheckKey(platform string, version int, dataDir string, dataKey string) *Result {
+	r := &Result{Name: "data_key"}
+	if dataKey == "" {
+		r.Status = StatusWarn
+		r.Message = "data key is not configured"
+		r.Fix = "run `chatlog key` with WeChat running, or pass --data-key"
+		return r
+	}
+	if dataDir == "" {
+		r.Status = StatusSkip
+		r.Message = "data dir is not configured"
+		return r
+	}
+
+	validator, err := decrypt.NewValidator(platform, version, dataDir)
+	if err != nil {
+		r.Status = StatusFail
+		r.Message = err.Error()
+		r.Fix = "check platform, version and data dir"
+		return r
+	}
+
+	valid := false
+	if strings.HasPrefix(dataKey, "derived:") {
+		for _, k := range strings.Split(strings.TrimPrefix(dataKey, "derived:"), ",") {
+			if b, err := hex.DecodeString(k); err == nil && validator.ValidateDerivedKey(b) {
+				valid = true
+				break
+			}
+		}
+	} else if b, err := hex.DecodeString(dataKey); err == nil {
+		valid = validator.Validate(b)
+	}
+
+	if !valid {
+		r.Status = StatusFail
+		r.Message = "data key does not match the database header"
+		r.Fix = "run `chatlog key --force` to extract a fresh key"
+		return r
+	}
+	r.Status = StatusOK
+	r.Message = "data key matches the database header"
+	return r
+}
+
+func CheckDiskSpace(workDir string, dataDir string) *Result {
+	r := &Result{Name: "disk_space"}
+	if workDir == "" {
+		r.Status = StatusSkip
+		r.Message = "work dir is not configured"
+		return r
+	}
+
+	// 工作目录可能尚未创建，检查已存在的上级目录所在的磁盘
+	freeSpace, path, err := util.FreeSpace(workDir)
+	if err != nil {
+		r.Status = StatusWarn
+		r.Message = err.Error()
+		return r
+	}
+
+	var need int64 = MinFreeSpace
+	if dataDir != "" {
+		if size := dirSize(filepath.Join(dataDir, "db_storage")); size > need {
+			need = size
+		}
+	}
+
+	free := util.ByteCountSI(freeSpace)
+	if freeSpace < need {
+		r.Status = StatusFail
+		r.Message = fmt.Sprintf("%s free on %s, need about %s", free, path, util.ByteCountSI(need))
+		r.Fix = "free up disk space or choose another --work-dir"
+		return r
+	}
+	r.Status = StatusOK
+	r.Message = fmt.Sprintf("%s free on %s", free, path)
+	return r
+}
+
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
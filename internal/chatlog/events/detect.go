@@ -0,0 +1,274 @@
+package events
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultDuration 未说明结束时间的约定默认持续的时长
+const DefaultDuration = time.Hour
+
+// Event 从一条消息中识别出的约定
+type Event struct {
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	AllDay   bool      `json:"allDay"`             // 只有日期没有时间
+	Text     string    `json:"text"`               // 识别出的日期与时间原文
+	Location string    `json:"location,omitempty"` // 识别出的地点
+}
+
+var (
+	// 日期：2024年3月5日、3月5号、今天、明天、后天、下周四、星期五、tomorrow、next Thursday、Mar 5
+	reCNDate     = regexp.MustCompile(`(?:(\d{4})年)?(\d{1,2})月(\d{1,2})[日号]`)
+	reCNRelDay   = regexp.MustCompile(`(大后天|后天|明天|明日|今天|今日|今晚|昨天|前天)`)
+	reCNWeekday  = regexp.MustCompile(`(下个?|这个?|本)?(?:周|星期|礼拜)([一二三四五六日天])`)
+	reENRelDay   = regexp.MustCompile(`(?i)\b(today|tonight|tomorrow|yesterday)\b`)
+	reENWeekday  = regexp.MustCompile(`(?i)\b(next |this )?(monday|tuesday|wednesday|thursday|friday|saturday|sunday|mon|tue|tues|wed|thu|thur|thurs|fri|sat|sun)\b`)
+	reENMonthDay = regexp.MustCompile(`(?i)\b(jan|feb|mar|apr|may|jun|jul|aug|sep|sept|oct|nov|dec)[a-z]*\.? (\d{1,2})(?:st|nd|rd|th)?\b`)
+
+	// 时间：下午3点半、晚上七点、15:30、3pm、3:30 pm
+	reCNTime = regexp.MustCompile(`(凌晨|早上|早晨|上午|中午|下午|傍晚|晚上)?(\d{1,2}|[零一二两三四五六七八九十]{1,3})(?:[点:：]|点钟)(半|一刻|三刻|\d{1,2}分?|[零一二三四五六七八九十]{1,3}分)?`)
+	reENTime = regexp.MustCompile(`(?i)\b(\d{1,2})(?::(\d{2}))?\s*(am|pm)\b|\b(\d{1,2}):(\d{2})\b`)
+
+	// 约定相关的词语，只有时间或只有日期时需要同时出现
+	reKeyword = regexp.MustCompile(`(?i)开会|会议|见面|见|约|碰头|面试|聚餐|吃饭|电话|视频|沟通|讨论|集合|出发|meeting|meet|call|see you|lunch|dinner|breakfast|interview|appointment|sync`)
+
+	// 地点：在公司见、在星巴克开会、at the office
+	reCNLocation = regexp.MustCompile(`在([^\s，,。！？!?、]{1,15}?)(?:见面|见|开会|碰头|集合|吃饭|聚餐)`)
+	reENLocation = regexp.MustCompile(`(?i)\bat ((?:the )?[a-z][a-z' ]{1,30}?)(?:[.,!?;]|$| on | at | tomorrow| today| next )`)
+)
+
+var cnNumbers = map[rune]int{'零': 0, '一': 1, '二': 2, '两': 2, '三': 3, '四': 4, '五': 5, '六': 6, '七': 7, '八': 8, '九': 9}
+
+var enMonths = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April, "may": time.May, "jun": time.June,
+	"jul": time.July, "aug": time.August, "sep": time.September, "oct": time.October, "nov": time.November, "dec": time.December,
+}
+
+var enWeekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Detect 识别消息中的约定，ref 为消息发送的时间，用于推算“明天”“下周四”等相对日期
+// 同时有日期和时间，或者只有其中之一但出现了开会、见面等词语时才认为是约定；推算出的时间早于消息时间时忽略
+func Detect(text string, ref time.Time) *Event {
+	date, dateText, hasDate := detectDate(text, ref)
+	hour, minute, timeText, hasTime := detectTime(text)
+	if !hasDate && !hasTime {
+		return nil
+	}
+	if !(hasDate && hasTime) && !reKeyword.MatchString(text) {
+		return nil
+	}
+
+	e := &Event{Text: strings.TrimSpace(dateText + " " + timeText), Location: detectLocation(text)}
+	if !hasDate {
+		date = time.Date(ref.Year(), ref.Month(), ref.Day(), 0, 0, 0, 0, ref.Location())
+	}
+	if hasTime {
+		// 今晚 7 点
+		if hour < 12 && (dateText == "今晚" || strings.EqualFold(dateText, "tonight")) {
+			hour += 12
+		}
+		e.Start = time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, ref.Location())
+		// 只有时间且已经过去时，指的是第二天
+		if !hasDate && e.Start.Before(ref) {
+			e.Start = e.Start.AddDate(0, 0, 1)
+		}
+		e.End = e.Start.Add(DefaultDuration)
+		if e.Start.Before(ref) {
+			return nil
+		}
+	} else {
+		e.AllDay = true
+		e.Start = date
+		e.End = date.AddDate(0, 0, 1)
+		if e.End.Before(ref) {
+			return nil
+		}
+	}
+	return e
+}
+
+// detectDate 返回消息中第一个日期对应的零点
+func detectDate(text string, ref time.Time) (time.Time, string, bool) {
+	today := time.Date(ref.Year(), ref.Month(), ref.Day(), 0, 0, 0, 0, ref.Location())
+
+	if m := reCNDate.FindStringSubmatch(text); m != nil {
+		month, _ := strconv.Atoi(m[2])
+		day, _ := strconv.Atoi(m[3])
+		if month >= 1 && month <= 12 && day >= 1 && day <= 31 {
+			if len(m[1]) != 0 {
+				year, _ := strconv.Atoi(m[1])
+				return time.Date(year, time.Month(month), day, 0, 0, 0, 0, ref.Location()), m[0], true
+			}
+			return nextYearly(today, time.Month(month), day), m[0], true
+		}
+	}
+	if m := reENMonthDay.FindStringSubmatch(text); m != nil {
+		day, _ := strconv.Atoi(m[2])
+		if day >= 1 && day <= 31 {
+			return nextYearly(today, enMonths[strings.ToLower(m[1][:3])], day), m[0], true
+		}
+	}
+	if m := reCNRelDay.FindStringSubmatch(text); m != nil {
+		offset := map[string]int{"今天": 0, "今日": 0, "今晚": 0, "明天": 1, "明日": 1, "后天": 2, "大后天": 3, "昨天": -1, "前天": -2}[m[1]]
+		return today.AddDate(0, 0, offset), m[0], true
+	}
+	if m := reENRelDay.FindStringSubmatch(text); m != nil {
+		offset := map[string]int{"tomorrow": 1, "yesterday": -1}[strings.ToLower(m[1])]
+		return today.AddDate(0, 0, offset), m[0], true
+	}
+	if m := reCNWeekday.FindStringSubmatch(text); m != nil {
+		wd := time.Weekday(strings.IndexRune("日一二三四五六", []rune(m[2])[0]) / len("一"))
+		if m[2] == "天" {
+			wd = time.Sunday
+		}
+		return weekday(today, wd, strings.HasPrefix(m[1], "下"), len(m[1]) != 0 && !strings.HasPrefix(m[1], "下")), m[0], true
+	}
+	if m := reENWeekday.FindStringSubmatch(text); m != nil {
+		wd := enWeekdays[strings.ToLower(m[2])[:3]]
+		prefix := strings.ToLower(strings.TrimSpace(m[1]))
+		return weekday(today, wd, prefix == "next", prefix == "this"), m[0], true
+	}
+	return time.Time{}, "", false
+}
+
+// nextYearly 没有年份的日期，已经过去一个月以上时为明年
+func nextYearly(today time.Time, month time.Month, day int) time.Time {
+	d := time.Date(today.Year(), month, day, 0, 0, 0, 0, today.Location())
+	if d.Before(today.AddDate(0, -1, 0)) {
+		d = d.AddDate(1, 0, 0)
+	}
+	return d
+}
+
+// weekday 推算星期几的日期，周一为一周的开始
+// next 为下周的该日，this 为本周的该日，都没有时为今天或之后最近的该日
+func weekday(today time.Time, wd time.Weekday, next, this bool) time.Time {
+	if next || this {
+		// 本周一
+		monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+		d := monday.AddDate(0, 0, (int(wd)+6)%7)
+		if next {
+			d = d.AddDate(0, 0, 7)
+		}
+		return d
+	}
+	return today.AddDate(0, 0, (int(wd)-int(today.Weekday())+7)%7)
+}
+
+// detectTime 返回消息中第一个时间的小时与分钟
+func detectTime(text string) (int, int, string, bool) {
+	if m := reENTime.FindStringSubmatch(text); m != nil {
+		if len(m[1]) != 0 {
+			hour, _ := strconv.Atoi(m[1])
+			minute, _ := strconv.Atoi(m[2])
+			if hour < 1 || hour > 12 || minute > 59 {
+				return 0, 0, "", false
+			}
+			if strings.EqualFold(m[3], "pm") && hour < 12 {
+				hour += 12
+			} else if strings.EqualFold(m[3], "am") && hour == 12 {
+				hour = 0
+			}
+			return hour, minute, m[0], true
+		}
+		hour, _ := strconv.Atoi(m[4])
+		minute, _ := strconv.Atoi(m[5])
+		if hour <= 23 && minute <= 59 {
+			return adjustHour(hour, ""), minute, m[0], true
+		}
+	}
+	for _, m := range reCNTime.FindAllStringSubmatch(text, -1) {
+		// “有一点”“快一点”不是时间
+		if m[2] == "一" && len(m[1]) == 0 && len(m[3]) == 0 {
+			continue
+		}
+		hour := parseNumber(m[2])
+		if hour < 0 || hour > 24 {
+			continue
+		}
+		minute := 0
+		switch suffix := strings.TrimSuffix(m[3], "分"); suffix {
+		case "":
+		case "半":
+			minute = 30
+		case "一刻":
+			minute = 15
+		case "三刻":
+			minute = 45
+		default:
+			minute = parseNumber(suffix)
+		}
+		if minute < 0 || minute > 59 {
+			continue
+		}
+		return adjustHour(hour, m[1]), minute, m[0], true
+	}
+	return 0, 0, "", false
+}
+
+// adjustHour 按上午、下午等换算为 24 小时制，没有说明时 1 到 6 点视为下午
+func adjustHour(hour int, period string) int {
+	switch period {
+	case "下午", "傍晚", "晚上":
+		if hour < 12 {
+			hour += 12
+		}
+	case "中午":
+		if hour < 6 {
+			hour += 12
+		}
+	case "":
+		if hour >= 1 && hour <= 6 {
+			hour += 12
+		}
+	}
+	return hour % 24
+}
+
+// parseNumber 解析阿拉伯数字或一百以内的中文数字，无法解析时返回 -1
+func parseNumber(s string) int {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n
+	}
+	n, cur := 0, -1
+	for _, r := range s {
+		if r == '十' {
+			if cur < 0 {
+				cur = 1
+			}
+			n += cur * 10
+			cur = -1
+			continue
+		}
+		v, ok := cnNumbers[r]
+		if !ok {
+			return -1
+		}
+		cur = v
+	}
+	if cur > 0 {
+		n += cur
+	}
+	return n
+}
+
+// detectLocation 返回消息中的地点
+func detectLocation(text string) string {
+	if m := reCNLocation.FindStringSubmatch(text); m != nil {
+		return m[1]
+	}
+	for _, m := range reENLocation.FindAllStringSubmatch(text, -1) {
+		loc := strings.TrimSpace(m[1])
+		// 跳过 at 3pm 等时间
+		if len(loc) != 0 && !reENTime.MatchString(loc) {
+			return loc
+		}
+	}
+	return ""
+}
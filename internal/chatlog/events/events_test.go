@@ -0,0 +1,84 @@
+package events
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDetect(t *testing.T) {
+	// 2024-03-05 周二 10:00
+	ref := time.Date(2024, 3, 5, 10, 0, 0, 0, time.Local)
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2024, month, day, hour, minute, 0, 0, time.Local)
+	}
+	tests := []struct {
+		text     string
+		start    time.Time
+		allDay   bool
+		location string
+	}{
+		{"see you Thursday 3pm at the office", at(3, 7, 15, 0), false, "the office"},
+		{"Lunch tomorrow at 12:30pm?", at(3, 6, 12, 30), false, ""},
+		{"next Monday 9:00 am meeting", at(3, 11, 9, 0), false, ""},
+		{"明天下午3点半在公司见", at(3, 6, 15, 30), false, "公司"},
+		{"下周四上午十点开会", at(3, 14, 10, 0), false, ""},
+		{"今晚7点吃饭", at(3, 5, 19, 0), false, ""},
+		{"3月8号聚餐", at(3, 8, 0, 0), true, ""},
+		{"周日见", at(3, 10, 0, 0), true, ""},
+		{"两点打电话", at(3, 5, 14, 0), false, ""},
+		{"9点见", at(3, 6, 9, 0), false, ""},
+		{"Mar 20 interview", at(3, 20, 0, 0), true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			e := Detect(tt.text, ref)
+			if e == nil {
+				t.Fatal("no event detected")
+			}
+			if !e.Start.Equal(tt.start) || e.AllDay != tt.allDay || e.Location != tt.location {
+				t.Errorf("got start=%s allDay=%v location=%q", e.Start, e.AllDay, e.Location)
+			}
+		})
+	}
+
+	for _, text := range []string{
+		"好的",
+		"我有一点事，明天说",
+		"明天天气不错",
+		"昨天下午3点开会",
+		"3月1日见",
+		"the build took 3:15",
+	} {
+		if e := Detect(text, ref); e != nil {
+			t.Errorf("Detect(%q) = %+v, want nil", text, e)
+		}
+	}
+}
+
+func TestICS(t *testing.T) {
+	start := time.Date(2024, 3, 7, 15, 0, 0, 0, time.UTC)
+	ics := string(ICS("chatlog", []*CalendarEvent{
+		{Event: &Event{Start: start, End: start.Add(time.Hour), Location: "the office"},
+			UID: "1@chatlog", Summary: "Alice: see you Thursday, 3pm", Description: strings.Repeat("很长的描述", 20)},
+		{Event: &Event{Start: start.Truncate(24 * time.Hour), End: start.AddDate(0, 0, 1), AllDay: true},
+			UID: "2@chatlog", Summary: "聚餐"},
+	}))
+
+	for _, want := range []string{
+		"DTSTART:20240307T150000Z\r\n",
+		"SUMMARY:Alice: see you Thursday\\, 3pm\r\n",
+		"LOCATION:the office\r\n",
+		"DTSTART;VALUE=DATE:20240307\r\n",
+		"DTEND;VALUE=DATE:20240308\r\n",
+	} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("missing %q in\n%s", want, ics)
+		}
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(ics, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line too long: %q", line)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package events
+
+import (
+	"bytes"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// CalendarEvent 日历中的一个事件
+type CalendarEvent struct {
+	*Event
+	UID         string
+	Summary     string
+	Description string
+}
+
+// ICS 按 RFC 5545 生成 iCalendar 日历
+func ICS(name string, list []*CalendarEvent) []byte {
+	var b bytes.Buffer
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	writeLine(&b, "BEGIN:VCALENDAR")
+	writeLine(&b, "VERSION:2.0")
+	writeLine(&b, "PRODID:-//chatlog//events//CN")
+	writeLine(&b, "CALSCALE:GREGORIAN")
+	writeLine(&b, "METHOD:PUBLISH")
+	if len(name) != 0 {
+		writeLine(&b, "X-WR-CALNAME:"+escapeText(name))
+	}
+	for _, e := range list {
+		writeLine(&b, "BEGIN:VEVENT")
+		writeLine(&b, "UID:"+escapeText(e.UID))
+		writeLine(&b, "DTSTAMP:"+stamp)
+		if e.AllDay {
+			writeLine(&b, "DTSTART;VALUE=DATE:"+e.Start.Format("20060102"))
+			writeLine(&b, "DTEND;VALUE=DATE:"+e.End.Format("20060102"))
+		} else {
+			writeLine(&b, "DTSTART:"+e.Start.UTC().Format("20060102T150405Z"))
+			writeLine(&b, "DTEND:"+e.End.UTC().Format("20060102T150405Z"))
+		}
+		writeLine(&b, "SUMMARY:"+escapeText(e.Summary))
+		if len(e.Location) != 0 {
+			writeLine(&b, "LOCATION:"+escapeText(e.Location))
+		}
+		if len(e.Description) != 0 {
+			writeLine(&b, "DESCRIPTION:"+escapeText(e.Description))
+		}
+		writeLine(&b, "END:VEVENT")
+	}
+	writeLine(&b, "END:VCALENDAR")
+	return b.Bytes()
+}
+
+// escapeText 转义 TEXT 类型的值
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(s)
+}
+
+// writeLine 写入一行，超过 75 字节时折行，不拆分 UTF-8 字符
+func writeLine(b *bytes.Buffer, line string) {
+	const limit = 75
+	for first := true; ; first = false {
+		max := limit
+		if !first {
+			// 续行以空格开头
+			b.WriteByte(' ')
+			max--
+		}
+		if len(line) <= max {
+			b.WriteString(line)
+			b.WriteString("\r\n")
+			return
+		}
+		n := max
+		for n > 0 && !utf8.RuneStart(line[n]) {
+			n--
+		}
+		b.WriteString(line[:n])
+		b.WriteString("\r\n")
+		line = line[n:]
+	}
+}
@@ -0,0 +1,163 @@
+package chatlog
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	scripting "github.com/DanielMao1/chatlog/internal/chatlog/script"
+	"github.com/DanielMao1/chatlog/internal/i18n"
+	"github.com/DanielMao1/chatlog/internal/model"
+	"github.com/DanielMao1/chatlog/pkg/progress"
+	"github.com/DanielMao1/chatlog/pkg/util"
+)
+
+const (
+	ExportText   = "txt"
+	ExportCSV    = "csv"
+	ExportJSON   = "json"
+	ExportMatrix = "matrix" // Matrix 房间事件 JSON，文件扩展名为 .json
+)
+
+// ExportFormats 支持的导出格式
+var ExportFormats = []string{ExportText, ExportCSV, ExportJSON, ExportMatrix}
+
+// ExportExt 返回导出格式对应的文件扩展名
+func ExportExt(format string) string {
+	if format == ExportMatrix {
+		return ExportJSON
+	}
+	return format
+}
+
+// Export 将聊天对象在时间范围内的消息导出到文件，返回导出的消息数量
+// format 为空时按文件扩展名选择格式，translate 不为空时附上文本消息翻译为该语言的译文，导出进度通过 progress 发布
+// script 不为空时导出前对每条消息运行该脚本，被脚本丢弃的消息不导出；Ctrl-C 时停止导出并删除未写完的文件
+func (m *Manager) Export(talker, timeRange, format, path, translate, script string) (int, error) {
+	if len(talker) == 0 {
+		return 0, fmt.Errorf("talker is required for export")
+	}
+	if len(path) == 0 {
+		return 0, fmt.Errorf("output file is required for export")
+	}
+	if len(format) == 0 {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+
+	if len(timeRange) == 0 {
+		timeRange = "all"
+	}
+	sc, err := scripting.Compile(script)
+	if err != nil {
+		return 0, err
+	}
+	start, end, ok := util.TimeRangeOf(timeRange)
+	if !ok {
+		return 0, fmt.Errorf("invalid time range: %s", timeRange)
+	}
+
+	if m.db.GetDB() == nil {
+		if err := m.db.Start(); err != nil {
+			return 0, i18n.Errorf("数据库未启动: %v", err)
+		}
+	}
+
+	tracker := progress.NewTracker(progress.StageExport, "", 1, 0)
+	defer tracker.Done()
+
+	messages, err := m.db.GetMessages(m.runCtx, start, end, talker, "", "", 0, 0)
+	if err != nil {
+		return 0, err
+	}
+	if len(translate) != 0 {
+		if err := m.translateMessages(messages, translate); err != nil {
+			return 0, err
+		}
+	}
+	if sc != nil {
+		if messages, err = runScript(sc, messages); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := util.PrepareDir(filepath.Dir(path)); err != nil {
+		return 0, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	// 导出被取消时删除未写完的文件，在文件关闭后执行
+	canceled := false
+	defer func() {
+		if canceled {
+			os.Remove(path)
+		}
+	}()
+	defer f.Close()
+
+	// 以消息条数作为进度
+	tracker.Start(path, int64(len(messages)))
+	defer tracker.Finish(path)
+
+	switch format {
+	case ExportCSV:
+		w := csv.NewWriter(f)
+		w.Write([]string{"Time", "SenderName", "Sender", "TalkerName", "Talker", "Content"})
+		for _, msg := range messages {
+			if err := m.runCtx.Err(); err != nil {
+				canceled = true
+				return 0, err
+			}
+			w.Write(msg.CSV(""))
+			tracker.Add(path, 1)
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return 0, err
+		}
+	case ExportJSON:
+		if err := json.NewEncoder(f).Encode(messages); err != nil {
+			return 0, err
+		}
+	case ExportMatrix:
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(matrixExport(talker, messages)); err != nil {
+			return 0, err
+		}
+	default:
+		showChatRoom := strings.Contains(talker, ",")
+		timeFormat := util.PerfectTimeFormat(start, end)
+		for _, msg := range messages {
+			if err := m.runCtx.Err(); err != nil {
+				canceled = true
+				return 0, err
+			}
+			if _, err := f.WriteString(msg.PlainText(showChatRoom, timeFormat, "") + "\n"); err != nil {
+				return 0, err
+			}
+			tracker.Add(path, 1)
+		}
+	}
+
+	return len(messages), f.Close()
+}
+
+// runScript 对每条消息运行导出脚本，返回未被丢弃的消息
+func runScript(sc *scripting.Script, messages []*model.Message) ([]*model.Message, error) {
+	ret := messages[:0]
+	for _, msg := range messages {
+		r, err := sc.Run(msg)
+		if err != nil {
+			return nil, fmt.Errorf("%s %d: %w", msg.Talker, msg.Seq, err)
+		}
+		if !r.Drop {
+			ret = append(ret, msg)
+		}
+	}
+	return ret, nil
+}
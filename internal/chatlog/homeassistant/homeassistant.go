@@ -0,0 +1,195 @@
+package homeassistant
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+	"github.com/DanielMao1/chatlog/internal/chatlog/mqtt"
+	"github.com/DanielMao1/chatlog/pkg/version"
+)
+
+const (
+	// DefaultDiscoveryPrefix Home Assistant 默认的 discovery 主题前缀
+	DefaultDiscoveryPrefix = "homeassistant"
+
+	// DefaultInterval 通过 MQTT 发布状态的默认间隔
+	DefaultInterval = time.Minute
+
+	// PreviewLength 最后一条消息预览的最大字数，Home Assistant 的状态最长 255 个字符
+	PreviewLength = 100
+)
+
+// Talker 聊天对象的新消息数量与最后一条消息
+type Talker struct {
+	Talker      string    `json:"talker"`
+	Name        string    `json:"name"`
+	Today       int       `json:"today"` // 今天的消息数量
+	LastTime    time.Time `json:"last_time,omitempty"`
+	LastSender  string    `json:"last_sender,omitempty"`
+	LastMessage string    `json:"last_message,omitempty"`
+}
+
+// State chatlog 的状态，HTTP 接口与 MQTT 发布的内容
+type State struct {
+	// Status 数据库状态：ready、decrypting、error、init
+	Status            string    `json:"status"`
+	Today             int       `json:"today"` // 所有显示的聊天对象今天的消息数量
+	RequestsPerMinute int       `json:"requests_per_minute"`
+	Updated           time.Time `json:"updated"`
+	Talkers           []*Talker `json:"talkers"`
+}
+
+// Message 发布到 MQTT 的一条消息
+type Message struct {
+	Topic   string
+	Payload []byte
+	Retain  bool
+}
+
+// ObjectID 将聊天对象转换为 Home Assistant 实体 ID 可用的字符
+func ObjectID(talker string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, talker)
+}
+
+// Discovery 返回 MQTT Discovery 的传感器配置消息：数据库状态、今天的消息数量，以及每个聊天对象的消息数量
+func Discovery(prefix, topic string, s *State) []Message {
+	device := map[string]any{
+		"identifiers":  []string{"chatlog"},
+		"name":         "Chatlog",
+		"manufacturer": "chatlog",
+		"sw_version":   version.Version,
+	}
+	sensor := func(id, name, stateTopic, template, icon string, attributes bool) Message {
+		config := map[string]any{
+			"name":               name,
+			"unique_id":          "chatlog_" + id,
+			"object_id":          "chatlog_" + id,
+			"state_topic":        stateTopic,
+			"value_template":     template,
+			"icon":               icon,
+			"availability_topic": topic + "/availability",
+			"device":             device,
+		}
+		if attributes {
+			config["json_attributes_topic"] = stateTopic
+		}
+		b, _ := json.Marshal(config)
+		return Message{Topic: prefix + "/sensor/chatlog/" + id + "/config", Payload: b, Retain: true}
+	}
+
+	msgs := []Message{
+		sensor("status", "Status", topic+"/state", "{{ value_json.status }}", "mdi:database", false),
+		sensor("today", "Messages today", topic+"/state", "{{ value_json.today }}", "mdi:message-text", false),
+	}
+	for _, t := range s.Talkers {
+		id := ObjectID(t.Talker)
+		name := t.Name
+		if len(name) == 0 {
+			name = t.Talker
+		}
+		msgs = append(msgs, sensor(id, name, topic+"/talker/"+id, "{{ value_json.today }}", "mdi:chat", true))
+	}
+	return msgs
+}
+
+// States 返回各传感器的状态消息
+func States(topic string, s *State) []Message {
+	b, _ := json.Marshal(s)
+	msgs := []Message{{Topic: topic + "/state", Payload: b, Retain: true}}
+	for _, t := range s.Talkers {
+		b, _ := json.Marshal(t)
+		msgs = append(msgs, Message{Topic: topic + "/talker/" + ObjectID(t.Talker), Payload: b, Retain: true})
+	}
+	return msgs
+}
+
+// Publisher 定期通过 MQTT 发布状态，开启 discovery 时首次连接后发布传感器配置
+type Publisher struct {
+	ha    *conf.HomeAssistant
+	mqtt  *conf.MQTT
+	state func(ctx context.Context) *State
+}
+
+// NewPublisher state 返回当前的状态，每次发布时调用
+func NewPublisher(ha *conf.HomeAssistant, m *conf.MQTT, state func(ctx context.Context) *State) *Publisher {
+	return &Publisher{ha: ha, mqtt: m, state: state}
+}
+
+// Run 每隔 interval 发布一次状态，连接断开时在下次发布前重新连接，直到 ctx 结束
+func (p *Publisher) Run(ctx context.Context) {
+	interval := p.ha.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	topic := mqtt.Topic(p.mqtt) + "/homeassistant"
+	var client *mqtt.Client
+	defer func() {
+		if client != nil {
+			client.Publish(context.Background(), topic+"/availability", []byte("offline"), true)
+			client.Close()
+		}
+	}()
+
+	for {
+		if client != nil && client.Err() != nil {
+			client = nil
+		}
+		var err error
+		if client == nil {
+			client, err = p.connect(ctx, topic)
+		}
+		if err == nil {
+			err = p.publish(ctx, client, States(topic, p.state(ctx)))
+		}
+		if err != nil && ctx.Err() == nil {
+			log.Debug().Err(err).Msg("publish home assistant state failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// connect 连接 MQTT 服务器，发布上线状态与传感器配置，异常断开时服务器发布下线状态
+func (p *Publisher) connect(ctx context.Context, topic string) (*mqtt.Client, error) {
+	client, err := mqtt.Dial(ctx, p.mqtt, &mqtt.Will{Topic: topic + "/availability", Payload: []byte("offline"), Retain: true})
+	if err != nil {
+		return nil, err
+	}
+	msgs := []Message{}
+	if p.ha.Discovery {
+		prefix := p.ha.DiscoveryPrefix
+		if len(prefix) == 0 {
+			prefix = DefaultDiscoveryPrefix
+		}
+		msgs = Discovery(prefix, topic, p.state(ctx))
+	}
+	msgs = append(msgs, Message{Topic: topic + "/availability", Payload: []byte("online"), Retain: true})
+	if err := p.publish(ctx, client, msgs); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+func (p *Publisher) publish(ctx context.Context, client *mqtt.Client, msgs []Message) error {
+	for _, m := range msgs {
+		if err := client.Publish(ctx, m.Topic, m.Payload, m.Retain); err != nil {
+			return err
+		}
+	}
+	return nil
+}
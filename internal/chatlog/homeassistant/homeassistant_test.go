@@ -0,0 +1,46 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDiscovery(t *testing.T) {
+	s := &State{
+		Status: "ready",
+		Today:  3,
+		Talkers: []*Talker{
+			{Talker: "12345@chatroom", Name: "家庭群", Today: 2, LastTime: time.Unix(1700000000, 0), LastSender: "妈妈", LastMessage: "晚饭好了"},
+			{Talker: "wxid_a", Today: 1},
+		},
+	}
+
+	msgs := Discovery("homeassistant", "chatlog/homeassistant", s)
+	if len(msgs) != 4 {
+		t.Fatalf("discovery messages = %d, want 4", len(msgs))
+	}
+	if msgs[2].Topic != "homeassistant/sensor/chatlog/12345_chatroom/config" || !msgs[2].Retain {
+		t.Errorf("talker config topic = %s", msgs[2].Topic)
+	}
+	var config map[string]any
+	if err := json.Unmarshal(msgs[2].Payload, &config); err != nil {
+		t.Fatal(err)
+	}
+	if config["name"] != "家庭群" || config["state_topic"] != "chatlog/homeassistant/talker/12345_chatroom" ||
+		config["json_attributes_topic"] != config["state_topic"] || config["unique_id"] != "chatlog_12345_chatroom" {
+		t.Errorf("talker config = %v", config)
+	}
+	if err := json.Unmarshal(msgs[3].Payload, &config); err != nil || config["name"] != "wxid_a" {
+		t.Errorf("talker without name = %v", config)
+	}
+
+	states := States("chatlog/homeassistant", s)
+	if len(states) != 3 || states[0].Topic != "chatlog/homeassistant/state" || states[1].Topic != "chatlog/homeassistant/talker/12345_chatroom" {
+		t.Fatalf("states = %v", states)
+	}
+	var talker Talker
+	if err := json.Unmarshal(states[1].Payload, &talker); err != nil || talker.Today != 2 || talker.LastMessage != "晚饭好了" {
+		t.Errorf("talker state = %+v, %v", talker, err)
+	}
+}
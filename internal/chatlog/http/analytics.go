@@ -0,0 +1,207 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/analytics"
+	"github.com/DanielMao1/chatlog/internal/chatlog/database"
+	"github.com/DanielMao1/chatlog/internal/errors"
+	"github.com/DanielMao1/chatlog/internal/model"
+	"github.com/DanielMao1/chatlog/pkg/util"
+)
+
+const (
+	// StatsDefaultTop 统计接口默认返回的消息最多的联系人与群聊数量
+	StatsDefaultTop = 10
+
+	// AnalyticsDefaultTime 未指定时间范围时分析的时间范围
+	AnalyticsDefaultTime = "last-1y"
+
+	// WordsDefaultTop 词频接口默认返回的词数量
+	WordsDefaultTop = 100
+)
+
+// startAnalytics 配置了工作目录时读取保存的分析结果，配置了 analytics.talkers 时在后台定期分析
+func (s *Service) startAnalytics() {
+	if len(s.conf.GetWorkDir()) == 0 {
+		return
+	}
+	store, err := analytics.Open(filepath.Join(s.conf.GetWorkDir(), analytics.StoreFile))
+	if err != nil {
+		log.Warn().Err(err).Msg("read analytics failed")
+		return
+	}
+	s.analytics = analytics.NewService(store, &analyticsSource{db: s.db})
+	c := s.conf.GetAnalytics()
+	if c == nil || len(c.Talkers) == 0 {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.analyticsCancel = cancel
+	go s.analytics.Run(ctx, c.Talkers, c.Interval)
+}
+
+// stopAnalytics 停止定期分析
+func (s *Service) stopAnalytics() {
+	if s.analyticsCancel != nil {
+		s.analyticsCancel()
+		s.analyticsCancel = nil
+	}
+}
+
+// handleStats 返回时间范围内的消息统计
+func (s *Service) handleStats(c *gin.Context) {
+	q := struct {
+		Time string `form:"time"`
+		Top  int    `form:"top"`
+	}{}
+	if err := c.BindQuery(&q); err != nil {
+		errors.Err(c, err)
+		return
+	}
+	if len(q.Time) == 0 {
+		q.Time = "all"
+	}
+	start, end, ok := util.TimeRangeOf(q.Time)
+	if !ok {
+		errors.Err(c, errors.InvalidArg("time"))
+		return
+	}
+	if q.Top <= 0 {
+		q.Top = StatsDefaultTop
+	}
+	stats, err := s.db.GetStats(c.Request.Context(), start, end, q.Top)
+	if err != nil {
+		errors.Err(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// handleAnalytics 返回聊天对象按月的情感与话题
+// analytics.talkers 中的聊天对象返回后台定期分析的结果，refresh 为 true 时重新分析；其他聊天对象在请求时分析
+func (s *Service) handleAnalytics(c *gin.Context) {
+	q := struct {
+		Talker  string `form:"talker"`
+		Time    string `form:"time"`
+		Refresh bool   `form:"refresh"`
+	}{}
+	if err := c.BindQuery(&q); err != nil {
+		errors.Err(c, err)
+		return
+	}
+	if len(q.Talker) == 0 || strings.Contains(q.Talker, ",") {
+		errors.Err(c, errors.InvalidArg("talker"))
+		return
+	}
+	if len(q.Time) == 0 {
+		q.Time = AnalyticsDefaultTime
+	}
+	start, end, ok := util.TimeRangeOf(q.Time)
+	if !ok {
+		errors.Err(c, errors.InvalidArg("time"))
+		return
+	}
+
+	var months []*analytics.Month
+	if ac := s.conf.GetAnalytics(); s.analytics != nil && ac != nil && slices.Contains(ac.Talkers, q.Talker) {
+		store := s.analytics.Store()
+		if q.Refresh || store.Updated(q.Talker).IsZero() {
+			if err := s.analytics.Update(c.Request.Context(), q.Talker); err != nil {
+				errors.Err(c, err)
+				return
+			}
+		}
+		months = store.Months(q.Talker, start, end)
+	} else {
+		// 从 start 所在月份的第一天开始分析，每月的结果都包含整月的消息
+		start = time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, start.Location())
+		messages, err := (&analyticsSource{db: s.db}).Messages(c.Request.Context(), q.Talker, start, end)
+		if err != nil {
+			errors.Err(c, err)
+			return
+		}
+		months = analytics.Analyze(messages)
+	}
+	c.JSON(http.StatusOK, months)
+}
+
+// handleWords 返回聊天对象在时间范围内出现最多的词，用于绘制词云
+func (s *Service) handleWords(c *gin.Context) {
+	q := struct {
+		Talker string `form:"talker"`
+		Sender string `form:"sender"`
+		Time   string `form:"time"`
+		Top    int    `form:"top"`
+	}{}
+	if err := c.BindQuery(&q); err != nil {
+		errors.Err(c, err)
+		return
+	}
+	if len(q.Talker) == 0 {
+		errors.Err(c, errors.InvalidArg("talker"))
+		return
+	}
+	if len(q.Time) == 0 {
+		q.Time = AnalyticsDefaultTime
+	}
+	start, end, ok := util.TimeRangeOf(q.Time)
+	if !ok {
+		errors.Err(c, errors.InvalidArg("time"))
+		return
+	}
+	if q.Top <= 0 {
+		q.Top = WordsDefaultTop
+	}
+	messages, err := s.db.GetMessages(c.Request.Context(), start, end, q.Talker, q.Sender, "", 0, 0)
+	if err != nil {
+		errors.Err(c, err)
+		return
+	}
+	texts := make([]string, 0, len(messages))
+	for _, m := range messages {
+		if m.Type == model.MessageTypeText {
+			texts = append(texts, m.Content)
+		}
+	}
+	c.JSON(http.StatusOK, analytics.Words(texts, q.Top))
+}
+
+// analyticsSource 从数据库读取参与分析的文本消息
+type analyticsSource struct {
+	db *database.Service
+}
+
+func (src *analyticsSource) Messages(ctx context.Context, talker string, start, end time.Time) ([]*analytics.Message, error) {
+	if src.db.GetDB() == nil {
+		return nil, fmt.Errorf("database is not ready")
+	}
+	messages, err := src.db.GetMessages(ctx, start, end, talker, "", "", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*analytics.Message, 0, len(messages))
+	for _, m := range messages {
+		if m.Type != model.MessageTypeText {
+			continue
+		}
+		sender := m.SenderName
+		if len(sender) == 0 {
+			sender = m.Sender
+		}
+		if m.IsSelf {
+			sender = "我"
+		}
+		ret = append(ret, &analytics.Message{Time: m.Time, Sender: sender, Text: m.Content})
+	}
+	return ret, nil
+}
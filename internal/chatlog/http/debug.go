@@ -0,0 +1,94 @@
+package http
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/DanielMao1/chatlog/pkg/version"
+)
+
+// started 进程启动的时间
+var started = time.Now()
+
+// Runtime /debug/runtime 返回的运行时信息
+type Runtime struct {
+	Version    string    `json:"version"`
+	GoVersion  string    `json:"goVersion"`
+	OS         string    `json:"os"`
+	Arch       string    `json:"arch"`
+	PID        int       `json:"pid"`
+	Started    time.Time `json:"started"`
+	Uptime     string    `json:"uptime"`
+	NumCPU     int       `json:"numCPU"`
+	GOMAXPROCS int       `json:"gomaxprocs"`
+	Goroutines int       `json:"goroutines"`
+	HeapAlloc  uint64    `json:"heapAlloc"`
+	HeapInuse  uint64    `json:"heapInuse"`
+	HeapSys    uint64    `json:"heapSys"`
+	Sys        uint64    `json:"sys"`
+	NumGC      uint32    `json:"numGC"`
+	PauseTotal string    `json:"pauseTotal"`
+}
+
+// initDebugRouter 配置了 pprof 时开启 /debug/pprof 与 /debug/runtime，与其他接口一样需要认证
+func (s *Service) initDebugRouter() {
+	if !s.conf.GetPprof() {
+		return
+	}
+	debug := s.router.Group("/debug", s.authMiddleware())
+	debug.GET("/runtime", s.handleRuntime)
+	debug.GET("/pprof/*name", handlePprof)
+	debug.POST("/pprof/*name", handlePprof)
+}
+
+// handlePprof 转发到 net/http/pprof，/debug/pprof/ 为索引页，其他名称为对应的 profile
+func handlePprof(c *gin.Context) {
+	switch name := strings.TrimPrefix(c.Param("name"), "/"); name {
+	case "":
+		pprof.Index(c.Writer, c.Request)
+	case "cmdline":
+		pprof.Cmdline(c.Writer, c.Request)
+	case "profile":
+		pprof.Profile(c.Writer, c.Request)
+	case "symbol":
+		pprof.Symbol(c.Writer, c.Request)
+	case "trace":
+		pprof.Trace(c.Writer, c.Request)
+	default:
+		pprof.Handler(name).ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+func (s *Service) handleRuntime(c *gin.Context) {
+	c.JSON(http.StatusOK, CurrentRuntime())
+}
+
+// CurrentRuntime 返回当前进程的运行时信息
+func CurrentRuntime() *Runtime {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return &Runtime{
+		Version:    version.Version,
+		GoVersion:  runtime.Version(),
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		PID:        os.Getpid(),
+		Started:    started,
+		Uptime:     time.Since(started).Round(time.Second).String(),
+		NumCPU:     runtime.NumCPU(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		Goroutines: runtime.NumGoroutine(),
+		HeapAlloc:  m.HeapAlloc,
+		HeapInuse:  m.HeapInuse,
+		HeapSys:    m.HeapSys,
+		Sys:        m.Sys,
+		NumGC:      m.NumGC,
+		PauseTotal: time.Duration(m.PauseTotalNs).String(),
+	}
+}
@@ -0,0 +1,46 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/push"
+	"github.com/DanielMao1/chatlog/internal/errors"
+)
+
+// handleDeliveries 返回最近的推送记录与死信目录中推送失败的请求
+func (s *Service) handleDeliveries(c *gin.Context) {
+	failed, err := push.DeadLetters()
+	if err != nil {
+		errors.Err(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"recent": push.Recent(),
+		"failed": failed,
+	})
+}
+
+// handleRedeliver 重新推送死信目录中的请求
+func (s *Service) handleRedeliver(c *gin.Context) {
+	dl, err := push.Redeliver(c.Param("id"), s.conf.GetDestinations())
+	if dl == nil {
+		errors.Err(c, errors.New(err, http.StatusNotFound, err.Error()))
+		return
+	}
+	if err != nil {
+		errors.Err(c, errors.New(err, http.StatusBadGateway, err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, dl)
+}
+
+// handleDiscardDelivery 从死信目录删除请求
+func (s *Service) handleDiscardDelivery(c *gin.Context) {
+	if err := push.Discard(c.Param("id")); err != nil {
+		errors.Err(c, errors.New(err, http.StatusNotFound, err.Error()))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,150 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/events"
+	"github.com/DanielMao1/chatlog/internal/errors"
+	"github.com/DanielMao1/chatlog/internal/model"
+	"github.com/DanielMao1/chatlog/pkg/util"
+)
+
+const (
+	// EventDefaultTime 未指定时间范围时识别该范围内消息中的约定
+	EventDefaultTime = "last-30d"
+
+	// EventSessions 未指定聊天对象时检查的最近会话数量
+	EventSessions = 50
+
+	// EventSummaryLength 日历事件标题中消息内容的最大字数
+	EventSummaryLength = 40
+)
+
+// EventItem 从消息中识别出的约定
+type EventItem struct {
+	*events.Event
+	ID         string    `json:"id"` // 来源消息的 ID，格式为 序号:聊天对象
+	Talker     string    `json:"talker"`
+	TalkerName string    `json:"talkerName"`
+	Sender     string    `json:"sender"`
+	SenderName string    `json:"senderName"`
+	Time       time.Time `json:"time"` // 消息发送时间
+	Content    string    `json:"content"`
+}
+
+// handleEvents 返回从消息中识别出的约定，format=ics 或请求 /events.ics 时返回 iCalendar 日历，可在日历应用中订阅
+func (s *Service) handleEvents(c *gin.Context) {
+	q := struct {
+		Time   string `form:"time"`
+		Talker string `form:"talker"`
+		Format string `form:"format"`
+	}{}
+	if err := c.BindQuery(&q); err != nil {
+		errors.Err(c, err)
+		return
+	}
+	if len(q.Time) == 0 {
+		q.Time = EventDefaultTime
+	}
+	start, end, ok := util.TimeRangeOf(q.Time)
+	if !ok {
+		errors.Err(c, errors.InvalidArg("time"))
+		return
+	}
+
+	messages, err := s.eventMessages(c.Request.Context(), q.Talker, start, end)
+	if err != nil {
+		errors.Err(c, err)
+		return
+	}
+	items := detectEvents(messages)
+
+	if strings.HasSuffix(c.Request.URL.Path, ".ics") || strings.EqualFold(q.Format, "ics") {
+		list := make([]*events.CalendarEvent, 0, len(items))
+		for _, item := range items {
+			list = append(list, &events.CalendarEvent{
+				Event:   item.Event,
+				UID:     item.ID + "@chatlog",
+				Summary: item.summary(),
+				Description: item.SenderName + " " + item.Time.Format("2006-01-02 15:04:05") + "\n" +
+					item.Content,
+			})
+		}
+		c.Header("Cache-Control", "no-cache")
+		c.Data(http.StatusOK, "text/calendar; charset=utf-8", events.ICS("chatlog", list))
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+// eventMessages 返回聊天对象在时间范围内的消息，talker 为空时查询范围内有新消息的最近会话
+func (s *Service) eventMessages(ctx context.Context, talker string, start, end time.Time) ([]*model.Message, error) {
+	if len(talker) == 0 {
+		sessions, err := s.db.GetSessions(ctx, "", EventSessions, 0)
+		if err != nil {
+			return nil, err
+		}
+		talkers := make([]string, 0, len(sessions.Items))
+		for _, session := range sessions.Items {
+			if !session.NTime.Before(start) {
+				talkers = append(talkers, session.UserName)
+			}
+		}
+		if len(talkers) == 0 {
+			return nil, nil
+		}
+		talker = strings.Join(talkers, ",")
+	}
+	return s.db.GetMessages(ctx, start, end, talker, "", "", 0, 0)
+}
+
+// detectEvents 识别文本消息中的约定
+func detectEvents(messages []*model.Message) []*EventItem {
+	items := make([]*EventItem, 0)
+	for _, m := range messages {
+		if m.Type != model.MessageTypeText {
+			continue
+		}
+		e := events.Detect(m.Content, m.Time)
+		if e == nil {
+			continue
+		}
+		senderName := m.SenderName
+		if m.IsSelf {
+			senderName = "我"
+		} else if len(senderName) == 0 {
+			senderName = m.Sender
+		}
+		items = append(items, &EventItem{
+			Event:      e,
+			ID:         strconv.FormatInt(m.Seq, 10) + ":" + m.Talker,
+			Talker:     m.Talker,
+			TalkerName: m.TalkerName,
+			Sender:     m.Sender,
+			SenderName: senderName,
+			Time:       m.Time,
+			Content:    m.Content,
+		})
+	}
+	return items
+}
+
+// summary 日历事件的标题，为聊天对象名称与截断的消息内容
+func (e *EventItem) summary() string {
+	content := strings.Join(strings.Fields(e.Content), " ")
+	if utf8.RuneCountInString(content) > EventSummaryLength {
+		content = string([]rune(content)[:EventSummaryLength]) + "…"
+	}
+	name := e.TalkerName
+	if len(name) == 0 {
+		name = e.Talker
+	}
+	return name + ": " + content
+}
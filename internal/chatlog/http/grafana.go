@@ -0,0 +1,258 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/DanielMao1/chatlog/internal/errors"
+	"github.com/DanielMao1/chatlog/internal/model"
+)
+
+const (
+	// Grafana 指标名称，messages:<聊天对象> 为单个聊天对象的消息数量
+	GrafanaMessages   = "messages"
+	GrafanaContacts   = "contacts"
+	GrafanaDecryptLag = "decrypt_lag"
+
+	// GrafanaDefaultDays 请求未指定时间范围时统计的天数
+	GrafanaDefaultDays = 30
+
+	// GrafanaTopContacts contacts 指标包含的联系人与群聊数量
+	GrafanaTopContacts = 10
+
+	// GrafanaSearchSessions 搜索指标时列出的会话数量
+	GrafanaSearchSessions = 20
+
+	// GrafanaAnnotationLimit 注释最多返回的消息数量
+	GrafanaAnnotationLimit = 200
+)
+
+// grafanaRange Grafana 面板的时间范围
+type grafanaRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// grafanaSeries 时间序列，datapoints 为 [值, 毫秒时间戳]
+type grafanaSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+type grafanaColumn struct {
+	Text string `json:"text"`
+	Type string `json:"type"`
+}
+
+// grafanaTable 表格，type 固定为 table
+type grafanaTable struct {
+	Type    string          `json:"type"`
+	Columns []grafanaColumn `json:"columns"`
+	Rows    [][]any         `json:"rows"`
+}
+
+// handleGrafanaTest Grafana 添加数据源时测试连接
+func (s *Service) handleGrafanaTest(c *gin.Context) {
+	c.String(http.StatusOK, "OK")
+}
+
+// handleGrafanaSearch 返回可用的指标，包括最近会话的 messages:<聊天对象>
+func (s *Service) handleGrafanaSearch(c *gin.Context) {
+	var req struct {
+		Target string `json:"target"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		errors.Err(c, errors.InvalidArg("body"))
+		return
+	}
+	metrics := []string{GrafanaMessages, GrafanaContacts, GrafanaDecryptLag}
+	sessions, err := s.db.GetSessions(c.Request.Context(), req.Target, GrafanaSearchSessions, 0)
+	if err != nil {
+		errors.Err(c, err)
+		return
+	}
+	for _, session := range sessions.Items {
+		metrics = append(metrics, GrafanaMessages+":"+session.UserName)
+	}
+	c.JSON(http.StatusOK, metrics)
+}
+
+// handleGrafanaQuery 按面板的时间范围返回指标，messages 为每天的消息数量，contacts 为最活跃的聊天对象，decrypt_lag 为工作目录落后数据目录的秒数
+func (s *Service) handleGrafanaQuery(c *gin.Context) {
+	var req struct {
+		Range   grafanaRange `json:"range"`
+		Targets []struct {
+			Target string `json:"target"`
+			Type   string `json:"type"`
+		} `json:"targets"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors.Err(c, errors.InvalidArg("body"))
+		return
+	}
+	from, to := req.Range.From.Local(), req.Range.To.Local()
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() || from.After(to) {
+		from = to.AddDate(0, 0, -GrafanaDefaultDays)
+	}
+
+	result := make([]any, 0, len(req.Targets))
+	for _, t := range req.Targets {
+		target, talker, _ := strings.Cut(t.Target, ":")
+		switch target {
+		case GrafanaMessages:
+			counts, err := s.db.CountMessages(c.Request.Context(), from, to, talker)
+			if err != nil {
+				errors.Err(c, err)
+				return
+			}
+			result = append(result, dailySeries(t.Target, counts, from, to))
+		case GrafanaContacts:
+			items, err := s.topTalkers(c.Request.Context(), from, to)
+			if err != nil {
+				errors.Err(c, err)
+				return
+			}
+			if t.Type == "table" {
+				result = append(result, contactsTable(items))
+				continue
+			}
+			series, err := s.contactSeries(c.Request.Context(), items, from, to)
+			if err != nil {
+				errors.Err(c, err)
+				return
+			}
+			for _, item := range series {
+				result = append(result, item)
+			}
+		case GrafanaDecryptLag:
+			series := &grafanaSeries{Target: t.Target, Datapoints: [][2]float64{}}
+			if lag, ok := s.DecryptLag(); ok {
+				series.Datapoints = append(series.Datapoints, [2]float64{lag.Seconds(), float64(time.Now().UnixMilli())})
+			}
+			result = append(result, series)
+		default:
+			errors.Err(c, errors.InvalidArg("target"))
+			return
+		}
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// handleGrafanaAnnotations 将消息作为注释显示在面板上，query 为聊天对象，其后以空格分隔关键词
+func (s *Service) handleGrafanaAnnotations(c *gin.Context) {
+	var req struct {
+		Range      grafanaRange   `json:"range"`
+		Annotation map[string]any `json:"annotation"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors.Err(c, errors.InvalidArg("body"))
+		return
+	}
+	query, _ := req.Annotation["query"].(string)
+	talker, keyword, _ := strings.Cut(strings.TrimSpace(query), " ")
+	if len(talker) == 0 {
+		errors.Err(c, errors.InvalidArg("query"))
+		return
+	}
+	messages, err := s.db.GetMessages(c.Request.Context(), req.Range.From.Local(), req.Range.To.Local(), talker, "", strings.TrimSpace(keyword), GrafanaAnnotationLimit, 0)
+	if err != nil {
+		errors.Err(c, err)
+		return
+	}
+
+	annotations := make([]gin.H, 0, len(messages))
+	for _, m := range messages {
+		sender := m.SenderName
+		if m.IsSelf {
+			sender = "我"
+		} else if len(sender) == 0 {
+			sender = m.Sender
+		}
+		annotations = append(annotations, gin.H{
+			"annotation": req.Annotation,
+			"time":       m.Time.UnixMilli(),
+			"title":      sender,
+			"text":       m.PlainTextContent(),
+			"tags":       []string{m.Talker},
+		})
+	}
+	c.JSON(http.StatusOK, annotations)
+}
+
+// topTalkers 返回时间范围内消息最多的联系人与群聊
+func (s *Service) topTalkers(ctx context.Context, from, to time.Time) ([]*model.StatsItem, error) {
+	stats, err := s.db.GetStats(ctx, from, to, GrafanaTopContacts)
+	if err != nil {
+		return nil, err
+	}
+	items := append(append([]*model.StatsItem{}, stats.TopContact...), stats.TopRoom...)
+	return model.SortStatsItems(items, GrafanaTopContacts), nil
+}
+
+// contactSeries 返回每个聊天对象每天的消息数量
+func (s *Service) contactSeries(ctx context.Context, items []*model.StatsItem, from, to time.Time) ([]*grafanaSeries, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	talkers := make([]string, 0, len(items))
+	for _, item := range items {
+		talkers = append(talkers, item.UserName)
+	}
+	counts, err := s.db.CountMessages(ctx, from, to, strings.Join(talkers, ","))
+	if err != nil {
+		return nil, err
+	}
+	byTalker := make(map[string][]*model.MessageCount)
+	for _, c := range counts {
+		byTalker[c.Talker] = append(byTalker[c.Talker], c)
+	}
+	series := make([]*grafanaSeries, 0, len(items))
+	for _, item := range items {
+		name := item.Name
+		if len(name) == 0 {
+			name = item.UserName
+		}
+		series = append(series, dailySeries(name, byTalker[item.UserName], from, to))
+	}
+	return series, nil
+}
+
+// contactsTable 以表格返回聊天对象的消息数量
+func contactsTable(items []*model.StatsItem) *grafanaTable {
+	table := &grafanaTable{
+		Type: "table",
+		Columns: []grafanaColumn{
+			{Text: "Name", Type: "string"},
+			{Text: "UserName", Type: "string"},
+			{Text: "Count", Type: "number"},
+		},
+		Rows: make([][]any, 0, len(items)),
+	}
+	for _, item := range items {
+		table.Rows = append(table.Rows, []any{item.Name, item.UserName, item.Count})
+	}
+	return table
+}
+
+// dailySeries 将消息数量按天汇总为时间序列，没有消息的日期为 0，时间戳为当天零点
+func dailySeries(target string, counts []*model.MessageCount, from, to time.Time) *grafanaSeries {
+	byDay := make(map[string]int)
+	for _, c := range counts {
+		byDay[c.Day] += c.Count
+	}
+	series := &grafanaSeries{Target: target, Datapoints: [][2]float64{}}
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	for ; !day.After(to); day = day.AddDate(0, 0, 1) {
+		key := day.Format("2006-01-02")
+		series.Datapoints = append(series.Datapoints, [2]float64{float64(byDay[key]), float64(day.UnixMilli())})
+	}
+	return series
+}
@@ -0,0 +1,28 @@
+package http
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/model"
+)
+
+func TestDailySeries(t *testing.T) {
+	from := time.Date(2024, 3, 1, 15, 30, 0, 0, time.Local)
+	to := time.Date(2024, 3, 3, 9, 0, 0, 0, time.Local)
+	counts := []*model.MessageCount{
+		{Talker: "a", Day: "2024-03-01", Type: 1, Count: 2},
+		{Talker: "b", Day: "2024-03-01", Type: 3, Count: 1},
+		{Talker: "a", Day: "2024-03-03", Type: 1, Count: 5},
+	}
+	day := func(d int) float64 {
+		return float64(time.Date(2024, 3, d, 0, 0, 0, 0, time.Local).UnixMilli())
+	}
+
+	got := dailySeries("messages", counts, from, to)
+	want := &grafanaSeries{Target: "messages", Datapoints: [][2]float64{{3, day(1)}, {0, day(2)}, {5, day(3)}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("dailySeries = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,110 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/database"
+	"github.com/DanielMao1/chatlog/internal/chatlog/homeassistant"
+)
+
+// HomeAssistantSessions 未配置 homeassistant.talkers 时显示的最近会话数量
+const HomeAssistantSessions = 5
+
+// startHomeAssistant 配置了 mqtt 与 homeassistant.talkers 时在后台定期发布状态
+func (s *Service) startHomeAssistant() {
+	c, m := s.conf.GetHomeAssistant(), s.conf.GetMQTT()
+	if c == nil || m == nil || len(m.Broker) == 0 || len(c.Talkers) == 0 {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.haCancel = cancel
+	go homeassistant.NewPublisher(c, m, s.homeAssistantState).Run(ctx)
+	log.Info().Str("broker", m.Broker).Msg("home assistant mqtt publisher started")
+}
+
+// stopHomeAssistant 停止发布状态
+func (s *Service) stopHomeAssistant() {
+	if s.haCancel != nil {
+		s.haCancel()
+		s.haCancel = nil
+	}
+}
+
+// handleHomeAssistant 返回 Home Assistant RESTful 传感器使用的状态，数据库未就绪时也返回，便于显示服务状态
+func (s *Service) handleHomeAssistant(c *gin.Context) {
+	c.JSON(http.StatusOK, s.homeAssistantState(c.Request.Context()))
+}
+
+// homeAssistantState 统计聊天对象今天的消息数量与最后一条消息
+func (s *Service) homeAssistantState(ctx context.Context) *homeassistant.State {
+	state := &homeassistant.State{
+		Status:            dbStatus(s.db),
+		RequestsPerMinute: s.RequestsPerMinute(),
+		Updated:           time.Now(),
+		Talkers:           []*homeassistant.Talker{},
+	}
+
+	var talkers []string
+	if c := s.conf.GetHomeAssistant(); c != nil {
+		talkers = c.Talkers
+	}
+	ready := s.db.State == database.StateReady
+	if len(talkers) == 0 && ready {
+		if sessions, err := s.db.GetSessions(ctx, "", HomeAssistantSessions, 0); err == nil {
+			for _, session := range sessions.Items {
+				talkers = append(talkers, session.UserName)
+			}
+		}
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for _, talker := range talkers {
+		t := &homeassistant.Talker{Talker: talker}
+		state.Talkers = append(state.Talkers, t)
+		if !ready {
+			continue
+		}
+		messages, err := s.db.GetMessages(ctx, today, now, talker, "", "", 0, 0)
+		if err != nil {
+			log.Debug().Err(err).Str("talker", talker).Msg("get messages for home assistant failed")
+			continue
+		}
+		t.Today = len(messages)
+		state.Today += len(messages)
+		if len(messages) == 0 {
+			continue
+		}
+		last := messages[len(messages)-1]
+		t.Name = last.TalkerName
+		t.LastTime = last.Time
+		t.LastSender = last.SenderName
+		if len(t.LastSender) == 0 {
+			t.LastSender = last.Sender
+		}
+		t.LastMessage = strings.TrimSpace(last.PlainTextContent())
+		if r := []rune(t.LastMessage); len(r) > homeassistant.PreviewLength {
+			t.LastMessage = string(r[:homeassistant.PreviewLength]) + "…"
+		}
+	}
+	return state
+}
+
+// dbStatus 返回数据库状态的名称
+func dbStatus(db *database.Service) string {
+	switch db.State {
+	case database.StateReady:
+		return "ready"
+	case database.StateDecrypting:
+		return "decrypting"
+	case database.StateError:
+		return "error"
+	}
+	return "init"
+}
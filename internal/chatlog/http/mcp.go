@@ -18,12 +18,16 @@ import (
 )
 
 func (s *Service) initMCPServer() {
-	s.mcpServer = server.NewMCPServer(conf.AppName, version.Version)
+	s.mcpServer = server.NewMCPServer(conf.AppName, version.Version,
+		server.WithResourceCapabilities(false, false),
+		server.WithPromptCapabilities(false),
+	)
 	s.mcpServer.AddTool(ContactTool, s.handleMCPContact)
 	s.mcpServer.AddTool(ChatRoomTool, s.handleMCPChatRoom)
 	s.mcpServer.AddTool(RecentChatTool, s.handleMCPRecentChat)
 	s.mcpServer.AddTool(ChatLogTool, s.handleMCPChatLog)
 	s.mcpServer.AddTool(CurrentTimeTool, s.handleMCPCurrentTime)
+	s.initMCPResources()
 	s.mcpSSEServer = server.NewSSEServer(s.mcpServer)
 	s.mcpStreamableServer = server.NewStreamableHTTPServer(s.mcpServer)
 }
@@ -168,7 +172,7 @@ func (s *Service) handleMCPContact(ctx context.Context, request mcp.CallToolRequ
 		return errors.ErrMCPTool(err), nil
 	}
 
-	list, err := s.db.GetContacts(req.Keyword, req.Limit, req.Offset)
+	list, err := s.db.GetContacts(ctx, req.Keyword, req.Limit, req.Offset)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get contacts")
 		return errors.ErrMCPTool(err), nil
@@ -203,7 +207,7 @@ func (s *Service) handleMCPChatRoom(ctx context.Context, request mcp.CallToolReq
 		return errors.ErrMCPTool(err), nil
 	}
 
-	list, err := s.db.GetChatRooms(req.Keyword, req.Limit, req.Offset)
+	list, err := s.db.GetChatRooms(ctx, req.Keyword, req.Limit, req.Offset)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get chat rooms")
 		return errors.ErrMCPTool(err), nil
@@ -238,7 +242,7 @@ func (s *Service) handleMCPRecentChat(ctx context.Context, request mcp.CallToolR
 		return errors.ErrMCPTool(err), nil
 	}
 
-	data, err := s.db.GetSessions(req.Keyword, req.Limit, req.Offset)
+	data, err := s.db.GetSessions(ctx, req.Keyword, req.Limit, req.Offset)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get sessions")
 		return errors.ErrMCPTool(err), nil
@@ -291,7 +295,7 @@ func (s *Service) handleMCPChatLog(ctx context.Context, request mcp.CallToolRequ
 		req.Offset = 0
 	}
 
-	messages, err := s.db.GetMessages(start, end, req.Talker, req.Sender, req.Keyword, req.Limit, req.Offset)
+	messages, err := s.db.GetMessages(ctx, start, end, req.Talker, req.Sender, req.Keyword, req.Limit, req.Offset)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get messages")
 		return errors.ErrMCPTool(err), nil
@@ -0,0 +1,210 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/DanielMao1/chatlog/internal/errors"
+	"github.com/DanielMao1/chatlog/internal/model"
+	"github.com/DanielMao1/chatlog/pkg/util"
+)
+
+const (
+	// MCPSessionsURI 最近会话列表资源
+	MCPSessionsURI = "chatlog://sessions"
+
+	// MCPSessionLimit 最近会话列表资源包含的会话数量
+	MCPSessionLimit = 100
+
+	// MCPPageSize 聊天记录资源每页的消息数量
+	MCPPageSize = 200
+
+	// MCPDefaultTime 聊天记录资源未指定时间时的默认时间范围
+	MCPDefaultTime = "last-7d"
+
+	// MCPPromptMessages 预置提示词中最多附带的消息数量，超过时保留最近的消息
+	MCPPromptMessages = 500
+)
+
+func (s *Service) initMCPResources() {
+	s.mcpServer.AddResource(SessionsResource, s.handleMCPSessions)
+	s.mcpServer.AddResourceTemplate(ChatLogResource, s.handleMCPChatLogResource)
+	s.mcpServer.AddPrompt(SummarizeWeekPrompt, s.handleMCPSummarizeWeek)
+	s.mcpServer.AddPrompt(DraftReplyPrompt, s.handleMCPDraftReply)
+}
+
+var SessionsResource = mcp.NewResource(
+	MCPSessionsURI,
+	"recent_sessions",
+	mcp.WithResourceDescription("最近会话列表，包括个人聊天和群聊，每个会话附带最后一条消息"),
+	mcp.WithMIMEType("text/plain"),
+)
+
+var ChatLogResource = mcp.NewResourceTemplate(
+	"chatlog://chatlog/{talker}{?time,page}",
+	"chat_log",
+	mcp.WithTemplateDescription(`与某个聊天对象的聊天记录，按页返回。talker 为聊天对象的 ID、备注名或昵称；time 为时间范围，格式与 query_chat_log 相同，默认最近 7 天；page 为页码，从 1 开始，每页 200 条消息，还有更多记录时末尾给出下一页的地址`),
+	mcp.WithTemplateMIMEType("text/plain"),
+)
+
+var SummarizeWeekPrompt = mcp.NewPrompt(
+	"summarize_week",
+	mcp.WithPromptDescription("总结本周与某个联系人或群聊的聊天内容"),
+	mcp.WithArgument("talker", mcp.RequiredArgument(), mcp.ArgumentDescription("聊天对象的 ID、备注名或昵称")),
+)
+
+var DraftReplyPrompt = mcp.NewPrompt(
+	"draft_reply",
+	mcp.WithPromptDescription("根据最近的聊天记录起草一条回复，例如延续上次的谈判或讨论"),
+	mcp.WithArgument("talker", mcp.RequiredArgument(), mcp.ArgumentDescription("聊天对象的 ID、备注名或昵称")),
+	mcp.WithArgument("topic", mcp.ArgumentDescription("回复的话题或目的，例如“价格谈判”，可以为空")),
+)
+
+func (s *Service) handleMCPSessions(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	data, err := s.db.GetSessions(ctx, "", MCPSessionLimit, 0)
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	for _, session := range data.Items {
+		buf.WriteString(session.PlainText(120))
+		buf.WriteString("\n")
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{URI: request.Params.URI, MIMEType: "text/plain", Text: buf.String()},
+	}, nil
+}
+
+// handleMCPChatLogResource 返回聊天记录资源的一页
+// 查询参数的顺序不固定时模板无法解析出参数，因此直接解析 URI
+func (s *Service) handleMCPChatLogResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	u, err := url.Parse(request.Params.URI)
+	if err != nil {
+		return nil, errors.InvalidArg("uri")
+	}
+	talker, err := url.PathUnescape(strings.TrimPrefix(u.EscapedPath(), "/"))
+	if err != nil || len(talker) == 0 {
+		return nil, errors.InvalidArg("talker")
+	}
+	query := u.Query()
+	timeRange := query.Get("time")
+	if len(timeRange) == 0 {
+		timeRange = MCPDefaultTime
+	}
+	start, end, ok := util.TimeRangeOf(timeRange)
+	if !ok {
+		return nil, errors.InvalidArg("time")
+	}
+	page := 1
+	if p := query.Get("page"); len(p) != 0 {
+		if page, err = strconv.Atoi(p); err != nil || page < 1 {
+			return nil, errors.InvalidArg("page")
+		}
+	}
+
+	// 多取一条判断是否还有下一页
+	messages, err := s.db.GetMessages(ctx, start, end, talker, "", "", MCPPageSize+1, (page-1)*MCPPageSize)
+	if err != nil {
+		return nil, err
+	}
+	more := len(messages) > MCPPageSize
+	if more {
+		messages = messages[:MCPPageSize]
+	}
+
+	buf := &bytes.Buffer{}
+	if len(messages) == 0 {
+		buf.WriteString("未找到符合查询条件的聊天记录")
+	}
+	writeMCPMessages(buf, messages, talker, start, end)
+	if more {
+		next := url.Values{"time": {timeRange}, "page": {strconv.Itoa(page + 1)}}
+		fmt.Fprintf(buf, "\n（第 %d 页，下一页：chatlog://chatlog/%s?%s）\n", page, url.PathEscape(talker), next.Encode())
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{URI: request.Params.URI, MIMEType: "text/plain", Text: buf.String()},
+	}, nil
+}
+
+func (s *Service) handleMCPSummarizeWeek(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	talker := strings.TrimSpace(request.Params.Arguments["talker"])
+	if len(talker) == 0 {
+		return nil, errors.InvalidArg("talker")
+	}
+	transcript, err := s.promptTranscript(ctx, talker, "last-7d")
+	if err != nil {
+		return nil, err
+	}
+	text := fmt.Sprintf(`以下是我最近 7 天与「%s」的聊天记录。请总结这一周聊了什么：
+1. 按话题列出主要内容，注明大致日期
+2. 列出达成的结论、约定和待办事项，注明负责人
+3. 列出仍未解决、需要我跟进的问题
+
+如果需要更多上下文，可以使用 query_chat_log 工具查询。
+
+%s`, talker, transcript)
+	return &mcp.GetPromptResult{
+		Description: "总结本周与「" + talker + "」的聊天",
+		Messages:    []mcp.PromptMessage{mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text))},
+	}, nil
+}
+
+func (s *Service) handleMCPDraftReply(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	talker := strings.TrimSpace(request.Params.Arguments["talker"])
+	if len(talker) == 0 {
+		return nil, errors.InvalidArg("talker")
+	}
+	topic := strings.TrimSpace(request.Params.Arguments["topic"])
+	transcript, err := s.promptTranscript(ctx, talker, "last-30d")
+	if err != nil {
+		return nil, err
+	}
+	goal := "延续最近一次讨论"
+	if len(topic) != 0 {
+		goal = "围绕「" + topic + "」"
+	}
+	text := fmt.Sprintf(`以下是我最近 30 天与「%s」的聊天记录。请%s，以我的身份起草一条回复：
+1. 先用两三句话概括对方目前的立场、诉求和双方已经达成的共识
+2. 回复的语气与我以往的消息保持一致，不要编造聊天记录中没有的事实或承诺
+3. 给出一条可以直接发送的回复，必要时再附一条更委婉或更强硬的备选
+
+如果需要更早的上下文，可以使用 query_chat_log 工具查询。
+
+%s`, talker, goal, transcript)
+	return &mcp.GetPromptResult{
+		Description: "起草给「" + talker + "」的回复",
+		Messages:    []mcp.PromptMessage{mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text))},
+	}, nil
+}
+
+// promptTranscript 返回预置提示词附带的聊天记录，超过 MCPPromptMessages 条时保留最近的消息
+func (s *Service) promptTranscript(ctx context.Context, talker, timeRange string) (string, error) {
+	start, end, _ := util.TimeRangeOf(timeRange)
+	messages, err := s.db.GetMessages(ctx, start, end, talker, "", "", 0, 0)
+	if err != nil {
+		return "", err
+	}
+	if len(messages) == 0 {
+		return "（这段时间没有聊天记录）", nil
+	}
+	if len(messages) > MCPPromptMessages {
+		messages = messages[len(messages)-MCPPromptMessages:]
+	}
+	buf := &bytes.Buffer{}
+	writeMCPMessages(buf, messages, talker, start, end)
+	return buf.String(), nil
+}
+
+func writeMCPMessages(buf *bytes.Buffer, messages []*model.Message, talker string, start, end time.Time) {
+	for _, m := range messages {
+		buf.WriteString(m.PlainText(strings.Contains(talker, ","), util.PerfectTimeFormat(start, end), ""))
+		buf.WriteString("\n")
+	}
+}
@@ -1,10 +1,12 @@
 package http
 
 import (
-	"net/http"
+	"crypto/subtle"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/DanielMao1/chatlog/internal/chatlog/database"
+	"github.com/DanielMao1/chatlog/internal/errors"
 )
 
 func corsMiddleware() gin.HandlerFunc {
@@ -23,19 +25,48 @@ func corsMiddleware() gin.HandlerFunc {
 	}
 }
 
+// authCookie 内置页面保存令牌的 Cookie，浏览器打开媒体链接时会自动携带
+const authCookie = "chatlog_token"
+
+// authMiddleware 配置了 auth_token 时，要求请求携带 Authorization: Bearer <token>、token 查询参数或 chatlog_token Cookie
+func (s *Service) authMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := s.conf.GetAuthToken()
+		if len(token) == 0 {
+			c.Next()
+			return
+		}
+
+		got := c.Query("token")
+		if len(got) == 0 {
+			got, _ = c.Cookie(authCookie)
+		}
+		if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			got = strings.TrimPrefix(auth, "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			errors.Err(c, errors.ErrUnauthorized)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 func (s *Service) checkDBStateMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		switch s.db.State {
 		case database.StateInit:
-			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "database is not ready"})
+			errors.Err(c, errors.ErrDBNotReady)
 			c.Abort()
 			return
 		case database.StateDecrypting:
-			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "database is decrypting, please wait"})
+			errors.Err(c, errors.ErrDBDecrypting)
 			c.Abort()
 			return
 		case database.StateError:
-			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "database is error: " + s.db.StateMsg})
+			errors.Err(c, errors.DBStateError(s.db.StateMsg))
 			c.Abort()
 			return
 		}
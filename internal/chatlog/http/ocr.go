@@ -0,0 +1,247 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/ocr"
+	"github.com/DanielMao1/chatlog/internal/errors"
+	"github.com/DanielMao1/chatlog/internal/model"
+	"github.com/DanielMao1/chatlog/pkg/util/dat2img"
+)
+
+// OCRLimit 搜索识别结果时默认返回的数量
+const OCRLimit = 50
+
+// startOCR 配置了 ocr 时打开工作目录中的识别结果，配置了 talkers 时在后台定期识别新图片
+func (s *Service) startOCR() {
+	c := s.conf.GetOCR()
+	recognizer, err := ocr.New(c)
+	if err != nil {
+		log.Warn().Err(err).Msg("ocr disabled")
+		return
+	}
+	if recognizer == nil {
+		return
+	}
+	if len(s.conf.GetWorkDir()) == 0 {
+		log.Warn().Msg("ocr disabled, work dir is not configured")
+		return
+	}
+	store, err := ocr.Open(filepath.Join(s.conf.GetWorkDir(), ocr.TextFile))
+	if err != nil {
+		log.Warn().Err(err).Msg("ocr disabled")
+		return
+	}
+	s.ocr = ocr.NewService(store, recognizer, &ocrSource{s: s})
+	if len(c.Talkers) == 0 {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.ocrCancel = cancel
+	go s.ocr.Run(ctx, c.Talkers, c.Interval)
+}
+
+// stopOCR 停止批量识别并关闭识别结果
+func (s *Service) stopOCR() {
+	if s.ocrCancel != nil {
+		s.ocrCancel()
+		s.ocrCancel = nil
+	}
+	if s.ocr != nil {
+		s.ocr.Store().Close()
+	}
+}
+
+// handleOCR 返回一条图片消息的识别结果，没有保存的结果时识别
+func (s *Service) handleOCR(c *gin.Context) {
+	q := struct {
+		Talker string `form:"talker"`
+		Seq    int64  `form:"seq"`
+	}{}
+	if err := c.BindQuery(&q); err != nil {
+		errors.Err(c, err)
+		return
+	}
+	if s.ocr == nil {
+		errors.Err(c, errors.New(nil, http.StatusServiceUnavailable, "ocr is not enabled, configure ocr first"))
+		return
+	}
+	if len(q.Talker) == 0 || strings.Contains(q.Talker, ",") {
+		errors.Err(c, errors.InvalidArg("talker"))
+		return
+	}
+	if q.Seq <= 0 {
+		errors.Err(c, errors.InvalidArg("seq"))
+		return
+	}
+
+	m, err := s.messageOf(c.Request.Context(), q.Talker, q.Seq)
+	if err != nil {
+		errors.Err(c, err)
+		return
+	}
+	if m == nil || m.Type != model.MessageTypeImage {
+		errors.Err(c, errors.New(nil, http.StatusNotFound, "image message not found"))
+		return
+	}
+
+	t, err := s.ocr.Recognize(c.Request.Context(), imageOf(m))
+	if err != nil {
+		errors.Err(c, errors.Newf(err, http.StatusBadGateway, "ocr failed"))
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+// handleOCRSearch 按关键词搜索图片消息的识别结果
+func (s *Service) handleOCRSearch(c *gin.Context) {
+	q := struct {
+		Keyword string `form:"keyword"`
+		Talker  string `form:"talker"`
+		Limit   int    `form:"limit"`
+		Format  string `form:"format"`
+	}{}
+	if err := c.BindQuery(&q); err != nil {
+		errors.Err(c, err)
+		return
+	}
+	if s.ocr == nil {
+		errors.Err(c, errors.New(nil, http.StatusServiceUnavailable, "ocr is not enabled, configure ocr first"))
+		return
+	}
+	if q.Limit <= 0 {
+		q.Limit = OCRLimit
+	}
+
+	list, err := s.ocr.Store().Search(q.Keyword, q.Talker, q.Limit)
+	if err != nil {
+		errors.Err(c, err)
+		return
+	}
+
+	switch strings.ToLower(q.Format) {
+	case "json":
+		c.JSON(http.StatusOK, list)
+	default:
+		c.Writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, t := range list {
+			if len(t.Text) == 0 {
+				continue
+			}
+			c.Writer.WriteString(fmt.Sprintf("%s %s %d http://%s/image/%s\n%s\n\n", t.Talker, t.Time.Format("2006-01-02 15:04:05"), t.Seq, c.Request.Host, t.Image, t.Text))
+		}
+	}
+}
+
+// attachOCR 为已识别的图片消息附上识别出的文字，保存在 Contents["ocr"]
+func (s *Service) attachOCR(messages []*model.Message) {
+	if s.ocr == nil {
+		return
+	}
+	spans := spansOf(messages, model.MessageTypeImage)
+	if len(spans) == 0 {
+		return
+	}
+
+	texts := make(map[string]map[int64]*ocr.Text, len(spans))
+	for talker, r := range spans {
+		byseq, err := s.ocr.Store().Range(talker, r.start, r.end)
+		if err != nil {
+			log.Debug().Err(err).Str("talker", talker).Msg("read ocr texts failed")
+			continue
+		}
+		texts[talker] = byseq
+	}
+	for _, m := range messages {
+		if m.Type != model.MessageTypeImage {
+			continue
+		}
+		if t, ok := texts[m.Talker][m.Seq]; ok && len(t.Text) != 0 {
+			if m.Contents == nil {
+				m.Contents = make(map[string]interface{})
+			}
+			m.Contents["ocr"] = t.Text
+		}
+	}
+}
+
+// attachText 为语音与图片消息附上转写与识别出的文字，并附上插件的标注
+func (s *Service) attachText(messages []*model.Message) {
+	s.attachTranscripts(messages)
+	s.attachOCR(messages)
+	s.attachAnnotations(messages)
+}
+
+// imageOf 返回图片消息的识别信息，原图优先，其次是 md5 与缩略图
+func imageOf(m *model.Message) *ocr.Image {
+	img := &ocr.Image{Talker: m.Talker, Seq: m.Seq, Time: m.Time}
+	for _, k := range []string{"path", "md5", "thumbpath"} {
+		if key, ok := m.Contents[k].(string); ok && len(key) != 0 {
+			img.Keys = append(img.Keys, key)
+		}
+	}
+	return img
+}
+
+// ocrSource 从数据库与数据目录读取图片消息与解密后的图片
+type ocrSource struct {
+	s *Service
+}
+
+func (src *ocrSource) Images(ctx context.Context, talker string, since time.Time) ([]*ocr.Image, error) {
+	if src.s.db.GetDB() == nil {
+		return nil, fmt.Errorf("database is not ready")
+	}
+	if since.IsZero() {
+		since = time.Unix(0, 0)
+	}
+	messages, err := src.s.db.GetMessages(ctx, since, time.Now(), talker, "", "", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*ocr.Image, 0)
+	for _, m := range messages {
+		if m.Type == model.MessageTypeImage {
+			ret = append(ret, imageOf(m))
+		}
+	}
+	return ret, nil
+}
+
+// Data 与 /image 接口一样依次查找各个键对应的文件，.dat 文件解密后返回
+func (src *ocrSource) Data(ctx context.Context, img *ocr.Image) ([]byte, string, error) {
+	for _, k := range img.Keys {
+		path, err := src.s.findPath("image", k)
+		if err != nil {
+			media, err := src.s.db.GetMedia(ctx, "image", k)
+			if err != nil {
+				continue
+			}
+			path = media.Path
+		}
+		data, err := os.ReadFile(filepath.Join(src.s.conf.GetDataDir(), path))
+		if err != nil {
+			continue
+		}
+		ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+		if ext == "dat" {
+			if data, ext, err = dat2img.Dat2ImageContext(ctx, data); err != nil {
+				continue
+			}
+		}
+		switch ext {
+		case "jpg", "jpeg", "png", "gif", "bmp", "webp":
+			return data, ext, nil
+		}
+	}
+	return nil, "", errors.ErrMediaNotFound
+}
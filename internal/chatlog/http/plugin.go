@@ -0,0 +1,171 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/plugin"
+	"github.com/DanielMao1/chatlog/internal/errors"
+	"github.com/DanielMao1/chatlog/internal/model"
+)
+
+// AnnotationLimit 查询标注时默认返回的数量
+const AnnotationLimit = 100
+
+// startPlugins 为每个配置的插件在后台处理新消息
+func (s *Service) startPlugins() {
+	configs := s.conf.GetPlugins()
+	if len(configs) == 0 {
+		return
+	}
+	if len(s.conf.GetWorkDir()) == 0 {
+		log.Warn().Msg("plugins disabled, work dir is not configured")
+		return
+	}
+	store, err := plugin.Open(filepath.Join(s.conf.GetWorkDir(), plugin.AnnotationFile))
+	if err != nil {
+		log.Warn().Err(err).Msg("plugins disabled")
+		return
+	}
+	s.plugins = plugin.NewService(store, &pluginSource{s: s})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.pluginsCancel = cancel
+	names := make(map[string]bool)
+	for _, c := range configs {
+		p, err := plugin.New(c)
+		if err != nil {
+			log.Warn().Err(err).Msg("skip plugin")
+			continue
+		}
+		if names[p.Name()] {
+			log.Warn().Str("plugin", p.Name()).Msg("skip plugin with duplicate name")
+			continue
+		}
+		names[p.Name()] = true
+		if len(p.Talkers()) == 0 {
+			log.Warn().Str("plugin", p.Name()).Msg("skip plugin without talkers")
+			continue
+		}
+		go s.plugins.Run(ctx, p)
+	}
+}
+
+// stopPlugins 停止插件并关闭标注数据库
+func (s *Service) stopPlugins() {
+	if s.pluginsCancel != nil {
+		s.pluginsCancel()
+		s.pluginsCancel = nil
+	}
+	if s.plugins != nil {
+		s.plugins.Store().Close()
+	}
+}
+
+// handleAnnotations 按标签、聊天对象或插件查询插件的标注
+func (s *Service) handleAnnotations(c *gin.Context) {
+	q := struct {
+		Tag    string `form:"tag"`
+		Talker string `form:"talker"`
+		Plugin string `form:"plugin"`
+		Limit  int    `form:"limit"`
+	}{}
+	if err := c.BindQuery(&q); err != nil {
+		errors.Err(c, err)
+		return
+	}
+	if s.plugins == nil {
+		errors.Err(c, errors.New(nil, http.StatusServiceUnavailable, "plugins are not enabled, configure plugins first"))
+		return
+	}
+	if q.Limit <= 0 {
+		q.Limit = AnnotationLimit
+	}
+
+	list, err := s.plugins.Store().Search(q.Tag, q.Talker, q.Plugin, q.Limit)
+	if err != nil {
+		errors.Err(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// attachAnnotations 为消息附上插件的标注，保存在 Contents["annotations"]
+// 插件返回了脱敏内容的文本消息，内容替换为脱敏后的内容，Contents["redacted"] 为给出脱敏内容的插件
+func (s *Service) attachAnnotations(messages []*model.Message) {
+	if s.plugins == nil {
+		return
+	}
+	spans := spansOf(messages, 0)
+	if len(spans) == 0 {
+		return
+	}
+
+	annotations := make(map[string]map[int64][]*plugin.Annotation, len(spans))
+	for talker, r := range spans {
+		byseq, err := s.plugins.Store().Range(talker, r.start, r.end)
+		if err != nil {
+			log.Debug().Err(err).Str("talker", talker).Msg("read annotations failed")
+			continue
+		}
+		annotations[talker] = byseq
+	}
+	for _, m := range messages {
+		list, ok := annotations[m.Talker][m.Seq]
+		if !ok {
+			continue
+		}
+		m.SetContent("annotations", list)
+		if m.Type != model.MessageTypeText {
+			continue
+		}
+		for _, a := range list {
+			if len(a.Redacted) != 0 {
+				m.Content = a.Redacted
+				m.SetContent("redacted", a.Plugin)
+				break
+			}
+		}
+	}
+}
+
+// pluginSource 从数据库读取交给插件处理的消息
+type pluginSource struct {
+	s *Service
+}
+
+func (src *pluginSource) Messages(ctx context.Context, talker string, since time.Time) ([]*plugin.Message, error) {
+	if src.s.db.GetDB() == nil {
+		return nil, fmt.Errorf("database is not ready")
+	}
+	if since.IsZero() {
+		since = time.Unix(0, 0)
+	}
+	messages, err := src.s.db.GetMessages(ctx, since, time.Now(), talker, "", "", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*plugin.Message, 0, len(messages))
+	for _, m := range messages {
+		m.SetContent("host", src.s.conf.GetHTTPAddr())
+		ret = append(ret, &plugin.Message{
+			Talker:     m.Talker,
+			TalkerName: m.TalkerName,
+			Seq:        m.Seq,
+			Time:       m.Time,
+			Sender:     m.Sender,
+			SenderName: m.SenderName,
+			IsSelf:     m.IsSelf,
+			Type:       m.Type,
+			SubType:    m.SubType,
+			Content:    m.PlainTextContent(),
+		})
+	}
+	return ret, nil
+}
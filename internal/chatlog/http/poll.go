@@ -0,0 +1,219 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/DanielMao1/chatlog/internal/errors"
+	"github.com/DanielMao1/chatlog/internal/model"
+)
+
+const (
+	// PollDefaultLimit、PollMaxLimit 轮询接口默认与最多返回的消息数量
+	PollDefaultLimit = 50
+	PollMaxLimit     = 500
+
+	// PollWindow 未指定 since_id 时返回该时长内最近的消息
+	PollWindow = 24 * time.Hour
+
+	// PollSessions 未指定聊天对象时检查的最近会话数量
+	PollSessions = 50
+
+	// PollTestLimit 触发器测试接口返回的示例消息数量
+	PollTestLimit = 3
+)
+
+// PollItem 轮询接口返回的一条消息，字段保持扁平以便在自动化平台中直接引用
+type PollItem struct {
+	ID         string    `json:"id"` // 稳定的消息 ID，格式为 序号:聊天对象
+	Seq        int64     `json:"seq"`
+	Time       time.Time `json:"time"`
+	Talker     string    `json:"talker"`
+	TalkerName string    `json:"talkerName"`
+	IsChatRoom bool      `json:"isChatRoom"`
+	Sender     string    `json:"sender"`
+	SenderName string    `json:"senderName"`
+	IsSelf     bool      `json:"isSelf"`
+	Type       int64     `json:"type"`
+	SubType    int64     `json:"subType"`
+	Content    string    `json:"content"`
+	// Translation 指定 translate 时文本消息的译文
+	Translation string `json:"translation,omitempty"`
+}
+
+// pollCursor 轮询进度，消息按 序号、聊天对象 排序，不同聊天对象的消息序号相同时也不会遗漏
+type pollCursor struct {
+	Seq    int64
+	Talker string
+}
+
+// pollID 返回消息的稳定 ID
+func pollID(m *model.Message) string {
+	return strconv.FormatInt(m.Seq, 10) + ":" + m.Talker
+}
+
+// parseSinceID 解析 since_id，可以是消息 ID 或只有序号
+func parseSinceID(s string) (pollCursor, bool) {
+	seq, talker, _ := strings.Cut(s, ":")
+	n, err := strconv.ParseInt(seq, 10, 64)
+	if err != nil || n < 0 {
+		return pollCursor{}, false
+	}
+	return pollCursor{Seq: n, Talker: talker}, true
+}
+
+// after 判断消息是否在轮询进度之后，since_id 只有序号时不包括该序号的消息
+func (c pollCursor) after(m *model.Message) bool {
+	return m.Seq > c.Seq || (m.Seq == c.Seq && len(c.Talker) != 0 && m.Talker > c.Talker)
+}
+
+// time 返回轮询进度对应的时间，消息序号为 10 位时间戳 + 3 位序号
+func (c pollCursor) time() time.Time {
+	return time.Unix(c.Seq/1000, 0)
+}
+
+// pollItems 返回轮询结果，按时间倒序排列
+// 指定 since 时返回其后最早的 limit 条消息，调用方以第一条的 ID 作为下次的 since_id，消息较多时不会遗漏；否则返回最近的 limit 条消息
+func pollItems(messages []*model.Message, since *pollCursor, limit int) []*PollItem {
+	sort.Slice(messages, func(i, j int) bool {
+		if messages[i].Seq != messages[j].Seq {
+			return messages[i].Seq < messages[j].Seq
+		}
+		return messages[i].Talker < messages[j].Talker
+	})
+	if since != nil {
+		fresh := messages[:0]
+		for _, m := range messages {
+			if since.after(m) {
+				fresh = append(fresh, m)
+			}
+		}
+		messages = fresh
+		if len(messages) > limit {
+			messages = messages[:limit]
+		}
+	} else if len(messages) > limit {
+		messages = messages[len(messages)-limit:]
+	}
+
+	items := make([]*PollItem, 0, len(messages))
+	for i := len(messages) - 1; i >= 0; i-- {
+		m := messages[i]
+		items = append(items, &PollItem{
+			ID:         pollID(m),
+			Seq:        m.Seq,
+			Time:       m.Time,
+			Talker:     m.Talker,
+			TalkerName: m.TalkerName,
+			IsChatRoom: m.IsChatRoom,
+			Sender:     m.Sender,
+			SenderName: m.SenderName,
+			IsSelf:     m.IsSelf,
+			Type:       m.Type,
+			SubType:    m.SubType,
+			Content:    m.PlainTextContent(),
+		})
+	}
+	return items
+}
+
+// handlePollMessages 供 n8n、Zapier 等自动化平台轮询新消息，返回 since_id 之后的消息
+// talker 为空时检查最近有新消息的会话
+func (s *Service) handlePollMessages(c *gin.Context) {
+	q := struct {
+		Talker    string `form:"talker"`
+		SinceID   string `form:"since_id"`
+		Limit     int    `form:"limit"`
+		Translate string `form:"translate"`
+	}{}
+	if err := c.BindQuery(&q); err != nil {
+		errors.Err(c, err)
+		return
+	}
+	if q.Limit <= 0 {
+		q.Limit = PollDefaultLimit
+	}
+	q.Limit = min(q.Limit, PollMaxLimit)
+
+	var since *pollCursor
+	start := time.Now().Add(-PollWindow)
+	if len(q.SinceID) != 0 {
+		cursor, ok := parseSinceID(q.SinceID)
+		if !ok {
+			errors.Err(c, errors.InvalidArg("since_id"))
+			return
+		}
+		since, start = &cursor, cursor.time()
+	}
+
+	messages, err := s.pollMessages(c.Request.Context(), q.Talker, start)
+	if err != nil {
+		errors.Err(c, err)
+		return
+	}
+	items := pollItems(messages, since, q.Limit)
+	if len(q.Translate) != 0 {
+		if err := s.translateItems(c.Request.Context(), items, q.Translate); err != nil {
+			errors.Err(c, err)
+			return
+		}
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+// translateItems 将轮询结果中的文本消息翻译为目标语言
+func (s *Service) translateItems(ctx context.Context, items []*PollItem, target string) error {
+	texts := make([]string, 0)
+	index := make([]*PollItem, 0)
+	for _, item := range items {
+		if item.Type == model.MessageTypeText {
+			texts = append(texts, item.Content)
+			index = append(index, item)
+		}
+	}
+	translated, err := s.translateTexts(ctx, texts, target)
+	if err != nil {
+		return err
+	}
+	for i, tr := range translated {
+		index[i].Translation = tr
+	}
+	return nil
+}
+
+// handlePollTest 供自动化平台配置触发器时测试连接，返回最近的几条消息作为示例
+func (s *Service) handlePollTest(c *gin.Context) {
+	messages, err := s.pollMessages(c.Request.Context(), c.Query("talker"), time.Now().Add(-PollWindow))
+	if err != nil {
+		errors.Err(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, pollItems(messages, nil, PollTestLimit))
+}
+
+// pollMessages 返回聊天对象从 start 开始的消息，talker 为空时查询 start 之后有新消息的最近会话
+func (s *Service) pollMessages(ctx context.Context, talker string, start time.Time) ([]*model.Message, error) {
+	if len(talker) == 0 {
+		sessions, err := s.db.GetSessions(ctx, "", PollSessions, 0)
+		if err != nil {
+			return nil, err
+		}
+		talkers := make([]string, 0, len(sessions.Items))
+		for _, session := range sessions.Items {
+			if !session.NTime.Before(start) {
+				talkers = append(talkers, session.UserName)
+			}
+		}
+		if len(talkers) == 0 {
+			return nil, nil
+		}
+		talker = strings.Join(talkers, ",")
+	}
+	return s.db.GetMessages(ctx, start, time.Now(), talker, "", "", 0, 0)
+}
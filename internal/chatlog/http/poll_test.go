@@ -0,0 +1,58 @@
+package http
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DanielMao1/chatlog/internal/model"
+)
+
+func TestPollItems(t *testing.T) {
+	messages := func() []*model.Message {
+		return []*model.Message{
+			{Seq: 1700000003000, Talker: "b"},
+			{Seq: 1700000001000, Talker: "a"},
+			{Seq: 1700000002000, Talker: "b"},
+			{Seq: 1700000002000, Talker: "a"},
+		}
+	}
+	ids := func(items []*PollItem) []string {
+		ret := []string{}
+		for _, item := range items {
+			ret = append(ret, item.ID)
+		}
+		return ret
+	}
+
+	tests := []struct {
+		name  string
+		since string
+		limit int
+		want  []string
+	}{
+		{"latest", "", 2, []string{"1700000003000:b", "1700000002000:b"}},
+		{"since id", "1700000002000:a", 10, []string{"1700000003000:b", "1700000002000:b"}},
+		{"since seq", "1700000001000", 10, []string{"1700000003000:b", "1700000002000:b", "1700000002000:a"}},
+		{"earliest after since", "1700000001000:a", 2, []string{"1700000002000:b", "1700000002000:a"}},
+		{"up to date", "1700000003000:b", 10, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var since *pollCursor
+			if len(tt.since) != 0 {
+				c, ok := parseSinceID(tt.since)
+				if !ok {
+					t.Fatalf("parseSinceID(%q) failed", tt.since)
+				}
+				since = &c
+			}
+			if got := ids(pollItems(messages(), since, tt.limit)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pollItems = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, ok := parseSinceID("abc"); ok {
+		t.Error("parseSinceID(abc) should fail")
+	}
+}
@@ -27,7 +27,9 @@ func (s *Service) initRouter() {
 	s.initBaseRouter()
 	s.initMediaRouter()
 	s.initAPIRouter()
+	s.initHomeAssistantRouter()
 	s.initMCPRouter()
+	s.initDebugRouter()
 }
 
 func (s *Service) initBaseRouter() {
@@ -38,38 +40,73 @@ func (s *Service) initBaseRouter() {
 	s.router.StaticFileFS("/", "./index.htm", http.FS(staticDir))
 
 	s.router.GET("/health", func(ctx *gin.Context) {
-		ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+		resp := gin.H{"status": "ok"}
+		if lag, ok := s.DecryptLag(); ok {
+			resp["decrypt_lag"] = lag.Seconds()
+		}
+		ctx.JSON(http.StatusOK, resp)
 	})
 
 	s.router.NoRoute(s.NoRoute)
 }
 
 func (s *Service) initMediaRouter() {
-	s.router.GET("/image/*key", func(c *gin.Context) { s.handleMedia(c, "image") })
-	s.router.GET("/video/*key", func(c *gin.Context) { s.handleMedia(c, "video") })
-	s.router.GET("/file/*key", func(c *gin.Context) { s.handleMedia(c, "file") })
-	s.router.GET("/voice/*key", func(c *gin.Context) { s.handleMedia(c, "voice") })
-	s.router.GET("/data/*path", s.handleMediaData)
+	media := s.router.Group("/", s.authMiddleware())
+	media.GET("/image/*key", func(c *gin.Context) { s.handleMedia(c, "image") })
+	media.GET("/video/*key", func(c *gin.Context) { s.handleMedia(c, "video") })
+	media.GET("/file/*key", func(c *gin.Context) { s.handleMedia(c, "file") })
+	media.GET("/voice/*key", func(c *gin.Context) { s.handleMedia(c, "voice") })
+	media.GET("/data/*path", s.handleMediaData)
 }
 
 func (s *Service) initAPIRouter() {
-	api := s.router.Group("/api/v1", s.checkDBStateMiddleware())
+	api := s.router.Group("/api/v1", s.authMiddleware(), s.checkDBStateMiddleware())
 	{
 		api.GET("/chatlog", s.handleChatlog)
 		api.GET("/contact", s.handleContacts)
 		api.GET("/chatroom", s.handleChatRooms)
 		api.GET("/session", s.handleSessions)
+		api.GET("/semantic-search", s.handleSemanticSearch)
+		api.GET("/ask", s.handleAsk)
+		api.POST("/ask", s.handleAsk)
+		api.GET("/poll/messages", s.handlePollMessages)
+		api.GET("/poll/test", s.handlePollTest)
+		api.GET("/events", s.handleEvents)
+		api.GET("/events.ics", s.handleEvents)
+		api.GET("/grafana", s.handleGrafanaTest)
+		api.POST("/grafana/search", s.handleGrafanaSearch)
+		api.POST("/grafana/query", s.handleGrafanaQuery)
+		api.POST("/grafana/annotations", s.handleGrafanaAnnotations)
+		api.GET("/transcribe", s.handleTranscribe)
+		api.GET("/transcripts", s.handleTranscripts)
+		api.GET("/ocr", s.handleOCR)
+		api.GET("/ocr/search", s.handleOCRSearch)
+		api.GET("/annotations", s.handleAnnotations)
+		api.GET("/stats", s.handleStats)
+		api.GET("/stats/analytics", s.handleAnalytics)
+		api.GET("/stats/words", s.handleWords)
+		api.GET("/shortcuts/latest", s.handleShortcutsLatest)
+		api.GET("/shortcuts/today", s.handleShortcutsToday)
+		api.GET("/deliveries", s.handleDeliveries)
+		api.POST("/deliveries/:id/retry", s.handleRedeliver)
+		api.DELETE("/deliveries/:id", s.handleDiscardDelivery)
 	}
 }
 
+func (s *Service) initHomeAssistantRouter() {
+	// 数据库未就绪时也返回服务状态，不经过 checkDBStateMiddleware
+	s.router.GET("/api/v1/homeassistant", s.authMiddleware(), s.handleHomeAssistant)
+}
+
 func (s *Service) initMCPRouter() {
-	s.router.Any("/mcp", func(c *gin.Context) {
+	mcp := s.router.Group("/", s.authMiddleware())
+	mcp.Any("/mcp", func(c *gin.Context) {
 		s.mcpStreamableServer.ServeHTTP(c.Writer, c.Request)
 	})
-	s.router.Any("/sse", func(c *gin.Context) {
+	mcp.Any("/sse", func(c *gin.Context) {
 		s.mcpSSEServer.ServeHTTP(c.Writer, c.Request)
 	})
-	s.router.Any("/message", func(c *gin.Context) {
+	mcp.Any("/message", func(c *gin.Context) {
 		s.mcpSSEServer.ServeHTTP(c.Writer, c.Request)
 	})
 }
@@ -90,13 +127,14 @@ func (s *Service) NoRoute(c *gin.Context) {
 func (s *Service) handleChatlog(c *gin.Context) {
 
 	q := struct {
-		Time    string `form:"time"`
-		Talker  string `form:"talker"`
-		Sender  string `form:"sender"`
-		Keyword string `form:"keyword"`
-		Limit   int    `form:"limit"`
-		Offset  int    `form:"offset"`
-		Format  string `form:"format"`
+		Time      string `form:"time"`
+		Talker    string `form:"talker"`
+		Sender    string `form:"sender"`
+		Keyword   string `form:"keyword"`
+		Limit     int    `form:"limit"`
+		Offset    int    `form:"offset"`
+		Format    string `form:"format"`
+		Translate string `form:"translate"` // 目标语言，如 en，为空时不翻译
 	}{}
 
 	if err := c.BindQuery(&q); err != nil {
@@ -117,11 +155,18 @@ func (s *Service) handleChatlog(c *gin.Context) {
 		q.Offset = 0
 	}
 
-	messages, err := s.db.GetMessages(start, end, q.Talker, q.Sender, q.Keyword, q.Limit, q.Offset)
+	messages, err := s.db.GetMessages(c.Request.Context(), start, end, q.Talker, q.Sender, q.Keyword, q.Limit, q.Offset)
 	if err != nil {
 		errors.Err(c, err)
 		return
 	}
+	s.attachText(messages)
+	if len(q.Translate) != 0 {
+		if err := s.translateMessages(c.Request.Context(), messages, q.Translate); err != nil {
+			errors.Err(c, err)
+			return
+		}
+	}
 
 	switch strings.ToLower(q.Format) {
 	case "csv":
@@ -169,7 +214,7 @@ func (s *Service) handleContacts(c *gin.Context) {
 		return
 	}
 
-	list, err := s.db.GetContacts(q.Keyword, q.Limit, q.Offset)
+	list, err := s.db.GetContacts(c.Request.Context(), q.Keyword, q.Limit, q.Offset)
 	if err != nil {
 		errors.Err(c, err)
 		return
@@ -214,7 +259,7 @@ func (s *Service) handleChatRooms(c *gin.Context) {
 		return
 	}
 
-	list, err := s.db.GetChatRooms(q.Keyword, q.Limit, q.Offset)
+	list, err := s.db.GetChatRooms(c.Request.Context(), q.Keyword, q.Limit, q.Offset)
 	if err != nil {
 		errors.Err(c, err)
 		return
@@ -258,7 +303,7 @@ func (s *Service) handleSessions(c *gin.Context) {
 		return
 	}
 
-	sessions, err := s.db.GetSessions(q.Keyword, q.Limit, q.Offset)
+	sessions, err := s.db.GetSessions(c.Request.Context(), q.Keyword, q.Limit, q.Offset)
 	if err != nil {
 		errors.Err(c, err)
 		return
@@ -313,7 +358,7 @@ func (s *Service) handleMedia(c *gin.Context, _type string) {
 				return
 			}
 		}
-		media, err := s.db.GetMedia(_type, k)
+		media, err := s.db.GetMedia(c.Request.Context(), _type, k)
 		if err != nil {
 			_err = err
 			continue
@@ -390,7 +435,7 @@ func (s *Service) HandleDatFile(c *gin.Context, path string) {
 		errors.Err(c, err)
 		return
 	}
-	out, ext, err := dat2img.Dat2Image(b)
+	out, ext, err := dat2img.Dat2ImageContext(c.Request.Context(), b)
 	if err != nil {
 		c.File(path)
 		return
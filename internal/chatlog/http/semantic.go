@@ -0,0 +1,195 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/database"
+	"github.com/DanielMao1/chatlog/internal/chatlog/llm"
+	"github.com/DanielMao1/chatlog/internal/chatlog/semantic"
+	"github.com/DanielMao1/chatlog/internal/errors"
+	"github.com/DanielMao1/chatlog/internal/model"
+)
+
+// startSemantic 配置了 embedding 时读取工作目录中的向量索引，并在后台定期为新消息建立索引
+func (s *Service) startSemantic() {
+	c := s.conf.GetEmbedding()
+	embedder, err := llm.NewEmbedder(c)
+	if err != nil {
+		log.Warn().Err(err).Msg("semantic search disabled")
+		return
+	}
+	if embedder == nil {
+		return
+	}
+	if len(s.conf.GetWorkDir()) == 0 {
+		log.Warn().Msg("semantic search disabled, work dir is not configured")
+		return
+	}
+	searcher, err := semantic.New(filepath.Join(s.conf.GetWorkDir(), semantic.IndexFile), embedder, &semanticSource{db: s.db, talkers: c.Talkers, attach: s.attachText})
+	if err != nil {
+		log.Warn().Err(err).Msg("semantic search disabled")
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.semantic, s.semanticCancel = searcher, cancel
+	go searcher.Run(ctx, c.Interval)
+}
+
+// stopSemantic 停止更新向量索引
+func (s *Service) stopSemantic() {
+	if s.semanticCancel != nil {
+		s.semanticCancel()
+		s.semanticCancel = nil
+	}
+}
+
+func (s *Service) handleSemanticSearch(c *gin.Context) {
+	q := struct {
+		Query  string `form:"q"`
+		Talker string `form:"talker"`
+		Limit  int    `form:"limit"`
+		Format string `form:"format"`
+	}{}
+	if err := c.BindQuery(&q); err != nil {
+		errors.Err(c, err)
+		return
+	}
+	if s.semantic == nil {
+		errors.Err(c, errors.New(nil, http.StatusServiceUnavailable, "semantic search is not enabled, configure embedding first"))
+		return
+	}
+	if len(strings.TrimSpace(q.Query)) == 0 {
+		errors.Err(c, errors.InvalidArg("q"))
+		return
+	}
+
+	hits, err := s.semantic.Search(c.Request.Context(), q.Query, q.Talker, q.Limit)
+	if err != nil {
+		errors.Err(c, errors.Newf(err, http.StatusBadGateway, "semantic search failed"))
+		return
+	}
+
+	switch strings.ToLower(q.Format) {
+	case "json":
+		c.JSON(http.StatusOK, hits)
+	default:
+		c.Writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, h := range hits {
+			c.Writer.WriteString(fmt.Sprintf("%s(%s) %s ~ %s  score %.3f\n%s\n\n",
+				h.Name, h.Talker, h.Start.Format("2006-01-02 15:04"), h.End.Format("15:04"), h.Score, h.Text))
+		}
+	}
+}
+
+// AskLimit 问答时检索的聊天记录数量
+const AskLimit = 8
+
+// handleAsk 检索与问题相关的聊天记录，由大模型回答并附带引用的消息序号与时间
+// GET 使用查询参数，POST 使用 JSON 请求体
+func (s *Service) handleAsk(c *gin.Context) {
+	q := struct {
+		Question string `form:"q" json:"question"`
+		Talker   string `form:"talker" json:"talker"`
+		Limit    int    `form:"limit" json:"limit"`
+	}{}
+	if err := c.ShouldBind(&q); err != nil {
+		errors.Err(c, errors.InvalidArg("body"))
+		return
+	}
+	if s.semantic == nil {
+		errors.Err(c, errors.New(nil, http.StatusServiceUnavailable, "semantic search is not enabled, configure embedding first"))
+		return
+	}
+	provider, err := llm.New(s.conf.GetLLM())
+	if err != nil {
+		errors.Err(c, errors.Newf(err, http.StatusServiceUnavailable, "invalid llm config"))
+		return
+	}
+	if provider == nil {
+		errors.Err(c, errors.New(nil, http.StatusServiceUnavailable, "llm is not configured"))
+		return
+	}
+	if len(strings.TrimSpace(q.Question)) == 0 {
+		errors.Err(c, errors.InvalidArg("q"))
+		return
+	}
+	if q.Limit <= 0 {
+		q.Limit = AskLimit
+	}
+
+	answer, err := s.semantic.Ask(c.Request.Context(), provider, q.Question, q.Talker, q.Limit)
+	if err != nil {
+		errors.Err(c, errors.Newf(err, http.StatusBadGateway, "ask failed"))
+		return
+	}
+	c.JSON(http.StatusOK, answer)
+}
+
+// semanticSource 从数据库读取建立索引的消息
+type semanticSource struct {
+	db      *database.Service
+	talkers []string
+	// attach 为语音与图片消息附上转写与识别出的文字，使其内容也能被检索
+	attach func([]*model.Message)
+}
+
+func (src *semanticSource) Talkers(ctx context.Context) ([]string, error) {
+	if len(src.talkers) != 0 {
+		return src.talkers, nil
+	}
+	if src.db.GetDB() == nil {
+		return nil, fmt.Errorf("database is not ready")
+	}
+	resp, err := src.db.GetSessions(ctx, "", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	talkers := make([]string, 0, len(resp.Items))
+	for _, session := range resp.Items {
+		talkers = append(talkers, session.UserName)
+	}
+	return talkers, nil
+}
+
+func (src *semanticSource) Messages(ctx context.Context, talker string, since time.Time) ([]*semantic.Message, error) {
+	if src.db.GetDB() == nil {
+		return nil, fmt.Errorf("database is not ready")
+	}
+	if since.IsZero() {
+		since = time.Unix(0, 0)
+	}
+	messages, err := src.db.GetMessages(ctx, since, time.Now(), talker, "", "", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	if src.attach != nil {
+		src.attach(messages)
+	}
+	ret := make([]*semantic.Message, 0, len(messages))
+	for _, msg := range messages {
+		sender := msg.SenderName
+		if len(sender) == 0 {
+			sender = msg.Sender
+		}
+		if msg.IsSelf {
+			sender = "我"
+		}
+		ret = append(ret, &semantic.Message{
+			Talker:     msg.Talker,
+			TalkerName: msg.TalkerName,
+			Seq:        msg.Seq,
+			Time:       msg.Time,
+			Sender:     sender,
+			Text:       msg.PlainTextContent(),
+		})
+	}
+	return ret, nil
+}
@@ -2,6 +2,7 @@ package http
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"time"
 
@@ -9,7 +10,13 @@ import (
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/rs/zerolog/log"
 
+	"github.com/DanielMao1/chatlog/internal/chatlog/analytics"
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
 	"github.com/DanielMao1/chatlog/internal/chatlog/database"
+	"github.com/DanielMao1/chatlog/internal/chatlog/ocr"
+	"github.com/DanielMao1/chatlog/internal/chatlog/plugin"
+	"github.com/DanielMao1/chatlog/internal/chatlog/semantic"
+	"github.com/DanielMao1/chatlog/internal/chatlog/stt"
 	"github.com/DanielMao1/chatlog/internal/errors"
 )
 
@@ -20,14 +27,55 @@ type Service struct {
 	router *gin.Engine
 	server *http.Server
 
+	requests *requestCounter
+
 	mcpServer           *server.MCPServer
 	mcpSSEServer        *server.SSEServer
 	mcpStreamableServer *server.StreamableHTTPServer
+
+	// semantic 配置了 embedding 时的语义搜索，未开启时为 nil
+	semantic       *semantic.Searcher
+	semanticCancel context.CancelFunc
+
+	// stt 配置了语音转文字时的转写服务，未开启时为 nil
+	stt       *stt.Service
+	sttCancel context.CancelFunc
+
+	// ocr 配置了图片文字识别时的识别服务，未开启时为 nil
+	ocr       *ocr.Service
+	ocrCancel context.CancelFunc
+
+	// analytics 保存定期分析的情感与话题，未配置工作目录时为 nil
+	analytics       *analytics.Service
+	analyticsCancel context.CancelFunc
+
+	// plugins 配置了插件时保存标注的插件服务，未开启时为 nil
+	plugins       *plugin.Service
+	pluginsCancel context.CancelFunc
+
+	// haCancel 停止通过 MQTT 发布 Home Assistant 状态
+	haCancel context.CancelFunc
+
+	// decryptLag 返回工作目录落后数据目录的时间，未设置或未开启自动解密时 decrypt_lag 指标为空
+	decryptLag func() (time.Duration, bool)
 }
 
 type Config interface {
 	GetHTTPAddr() string
 	GetDataDir() string
+	GetAuthToken() string
+	GetWorkDir() string
+	GetEmbedding() *conf.Embedding
+	GetLLM() *conf.LLM
+	GetMQTT() *conf.MQTT
+	GetHomeAssistant() *conf.HomeAssistant
+	GetSTT() *conf.STT
+	GetOCR() *conf.OCR
+	GetTranslate() *conf.Translate
+	GetAnalytics() *conf.Analytics
+	GetPlugins() []*conf.Plugin
+	GetDestinations() map[string]*conf.Destination
+	GetPprof() bool
 }
 
 func NewService(conf Config, db *database.Service) *Service {
@@ -39,18 +87,22 @@ func NewService(conf Config, db *database.Service) *Service {
 		log.Err(err).Msg("Failed to set trusted proxies")
 	}
 
+	requests := &requestCounter{}
+
 	// Middleware
 	router.Use(
 		errors.RecoveryMiddleware(),
 		errors.ErrorHandlerMiddleware(),
 		gin.LoggerWithWriter(log.Logger, "/health"),
+		requests.middleware(),
 		corsMiddleware(),
 	)
 
 	s := &Service{
-		conf:   conf,
-		db:     db,
-		router: router,
+		conf:     conf,
+		db:       db,
+		router:   router,
+		requests: requests,
 	}
 
 	s.initMCPServer()
@@ -74,21 +126,56 @@ func (s *Service) Start() error {
 
 	log.Info().Msg("Starting HTTP server on " + s.conf.GetHTTPAddr())
 
+	s.startSTT()
+	s.startOCR()
+	s.startAnalytics()
+	s.startSemantic()
+	s.startHomeAssistant()
+	s.startPlugins()
 	return nil
 }
 
-func (s *Service) ListenAndServe() error {
+// ListenAndServe 提供 HTTP 服务直到 ctx 取消，请求的上下文随 ctx 取消，进行中的查询与媒体转换随之停止
+func (s *Service) ListenAndServe(ctx context.Context) error {
 
 	s.server = &http.Server{
-		Addr:    s.conf.GetHTTPAddr(),
-		Handler: s.router,
+		Addr:        s.conf.GetHTTPAddr(),
+		Handler:     s.router,
+		BaseContext: func(net.Listener) context.Context { return ctx },
 	}
+	go func(server *http.Server) {
+		<-ctx.Done()
+		c, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		server.Shutdown(c)
+	}(s.server)
 
 	log.Info().Msg("Starting HTTP server on " + s.conf.GetHTTPAddr())
-	return s.server.ListenAndServe()
+	s.startSTT()
+	defer s.stopSTT()
+	s.startOCR()
+	defer s.stopOCR()
+	s.startAnalytics()
+	defer s.stopAnalytics()
+	s.startSemantic()
+	defer s.stopSemantic()
+	s.startHomeAssistant()
+	defer s.stopHomeAssistant()
+	s.startPlugins()
+	defer s.stopPlugins()
+	if err := s.server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
 }
 
 func (s *Service) Stop() error {
+	s.stopSemantic()
+	s.stopSTT()
+	s.stopOCR()
+	s.stopAnalytics()
+	s.stopHomeAssistant()
+	s.stopPlugins()
 
 	if s.server == nil {
 		return nil
@@ -0,0 +1,186 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/llm"
+	"github.com/DanielMao1/chatlog/internal/chatlog/summarize"
+	"github.com/DanielMao1/chatlog/internal/errors"
+	"github.com/DanielMao1/chatlog/internal/model"
+)
+
+const (
+	// ShortcutsDefaultCount、ShortcutsMaxCount 最近消息接口默认与最多返回的消息数量
+	ShortcutsDefaultCount = 10
+	ShortcutsMaxCount     = 100
+
+	// ShortcutsSessions 今日概况中列出的会话数量
+	ShortcutsSessions = 10
+)
+
+// 快捷指令接口面向 iOS 快捷指令等只能方便处理文本的客户端，返回纯文本，错误也以纯文本返回
+// 令牌可以通过 token 查询参数传递，见 authMiddleware
+
+// handleShortcutsLatest 以纯文本返回聊天对象最近的 n 条消息，每行一条，按时间排列
+func (s *Service) handleShortcutsLatest(c *gin.Context) {
+	q := struct {
+		Talker string `form:"talker"`
+		N      int    `form:"n"`
+	}{}
+	if err := c.BindQuery(&q); err != nil || len(strings.TrimSpace(q.Talker)) == 0 {
+		c.String(http.StatusBadRequest, "talker is required")
+		return
+	}
+	if q.N <= 0 {
+		q.N = ShortcutsDefaultCount
+	}
+	q.N = min(q.N, ShortcutsMaxCount)
+
+	// 只查询最近一周，没有消息时再查询全部
+	now := time.Now()
+	messages, err := s.db.GetMessages(c.Request.Context(), now.AddDate(0, 0, -7), now, q.Talker, "", "", 0, 0)
+	if err == nil && len(messages) < q.N {
+		messages, err = s.db.GetMessages(c.Request.Context(), time.Unix(0, 0), now, q.Talker, "", "", 0, 0)
+	}
+	if err != nil {
+		shortcutsError(c, err)
+		return
+	}
+	if len(messages) > q.N {
+		messages = messages[len(messages)-q.N:]
+	}
+
+	var buf strings.Builder
+	for _, m := range messages {
+		buf.WriteString(shortcutsLine(m, now))
+		buf.WriteString("\n")
+	}
+	c.String(http.StatusOK, strings.TrimSpace(buf.String()))
+}
+
+// handleShortcutsToday 以纯文本返回今天的概况
+// 指定 talker 时返回该聊天对象今天的总结，配置了 llm 时由大模型总结；否则列出今天消息最多的会话
+func (s *Service) handleShortcutsToday(c *gin.Context) {
+	talker := strings.TrimSpace(c.Query("talker"))
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	if len(talker) == 0 {
+		text, err := s.todayOverview(c.Request.Context(), today, now)
+		if err != nil {
+			shortcutsError(c, err)
+			return
+		}
+		c.String(http.StatusOK, text)
+		return
+	}
+
+	messages, err := s.db.GetMessages(c.Request.Context(), today, now, talker, "", "", 0, 0)
+	if err != nil {
+		shortcutsError(c, err)
+		return
+	}
+	if len(messages) == 0 {
+		c.String(http.StatusOK, fmt.Sprintf("%s 今天没有消息", talker))
+		return
+	}
+	name := messages[0].TalkerName
+	if len(name) == 0 {
+		name = messages[0].Talker
+	}
+	payload := summarize.Build(messages[0].Talker, name, today, messages, now)
+	if provider, err := llm.New(s.conf.GetLLM()); err == nil && provider != nil {
+		if err := summarize.Digest(c.Request.Context(), provider, s.conf.GetLLM(), payload); err != nil {
+			log.Debug().Err(err).Msg("llm summary for shortcuts failed")
+		}
+	}
+
+	text := fmt.Sprintf("%s 今天 %d 条消息\n\n%s", payload.Group, payload.MessageCount, payload.Summary)
+	if len(payload.Highlights) != 0 {
+		text += "\n\n• " + strings.Join(payload.Highlights, "\n• ")
+	}
+	c.String(http.StatusOK, text)
+}
+
+// todayOverview 列出今天消息最多的会话
+func (s *Service) todayOverview(ctx context.Context, today, now time.Time) (string, error) {
+	counts, err := s.db.CountMessages(ctx, today, now, "")
+	if err != nil {
+		return "", err
+	}
+	total := 0
+	byTalker := make(map[string]int)
+	for _, c := range counts {
+		total += c.Count
+		byTalker[c.Talker] += c.Count
+	}
+	if total == 0 {
+		return "今天没有消息", nil
+	}
+
+	talkers := make([]string, 0, len(byTalker))
+	for t := range byTalker {
+		talkers = append(talkers, t)
+	}
+	sort.Slice(talkers, func(i, j int) bool {
+		if byTalker[talkers[i]] != byTalker[talkers[j]] {
+			return byTalker[talkers[i]] > byTalker[talkers[j]]
+		}
+		return talkers[i] < talkers[j]
+	})
+	names := make(map[string]string)
+	if sessions, err := s.db.GetSessions(ctx, "", 0, 0); err == nil {
+		for _, session := range sessions.Items {
+			names[session.UserName] = session.NickName
+		}
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "今天 %d 个会话共 %d 条消息\n", len(talkers), total)
+	for i, t := range talkers {
+		if i >= ShortcutsSessions {
+			fmt.Fprintf(&buf, "……还有 %d 个会话\n", len(talkers)-ShortcutsSessions)
+			break
+		}
+		name := names[t]
+		if len(name) == 0 {
+			name = t
+		}
+		fmt.Fprintf(&buf, "%s：%d 条\n", name, byTalker[t])
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// shortcutsLine 将一条消息格式化为一行文本，不是今天的消息带上日期
+func shortcutsLine(m *model.Message, now time.Time) string {
+	layout := "15:04"
+	if m.Time.Format("2006-01-02") != now.Format("2006-01-02") {
+		layout = "01-02 15:04"
+	}
+	sender := m.SenderName
+	if len(sender) == 0 {
+		sender = m.Sender
+	}
+	if m.IsSelf {
+		sender = "我"
+	}
+	content := strings.Join(strings.Fields(m.PlainTextContent()), " ")
+	return fmt.Sprintf("%s %s：%s", m.Time.Format(layout), sender, content)
+}
+
+// shortcutsError 以纯文本返回错误
+func shortcutsError(c *gin.Context, err error) {
+	code := http.StatusInternalServerError
+	if appErr, ok := err.(*errors.Error); ok {
+		code = appErr.Code
+	}
+	c.String(code, err.Error())
+}
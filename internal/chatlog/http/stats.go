@@ -0,0 +1,72 @@
+package http
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestWindow 统计请求数的时间窗口，按秒分桶
+const requestWindow = 60
+
+// requestCounter 统计最近一分钟内处理的请求数
+type requestCounter struct {
+	mu      sync.Mutex
+	seconds [requestWindow]int64
+	counts  [requestWindow]int
+}
+
+func (c *requestCounter) add(now time.Time) {
+	sec := now.Unix()
+	i := sec % requestWindow
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.seconds[i] != sec {
+		c.seconds[i] = sec
+		c.counts[i] = 0
+	}
+	c.counts[i]++
+}
+
+// count 返回 now 之前一分钟内的请求数
+func (c *requestCounter) count(now time.Time) int {
+	sec := now.Unix()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	total := 0
+	for i := range c.seconds {
+		if sec-c.seconds[i] < requestWindow {
+			total += c.counts[i]
+		}
+	}
+	return total
+}
+
+// middleware 统计请求数，不包括健康检查
+func (c *requestCounter) middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.Request.URL.Path != "/health" {
+			c.add(time.Now())
+		}
+		ctx.Next()
+	}
+}
+
+// SetDecryptLag 设置获取解密落后时间的函数，用于 decrypt_lag 指标
+func (s *Service) SetDecryptLag(f func() (time.Duration, bool)) {
+	s.decryptLag = f
+}
+
+// DecryptLag 返回工作目录落后数据目录的时间，未开启自动解密时 ok 为 false
+func (s *Service) DecryptLag() (time.Duration, bool) {
+	if s.decryptLag == nil {
+		return 0, false
+	}
+	return s.decryptLag()
+}
+
+// RequestsPerMinute 返回最近一分钟内处理的请求数
+func (s *Service) RequestsPerMinute() int {
+	return s.requests.count(time.Now())
+}
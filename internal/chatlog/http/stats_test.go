@@ -0,0 +1,26 @@
+package http
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRequestCounter(t *testing.T) {
+	c := &requestCounter{}
+	now := time.Unix(1700000000, 0)
+
+	for i := 0; i < 3; i++ {
+		c.add(now.Add(-90 * time.Second))
+	}
+	c.add(now.Add(-59 * time.Second))
+	c.add(now.Add(-10 * time.Second))
+	c.add(now)
+	c.add(now)
+
+	if got := c.count(now); got != 4 {
+		t.Errorf("count = %d, want 4", got)
+	}
+	if got := c.count(now.Add(time.Minute)); got != 0 {
+		t.Errorf("count after a minute = %d, want 0", got)
+	}
+}
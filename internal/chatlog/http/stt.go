@@ -0,0 +1,260 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/database"
+	"github.com/DanielMao1/chatlog/internal/chatlog/stt"
+	"github.com/DanielMao1/chatlog/internal/errors"
+	"github.com/DanielMao1/chatlog/internal/model"
+	"github.com/DanielMao1/chatlog/pkg/util/silk"
+)
+
+// TranscriptLimit 搜索转写结果时默认返回的数量
+const TranscriptLimit = 50
+
+// startSTT 配置了 stt 时打开工作目录中的转写结果，配置了 talkers 时在后台定期转写新语音
+func (s *Service) startSTT() {
+	c := s.conf.GetSTT()
+	transcriber, err := stt.New(c)
+	if err != nil {
+		log.Warn().Err(err).Msg("speech to text disabled")
+		return
+	}
+	if transcriber == nil {
+		return
+	}
+	if len(s.conf.GetWorkDir()) == 0 {
+		log.Warn().Msg("speech to text disabled, work dir is not configured")
+		return
+	}
+	store, err := stt.Open(filepath.Join(s.conf.GetWorkDir(), stt.TranscriptFile))
+	if err != nil {
+		log.Warn().Err(err).Msg("speech to text disabled")
+		return
+	}
+	s.stt = stt.NewService(store, transcriber, &sttSource{db: s.db})
+	if len(c.Talkers) == 0 {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.sttCancel = cancel
+	go s.stt.Run(ctx, c.Talkers, c.Interval)
+}
+
+// stopSTT 停止批量转写并关闭转写结果
+func (s *Service) stopSTT() {
+	if s.sttCancel != nil {
+		s.sttCancel()
+		s.sttCancel = nil
+	}
+	if s.stt != nil {
+		s.stt.Store().Close()
+	}
+}
+
+// handleTranscribe 返回一条语音消息的转写结果，没有保存的结果时转写
+func (s *Service) handleTranscribe(c *gin.Context) {
+	q := struct {
+		Talker string `form:"talker"`
+		Seq    int64  `form:"seq"`
+	}{}
+	if err := c.BindQuery(&q); err != nil {
+		errors.Err(c, err)
+		return
+	}
+	if s.stt == nil {
+		errors.Err(c, errors.New(nil, http.StatusServiceUnavailable, "speech to text is not enabled, configure stt first"))
+		return
+	}
+	if len(q.Talker) == 0 || strings.Contains(q.Talker, ",") {
+		errors.Err(c, errors.InvalidArg("talker"))
+		return
+	}
+	if q.Seq <= 0 {
+		errors.Err(c, errors.InvalidArg("seq"))
+		return
+	}
+
+	m, err := s.messageOf(c.Request.Context(), q.Talker, q.Seq)
+	if err != nil {
+		errors.Err(c, err)
+		return
+	}
+	if m == nil || m.Type != model.MessageTypeVoice {
+		errors.Err(c, errors.New(nil, http.StatusNotFound, "voice message not found"))
+		return
+	}
+
+	t, err := s.stt.Transcribe(c.Request.Context(), voiceOf(m))
+	if err != nil {
+		errors.Err(c, errors.Newf(err, http.StatusBadGateway, "transcribe failed"))
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+// handleTranscripts 按关键词搜索语音消息的转写结果
+func (s *Service) handleTranscripts(c *gin.Context) {
+	q := struct {
+		Keyword string `form:"keyword"`
+		Talker  string `form:"talker"`
+		Limit   int    `form:"limit"`
+		Format  string `form:"format"`
+	}{}
+	if err := c.BindQuery(&q); err != nil {
+		errors.Err(c, err)
+		return
+	}
+	if s.stt == nil {
+		errors.Err(c, errors.New(nil, http.StatusServiceUnavailable, "speech to text is not enabled, configure stt first"))
+		return
+	}
+	if q.Limit <= 0 {
+		q.Limit = TranscriptLimit
+	}
+
+	list, err := s.stt.Store().Search(q.Keyword, q.Talker, q.Limit)
+	if err != nil {
+		errors.Err(c, err)
+		return
+	}
+
+	switch strings.ToLower(q.Format) {
+	case "json":
+		c.JSON(http.StatusOK, list)
+	default:
+		c.Writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, t := range list {
+			c.Writer.WriteString(fmt.Sprintf("%s %s %d\n%s\n\n", t.Talker, t.Time.Format("2006-01-02 15:04:05"), t.Seq, t.Text))
+		}
+	}
+}
+
+// attachTranscripts 为已转写的语音消息附上转写结果，保存在 Contents["transcript"]
+func (s *Service) attachTranscripts(messages []*model.Message) {
+	if s.stt == nil {
+		return
+	}
+	spans := spansOf(messages, model.MessageTypeVoice)
+	if len(spans) == 0 {
+		return
+	}
+
+	transcripts := make(map[string]map[int64]*stt.Transcript, len(spans))
+	for talker, r := range spans {
+		byseq, err := s.stt.Store().Range(talker, r.start, r.end)
+		if err != nil {
+			log.Debug().Err(err).Str("talker", talker).Msg("read transcripts failed")
+			continue
+		}
+		transcripts[talker] = byseq
+	}
+	for _, m := range messages {
+		if m.Type != model.MessageTypeVoice {
+			continue
+		}
+		if t, ok := transcripts[m.Talker][m.Seq]; ok {
+			if m.Contents == nil {
+				m.Contents = make(map[string]interface{})
+			}
+			m.Contents["transcript"] = t.Text
+		}
+	}
+}
+
+// messageOf 按序号查找聊天对象的一条消息，没有时返回 nil
+func (s *Service) messageOf(ctx context.Context, talker string, seq int64) (*model.Message, error) {
+	// 消息序号的前 10 位是发送时间
+	ts := time.Unix(seq/1000, 0)
+	messages, err := s.db.GetMessages(ctx, ts, ts.Add(time.Second), talker, "", "", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range messages {
+		if m.Seq == seq {
+			return m, nil
+		}
+	}
+	return nil, nil
+}
+
+// span 一个聊天对象的消息时间范围
+type span struct{ start, end time.Time }
+
+// spansOf 返回每个聊天对象中该类型消息的时间范围，_type 为 0 时包括所有消息
+func spansOf(messages []*model.Message, _type int64) map[string]*span {
+	spans := make(map[string]*span)
+	for _, m := range messages {
+		if _type != 0 && m.Type != _type {
+			continue
+		}
+		if r, ok := spans[m.Talker]; !ok {
+			spans[m.Talker] = &span{m.Time, m.Time}
+		} else if m.Time.Before(r.start) {
+			r.start = m.Time
+		} else if m.Time.After(r.end) {
+			r.end = m.Time
+		}
+	}
+	return spans
+}
+
+// voiceOf 返回语音消息的转写信息
+func voiceOf(m *model.Message) *stt.Voice {
+	v := &stt.Voice{Talker: m.Talker, Seq: m.Seq, Time: m.Time}
+	if key, ok := m.Contents["voice"]; ok {
+		v.Key = fmt.Sprint(key)
+	}
+	return v
+}
+
+// sttSource 从数据库读取语音消息与音频
+type sttSource struct {
+	db *database.Service
+}
+
+func (src *sttSource) Voices(ctx context.Context, talker string, since time.Time) ([]*stt.Voice, error) {
+	if src.db.GetDB() == nil {
+		return nil, fmt.Errorf("database is not ready")
+	}
+	if since.IsZero() {
+		since = time.Unix(0, 0)
+	}
+	messages, err := src.db.GetMessages(ctx, since, time.Now(), talker, "", "", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*stt.Voice, 0)
+	for _, m := range messages {
+		if m.Type == model.MessageTypeVoice {
+			ret = append(ret, voiceOf(m))
+		}
+	}
+	return ret, nil
+}
+
+// Audio 返回转换为 mp3 的语音，转换失败时返回原始的 silk 数据
+func (src *sttSource) Audio(ctx context.Context, v *stt.Voice) ([]byte, string, error) {
+	if len(v.Key) == 0 {
+		return nil, "", errors.ErrMediaNotFound
+	}
+	media, err := src.db.GetMedia(ctx, "voice", v.Key)
+	if err != nil {
+		return nil, "", err
+	}
+	out, err := silk.Silk2MP3(media.Data)
+	if err != nil {
+		return media.Data, "voice_" + strconv.FormatInt(v.Seq, 10) + ".silk", nil
+	}
+	return out, "voice_" + strconv.FormatInt(v.Seq, 10) + ".mp3", nil
+}
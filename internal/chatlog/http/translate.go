@@ -0,0 +1,56 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/translate"
+	"github.com/DanielMao1/chatlog/internal/errors"
+	"github.com/DanielMao1/chatlog/internal/model"
+)
+
+// translateMessages 将文本消息翻译为目标语言，译文保存在 Contents["translation"]，原文不变
+func (s *Service) translateMessages(ctx context.Context, messages []*model.Message, target string) error {
+	texts := make([]string, 0)
+	index := make([]*model.Message, 0)
+	for _, m := range messages {
+		if m.Type == model.MessageTypeText {
+			texts = append(texts, m.Content)
+			index = append(index, m)
+		}
+	}
+	translated, err := s.translateTexts(ctx, texts, target)
+	if err != nil {
+		return err
+	}
+	for i, tr := range translated {
+		if len(tr) == 0 {
+			continue
+		}
+		m := index[i]
+		if m.Contents == nil {
+			m.Contents = make(map[string]interface{})
+		}
+		m.Contents["translation"] = tr
+	}
+	return nil
+}
+
+// translateTexts 使用配置的翻译接口翻译文本，未配置时返回错误
+func (s *Service) translateTexts(ctx context.Context, texts []string, target string) ([]string, error) {
+	t, err := translate.New(s.conf.GetTranslate(), s.conf.GetLLM())
+	if err != nil {
+		return nil, errors.Newf(err, http.StatusServiceUnavailable, "invalid translate config")
+	}
+	if t == nil {
+		return nil, errors.New(nil, http.StatusServiceUnavailable, "translation is not enabled, configure translate first")
+	}
+	if len(texts) == 0 {
+		return texts, nil
+	}
+	ret, err := translate.Texts(ctx, t, texts, target)
+	if err != nil {
+		return nil, errors.Newf(err, http.StatusBadGateway, "translate failed")
+	}
+	return ret, nil
+}
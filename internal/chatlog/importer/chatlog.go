@@ -0,0 +1,142 @@
+package importer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/model"
+)
+
+// header csv 表头，列名转为小写
+type header map[string]int
+
+func newHeader(record []string) header {
+	h := make(header, len(record))
+	for i, name := range record {
+		h[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	return h
+}
+
+func (h header) has(names ...string) bool {
+	for _, name := range names {
+		if _, ok := h[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (h header) get(record []string, name string) string {
+	if i, ok := h[name]; ok && i < len(record) {
+		return record[i]
+	}
+	return ""
+}
+
+func readHeader(path string) (header, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	record, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	return newHeader(record), nil
+}
+
+// readCSV 逐行读取 csv，fn 返回错误时停止
+func readCSV(path string, fn func(h header, record []string) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	first, err := r.Read()
+	if err != nil {
+		return err
+	}
+	h := newHeader(first)
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(h, record); err != nil {
+			return err
+		}
+	}
+}
+
+// readChatlog 读取 chatlog 导出的消息，json 为 /api/v1/chatlog?format=json 的结果
+// csv 中没有是否自己发送的信息，发送人与 --self 相同的消息视为自己发送
+func readChatlog(path string, opts Options, w *writer) error {
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		return readChatlogJSON(path, w)
+	}
+	return readCSV(path, func(h header, record []string) error {
+		t, err := time.ParseInLocation("2006-01-02 15:04:05", h.get(record, "time"), time.Local)
+		if err != nil {
+			return err
+		}
+		talker := h.get(record, "talker")
+		if len(talker) == 0 {
+			talker = opts.Talker
+		}
+		sender := h.get(record, "sender")
+		return w.AddMessage(&model.Message{
+			Time:       t,
+			Talker:     talker,
+			TalkerName: h.get(record, "talkername"),
+			Sender:     sender,
+			SenderName: h.get(record, "sendername"),
+			IsSelf:     (len(opts.Self) != 0 && sender == opts.Self) || sender == "我",
+			Type:       model.MessageTypeText,
+			Content:    stripMediaLinks(h.get(record, "content")),
+		})
+	})
+}
+
+func readChatlogJSON(path string, w *writer) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var msgs []*model.Message
+	if err := json.NewDecoder(f).Decode(&msgs); err != nil {
+		return err
+	}
+	for _, m := range msgs {
+		if m.Type != model.MessageTypeText && m.Type != model.MessageTypeSystem {
+			// 导出的 JSON 只保留了解析后的媒体信息，按描述文本导入
+			m.SetContent("host", "")
+			m.Content = stripMediaLinks(m.PlainTextContent())
+			m.Type, m.SubType = model.MessageTypeText, 0
+		}
+		if err := w.AddMessage(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func stripMediaLinks(s string) string {
+	return mediaLinkRe.ReplaceAllString(s, "$1")
+}
@@ -0,0 +1,212 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/merge"
+)
+
+const (
+	FormatChatlog   = "chatlog"   // chatlog 导出的 csv / json
+	FormatMemoTrace = "memotrace" // 留痕（WeChatMsg）导出的 csv
+	FormatIOS       = "ios"       // iOS 备份中的 MM.sqlite
+)
+
+// Formats 支持的导入格式
+var Formats = []string{FormatChatlog, FormatMemoTrace, FormatIOS}
+
+// Options 导入参数
+type Options struct {
+	Input   string // 导出文件、导出文件所在目录或 iOS 备份中的 DB 目录
+	WorkDir string // 目标工作目录，已有数据时合并导入
+	Format  string // 导入格式，为空时自动识别
+	Talker  string // 聊天对象，导出文件中不包含聊天对象 ID 时使用
+	Self    string // 自己的微信 ID，为空时使用导出文件中的发送人
+}
+
+// Result 导入结果
+type Result struct {
+	WorkDir  string `json:"workDir"`
+	Format   string `json:"format"`
+	Files    int    `json:"files"`
+	Messages int    `json:"messages"` // 新增的消息数，与已有数据重复的消息不计入
+	Talkers  int    `json:"talkers"`
+	Contacts int    `json:"contacts"`
+}
+
+// reader 读取一个输入文件并写入 writer
+type reader func(path string, opts Options, w *writer) error
+
+var readers = map[string]reader{
+	FormatChatlog:   readChatlog,
+	FormatMemoTrace: readMemoTrace,
+	FormatIOS:       readIOS,
+}
+
+// 导出文件中指向 chatlog 服务的媒体链接，导入后原链接已无法访问
+var mediaLinkRe = regexp.MustCompile(`!?(\[[^\]]*\])\(https?://[^/\s)]*/(?:image|video|voice|file)/[^)\s]*\)`)
+
+// Run 将外部导出的聊天记录转换为微信 4.0 工作目录的结构
+// 目标工作目录为空时直接写入，已有 4.0 数据时先写入临时目录，再经合并存档合并到工作目录，
+// 之后解密覆盖工作目录中的数据库时导入的消息会被重新合并；重复导入同一份数据不会产生重复消息
+func Run(opts Options) (*Result, error) {
+	if len(opts.Input) == 0 {
+		return nil, fmt.Errorf("input is required")
+	}
+	if len(opts.WorkDir) == 0 {
+		return nil, fmt.Errorf("workDir is required")
+	}
+	files, format, err := detect(opts.Input, opts.Format)
+	if err != nil {
+		return nil, err
+	}
+	dst := opts.WorkDir
+	platform, version := merge.Detect(opts.WorkDir)
+	switch version {
+	case 0:
+	case 4:
+		if dst, err = os.MkdirTemp("", "chatlog-import-*"); err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dst)
+	default:
+		return nil, fmt.Errorf("import into %s %d.x work dir is not supported, use an empty or 4.0 work dir", platform, version)
+	}
+
+	w, err := newWriter(dst, opts.Self)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		log.Info().Msgf("importing %s", file)
+		if err := readers[format](file, opts, w); err != nil {
+			w.Close()
+			return nil, fmt.Errorf("import %s failed: %w", file, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	ret := &Result{
+		WorkDir:  opts.WorkDir,
+		Format:   format,
+		Files:    len(files),
+		Messages: w.messages,
+		Talkers:  len(w.sessions),
+		Contacts: len(w.contacts),
+	}
+	if dst != opts.WorkDir {
+		m, err := merge.Archive(opts.WorkDir, []string{dst}, platform, version)
+		if err != nil {
+			return nil, err
+		}
+		ret.Messages = int(m.Messages)
+	}
+	return ret, nil
+}
+
+// detect 识别输入格式并列出需要导入的文件
+func detect(input, format string) ([]string, string, error) {
+	info, err := os.Stat(input)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if info.IsDir() {
+		if path := findFile(input, "MM.sqlite"); len(path) != 0 && (len(format) == 0 || format == FormatIOS) {
+			return []string{path}, FormatIOS, nil
+		}
+	} else if info.Name() == "MM.sqlite" && (len(format) == 0 || format == FormatIOS) {
+		return []string{input}, FormatIOS, nil
+	}
+
+	files := []string{input}
+	if info.IsDir() {
+		files = files[:0]
+		err := filepath.Walk(input, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			switch strings.ToLower(filepath.Ext(path)) {
+			case ".csv", ".json":
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		sort.Strings(files)
+	}
+	if len(files) == 0 {
+		return nil, "", fmt.Errorf("no export file found in %s", input)
+	}
+
+	if len(format) != 0 {
+		if _, ok := readers[format]; !ok {
+			return nil, "", fmt.Errorf("unsupported format: %s, supported formats: %s", format, strings.Join(Formats, ", "))
+		}
+		return files, format, nil
+	}
+	// 目录中无法识别的文件跳过
+	matched := make([]string, 0, len(files))
+	for _, file := range files {
+		f := detectFile(file)
+		if len(f) == 0 {
+			log.Debug().Msgf("skip unknown file %s", file)
+			continue
+		}
+		if len(format) != 0 && f != format {
+			return nil, "", fmt.Errorf("mixed export formats in %s, use --format to choose one", input)
+		}
+		format = f
+		matched = append(matched, file)
+	}
+	if len(format) == 0 {
+		return nil, "", fmt.Errorf("unknown export format: %s", input)
+	}
+	return matched, format, nil
+}
+
+// detectFile 根据扩展名和 csv 表头识别单个导出文件的格式
+func detectFile(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatChatlog
+	case ".csv":
+		header, err := readHeader(path)
+		if err != nil {
+			return ""
+		}
+		switch {
+		case header.has("strcontent", "issender"):
+			return FormatMemoTrace
+		case header.has("talker", "content"):
+			return FormatChatlog
+		}
+	}
+	return ""
+}
+
+func findFile(dir, name string) string {
+	ret := ""
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !info.IsDir() && info.Name() == name {
+			ret = path
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return ret
+}
@@ -0,0 +1,263 @@
+package importer
+
+import (
+	"context"
+	"crypto/md5"
+	"database/sql"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/merge"
+	"github.com/DanielMao1/chatlog/internal/model"
+	"github.com/DanielMao1/chatlog/internal/wechatdb"
+)
+
+const memoTraceCSV = `localId,TalkerId,Type,SubType,IsSender,CreateTime,Status,StrContent,StrTime,Remark,NickName,Sender
+1,5,1,0,0,1700000000,2,hello,2023-11-15 06:13:20,Bob,bob,wxid_bob
+2,5,1,0,1,1700000005,2,hi,2023-11-15 06:13:25,,,wxid_me
+`
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "bob.csv")
+	if err := os.WriteFile(input, []byte(memoTraceCSV), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workDir := filepath.Join(dir, "work")
+
+	for i, want := range []int{2, 0} {
+		ret, err := Run(Options{Input: input, WorkDir: workDir})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ret.Format != FormatMemoTrace || ret.Messages != want {
+			t.Errorf("import #%d: format %s, %d messages, want %d", i+1, ret.Format, ret.Messages, want)
+		}
+	}
+
+	db, err := wechatdb.New(workDir, "windows", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	msgs, err := db.GetMessages(context.Background(), time.Unix(0, 0), time.Now(), "wxid_bob", "", "", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 || msgs[0].Content != "hello" || msgs[0].IsSelf || !msgs[1].IsSelf {
+		t.Fatalf("unexpected messages: %+v", msgs)
+	}
+}
+
+// messages 打开导入后的工作目录，返回聊天对象的全部消息
+func messages(t *testing.T, workDir, talker string) []*model.Message {
+	t.Helper()
+	db, err := wechatdb.New(workDir, "windows", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	msgs, err := db.GetMessages(context.Background(), time.Unix(0, 0), time.Now(), talker, "", "", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msgs
+}
+
+func writeFile(t *testing.T, path, content string) string {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadChatlogCSV(t *testing.T) {
+	dir := t.TempDir()
+	input := writeFile(t, filepath.Join(dir, "alice.csv"), `Time,SenderName,Sender,TalkerName,Talker,Content
+2024-01-02 10:00:00,Alice,wxid_alice,Alice,wxid_alice,see ![图片](http://127.0.0.1:5030/image/abc)
+2024-01-02 10:01:00,Me,wxid_me,Alice,wxid_alice,ok
+`)
+	workDir := filepath.Join(dir, "work")
+	ret, err := Run(Options{Input: input, WorkDir: workDir, Self: "wxid_me"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.Format != FormatChatlog || ret.Messages != 2 {
+		t.Fatalf("unexpected result: %+v", ret)
+	}
+	msgs := messages(t, workDir, "wxid_alice")
+	if len(msgs) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(msgs))
+	}
+	// 指向 chatlog 服务的媒体链接只保留描述
+	if msgs[0].Content != "see [图片]" || msgs[0].IsSelf {
+		t.Errorf("unexpected first message: %+v", msgs[0])
+	}
+	if msgs[1].Content != "ok" || !msgs[1].IsSelf {
+		t.Errorf("expected second message sent by self: %+v", msgs[1])
+	}
+}
+
+func TestReadChatlogJSON(t *testing.T) {
+	dir := t.TempDir()
+	input := writeFile(t, filepath.Join(dir, "export.json"), `[
+{"time":"2024-01-02T10:00:00+08:00","talker":"123@chatroom","talkerName":"Team","isChatRoom":true,"sender":"wxid_bob","senderName":"Bob","isSelf":false,"type":1,"subType":0,"content":"morning"},
+{"time":"2024-01-02T10:02:00+08:00","talker":"123@chatroom","talkerName":"Team","isChatRoom":true,"sender":"wxid_me","senderName":"Me","isSelf":true,"type":1,"subType":0,"content":"hi all"}
+]`)
+	workDir := filepath.Join(dir, "work")
+	ret, err := Run(Options{Input: input, WorkDir: workDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.Format != FormatChatlog || ret.Messages != 2 || ret.Talkers != 1 {
+		t.Fatalf("unexpected result: %+v", ret)
+	}
+	msgs := messages(t, workDir, "123@chatroom")
+	if len(msgs) != 2 || msgs[0].Content != "morning" || msgs[0].Sender != "wxid_bob" || !msgs[1].IsSelf {
+		t.Fatalf("unexpected messages: %+v", msgs)
+	}
+}
+
+func TestReadMemoTraceGroup(t *testing.T) {
+	const group = `localId,TalkerId,Type,SubType,IsSender,CreateTime,Status,StrContent,StrTime,Remark,NickName,Sender
+1,5,1,0,0,1700000000,2,hello,2023-11-15 06:13:20,,bob,wxid_bob
+2,5,1,0,0,1700000005,2,hey,2023-11-15 06:13:25,,carol,wxid_carol
+`
+	dir := t.TempDir()
+	input := writeFile(t, filepath.Join(dir, "group.csv"), group)
+	if _, err := Run(Options{Input: input, WorkDir: filepath.Join(dir, "work1")}); err == nil {
+		t.Fatal("expected error for group chat export without talker")
+	}
+
+	// 以群 ID 命名的目录指定聊天对象
+	input = writeFile(t, filepath.Join(dir, "123@chatroom", "123@chatroom.csv"), group)
+	workDir := filepath.Join(dir, "work2")
+	ret, err := Run(Options{Input: input, WorkDir: workDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.Messages != 2 {
+		t.Fatalf("expected 2 messages, got %d", ret.Messages)
+	}
+	msgs := messages(t, workDir, "123@chatroom")
+	if len(msgs) != 2 || msgs[1].Sender != "wxid_carol" {
+		t.Fatalf("unexpected messages: %+v", msgs)
+	}
+}
+
+func TestReadIOS(t *testing.T) {
+	dir := t.TempDir()
+	dbDir := filepath.Join(dir, "Documents", "0123456789abcdef", "DB")
+	if err := os.MkdirAll(dbDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	remark := protowire.AppendTag(nil, 1, protowire.BytesType)
+	remark = protowire.AppendString(remark, "Bob")
+	remark = protowire.AppendTag(remark, 3, protowire.BytesType)
+	remark = protowire.AppendString(remark, "Bobby")
+	execSQL(t, filepath.Join(dbDir, "WCDB_Contact.sqlite"),
+		"CREATE TABLE Friend (userName TEXT, dbContactRemark BLOB)")
+	execSQL(t, filepath.Join(dbDir, "WCDB_Contact.sqlite"),
+		"INSERT INTO Friend VALUES ('wxid_bob', ?), ('123@chatroom', NULL)", remark)
+
+	chat := func(talker string) string {
+		sum := md5.Sum([]byte(talker))
+		return "Chat_" + hex.EncodeToString(sum[:])
+	}
+	mm := filepath.Join(dbDir, "MM.sqlite")
+	for _, talker := range []string{"wxid_bob", "123@chatroom"} {
+		execSQL(t, mm, "CREATE TABLE "+chat(talker)+" (MesLocalID INTEGER PRIMARY KEY, CreateTime INTEGER, Des INTEGER, Message TEXT, Type INTEGER)")
+	}
+	execSQL(t, mm, "INSERT INTO "+chat("wxid_bob")+" (CreateTime, Des, Message, Type) VALUES (1700000000, 1, 'hello', 1), (1700000010, 0, 'hi bob', 1)")
+	execSQL(t, mm, "INSERT INTO "+chat("123@chatroom")+" (CreateTime, Des, Message, Type) VALUES (1700000020, 1, 'wxid_carol:\nmorning', 1)")
+	// 联系人中没有的会话跳过
+	execSQL(t, mm, "CREATE TABLE "+chat("wxid_unknown")+" (MesLocalID INTEGER PRIMARY KEY, CreateTime INTEGER, Des INTEGER, Message TEXT, Type INTEGER)")
+
+	workDir := filepath.Join(dir, "work")
+	ret, err := Run(Options{Input: filepath.Join(dir, "Documents"), WorkDir: workDir, Self: "wxid_me"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.Format != FormatIOS || ret.Messages != 3 || ret.Contacts != 3 {
+		t.Fatalf("unexpected result: %+v", ret)
+	}
+	msgs := messages(t, workDir, "wxid_bob")
+	if len(msgs) != 2 || msgs[0].IsSelf || !msgs[1].IsSelf || msgs[1].Content != "hi bob" {
+		t.Fatalf("unexpected messages: %+v", msgs)
+	}
+	msgs = messages(t, workDir, "123@chatroom")
+	if len(msgs) != 1 || msgs[0].Sender != "wxid_carol" || msgs[0].Content != "morning" {
+		t.Fatalf("unexpected group messages: %+v", msgs)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.csv"), memoTraceCSV)
+	writeFile(t, filepath.Join(dir, "b.json"), "[]")
+	writeFile(t, filepath.Join(dir, "notes.csv"), "foo,bar\n1,2\n")
+
+	if _, _, err := detect(dir, ""); err == nil {
+		t.Fatal("expected error for mixed formats")
+	}
+	files, format, err := detect(dir, FormatChatlog)
+	if err != nil || format != FormatChatlog || len(files) != 3 {
+		t.Fatalf("format override: %v %s %v", files, format, err)
+	}
+	if _, _, err := detect(filepath.Join(dir, "notes.csv"), ""); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+	if _, _, err := detect(dir, "pcbackup"); err == nil {
+		t.Fatal("expected error for unsupported format")
+	}
+}
+
+func TestRunIntoWorkDirUsesArchive(t *testing.T) {
+	dir := t.TempDir()
+	workDir := filepath.Join(dir, "work")
+	first := writeFile(t, filepath.Join(dir, "first", "bob.csv"), memoTraceCSV)
+	if _, err := Run(Options{Input: first, WorkDir: workDir}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(merge.ArchiveDir(workDir)); !os.IsNotExist(err) {
+		t.Fatal("import into an empty work dir should not create an archive")
+	}
+
+	second := writeFile(t, filepath.Join(dir, "second", "bob.csv"), memoTraceCSV+
+		"3,5,1,0,0,1700000100,2,later,2023-11-15 06:15:00,Bob,bob,wxid_bob\n")
+	ret, err := Run(Options{Input: second, WorkDir: workDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.Messages != 1 {
+		t.Fatalf("expected 1 new message, got %d", ret.Messages)
+	}
+	if _, err := os.Stat(merge.ArchiveDir(workDir)); err != nil {
+		t.Fatalf("expected archive next to the work dir: %v", err)
+	}
+	if msgs := messages(t, workDir, "wxid_bob"); len(msgs) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(msgs))
+	}
+}
+
+func execSQL(t *testing.T, path, query string, args ...any) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(query, args...); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+}
@@ -0,0 +1,154 @@
+package importer
+
+import (
+	"crypto/md5"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/DanielMao1/chatlog/internal/model"
+)
+
+// readIOS 读取 iOS 备份中的 MM.sqlite，联系人来自同目录下的 WCDB_Contact.sqlite
+// 每个会话一张 Chat_md5(talker) 表，Des 为 0 表示自己发送，群聊中他人的消息以 "wxid:\n" 开头
+func readIOS(path string, opts Options, w *writer) error {
+	talkers, err := readIOSContacts(filepath.Join(filepath.Dir(path), "WCDB_Contact.sqlite"), w)
+	if err != nil {
+		return err
+	}
+	if len(opts.Talker) != 0 {
+		sum := md5.Sum([]byte(opts.Talker))
+		talkers[hex.EncodeToString(sum[:])] = opts.Talker
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type='table' AND name LIKE 'Chat\\_%' ESCAPE '\\'")
+	if err != nil {
+		return err
+	}
+	tables := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+
+	for _, table := range tables {
+		talker, ok := talkers[strings.TrimPrefix(table, "Chat_")]
+		if !ok {
+			log.Warn().Msgf("skip %s, talker not found in contacts", table)
+			continue
+		}
+		if err := readIOSChat(db, table, talker, w); err != nil {
+			return fmt.Errorf("read %s failed: %w", table, err)
+		}
+	}
+	return nil
+}
+
+func readIOSChat(db *sql.DB, table, talker string, w *writer) error {
+	rows, err := db.Query(fmt.Sprintf("SELECT CreateTime, Des, IFNULL(Message, ''), Type FROM %s ORDER BY CreateTime, MesLocalID", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	isChatRoom := strings.HasSuffix(talker, "@chatroom")
+	for rows.Next() {
+		var createTime, des, msgType int64
+		var content string
+		if err := rows.Scan(&createTime, &des, &content, &msgType); err != nil {
+			return err
+		}
+		m := &model.Message{
+			Time:    time.Unix(createTime, 0),
+			Talker:  talker,
+			Sender:  talker,
+			IsSelf:  des == 0,
+			Type:    msgType,
+			Content: content,
+		}
+		if isChatRoom {
+			m.Sender = ""
+			if split := strings.SplitN(content, ":\n", 2); !m.IsSelf && len(split) == 2 {
+				m.Sender, m.Content = split[0], split[1]
+			}
+		}
+		if err := w.AddMessage(m); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// readIOSContacts 读取联系人，返回用户名 md5 到用户名的映射，用于还原 Chat_ 表对应的会话
+func readIOSContacts(path string, w *writer) (map[string]string, error) {
+	talkers := make(map[string]string)
+	if _, err := os.Stat(path); err != nil {
+		log.Warn().Err(err).Msg("contacts not found, only chats given by --talker will be imported")
+		return talkers, nil
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT userName, dbContactRemark FROM Friend")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userName string
+		var remark []byte
+		if err := rows.Scan(&userName, &remark); err != nil {
+			return nil, err
+		}
+		sum := md5.Sum([]byte(userName))
+		talkers[hex.EncodeToString(sum[:])] = userName
+
+		fields := parseContactRemark(remark)
+		w.AddContact(userName, fields[1], fields[2], fields[3], !strings.HasSuffix(userName, "@chatroom"))
+	}
+	return talkers, rows.Err()
+}
+
+// parseContactRemark 解析 dbContactRemark，字段 1 为昵称，2 为微信号，3 为备注
+func parseContactRemark(b []byte) map[protowire.Number]string {
+	ret := make(map[protowire.Number]string)
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			break
+		}
+		b = b[n:]
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			break
+		}
+		if typ == protowire.BytesType {
+			v, _ := protowire.ConsumeBytes(b)
+			ret[num] = string(v)
+		}
+		b = b[n:]
+	}
+	return ret
+}
@@ -0,0 +1,90 @@
+package importer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/model"
+)
+
+// readMemoTrace 读取留痕（WeChatMsg）导出的 csv，每个文件对应一个聊天对象
+// 表头为 localId,TalkerId,Type,SubType,IsSender,CreateTime,Status,StrContent,StrTime,Remark,NickName,Sender
+// 文件中没有聊天对象的微信 ID：单聊取对方的 Sender，群聊需要通过 --talker 或以群 ID 命名的目录指定
+func readMemoTrace(path string, opts Options, w *writer) error {
+	var h header
+	records := make([][]string, 0)
+	if err := readCSV(path, func(_h header, record []string) error {
+		h = _h
+		records = append(records, record)
+		return nil
+	}); err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	talker, talkerName := opts.Talker, ""
+	if len(talker) == 0 {
+		if dir := filepath.Base(filepath.Dir(path)); strings.HasSuffix(dir, "@chatroom") {
+			talker = dir
+		}
+	}
+	if len(talker) == 0 {
+		senders := make(map[string]bool)
+		for _, record := range records {
+			if h.get(record, "issender") != "1" {
+				senders[h.get(record, "sender")] = true
+			}
+		}
+		if len(senders) > 1 {
+			return fmt.Errorf("group chat export, use --talker to set the chat room id")
+		}
+		for sender := range senders {
+			talker = sender
+		}
+		if len(talker) == 0 {
+			return fmt.Errorf("can not find the talker, use --talker to set it")
+		}
+	}
+
+	for _, record := range records {
+		createTime, err := strconv.ParseInt(h.get(record, "createtime"), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid CreateTime %q", h.get(record, "createtime"))
+		}
+		msgType, _ := strconv.ParseInt(h.get(record, "type"), 10, 64)
+		subType, _ := strconv.ParseInt(h.get(record, "subtype"), 10, 64)
+		isSelf := h.get(record, "issender") == "1"
+		sender := h.get(record, "sender")
+
+		senderName := h.get(record, "remark")
+		if len(senderName) == 0 {
+			senderName = h.get(record, "nickname")
+		}
+		if !isSelf && sender == talker && len(talkerName) == 0 {
+			talkerName = senderName
+		}
+
+		if !isSelf {
+			w.AddContact(sender, h.get(record, "nickname"), "", h.get(record, "remark"), sender == talker)
+		}
+		if err := w.AddMessage(&model.Message{
+			Time:       time.Unix(createTime, 0),
+			Talker:     talker,
+			TalkerName: talkerName,
+			Sender:     sender,
+			SenderName: senderName,
+			IsSelf:     isSelf,
+			Type:       msgType,
+			SubType:    subType,
+			Content:    h.get(record, "strcontent"),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,458 @@
+package importer
+
+import (
+	"crypto/md5"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/DanielMao1/chatlog/internal/model"
+	"github.com/DanielMao1/chatlog/internal/model/wxproto"
+	"github.com/DanielMao1/chatlog/pkg/util"
+)
+
+// 与微信 4.0 解密后的表结构一致，只保留 chatlog 读取的字段
+const (
+	schemaMessage = `
+CREATE TABLE IF NOT EXISTS Timestamp(timestamp INTEGER);
+CREATE TABLE IF NOT EXISTS Name2Id(user_name TEXT PRIMARY KEY, is_session INTEGER);`
+
+	schemaMsgTable = `
+CREATE TABLE IF NOT EXISTS %s(
+local_id INTEGER PRIMARY KEY AUTOINCREMENT,
+server_id INTEGER,
+local_type INTEGER,
+sort_seq INTEGER,
+real_sender_id INTEGER,
+create_time INTEGER,
+status INTEGER,
+upload_status INTEGER,
+download_status INTEGER,
+server_seq INTEGER,
+origin_source INTEGER,
+source TEXT,
+message_content TEXT,
+compress_content TEXT,
+packed_info_data BLOB,
+WCDB_CT_message_content INTEGER DEFAULT NULL,
+WCDB_CT_source INTEGER DEFAULT NULL
+)`
+
+	schemaContact = `
+CREATE TABLE IF NOT EXISTS contact(
+id INTEGER PRIMARY KEY,
+username TEXT,
+local_type INTEGER,
+alias TEXT,
+encrypt_username TEXT,
+flag INTEGER,
+delete_flag INTEGER,
+verify_flag INTEGER,
+remark TEXT,
+remark_quan_pin TEXT,
+remark_pin_yin_initial TEXT,
+nick_name TEXT,
+pin_yin_initial TEXT,
+quan_pin TEXT,
+big_head_url TEXT,
+small_head_url TEXT,
+head_img_md5 TEXT,
+chat_room_notify INTEGER,
+is_in_chat_room INTEGER,
+description TEXT,
+extra_buffer BLOB,
+chat_room_type INTEGER
+);
+CREATE TABLE IF NOT EXISTS chat_room(
+id INTEGER PRIMARY KEY,
+username TEXT,
+owner TEXT,
+ext_buffer BLOB
+);`
+
+	schemaSession = `
+CREATE TABLE IF NOT EXISTS SessionTable(
+username TEXT PRIMARY KEY,
+type INTEGER,
+unread_count INTEGER,
+unread_first_msg_srv_id INTEGER,
+is_hidden INTEGER,
+summary TEXT,
+draft TEXT,
+status INTEGER,
+last_timestamp INTEGER,
+sort_timestamp INTEGER,
+last_clear_unread_timestamp INTEGER,
+last_msg_locald_id INTEGER,
+last_msg_type INTEGER,
+last_msg_sub_type INTEGER,
+last_msg_sender TEXT,
+last_sender_display_name TEXT,
+last_msg_ext_type INTEGER
+)`
+)
+
+// 消息状态，与 4.0 相同，2 为自己发送，4 为接收
+const (
+	statusSent     = 2
+	statusReceived = 4
+)
+
+// contact 导入过程中收集的联系人
+type contact struct {
+	userName string
+	nickName string
+	alias    string
+	remark   string
+	isFriend bool
+}
+
+// session 每个聊天对象的最后一条消息
+type session struct {
+	msg     *model.Message
+	summary string
+}
+
+// writer 以微信 4.0 工作目录的结构写入消息、联系人和会话
+// 消息写入 db_storage/message/message_0.db，其他数据在关闭时写入
+type writer struct {
+	dir  string
+	self string
+
+	db      *sql.DB
+	tx      *sql.Tx
+	tables  map[string]bool
+	ids     map[string]int64
+	seqs    map[string]int64
+	minTime int64
+
+	contacts map[string]*contact
+	members  map[string]map[string]string
+	sessions map[string]*session
+
+	messages int
+}
+
+func newWriter(dir, self string) (*writer, error) {
+	msgDir := filepath.Join(dir, "db_storage", "message")
+	if err := util.PrepareDir(msgDir); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite3", filepath.Join(msgDir, "message_0.db"))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(schemaMessage); err != nil {
+		db.Close()
+		return nil, err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &writer{
+		dir:      dir,
+		self:     self,
+		db:       db,
+		tx:       tx,
+		tables:   make(map[string]bool),
+		ids:      make(map[string]int64),
+		seqs:     make(map[string]int64),
+		minTime:  math.MaxInt64,
+		contacts: make(map[string]*contact),
+		members:  make(map[string]map[string]string),
+		sessions: make(map[string]*session),
+	}, nil
+}
+
+// AddContact 记录联系人，已有的非空字段不会被覆盖
+func (w *writer) AddContact(userName, nickName, alias, remark string, isFriend bool) {
+	if len(userName) == 0 {
+		return
+	}
+	c, ok := w.contacts[userName]
+	if !ok {
+		c = &contact{userName: userName}
+		w.contacts[userName] = c
+	}
+	if len(c.nickName) == 0 {
+		c.nickName = nickName
+	}
+	if len(c.alias) == 0 {
+		c.alias = alias
+	}
+	if len(c.remark) == 0 {
+		c.remark = remark
+	}
+	c.isFriend = c.isFriend || isFriend
+}
+
+// AddMessage 写入一条消息，需要 Talker、Time，群聊消息还需要 Sender
+// Content 为文本或原始 XML，非文本消息没有 XML 时按文本保存
+func (w *writer) AddMessage(m *model.Message) error {
+	if len(m.Talker) == 0 {
+		return fmt.Errorf("message without talker at %s", m.Time)
+	}
+	isChatRoom := strings.HasSuffix(m.Talker, "@chatroom")
+
+	sender := m.Sender
+	switch {
+	case m.IsSelf && len(w.self) != 0:
+		sender = w.self
+	case m.IsSelf && len(sender) == 0:
+		sender = "self"
+	case len(sender) == 0 && !isChatRoom:
+		sender = m.Talker
+	}
+
+	msgType, content := m.Type, m.Content
+	if msgType == 0 {
+		msgType = model.MessageTypeText
+	}
+	switch {
+	case msgType == model.MessageTypeText || msgType == model.MessageTypeSystem:
+	case !strings.HasPrefix(strings.TrimSpace(content), "<"):
+		// 导出文件中的媒体消息只有描述文本，无法还原 XML
+		msgType = model.MessageTypeText
+	default:
+		// 4.0 的 local_type 高 32 位为子类型
+		msgType |= m.SubType << 32
+	}
+	text := summary(msgType, content)
+	if isChatRoom && !m.IsSelf && msgType != model.MessageTypeSystem && len(sender) != 0 {
+		content = sender + ":\n" + content
+	}
+
+	table, err := w.table(m.Talker)
+	if err != nil {
+		return err
+	}
+	senderID, err := w.id(sender)
+	if err != nil {
+		return err
+	}
+
+	createTime := m.Time.Unix()
+	seq := m.Seq
+	if seq == 0 {
+		// 同一秒内的消息按出现顺序编号
+		key := fmt.Sprintf("%s|%d", m.Talker, createTime)
+		seq = createTime*1000 + w.seqs[key]
+		w.seqs[key]++
+	}
+	status := statusReceived
+	if m.IsSelf {
+		status = statusSent
+	}
+
+	_, err = w.tx.Exec(fmt.Sprintf(`INSERT INTO %s (server_id, local_type, sort_seq, real_sender_id, create_time, status, message_content)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`, table),
+		serverID(m.Talker, sender, createTime, content), msgType, seq, senderID, createTime, status, content)
+	if err != nil {
+		return err
+	}
+	if createTime < w.minTime {
+		w.minTime = createTime
+	}
+	w.messages++
+
+	w.AddContact(m.Talker, m.TalkerName, "", "", !isChatRoom)
+	if isChatRoom && len(sender) != 0 && !m.IsSelf {
+		w.AddContact(sender, m.SenderName, "", "", false)
+		if len(m.SenderName) != 0 {
+			if w.members[m.Talker] == nil {
+				w.members[m.Talker] = make(map[string]string)
+			}
+			w.members[m.Talker][sender] = m.SenderName
+		}
+	}
+
+	if s := w.sessions[m.Talker]; s == nil || !m.Time.Before(s.msg.Time) {
+		w.sessions[m.Talker] = &session{msg: &model.Message{
+			Time:       m.Time,
+			Sender:     sender,
+			SenderName: m.SenderName,
+			Type:       msgType,
+		}, summary: text}
+	}
+	return nil
+}
+
+// table 返回聊天对象的消息表，不存在时创建
+func (w *writer) table(talker string) (string, error) {
+	sum := md5.Sum([]byte(talker))
+	table := "Msg_" + hex.EncodeToString(sum[:])
+	if w.tables[table] {
+		return table, nil
+	}
+	if _, err := w.tx.Exec(fmt.Sprintf(schemaMsgTable, table)); err != nil {
+		return "", err
+	}
+	if _, err := w.id(talker); err != nil {
+		return "", err
+	}
+	if _, err := w.tx.Exec("UPDATE Name2Id SET is_session = 1 WHERE user_name = ?", talker); err != nil {
+		return "", err
+	}
+	w.tables[table] = true
+	return table, nil
+}
+
+// id 返回用户名在 Name2Id 中的 rowid
+func (w *writer) id(userName string) (int64, error) {
+	if id, ok := w.ids[userName]; ok {
+		return id, nil
+	}
+	if _, err := w.tx.Exec("INSERT OR IGNORE INTO Name2Id (user_name, is_session) VALUES (?, 0)", userName); err != nil {
+		return 0, err
+	}
+	var id int64
+	if err := w.tx.QueryRow("SELECT rowid FROM Name2Id WHERE user_name = ?", userName).Scan(&id); err != nil {
+		return 0, err
+	}
+	w.ids[userName] = id
+	return id, nil
+}
+
+// Close 提交消息并写入联系人和会话
+func (w *writer) Close() error {
+	defer w.db.Close()
+	if w.messages > 0 {
+		if _, err := w.tx.Exec("DELETE FROM Timestamp"); err != nil {
+			w.tx.Rollback()
+			return err
+		}
+		if _, err := w.tx.Exec("INSERT INTO Timestamp (timestamp) VALUES (?)", w.minTime); err != nil {
+			w.tx.Rollback()
+			return err
+		}
+	}
+	if err := w.tx.Commit(); err != nil {
+		return err
+	}
+	if err := w.writeContacts(); err != nil {
+		return err
+	}
+	return w.writeSessions()
+}
+
+func (w *writer) writeContacts() error {
+	return w.writeDB(filepath.Join("contact", "contact.db"), schemaContact, func(tx *sql.Tx) error {
+		names := make([]string, 0, len(w.contacts))
+		for name := range w.contacts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			c := w.contacts[name]
+			localType := 1
+			switch {
+			case strings.HasSuffix(name, "@chatroom"):
+				localType = 2
+			case !c.isFriend:
+				localType = 3
+			}
+			// id 由用户名生成，合并到已有工作目录时同一联系人不会重复，不同联系人不会冲突
+			if _, err := tx.Exec("INSERT INTO contact (id, username, local_type, alias, remark, nick_name) VALUES (?, ?, ?, ?, ?, ?)",
+				rowID(name), name, localType, c.alias, c.remark, c.nickName); err != nil {
+				return err
+			}
+			if localType != 2 {
+				continue
+			}
+			data := &wxproto.RoomData{}
+			for _, user := range sortedKeys(w.members[name]) {
+				displayName := w.members[name][user]
+				data.Users = append(data.Users, &wxproto.RoomDataUser{UserName: user, DisplayName: &displayName})
+			}
+			b, err := proto.Marshal(data)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec("INSERT INTO chat_room (id, username, owner, ext_buffer) VALUES (?, ?, '', ?)", rowID(name), name, b); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (w *writer) writeSessions() error {
+	return w.writeDB(filepath.Join("session", "session.db"), schemaSession, func(tx *sql.Tx) error {
+		for talker, s := range w.sessions {
+			t := s.msg.Time.Unix()
+			if _, err := tx.Exec(`INSERT INTO SessionTable (username, type, summary, last_timestamp, sort_timestamp, last_msg_type, last_msg_sender, last_sender_display_name)
+				VALUES (?, 0, ?, ?, ?, ?, ?, ?)`, talker, s.summary, t, t, s.msg.Type, s.msg.Sender, s.msg.SenderName); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// writeDB 在 db_storage 下创建数据库并在一个事务中写入
+func (w *writer) writeDB(rel, schema string, fn func(tx *sql.Tx) error) error {
+	path := filepath.Join(w.dir, "db_storage", rel)
+	if err := util.PrepareDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if _, err := db.Exec(schema); err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// summary 会话列表中显示的最后一条消息
+func summary(msgType int64, content string) string {
+	m := &model.Message{Type: msgType}
+	m.ParseMediaInfo(content)
+	text := strings.ReplaceAll(m.PlainTextContent(), "\n", " ")
+	if r := []rune(text); len(r) > 50 {
+		text = string(r[:50])
+	}
+	return text
+}
+
+func rowID(userName string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(userName))
+	return int64(h.Sum64() & math.MaxInt64)
+}
+
+// serverID 由消息内容生成，重复导入同一份数据时保持不变，便于合并时去重
+func serverID(talker, sender string, createTime int64, content string) int64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s\x00%s\x00%d\x00%s", talker, sender, createTime, content)
+	return int64(h.Sum64() & math.MaxInt64)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,99 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+)
+
+// Embedder 向量模型接口，将文本转换为向量，用于语义搜索
+type Embedder interface {
+	// Name 返回接口名称与模型，模型变化时需要重建索引
+	Name() string
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// NewEmbedder 按配置创建向量模型接口，未配置或 provider 为 none 时返回 nil
+func NewEmbedder(c *conf.Embedding) (Embedder, error) {
+	if c == nil {
+		return nil, nil
+	}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+
+	provider := strings.ToLower(strings.TrimSpace(c.Provider))
+	switch provider {
+	case "", ProviderNone:
+		return nil, nil
+	case ProviderOpenAI, ProviderOllama:
+	default:
+		return nil, fmt.Errorf("invalid embedding.provider %q, use openai, ollama or none", c.Provider)
+	}
+	if len(c.Model) == 0 {
+		return nil, fmt.Errorf("embedding.model is required for provider %s", provider)
+	}
+	if provider == ProviderOllama {
+		url := c.URL
+		if len(url) == 0 {
+			url = "http://localhost:11434"
+		}
+		return &ollama{url: strings.TrimSuffix(url, "/"), model: c.Model, client: client}, nil
+	}
+	url := c.URL
+	if len(url) == 0 {
+		url = "https://api.openai.com/v1"
+	}
+	return &openAI{url: strings.TrimSuffix(url, "/"), model: c.Model, apiKey: c.APIKey, client: client}, nil
+}
+
+// Embed 调用 /embeddings 接口
+func (p *openAI) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	headers := map[string]string{}
+	if len(p.apiKey) != 0 {
+		headers["Authorization"] = "Bearer " + p.apiKey
+	}
+	var resp struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := postJSON(ctx, p.client, p.url+"/embeddings", headers, map[string]any{"model": p.model, "input": texts}, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("%s returned %d embeddings for %d texts", p.Name(), len(resp.Data), len(texts))
+	}
+	ret := make([][]float32, len(texts))
+	for _, d := range resp.Data {
+		if d.Index < 0 || d.Index >= len(ret) {
+			return nil, fmt.Errorf("%s returned invalid index %d", p.Name(), d.Index)
+		}
+		ret[d.Index] = d.Embedding
+	}
+	return ret, nil
+}
+
+// Embed 调用 /api/embed 接口
+func (p *ollama) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	var resp struct {
+		Embeddings [][]float32 `json:"embeddings"`
+		Error      string      `json:"error"`
+	}
+	if err := postJSON(ctx, p.client, p.url+"/api/embed", nil, map[string]any{"model": p.model, "input": texts}, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Error) != 0 {
+		return nil, fmt.Errorf("%s: %s", p.Name(), resp.Error)
+	}
+	if len(resp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("%s returned %d embeddings for %d texts", p.Name(), len(resp.Embeddings), len(texts))
+	}
+	return resp.Embeddings, nil
+}
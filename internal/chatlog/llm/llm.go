@@ -0,0 +1,114 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+)
+
+const (
+	ProviderNone   = "none"   // 不使用大模型
+	ProviderOpenAI = "openai" // OpenAI 兼容的 /chat/completions 接口
+	ProviderOllama = "ollama" // 本地 Ollama 的 /api/chat 接口
+
+	// DefaultTimeout 未配置 timeout 时的请求超时，大模型生成较慢
+	DefaultTimeout = 2 * time.Minute
+
+	// DefaultMaxChars 未配置 max_chars 时发送给大模型的最大字数
+	DefaultMaxChars = 24000
+)
+
+// Provider 大模型接口，根据系统提示词与用户输入生成回复
+type Provider interface {
+	// Name 返回接口名称与模型，用于日志与推送内容
+	Name() string
+	Complete(ctx context.Context, system, prompt string) (string, error)
+}
+
+// New 按配置创建大模型接口，未配置或 provider 为 none 时返回 nil
+func New(c *conf.LLM) (Provider, error) {
+	if c == nil {
+		return nil, nil
+	}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+
+	switch strings.ToLower(strings.TrimSpace(c.Provider)) {
+	case "", ProviderNone:
+		return nil, nil
+	case ProviderOpenAI:
+		if len(c.Model) == 0 {
+			return nil, fmt.Errorf("llm.model is required for provider openai")
+		}
+		url := c.URL
+		if len(url) == 0 {
+			url = "https://api.openai.com/v1"
+		}
+		return &openAI{url: strings.TrimSuffix(url, "/"), model: c.Model, apiKey: c.APIKey, client: client}, nil
+	case ProviderOllama:
+		if len(c.Model) == 0 {
+			return nil, fmt.Errorf("llm.model is required for provider ollama")
+		}
+		url := c.URL
+		if len(url) == 0 {
+			url = "http://localhost:11434"
+		}
+		return &ollama{url: strings.TrimSuffix(url, "/"), model: c.Model, client: client}, nil
+	}
+	return nil, fmt.Errorf("invalid llm.provider %q, use openai, ollama or none", c.Provider)
+}
+
+// MaxChars 返回配置的最大字数，未配置时为 DefaultMaxChars
+func MaxChars(c *conf.LLM) int {
+	if c == nil || c.MaxChars <= 0 {
+		return DefaultMaxChars
+	}
+	return c.MaxChars
+}
+
+// postJSON 发送 JSON 请求并解析 JSON 响应，非 2xx 响应视为失败并附带响应内容
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body, out any) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg := strings.TrimSpace(string(data))
+		if r := []rune(msg); len(r) > 200 {
+			msg = string(r[:200]) + "..."
+		}
+		return fmt.Errorf("post to %s failed, status code: %d, %s", url, resp.StatusCode, msg)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("invalid response from %s: %v", url, err)
+	}
+	return nil
+}
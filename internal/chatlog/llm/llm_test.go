@@ -0,0 +1,130 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+)
+
+func TestNew(t *testing.T) {
+	for _, c := range []*conf.LLM{nil, {}, {Provider: "none"}} {
+		if p, err := New(c); p != nil || err != nil {
+			t.Errorf("New(%+v) = %v, %v, want nil provider", c, p, err)
+		}
+	}
+	if _, err := New(&conf.LLM{Provider: "openai"}); err == nil {
+		t.Error("openai without model should fail")
+	}
+	if _, err := New(&conf.LLM{Provider: "unknown", Model: "m"}); err == nil {
+		t.Error("unknown provider should fail")
+	}
+}
+
+func TestOpenAI(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" || r.Header.Get("Authorization") != "Bearer sk-test" {
+			t.Errorf("unexpected request %s %s", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		var req struct {
+			Model    string        `json:"model"`
+			Messages []chatMessage `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "gpt-test" || len(req.Messages) != 2 || req.Messages[1].Content != "hello" {
+			t.Errorf("unexpected request body %+v", req)
+		}
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "world"}}]}`))
+	}))
+	defer srv.Close()
+
+	p, err := New(&conf.LLM{Provider: "openai", URL: srv.URL + "/v1/", Model: "gpt-test", APIKey: "sk-test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := p.Complete(context.Background(), "system", "hello")
+	if err != nil || got != "world" {
+		t.Errorf("Complete() = %q, %v, want world", got, err)
+	}
+}
+
+func TestOllama(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var req map[string]any
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["stream"] != false || req["model"] != "qwen2.5" {
+			t.Errorf("unexpected request body %v", req)
+		}
+		w.Write([]byte(`{"message": {"role": "assistant", "content": "ok"}}`))
+	}))
+	defer srv.Close()
+
+	p, err := New(&conf.LLM{Provider: "Ollama", URL: srv.URL, Model: "qwen2.5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name() != "ollama/qwen2.5" {
+		t.Errorf("Name() = %q", p.Name())
+	}
+	got, err := p.Complete(context.Background(), "system", "hello")
+	if err != nil || got != "ok" {
+		t.Errorf("Complete() = %q, %v, want ok", got, err)
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error": "invalid api key"}`, http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	p, _ := New(&conf.LLM{Provider: "openai", URL: srv.URL, Model: "m"})
+	if _, err := p.Complete(context.Background(), "", "hello"); err == nil {
+		t.Error("non 2xx response should fail")
+	}
+}
+
+func TestEmbed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/embeddings":
+			// 返回顺序与输入不同时按 index 排列
+			w.Write([]byte(`{"data": [{"index": 1, "embedding": [0, 1]}, {"index": 0, "embedding": [1, 0]}]}`))
+		case "/api/embed":
+			w.Write([]byte(`{"embeddings": [[1, 0], [0, 1]]}`))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	for _, c := range []*conf.Embedding{
+		{Provider: "openai", URL: srv.URL + "/v1", Model: "text-embedding-3-small"},
+		{Provider: "ollama", URL: srv.URL, Model: "bge-m3"},
+	} {
+		e, err := NewEmbedder(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := e.Embed(context.Background(), []string{"a", "b"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 2 || got[0][0] != 1 || got[1][1] != 1 {
+			t.Errorf("%s: Embed() = %v", e.Name(), got)
+		}
+	}
+
+	if e, err := NewEmbedder(&conf.Embedding{Provider: "none"}); e != nil || err != nil {
+		t.Errorf("provider none should disable embedding, got %v, %v", e, err)
+	}
+	if _, err := NewEmbedder(&conf.Embedding{Provider: "ollama"}); err == nil {
+		t.Error("missing model should fail")
+	}
+}
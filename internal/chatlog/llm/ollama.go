@@ -0,0 +1,40 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// ollama 本地运行的 Ollama，聊天记录不离开本机
+type ollama struct {
+	url    string
+	model  string
+	client *http.Client
+}
+
+func (p *ollama) Name() string {
+	return ProviderOllama + "/" + p.model
+}
+
+func (p *ollama) Complete(ctx context.Context, system, prompt string) (string, error) {
+	req := map[string]any{
+		"model": p.model,
+		"messages": []chatMessage{
+			{Role: "system", Content: system},
+			{Role: "user", Content: prompt},
+		},
+		"stream": false,
+	}
+	var resp struct {
+		Message chatMessage `json:"message"`
+		Error   string      `json:"error"`
+	}
+	if err := postJSON(ctx, p.client, p.url+"/api/chat", nil, req, &resp); err != nil {
+		return "", err
+	}
+	if len(resp.Error) != 0 {
+		return "", fmt.Errorf("%s: %s", p.Name(), resp.Error)
+	}
+	return resp.Message.Content, nil
+}
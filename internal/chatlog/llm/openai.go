@@ -0,0 +1,50 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// openAI OpenAI 兼容的接口，也适用于 DeepSeek、通义千问、vLLM 等提供相同接口的服务
+type openAI struct {
+	url    string
+	model  string
+	apiKey string
+	client *http.Client
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+func (p *openAI) Name() string {
+	return ProviderOpenAI + "/" + p.model
+}
+
+func (p *openAI) Complete(ctx context.Context, system, prompt string) (string, error) {
+	req := map[string]any{
+		"model": p.model,
+		"messages": []chatMessage{
+			{Role: "system", Content: system},
+			{Role: "user", Content: prompt},
+		},
+	}
+	headers := map[string]string{}
+	if len(p.apiKey) != 0 {
+		headers["Authorization"] = "Bearer " + p.apiKey
+	}
+	var resp struct {
+		Choices []struct {
+			Message chatMessage `json:"message"`
+		} `json:"choices"`
+	}
+	if err := postJSON(ctx, p.client, p.url+"/chat/completions", headers, req, &resp); err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("empty response from %s", p.Name())
+	}
+	return resp.Choices[0].Message.Content, nil
+}
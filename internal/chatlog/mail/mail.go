@@ -0,0 +1,149 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+)
+
+const (
+	// DefaultPort 未配置端口时使用的提交端口
+	DefaultPort = 587
+
+	// TLSPort 使用 TLS 直连的端口
+	TLSPort = 465
+
+	// Timeout 连接与发送的超时
+	Timeout = 30 * time.Second
+)
+
+// Message 一封邮件，同时包含纯文本与 HTML 内容
+type Message struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Send 通过 SMTP 服务器发送邮件，msg.To 为空时发送给 smtp.to
+func Send(c *conf.SMTP, msg *Message) error {
+	if c == nil || len(c.Host) == 0 {
+		return fmt.Errorf("smtp.host is not configured")
+	}
+	from := c.From
+	if len(from) == 0 {
+		from = c.Username
+	}
+	if len(from) == 0 {
+		return fmt.Errorf("smtp.from is not configured")
+	}
+	to := msg.To
+	if len(to) == 0 {
+		to = c.To
+	}
+	if len(to) == 0 {
+		return fmt.Errorf("no recipient, set smtp.to")
+	}
+	port := c.Port
+	if port == 0 {
+		port = DefaultPort
+	}
+	addr := net.JoinHostPort(c.Host, strconv.Itoa(port))
+
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: Timeout}
+	if port == TLSPort {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: c.Host})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(Timeout))
+
+	client, err := smtp.NewClient(conn, c.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && port != TLSPort {
+		if err := client.StartTLS(&tls.Config{ServerName: c.Host}); err != nil {
+			return err
+		}
+	}
+	if len(c.Username) != 0 {
+		if err := client.Auth(smtp.PlainAuth("", c.Username, c.Password, c.Host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("recipient %s: %w", rcpt, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(Build(from, to, msg, time.Now())); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// Build 生成 MIME 格式的邮件，正文为 multipart/alternative，纯文本与 HTML 均使用 base64 编码
+func Build(from string, to []string, msg *Message, now time.Time) []byte {
+	var buf bytes.Buffer
+	body := multipart.NewWriter(&buf)
+
+	header := func(k, v string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", k, v)
+	}
+	header("From", from)
+	header("To", strings.Join(to, ", "))
+	header("Subject", mime.BEncoding.Encode("utf-8", msg.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "multipart/alternative; boundary="+body.Boundary())
+	buf.WriteString("\r\n")
+
+	part := func(contentType, content string) {
+		w, _ := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {contentType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		encoded := base64.StdEncoding.EncodeToString([]byte(content))
+		for len(encoded) > 76 {
+			w.Write([]byte(encoded[:76] + "\r\n"))
+			encoded = encoded[76:]
+		}
+		w.Write([]byte(encoded + "\r\n"))
+	}
+	part("text/plain", msg.Text)
+	if len(msg.HTML) != 0 {
+		part("text/html", msg.HTML)
+	}
+	body.Close()
+	return buf.Bytes()
+}
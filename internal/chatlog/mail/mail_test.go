@@ -0,0 +1,133 @@
+package mail
+
+import (
+	"bufio"
+	"encoding/base64"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+)
+
+// fakeServer 只实现发送邮件所需命令的 SMTP 服务器，返回收到的收件人与邮件内容
+func fakeServer(t *testing.T) (int, chan []string, chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	rcpts, data := make(chan []string, 1), make(chan string, 1)
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 localhost ESMTP")
+		var to []string
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "MAIL FROM"):
+				reply("250 OK")
+			case strings.HasPrefix(cmd, "RCPT TO"):
+				to = append(to, strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>"))
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				var b strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					b.WriteString(l)
+				}
+				rcpts <- to
+				data <- b.String()
+				reply("250 OK")
+			case cmd == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, rcpts, data
+}
+
+func TestSend(t *testing.T) {
+	port, rcpts, data := fakeServer(t)
+	c := &conf.SMTP{Host: "127.0.0.1", Port: port, From: "chatlog@example.com", To: []string{"me@example.com", "you@example.com"}}
+	if err := Send(c, &Message{Subject: "聊天摘要", Text: "正文", HTML: "<p>正文</p>"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-rcpts; strings.Join(got, ",") != "me@example.com,you@example.com" {
+		t.Errorf("recipients = %v", got)
+	}
+	m, err := mail.ReadMessage(strings.NewReader(<-data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject, _ := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject")); subject != "聊天摘要" {
+		t.Errorf("Subject = %q", subject)
+	}
+	_, params, _ := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	mr := multipart.NewReader(m.Body, params["boundary"])
+	var types []string
+	for {
+		p, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		types = append(types, p.Header.Get("Content-Type"))
+		if strings.HasPrefix(p.Header.Get("Content-Type"), "text/plain") {
+			b := make([]byte, 64)
+			n, _ := p.Read(b)
+			decoded, _ := base64.StdEncoding.DecodeString(strings.TrimSpace(string(b[:n])))
+			if string(decoded) != "正文" {
+				t.Errorf("text body = %q", decoded)
+			}
+		}
+	}
+	if len(types) != 2 {
+		t.Errorf("parts = %v, want text and html", types)
+	}
+}
+
+func TestSendValidate(t *testing.T) {
+	if err := Send(&conf.SMTP{}, &Message{}); err == nil {
+		t.Error("missing host should fail")
+	}
+	if err := Send(&conf.SMTP{Host: "localhost", From: "a@example.com"}, &Message{}); err == nil {
+		t.Error("missing recipient should fail")
+	}
+}
+
+func TestBuildWrapsLines(t *testing.T) {
+	msg := Build("a@example.com", []string{"b@example.com"}, &Message{Subject: "s", Text: strings.Repeat("长", 200)}, time.Unix(0, 0))
+	for _, line := range strings.Split(string(msg), "\r\n") {
+		if len(line) > 998 {
+			t.Fatalf("line longer than 998 bytes: %d", len(line))
+		}
+		if len(line) > 76 && !strings.Contains(line, ":") {
+			t.Errorf("body line not wrapped: %d bytes", len(line))
+		}
+	}
+}
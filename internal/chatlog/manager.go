@@ -1,24 +1,46 @@
 package chatlog
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"encoding/hex"
 	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/rs/zerolog/log"
+	"github.com/DanielMao1/chatlog/internal/chatlog/backup"
 	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
 	"github.com/DanielMao1/chatlog/internal/chatlog/ctx"
 	"github.com/DanielMao1/chatlog/internal/chatlog/database"
+	"github.com/DanielMao1/chatlog/internal/chatlog/diff"
+	"github.com/DanielMao1/chatlog/internal/chatlog/doctor"
 	chathttp "github.com/DanielMao1/chatlog/internal/chatlog/http"
+	"github.com/DanielMao1/chatlog/internal/chatlog/importer"
+	"github.com/DanielMao1/chatlog/internal/chatlog/llm"
+	"github.com/DanielMao1/chatlog/internal/chatlog/merge"
+	"github.com/DanielMao1/chatlog/internal/chatlog/prune"
+	"github.com/DanielMao1/chatlog/internal/chatlog/push"
+	"github.com/DanielMao1/chatlog/internal/chatlog/schedule"
+	"github.com/DanielMao1/chatlog/internal/chatlog/sessions"
+	"github.com/DanielMao1/chatlog/internal/chatlog/summarize"
+	"github.com/DanielMao1/chatlog/internal/chatlog/telegram"
 	"github.com/DanielMao1/chatlog/internal/chatlog/wechat"
+	"github.com/DanielMao1/chatlog/internal/errors"
+	"github.com/DanielMao1/chatlog/internal/i18n"
 	"github.com/DanielMao1/chatlog/internal/model"
 	iwechat "github.com/DanielMao1/chatlog/internal/wechat"
+	"github.com/DanielMao1/chatlog/internal/wechat/decrypt"
+	"github.com/DanielMao1/chatlog/internal/wechatdb"
 	"github.com/DanielMao1/chatlog/pkg/config"
+	"github.com/DanielMao1/chatlog/pkg/filecopy"
 	"github.com/DanielMao1/chatlog/pkg/util"
 	"github.com/DanielMao1/chatlog/pkg/util/dat2img"
 )
@@ -36,10 +58,29 @@ type Manager struct {
 
 	// Terminal UI
 	app *App
+
+	// scheduler 执行配置中的定时任务，没有任务时为 nil
+	scheduler *schedule.Scheduler
+
+	// imageKeyOnce 预览图片前设置一次 4.0 版本图片的解密密钥
+	imageKeyOnce sync.Once
+
+	// notifier 关注的聊天对象的新消息通知
+	notifier notifier
+
+	// bot 配置了 telegram 时的 Telegram 机器人
+	bot *telegram.Bot
+
+	// runCtx 命令的上下文，Ctrl-C 时取消，进行中的解密、查询、导出与媒体转换随之停止
+	runCtx context.Context
 }
 
-func New() *Manager {
-	return &Manager{}
+// New 创建管理器，c 取消时停止进行中的工作，为 nil 时不会取消
+func New(c context.Context) *Manager {
+	if c == nil {
+		c = context.Background()
+	}
+	return &Manager{runCtx: c}
 }
 
 func (m *Manager) Run(configPath string) error {
@@ -55,10 +96,19 @@ func (m *Manager) Run(configPath string) error {
 	m.db = database.NewService(m.ctx)
 
 	m.http = chathttp.NewService(m.ctx, m.db)
+	m.http.SetDecryptLag(m.wechat.DecryptLag)
 
+	// 优先选择上次使用的账号对应的微信实例
 	m.ctx.WeChatInstances = m.wechat.GetWeChatInstances()
 	if len(m.ctx.WeChatInstances) >= 1 {
-		m.ctx.SwitchCurrent(m.ctx.WeChatInstances[0])
+		current := m.ctx.WeChatInstances[0]
+		for _, instance := range m.ctx.WeChatInstances {
+			if instance.Name == m.ctx.Account {
+				current = instance
+				break
+			}
+		}
+		m.ctx.SwitchCurrent(current)
 	}
 
 	if m.ctx.HTTPEnabled {
@@ -67,14 +117,88 @@ func (m *Manager) Run(configPath string) error {
 			m.StopService()
 		}
 	}
-	// 启动终端UI
+
+	// 定时导出、备份与总结推送
+	m.startScheduler()
+
+	// Telegram 机器人
+	m.startTelegram()
+
+	// 标准输出不是终端时（cron、CI、docker logs 等）无法显示终端UI，改为无界面运行
+	if !util.IsTerminal(os.Stdout) {
+		log.Warn().Msg("stdout is not a terminal, running without the terminal UI")
+		return m.runHeadless()
+	}
+
+	// 启动终端UI，阻塞到退出；界面崩溃时记录错误并退回无界面运行，HTTP 服务与定时任务不中断
 	m.app = NewApp(m.ctx, m)
-	m.app.Run() // 阻塞
+	if err := m.app.Run(); err != nil {
+		log.Err(err).Msg("terminal UI crashed, falling back to running without the terminal UI")
+		fmt.Fprintf(os.Stderr, "chatlog: terminal UI crashed: %v\nthe error has been logged; the HTTP server and scheduled jobs keep running, use the command line (see chatlog --help) or restart chatlog to get the UI back\n", err)
+		return m.runHeadless()
+	}
 	return nil
 }
 
+// HeadlessStatusInterval 无界面运行时输出状态日志的间隔
+const HeadlessStatusInterval = time.Minute
+
+// runHeadless 不启动终端UI，按配置提供 HTTP 服务、执行定时任务并定期输出状态日志，收到 SIGINT/SIGTERM 后退出
+// 开启自动解密时，关注的聊天对象的新消息通过 Telegram 机器人或推送目标通知
+func (m *Manager) runHeadless() error {
+	if !m.ctx.HTTPEnabled && !m.HasScheduledJobs() && m.bot == nil {
+		log.Warn().Msg("neither http server nor scheduled jobs are enabled for this account, nothing to run; use `chatlog --no-tui --serve` to run without a terminal")
+		return nil
+	}
+	if m.ctx.HTTPEnabled {
+		defer m.StopService()
+	}
+
+	// 收到 SIGINT/SIGTERM 时命令的上下文被取消
+	ctx := m.runCtx
+
+	tick := time.NewTicker(HeadlessStatusInterval)
+	defer tick.Stop()
+
+	for {
+		m.logStatus()
+		if m.ctx.AutoDecrypt {
+			for _, n := range m.CheckNotify() {
+				m.deliverNotify(n)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("shutting down")
+			return nil
+		case <-tick.C:
+		}
+	}
+}
+
+// logStatus 输出当前账号和服务的状态
+func (m *Manager) logStatus() {
+	if err := m.RefreshSession(); err != nil {
+		log.Debug().Err(err).Msg("refresh session failed")
+	}
+	event := log.Info().
+		Str("account", m.ctx.Account).
+		Str("http_addr", m.ctx.HTTPAddr).
+		Int("requests_per_minute", m.HTTPRequestsPerMinute())
+	if m.ctx.LastSession.Unix() > 1000000000 {
+		event = event.Time("last_session", m.ctx.LastSession)
+	}
+	if lag, ok := m.wechat.DecryptLag(); ok {
+		event = event.Float64("decrypt_lag", lag.Seconds())
+	}
+	event.Msg("status")
+}
+
+// Switch 切换当前账号，停止当前账号的服务后按新账号的配置启动服务
+// 新账号的 HTTP 地址已被其他进程占用时（如另一个 chatlog 实例正在为该账号提供服务），不启动也不修改其配置
 func (m *Manager) Switch(info *iwechat.Account, history string) error {
-	if m.ctx.AutoDecrypt {
+	autoDecrypt := m.ctx.AutoDecrypt
+	if autoDecrypt {
 		if err := m.StopAutoDecrypt(); err != nil {
 			return err
 		}
@@ -83,6 +207,9 @@ func (m *Manager) Switch(info *iwechat.Account, history string) error {
 		if err := m.stopService(); err != nil {
 			return err
 		}
+	} else if m.db.GetDB() != nil {
+		// 浏览聊天记录时打开的数据库属于当前账号
+		m.db.Stop()
 	}
 	if info != nil {
 		m.ctx.SwitchCurrent(info)
@@ -91,12 +218,22 @@ func (m *Manager) Switch(info *iwechat.Account, history string) error {
 	}
 
 	if m.ctx.HTTPEnabled {
-		// 启动HTTP服务
-		if err := m.StartService(); err != nil {
+		if addrInUse(m.ctx.GetHTTPAddr()) {
+			log.Info().Str("addr", m.ctx.GetHTTPAddr()).Msg("HTTP 地址已被占用，不启动服务")
+			m.ctx.HTTPEnabled = false
+		} else if err := m.StartService(); err != nil {
+			// 启动HTTP服务
 			log.Info().Err(err).Msg("启动服务失败")
 			m.StopService()
 		}
 	}
+
+	// 新账号已解密过时继续自动解密
+	if autoDecrypt && len(m.ctx.DataKey) != 0 && len(m.ctx.WorkDir) != 0 {
+		if err := m.StartAutoDecrypt(); err != nil {
+			log.Info().Err(err).Msg("恢复自动解密失败")
+		}
+	}
 	return nil
 }
 
@@ -155,24 +292,9 @@ func (m *Manager) stopService() error {
 	return nil
 }
 
-func (m *Manager) SetHTTPAddr(text string) error {
-	var addr string
-	if util.IsNumeric(text) {
-		addr = fmt.Sprintf("127.0.0.1:%s", text)
-	} else if strings.HasPrefix(text, "http://") {
-		addr = strings.TrimPrefix(text, "http://")
-	} else if strings.HasPrefix(text, "https://") {
-		addr = strings.TrimPrefix(text, "https://")
-	} else {
-		addr = text
-	}
-	m.ctx.SetHTTPAddr(addr)
-	return nil
-}
-
 func (m *Manager) GetDataKey() error {
 	if m.ctx.Current == nil {
-		return fmt.Errorf("未选择任何账号")
+		return i18n.Errorf("未选择任何账号")
 	}
 	if _, err := m.wechat.GetDataKey(m.ctx.Current); err != nil {
 		return err
@@ -182,10 +304,11 @@ func (m *Manager) GetDataKey() error {
 	return nil
 }
 
-func (m *Manager) DecryptDBFiles() error {
+// DecryptDBFiles 解密当前账号的数据库，force 为 false 时跳过未变化的数据库
+func (m *Manager) DecryptDBFiles(force bool) error {
 	if m.ctx.DataKey == "" {
 		if m.ctx.Current == nil {
-			return fmt.Errorf("未选择任何账号")
+			return i18n.Errorf("未选择任何账号")
 		}
 		if err := m.GetDataKey(); err != nil {
 			return err
@@ -195,7 +318,7 @@ func (m *Manager) DecryptDBFiles() error {
 		m.ctx.WorkDir = util.DefaultWorkDir(m.ctx.Account)
 	}
 
-	if err := m.wechat.DecryptDBFiles(); err != nil {
+	if err := m.wechat.DecryptDBFiles(m.runCtx, force); err != nil {
 		return err
 	}
 	m.ctx.Refresh()
@@ -205,10 +328,10 @@ func (m *Manager) DecryptDBFiles() error {
 
 func (m *Manager) StartAutoDecrypt() error {
 	if m.ctx.DataKey == "" || m.ctx.DataDir == "" {
-		return fmt.Errorf("请先获取密钥")
+		return i18n.Errorf("请先获取密钥")
 	}
 	if m.ctx.WorkDir == "" {
-		return fmt.Errorf("请先执行解密数据")
+		return i18n.Errorf("请先执行解密数据")
 	}
 
 	if err := m.wechat.StartAutoDecrypt(); err != nil {
@@ -228,13 +351,26 @@ func (m *Manager) StopAutoDecrypt() error {
 	return nil
 }
 
+// LastAutoDecrypt 返回最近一次自动解密成功的时间
+func (m *Manager) LastAutoDecrypt() time.Time {
+	return m.wechat.LastAutoDecrypt()
+}
+
+// HTTPRequestsPerMinute 返回 HTTP 服务最近一分钟内处理的请求数
+func (m *Manager) HTTPRequestsPerMinute() int {
+	if !m.ctx.HTTPEnabled {
+		return 0
+	}
+	return m.http.RequestsPerMinute()
+}
+
 func (m *Manager) RefreshSession() error {
 	if m.db.GetDB() == nil {
 		if err := m.db.Start(); err != nil {
 			return err
 		}
 	}
-	resp, err := m.db.GetSessions("", 1, 0)
+	resp, err := m.db.GetSessions(m.runCtx, "", 1, 0)
 	if err != nil {
 		return err
 	}
@@ -245,147 +381,472 @@ func (m *Manager) RefreshSession() error {
 	return nil
 }
 
-func (m *Manager) SummarizeFileHelper() (string, error) {
+// Summarize 总结聊天对象在 since 时长内的消息，并按 to 推送到配置的地址或 URL，to 为 stdout 时只返回结果
+func (m *Manager) Summarize(talker string, since time.Duration, to string) (*summarize.Payload, error) {
 	// Ensure database is started
 	if m.db.GetDB() == nil {
 		if err := m.db.Start(); err != nil {
-			return "", fmt.Errorf("数据库未启动: %v", err)
+			return nil, i18n.Errorf("数据库未启动: %v", err)
 		}
 	}
+	return m.summarize(m.ctx.GetSummarize(), m.ctx.GetLLM(), m.ctx.GetDestinations(), talker, since, to)
+}
 
-	// Query filehelper messages from the past 24 hours
-	now := time.Now()
-	start := now.Add(-24 * time.Hour)
-	messages, err := m.db.GetMessages(start, now, "filehelper", "", "", 0, 0)
+// BrowseSessions 分页返回 TUI 消息浏览的会话列表
+func (m *Manager) BrowseSessions(keyword string, limit, offset int) ([]*model.Session, error) {
+	if m.db.GetDB() == nil {
+		if err := m.db.Start(); err != nil {
+			return nil, i18n.Errorf("数据库未启动: %v", err)
+		}
+	}
+	resp, err := m.db.GetSessions(m.runCtx, keyword, limit, offset)
 	if err != nil {
-		return "", fmt.Errorf("查询消息失败: %v", err)
+		return nil, err
 	}
+	return resp.Items, nil
+}
 
-	if len(messages) == 0 {
-		return "", fmt.Errorf("过去24小时内没有文件传输助手的消息")
+// BrowseMessages 返回 TUI 消息浏览中会话在时间范围内的消息
+func (m *Manager) BrowseMessages(talker string, start, end time.Time) ([]*model.Message, error) {
+	if m.db.GetDB() == nil {
+		if err := m.db.Start(); err != nil {
+			return nil, i18n.Errorf("数据库未启动: %v", err)
+		}
 	}
+	return m.db.GetMessages(m.runCtx, start, end, talker, "", "", 0, 0)
+}
 
-	// Build summary text and highlights
-	var summaryBuf strings.Builder
-	var highlights []string
-	for _, msg := range messages {
-		line := fmt.Sprintf("[%s] %s", msg.Time.Format("15:04"), msg.PlainTextContent())
-		summaryBuf.WriteString(line)
-		summaryBuf.WriteString("\n")
+// Pinned 返回置顶的聊天对象
+func (m *Manager) Pinned() []string {
+	return m.ctx.GetPinned()
+}
 
-		// Extract highlights from share messages (links, files, etc.)
-		if msg.Type == model.MessageTypeShare && msg.Contents != nil {
-			if title, ok := msg.Contents["title"].(string); ok && title != "" {
-				highlights = append(highlights, title)
+// Pin 置顶或取消置顶聊天对象，新置顶的对象排在最后
+func (m *Manager) Pin(talker string, pin bool) error {
+	pinned := make([]string, 0, len(m.ctx.GetPinned())+1)
+	for _, t := range m.ctx.GetPinned() {
+		if t != talker {
+			pinned = append(pinned, t)
+		}
+	}
+	if pin {
+		pinned = append(pinned, talker)
+	}
+	return m.ctx.SetPinned(pinned)
+}
+
+// MentionNames 返回自己在会话中可能被 @ 的名称，包括昵称与群昵称，用于 TUI 中筛选提到我的消息
+func (m *Manager) MentionNames(talker, self string) []string {
+	if m.db.GetDB() == nil {
+		return nil
+	}
+	names := make([]string, 0, 2)
+	if resp, err := m.db.GetContacts(m.runCtx, self, 0, 0); err == nil {
+		for _, c := range resp.Items {
+			if c.UserName == self && len(c.NickName) != 0 {
+				names = append(names, c.NickName)
+			}
+		}
+	}
+	if resp, err := m.db.GetChatRooms(m.runCtx, talker, 0, 0); err == nil {
+		for _, r := range resp.Items {
+			if name, ok := r.User2DisplayName[self]; r.Name == talker && ok && len(name) != 0 {
+				names = append(names, name)
 			}
 		}
 	}
+	return names
+}
+
+// SearchMessages 在所有会话中搜索包含关键字的消息，不区分大小写，按时间倒序返回最近的 limit 条
+func (m *Manager) SearchMessages(keyword string, limit int) ([]*model.Message, error) {
+	if m.db.GetDB() == nil {
+		if err := m.db.Start(); err != nil {
+			return nil, i18n.Errorf("数据库未启动: %v", err)
+		}
+	}
+	keyword = strings.TrimSpace(keyword)
+	if len(keyword) == 0 {
+		return nil, nil
+	}
+	sessions, err := m.db.GetSessions(m.runCtx, "", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	talkers := make([]string, 0, len(sessions.Items))
+	for _, s := range sessions.Items {
+		talkers = append(talkers, s.UserName)
+	}
+	if len(talkers) == 0 {
+		return nil, nil
+	}
 
-	summary := strings.TrimSpace(summaryBuf.String())
+	start, end, _ := util.TimeRangeOf("all")
+	messages, err := m.db.GetMessages(m.runCtx, start, end, strings.Join(talkers, ","), "", "(?i)"+regexp.QuoteMeta(keyword), 0, 0)
+	if err != nil {
+		if errors.GetCode(err) == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].Seq > messages[j].Seq
+	})
+	if limit > 0 && len(messages) > limit {
+		messages = messages[:limit]
+	}
+	return messages, nil
+}
+
+// BrowseImage 返回 TUI 中预览的图片消息解密后的图片数据
+// 与 HTTP 服务相同，依次尝试 md5 对应的媒体文件、原图路径与缩略图路径
+func (m *Manager) BrowseImage(msg *model.Message) ([]byte, error) {
+	if m.db.GetDB() == nil {
+		if err := m.db.Start(); err != nil {
+			return nil, i18n.Errorf("数据库未启动: %v", err)
+		}
+	}
+	if m.ctx.Version == 4 {
+		m.imageKeyOnce.Do(func() {
+			dat2img.SetAesKey(m.ctx.ImgKey)
+			dat2img.ScanAndSetXorKey(m.ctx.DataDir)
+		})
+	}
 
-	// Build POST payload
-	payload := map[string]any{
-		"source":        "wechat",
-		"group":         "文件传输助手",
-		"summary":       summary,
-		"highlights":    highlights,
-		"message_count": len(messages),
-		"ts":            now.Format(time.RFC3339),
+	var paths []string
+	if md5, ok := msg.Contents["md5"].(string); ok && len(md5) != 0 {
+		if media, err := m.db.GetMedia(m.runCtx, "image", md5); err == nil {
+			paths = append(paths, filepath.Join(m.ctx.DataDir, media.Path))
+		}
 	}
-	body, err := json.Marshal(payload)
+	for _, key := range []string{"path", "thumbpath"} {
+		if p, ok := msg.Contents[key].(string); ok && len(p) != 0 {
+			base := filepath.Join(m.ctx.DataDir, p)
+			paths = append(paths, base, base+"_h.dat", base+".dat", base+"_t.dat")
+		}
+	}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if strings.EqualFold(filepath.Ext(path), ".dat") {
+			out, _, err := dat2img.Dat2ImageContext(m.runCtx, data)
+			if err != nil {
+				log.Debug().Err(err).Str("path", path).Msg("decrypt image failed")
+				continue
+			}
+			data = out
+		}
+		return data, nil
+	}
+	return nil, errors.ErrMediaNotFound
+}
+
+// MediaPath 返回 TUI 中复制的图片、视频或文件消息在数据目录中的文件路径
+func (m *Manager) MediaPath(msg *model.Message) (string, error) {
+	if m.db.GetDB() == nil {
+		if err := m.db.Start(); err != nil {
+			return "", i18n.Errorf("数据库未启动: %v", err)
+		}
+	}
+
+	var _type string
+	var suffixes []string
+	switch {
+	case msg.Type == model.MessageTypeImage:
+		_type, suffixes = "image", []string{"", "_h.dat", ".dat", "_t.dat"}
+	case msg.Type == model.MessageTypeVideo:
+		_type, suffixes = "video", []string{"", ".mp4", "_thumb.jpg"}
+	case msg.Type == model.MessageTypeShare && msg.SubType == model.MessageSubTypeFile:
+		_type, suffixes = "file", []string{""}
+	default:
+		return "", i18n.Errorf("该消息没有媒体文件")
+	}
+
+	var paths []string
+	if md5, ok := msg.Contents["md5"].(string); ok && len(md5) != 0 {
+		if media, err := m.db.GetMedia(m.runCtx, _type, md5); err == nil {
+			paths = append(paths, filepath.Join(m.ctx.DataDir, media.Path))
+		}
+	}
+	for _, key := range []string{"path", "thumbpath"} {
+		if p, ok := msg.Contents[key].(string); ok && len(p) != 0 {
+			base := filepath.Join(m.ctx.DataDir, p)
+			for _, suffix := range suffixes {
+				paths = append(paths, base+suffix)
+			}
+		}
+	}
+
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+	return "", errors.ErrMediaNotFound
+}
+
+// BrowseTalkers 返回 TUI 中可选择的联系人与群聊
+func (m *Manager) BrowseTalkers() ([]*model.Contact, []*model.ChatRoom, error) {
+	if m.db.GetDB() == nil {
+		if err := m.db.Start(); err != nil {
+			return nil, nil, i18n.Errorf("数据库未启动: %v", err)
+		}
+	}
+	contacts, err := m.db.GetContacts(m.runCtx, "", 0, 0)
 	if err != nil {
-		return "", fmt.Errorf("序列化失败: %v", err)
+		return nil, nil, err
 	}
+	chatRooms, err := m.db.GetChatRooms(m.runCtx, "", 0, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	return contacts.Items, chatRooms.Items, nil
+}
 
-	// POST to ingest API
-	req, err := http.NewRequest("POST", "http://8.135.4.47:8787/ingest", bytes.NewBuffer(body))
+func (m *Manager) summarize(c *conf.Summarize, lc *conf.LLM, dests map[string]*conf.Destination, talker string, since time.Duration, to string) (*summarize.Payload, error) {
+	dest, err := summarize.Target(to, c, dests)
 	if err != nil {
-		return "", fmt.Errorf("创建请求失败: %v", err)
+		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Relay-Token", "8256e4c58d8105a8192e8798afadc31c23cec2d780d1111fd65a2c83642e2d63")
+	payload, err := m.buildSummary(lc, talker, since)
+	if err != nil {
+		return nil, err
+	}
+	if payload == nil {
+		return nil, i18n.Errorf("%s 在过去 %s 内没有消息", talker, since)
+	}
+	if dest == nil {
+		return payload, nil
+	}
+	if err := push.Send(dest, payload); err != nil {
+		return nil, i18n.Errorf("推送失败: %v", err)
+	}
+
+	log.Info().Str("talker", payload.Talker).Int("message_count", payload.MessageCount).Msg("总结推送成功")
+	return payload, nil
+}
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+// buildSummary 总结聊天对象在 since 时长内的消息，配置了大模型时由大模型生成总结，没有消息时返回 nil
+func (m *Manager) buildSummary(lc *conf.LLM, talker string, since time.Duration) (*summarize.Payload, error) {
+	provider, err := llm.New(lc)
 	if err != nil {
-		return "", fmt.Errorf("推送失败: %v", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("推送失败, 状态码: %d", resp.StatusCode)
+	now := time.Now()
+	start := now.Add(-since)
+	messages, err := m.db.GetMessages(m.runCtx, start, now, talker, "", "", 0, 0)
+	if err != nil {
+		return nil, i18n.Errorf("查询消息失败: %v", err)
+	}
+	if len(messages) == 0 {
+		return nil, nil
 	}
 
-	log.Info().Int("message_count", len(messages)).Msg("文件传输助手总结推送成功")
-	return summary, nil
+	// 按名称查询时使用消息中的聊天对象 ID
+	talker, name := messages[0].Talker, messages[0].TalkerName
+	if len(name) == 0 {
+		if resp, err := m.db.GetContacts(m.runCtx, talker, 1, 0); err == nil && len(resp.Items) > 0 {
+			name = resp.Items[0].DisplayName()
+		}
+	}
+	payload := summarize.Build(talker, name, start, messages, now)
+	payload.Link = m.chatlogLink(talker, start, now)
+	if provider != nil {
+		if err := summarize.Digest(m.runCtx, provider, lc, payload); err != nil {
+			return nil, i18n.Errorf("大模型生成总结失败: %v", err)
+		}
+		log.Debug().Str("talker", payload.Talker).Str("provider", payload.Provider).Msg("llm summary generated")
+	}
+	return payload, nil
 }
 
-func (m *Manager) CommandKey(configPath string, pid int, force bool, showXorKey bool) (string, error) {
+// History 返回 TUI 配置中记录的历史账号
+func (m *Manager) History(configPath string) ([]conf.ProcessConfig, error) {
+	tc, _, err := conf.LoadTUIConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	return tc.History, nil
+}
+
+// KeyResult key 命令的结果，未指定进程且存在多个微信进程时只包含进程信息
+type KeyResult struct {
+	PID         uint32       `json:"pid"`
+	Account     string       `json:"account"`
+	Platform    string       `json:"platform"`
+	Version     int          `json:"version"`
+	FullVersion string       `json:"fullVersion"`
+	DataDir     string       `json:"dataDir"`
+	DataKey     string       `json:"dataKey,omitempty"`
+	DerivedKeys []DerivedKey `json:"derivedKeys,omitempty"`
+	ImgKey      string       `json:"imgKey,omitempty"`
+	XorKey      string       `json:"xorKey,omitempty"`
+}
+
+// DerivedKey macOS 4.0 每个数据库使用独立的派生密钥，DB 为相对于数据目录的路径，无法匹配时为空
+type DerivedKey struct {
+	Key string `json:"key"`
+	DB  string `json:"db"`
+}
+
+func (r *KeyResult) String() string {
+	if len(r.DataKey) == 0 {
+		return fmt.Sprintf("PID: %d. %s[Version: %s Data Dir: %s ]", r.PID, r.Account, r.FullVersion, r.DataDir)
+	}
+	result := fmt.Sprintf("Data Key: [%s]\nImage Key: [%s]", r.DataKey, r.ImgKey)
+	for _, dk := range r.DerivedKeys {
+		db := dk.DB
+		if len(db) == 0 {
+			db = "unknown"
+		}
+		result += fmt.Sprintf("\nDerived Key: [%s] DB: [%s]", dk.Key, db)
+	}
+	if len(r.XorKey) != 0 {
+		result += fmt.Sprintf("\nXor Key: [%s]", r.XorKey)
+	}
+	return result
+}
+
+// CommandKey 获取微信进程的密钥，account 不为空时只处理该账号（名称或微信 ID）的进程
+func (m *Manager) CommandKey(configPath string, pid int, force bool, showXorKey bool, account string) ([]*KeyResult, error) {
 
 	var err error
 	m.ctx, err = ctx.New(configPath)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	m.wechat = wechat.NewService(m.ctx)
 
 	m.ctx.WeChatInstances = m.wechat.GetWeChatInstances()
 	if len(m.ctx.WeChatInstances) == 0 {
-		return "", fmt.Errorf("wechat process not found")
+		return nil, errors.ErrWeChatProcessNotFound
+	}
+
+	if len(account) != 0 {
+		var ins *iwechat.Account
+		for _, i := range m.ctx.WeChatInstances {
+			if i.Name == account || strings.HasPrefix(i.Name, account+"_") {
+				ins = i
+				break
+			}
+		}
+		if ins == nil {
+			return nil, errors.WeChatAccountNotFound(account)
+		}
+		m.ctx.SwitchCurrent(ins)
+		m.ctx.WeChatInstances = []*iwechat.Account{ins}
 	}
 
 	if len(m.ctx.WeChatInstances) == 1 {
 		key, imgKey := m.ctx.DataKey, m.ctx.ImgKey
 		if len(key) == 0 || len(imgKey) == 0 || force {
-			key, imgKey, err = m.ctx.WeChatInstances[0].GetKey(context.Background())
+			key, imgKey, err = m.ctx.WeChatInstances[0].GetKey(m.runCtx)
 			if err != nil {
-				return "", err
+				return nil, err
 			}
 			m.ctx.Refresh()
 			m.ctx.UpdateConfig()
 		}
-
-		result := fmt.Sprintf("Data Key: [%s]\nImage Key: [%s]", key, imgKey)
-		if m.ctx.Version == 4 && showXorKey {
-			if b, err := dat2img.ScanAndSetXorKey(m.ctx.DataDir); err == nil {
-				result += fmt.Sprintf("\nXor Key: [0x%X]", b)
-			}
-		}
-
-		return result, nil
+		return []*KeyResult{m.keyResult(m.ctx.WeChatInstances[0], key, imgKey, showXorKey)}, nil
 	}
 	if pid == 0 {
-		str := "Select a process:\n"
+		ret := make([]*KeyResult, 0, len(m.ctx.WeChatInstances))
 		for _, ins := range m.ctx.WeChatInstances {
-			str += fmt.Sprintf("PID: %d. %s[Version: %s Data Dir: %s ]\n", ins.PID, ins.Name, ins.FullVersion, ins.DataDir)
+			ret = append(ret, m.keyResult(ins, "", "", false))
 		}
-		return str, nil
+		return ret, nil
 	}
 	for _, ins := range m.ctx.WeChatInstances {
 		if ins.PID == uint32(pid) {
 			key, imgKey := ins.Key, ins.ImgKey
 			if len(key) == 0 || len(imgKey) == 0 || force {
-				key, imgKey, err = ins.GetKey(context.Background())
+				key, imgKey, err = ins.GetKey(m.runCtx)
 				if err != nil {
-					return "", err
+					return nil, err
 				}
 				m.ctx.Refresh()
 				m.ctx.UpdateConfig()
 			}
-			result := fmt.Sprintf("Data Key: [%s]\nImage Key: [%s]", key, imgKey)
-			if m.ctx.Version == 4 && showXorKey {
-				if b, err := dat2img.ScanAndSetXorKey(m.ctx.DataDir); err == nil {
-					result += fmt.Sprintf("\nXor Key: [0x%X]", b)
+			return []*KeyResult{m.keyResult(ins, key, imgKey, showXorKey)}, nil
+		}
+	}
+	return nil, errors.ErrWeChatProcessNotFound
+}
+
+func (m *Manager) keyResult(ins *iwechat.Account, key, imgKey string, showXorKey bool) *KeyResult {
+	ret := &KeyResult{
+		PID:         ins.PID,
+		Account:     ins.Name,
+		Platform:    ins.Platform,
+		Version:     ins.Version,
+		FullVersion: ins.FullVersion,
+		DataDir:     ins.DataDir,
+		DataKey:     key,
+		ImgKey:      imgKey,
+	}
+	if strings.HasPrefix(key, "derived:") {
+		ret.DerivedKeys = derivedKeys(ins, strings.TrimPrefix(key, "derived:"))
+	}
+	if len(key) != 0 && m.ctx.Version == 4 && showXorKey {
+		if b, err := dat2img.ScanAndSetXorKey(m.ctx.DataDir); err == nil {
+			ret.XorKey = fmt.Sprintf("0x%X", b)
+		}
+	}
+	return ret
+}
+
+// derivedKeys 将派生密钥与其可解密的数据库对应起来
+func derivedKeys(ins *iwechat.Account, keys string) []DerivedKey {
+	validator, err := decrypt.NewValidator(ins.Platform, ins.Version, ins.DataDir)
+	if err != nil {
+		log.Debug().Err(err).Msg("failed to create validator for derived keys")
+	}
+	ret := make([]DerivedKey, 0)
+	for _, k := range strings.Split(keys, ",") {
+		dk := DerivedKey{Key: k}
+		if b, err := hex.DecodeString(k); err == nil && validator != nil {
+			if path := validator.DerivedKeyTarget(b); len(path) != 0 {
+				if rel, err := filepath.Rel(ins.DataDir, path); err == nil {
+					path = filepath.ToSlash(rel)
 				}
+				dk.DB = path
 			}
-			return result, nil
 		}
+		ret = append(ret, dk)
+	}
+	return ret
+}
+
+// CommandDecrypt 解密数据目录中的数据库，force 为 false 时跳过解密后未变化的数据库
+func (m *Manager) CommandDecrypt(configPath string, cmdConf map[string]any, force bool) error {
+
+	if err := m.loadDecryptConfig(configPath, cmdConf); err != nil {
+		return err
+	}
+
+	if err := m.wechat.DecryptDBFiles(m.runCtx, force); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CommandDecryptPlan 列出解密将要处理的数据库，不写入任何文件
+func (m *Manager) CommandDecryptPlan(configPath string, cmdConf map[string]any, force bool) ([]*wechat.PlanItem, error) {
+
+	if err := m.loadDecryptConfig(configPath, cmdConf); err != nil {
+		return nil, err
 	}
-	return "", fmt.Errorf("wechat process not found")
+
+	return m.wechat.PlanDecrypt(force)
 }
 
-func (m *Manager) CommandDecrypt(configPath string, cmdConf map[string]any) error {
+func (m *Manager) loadDecryptConfig(configPath string, cmdConf map[string]any) error {
 
 	var err error
 	m.sc, m.scm, err = conf.LoadServiceConfig(configPath, cmdConf)
@@ -395,21 +856,396 @@ func (m *Manager) CommandDecrypt(configPath string, cmdConf map[string]any) erro
 
 	dataDir := m.sc.GetDataDir()
 	if len(dataDir) == 0 {
-		return fmt.Errorf("dataDir is required")
+		return errors.ConfigRequired("dataDir")
 	}
 
 	dataKey := m.sc.GetDataKey()
 	if len(dataKey) == 0 {
-		return fmt.Errorf("dataKey is required")
+		return errors.ConfigRequired("dataKey")
 	}
 
 	m.wechat = wechat.NewService(m.sc)
+	return nil
+}
+
+func (m *Manager) CommandStats(configPath string, cmdConf map[string]any, timeRange string, top int) (*model.Stats, error) {
 
-	if err := m.wechat.DecryptDBFiles(); err != nil {
+	var err error
+	m.sc, m.scm, err = conf.LoadServiceConfig(configPath, cmdConf)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(m.sc.GetWorkDir()) == 0 {
+		return nil, errors.ConfigRequired("workDir")
+	}
+
+	start, end, ok := util.TimeRangeOf(timeRange)
+	if !ok {
+		return nil, fmt.Errorf("invalid time range: %s", timeRange)
+	}
+
+	m.db = database.NewService(m.sc)
+	if err := m.db.Start(); err != nil {
+		return nil, err
+	}
+	defer m.db.Stop()
+
+	return m.db.GetStats(m.runCtx, start, end, top)
+}
+
+// CommandSummarize 总结聊天对象最近的消息，推送目标见 Summarize
+func (m *Manager) CommandSummarize(configPath string, cmdConf map[string]any, talker string, since time.Duration, to string) (*summarize.Payload, error) {
+
+	var err error
+	m.sc, m.scm, err = conf.LoadServiceConfig(configPath, cmdConf)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(m.sc.GetWorkDir()) == 0 {
+		return nil, errors.ConfigRequired("workDir")
+	}
+	if len(talker) == 0 {
+		return nil, fmt.Errorf("talker is required")
+	}
+
+	m.db = database.NewService(m.sc)
+	if err := m.db.Start(); err != nil {
+		return nil, err
+	}
+	defer m.db.Stop()
+
+	return m.summarize(m.sc.GetSummarize(), m.sc.GetLLM(), m.sc.GetDestinations(), talker, since, to)
+}
+
+// CommandSessions 按最近活动时间列出会话，并统计 since 之后的新消息数
+// since 为零值时使用上次运行的时间，save 为 true 时记录本次运行时间
+func (m *Manager) CommandSessions(configPath string, cmdConf map[string]any, keyword string, limit int, since time.Time, save bool) (*sessions.Result, error) {
+
+	var err error
+	m.sc, m.scm, err = conf.LoadServiceConfig(configPath, cmdConf)
+	if err != nil {
+		return nil, err
+	}
+
+	workDir := m.sc.GetWorkDir()
+	if len(workDir) == 0 {
+		return nil, errors.ConfigRequired("workDir")
+	}
+
+	m.db = database.NewService(m.sc)
+	if err := m.db.Start(); err != nil {
+		return nil, err
+	}
+	defer m.db.Stop()
+
+	list, err := m.db.GetSessions(m.runCtx, keyword, limit, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if since.IsZero() {
+		since = sessions.LastRun(workDir)
+	}
+	now := time.Now()
+	ret, err := sessions.Build(m.runCtx, m.db, list.Items, since, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if save {
+		if err := sessions.SaveLastRun(workDir, now); err != nil {
+			log.Warn().Err(err).Msg("failed to save the time of this run")
+		}
+	}
+	return ret, nil
+}
+
+func (m *Manager) CommandContacts(configPath string, cmdConf map[string]any, keyword string) (*wechatdb.GetContactsResp, *wechatdb.GetChatRoomsResp, error) {
+
+	var err error
+	m.sc, m.scm, err = conf.LoadServiceConfig(configPath, cmdConf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(m.sc.GetWorkDir()) == 0 {
+		return nil, nil, errors.ConfigRequired("workDir")
+	}
+
+	m.db = database.NewService(m.sc)
+	if err := m.db.Start(); err != nil {
+		return nil, nil, err
+	}
+	defer m.db.Stop()
+
+	contacts, err := m.db.GetContacts(m.runCtx, keyword, 0, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	chatRooms, err := m.db.GetChatRooms(m.runCtx, keyword, 0, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return contacts, chatRooms, nil
+}
+
+func (m *Manager) CommandDoctor(configPath string, cmdConf map[string]any) []*doctor.Result {
+
+	var err error
+	m.sc, m.scm, err = conf.LoadServiceConfig(configPath, cmdConf)
+	results := []*doctor.Result{doctor.CheckConfig(err)}
+	if err != nil {
+		m.sc = &conf.ServerConfig{}
+	}
+
+	return append(results, doctor.Run(m.sc)...)
+}
+
+func (m *Manager) CommandWatch(configPath string, cmdConf map[string]any, talker string, handler func(*model.Message)) error {
+
+	var err error
+	m.sc, m.scm, err = conf.LoadServiceConfig(configPath, cmdConf)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	if len(m.sc.GetWorkDir()) == 0 {
+		return errors.ConfigRequired("workDir")
+	}
+
+	m.wechat = wechat.NewService(m.sc)
+	m.db = database.NewService(m.sc)
+
+	// 配置了数据目录和密钥时，自行开启自动解密；否则仅监听工作目录的变化
+	if len(m.sc.GetDataDir()) != 0 && len(m.sc.GetDataKey()) != 0 {
+		if err := m.wechat.StartAutoDecrypt(); err != nil {
+			return err
+		}
+		defer m.wechat.StopAutoDecrypt()
+		log.Info().Msg("auto decrypt is enabled")
+	}
+
+	if err := m.db.Start(); err != nil {
+		return err
+	}
+	defer m.db.Stop()
+
+	feed := m.db.NewFeed(talker, time.Now())
+	notify := make(chan struct{}, 1)
+	if err := m.db.GetDB().SetCallback("message", func(event fsnotify.Event) error {
+		if !event.Op.Has(fsnotify.Create) {
+			return nil
+		}
+		select {
+		case notify <- struct{}{}:
+		default:
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	ctx := m.runCtx
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-notify:
+			messages, err := feed.Next(ctx)
+			if err != nil {
+				log.Debug().Err(err).Msg("get new messages failed")
+				continue
+			}
+			for _, msg := range messages {
+				handler(msg)
+			}
+		}
+	}
+}
+
+func (m *Manager) CommandBackup(configPath string, cmdConf map[string]any, opts backup.Options) (*backup.Result, error) {
+
+	var err error
+	m.sc, m.scm, err = conf.LoadServiceConfig(configPath, cmdConf)
+	if err != nil {
+		return nil, err
+	}
+
+	workDir := m.sc.GetWorkDir()
+	if len(workDir) == 0 {
+		return nil, errors.ConfigRequired("workDir")
+	}
+	if _, err := os.Stat(workDir); err != nil {
+		return nil, err
+	}
+
+	opts.WorkDir = workDir
+	if len(opts.ConfigDir) == 0 {
+		opts.ConfigDir = m.scm.Path
+	}
+	if len(opts.OutputDir) == 0 {
+		opts.OutputDir = filepath.Join(filepath.Dir(workDir), "backup")
+	}
+	if opts.Remote == nil {
+		opts.Remote = m.sc.GetBackupRemote()
+	}
+
+	manifest := &backup.Manifest{
+		Account:     filepath.Base(workDir),
+		Platform:    m.sc.GetPlatform(),
+		Version:     m.sc.GetVersion(),
+		FullVersion: m.sc.FullVersion,
+		DataDir:     m.sc.GetDataDir(),
+		DataKey:     m.sc.GetDataKey(),
+		ImgKey:      m.sc.GetImgKey(),
+		WorkDir:     workDir,
+	}
+
+	return backup.Create(opts, manifest)
+}
+
+func (m *Manager) CommandPrune(configPath string, cmdConf map[string]any, opts prune.Options) (*prune.Result, error) {
+
+	var err error
+	m.sc, m.scm, err = conf.LoadServiceConfig(configPath, cmdConf)
+	if err != nil {
+		return nil, err
+	}
+
+	workDir := m.sc.GetWorkDir()
+	if len(workDir) == 0 {
+		return nil, errors.ConfigRequired("workDir")
+	}
+
+	pc := m.sc.GetPrune()
+	opts.WorkDir = workDir
+	opts.DataDir = m.sc.GetDataDir()
+	opts.Account = filepath.Base(workDir)
+	opts.KeepBackups = pc.KeepBackups
+	opts.TempMaxAge = pc.TempMaxAge
+	opts.CacheMaxAge = pc.CacheMaxAge
+	opts.Stale = pc.Stale
+	opts.CacheDir = filecopy.TempDir()
+	if len(opts.BackupDir) == 0 {
+		opts.BackupDir = filepath.Join(filepath.Dir(workDir), "backup")
+	}
+
+	return prune.Run(opts)
+}
+
+// CommandDiff 比较两个快照中的消息，快照可以是工作目录或备份文件，newPath 为空时与当前工作目录比较
+func (m *Manager) CommandDiff(configPath string, cmdConf map[string]any, oldPath, newPath, talker, password string) (*diff.Result, error) {
+
+	var err error
+	m.sc, m.scm, err = conf.LoadServiceConfig(configPath, cmdConf)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(newPath) == 0 {
+		newPath = m.sc.GetWorkDir()
+		if len(newPath) == 0 {
+			return nil, errors.ConfigRequired("workDir")
+		}
+	}
+
+	dbs := make([]*wechatdb.DB, 0, 2)
+	tmpDirs := make([]string, 0, 2)
+	defer func() {
+		for _, db := range dbs {
+			db.Close()
+		}
+		for _, dir := range tmpDirs {
+			os.RemoveAll(dir)
+		}
+	}()
+	for _, path := range []string{oldPath, newPath} {
+		dir := path
+		if strings.HasSuffix(path, backup.Ext) || strings.HasSuffix(path, backup.EncryptedExt) {
+			if dir, err = os.MkdirTemp("", "chatlog-diff-*"); err != nil {
+				return nil, err
+			}
+			tmpDirs = append(tmpDirs, dir)
+			if err := backup.ExtractWorkDir(path, password, dir); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+
+		platform, version := merge.Detect(dir)
+		if version == 0 {
+			return nil, fmt.Errorf("no message database found in %s", path)
+		}
+		// 4.0 的目录结构与平台无关
+		if len(platform) == 0 {
+			platform = m.sc.GetPlatform()
+		}
+		if len(platform) == 0 {
+			platform = runtime.GOOS
+		}
+
+		db, err := wechatdb.New(dir, platform, version)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		dbs = append(dbs, db)
+	}
+
+	ret, err := diff.Compare(m.runCtx, dbs[0], dbs[1], talker)
+	if err != nil {
+		return nil, err
+	}
+	ret.Old, ret.New = oldPath, newPath
+	return ret, nil
+}
+
+func (m *Manager) CommandMerge(configPath string, cmdConf map[string]any, srcs []string) (*merge.Result, error) {
+
+	var err error
+	m.sc, m.scm, err = conf.LoadServiceConfig(configPath, cmdConf)
+	if err != nil {
+		return nil, err
+	}
+
+	workDir := m.sc.GetWorkDir()
+	if len(workDir) == 0 {
+		return nil, errors.ConfigRequired("workDir")
+	}
+	if len(srcs) == 0 {
+		return nil, fmt.Errorf("source dir is required")
+	}
+
+	// 未指定平台和版本时，根据源目录中的文件推断
+	platform, version := m.sc.GetPlatform(), m.sc.GetVersion()
+	if version == 0 {
+		platform, version = merge.Detect(srcs[0])
+		if version == 0 {
+			return nil, fmt.Errorf("cannot detect platform and version of %s", srcs[0])
+		}
+	}
+
+	return merge.Archive(workDir, srcs, platform, version)
+}
+
+// CommandImport 将外部导出的聊天记录导入到工作目录，导入后以 4.0 版本读取
+func (m *Manager) CommandImport(configPath string, cmdConf map[string]any, opts importer.Options) (*importer.Result, error) {
+
+	var err error
+	m.sc, m.scm, err = conf.LoadServiceConfig(configPath, cmdConf)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.WorkDir = m.sc.GetWorkDir()
+	if len(opts.WorkDir) == 0 {
+		return nil, errors.ConfigRequired("workDir")
+	}
+
+	return importer.Run(opts)
 }
 
 func (m *Manager) CommandHTTPServer(configPath string, cmdConf map[string]any) error {
@@ -423,12 +1259,12 @@ func (m *Manager) CommandHTTPServer(configPath string, cmdConf map[string]any) e
 	dataDir := m.sc.GetDataDir()
 	workDir := m.sc.GetWorkDir()
 	if len(dataDir) == 0 && len(workDir) == 0 {
-		return fmt.Errorf("dataDir or workDir is required")
+		return errors.ConfigRequired("dataDir or workDir")
 	}
 
 	dataKey := m.sc.GetDataKey()
 	if len(dataKey) == 0 {
-		return fmt.Errorf("dataKey is required")
+		return errors.ConfigRequired("dataKey")
 	}
 
 	// 如果是 4.0 版本，处理图片密钥
@@ -445,6 +1281,7 @@ func (m *Manager) CommandHTTPServer(configPath string, cmdConf map[string]any) e
 	m.db = database.NewService(m.sc)
 
 	m.http = chathttp.NewService(m.sc, m.db)
+	m.http.SetDecryptLag(m.wechat.DecryptLag)
 
 	if m.sc.GetAutoDecrypt() {
 		if err := m.wechat.StartAutoDecrypt(); err != nil {
@@ -459,7 +1296,7 @@ func (m *Manager) CommandHTTPServer(configPath string, cmdConf map[string]any) e
 		if entries, err := os.ReadDir(workDir); err == nil && len(entries) == 0 {
 			log.Info().Msgf("work dir is empty, decrypt data.")
 			m.db.SetDecrypting()
-			if err := m.wechat.DecryptDBFiles(); err != nil {
+			if err := m.wechat.DecryptDBFiles(m.runCtx, false); err != nil {
 				log.Info().Msgf("decrypt data failed: %v", err)
 				return
 			}
@@ -470,7 +1307,8 @@ func (m *Manager) CommandHTTPServer(configPath string, cmdConf map[string]any) e
 		if err := m.db.Start(); err != nil {
 			log.Info().Msgf("start db failed, try to decrypt data.")
 			m.db.SetDecrypting()
-			if err := m.wechat.DecryptDBFiles(); err != nil {
+			// 已有的解密结果可能已损坏，全部重新解密
+			if err := m.wechat.DecryptDBFiles(m.runCtx, true); err != nil {
 				log.Info().Msgf("decrypt data failed: %v", err)
 				return
 			}
@@ -483,5 +1321,5 @@ func (m *Manager) CommandHTTPServer(configPath string, cmdConf map[string]any) e
 		}
 	}()
 
-	return m.http.ListenAndServe()
+	return m.http.ListenAndServe(m.runCtx)
 }
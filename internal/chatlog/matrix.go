@@ -0,0 +1,124 @@
+package chatlog
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+	"github.com/DanielMao1/chatlog/internal/chatlog/database"
+	"github.com/DanielMao1/chatlog/internal/chatlog/matrix"
+	"github.com/DanielMao1/chatlog/internal/errors"
+	"github.com/DanielMao1/chatlog/internal/model"
+	"github.com/DanielMao1/chatlog/pkg/util"
+)
+
+// CommandMatrix 将聊天对象在时间范围内的消息回放到 Matrix 房间，output 非空时导出为 Matrix 事件 JSON 文件
+// room 为空时使用配置中的 matrix.room，返回发送或导出的消息数量
+func (m *Manager) CommandMatrix(configPath string, cmdConf map[string]any, talker, timeRange, room, output string, onProgress func(sent, total int)) (int, error) {
+
+	var err error
+	m.sc, m.scm, err = conf.LoadServiceConfig(configPath, cmdConf)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(m.sc.GetWorkDir()) == 0 {
+		return 0, errors.ConfigRequired("workDir")
+	}
+	if len(talker) == 0 {
+		return 0, fmt.Errorf("talker is required")
+	}
+
+	m.db = database.NewService(m.sc)
+	if err := m.db.Start(); err != nil {
+		return 0, err
+	}
+	defer m.db.Stop()
+
+	if len(output) != 0 {
+		return m.Export(talker, timeRange, ExportMatrix, output, "", "")
+	}
+
+	c := m.sc.GetMatrix()
+	if c == nil {
+		c = &conf.Matrix{}
+	}
+	if len(room) == 0 {
+		room = c.Room
+	}
+	if len(room) == 0 {
+		return 0, fmt.Errorf("matrix room is required, use --room or matrix.room")
+	}
+	client, err := matrix.NewClient(c)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(timeRange) == 0 {
+		timeRange = "all"
+	}
+	start, end, ok := util.TimeRangeOf(timeRange)
+	if !ok {
+		return 0, fmt.Errorf("invalid time range: %s", timeRange)
+	}
+	messages, err := m.db.GetMessages(m.runCtx, start, end, talker, "", "", 0, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx := m.runCtx
+	roomID, err := client.ResolveRoom(ctx, room)
+	if err != nil {
+		return 0, err
+	}
+	list := matrixMessages(messages)
+	return client.Replay(ctx, roomID, talker, list, func(sent int) {
+		if onProgress != nil {
+			onProgress(sent, len(list))
+		}
+	})
+}
+
+// matrixExport 将一个会话的消息转换为 Matrix 房间导出
+func matrixExport(talker string, messages []*model.Message) *matrix.Export {
+	name := talker
+	if len(messages) != 0 && len(messages[0].TalkerName) != 0 {
+		name = messages[0].TalkerName
+	}
+	return matrix.NewExport(talker, name, matrix.DefaultDomain, matrixMessages(messages))
+}
+
+// matrixMessages 转换为 Matrix 消息，多媒体消息的链接保留在正文中
+func matrixMessages(messages []*model.Message) []*matrix.Message {
+	list := make([]*matrix.Message, 0, len(messages))
+	for _, msg := range messages {
+		senderName := msg.SenderName
+		if msg.IsSelf && len(senderName) == 0 {
+			senderName = "我"
+		}
+		list = append(list, &matrix.Message{
+			ID:         strconv.FormatInt(msg.Seq, 10),
+			Sender:     msg.Sender,
+			SenderName: senderName,
+			Time:       msg.Time,
+			Body:       msg.PlainTextContent(),
+			MsgType:    matrixMsgType(msg.MediaType()),
+		})
+	}
+	return list
+}
+
+// matrixMsgType 返回媒体类型对应的 Matrix 消息类型
+func matrixMsgType(media string) string {
+	switch media {
+	case "image", "animation":
+		return matrix.MsgImage
+	case "video":
+		return matrix.MsgVideo
+	case "voice":
+		return matrix.MsgAudio
+	case "file":
+		return matrix.MsgFile
+	}
+	return matrix.MsgText
+}
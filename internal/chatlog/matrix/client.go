@@ -0,0 +1,160 @@
+package matrix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+)
+
+const (
+	// DefaultRetryAfter 服务器限流但没有返回等待时间时的重试间隔
+	DefaultRetryAfter = 2 * time.Second
+
+	// MaxRetries 限流时最多重试的次数
+	MaxRetries = 10
+)
+
+// Client 使用 access token 调用 Matrix 客户端-服务器 API
+type Client struct {
+	homeserver string
+	token      string
+	client     *http.Client
+}
+
+// NewClient 创建客户端
+func NewClient(c *conf.Matrix) (*Client, error) {
+	if c == nil || len(c.Homeserver) == 0 {
+		return nil, fmt.Errorf("matrix.homeserver is required")
+	}
+	if len(c.AccessToken) == 0 {
+		return nil, fmt.Errorf("matrix.access_token is required")
+	}
+	u, err := url.Parse(c.Homeserver)
+	if err != nil || len(u.Host) == 0 {
+		return nil, fmt.Errorf("invalid matrix.homeserver: %s", c.Homeserver)
+	}
+	return &Client{
+		homeserver: strings.TrimRight(c.Homeserver, "/"),
+		token:      c.AccessToken,
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// ResolveRoom 返回房间 ID，room 为别名（#xxx:server）时查询对应的房间
+func (c *Client) ResolveRoom(ctx context.Context, room string) (string, error) {
+	if !strings.HasPrefix(room, "#") {
+		return room, nil
+	}
+	var resp struct {
+		RoomID string `json:"room_id"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/_matrix/client/v3/directory/room/"+url.PathEscape(room), nil, &resp); err != nil {
+		return "", err
+	}
+	return resp.RoomID, nil
+}
+
+// Send 向房间发送消息，txnID 相同的请求服务器只处理一次，中断后重新回放不会重复发送
+func (c *Client) Send(ctx context.Context, roomID, txnID string, content map[string]any) (string, error) {
+	var resp struct {
+		EventID string `json:"event_id"`
+	}
+	path := "/_matrix/client/v3/rooms/" + url.PathEscape(roomID) + "/send/" + EventMessage + "/" + url.PathEscape(txnID)
+	if err := c.do(ctx, http.MethodPut, path, content, &resp); err != nil {
+		return "", err
+	}
+	return resp.EventID, nil
+}
+
+// Replay 按时间顺序将消息发送到房间，每条消息前加上发送者与原始时间，返回发送的数量
+// progress 在每条消息发送后调用，可以为 nil
+func (c *Client) Replay(ctx context.Context, roomID, talker string, messages []*Message, progress func(sent int)) (int, error) {
+	for i, m := range messages {
+		content := m.Content()
+		prefix := m.SenderName
+		if len(prefix) == 0 {
+			prefix = m.Sender
+		}
+		prefix += " (" + m.Time.Format("2006-01-02 15:04:05") + "): "
+		content["body"] = prefix + content["body"].(string)
+		// 文件等链接以文本形式发送，客户端才能显示
+		content["msgtype"] = MsgText
+		delete(content, "external_url")
+
+		if _, err := c.Send(ctx, roomID, "chatlog-"+localpart(talker+"_"+m.ID), content); err != nil {
+			return i, err
+		}
+		if progress != nil {
+			progress(i + 1)
+		}
+	}
+	return len(messages), nil
+}
+
+// do 发送请求，服务器限流（M_LIMIT_EXCEEDED）时按 retry_after_ms 等待后重试
+func (c *Client) do(ctx context.Context, method, path string, body any, result any) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	for retry := 0; ; retry++ {
+		req, err := http.NewRequestWithContext(ctx, method, c.homeserver+path, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return err
+		}
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			if result == nil {
+				return nil
+			}
+			return json.Unmarshal(respBody, result)
+		}
+
+		var e struct {
+			ErrCode      string `json:"errcode"`
+			Error        string `json:"error"`
+			RetryAfterMs int64  `json:"retry_after_ms"`
+		}
+		json.Unmarshal(respBody, &e)
+		if resp.StatusCode == http.StatusTooManyRequests && retry < MaxRetries {
+			wait := time.Duration(e.RetryAfterMs) * time.Millisecond
+			if wait <= 0 {
+				wait = DefaultRetryAfter
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+			continue
+		}
+		if len(e.ErrCode) != 0 {
+			return fmt.Errorf("matrix %s: %s", e.ErrCode, e.Error)
+		}
+		return fmt.Errorf("matrix status %s", resp.Status)
+	}
+}
@@ -0,0 +1,167 @@
+package matrix
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultDomain 导出的用户与房间 ID 使用的服务器名称
+	DefaultDomain = "wechat.chatlog"
+
+	// Matrix 消息类型
+	MsgText  = "m.text"
+	MsgImage = "m.image"
+	MsgVideo = "m.video"
+	MsgAudio = "m.audio"
+	MsgFile  = "m.file"
+
+	// 事件类型
+	EventMessage = "m.room.message"
+	EventMember  = "m.room.member"
+	EventName    = "m.room.name"
+)
+
+// Message 待导出或回放的一条聊天消息
+type Message struct {
+	ID         string // 稳定的消息 ID，用于事件 ID 与回放去重
+	Sender     string
+	SenderName string
+	Time       time.Time
+	Body       string
+	MsgType    string // 为空时为 m.text
+	URL        string // 多媒体消息的链接，选填
+}
+
+// Event Matrix 客户端-服务器 API 格式的房间事件
+type Event struct {
+	Type           string         `json:"type"`
+	EventID        string         `json:"event_id"`
+	RoomID         string         `json:"room_id"`
+	Sender         string         `json:"sender"`
+	OriginServerTS int64          `json:"origin_server_ts"`
+	StateKey       *string        `json:"state_key,omitempty"`
+	Content        map[string]any `json:"content"`
+}
+
+// Export 与 Element 导出聊天记录 JSON 相同结构的房间导出
+type Export struct {
+	RoomName   string   `json:"room_name"`
+	RoomID     string   `json:"room_id"`
+	ExportDate string   `json:"export_date"`
+	ExportedBy string   `json:"exported_by"`
+	Messages   []*Event `json:"messages"`
+}
+
+// NewExport 将一个会话的消息转换为 Matrix 房间事件，每个发送者在第一条消息前加入房间
+func NewExport(talker, roomName, domain string, messages []*Message) *Export {
+	if len(domain) == 0 {
+		domain = DefaultDomain
+	}
+	roomID := RoomID(talker, domain)
+	export := &Export{
+		RoomName:   roomName,
+		RoomID:     roomID,
+		ExportDate: time.Now().Format("2006-01-02"),
+		ExportedBy: "chatlog",
+		Messages:   make([]*Event, 0, len(messages)+1),
+	}
+
+	var ts int64
+	if len(messages) != 0 {
+		ts = messages[0].Time.UnixMilli()
+	}
+	empty := ""
+	export.Messages = append(export.Messages, &Event{
+		Type:           EventName,
+		EventID:        EventID("name", talker, domain),
+		RoomID:         roomID,
+		Sender:         UserID("chatlog", domain),
+		OriginServerTS: ts,
+		StateKey:       &empty,
+		Content:        map[string]any{"name": roomName},
+	})
+
+	joined := make(map[string]bool)
+	for _, m := range messages {
+		sender := UserID(m.Sender, domain)
+		if !joined[sender] {
+			joined[sender] = true
+			stateKey := sender
+			content := map[string]any{"membership": "join"}
+			if len(m.SenderName) != 0 {
+				content["displayname"] = m.SenderName
+			}
+			export.Messages = append(export.Messages, &Event{
+				Type:           EventMember,
+				EventID:        EventID("join:"+m.Sender, talker, domain),
+				RoomID:         roomID,
+				Sender:         sender,
+				OriginServerTS: m.Time.UnixMilli(),
+				StateKey:       &stateKey,
+				Content:        content,
+			})
+		}
+		export.Messages = append(export.Messages, &Event{
+			Type:           EventMessage,
+			EventID:        EventID(m.ID, talker, domain),
+			RoomID:         roomID,
+			Sender:         sender,
+			OriginServerTS: m.Time.UnixMilli(),
+			Content:        m.Content(),
+		})
+	}
+	return export
+}
+
+// Content 返回 m.room.message 事件的内容，多媒体消息没有上传到服务器，链接放在正文中
+func (m *Message) Content() map[string]any {
+	msgType := m.MsgType
+	if len(msgType) == 0 {
+		msgType = MsgText
+	}
+	body := m.Body
+	if len(m.URL) != 0 && !strings.Contains(body, m.URL) {
+		body = strings.TrimSpace(body + " " + m.URL)
+	}
+	content := map[string]any{"msgtype": msgType, "body": body}
+	if len(m.URL) != 0 && msgType != MsgText {
+		content["external_url"] = m.URL
+	}
+	return content
+}
+
+// UserID 返回微信 ID 对应的 Matrix 用户 ID，localpart 只保留 Matrix 允许的字符
+func UserID(username, domain string) string {
+	return "@" + localpart(username) + ":" + domain
+}
+
+// RoomID 返回会话对应的 Matrix 房间 ID
+func RoomID(talker, domain string) string {
+	return "!" + localpart(talker) + ":" + domain
+}
+
+// EventID 返回消息对应的事件 ID
+func EventID(id, talker, domain string) string {
+	return "$" + localpart(talker+"_"+id) + ":" + domain
+}
+
+// localpart 按 Matrix 规范转换字符：大写字母转为 _ 加小写字母，_ 转为 __，a-z0-9.-/ 以外的字符转为 =xx
+func localpart(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '.', c == '-', c == '/':
+			b.WriteByte(c)
+		case c >= 'A' && c <= 'Z':
+			b.WriteByte('_')
+			b.WriteByte(c + 'a' - 'A')
+		case c == '_':
+			b.WriteString("__")
+		default:
+			fmt.Fprintf(&b, "=%02x", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,131 @@
+package matrix
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+)
+
+func TestIDs(t *testing.T) {
+	tests := map[string]string{
+		UserID("wxid_Abc", "example.org"):        "@wxid___abc:example.org",
+		RoomID("123@chatroom", "example.org"):    "!123=40chatroom:example.org",
+		EventID("1700000000001", "a", "x.local"): "$a__1700000000001:x.local",
+	}
+	for got, want := range tests {
+		if got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	}
+}
+
+func TestNewExport(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	export := NewExport("123@chatroom", "Project", "", []*Message{
+		{ID: "1", Sender: "wxid_a", SenderName: "Alice", Time: ts, Body: "hello"},
+		{ID: "2", Sender: "wxid_b", Time: ts.Add(time.Minute), Body: "[图片]", MsgType: MsgImage, URL: "http://127.0.0.1:5030/image/x"},
+		{ID: "3", Sender: "wxid_a", SenderName: "Alice", Time: ts.Add(2 * time.Minute), Body: "bye"},
+	})
+
+	var types []string
+	for _, e := range export.Messages {
+		types = append(types, e.Type)
+	}
+	want := []string{EventName, EventMember, EventMessage, EventMember, EventMessage, EventMessage}
+	if strings.Join(types, ",") != strings.Join(want, ",") {
+		t.Fatalf("event types = %v, want %v", types, want)
+	}
+	if export.RoomID != "!123=40chatroom:"+DefaultDomain {
+		t.Errorf("room id = %s", export.RoomID)
+	}
+	join := export.Messages[1]
+	if *join.StateKey != "@wxid__a:"+DefaultDomain || join.Content["displayname"] != "Alice" {
+		t.Errorf("join event = %+v", join)
+	}
+	image := export.Messages[4]
+	if image.Content["msgtype"] != MsgImage || image.Content["body"] != "[图片] http://127.0.0.1:5030/image/x" ||
+		image.OriginServerTS != ts.Add(time.Minute).UnixMilli() {
+		t.Errorf("image event = %+v", image)
+	}
+	if _, err := json.Marshal(export); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		bodies  []string
+		txnIDs  = map[string]bool{}
+		limited bool
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			io.WriteString(w, `{"errcode":"M_UNKNOWN_TOKEN","error":"Invalid access token"}`)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.URL.Path == "/_matrix/client/v3/directory/room/#group:example.org":
+			io.WriteString(w, `{"room_id":"!room:example.org"}`)
+		case strings.HasPrefix(r.URL.Path, "/_matrix/client/v3/rooms/!room:example.org/send/m.room.message/"):
+			// 第一次发送时限流
+			if !limited {
+				limited = true
+				w.WriteHeader(http.StatusTooManyRequests)
+				io.WriteString(w, `{"errcode":"M_LIMIT_EXCEEDED","retry_after_ms":10}`)
+				return
+			}
+			var content map[string]any
+			json.NewDecoder(r.Body).Decode(&content)
+			txnID := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+			if !txnIDs[txnID] {
+				txnIDs[txnID] = true
+				bodies = append(bodies, content["body"].(string))
+			}
+			io.WriteString(w, `{"event_id":"$e"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(&conf.Matrix{Homeserver: srv.URL, AccessToken: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	room, err := client.ResolveRoom(context.Background(), "#group:example.org")
+	if err != nil || room != "!room:example.org" {
+		t.Fatalf("ResolveRoom = %s, %v", room, err)
+	}
+	ts := time.Date(2024, 3, 1, 10, 0, 0, 0, time.Local)
+	messages := []*Message{
+		{ID: "1", Sender: "wxid_a", SenderName: "Alice", Time: ts, Body: "hello"},
+		{ID: "2", Sender: "wxid_b", Time: ts, Body: "hi"},
+	}
+	// 重复回放时服务器按 txnID 去重
+	for i := 0; i < 2; i++ {
+		if n, err := client.Replay(context.Background(), room, "123@chatroom", messages, nil); err != nil || n != 2 {
+			t.Fatalf("Replay = %d, %v", n, err)
+		}
+	}
+	want := []string{"Alice (2024-03-01 10:00:00): hello", "wxid_b (2024-03-01 10:00:00): hi"}
+	if strings.Join(bodies, "\n") != strings.Join(want, "\n") {
+		t.Errorf("bodies = %q", bodies)
+	}
+
+	bad, _ := NewClient(&conf.Matrix{Homeserver: srv.URL, AccessToken: "bad"})
+	if _, err := bad.Send(context.Background(), room, "x", map[string]any{}); err == nil || !strings.Contains(err.Error(), "M_UNKNOWN_TOKEN") {
+		t.Errorf("Send error = %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+package merge
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// applyMu 自动解密可能同时完成多个数据库，避免并发地将存档合并到工作目录
+var applyMu sync.Mutex
+
+// ArchiveDir 返回工作目录的合并存档目录，位于工作目录旁
+// 解密会用数据目录中的数据库覆盖工作目录中的同名数据库，合并的数据因此同时保存在存档目录中，解密后从存档重新合并
+func ArchiveDir(workDir string) string {
+	return filepath.Clean(workDir) + "-merged"
+}
+
+// Archive 将 srcs 合并到工作目录的存档目录，再将存档合并到工作目录
+// 返回的结果为工作目录中新增的数据
+func Archive(workDir string, srcs []string, platform string, version int) (*Result, error) {
+	if _, err := Merge(ArchiveDir(workDir), srcs, platform, version); err != nil {
+		return nil, err
+	}
+	return Apply(workDir, platform, version)
+}
+
+// Apply 将存档目录重新合并到工作目录，已有的消息和记录会被跳过，没有存档时不做任何处理
+func Apply(workDir string, platform string, version int) (*Result, error) {
+	archive := ArchiveDir(workDir)
+	if _, err := os.Stat(archive); err != nil {
+		if os.IsNotExist(err) {
+			return &Result{}, nil
+		}
+		return nil, err
+	}
+	applyMu.Lock()
+	defer applyMu.Unlock()
+	return merge(workDir, []string{archive}, platform, version)
+}
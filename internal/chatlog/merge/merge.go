@@ -0,0 +1,582 @@
+package merge
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/rs/zerolog/log"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/decrypted"
+	"github.com/DanielMao1/chatlog/pkg/util"
+	"github.com/DanielMao1/chatlog/pkg/util/zstd"
+)
+
+// Result 合并结果
+type Result struct {
+	Files    int   `json:"files"`    // 目标目录中不存在、直接复制的文件数
+	Messages int64 `json:"messages"` // 新增的消息数
+	Records  int64 `json:"records"`  // 联系人、会话等其他表新增的记录数
+}
+
+// Merge 将多个解密后的工作目录合并到 dst
+// 消息按平台对应的字段去重，其他表中 dst 已存在的记录保持不变，只补充缺失的记录
+// 合并期间 dst 不应被其他 chatlog 进程使用
+func Merge(dst string, srcs []string, platform string, version int) (*Result, error) {
+	for _, src := range srcs {
+		log.Info().Msgf("merging %s into %s", src, dst)
+	}
+	return merge(dst, srcs, platform, version)
+}
+
+func merge(dst string, srcs []string, platform string, version int) (*Result, error) {
+	s := getSpec(platform, version)
+	if s == nil {
+		return nil, fmt.Errorf("unsupported platform: %s v%d", platform, version)
+	}
+	if err := util.PrepareDir(dst); err != nil {
+		return nil, err
+	}
+
+	dstAbs, _ := filepath.Abs(dst)
+	ret := &Result{}
+	for _, src := range srcs {
+		if srcAbs, _ := filepath.Abs(src); srcAbs == dstAbs {
+			return ret, fmt.Errorf("source is the same as destination: %s", src)
+		}
+		if err := mergeDir(s, dst, src, ret); err != nil {
+			return ret, err
+		}
+	}
+	return ret, nil
+}
+
+type file struct {
+	path string
+	rel  string
+}
+
+func mergeDir(s *spec, dst, src string, ret *Result) error {
+	msgFiles := make([]file, 0)
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// 解密清单只描述源目录自身，合并来的数据库不能被当作解密生成的数据库
+		if info.IsDir() || isTempFile(path) || info.Name() == decrypted.StateFile {
+			return nil
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if s.pattern.MatchString(info.Name()) {
+			msgFiles = append(msgFiles, file{path: path, rel: rel})
+			return nil
+		}
+
+		target := filepath.Join(dst, rel)
+		if _, err := os.Stat(target); os.IsNotExist(err) {
+			ret.Files++
+			return copyFile(path, target)
+		}
+		if !strings.HasSuffix(info.Name(), ".db") {
+			return nil
+		}
+		n, err := mergeDB(target, path)
+		if err != nil {
+			return fmt.Errorf("merge %s failed: %w", rel, err)
+		}
+		ret.Records += n
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	shards, err := findShards(s, dst)
+	if err != nil {
+		return err
+	}
+	// 目标目录中还没有消息库，直接复制
+	if len(shards) == 0 {
+		for _, f := range msgFiles {
+			if err := copyFile(f.path, filepath.Join(dst, f.rel)); err != nil {
+				return err
+			}
+			ret.Files++
+		}
+		return nil
+	}
+
+	for _, f := range msgFiles {
+		var n int64
+		if len(s.timeCol) != 0 {
+			n, err = mergeTimeShards(s, shards, f.path)
+		} else {
+			n, err = mergeTableShards(s, shards, f)
+		}
+		if err != nil {
+			return fmt.Errorf("merge %s failed: %w", f.rel, err)
+		}
+		ret.Messages += n
+	}
+	return nil
+}
+
+// mergeTimeShards 按消息时间将 src 中的消息写入对应的目标分库，保持分库之间的时间范围互不重叠
+func mergeTimeShards(s *spec, shards []string, src string) (int64, error) {
+	type shard struct {
+		path  string
+		start int64
+	}
+	infos := make([]shard, 0, len(shards))
+	for _, path := range shards {
+		db, err := openDB(path)
+		if err != nil {
+			return 0, err
+		}
+		start, err := s.startTime(db)
+		db.Close()
+		if err != nil {
+			return 0, fmt.Errorf("read start time of %s failed: %w", path, err)
+		}
+		infos = append(infos, shard{path: path, start: start})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].start < infos[j].start })
+
+	var total int64
+	for i, info := range infos {
+		lo, hi := int64(math.MinInt64), int64(math.MaxInt64)
+		if i > 0 {
+			lo = info.start
+		}
+		if i < len(infos)-1 {
+			hi = infos[i+1].start
+		}
+		cond := fmt.Sprintf("s.%[1]s >= ? AND s.%[1]s < ?", quote(s.timeCol))
+		n, err := mergeMessages(s, info.path, src, nil, cond, []any{lo, hi}, func(db *sql.DB, tables []string) error {
+			// 早于第一个分库起始时间的消息写入第一个分库，需要同时调整起始时间
+			if i > 0 {
+				return nil
+			}
+			minTime := info.start
+			for _, table := range tables {
+				var t sql.NullInt64
+				if err := db.QueryRow(fmt.Sprintf("SELECT MIN(%s) FROM src.%s", quote(s.timeCol), quote(table))).Scan(&t); err != nil {
+					return err
+				}
+				if t.Valid && t.Int64 < minTime {
+					minTime = t.Int64
+				}
+			}
+			if minTime < info.start {
+				return s.setStartTime(db, minTime)
+			}
+			return nil
+		})
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// mergeTableShards 消息表不按时间分库时，同一会话的消息写入目标目录中已有该会话表的库
+func mergeTableShards(s *spec, shards []string, src file) (int64, error) {
+	owner := make(map[string]string)
+	for _, path := range shards {
+		db, err := openDB(path)
+		if err != nil {
+			return 0, err
+		}
+		tables, err := listTables(db, "main", s.table)
+		db.Close()
+		if err != nil {
+			return 0, err
+		}
+		for _, t := range tables {
+			owner[t.name] = path
+		}
+	}
+
+	// 新会话写入同名的目标库，不存在时写入第一个库
+	fallback := shards[0]
+	for _, path := range shards {
+		if filepath.Base(path) == filepath.Base(src.path) {
+			fallback = path
+		}
+	}
+
+	var total int64
+	for _, path := range shards {
+		n, err := mergeMessages(s, path, src.path, func(table string) bool {
+			target, ok := owner[table]
+			if !ok {
+				target = fallback
+			}
+			return target == path
+		}, "", nil, nil)
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// mergeMessages 将 src 中的消息表合并到 dst
+// filter 选择需要合并的表，cond 为额外的过滤条件，after 在提交前执行
+func mergeMessages(
+	s *spec,
+	dst, src string,
+	filter func(table string) bool,
+	cond string, args []any,
+	after func(db *sql.DB, tables []string) error,
+) (int64, error) {
+	db, err := openDB(dst)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	if _, err := db.Exec("ATTACH DATABASE ? AS src", src); err != nil {
+		return 0, err
+	}
+
+	tables, err := listTables(db, "src", s.table)
+	if err != nil {
+		return 0, err
+	}
+	names := make([]string, 0, len(tables))
+	for _, t := range tables {
+		if filter == nil || filter(t.name) {
+			names = append(names, t.name)
+		}
+	}
+	if len(names) == 0 {
+		return 0, nil
+	}
+
+	if _, err := db.Exec("BEGIN"); err != nil {
+		return 0, err
+	}
+	n, err := func() (int64, error) {
+		if len(s.idTable) != 0 {
+			if _, err := mergeTable(db, s.idTable, []string{s.idName}, "", nil, nil); err != nil {
+				return 0, err
+			}
+		}
+
+		var remap map[string]string
+		if len(s.idTable) != 0 {
+			// 按用户名将发送者 id 转换为目标库中的 rowid
+			remap = map[string]string{
+				s.idRef: fmt.Sprintf(
+					"IFNULL((SELECT d.rowid FROM main.%[1]s d JOIN src.%[1]s n ON d.%[2]s = n.%[2]s WHERE n.rowid = s.%[3]s), s.%[3]s)",
+					quote(s.idTable), quote(s.idName), quote(s.idRef)),
+			}
+		}
+
+		var total int64
+		for _, name := range names {
+			n, err := mergeTable(db, name, s.key, cond, args, remap)
+			if err != nil {
+				return total, err
+			}
+			total += n
+		}
+		if after != nil {
+			if err := after(db, names); err != nil {
+				return total, err
+			}
+		}
+		return total, nil
+	}()
+	if err != nil {
+		db.Exec("ROLLBACK")
+		return 0, err
+	}
+	if _, err := db.Exec("COMMIT"); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// mergeDB 合并非消息库，dst 中已存在的记录保持不变
+func mergeDB(dst, src string) (int64, error) {
+	db, err := openDB(dst)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	if _, err := db.Exec("ATTACH DATABASE ? AS src", src); err != nil {
+		return 0, err
+	}
+
+	tables, err := listTables(db, "src", "%")
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := db.Exec("BEGIN"); err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, t := range tables {
+		n, err := mergeTable(db, t.name, nil, "", nil, nil)
+		if err != nil {
+			db.Exec("ROLLBACK")
+			return 0, fmt.Errorf("table %s: %w", t.name, err)
+		}
+		total += n
+	}
+	if _, err := db.Exec("COMMIT"); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// mergeTable 将 src.table 中的记录插入 main.table，表不存在时先创建
+// key 不为空时按 key 去重，并由目标库重新分配自增主键；否则跳过完全相同或主键冲突的记录
+func mergeTable(db *sql.DB, table string, key []string, cond string, args []any, remap map[string]string) (int64, error) {
+	srcCols, err := listColumns(db, "src", table)
+	if err != nil || len(srcCols) == 0 {
+		return 0, err
+	}
+	if err := ensureTable(db, table); err != nil {
+		return 0, err
+	}
+
+	dstCols, err := listColumns(db, "main", table)
+	if err != nil {
+		return 0, err
+	}
+	exists := make(map[string]bool, len(dstCols))
+	for _, c := range dstCols {
+		exists[c.name] = true
+	}
+
+	rowid := rowidColumn(srcCols)
+	cols := make([]string, 0, len(srcCols))
+	exprs := make([]string, 0, len(srcCols))
+	for _, c := range srcCols {
+		if !exists[c.name] || (len(key) != 0 && c.name == rowid) {
+			continue
+		}
+		cols = append(cols, quote(c.name))
+		if e, ok := remap[c.name]; ok {
+			exprs = append(exprs, e)
+		} else {
+			exprs = append(exprs, "s."+quote(c.name))
+		}
+	}
+
+	conds := make([]string, 0)
+	if len(cond) != 0 {
+		conds = append(conds, cond)
+	}
+	var query string
+	if len(key) != 0 {
+		match := make([]string, 0, len(key))
+		for _, k := range key {
+			match = append(match, fmt.Sprintf("d.%[1]s IS s.%[1]s", quote(k)))
+		}
+		conds = append(conds, fmt.Sprintf("NOT EXISTS (SELECT 1 FROM main.%s d WHERE %s)", quote(table), strings.Join(match, " AND ")))
+		query = fmt.Sprintf("INSERT INTO main.%s (%s) SELECT %s FROM src.%s s WHERE %s",
+			quote(table), strings.Join(cols, ", "), strings.Join(exprs, ", "), quote(table), strings.Join(conds, " AND "))
+	} else {
+		where := ""
+		if len(conds) != 0 {
+			where = " WHERE " + strings.Join(conds, " AND ")
+		}
+		query = fmt.Sprintf("INSERT OR IGNORE INTO main.%[1]s (%[2]s) SELECT %[3]s FROM src.%[1]s s%[4]s EXCEPT SELECT %[2]s FROM main.%[1]s",
+			quote(table), strings.Join(cols, ", "), strings.Join(exprs, ", "), where)
+	}
+
+	res, err := db.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ensureTable 目标库中没有该表时，按源库的定义创建表和索引
+func ensureTable(db *sql.DB, table string) error {
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM main.sqlite_master WHERE type='table' AND name=?", table).Scan(&n); err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+
+	rows, err := db.Query("SELECT sql FROM src.sqlite_master WHERE tbl_name=? AND sql IS NOT NULL ORDER BY type DESC", table)
+	if err != nil {
+		return err
+	}
+	stmts := make([]string, 0)
+	for rows.Next() {
+		var stmt string
+		if err := rows.Scan(&stmt); err != nil {
+			rows.Close()
+			return err
+		}
+		stmts = append(stmts, stmt)
+	}
+	rows.Close()
+
+	// ORDER BY type DESC 保证先创建表（table）再创建索引（index）
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type table struct {
+	name string
+	sql  string
+}
+
+// listTables 返回普通表，跳过 sqlite 内部表以及全文索引等虚拟表和它们的影子表
+func listTables(db *sql.DB, schema, like string) ([]table, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT name, IFNULL(sql, '') FROM %s.sqlite_master WHERE type='table' AND name LIKE ? AND name NOT LIKE 'sqlite_%%'", schema), like)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	all := make([]table, 0)
+	virtual := make([]string, 0)
+	for rows.Next() {
+		var t table
+		if err := rows.Scan(&t.name, &t.sql); err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(strings.ToUpper(t.sql), "CREATE VIRTUAL") {
+			virtual = append(virtual, t.name+"_")
+			continue
+		}
+		all = append(all, t)
+	}
+
+	ret := make([]table, 0, len(all))
+	for _, t := range all {
+		shadow := false
+		for _, prefix := range virtual {
+			if strings.HasPrefix(t.name, prefix) {
+				shadow = true
+				break
+			}
+		}
+		if !shadow {
+			ret = append(ret, t)
+		}
+	}
+	return ret, rows.Err()
+}
+
+type column struct {
+	name string
+	typ  string
+	pk   int
+}
+
+func listColumns(db *sql.DB, schema, table string) ([]column, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA %s.table_info(%s)", schema, quote(table)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ret := make([]column, 0)
+	for rows.Next() {
+		var c column
+		var cid, notnull int
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &c.name, &c.typ, &notnull, &dflt, &c.pk); err != nil {
+			return nil, err
+		}
+		ret = append(ret, c)
+	}
+	return ret, rows.Err()
+}
+
+// rowidColumn 返回作为 rowid 别名的 INTEGER PRIMARY KEY 列
+func rowidColumn(cols []column) string {
+	name := ""
+	for _, c := range cols {
+		if c.pk == 0 {
+			continue
+		}
+		if c.pk > 1 || name != "" || !strings.EqualFold(c.typ, "INTEGER") {
+			return ""
+		}
+		name = c.name
+	}
+	return name
+}
+
+func findShards(s *spec, dir string) ([]string, error) {
+	ret := make([]string, 0)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && s.pattern.MatchString(info.Name()) {
+			ret = append(ret, path)
+		}
+		return nil
+	})
+	return ret, err
+}
+
+func openDB(path string) (*sql.DB, error) {
+	// 压缩的工作目录中的数据库需要先解压才能写入，下次解密时重新压缩
+	if err := zstd.InflateFile(path); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	// ATTACH 只对当前连接生效
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+func quote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func isTempFile(path string) bool {
+	return strings.HasSuffix(path, "-wal") || strings.HasSuffix(path, "-shm") || strings.HasSuffix(path, "-journal")
+}
+
+func copyFile(src, dst string) error {
+	if err := util.PrepareDir(filepath.Dir(dst)); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,273 @@
+package merge
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	schemaV4 = `
+CREATE TABLE Timestamp (timestamp INTEGER);
+CREATE TABLE Name2Id (user_name TEXT PRIMARY KEY);
+CREATE TABLE Msg_abc (local_id INTEGER PRIMARY KEY AUTOINCREMENT, server_id INTEGER, sort_seq INTEGER, real_sender_id INTEGER, create_time INTEGER, message_content TEXT);`
+
+	schemaWindowsV3 = `
+CREATE TABLE DBInfo (tableIndex INTEGER, tableVersion INTEGER, tableDesc TEXT);
+CREATE TABLE Name2ID (UsrName TEXT PRIMARY KEY);
+CREATE TABLE MSG (localId INTEGER PRIMARY KEY AUTOINCREMENT, TalkerId INTEGER, MsgSvrID INTEGER, CreateTime INTEGER, Sequence INTEGER, StrTalker TEXT, StrContent TEXT);`
+)
+
+// newDB 在 dir 下创建数据库并执行 stmts
+func newDB(t *testing.T, dir, rel string, stmts ...string) string {
+	t.Helper()
+	path := filepath.Join(dir, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	return path
+}
+
+func queryInt(t *testing.T, path, query string, args ...any) int64 {
+	t.Helper()
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var n int64
+	if err := db.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return n
+}
+
+func TestMergeV4TimeShards(t *testing.T) {
+	dst, src := t.TempDir(), t.TempDir()
+	shard0 := newDB(t, dst, "db_storage/message/message_0.db", schemaV4,
+		"INSERT INTO Timestamp VALUES (1000)",
+		"INSERT INTO Name2Id (user_name) VALUES ('a'), ('b')",
+		"INSERT INTO Msg_abc (server_id, sort_seq, real_sender_id, create_time, message_content) VALUES (1, 1000000, 1, 1000, 'old')")
+	shard1 := newDB(t, dst, "db_storage/message/message_1.db", schemaV4,
+		"INSERT INTO Timestamp VALUES (2000)",
+		"INSERT INTO Name2Id (user_name) VALUES ('a')")
+
+	// src 中 b 的 rowid 为 1，c 为 2，合并后应分别对应 dst 中的 2 和新分配的 rowid
+	newDB(t, src, "db_storage/message/message_0.db", schemaV4,
+		"INSERT INTO Timestamp VALUES (500)",
+		"INSERT INTO Name2Id (user_name) VALUES ('b'), ('c')",
+		"INSERT INTO Msg_abc (server_id, sort_seq, real_sender_id, create_time, message_content) VALUES (1, 1000000, 1, 1000, 'dup')",
+		"INSERT INTO Msg_abc (server_id, sort_seq, real_sender_id, create_time, message_content) VALUES (2, 500000, 1, 500, 'early')",
+		"INSERT INTO Msg_abc (server_id, sort_seq, real_sender_id, create_time, message_content) VALUES (3, 1500000, 2, 1500, 'middle')",
+		"INSERT INTO Msg_abc (server_id, sort_seq, real_sender_id, create_time, message_content) VALUES (4, 2500000, 2, 2500, 'late')")
+
+	ret, err := Merge(dst, []string{src}, "", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.Messages != 3 {
+		t.Fatalf("expected 3 messages added, got %d", ret.Messages)
+	}
+
+	// 早于第一个分库的消息写入第一个分库并调整起始时间
+	if n := queryInt(t, shard0, "SELECT COUNT(*) FROM Msg_abc"); n != 3 {
+		t.Fatalf("expected 3 messages in shard 0, got %d", n)
+	}
+	if n := queryInt(t, shard0, "SELECT timestamp FROM Timestamp"); n != 500 {
+		t.Fatalf("expected start time of shard 0 moved to 500, got %d", n)
+	}
+	if n := queryInt(t, shard1, "SELECT COUNT(*) FROM Msg_abc WHERE create_time = 2500"); n != 1 {
+		t.Fatalf("expected late message in shard 1, got %d", n)
+	}
+	if n := queryInt(t, shard1, "SELECT timestamp FROM Timestamp"); n != 2000 {
+		t.Fatalf("start time of shard 1 should not change, got %d", n)
+	}
+
+	// 发送者按用户名映射到目标库的 rowid
+	if n := queryInt(t, shard0, "SELECT real_sender_id FROM Msg_abc WHERE server_id = 2"); n != 2 {
+		t.Fatalf("expected sender b remapped to rowid 2, got %d", n)
+	}
+	c := queryInt(t, shard0, "SELECT rowid FROM Name2Id WHERE user_name = 'c'")
+	if n := queryInt(t, shard0, "SELECT real_sender_id FROM Msg_abc WHERE server_id = 3"); n != c {
+		t.Fatalf("expected sender c remapped to rowid %d, got %d", c, n)
+	}
+	if n := queryInt(t, shard1, "SELECT real_sender_id FROM Msg_abc WHERE server_id = 4"); n != queryInt(t, shard1, "SELECT rowid FROM Name2Id WHERE user_name = 'c'") {
+		t.Fatalf("expected sender c remapped in shard 1, got %d", n)
+	}
+
+	// 再次合并不会产生重复消息
+	ret, err = Merge(dst, []string{src}, "", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.Messages != 0 {
+		t.Fatalf("expected no messages added on second merge, got %d", ret.Messages)
+	}
+}
+
+func TestMergeWindowsV3Dedup(t *testing.T) {
+	dst, src := t.TempDir(), t.TempDir()
+	shard := newDB(t, dst, "Msg/Multi/MSG0.db", schemaWindowsV3,
+		"INSERT INTO DBInfo VALUES (0, 1000000, 'Start Time')",
+		"INSERT INTO Name2ID (UsrName) VALUES ('x')",
+		"INSERT INTO MSG (TalkerId, MsgSvrID, CreateTime, Sequence, StrTalker, StrContent) VALUES (1, 9, 1000, 1000000, 'x', 'old')")
+	newDB(t, src, "Msg/Multi/MSG0.db", schemaWindowsV3,
+		"INSERT INTO DBInfo VALUES (0, 400000, 'Start Time')",
+		"INSERT INTO Name2ID (UsrName) VALUES ('x')",
+		// 同一会话同一 Sequence 为重复消息，不同会话的相同 Sequence 不是
+		"INSERT INTO MSG (TalkerId, MsgSvrID, CreateTime, Sequence, StrTalker, StrContent) VALUES (1, 9, 1000, 1000000, 'x', 'dup')",
+		"INSERT INTO MSG (TalkerId, MsgSvrID, CreateTime, Sequence, StrTalker, StrContent) VALUES (1, 10, 1000, 1000000, 'y', 'other talker')",
+		"INSERT INTO MSG (TalkerId, MsgSvrID, CreateTime, Sequence, StrTalker, StrContent) VALUES (1, 11, 400, 400000, 'x', 'early')")
+
+	ret, err := Merge(dst, []string{src}, "windows", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.Messages != 2 {
+		t.Fatalf("expected 2 messages added, got %d", ret.Messages)
+	}
+	if n := queryInt(t, shard, "SELECT COUNT(*) FROM MSG"); n != 3 {
+		t.Fatalf("expected 3 messages, got %d", n)
+	}
+	// 起始时间以毫秒保存
+	if n := queryInt(t, shard, "SELECT tableVersion FROM DBInfo WHERE tableDesc = 'Start Time'"); n != 400000 {
+		t.Fatalf("expected start time 400000, got %d", n)
+	}
+}
+
+func TestMergeDarwinV3Dedup(t *testing.T) {
+	dst, src := t.TempDir(), t.TempDir()
+	shard0 := newDB(t, dst, "Message/msg_0.db",
+		"CREATE TABLE Chat_a (mesLocalID INTEGER PRIMARY KEY AUTOINCREMENT, mesSvrID INTEGER, msgCreateTime INTEGER, msgContent TEXT)",
+		"INSERT INTO Chat_a (mesSvrID, msgCreateTime, msgContent) VALUES (1, 100, 'old')")
+	shard1 := newDB(t, dst, "Message/msg_1.db",
+		"CREATE TABLE Chat_b (mesLocalID INTEGER PRIMARY KEY AUTOINCREMENT, mesSvrID INTEGER, msgCreateTime INTEGER, msgContent TEXT)")
+	newDB(t, src, "Message/msg_1.db",
+		"CREATE TABLE Chat_a (mesLocalID INTEGER PRIMARY KEY AUTOINCREMENT, mesSvrID INTEGER, msgCreateTime INTEGER, msgContent TEXT)",
+		"CREATE TABLE Chat_c (mesLocalID INTEGER PRIMARY KEY AUTOINCREMENT, mesSvrID INTEGER, msgCreateTime INTEGER, msgContent TEXT)",
+		"INSERT INTO Chat_a (mesSvrID, msgCreateTime, msgContent) VALUES (1, 100, 'dup')",
+		"INSERT INTO Chat_a (mesSvrID, msgCreateTime, msgContent) VALUES (1, 200, 'same server id, different time')",
+		"INSERT INTO Chat_c (mesSvrID, msgCreateTime, msgContent) VALUES (3, 300, 'new chat')")
+
+	ret, err := Merge(dst, []string{src}, "darwin", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.Messages != 2 {
+		t.Fatalf("expected 2 messages added, got %d", ret.Messages)
+	}
+	// 已有会话写入该会话所在的库，新会话写入同名的库
+	if n := queryInt(t, shard0, "SELECT COUNT(*) FROM Chat_a"); n != 2 {
+		t.Fatalf("expected 2 messages in Chat_a, got %d", n)
+	}
+	if n := queryInt(t, shard1, "SELECT COUNT(*) FROM Chat_c"); n != 1 {
+		t.Fatalf("expected new chat in msg_1.db, got %d", n)
+	}
+	if n := queryInt(t, shard0, "SELECT COUNT(*) FROM sqlite_master WHERE name = 'Chat_c'"); n != 0 {
+		t.Fatal("new chat should not be created in msg_0.db")
+	}
+}
+
+func TestMergeRollback(t *testing.T) {
+	dst, src := t.TempDir(), t.TempDir()
+	// 目标消息表有源库中没有的 NOT NULL 列，插入消息时失败
+	shard := newDB(t, dst, "db_storage/message/message_0.db",
+		"CREATE TABLE Timestamp (timestamp INTEGER)",
+		"CREATE TABLE Name2Id (user_name TEXT PRIMARY KEY)",
+		"CREATE TABLE Msg_abc (local_id INTEGER PRIMARY KEY AUTOINCREMENT, server_id INTEGER, sort_seq INTEGER, real_sender_id INTEGER, create_time INTEGER, status INTEGER NOT NULL)",
+		"INSERT INTO Timestamp VALUES (0)",
+		"INSERT INTO Name2Id (user_name) VALUES ('a')")
+	newDB(t, src, "db_storage/message/message_0.db",
+		"CREATE TABLE Timestamp (timestamp INTEGER)",
+		"CREATE TABLE Name2Id (user_name TEXT PRIMARY KEY)",
+		"CREATE TABLE Msg_abc (local_id INTEGER PRIMARY KEY AUTOINCREMENT, server_id INTEGER, sort_seq INTEGER, real_sender_id INTEGER, create_time INTEGER)",
+		"INSERT INTO Timestamp VALUES (0)",
+		"INSERT INTO Name2Id (user_name) VALUES ('b')",
+		"INSERT INTO Msg_abc (server_id, sort_seq, real_sender_id, create_time) VALUES (1, 1000, 1, 10)")
+
+	if _, err := Merge(dst, []string{src}, "", 4); err == nil {
+		t.Fatal("expected merge to fail")
+	}
+	// Name2Id 在同一事务中写入，失败后应回滚
+	if n := queryInt(t, shard, "SELECT COUNT(*) FROM Name2Id"); n != 1 {
+		t.Fatalf("expected Name2Id rolled back, got %d rows", n)
+	}
+	if n := queryInt(t, shard, "SELECT COUNT(*) FROM Msg_abc"); n != 0 {
+		t.Fatalf("expected no messages after rollback, got %d", n)
+	}
+}
+
+func TestArchiveSurvivesDecrypt(t *testing.T) {
+	root := t.TempDir()
+	workDir, src := filepath.Join(root, "work"), filepath.Join(root, "other")
+	decrypted := []string{schemaV4,
+		"INSERT INTO Timestamp VALUES (0)",
+		"INSERT INTO Name2Id (user_name) VALUES ('a')",
+		"INSERT INTO Msg_abc (server_id, sort_seq, real_sender_id, create_time) VALUES (1, 1000, 1, 10)"}
+	shard := newDB(t, workDir, "db_storage/message/message_0.db", decrypted...)
+	newDB(t, src, "db_storage/message/message_0.db", schemaV4,
+		"INSERT INTO Timestamp VALUES (0)",
+		"INSERT INTO Name2Id (user_name) VALUES ('a')",
+		"INSERT INTO Msg_abc (server_id, sort_seq, real_sender_id, create_time) VALUES (2, 2000, 1, 20)")
+
+	ret, err := Archive(workDir, []string{src}, "", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.Messages != 1 {
+		t.Fatalf("expected 1 message added, got %d", ret.Messages)
+	}
+
+	// 解密用数据目录中的数据库覆盖工作目录中的分片，合并的消息丢失
+	if err := os.Remove(shard); err != nil {
+		t.Fatal(err)
+	}
+	newDB(t, workDir, "db_storage/message/message_0.db", decrypted...)
+	if n := queryInt(t, shard, "SELECT COUNT(*) FROM Msg_abc"); n != 1 {
+		t.Fatalf("expected overwritten shard, got %d messages", n)
+	}
+
+	ret, err = Apply(workDir, "", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.Messages != 1 || queryInt(t, shard, "SELECT COUNT(*) FROM Msg_abc") != 2 {
+		t.Fatalf("expected merged message re-applied, got %d", ret.Messages)
+	}
+
+	// 没有存档的工作目录不受影响
+	if ret, err := Apply(src, "", 4); err != nil || ret.Messages != 0 {
+		t.Fatalf("expected no-op without archive, got %+v, %v", ret, err)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	for _, c := range []struct {
+		rel      string
+		platform string
+		version  int
+	}{
+		{"db_storage/message/message_0.db", "", 4},
+		{"Msg/Multi/MSG0.db", "windows", 3},
+		{"Message/msg_2.db", "darwin", 3},
+	} {
+		dir := t.TempDir()
+		newDB(t, dir, c.rel, "CREATE TABLE t (id INTEGER)")
+		if platform, version := Detect(dir); platform != c.platform || version != c.version {
+			t.Fatalf("%s: expected %s v%d, got %s v%d", c.rel, c.platform, c.version, platform, version)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package merge
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// spec 描述各平台消息数据库的结构
+type spec struct {
+	// 消息数据库文件名
+	pattern *regexp.Regexp
+	// 消息表名的 LIKE 模式
+	table string
+	// 去重字段，同一消息在不同快照中这些字段相同
+	key []string
+	// 时间字段（秒），消息库按时间分库时使用，为空表示不分库
+	timeCol string
+	// 会话/发送者 id 映射表，消息表中只记录该表的 rowid，不同快照中同一用户的 rowid 可能不同
+	idTable, idName, idRef string
+	// 读取和设置分库的起始时间（秒）
+	startTime    func(db *sql.DB) (int64, error)
+	setStartTime func(db *sql.DB, t int64) error
+}
+
+var (
+	// 微信 4.0，message_N.db 按时间分库
+	specV4 = &spec{
+		pattern: regexp.MustCompile(`^message_([0-9]?[0-9])?\.db$`),
+		table:   "Msg_%",
+		key:     []string{"sort_seq", "server_id"},
+		timeCol: "create_time",
+		idTable: "Name2Id",
+		idName:  "user_name",
+		idRef:   "real_sender_id",
+		startTime: func(db *sql.DB) (int64, error) {
+			var t int64
+			err := db.QueryRow("SELECT timestamp FROM Timestamp LIMIT 1").Scan(&t)
+			return t, err
+		},
+		setStartTime: func(db *sql.DB, t int64) error {
+			_, err := db.Exec("UPDATE Timestamp SET timestamp = ?", t)
+			return err
+		},
+	}
+
+	// Windows 微信 3.x，MSGN.db 按时间分库
+	specWindowsV3 = &spec{
+		pattern: regexp.MustCompile(`^MSG([0-9]?[0-9])?\.db$`),
+		table:   "MSG",
+		key:     []string{"StrTalker", "Sequence"},
+		timeCol: "CreateTime",
+		idTable: "Name2ID",
+		idName:  "UsrName",
+		idRef:   "TalkerId",
+		startTime: func(db *sql.DB) (int64, error) {
+			var t int64
+			err := db.QueryRow("SELECT tableVersion FROM DBInfo WHERE tableDesc LIKE '%Start Time%' LIMIT 1").Scan(&t)
+			return t / 1000, err
+		},
+		setStartTime: func(db *sql.DB, t int64) error {
+			_, err := db.Exec("UPDATE DBInfo SET tableVersion = ? WHERE tableDesc LIKE '%Start Time%'", t*1000)
+			return err
+		},
+	}
+
+	// macOS 微信 3.x，每个会话一张 Chat_ 表，分布在 msg_N.db 中
+	specDarwinV3 = &spec{
+		pattern: regexp.MustCompile(`^msg_([0-9]?[0-9])?\.db$`),
+		table:   "Chat_%",
+		key:     []string{"mesSvrID", "msgCreateTime"},
+	}
+)
+
+func getSpec(platform string, version int) *spec {
+	switch {
+	case version == 4:
+		return specV4
+	case platform == "windows" && version == 3:
+		return specWindowsV3
+	case platform == "darwin" && version == 3:
+		return specDarwinV3
+	}
+	return nil
+}
+
+// Detect 根据工作目录中的消息数据库文件推断平台和版本
+// 4.0 的工作目录结构在 macOS 与 Windows 上相同，此时 platform 为空
+func Detect(dir string) (platform string, version int) {
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		switch name := info.Name(); {
+		case specV4.pattern.MatchString(name):
+			version = 4
+		case specWindowsV3.pattern.MatchString(name):
+			platform, version = "windows", 3
+		case specDarwinV3.pattern.MatchString(name):
+			platform, version = "darwin", 3
+		default:
+			return nil
+		}
+		return filepath.SkipAll
+	})
+	return platform, version
+}
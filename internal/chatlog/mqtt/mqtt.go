@@ -0,0 +1,409 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+)
+
+// 只实现发布消息需要的 MQTT 3.1.1 报文
+const (
+	packetConnect    = 0x10
+	packetConnack    = 0x20
+	packetPublish    = 0x30
+	packetPuback     = 0x40
+	packetPubrec     = 0x50
+	packetPubrel     = 0x62
+	packetPubcomp    = 0x70
+	packetPingreq    = 0xc0
+	packetPingresp   = 0xd0
+	packetDisconnect = 0xe0
+)
+
+const (
+	// DefaultTopic 未配置 mqtt.topic 时的主题前缀
+	DefaultTopic = "chatlog"
+
+	// KeepAlive 心跳间隔
+	KeepAlive = 60 * time.Second
+
+	// Timeout 连接与等待服务器确认的超时
+	Timeout = 10 * time.Second
+)
+
+// ErrClosed 连接已断开
+var ErrClosed = errors.New("mqtt connection closed")
+
+// Will 连接异常断开时由服务器发布的遗嘱消息
+type Will struct {
+	Topic   string
+	Payload []byte
+	Retain  bool
+}
+
+// ack 服务器对 QoS 1、2 消息的确认
+type ack struct {
+	typ byte
+	id  uint16
+}
+
+// Client 只发布消息的 MQTT 客户端，断开后需重新 Dial
+type Client struct {
+	conn net.Conn
+	qos  byte
+
+	// mu 保证同时只有一个报文在写入，QoS 1、2 的消息逐条等待确认
+	mu     sync.Mutex
+	nextID uint16
+
+	acks chan ack
+	done chan struct{}
+	once sync.Once
+	err  error
+}
+
+// Topic 返回 mqtt.topic 配置的主题前缀
+func Topic(c *conf.MQTT) string {
+	if c == nil || len(c.Topic) == 0 {
+		return DefaultTopic
+	}
+	return c.Topic
+}
+
+// MessageTopic 返回聊天对象的新消息发布的主题，聊天对象中的通配符与层级分隔符替换为 _
+func MessageTopic(c *conf.MQTT, talker string) string {
+	return Topic(c) + "/messages/" + strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '+', '#':
+			return '_'
+		}
+		return r
+	}, talker)
+}
+
+// Dial 连接 MQTT 服务器，will 不为空时设置遗嘱消息
+func Dial(ctx context.Context, c *conf.MQTT, will *Will) (*Client, error) {
+	if c == nil || len(c.Broker) == 0 {
+		return nil, fmt.Errorf("mqtt.broker is not configured")
+	}
+	if c.QoS < 0 || c.QoS > 2 {
+		return nil, fmt.Errorf("invalid mqtt.qos: %d", c.QoS)
+	}
+	u, err := url.Parse(c.Broker)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mqtt.broker: %v", err)
+	}
+	useTLS := false
+	port := "1883"
+	switch u.Scheme {
+	case "tcp", "mqtt", "":
+	case "ssl", "tls", "mqtts":
+		useTLS, port = true, "8883"
+	default:
+		return nil, fmt.Errorf("unsupported mqtt.broker scheme: %s", u.Scheme)
+	}
+	host := u.Host
+	if len(host) == 0 {
+		// 没有协议时 url 将地址解析为路径
+		host = u.Path
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, port)
+	}
+
+	dialer := &net.Dialer{Timeout: Timeout}
+	var conn net.Conn
+	if useTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: u.Hostname()}}).DialContext(ctx, "tcp", host)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", host)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	clientID := c.ClientID
+	if len(clientID) == 0 {
+		b := make([]byte, 4)
+		rand.Read(b)
+		clientID = "chatlog-" + hex.EncodeToString(b)
+	}
+	conn.SetDeadline(time.Now().Add(Timeout))
+	if _, err := conn.Write(connectPacket(clientID, c.Username, c.Password, will, byte(c.QoS))); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	r := bufio.NewReader(conn)
+	typ, body, err := readPacket(r)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if typ != packetConnack || len(body) != 2 {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected mqtt packet 0x%02x", typ)
+	}
+	if body[1] != 0 {
+		conn.Close()
+		return nil, fmt.Errorf("mqtt connection refused: %s", connackReason(body[1]))
+	}
+	conn.SetDeadline(time.Time{})
+
+	client := &Client{conn: conn, qos: byte(c.QoS), acks: make(chan ack, 8), done: make(chan struct{})}
+	go client.read(r)
+	go client.ping()
+	return client, nil
+}
+
+// Publish 发布消息，QoS 1、2 时等待服务器确认
+func (c *Client) Publish(ctx context.Context, topic string, payload []byte, retain bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var id uint16
+	if c.qos > 0 {
+		c.nextID++
+		if c.nextID == 0 {
+			c.nextID = 1
+		}
+		id = c.nextID
+	}
+	if err := c.write(publishPacket(topic, payload, c.qos, retain, id)); err != nil {
+		return err
+	}
+	switch c.qos {
+	case 1:
+		return c.wait(ctx, packetPuback, id)
+	case 2:
+		if err := c.wait(ctx, packetPubrec, id); err != nil {
+			return err
+		}
+		if err := c.write([]byte{packetPubrel, 2, byte(id >> 8), byte(id)}); err != nil {
+			return err
+		}
+		return c.wait(ctx, packetPubcomp, id)
+	}
+	return nil
+}
+
+// Done 连接断开时关闭
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err 返回连接断开的原因
+func (c *Client) Err() error {
+	select {
+	case <-c.done:
+		return c.err
+	default:
+		return nil
+	}
+}
+
+// Close 发送 DISCONNECT 并断开连接，之后不会发布遗嘱消息
+func (c *Client) Close() error {
+	c.mu.Lock()
+	c.write([]byte{packetDisconnect, 0})
+	c.mu.Unlock()
+	c.close(ErrClosed)
+	return nil
+}
+
+func (c *Client) close(err error) {
+	c.once.Do(func() {
+		c.err = err
+		c.conn.Close()
+		close(c.done)
+	})
+}
+
+// write 写入报文，调用时需持有 mu
+func (c *Client) write(b []byte) error {
+	select {
+	case <-c.done:
+		return c.err
+	default:
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(Timeout))
+	if _, err := c.conn.Write(b); err != nil {
+		c.close(err)
+		return err
+	}
+	return nil
+}
+
+// wait 等待服务器的确认报文
+func (c *Client) wait(ctx context.Context, typ byte, id uint16) error {
+	timer := time.NewTimer(Timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case a := <-c.acks:
+			if a.typ == typ && a.id == id {
+				return nil
+			}
+		case <-c.done:
+			return c.err
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return fmt.Errorf("mqtt ack timeout")
+		}
+	}
+}
+
+// read 读取服务器的报文，直到连接断开
+func (c *Client) read(r *bufio.Reader) {
+	for {
+		typ, body, err := readPacket(r)
+		if err != nil {
+			c.close(err)
+			return
+		}
+		switch typ & 0xf0 {
+		case packetPuback, packetPubrec, packetPubcomp:
+			if len(body) >= 2 {
+				// 超时后才到达的确认直接丢弃
+				select {
+				case c.acks <- ack{typ: typ & 0xf0, id: binary.BigEndian.Uint16(body)}:
+				default:
+				}
+			}
+		}
+	}
+}
+
+// ping 定期发送心跳，超过两个心跳间隔没有收到任何报文时服务器会断开连接
+func (c *Client) ping() {
+	ticker := time.NewTicker(KeepAlive / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			c.mu.Lock()
+			c.write([]byte{packetPingreq, 0})
+			c.mu.Unlock()
+		}
+	}
+}
+
+func connectPacket(clientID, username, password string, will *Will, qos byte) []byte {
+	var flags byte = 0x02 // clean session
+	body := appendString(nil, "MQTT")
+	payload := appendString(nil, clientID)
+	if will != nil {
+		flags |= 0x04 | qos<<3
+		if will.Retain {
+			flags |= 0x20
+		}
+		payload = appendString(payload, will.Topic)
+		payload = appendBytes(payload, will.Payload)
+	}
+	if len(username) != 0 {
+		flags |= 0x80
+		payload = appendString(payload, username)
+		if len(password) != 0 {
+			flags |= 0x40
+			payload = appendString(payload, password)
+		}
+	}
+	body = append(body, 4, flags, byte(KeepAlive/time.Second>>8), byte(KeepAlive/time.Second))
+	return packet(packetConnect, append(body, payload...))
+}
+
+func publishPacket(topic string, payload []byte, qos byte, retain bool, id uint16) []byte {
+	typ := byte(packetPublish) | qos<<1
+	if retain {
+		typ |= 0x01
+	}
+	body := appendString(nil, topic)
+	if qos > 0 {
+		body = append(body, byte(id>>8), byte(id))
+	}
+	return packet(typ, append(body, payload...))
+}
+
+// packet 添加固定报头，剩余长度为变长编码
+func packet(typ byte, body []byte) []byte {
+	b := []byte{typ}
+	n := len(body)
+	for {
+		d := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			d |= 0x80
+		}
+		b = append(b, d)
+		if n == 0 {
+			break
+		}
+	}
+	return append(b, body...)
+}
+
+func readPacket(r *bufio.Reader) (byte, []byte, error) {
+	typ, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	n, shift := 0, 0
+	for {
+		d, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		n |= int(d&0x7f) << shift
+		if d&0x80 == 0 {
+			break
+		}
+		if shift += 7; shift > 21 {
+			return 0, nil, fmt.Errorf("invalid mqtt remaining length")
+		}
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return typ, body, nil
+}
+
+func appendString(b []byte, s string) []byte {
+	return appendBytes(b, []byte(s))
+}
+
+func appendBytes(b []byte, data []byte) []byte {
+	b = append(b, byte(len(data)>>8), byte(len(data)))
+	return append(b, data...)
+}
+
+func connackReason(code byte) string {
+	switch code {
+	case 1:
+		return "unacceptable protocol version"
+	case 2:
+		return "identifier rejected"
+	case 3:
+		return "server unavailable"
+	case 4:
+		return "bad user name or password"
+	case 5:
+		return "not authorized"
+	}
+	return fmt.Sprintf("code %d", code)
+}
@@ -0,0 +1,142 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+)
+
+// published 服务器收到的 PUBLISH 报文
+type published struct {
+	Topic   string
+	Payload string
+	QoS     byte
+	Retain  bool
+}
+
+// fakeBroker 接受一个连接，记录 CONNECT 报文与收到的消息，按 QoS 回复确认
+func fakeBroker(t *testing.T, returnCode byte) (string, chan []byte, chan published) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	connects := make(chan []byte, 1)
+	messages := make(chan published, 10)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		typ, body, err := readPacket(r)
+		if err != nil || typ != packetConnect {
+			return
+		}
+		connects <- body
+		conn.Write([]byte{packetConnack, 2, 0, returnCode})
+		for {
+			typ, body, err := readPacket(r)
+			if err != nil {
+				return
+			}
+			switch typ & 0xf0 {
+			case packetPublish:
+				n := int(binary.BigEndian.Uint16(body))
+				m := published{Topic: string(body[2 : 2+n]), QoS: typ >> 1 & 3, Retain: typ&1 == 1}
+				rest := body[2+n:]
+				var id []byte
+				if m.QoS > 0 {
+					id, rest = rest[:2], rest[2:]
+				}
+				m.Payload = string(rest)
+				messages <- m
+				switch m.QoS {
+				case 1:
+					conn.Write([]byte{packetPuback, 2, id[0], id[1]})
+				case 2:
+					conn.Write([]byte{packetPubrec, 2, id[0], id[1]})
+				}
+			case packetPubrel & 0xf0:
+				conn.Write([]byte{packetPubcomp, 2, body[0], body[1]})
+			case packetPingreq:
+				conn.Write([]byte{packetPingresp, 0})
+			case packetDisconnect:
+				return
+			}
+		}
+	}()
+	return "tcp://" + l.Addr().String(), connects, messages
+}
+
+func TestPublish(t *testing.T) {
+	for _, qos := range []int{0, 1, 2} {
+		addr, connects, messages := fakeBroker(t, 0)
+		c, err := Dial(context.Background(), &conf.MQTT{Broker: addr, Username: "user", Password: "pass", ClientID: "test", QoS: qos},
+			&Will{Topic: "chatlog/status", Payload: []byte("offline"), Retain: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		connect := <-connects
+		if flags := connect[7]; flags != 0x80|0x40|0x20|byte(qos)<<3|0x04|0x02 {
+			t.Errorf("qos %d: connect flags = %08b", qos, flags)
+		}
+		if err := c.Publish(context.Background(), "chatlog/wxid_a", []byte(`{"n":1}`), true); err != nil {
+			t.Fatalf("qos %d: %v", qos, err)
+		}
+		got := <-messages
+		want := published{Topic: "chatlog/wxid_a", Payload: `{"n":1}`, QoS: byte(qos), Retain: true}
+		if got != want {
+			t.Errorf("qos %d: published %+v, want %+v", qos, got, want)
+		}
+		c.Close()
+		if err := c.Publish(context.Background(), "chatlog/wxid_a", nil, false); err == nil {
+			t.Errorf("qos %d: publish after close should fail", qos)
+		}
+	}
+}
+
+func TestDialRefused(t *testing.T) {
+	addr, _, _ := fakeBroker(t, 5)
+	if _, err := Dial(context.Background(), &conf.MQTT{Broker: addr}, nil); err == nil || err.Error() != "mqtt connection refused: not authorized" {
+		t.Errorf("Dial error = %v", err)
+	}
+}
+
+func TestMessageTopic(t *testing.T) {
+	if got := MessageTopic(nil, "12345@chatroom"); got != "chatlog/messages/12345@chatroom" {
+		t.Errorf("MessageTopic = %q", got)
+	}
+	if got := MessageTopic(&conf.MQTT{Topic: "home/chat"}, "a/b+c#"); got != "home/chat/messages/a_b_c_" {
+		t.Errorf("MessageTopic = %q", got)
+	}
+}
+
+func TestPacketLength(t *testing.T) {
+	body := make([]byte, 321)
+	p := packet(packetPublish, body)
+	if p[1] != 0xc1 || p[2] != 0x02 || len(p) != 3+len(body) {
+		t.Errorf("remaining length = % x", p[1:3])
+	}
+	typ, got, err := readPacket(bufio.NewReader(&bytesReader{b: p}))
+	if err != nil || typ != packetPublish || len(got) != len(body) {
+		t.Errorf("readPacket = 0x%02x, %d, %v", typ, len(got), err)
+	}
+}
+
+type bytesReader struct{ b []byte }
+
+func (r *bytesReader) Read(p []byte) (int, error) {
+	if len(r.b) == 0 {
+		return 0, net.ErrClosed
+	}
+	n := copy(p, r.b)
+	r.b = r.b[n:]
+	return n, nil
+}
@@ -0,0 +1,205 @@
+package chatlog
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+	"github.com/DanielMao1/chatlog/internal/chatlog/push"
+	"github.com/DanielMao1/chatlog/internal/wechatdb"
+)
+
+// NotifySnippetLength 通知中消息摘要的最大字数
+const NotifySnippetLength = 60
+
+// Notification 关注的聊天对象的新消息通知，推送到推送目标时序列化为 JSON
+type Notification struct {
+	Rule    conf.NotifyRule `json:"-"`
+	Talker  string          `json:"talker"`
+	Name    string          `json:"name"` // 聊天对象名称
+	Sender  string          `json:"sender"`
+	Title   string          `json:"title"`
+	Snippet string          `json:"snippet"`
+	Time    time.Time       `json:"time"`
+	Link    string          `json:"link,omitempty"` // 在 HTTP 服务中查看当天聊天记录的地址
+}
+
+// ChatMessage 转换为 Slack、Discord 消息
+func (n *Notification) ChatMessage() *push.Message {
+	return &push.Message{Title: n.Name, Author: n.Sender, Text: n.Snippet, Link: n.Link}
+}
+
+// notifier 记录通知使用的增量消息流，关注的聊天对象或数据库变化时重新创建
+type notifier struct {
+	mu      sync.Mutex
+	feed    *wechatdb.Feed
+	db      *wechatdb.DB
+	talkers string
+	checked time.Time
+}
+
+// CheckNotify 自动解密完成后读取关注的聊天对象的新消息，返回需要发出的通知
+// 没有新的自动解密时直接返回，自己发送的消息不通知
+func (m *Manager) CheckNotify() []*Notification {
+	rules := m.ctx.GetNotify()
+	if len(rules) == 0 || m.db.GetDB() == nil {
+		return nil
+	}
+	last := m.LastAutoDecrypt()
+
+	n := &m.notifier
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	byTalker := make(map[string]conf.NotifyRule, len(rules))
+	talkers := make([]string, 0, len(rules))
+	for _, r := range rules {
+		byTalker[r.Talker] = r
+		talkers = append(talkers, r.Talker)
+	}
+	joined := strings.Join(talkers, ",")
+
+	// 关注列表或数据库变化时从当前时间开始，避免把历史消息当作新消息
+	if n.feed == nil || n.talkers != joined || n.db != m.db.GetDB() {
+		n.feed = m.db.NewFeed(joined, time.Now())
+		n.talkers = joined
+		n.db = m.db.GetDB()
+		n.checked = last
+		return nil
+	}
+	if !last.After(n.checked) {
+		return nil
+	}
+	n.checked = last
+
+	messages, err := n.feed.Next(m.runCtx)
+	if err != nil {
+		log.Debug().Err(err).Msg("get new messages for notification failed")
+		return nil
+	}
+
+	ret := make([]*Notification, 0)
+	for _, msg := range messages {
+		rule, ok := byTalker[msg.Talker]
+		if !ok || msg.IsSelf {
+			continue
+		}
+		name := msg.TalkerName
+		if len(name) == 0 {
+			name = msg.Talker
+		}
+		sender := msg.SenderName
+		if len(sender) == 0 {
+			sender = msg.Sender
+		}
+		title := name
+		if msg.IsChatRoom && len(sender) != 0 {
+			title = fmt.Sprintf("%s - %s", title, sender)
+		}
+		ret = append(ret, &Notification{
+			Rule:    rule,
+			Talker:  msg.Talker,
+			Name:    name,
+			Sender:  sender,
+			Title:   title,
+			Snippet: snippet(msg.PlainTextContent(), NotifySnippetLength),
+			Time:    msg.Time,
+			Link:    m.chatlogLink(msg.Talker, msg.Time, msg.Time),
+		})
+	}
+	return ret
+}
+
+// deliverNotify 将通知发送到 Telegram 与推送目标，失败时只记录日志
+func (m *Manager) deliverNotify(n *Notification) {
+	if n.Rule.Telegram {
+		m.sendTelegram(n)
+	}
+	if len(n.Rule.Destination) == 0 {
+		return
+	}
+	dest, err := push.Resolve(n.Rule.Destination, m.ctx.GetDestinations())
+	if err == nil {
+		err = push.Send(dest, n)
+	}
+	if err != nil {
+		log.Debug().Err(err).Str("destination", n.Rule.Destination).Msg("push notification failed")
+	}
+}
+
+// SetNotifyRule 添加或修改关注的聊天对象，所有通知方式都关闭时取消关注
+func (m *Manager) SetNotifyRule(rule conf.NotifyRule) error {
+	rule.Talker = strings.TrimSpace(rule.Talker)
+	rule.Destination = strings.TrimSpace(rule.Destination)
+	if len(rule.Talker) == 0 {
+		return fmt.Errorf("talker is required")
+	}
+	if c := m.ctx.GetTelegram(); rule.Telegram && (c == nil || len(c.Token) == 0) {
+		return fmt.Errorf("telegram.token is not configured")
+	}
+	if len(rule.Destination) != 0 {
+		if _, err := push.Resolve(rule.Destination, m.ctx.GetDestinations()); err != nil {
+			return err
+		}
+	}
+	enabled := rule.Bell || rule.Desktop || rule.Telegram || len(rule.Destination) != 0
+
+	rules := make([]conf.NotifyRule, 0, len(m.ctx.GetNotify())+1)
+	found := false
+	for _, r := range m.ctx.GetNotify() {
+		if r.Talker == rule.Talker {
+			found = true
+			if !enabled {
+				continue
+			}
+			r = rule
+		}
+		rules = append(rules, r)
+	}
+	if !found && enabled {
+		rules = append(rules, rule)
+	}
+	return m.ctx.SetNotify(rules)
+}
+
+// RemoveNotifyRule 取消关注聊天对象
+func (m *Manager) RemoveNotifyRule(talker string) error {
+	return m.SetNotifyRule(conf.NotifyRule{Talker: talker})
+}
+
+// chatlogLink 返回在 HTTP 服务中查看聊天对象在时间范围内聊天记录的地址，未开启 HTTP 服务时为空
+func (m *Manager) chatlogLink(talker string, start, end time.Time) string {
+	if !m.ctx.HTTPEnabled {
+		return ""
+	}
+	host := m.ctx.GetHTTPAddr()
+	if w := m.ctx.GetWebhook(); w != nil && len(w.Host) != 0 {
+		host = w.Host
+	}
+	// 监听所有地址时使用本机地址
+	if h, port, err := net.SplitHostPort(host); err == nil && (len(h) == 0 || h == "0.0.0.0" || h == "::") {
+		host = net.JoinHostPort("127.0.0.1", port)
+	}
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	q := url.Values{}
+	q.Set("talker", talker)
+	q.Set("time", start.Format("2006-01-02")+"~"+end.Format("2006-01-02"))
+	return strings.TrimRight(host, "/") + "/api/v1/chatlog?" + q.Encode()
+}
+
+// snippet 将消息内容合并为一行，超过 n 个字时截断
+func snippet(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if r := []rune(text); len(r) > n {
+		return string(r[:n]) + "…"
+	}
+	return text
+}
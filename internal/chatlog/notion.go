@@ -0,0 +1,208 @@
+package chatlog
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+	"github.com/DanielMao1/chatlog/internal/chatlog/database"
+	"github.com/DanielMao1/chatlog/internal/chatlog/llm"
+	"github.com/DanielMao1/chatlog/internal/chatlog/notion"
+	"github.com/DanielMao1/chatlog/internal/chatlog/summarize"
+	"github.com/DanielMao1/chatlog/internal/errors"
+	"github.com/DanielMao1/chatlog/internal/model"
+	"github.com/DanielMao1/chatlog/pkg/util"
+)
+
+// mediaLink 消息内容中图片、语音、视频的链接
+var mediaLink = regexp.MustCompile(`\]\((https?://[^)\s]+)\)`)
+
+// CommandNotion 将聊天对象在时间范围内的消息写入 Notion 数据库，每条消息一个页面
+// digest 为 true 时改为每天写入一篇总结，配置了 llm 时由大模型总结；已写入的消息与总结记录在工作目录中，重新运行不会重复写入
+// 返回写入的页面数量
+func (m *Manager) CommandNotion(configPath string, cmdConf map[string]any, talker, timeRange string, digest bool, onProgress func(written, total int)) (int, error) {
+
+	var err error
+	m.sc, m.scm, err = conf.LoadServiceConfig(configPath, cmdConf)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(m.sc.GetWorkDir()) == 0 {
+		return 0, errors.ConfigRequired("workDir")
+	}
+	if len(talker) == 0 {
+		return 0, fmt.Errorf("talker is required")
+	}
+	client, err := notion.NewClient(m.sc.GetNotion())
+	if err != nil {
+		return 0, err
+	}
+
+	if len(timeRange) == 0 {
+		timeRange = "all"
+		if digest {
+			timeRange = "yesterday"
+		}
+	}
+	start, end, ok := util.TimeRangeOf(timeRange)
+	if !ok {
+		return 0, fmt.Errorf("invalid time range: %s", timeRange)
+	}
+
+	m.db = database.NewService(m.sc)
+	if err := m.db.Start(); err != nil {
+		return 0, err
+	}
+	defer m.db.Stop()
+
+	messages, err := m.db.GetMessages(m.runCtx, start, end, talker, "", "", 0, 0)
+	if err != nil {
+		return 0, err
+	}
+	if len(messages) == 0 {
+		return 0, nil
+	}
+
+	ctx := m.runCtx
+	if err := client.Open(ctx); err != nil {
+		return 0, err
+	}
+	state, err := notion.LoadState(filepath.Join(m.sc.GetWorkDir(), notion.StateFile))
+	if err != nil {
+		return 0, err
+	}
+	// 按名称查询时使用消息中的聊天对象 ID 记录进度
+	cursor := state.Cursor(client.Database(), messages[0].Talker)
+
+	var n int
+	if digest {
+		n, err = m.notionDigests(ctx, client, cursor, messages, onProgress)
+	} else {
+		n, err = m.notionMessages(ctx, client, cursor, messages, onProgress)
+	}
+	if n != 0 {
+		if serr := state.Save(); serr != nil && err == nil {
+			err = serr
+		}
+	}
+	return n, err
+}
+
+// notionMessages 将 cursor 之后的每条消息写为一个页面
+func (m *Manager) notionMessages(ctx context.Context, client *notion.Client, cursor *notion.Cursor, messages []*model.Message, onProgress func(written, total int)) (int, error) {
+	pending := make([]*model.Message, 0, len(messages))
+	for _, msg := range messages {
+		if msg.Seq > cursor.Seq {
+			pending = append(pending, msg)
+		}
+	}
+
+	host := m.mediaHost()
+	for i, msg := range pending {
+		msg.SetContent("host", host)
+		content := msg.PlainTextContent()
+		sender := msg.SenderName
+		if len(sender) == 0 {
+			sender = msg.Sender
+		}
+		if msg.IsSelf {
+			sender = "我"
+		}
+		page := &notion.Page{
+			Title:  content,
+			Talker: notionTalkerName(msg),
+			Sender: sender,
+			Time:   msg.Time,
+		}
+		if match := mediaLink.FindStringSubmatch(content); match != nil {
+			page.Media = match[1]
+		}
+		if err := client.Create(ctx, page); err != nil {
+			return i, err
+		}
+		cursor.Seq, cursor.Time = msg.Seq, msg.Time
+		if onProgress != nil {
+			onProgress(i+1, len(pending))
+		}
+	}
+	return len(pending), nil
+}
+
+// notionDigests 将每天的消息总结为一个页面，跳过已写入总结的日期
+// 当天的消息还不完整，写入后不记录进度，之后可以再次写入当天的总结
+func (m *Manager) notionDigests(ctx context.Context, client *notion.Client, cursor *notion.Cursor, messages []*model.Message, onProgress func(written, total int)) (int, error) {
+	provider, err := llm.New(m.sc.GetLLM())
+	if err != nil {
+		return 0, err
+	}
+
+	var days []string
+	byDay := make(map[string][]*model.Message)
+	for _, msg := range messages {
+		day := msg.Time.Format("2006-01-02")
+		if day <= cursor.Day {
+			continue
+		}
+		if _, ok := byDay[day]; !ok {
+			days = append(days, day)
+		}
+		byDay[day] = append(byDay[day], msg)
+	}
+
+	today := time.Now().Format("2006-01-02")
+	for i, day := range days {
+		list := byDay[day]
+		dayStart, _ := time.ParseInLocation("2006-01-02", day, time.Local)
+		name := notionTalkerName(list[0])
+		payload := summarize.Build(list[0].Talker, name, dayStart, list, list[len(list)-1].Time)
+		if provider != nil {
+			if err := summarize.Digest(ctx, provider, m.sc.GetLLM(), payload); err != nil {
+				return i, fmt.Errorf("%s: %w", day, err)
+			}
+		}
+		page := &notion.Page{
+			Title:   fmt.Sprintf("%s %s（%d 条消息）", name, day, payload.MessageCount),
+			Talker:  name,
+			Time:    dayStart,
+			AllDay:  true,
+			Body:    []string{payload.Summary},
+			Bullets: payload.Highlights,
+		}
+		if err := client.Create(ctx, page); err != nil {
+			return i, fmt.Errorf("%s: %w", day, err)
+		}
+		if day < today {
+			cursor.Day = day
+		}
+		if onProgress != nil {
+			onProgress(i+1, len(days))
+		}
+	}
+	return len(days), nil
+}
+
+// mediaHost 返回消息中媒体链接使用的地址，与 webhook 推送的链接一致
+func (m *Manager) mediaHost() string {
+	host := m.sc.GetHTTPAddr()
+	if w := m.sc.GetWebhook(); w != nil && len(w.Host) != 0 {
+		host = w.Host
+	}
+	// 监听所有地址时使用本机地址
+	if h, port, err := net.SplitHostPort(host); err == nil && (len(h) == 0 || h == "0.0.0.0" || h == "::") {
+		host = net.JoinHostPort("127.0.0.1", port)
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(host, "http://"), "https://")
+}
+
+func notionTalkerName(msg *model.Message) string {
+	if len(msg.TalkerName) != 0 {
+		return msg.TalkerName
+	}
+	return msg.Talker
+}
@@ -0,0 +1,273 @@
+package notion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+)
+
+const (
+	// DefaultURL Notion API 地址
+	DefaultURL = "https://api.notion.com"
+
+	// APIVersion 请求使用的 Notion-Version
+	APIVersion = "2022-06-28"
+
+	// Interval 连续创建页面的间隔，Notion 限制每个 integration 平均每秒 3 个请求
+	Interval = 350 * time.Millisecond
+
+	// DefaultRetryAfter 服务器限流但没有返回等待时间时的重试间隔
+	DefaultRetryAfter = 2 * time.Second
+
+	// MaxRetries 限流时最多重试的次数
+	MaxRetries = 10
+)
+
+// Client 使用 integration token 调用 Notion API 向数据库添加页面
+type Client struct {
+	url      string
+	token    string
+	database string
+	names    conf.NotionProperties
+	client   *http.Client
+
+	title  string            // 数据库的标题属性名称
+	schema map[string]string // 数据库属性名称到类型的映射
+	last   time.Time         // 上一次创建页面的时间
+}
+
+// NewClient 创建客户端
+func NewClient(c *conf.Notion) (*Client, error) {
+	if c == nil || len(c.Token) == 0 {
+		return nil, fmt.Errorf("notion.token is required")
+	}
+	if len(c.DatabaseID) == 0 {
+		return nil, fmt.Errorf("notion.database_id is required")
+	}
+	base := c.URL
+	if len(base) == 0 {
+		base = DefaultURL
+	}
+	u, err := url.Parse(base)
+	if err != nil || len(u.Host) == 0 {
+		return nil, fmt.Errorf("invalid notion.url: %s", base)
+	}
+	names := c.Properties
+	for _, p := range []struct {
+		name *string
+		def  string
+	}{
+		{&names.Talker, DefaultTalker},
+		{&names.Sender, DefaultSender},
+		{&names.Time, DefaultTime},
+		{&names.Media, DefaultMedia},
+	} {
+		if len(*p.name) == 0 {
+			*p.name = p.def
+		}
+	}
+	return &Client{
+		url:      strings.TrimRight(base, "/"),
+		token:    c.Token,
+		database: strings.ReplaceAll(c.DatabaseID, "-", ""),
+		names:    names,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Database 返回数据库 ID
+func (c *Client) Database() string {
+	return c.database
+}
+
+// Open 读取数据库的属性，之后只写入数据库中存在的属性
+func (c *Client) Open(ctx context.Context) error {
+	var resp struct {
+		Properties map[string]struct {
+			Type string `json:"type"`
+		} `json:"properties"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/v1/databases/"+url.PathEscape(c.database), nil, &resp); err != nil {
+		return err
+	}
+	c.schema = make(map[string]string, len(resp.Properties))
+	for name, p := range resp.Properties {
+		c.schema[name] = p.Type
+		if p.Type == "title" {
+			c.title = name
+		}
+	}
+	if len(c.title) == 0 {
+		return fmt.Errorf("notion database %s has no title property", c.database)
+	}
+	return nil
+}
+
+// Create 在数据库中添加页面，连续调用时按 Interval 控制请求频率
+func (c *Client) Create(ctx context.Context, p *Page) error {
+	if c.schema == nil {
+		if err := c.Open(ctx); err != nil {
+			return err
+		}
+	}
+	if wait := Interval - time.Since(c.last); wait > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	c.last = time.Now()
+
+	body := map[string]any{
+		"parent":     map[string]any{"database_id": c.database},
+		"properties": c.properties(p),
+	}
+	if children := blocks(p); len(children) != 0 {
+		body["children"] = children
+	}
+	return c.do(ctx, http.MethodPost, "/v1/pages", body, nil)
+}
+
+// properties 按数据库中属性的类型转换页面字段，不存在或类型不支持的属性不写入
+func (c *Client) properties(p *Page) map[string]any {
+	title := p.Title
+	if len(strings.TrimSpace(title)) == 0 {
+		title = "-"
+	}
+	props := map[string]any{c.title: map[string]any{"title": richText(title)}}
+
+	timeText := p.Time.Format("2006-01-02 15:04:05")
+	date := map[string]any{"start": p.Time.Format(time.RFC3339)}
+	if p.AllDay {
+		timeText = p.Time.Format("2006-01-02")
+		date = map[string]any{"start": timeText}
+	}
+	set := func(name, value string) {
+		if len(value) == 0 || name == c.title {
+			return
+		}
+		switch c.schema[name] {
+		case "rich_text":
+			props[name] = map[string]any{"rich_text": richText(value)}
+		case "select":
+			// 选项名称不能包含逗号
+			props[name] = map[string]any{"select": map[string]any{"name": strings.ReplaceAll(value, ",", " ")}}
+		case "url":
+			props[name] = map[string]any{"url": value}
+		case "date":
+			props[name] = map[string]any{"date": date}
+		}
+	}
+	set(c.names.Talker, p.Talker)
+	set(c.names.Sender, p.Sender)
+	if !p.Time.IsZero() {
+		set(c.names.Time, timeText)
+	}
+	set(c.names.Media, p.Media)
+	return props
+}
+
+// blocks 将正文转换为段落与列表块，超出 MaxBlocks 的部分不写入
+func blocks(p *Page) []map[string]any {
+	var ret []map[string]any
+	add := func(kind, text string) {
+		if len(ret) < MaxBlocks {
+			ret = append(ret, map[string]any{
+				"object": "block",
+				"type":   kind,
+				kind:     map[string]any{"rich_text": richText(text)},
+			})
+		}
+	}
+	for _, text := range p.Body {
+		for _, para := range Paragraphs(text) {
+			add("paragraph", para)
+		}
+	}
+	for _, text := range p.Bullets {
+		add("bulleted_list_item", text)
+	}
+	return ret
+}
+
+// richText 将文本拆分为不超过 MaxText 的 rich text
+func richText(text string) []map[string]any {
+	var ret []map[string]any
+	r := []rune(text)
+	for len(r) > 0 {
+		n := min(len(r), MaxText)
+		ret = append(ret, map[string]any{"type": "text", "text": map[string]any{"content": string(r[:n])}})
+		r = r[n:]
+	}
+	return ret
+}
+
+// do 发送请求，服务器限流（429）时按 Retry-After 等待后重试
+func (c *Client) do(ctx context.Context, method, path string, body any, result any) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	for retry := 0; ; retry++ {
+		req, err := http.NewRequestWithContext(ctx, method, c.url+path, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		req.Header.Set("Notion-Version", APIVersion)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return err
+		}
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			if result == nil {
+				return nil
+			}
+			return json.Unmarshal(respBody, result)
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests && retry < MaxRetries {
+			wait := DefaultRetryAfter
+			if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s > 0 {
+				wait = time.Duration(s) * time.Second
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+			continue
+		}
+		var e struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(respBody, &e)
+		if len(e.Code) != 0 {
+			return fmt.Errorf("notion %s: %s", e.Code, e.Message)
+		}
+		return fmt.Errorf("notion status %s", resp.Status)
+	}
+}
@@ -0,0 +1,119 @@
+// Package notion 通过 Notion API 将聊天记录与每日总结写入 Notion 数据库，每条消息或每天的总结为一个页面
+package notion
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// StateFile 工作目录中记录每个聊天对象写入进度的文件
+	StateFile = ".chatlog-notion.json"
+
+	// MaxText 一段 rich text 的最大长度
+	MaxText = 2000
+
+	// MaxBlocks 创建页面时最多附带的内容块数量
+	MaxBlocks = 100
+)
+
+// 数据库属性的默认名称
+const (
+	DefaultTalker = "Talker"
+	DefaultSender = "Sender"
+	DefaultTime   = "Time"
+	DefaultMedia  = "Media"
+)
+
+// Page 写入数据库的一个页面
+type Page struct {
+	Title   string // 写入标题属性，消息内容或总结标题
+	Talker  string
+	Sender  string
+	Time    time.Time
+	AllDay  bool     // Time 只写入日期，用于每日总结
+	Media   string   // 图片、语音、视频的链接
+	Body    []string // 页面正文的段落
+	Bullets []string // 页面正文中段落之后的列表
+}
+
+// Cursor 一个聊天对象写入数据库的进度
+type Cursor struct {
+	Seq  int64     `json:"seq"`  // 已写入的最后一条消息
+	Time time.Time `json:"time"` // 已写入的最后一条消息的时间
+	Day  string    `json:"day"`  // 已写入总结的最后一天，格式为 2006-01-02
+}
+
+// State 写入进度，key 为数据库 ID 与聊天对象，同一聊天对象可以写入不同的数据库
+type State struct {
+	path    string
+	Cursors map[string]*Cursor `json:"cursors"`
+}
+
+// LoadState 读取写入进度，文件不存在时返回空的进度
+func LoadState(path string) (*State, error) {
+	s := &State{path: path, Cursors: make(map[string]*Cursor)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return s, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", StateFile, err)
+	}
+	if s.Cursors == nil {
+		s.Cursors = make(map[string]*Cursor)
+	}
+	return s, nil
+}
+
+// Cursor 返回聊天对象写入数据库的进度，没有记录时创建
+func (s *State) Cursor(database, talker string) *Cursor {
+	key := database + "/" + talker
+	c, ok := s.Cursors[key]
+	if !ok {
+		c = &Cursor{}
+		s.Cursors[key] = c
+	}
+	return c
+}
+
+// Save 保存写入进度
+func (s *State) Save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0644)
+}
+
+// Paragraphs 将文本按行合并为不超过 MaxText 的段落，超长的行拆分为多段
+func Paragraphs(text string) []string {
+	var ret []string
+	var cur []rune
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		r := []rune(line)
+		if len(cur) != 0 && len(cur)+1+len(r) > MaxText {
+			ret = append(ret, string(cur))
+			cur = nil
+		}
+		if len(cur) != 0 {
+			cur = append(cur, '\n')
+		}
+		cur = append(cur, r...)
+		for len(cur) > MaxText {
+			ret = append(ret, string(cur[:MaxText]))
+			cur = cur[MaxText:]
+		}
+	}
+	if len(strings.TrimSpace(string(cur))) != 0 {
+		ret = append(ret, string(cur))
+	}
+	return ret
+}
@@ -0,0 +1,139 @@
+package notion
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+)
+
+func TestCreate(t *testing.T) {
+	var mu sync.Mutex
+	var pages []map[string]any
+	limited := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("Notion-Version") != APIVersion {
+			w.WriteHeader(http.StatusUnauthorized)
+			io.WriteString(w, `{"code":"unauthorized","message":"API token is invalid."}`)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/databases/abc123":
+			io.WriteString(w, `{"properties":{
+				"Content":{"type":"title"},
+				"Talker":{"type":"select"},
+				"From":{"type":"rich_text"},
+				"Time":{"type":"date"},
+				"Media":{"type":"url"},
+				"Tags":{"type":"multi_select"}}}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/pages":
+			mu.Lock()
+			defer mu.Unlock()
+			if !limited {
+				limited = true
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			var page map[string]any
+			json.NewDecoder(r.Body).Decode(&page)
+			pages = append(pages, page)
+			io.WriteString(w, `{"object":"page","id":"p1"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(&conf.Notion{
+		Token:      "secret",
+		DatabaseID: "abc-123",
+		URL:        srv.URL,
+		Properties: conf.NotionProperties{Sender: "From"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)
+	err = c.Create(context.Background(), &Page{
+		Title:  "看一下这张图",
+		Talker: "项目群, 2024",
+		Sender: "Alice",
+		Time:   ts,
+		Media:  "http://127.0.0.1:5030/image/abc",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 1 {
+		t.Fatalf("got %d pages, want 1", len(pages))
+	}
+
+	data, _ := json.Marshal(pages[0]["properties"])
+	props := string(data)
+	for _, want := range []string{
+		`"Content":{"title":[{"text":{"content":"看一下这张图"},"type":"text"}]}`,
+		`"Talker":{"select":{"name":"项目群  2024"}}`,
+		`"From":{"rich_text":[{"text":{"content":"Alice"},"type":"text"}]}`,
+		`"Time":{"date":{"start":"2024-03-01T10:30:00Z"}}`,
+		`"Media":{"url":"http://127.0.0.1:5030/image/abc"}`,
+	} {
+		if !strings.Contains(props, want) {
+			t.Errorf("properties %s missing %s", props, want)
+		}
+	}
+	if strings.Contains(props, "Tags") {
+		t.Errorf("unsupported property written: %s", props)
+	}
+
+	bad, _ := NewClient(&conf.Notion{Token: "wrong", DatabaseID: "abc123", URL: srv.URL})
+	if err := bad.Open(context.Background()); err == nil || !strings.Contains(err.Error(), "unauthorized") {
+		t.Errorf("got %v, want unauthorized error", err)
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	long := strings.Repeat("字", MaxText+10)
+	got := blocks(&Page{Body: []string{"第一行\n第二行", long}, Bullets: []string{"重点"}})
+	if len(got) != 4 {
+		t.Fatalf("got %d blocks, want 4", len(got))
+	}
+	if got[0]["type"] != "paragraph" || got[3]["type"] != "bulleted_list_item" {
+		t.Errorf("unexpected block types: %v, %v", got[0]["type"], got[3]["type"])
+	}
+	if n := len(richText(long)); n != 2 {
+		t.Errorf("got %d rich text parts, want 2", n)
+	}
+}
+
+func TestState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), StateFile)
+	s, err := LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Cursor("db", "wxid_a").Seq = 42
+	s.Cursor("db", "wxid_a").Day = "2024-03-01"
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := s.Cursor("db", "wxid_a"); c.Seq != 42 || c.Day != "2024-03-01" {
+		t.Errorf("got %+v", c)
+	}
+	if c := s.Cursor("other", "wxid_a"); c.Seq != 0 {
+		t.Errorf("cursor of another database: %+v", c)
+	}
+}
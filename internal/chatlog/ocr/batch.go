@@ -0,0 +1,144 @@
+package ocr
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultInterval 默认批量识别新图片的间隔
+const DefaultInterval = 10 * time.Minute
+
+// Image 一条图片消息
+type Image struct {
+	Talker string
+	Seq    int64
+	Time   time.Time
+	Keys   []string // 获取图片数据的键，按优先顺序排列
+}
+
+// Source 提供图片消息与解密后的图片
+type Source interface {
+	// Images 返回聊天对象从 since 开始按时间排列的图片消息
+	Images(ctx context.Context, talker string, since time.Time) ([]*Image, error)
+	// Data 返回解密后的图片与图片格式，如 jpg、png
+	Data(ctx context.Context, img *Image) ([]byte, string, error)
+}
+
+// Service 识别图片消息中的文字并保存结果，同一张图片只识别一次
+type Service struct {
+	store      *Store
+	recognizer Recognizer
+	source     Source
+
+	// mu 保证同时只有一次批量识别
+	mu sync.Mutex
+}
+
+// NewService 创建识别服务
+func NewService(store *Store, recognizer Recognizer, source Source) *Service {
+	return &Service{store: store, recognizer: recognizer, source: source}
+}
+
+// Store 返回保存识别结果的数据库
+func (s *Service) Store() *Store {
+	return s.store
+}
+
+// Recognize 返回图片的识别结果，没有保存的结果时识别并保存
+func (s *Service) Recognize(ctx context.Context, img *Image) (*Text, error) {
+	if t, err := s.store.Get(img.Talker, img.Seq); err != nil || t != nil {
+		return t, err
+	}
+	data, ext, err := s.source.Data(ctx, img)
+	if err != nil {
+		return nil, err
+	}
+	return s.recognize(ctx, img, data, ext)
+}
+
+// recognize 识别图片并保存结果，没有文字的图片也保存空结果，避免重复识别
+func (s *Service) recognize(ctx context.Context, img *Image, data []byte, ext string) (*Text, error) {
+	text, err := s.recognizer.Recognize(ctx, data, ext)
+	if err != nil {
+		return nil, err
+	}
+	t := &Text{
+		Talker:   img.Talker,
+		Seq:      img.Seq,
+		Time:     img.Time,
+		Image:    strings.Join(img.Keys, ","),
+		Text:     text,
+		Provider: s.recognizer.Name(),
+		Created:  time.Now(),
+	}
+	return t, s.store.Put(t)
+}
+
+// Run 每隔 interval 识别聊天对象的新图片，直到 ctx 结束
+func (s *Service) Run(ctx context.Context, talkers []string, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.Update(ctx, talkers); err != nil {
+			log.Debug().Err(err).Msg("recognize image messages failed")
+		} else if n > 0 {
+			log.Info().Int("count", n).Msg("image messages recognized")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Update 识别聊天对象上次批量识别之后的图片，返回新识别的数量
+// 读取不到的图片（未下载或无法解密）跳过；识别失败时停在该张图片，下次从该张继续
+func (s *Service) Update(ctx context.Context, talkers []string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, talker := range talkers {
+		seq, since, err := s.store.Cursor(talker)
+		if err != nil {
+			return count, err
+		}
+		images, err := s.source.Images(ctx, talker, since)
+		if err != nil {
+			return count, fmt.Errorf("%s: %w", talker, err)
+		}
+		for _, img := range images {
+			if img.Seq <= seq {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return count, err
+			}
+			if t, err := s.store.Get(img.Talker, img.Seq); err != nil {
+				return count, err
+			} else if t == nil {
+				data, ext, err := s.source.Data(ctx, img)
+				if err != nil {
+					log.Debug().Err(err).Str("talker", talker).Int64("seq", img.Seq).Msg("skip image without data")
+				} else if _, err := s.recognize(ctx, img, data, ext); err != nil {
+					return count, fmt.Errorf("%s %d: %w", talker, img.Seq, err)
+				} else {
+					count++
+				}
+			}
+			if err := s.store.SetCursor(talker, img.Seq, img.Time); err != nil {
+				return count, err
+			}
+		}
+	}
+	return count, nil
+}
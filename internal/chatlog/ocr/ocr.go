@@ -0,0 +1,211 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+)
+
+const (
+	ProviderNone      = "none"      // 不识别
+	ProviderTesseract = "tesseract" // 本地 tesseract 可执行文件
+	ProviderOpenAI    = "openai"    // OpenAI 兼容的多模态 /chat/completions 接口
+
+	// DefaultTimeout 未配置 timeout 时单张图片的识别超时
+	DefaultTimeout = time.Minute
+
+	// DefaultBinary tesseract 未配置 binary 时在 PATH 中查找的可执行文件
+	DefaultBinary = "tesseract"
+
+	// DefaultLanguage tesseract 未配置 language 时使用的语言
+	DefaultLanguage = "chi_sim+eng"
+
+	// Prompt 使用多模态模型识别图片文字时的提示词
+	Prompt = "识别图片中的所有文字，按原有的阅读顺序逐行输出，不要翻译、解释或补充。图片中没有文字时不输出任何内容。"
+)
+
+// Recognizer 图片文字识别接口
+type Recognizer interface {
+	// Name 返回接口名称与模型，记录在识别结果中
+	Name() string
+	// Recognize 识别图片中的文字，ext 为图片格式，如 jpg、png
+	Recognize(ctx context.Context, image []byte, ext string) (string, error)
+}
+
+// New 按配置创建识别接口，未配置或 provider 为 none 时返回 nil
+func New(c *conf.OCR) (Recognizer, error) {
+	if c == nil {
+		return nil, nil
+	}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	switch strings.ToLower(strings.TrimSpace(c.Provider)) {
+	case "", ProviderNone:
+		return nil, nil
+	case ProviderTesseract:
+		binary := c.Binary
+		if len(binary) == 0 {
+			binary = DefaultBinary
+		}
+		path, err := exec.LookPath(binary)
+		if err != nil {
+			return nil, fmt.Errorf("tesseract binary %s not found: %v", binary, err)
+		}
+		language := c.Language
+		if len(language) == 0 {
+			language = DefaultLanguage
+		}
+		return &tesseract{binary: path, language: language, timeout: timeout}, nil
+	case ProviderOpenAI:
+		if len(c.Model) == 0 {
+			return nil, fmt.Errorf("ocr.model is required for provider openai, e.g. gpt-4o-mini")
+		}
+		url := c.URL
+		if len(url) == 0 {
+			url = "https://api.openai.com/v1"
+		}
+		return &openAI{url: strings.TrimSuffix(url, "/"), model: c.Model, apiKey: c.APIKey,
+			client: &http.Client{Timeout: timeout}}, nil
+	}
+	return nil, fmt.Errorf("invalid ocr.provider %q, use tesseract, openai or none", c.Provider)
+}
+
+// tesseract 调用本地 tesseract 识别，图片写入临时文件，结果从标准输出读取
+type tesseract struct {
+	binary   string
+	language string
+	timeout  time.Duration
+}
+
+func (t *tesseract) Name() string {
+	return ProviderTesseract + "/" + t.language
+}
+
+func (t *tesseract) Recognize(ctx context.Context, image []byte, ext string) (string, error) {
+	f, err := os.CreateTemp("", "chatlog-ocr-*."+ext)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(image)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, t.binary, f.Name(), "stdout", "-l", t.language)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if r := []rune(msg); len(r) > 200 {
+			msg = string(r[len(r)-200:])
+		}
+		return "", fmt.Errorf("tesseract failed: %v, %s", err, msg)
+	}
+	return Clean(string(out)), nil
+}
+
+// openAI OpenAI 兼容的多模态接口，图片以 data URL 发送
+type openAI struct {
+	url    string
+	model  string
+	apiKey string
+	client *http.Client
+}
+
+func (o *openAI) Name() string {
+	return ProviderOpenAI + "/" + o.model
+}
+
+func (o *openAI) Recognize(ctx context.Context, image []byte, ext string) (string, error) {
+	mime := "image/" + ext
+	if ext == "jpg" {
+		mime = "image/jpeg"
+	}
+	body, err := json.Marshal(map[string]any{
+		"model": o.model,
+		"messages": []map[string]any{{
+			"role": "user",
+			"content": []map[string]any{
+				{"type": "text", "text": Prompt},
+				{"type": "image_url", "image_url": map[string]string{
+					"url": "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(image),
+				}},
+			},
+		}},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	url := o.url + "/chat/completions"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(o.apiKey) != 0 {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg := strings.TrimSpace(string(data))
+		if r := []rune(msg); len(r) > 200 {
+			msg = string(r[:200]) + "..."
+		}
+		return "", fmt.Errorf("post to %s failed, status code: %d, %s", url, resp.StatusCode, msg)
+	}
+	var result struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return "", fmt.Errorf("invalid response from %s: %v", url, err)
+	}
+	if len(result.Choices) == 0 {
+		return "", fmt.Errorf("empty response from %s", o.Name())
+	}
+	return Clean(result.Choices[0].Message.Content), nil
+}
+
+// Clean 去掉识别结果中每行首尾的空白与空行
+func Clean(text string) string {
+	lines := strings.Split(text, "\n")
+	ret := lines[:0]
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); len(line) != 0 {
+			ret = append(ret, line)
+		}
+	}
+	return strings.Join(ret, "\n")
+}
@@ -0,0 +1,158 @@
+package ocr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+)
+
+// fakeRecognizer 返回图片内容作为识别结果
+type fakeRecognizer struct {
+	calls int
+	fail  bool
+}
+
+func (f *fakeRecognizer) Name() string { return "fake" }
+
+func (f *fakeRecognizer) Recognize(ctx context.Context, image []byte, ext string) (string, error) {
+	f.calls++
+	if f.fail {
+		return "", fmt.Errorf("service unavailable")
+	}
+	return string(image), nil
+}
+
+// fakeSource 每个聊天对象的图片，没有键的图片没有数据
+type fakeSource map[string][]*Image
+
+func (f fakeSource) Images(ctx context.Context, talker string, since time.Time) ([]*Image, error) {
+	var ret []*Image
+	for _, img := range f[talker] {
+		if !img.Time.Before(since) {
+			ret = append(ret, img)
+		}
+	}
+	return ret, nil
+}
+
+func (f fakeSource) Data(ctx context.Context, img *Image) ([]byte, string, error) {
+	if len(img.Keys) == 0 {
+		return nil, "", fmt.Errorf("image not found")
+	}
+	return []byte("text of " + img.Keys[0]), "jpg", nil
+}
+
+func TestUpdate(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), TextFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	ts := time.Unix(1700000000, 0)
+	source := fakeSource{"a": {
+		{Talker: "a", Seq: 1700000000000, Time: ts, Keys: []string{"i1", "md5"}},
+		{Talker: "a", Seq: 1700000001000, Time: ts.Add(time.Second)},
+		{Talker: "a", Seq: 1700000002000, Time: ts.Add(2 * time.Second), Keys: []string{"i3"}},
+	}}
+	recognizer := &fakeRecognizer{}
+	s := NewService(store, recognizer, source)
+
+	// 请求时识别的图片在批量识别时不再识别
+	if text, err := s.Recognize(context.Background(), source["a"][0]); err != nil || text.Text != "text of i1" || text.Image != "i1,md5" {
+		t.Fatalf("Recognize = %+v, %v", text, err)
+	}
+	n, err := s.Update(context.Background(), []string{"a"})
+	if err != nil || n != 1 || recognizer.calls != 2 {
+		t.Fatalf("Update = %d, %v, calls %d", n, err, recognizer.calls)
+	}
+
+	// 识别失败时停在该张图片
+	source["a"] = append(source["a"], &Image{Talker: "a", Seq: 1700000003000, Time: ts.Add(3 * time.Second), Keys: []string{"i4"}})
+	recognizer.fail = true
+	if _, err := s.Update(context.Background(), []string{"a"}); err == nil {
+		t.Fatal("Update should fail")
+	}
+	recognizer.fail = false
+	if n, err := s.Update(context.Background(), []string{"a"}); err != nil || n != 1 {
+		t.Fatalf("retry Update = %d, %v", n, err)
+	}
+
+	list, err := store.Search("of i3", "a", 0)
+	if err != nil || len(list) != 1 || list[0].Seq != 1700000002000 || list[0].Provider != "fake" {
+		t.Fatalf("Search = %+v, %v", list, err)
+	}
+	byseq, err := store.Range("a", ts, ts.Add(time.Hour))
+	if err != nil || len(byseq) != 3 {
+		t.Errorf("Range = %d, %v", len(byseq), err)
+	}
+}
+
+func TestOpenAI(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model    string `json:"model"`
+			Messages []struct {
+				Content []struct {
+					Type     string `json:"type"`
+					ImageURL struct {
+						URL string `json:"url"`
+					} `json:"image_url"`
+				} `json:"content"`
+			} `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || r.URL.Path != "/v1/chat/completions" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		url := req.Messages[0].Content[1].ImageURL.URL
+		fmt.Fprintf(w, `{"choices":[{"message":{"content":"  %s\n\n %s "}}]}`, req.Model, url)
+	}))
+	defer srv.Close()
+
+	r, err := New(&conf.OCR{Provider: "openai", URL: srv.URL + "/v1/", Model: "vl"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := r.Recognize(context.Background(), []byte("img"), "jpg")
+	if err != nil || text != "vl\ndata:image/jpeg;base64,aW1n" {
+		t.Errorf("Recognize = %q, %v", text, err)
+	}
+
+	if _, err := New(&conf.OCR{Provider: "openai"}); err == nil {
+		t.Error("openai without model should fail")
+	}
+	if _, err := New(&conf.OCR{Provider: "paddle"}); err == nil || !strings.Contains(err.Error(), "paddle") {
+		t.Errorf("New = %v", err)
+	}
+}
+
+func TestTesseract(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script")
+	}
+	// 模拟 tesseract，输出图片扩展名与语言
+	binary := filepath.Join(t.TempDir(), "tesseract")
+	script := "#!/bin/sh\ntest \"$2\" = stdout || exit 1\necho \"  ${1##*.}\"\necho\necho \"$4 \"\n"
+	if err := os.WriteFile(binary, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	r, err := New(&conf.OCR{Provider: "tesseract", Binary: binary})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := r.Recognize(context.Background(), []byte("img"), "png")
+	if err != nil || text != "png\nchi_sim+eng" {
+		t.Errorf("Recognize = %q, %v", text, err)
+	}
+}
@@ -0,0 +1,153 @@
+package ocr
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// TextFile 工作目录中保存识别结果的数据库，扩展名不是 .db，不会被当作解密的数据库
+const TextFile = "ocr.sqlite"
+
+// Text 一条图片消息的识别结果
+type Text struct {
+	Talker   string    `json:"talker"`
+	Seq      int64     `json:"seq"`
+	Time     time.Time `json:"time"`  // 消息发送时间
+	Image    string    `json:"image"` // 图片的键，多个以英文逗号分隔
+	Text     string    `json:"text"`
+	Provider string    `json:"provider"`
+	Created  time.Time `json:"created"`
+}
+
+// Store 保存识别结果与批量识别进度的数据库
+type Store struct {
+	db *sql.DB
+}
+
+// Open 打开或创建数据库
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS ocr (
+			talker TEXT NOT NULL,
+			seq INTEGER NOT NULL,
+			time INTEGER NOT NULL,
+			image TEXT NOT NULL,
+			text TEXT NOT NULL,
+			provider TEXT NOT NULL,
+			created INTEGER NOT NULL,
+			PRIMARY KEY (talker, seq)
+		);
+		CREATE TABLE IF NOT EXISTS cursor (
+			talker TEXT PRIMARY KEY,
+			seq INTEGER NOT NULL,
+			time INTEGER NOT NULL
+		);
+	`)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Close 关闭数据库
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Put 保存识别结果，已有的结果被覆盖
+func (s *Store) Put(t *Text) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO ocr (talker, seq, time, image, text, provider, created) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		t.Talker, t.Seq, t.Time.Unix(), t.Image, t.Text, t.Provider, t.Created.Unix())
+	return err
+}
+
+// Get 返回消息的识别结果，没有时返回 nil
+func (s *Store) Get(talker string, seq int64) (*Text, error) {
+	list, err := s.query(`WHERE talker = ? AND seq = ?`, talker, seq)
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	return list[0], nil
+}
+
+// Range 返回聊天对象在时间范围内的识别结果，键为消息序号
+func (s *Store) Range(talker string, start, end time.Time) (map[int64]*Text, error) {
+	list, err := s.query(`WHERE talker = ? AND time >= ? AND time <= ?`, talker, start.Unix(), end.Unix())
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[int64]*Text, len(list))
+	for _, t := range list {
+		ret[t.Seq] = t
+	}
+	return ret, nil
+}
+
+// Search 按关键词搜索识别结果，按时间倒序排列，talker 为空时搜索所有聊天对象
+func (s *Store) Search(keyword, talker string, limit int) ([]*Text, error) {
+	where := `WHERE text LIKE ? ESCAPE '\'`
+	args := []any{"%" + escapeLike(keyword) + "%"}
+	if len(talker) != 0 {
+		where += ` AND talker = ?`
+		args = append(args, talker)
+	}
+	where += ` ORDER BY time DESC, seq DESC`
+	if limit > 0 {
+		where += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	return s.query(where, args...)
+}
+
+// Cursor 返回聊天对象已批量识别的最后一张图片的序号与时间
+func (s *Store) Cursor(talker string) (int64, time.Time, error) {
+	var seq, ts int64
+	err := s.db.QueryRow(`SELECT seq, time FROM cursor WHERE talker = ?`, talker).Scan(&seq, &ts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, time.Time{}, nil
+	}
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return seq, time.Unix(ts, 0), nil
+}
+
+// SetCursor 记录批量识别进度
+func (s *Store) SetCursor(talker string, seq int64, t time.Time) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO cursor (talker, seq, time) VALUES (?, ?, ?)`, talker, seq, t.Unix())
+	return err
+}
+
+func (s *Store) query(where string, args ...any) ([]*Text, error) {
+	rows, err := s.db.Query(`SELECT talker, seq, time, image, text, provider, created FROM ocr `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ret := make([]*Text, 0)
+	for rows.Next() {
+		var t Text
+		var ts, created int64
+		if err := rows.Scan(&t.Talker, &t.Seq, &ts, &t.Image, &t.Text, &t.Provider, &created); err != nil {
+			return nil, err
+		}
+		t.Time, t.Created = time.Unix(ts, 0), time.Unix(created, 0)
+		ret = append(ret, &t)
+	}
+	return ret, rows.Err()
+}
+
+// escapeLike 转义 LIKE 中的通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
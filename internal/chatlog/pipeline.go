@@ -0,0 +1,188 @@
+package chatlog
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+	"github.com/DanielMao1/chatlog/internal/chatlog/database"
+	chathttp "github.com/DanielMao1/chatlog/internal/chatlog/http"
+	"github.com/DanielMao1/chatlog/internal/chatlog/wechat"
+	"github.com/DanielMao1/chatlog/internal/errors"
+	"github.com/DanielMao1/chatlog/pkg/util"
+	"github.com/DanielMao1/chatlog/pkg/util/dat2img"
+)
+
+const (
+	StageKey     = "key"
+	StageDecrypt = "decrypt"
+	StageExport  = "export"
+	StageServe   = "serve"
+)
+
+// PipelineOptions 非交互模式的参数
+type PipelineOptions struct {
+	PID          int    // 指定微信进程，为 0 时使用第一个进程
+	Force        bool   // 忽略已有密钥重新获取，并重新解密所有数据库
+	Export       string // 导出文件路径，按扩展名选择 csv / json / txt 格式
+	ExportTalker string // 导出的聊天对象
+	ExportTime   string // 导出的时间范围
+	Translate    string // 导出时附上译文的目标语言，为空时不翻译
+	ExportScript string // 导出前对每条消息运行的脚本文件
+	Serve        bool   // 解密完成后启动 HTTP 服务
+}
+
+// stageProgress 以结构化日志的形式输出各阶段进度
+func stageProgress(stage, status string, err error) {
+	e := log.Info()
+	if err != nil {
+		e = log.Error().Err(err)
+	}
+	e.Str("stage", stage).Str("status", status).Send()
+}
+
+// CommandPipeline 依次执行 获取密钥 → 解密 → 导出/服务，全程无需交互
+func (m *Manager) CommandPipeline(configPath string, cmdConf map[string]any, opts PipelineOptions) error {
+
+	var err error
+	m.sc, m.scm, err = conf.LoadServiceConfig(configPath, cmdConf)
+	if err != nil {
+		return err
+	}
+
+	// step 1. key
+	if len(m.sc.GetDataKey()) == 0 || opts.Force {
+		stageProgress(StageKey, "start", nil)
+		if err := m.pipelineKey(cmdConf, opts); err != nil {
+			stageProgress(StageKey, "failed", err)
+			return err
+		}
+		stageProgress(StageKey, "done", nil)
+	} else {
+		stageProgress(StageKey, "skipped", nil)
+	}
+
+	if len(m.sc.GetDataDir()) == 0 {
+		return errors.ConfigRequired("dataDir")
+	}
+	if len(m.sc.GetWorkDir()) == 0 {
+		m.scm.SetConfig("work_dir", util.DefaultWorkDir(filepath.Base(m.sc.GetDataDir())))
+		if err := m.scm.Load(m.sc); err != nil {
+			return err
+		}
+	}
+
+	// step 2. decrypt
+	stageProgress(StageDecrypt, "start", nil)
+	m.wechat = wechat.NewService(m.sc)
+	if err := m.wechat.DecryptDBFiles(m.runCtx, opts.Force); err != nil {
+		stageProgress(StageDecrypt, "failed", err)
+		return err
+	}
+	stageProgress(StageDecrypt, "done", nil)
+
+	m.db = database.NewService(m.sc)
+
+	// step 3. export
+	if len(opts.Export) != 0 {
+		stageProgress(StageExport, "start", nil)
+		if err := m.pipelineExport(opts); err != nil {
+			stageProgress(StageExport, "failed", err)
+			return err
+		}
+		stageProgress(StageExport, "done", nil)
+	}
+
+	// step 4. serve
+	if opts.Serve {
+		stageProgress(StageServe, "start", nil)
+		if m.sc.GetVersion() == 4 {
+			dat2img.SetAesKey(m.sc.GetImgKey())
+			go dat2img.ScanAndSetXorKey(m.sc.GetDataDir())
+		}
+		if m.sc.GetAutoDecrypt() {
+			if err := m.wechat.StartAutoDecrypt(); err != nil {
+				stageProgress(StageServe, "failed", err)
+				return err
+			}
+		}
+		if m.db.GetDB() == nil {
+			if err := m.db.Start(); err != nil {
+				stageProgress(StageServe, "failed", err)
+				return err
+			}
+		}
+		m.http = chathttp.NewService(m.sc, m.db)
+		return m.http.ListenAndServe(m.runCtx)
+	}
+
+	return nil
+}
+
+// pipelineKey 从微信进程获取密钥，并写入服务配置
+func (m *Manager) pipelineKey(cmdConf map[string]any, opts PipelineOptions) error {
+	instances := wechat.NewService(m.sc).GetWeChatInstances()
+	if len(instances) == 0 {
+		return errors.ErrWeChatProcessNotFound
+	}
+
+	ins := instances[0]
+	if opts.PID != 0 {
+		ins = nil
+		for _, i := range instances {
+			if i.PID == uint32(opts.PID) {
+				ins = i
+				break
+			}
+		}
+		if ins == nil {
+			return errors.WeChatProcessNotFound(opts.PID)
+		}
+	}
+
+	key, imgKey, err := ins.GetKey(m.runCtx)
+	if err != nil {
+		return err
+	}
+
+	values := map[string]any{
+		"data_key":     key,
+		"img_key":      imgKey,
+		"data_dir":     ins.DataDir,
+		"platform":     ins.Platform,
+		"version":      ins.Version,
+		"full_version": ins.FullVersion,
+	}
+	for k, v := range values {
+		// 命令行参数优先
+		if _, ok := cmdConf[k]; ok && k != "data_key" && k != "img_key" {
+			continue
+		}
+		m.scm.SetConfig(k, v)
+	}
+	if len(m.sc.GetWorkDir()) == 0 {
+		m.scm.SetConfig("work_dir", util.DefaultWorkDir(ins.Name))
+	}
+
+	return m.scm.Load(m.sc)
+}
+
+// pipelineExport 将指定聊天对象的消息导出到文件
+func (m *Manager) pipelineExport(opts PipelineOptions) error {
+	var script string
+	if len(opts.ExportScript) != 0 {
+		data, err := os.ReadFile(opts.ExportScript)
+		if err != nil {
+			return err
+		}
+		script = string(data)
+	}
+	count, err := m.Export(opts.ExportTalker, opts.ExportTime, "", opts.Export, opts.Translate, script)
+	if err != nil {
+		return err
+	}
+	log.Info().Str("stage", StageExport).Int("count", count).Str("file", opts.Export).Send()
+	return nil
+}
@@ -0,0 +1,185 @@
+// Package plugin 调用外部程序处理新消息，保存程序返回的标签、评分与脱敏内容
+//
+// 协议：chatlog 每处理一批消息启动一次插件程序，向 stdin 写入一个 Request JSON 对象后关闭 stdin，
+// 程序向 stdout 输出一个 Response JSON 对象并以 0 退出，stderr 只用于日志。
+// 程序只需要返回有标注的消息，不属于这批消息的标注被忽略；以非 0 退出或输出无效时，
+// 这批消息在下次处理时重新发送。
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+)
+
+const (
+	// ProtocolVersion 请求中的协议版本，不兼容的修改时增加
+	ProtocolVersion = 1
+
+	// DefaultInterval 默认处理新消息的间隔
+	DefaultInterval = 10 * time.Minute
+
+	// DefaultTimeout 默认处理一批消息的超时
+	DefaultTimeout = time.Minute
+
+	// DefaultBatchSize 默认每批的消息数量
+	DefaultBatchSize = 100
+
+	// MaxStderr 错误信息中保留的 stderr 长度
+	MaxStderr = 1024
+)
+
+// Message 发送给插件的一条消息
+type Message struct {
+	Talker     string    `json:"talker"`
+	TalkerName string    `json:"talkerName"`
+	Seq        int64     `json:"seq"`
+	Time       time.Time `json:"time"`
+	Sender     string    `json:"sender"`
+	SenderName string    `json:"senderName"`
+	IsSelf     bool      `json:"isSelf"`
+	Type       int64     `json:"type"`
+	SubType    int64     `json:"subType"`
+	Content    string    `json:"content"` // 消息的文字内容，多媒体消息为描述与链接
+}
+
+// Request 写入插件 stdin 的一批消息，按时间排列
+type Request struct {
+	Version  int        `json:"version"`
+	Plugin   string     `json:"plugin"`
+	Messages []*Message `json:"messages"`
+}
+
+// Response 插件输出到 stdout 的结果
+type Response struct {
+	Annotations []*Annotation `json:"annotations"`
+}
+
+// Annotation 插件对一条消息的标注，Plugin 与 Time 由 chatlog 填写
+type Annotation struct {
+	Plugin string             `json:"plugin"`
+	Talker string             `json:"talker"`
+	Seq    int64              `json:"seq"`
+	Time   time.Time          `json:"time"`
+	Tags   []string           `json:"tags,omitempty"`   // 标签，如 spam、todo
+	Scores map[string]float64 `json:"scores,omitempty"` // 评分，如 {"sentiment": 0.8}
+	// Redacted 脱敏后的内容，HTTP API 返回文本消息时替换原内容
+	Redacted string `json:"redacted,omitempty"`
+}
+
+// empty 判断标注是否没有任何内容
+func (a *Annotation) empty() bool {
+	return len(a.Tags) == 0 && len(a.Scores) == 0 && len(a.Redacted) == 0
+}
+
+// Plugin 一个插件程序
+type Plugin struct {
+	name      string
+	command   string
+	args      []string
+	talkers   []string
+	interval  time.Duration
+	timeout   time.Duration
+	batchSize int
+}
+
+// New 按配置创建插件
+func New(c *conf.Plugin) (*Plugin, error) {
+	if c == nil || len(c.Command) == 0 {
+		return nil, fmt.Errorf("plugin command is required")
+	}
+	p := &Plugin{
+		name:      c.Name,
+		command:   c.Command,
+		args:      c.Args,
+		talkers:   c.Talkers,
+		interval:  c.Interval,
+		timeout:   c.Timeout,
+		batchSize: c.BatchSize,
+	}
+	if len(p.name) == 0 {
+		p.name = strings.TrimSuffix(filepath.Base(c.Command), filepath.Ext(c.Command))
+	}
+	if p.interval <= 0 {
+		p.interval = DefaultInterval
+	}
+	if p.timeout <= 0 {
+		p.timeout = DefaultTimeout
+	}
+	if p.batchSize <= 0 {
+		p.batchSize = DefaultBatchSize
+	}
+	return p, nil
+}
+
+// Name 返回插件名称
+func (p *Plugin) Name() string {
+	return p.name
+}
+
+// Talkers 返回交给插件处理的聊天对象
+func (p *Plugin) Talkers() []string {
+	return p.talkers
+}
+
+// Process 启动插件处理一批消息，返回属于这批消息的非空标注
+func (p *Plugin) Process(ctx context.Context, messages []*Message) ([]*Annotation, error) {
+	data, err := json.Marshal(&Request{Version: ProtocolVersion, Plugin: p.name, Messages: messages})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, p.command, p.args...)
+	cmd.Stdin = bytes.NewReader(data)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("plugin %s timed out after %s", p.name, p.timeout)
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > MaxStderr {
+			msg = msg[len(msg)-MaxStderr:]
+		}
+		if len(msg) != 0 {
+			return nil, fmt.Errorf("plugin %s: %v: %s", p.name, err, msg)
+		}
+		return nil, fmt.Errorf("plugin %s: %v", p.name, err)
+	}
+
+	var resp Response
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return nil, fmt.Errorf("plugin %s: invalid output: %v", p.name, err)
+	}
+
+	type key struct {
+		talker string
+		seq    int64
+	}
+	batch := make(map[key]*Message, len(messages))
+	for _, m := range messages {
+		batch[key{m.Talker, m.Seq}] = m
+	}
+	ret := make([]*Annotation, 0, len(resp.Annotations))
+	for _, a := range resp.Annotations {
+		if a == nil || a.empty() {
+			continue
+		}
+		m, ok := batch[key{a.Talker, a.Seq}]
+		if !ok {
+			continue
+		}
+		a.Plugin, a.Time = p.name, m.Time
+		ret = append(ret, a)
+	}
+	return ret, nil
+}
@@ -0,0 +1,133 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+)
+
+// fakeSource 每个聊天对象的消息
+type fakeSource map[string][]*Message
+
+func (f fakeSource) Messages(ctx context.Context, talker string, since time.Time) ([]*Message, error) {
+	var ret []*Message
+	for _, m := range f[talker] {
+		if !m.Time.Before(since) {
+			ret = append(ret, m)
+		}
+	}
+	return ret, nil
+}
+
+// script 写入一个模拟插件的 shell 脚本，保存收到的请求并输出固定的标注
+func script(t *testing.T, body string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script plugin")
+	}
+	path := filepath.Join(t.TempDir(), "classifier.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestUpdate(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.json")
+	binary := script(t, `cat > `+input+`
+echo '{"annotations": [
+  {"talker": "wxid_a", "seq": 1, "tags": ["spam"], "scores": {"spam": 0.9}},
+  {"talker": "wxid_a", "seq": 2},
+  {"talker": "wxid_a", "seq": 3, "redacted": "电话 ***"},
+  {"talker": "wxid_b", "seq": 1, "tags": ["other"]}
+]}'
+`)
+
+	store, err := Open(filepath.Join(dir, AnnotationFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	ts := time.Date(2024, 3, 1, 10, 0, 0, 0, time.Local)
+	source := fakeSource{"wxid_a": {
+		{Talker: "wxid_a", Seq: 1, Time: ts, Content: "点击领取"},
+		{Talker: "wxid_a", Seq: 2, Time: ts.Add(time.Minute), Content: "你好"},
+		{Talker: "wxid_a", Seq: 3, Time: ts.Add(2 * time.Minute), Content: "电话 13800000000"},
+	}}
+	p, err := New(&conf.Plugin{Command: binary, Talkers: []string{"wxid_a"}, BatchSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name() != "classifier" {
+		t.Errorf("got name %q, want classifier", p.Name())
+	}
+
+	s := NewService(store, source)
+	n, err := s.Update(context.Background(), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 两批中 seq 1 与 seq 3 各保存一次，空标注与其他聊天对象的标注被忽略
+	if n != 2 {
+		t.Errorf("got %d annotations, want 2", n)
+	}
+	data, _ := os.ReadFile(input)
+	if !strings.Contains(string(data), `"version":1`) || !strings.Contains(string(data), `"plugin":"classifier"`) {
+		t.Errorf("unexpected request %s", data)
+	}
+
+	got, err := store.Range("wxid_a", ts, ts.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := got[1]; len(a) != 1 || a[0].Tags[0] != "spam" || a[0].Scores["spam"] != 0.9 || a[0].Plugin != "classifier" {
+		t.Errorf("unexpected annotation of seq 1: %+v", a)
+	}
+	if a := got[3]; len(a) != 1 || a[0].Redacted != "电话 ***" {
+		t.Errorf("unexpected annotation of seq 3: %+v", a)
+	}
+	if _, ok := got[2]; ok {
+		t.Errorf("empty annotation saved")
+	}
+
+	list, err := store.Search("spam", "", "", 10)
+	if err != nil || len(list) != 1 || list[0].Seq != 1 {
+		t.Errorf("search by tag: %+v, %v", list, err)
+	}
+
+	// 已处理的消息不再交给插件
+	if n, err := s.Update(context.Background(), p); err != nil || n != 0 {
+		t.Errorf("second update: %d, %v", n, err)
+	}
+}
+
+func TestProcessError(t *testing.T) {
+	binary := script(t, "cat > /dev/null\necho 'model not found' >&2\nexit 3\n")
+	p, err := New(&conf.Plugin{Name: "broken", Command: binary, Talkers: []string{"wxid_a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := Open(filepath.Join(t.TempDir(), AnnotationFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	source := fakeSource{"wxid_a": {{Talker: "wxid_a", Seq: 1, Time: time.Now(), Content: "你好"}}}
+	_, err = NewService(store, source).Update(context.Background(), p)
+	if err == nil || !strings.Contains(err.Error(), "model not found") {
+		t.Fatalf("got %v, want error with stderr", err)
+	}
+	// 失败的批次下次重新处理
+	if seq, _, _ := store.Cursor("broken", "wxid_a"); seq != 0 {
+		t.Errorf("cursor moved to %d after failure", seq)
+	}
+}
@@ -0,0 +1,101 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Source 提供交给插件处理的消息
+type Source interface {
+	// Messages 返回聊天对象从 since 开始按时间排列的消息
+	Messages(ctx context.Context, talker string, since time.Time) ([]*Message, error)
+}
+
+// Service 定期将聊天对象的新消息交给插件处理并保存标注，每条消息只交给同一插件处理一次
+type Service struct {
+	store  *Store
+	source Source
+
+	// mu 保证同时只有一个插件在处理
+	mu sync.Mutex
+}
+
+// NewService 创建插件服务
+func NewService(store *Store, source Source) *Service {
+	return &Service{store: store, source: source}
+}
+
+// Store 返回保存标注的数据库
+func (s *Service) Store() *Store {
+	return s.store
+}
+
+// Run 每隔插件配置的间隔处理聊天对象的新消息，直到 ctx 结束
+func (s *Service) Run(ctx context.Context, p *Plugin) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.Update(ctx, p); err != nil {
+			log.Warn().Err(err).Str("plugin", p.name).Msg("plugin failed")
+		} else if n > 0 {
+			log.Info().Str("plugin", p.name).Int("count", n).Msg("messages annotated by plugin")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Update 将聊天对象上次处理之后的消息分批交给插件处理，返回新保存的标注数量
+// 一批处理失败时停在该批，下次从该批继续
+func (s *Service) Update(ctx context.Context, p *Plugin) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, talker := range p.talkers {
+		seq, since, err := s.store.Cursor(p.name, talker)
+		if err != nil {
+			return count, err
+		}
+		messages, err := s.source.Messages(ctx, talker, since)
+		if err != nil {
+			return count, fmt.Errorf("%s: %w", talker, err)
+		}
+		pending := make([]*Message, 0, len(messages))
+		for _, m := range messages {
+			if m.Seq > seq {
+				pending = append(pending, m)
+			}
+		}
+
+		for len(pending) != 0 {
+			if err := ctx.Err(); err != nil {
+				return count, err
+			}
+			batch := pending[:min(len(pending), p.batchSize)]
+			pending = pending[len(batch):]
+			annotations, err := p.Process(ctx, batch)
+			if err != nil {
+				return count, fmt.Errorf("%s: %w", talker, err)
+			}
+			for _, a := range annotations {
+				if err := s.store.Put(a); err != nil {
+					return count, err
+				}
+			}
+			count += len(annotations)
+			last := batch[len(batch)-1]
+			if err := s.store.SetCursor(p.name, talker, last.Seq, last.Time); err != nil {
+				return count, err
+			}
+		}
+	}
+	return count, nil
+}
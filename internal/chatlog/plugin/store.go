@@ -0,0 +1,162 @@
+package plugin
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// AnnotationFile 工作目录中保存插件标注的数据库，扩展名不是 .db，不会被当作解密的数据库
+const AnnotationFile = "annotations.sqlite"
+
+// Store 保存插件标注与每个插件处理进度的数据库
+type Store struct {
+	db *sql.DB
+}
+
+// Open 打开或创建数据库
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS annotation (
+			plugin TEXT NOT NULL,
+			talker TEXT NOT NULL,
+			seq INTEGER NOT NULL,
+			time INTEGER NOT NULL,
+			tags TEXT NOT NULL,
+			scores TEXT NOT NULL,
+			redacted TEXT NOT NULL,
+			created INTEGER NOT NULL,
+			PRIMARY KEY (plugin, talker, seq)
+		);
+		CREATE INDEX IF NOT EXISTS annotation_time ON annotation (talker, time);
+		CREATE TABLE IF NOT EXISTS cursor (
+			plugin TEXT NOT NULL,
+			talker TEXT NOT NULL,
+			seq INTEGER NOT NULL,
+			time INTEGER NOT NULL,
+			PRIMARY KEY (plugin, talker)
+		);
+	`)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Close 关闭数据库
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Put 保存标注，同一插件对同一条消息的标注被覆盖
+func (s *Store) Put(a *Annotation) error {
+	tags, err := json.Marshal(a.Tags)
+	if err != nil {
+		return err
+	}
+	scores, err := json.Marshal(a.Scores)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT OR REPLACE INTO annotation (plugin, talker, seq, time, tags, scores, redacted, created) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		a.Plugin, a.Talker, a.Seq, a.Time.Unix(), string(tags), string(scores), a.Redacted, time.Now().Unix())
+	return err
+}
+
+// Range 返回聊天对象在时间范围内的标注，键为消息序号
+func (s *Store) Range(talker string, start, end time.Time) (map[int64][]*Annotation, error) {
+	list, err := s.query(`WHERE talker = ? AND time >= ? AND time <= ? ORDER BY plugin`, talker, start.Unix(), end.Unix())
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[int64][]*Annotation, len(list))
+	for _, a := range list {
+		ret[a.Seq] = append(ret[a.Seq], a)
+	}
+	return ret, nil
+}
+
+// Search 按标签查找标注，按时间倒序排列，tag、talker、plugin 为空时不限
+func (s *Store) Search(tag, talker, plugin string, limit int) ([]*Annotation, error) {
+	var conds []string
+	var args []any
+	if len(tag) != 0 {
+		data, _ := json.Marshal(tag)
+		conds = append(conds, `tags LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(string(data))+"%")
+	}
+	if len(talker) != 0 {
+		conds = append(conds, `talker = ?`)
+		args = append(args, talker)
+	}
+	if len(plugin) != 0 {
+		conds = append(conds, `plugin = ?`)
+		args = append(args, plugin)
+	}
+	where := ""
+	if len(conds) != 0 {
+		where = `WHERE ` + strings.Join(conds, ` AND `)
+	}
+	where += ` ORDER BY time DESC, seq DESC`
+	if limit > 0 {
+		where += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	return s.query(where, args...)
+}
+
+// Cursor 返回插件已处理的聊天对象最后一条消息的序号与时间
+func (s *Store) Cursor(plugin, talker string) (int64, time.Time, error) {
+	var seq, ts int64
+	err := s.db.QueryRow(`SELECT seq, time FROM cursor WHERE plugin = ? AND talker = ?`, plugin, talker).Scan(&seq, &ts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, time.Time{}, nil
+	}
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return seq, time.Unix(ts, 0), nil
+}
+
+// SetCursor 记录插件处理进度
+func (s *Store) SetCursor(plugin, talker string, seq int64, t time.Time) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO cursor (plugin, talker, seq, time) VALUES (?, ?, ?, ?)`, plugin, talker, seq, t.Unix())
+	return err
+}
+
+func (s *Store) query(where string, args ...any) ([]*Annotation, error) {
+	rows, err := s.db.Query(`SELECT plugin, talker, seq, time, tags, scores, redacted FROM annotation `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ret := make([]*Annotation, 0)
+	for rows.Next() {
+		var a Annotation
+		var ts int64
+		var tags, scores string
+		if err := rows.Scan(&a.Plugin, &a.Talker, &a.Seq, &ts, &tags, &scores, &a.Redacted); err != nil {
+			return nil, err
+		}
+		a.Time = time.Unix(ts, 0)
+		json.Unmarshal([]byte(tags), &a.Tags)
+		json.Unmarshal([]byte(scores), &a.Scores)
+		ret = append(ret, &a)
+	}
+	return ret, rows.Err()
+}
+
+// escapeLike 转义 LIKE 中的通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,241 @@
+package prune
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/backup"
+	"github.com/DanielMao1/chatlog/internal/chatlog/decrypted"
+)
+
+const (
+	ReasonStale  = "stale"  // chatlog 解密生成、数据目录中已不存在的数据库
+	ReasonTemp   = "temp"   // 中断的解密留下的临时文件
+	ReasonCache  = "cache"  // 媒体等临时副本
+	ReasonBackup = "backup" // 超出保留数量的备份
+
+	DefaultTempMaxAge  = time.Hour
+	DefaultCacheMaxAge = 24 * time.Hour
+)
+
+// Options 清理参数
+type Options struct {
+	WorkDir     string        // 解密后的工作目录
+	DataDir     string        // 微信数据目录，为空或不可访问时不清理过期分片
+	Stale       bool          // 清理过期分片，只删除解密清单中记录的数据库，merge、import 写入的数据库不受影响
+	BackupDir   string        // 备份目录，为空时不清理备份
+	Account     string        // 备份文件对应的账号
+	KeepBackups int           // 保留最近的备份数量，0 表示全部保留
+	TempMaxAge  time.Duration // 临时文件超过该时长才删除，避免误删正在解密的文件
+	CacheDir    string        // 临时副本目录，为空时不清理
+	CacheMaxAge time.Duration // 临时副本超过该时长未修改才删除
+	DryRun      bool          // 只列出将要删除的文件
+}
+
+// Item 被删除的文件
+type Item struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Reason string `json:"reason"`
+}
+
+// Result 清理结果
+type Result struct {
+	Items     []*Item `json:"items"`
+	Reclaimed int64   `json:"reclaimed"`
+	DryRun    bool    `json:"dryRun"`
+}
+
+type pruner struct {
+	opts Options
+	now  time.Time
+	ret  *Result
+}
+
+// Run 按保留策略清理工作目录中的过期分片、临时文件、临时副本和旧备份
+func Run(opts Options) (*Result, error) {
+	if len(opts.WorkDir) == 0 {
+		return nil, fmt.Errorf("workDir is required")
+	}
+	if _, err := os.Stat(opts.WorkDir); err != nil {
+		return nil, err
+	}
+	if opts.TempMaxAge <= 0 {
+		opts.TempMaxAge = DefaultTempMaxAge
+	}
+	if opts.CacheMaxAge <= 0 {
+		opts.CacheMaxAge = DefaultCacheMaxAge
+	}
+
+	p := &pruner{
+		opts: opts,
+		now:  time.Now(),
+		ret:  &Result{Items: make([]*Item, 0), DryRun: opts.DryRun},
+	}
+	if err := p.workDir(); err != nil {
+		return nil, err
+	}
+	if err := p.cache(); err != nil {
+		return nil, err
+	}
+	if err := p.backups(); err != nil {
+		return nil, err
+	}
+	return p.ret, nil
+}
+
+// workDir 清理工作目录中的临时文件和过期分片
+func (p *pruner) workDir() error {
+	var known map[string]bool
+	if p.opts.Stale {
+		if len(p.opts.DataDir) == 0 {
+			log.Warn().Msg("data dir is not configured, skip stale shards")
+		} else if _, err := os.Stat(p.opts.DataDir); err != nil {
+			log.Warn().Err(err).Msg("data dir is not accessible, skip stale shards")
+		} else if known, err = decrypted.Load(p.opts.WorkDir); err != nil {
+			return err
+		}
+	}
+
+	stale := make([]string, 0)
+	err := filepath.Walk(p.opts.WorkDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// 过期分片的 -wal 等文件已随分片一起删除
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		name := info.Name()
+		switch {
+		case strings.HasSuffix(name, ".tmp"):
+			if p.now.Sub(info.ModTime()) > p.opts.TempMaxAge {
+				return p.remove(path, info, ReasonTemp)
+			}
+		case isSidecar(name):
+			// 主数据库已不存在的 -wal / -shm / -journal
+			if _, err := os.Stat(sidecarDB(path)); os.IsNotExist(err) {
+				return p.remove(path, info, ReasonTemp)
+			}
+		case len(known) != 0 && strings.HasSuffix(name, ".db"):
+			rel, err := filepath.Rel(p.opts.WorkDir, path)
+			if err != nil {
+				return err
+			}
+			// 只有 chatlog 解密生成的数据库才可能过期，合并或导入的数据库在数据目录中本就不存在
+			if !known[filepath.ToSlash(rel)] {
+				return nil
+			}
+			if _, err := os.Stat(filepath.Join(p.opts.DataDir, rel)); os.IsNotExist(err) {
+				if err := p.remove(path, info, ReasonStale); err != nil {
+					return err
+				}
+				for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+					if info, err := os.Stat(path + suffix); err == nil {
+						if err := p.remove(path+suffix, info, ReasonStale); err != nil {
+							return err
+						}
+					}
+				}
+				stale = append(stale, rel)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(stale) == 0 || p.opts.DryRun {
+		return nil
+	}
+	return decrypted.Remove(p.opts.WorkDir, stale...)
+}
+
+// cache 清理长时间未使用的临时副本
+func (p *pruner) cache() error {
+	if len(p.opts.CacheDir) == 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(p.opts.CacheDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		if p.now.Sub(info.ModTime()) > p.opts.CacheMaxAge {
+			if err := p.remove(filepath.Join(p.opts.CacheDir, e.Name()), info, ReasonCache); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// backups 清理超出保留数量的备份
+func (p *pruner) backups() error {
+	if len(p.opts.BackupDir) == 0 || p.opts.KeepBackups <= 0 {
+		return nil
+	}
+	files, err := backup.List(p.opts.BackupDir, p.opts.Account)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if len(files) <= p.opts.KeepBackups {
+		return nil
+	}
+	for _, file := range files[:len(files)-p.opts.KeepBackups] {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		if err := p.remove(file, info, ReasonBackup); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *pruner) remove(path string, info os.FileInfo, reason string) error {
+	if !p.opts.DryRun {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		log.Debug().Str("reason", reason).Msgf("removed %s", path)
+	}
+	p.ret.Items = append(p.ret.Items, &Item{Path: path, Size: info.Size(), Reason: reason})
+	p.ret.Reclaimed += info.Size()
+	return nil
+}
+
+func isSidecar(name string) bool {
+	return strings.HasSuffix(name, "-wal") || strings.HasSuffix(name, "-shm") || strings.HasSuffix(name, "-journal")
+}
+
+func sidecarDB(path string) string {
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		if strings.HasSuffix(path, suffix) {
+			return strings.TrimSuffix(path, suffix)
+		}
+	}
+	return path
+}
@@ -0,0 +1,127 @@
+package prune
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/decrypted"
+	"github.com/DanielMao1/chatlog/internal/chatlog/merge"
+)
+
+// newShard 创建只有 Timestamp、Name2Id 表的 4.0 数据库
+func newShard(t *testing.T, path string, start int64) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, stmt := range []string{
+		"CREATE TABLE Timestamp (timestamp INTEGER)",
+		"CREATE TABLE Name2Id (user_name TEXT PRIMARY KEY)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Exec("INSERT INTO Timestamp VALUES (?)", start); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestStaleOnlyDecrypted(t *testing.T) {
+	dataDir, workDir := t.TempDir(), t.TempDir()
+
+	// message_0.db 仍在数据目录中，message_1.db 已被微信删除
+	for _, rel := range []string{"db_storage/message/message_0.db", "db_storage/message/message_1.db"} {
+		newShard(t, filepath.Join(workDir, rel), 0)
+		if err := decrypted.Record(workDir, rel); err != nil {
+			t.Fatal(err)
+		}
+	}
+	newShard(t, filepath.Join(dataDir, "db_storage/message/message_0.db"), 0)
+
+	// 其他机器的工作目录通过 merge 带来的数据库，本机数据目录中没有
+	other := t.TempDir()
+	newShard(t, filepath.Join(other, "db_storage/hardlink/hardlink.db"), 100)
+	if err := decrypted.Record(other, "db_storage/hardlink/hardlink.db"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := merge.Merge(workDir, []string{other}, "", 4); err != nil {
+		t.Fatal(err)
+	}
+	merged := filepath.Join(workDir, "db_storage/hardlink/hardlink.db")
+
+	// import 写入的数据库不经过解密
+	imported := filepath.Join(workDir, "db_storage/session/session_import.db")
+	newShard(t, imported, 0)
+
+	// 默认不清理过期分片
+	ret, err := Run(Options{WorkDir: workDir, DataDir: dataDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ret.Items) != 0 {
+		t.Fatalf("expected nothing removed without Stale, got %d items", len(ret.Items))
+	}
+
+	ret, err = Run(Options{WorkDir: workDir, DataDir: dataDir, Stale: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ret.Items) != 1 || ret.Items[0].Reason != ReasonStale || filepath.Base(ret.Items[0].Path) != "message_1.db" {
+		t.Fatalf("expected only message_1.db removed, got %+v", ret.Items)
+	}
+	if !exists(filepath.Join(workDir, "db_storage/message/message_0.db")) {
+		t.Fatal("shard still in data dir was removed")
+	}
+	if !exists(merged) {
+		t.Fatal("merged shard was removed")
+	}
+	if !exists(imported) {
+		t.Fatal("imported database was removed")
+	}
+
+	known, err := decrypted.Load(workDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if known["db_storage/message/message_1.db"] || !known["db_storage/message/message_0.db"] {
+		t.Fatalf("unexpected manifest after prune: %v", known)
+	}
+	if known["db_storage/hardlink/hardlink.db"] {
+		t.Fatal("manifest of the merged source leaked into the work dir")
+	}
+}
+
+func TestStaleDryRun(t *testing.T) {
+	dataDir, workDir := t.TempDir(), t.TempDir()
+	rel := "db_storage/message/message_1.db"
+	newShard(t, filepath.Join(workDir, rel), 0)
+	if err := decrypted.Record(workDir, rel); err != nil {
+		t.Fatal(err)
+	}
+
+	ret, err := Run(Options{WorkDir: workDir, DataDir: dataDir, Stale: true, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ret.Items) != 1 || !exists(filepath.Join(workDir, rel)) {
+		t.Fatalf("dry run should list but keep the shard, got %+v", ret.Items)
+	}
+	if known, _ := decrypted.Load(workDir); !known[rel] {
+		t.Fatal("dry run changed the manifest")
+	}
+}
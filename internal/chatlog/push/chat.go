@@ -0,0 +1,109 @@
+package push
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+)
+
+// 推送目标的请求体格式
+const (
+	FormatJSON    = "json"
+	FormatSlack   = "slack"
+	FormatDiscord = "discord"
+)
+
+const (
+	// slackMaxText Slack 消息的最大字数
+	slackMaxText = 3000
+	// discordMaxTitle、discordMaxDescription Discord embed 标题与内容的最大字数
+	discordMaxTitle       = 256
+	discordMaxDescription = 4096
+)
+
+// Message 发送到 Slack、Discord 等聊天软件时的消息
+type Message struct {
+	Title  string
+	Author string // 发送者，为空时不显示
+	Text   string
+	Link   string // 在 HTTP 服务中查看聊天记录的地址，为空时不显示
+}
+
+// ChatMessage 可以转换为聊天软件消息的推送内容，其他内容以 JSON 代码块发送
+type ChatMessage interface {
+	ChatMessage() *Message
+}
+
+// FormatOf 返回推送目标的请求体格式，未配置时按 URL 识别 Slack 与 Discord
+func FormatOf(d *conf.Destination) string {
+	if len(d.Format) != 0 {
+		return strings.ToLower(d.Format)
+	}
+	u, err := url.Parse(d.URL)
+	if err != nil {
+		return FormatJSON
+	}
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case host == "hooks.slack.com":
+		return FormatSlack
+	case (host == "discord.com" || host == "discordapp.com" || strings.HasSuffix(host, ".discord.com")) &&
+		strings.HasPrefix(u.Path, "/api/webhooks/"):
+		return FormatDiscord
+	}
+	return FormatJSON
+}
+
+// renderChat 生成 Slack 或 Discord incoming webhook 的请求体
+func renderChat(format string, payload any) ([]byte, error) {
+	var msg *Message
+	if m, ok := payload.(ChatMessage); ok {
+		msg = m.ChatMessage()
+	} else {
+		b, err := json.MarshalIndent(payload, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		msg = &Message{Title: "chatlog", Text: "```\n" + truncate(string(b), slackMaxText-8) + "\n```"}
+	}
+
+	if format == FormatDiscord {
+		embed := map[string]any{
+			"title":       truncate(msg.Title, discordMaxTitle),
+			"description": truncate(msg.Text, discordMaxDescription),
+		}
+		if len(msg.Author) != 0 {
+			embed["author"] = map[string]any{"name": truncate(msg.Author, discordMaxTitle)}
+		}
+		if len(msg.Link) != 0 {
+			embed["url"] = msg.Link
+		}
+		return json.Marshal(map[string]any{"username": "chatlog", "embeds": []any{embed}})
+	}
+
+	var text strings.Builder
+	text.WriteString("*" + slackEscape(msg.Title) + "*\n")
+	if len(msg.Author) != 0 {
+		text.WriteString(slackEscape(msg.Author) + ": ")
+	}
+	text.WriteString(slackEscape(truncate(msg.Text, slackMaxText)))
+	if len(msg.Link) != 0 {
+		text.WriteString("\n<" + msg.Link + "|chatlog>")
+	}
+	return json.Marshal(map[string]any{"text": text.String()})
+}
+
+// slackEscape 转义 Slack mrkdwn 中的控制字符
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// truncate 超过 n 个字时截断
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,258 @@
+package push
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+	"github.com/DanielMao1/chatlog/pkg/util"
+)
+
+const (
+	// HeaderDelivery 每次推送的 ID，重试时不变，接收端可以据此去重
+	HeaderDelivery = "X-Chatlog-Delivery"
+	// HeaderTimestamp 发送请求的 Unix 时间戳
+	HeaderTimestamp = "X-Chatlog-Timestamp"
+	// HeaderSignature 配置了 secret 时的签名，为 sha256= 加 HMAC-SHA256(secret, 时间戳 + "." + 请求体) 的十六进制
+	HeaderSignature = "X-Chatlog-Signature"
+
+	// DefaultRetries 未配置 retries 时的重试次数
+	DefaultRetries = 3
+	// DefaultBackoff 未配置 backoff 时第一次重试前的等待时间
+	DefaultBackoff = time.Second
+	// MaxBackoff 重试等待时间的上限
+	MaxBackoff = time.Minute
+
+	// SpoolDir 工作目录中保存推送失败请求的目录
+	SpoolDir = "deliveries"
+	// RecentSize 内存中保留的最近推送记录数量
+	RecentSize = 100
+)
+
+// 推送状态
+const (
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+// Delivery 一次推送，失败时连同请求体保存到死信目录
+type Delivery struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	ContentType string    `json:"contentType"`
+	Body        string    `json:"body,omitempty"`
+	Status      string    `json:"status"`
+	Attempts    int       `json:"attempts"`
+	Error       string    `json:"error,omitempty"`
+	Created     time.Time `json:"created"`
+	Updated     time.Time `json:"updated"`
+}
+
+var (
+	mu     sync.Mutex
+	spool  string
+	recent []*Delivery
+)
+
+// validID 推送 ID 的格式，防止通过 ID 访问死信目录以外的文件
+var validID = regexp.MustCompile(`^[0-9]{14}-[0-9a-f]{8}$`)
+
+// SetSpool 设置保存推送失败请求的目录，为空时不保存
+func SetSpool(dir string) {
+	mu.Lock()
+	defer mu.Unlock()
+	spool = dir
+}
+
+// Sign 计算请求体的签名，接收端用相同的 secret 计算并比较 X-Chatlog-Signature 验证请求
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newDelivery(d *conf.Destination, body []byte, contentType string) *Delivery {
+	b := make([]byte, 4)
+	rand.Read(b)
+	now := time.Now()
+	return &Delivery{
+		ID:          now.Format("20060102150405") + "-" + hex.EncodeToString(b),
+		URL:         d.URL,
+		ContentType: contentType,
+		Body:        string(body),
+		Created:     now,
+	}
+}
+
+// deliver 推送请求，失败时以指数退避重试，记录结果
+func deliver(d *conf.Destination, dl *Delivery) error {
+	retries := d.Retries
+	if retries == 0 {
+		retries = DefaultRetries
+	}
+	backoff := d.Backoff
+	if backoff <= 0 {
+		backoff = DefaultBackoff
+	}
+
+	var err error
+	for i := 0; ; i++ {
+		dl.Attempts++
+		var retry bool
+		if retry, err = post(d, dl); err == nil || !retry || i >= retries {
+			break
+		}
+		time.Sleep(backoff)
+		backoff = min(backoff*2, MaxBackoff)
+	}
+	record(dl, err)
+	return err
+}
+
+// record 记录推送结果，失败时保存到死信目录，成功时从死信目录删除
+func record(dl *Delivery, err error) {
+	dl.Updated = time.Now()
+	dl.Status, dl.Error = StatusDelivered, ""
+	if err != nil {
+		dl.Status, dl.Error = StatusFailed, err.Error()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	item := *dl
+	item.Body = ""
+	recent = append(recent, &item)
+	if len(recent) > RecentSize {
+		recent = recent[len(recent)-RecentSize:]
+	}
+	if len(spool) == 0 {
+		return
+	}
+	path := filepath.Join(spool, dl.ID+".json")
+	if err == nil {
+		os.Remove(path)
+		return
+	}
+	data, _ := json.MarshalIndent(dl, "", "  ")
+	if util.PrepareDir(spool) == nil {
+		os.WriteFile(path, data, 0600)
+	}
+}
+
+// Recent 返回最近的推送记录，最新的在前，不含请求体
+func Recent() []*Delivery {
+	mu.Lock()
+	defer mu.Unlock()
+	ret := slices.Clone(recent)
+	slices.Reverse(ret)
+	return ret
+}
+
+// DeadLetters 返回死信目录中推送失败的请求，最新的在前，不含请求体
+func DeadLetters() ([]*Delivery, error) {
+	mu.Lock()
+	dir := spool
+	mu.Unlock()
+	if len(dir) == 0 {
+		return []*Delivery{}, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []*Delivery{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*Delivery, 0, len(entries))
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || !validID.MatchString(id) {
+			continue
+		}
+		dl, err := load(dir, id)
+		if err != nil {
+			continue
+		}
+		dl.Body = ""
+		ret = append(ret, dl)
+	}
+	slices.SortFunc(ret, func(a, b *Delivery) int { return strings.Compare(b.ID, a.ID) })
+	return ret, nil
+}
+
+// Redeliver 重新推送死信目录中的请求，请求头与签名密钥使用 dests 中 URL 相同的推送目标，成功后从死信目录删除
+func Redeliver(id string, dests map[string]*conf.Destination) (*Delivery, error) {
+	dir, err := spoolOf(id)
+	if err != nil {
+		return nil, err
+	}
+	dl, err := load(dir, id)
+	if err != nil {
+		return nil, err
+	}
+	d := &conf.Destination{URL: dl.URL}
+	for _, dest := range dests {
+		if dest != nil && dest.URL == dl.URL {
+			d = dest
+			break
+		}
+	}
+	err = deliver(d, dl)
+	dl.Body = ""
+	return dl, err
+}
+
+// Discard 从死信目录删除请求
+func Discard(id string) error {
+	dir, err := spoolOf(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(dir, id+".json")); errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("delivery %s not found", id)
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// spoolOf 校验推送 ID 并返回死信目录
+func spoolOf(id string) (string, error) {
+	if !validID.MatchString(id) {
+		return "", fmt.Errorf("invalid delivery id: %s", id)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(spool) == 0 {
+		return "", fmt.Errorf("delivery spool is not configured")
+	}
+	return spool, nil
+}
+
+func load(dir, id string) (*Delivery, error) {
+	data, err := os.ReadFile(filepath.Join(dir, id+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("delivery %s not found", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var dl Delivery
+	if err := json.Unmarshal(data, &dl); err != nil {
+		return nil, err
+	}
+	return &dl, nil
+}
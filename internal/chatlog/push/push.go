@@ -0,0 +1,120 @@
+package push
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+)
+
+// DefaultTimeout 未配置 timeout 时的请求超时
+const DefaultTimeout = 10 * time.Second
+
+// Resolve 按名称查找推送目标
+func Resolve(name string, dests map[string]*conf.Destination) (*conf.Destination, error) {
+	d, ok := dests[name]
+	if !ok || d == nil {
+		return nil, fmt.Errorf("destination %s is not configured", name)
+	}
+	if len(d.URL) == 0 {
+		return nil, fmt.Errorf("destination %s has no url", name)
+	}
+	return d, nil
+}
+
+// Send 将 payload 渲染为请求体并 POST 到推送目标，非 2xx 响应视为失败
+// 失败时按推送目标的 retries 与 backoff 重试，仍然失败时将请求保存到死信目录，可以重新推送
+func Send(d *conf.Destination, payload any) error {
+	body, contentType, err := Render(d, payload)
+	if err != nil {
+		return err
+	}
+	return deliver(d, newDelivery(d, body, contentType))
+}
+
+// post 发送一次请求，返回的 bool 表示失败后是否值得重试
+func post(d *conf.Destination, dl *Delivery) (bool, error) {
+	body := []byte(dl.Body)
+	req, err := http.NewRequest(http.MethodPost, d.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", dl.ContentType)
+	for k, v := range d.Headers {
+		req.Header.Set(k, v)
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(HeaderDelivery, dl.ID)
+	req.Header.Set(HeaderTimestamp, ts)
+	if len(d.Secret) != 0 {
+		req.Header.Set(HeaderSignature, Sign(d.Secret, ts, body))
+	}
+
+	timeout := d.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// 除超时与限流外，4xx 说明请求本身有问题，重试也不会成功
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests
+		return retry, fmt.Errorf("post to %s failed, status code: %d", d.URL, resp.StatusCode)
+	}
+	return false, nil
+}
+
+// Render 生成请求体，未配置模板时按格式生成 Slack、Discord 消息或直接序列化为 JSON
+// 模板的数据为 payload 序列化为 JSON 后的对象，字段名与 JSON 一致，如 {{.talker}}；json 函数可将值转为 JSON
+func Render(d *conf.Destination, payload any) ([]byte, string, error) {
+	if format := FormatOf(d); len(d.Template) == 0 && (format == FormatSlack || format == FormatDiscord) {
+		body, err := renderChat(format, payload)
+		return body, "application/json", err
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(d.Template) == 0 {
+		return body, "application/json", nil
+	}
+
+	tmpl, err := template.New("body").Funcs(template.FuncMap{"json": toJSON}).Parse(d.Template)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid template: %v", err)
+	}
+	var data any
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, "", fmt.Errorf("render template failed: %v", err)
+	}
+
+	// 模板渲染结果为 JSON 时沿用 application/json，否则按纯文本发送
+	contentType := "text/plain; charset=utf-8"
+	if trimmed := strings.TrimSpace(buf.String()); json.Valid([]byte(trimmed)) {
+		contentType = "application/json"
+	}
+	return buf.Bytes(), contentType, nil
+}
+
+func toJSON(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,175 @@
+package push
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+)
+
+func TestRender(t *testing.T) {
+	payload := map[string]any{"talker": "filehelper", "highlights": []string{"a", "b"}}
+
+	tests := []struct {
+		template    string
+		body        string
+		contentType string
+	}{
+		{"", `{"highlights":["a","b"],"talker":"filehelper"}`, "application/json"},
+		{`{"text": {{json .talker}}, "items": {{json .highlights}}}`, `{"text": "filehelper", "items": ["a","b"]}`, "application/json"},
+		{`summary of {{.talker}}`, `summary of filehelper`, "text/plain; charset=utf-8"},
+	}
+	for _, tt := range tests {
+		body, contentType, err := Render(&conf.Destination{Template: tt.template}, payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != tt.body || contentType != tt.contentType {
+			t.Errorf("Render(%q) = %s, %s, want %s, %s", tt.template, body, contentType, tt.body, tt.contentType)
+		}
+	}
+}
+
+type alert struct{}
+
+func (alert) ChatMessage() *Message {
+	return &Message{Title: "项目群", Author: "小王", Text: "a < b", Link: "http://127.0.0.1:5030/api/v1/chatlog?talker=x"}
+}
+
+func TestFormatOf(t *testing.T) {
+	tests := map[string]string{
+		"https://hooks.slack.com/services/T/B/X":       FormatSlack,
+		"https://discord.com/api/webhooks/1/abc":       FormatDiscord,
+		"https://discordapp.com/api/webhooks/1/abc":    FormatDiscord,
+		"https://discord.com/channels/1":               FormatJSON,
+		"https://example.com/hooks.slack.com/x":        FormatJSON,
+		"https://open.feishu.cn/open-apis/bot/v2/hook": FormatJSON,
+	}
+	for u, want := range tests {
+		if got := FormatOf(&conf.Destination{URL: u}); got != want {
+			t.Errorf("FormatOf(%q) = %q, want %q", u, got, want)
+		}
+	}
+	if got := FormatOf(&conf.Destination{URL: "https://relay.example.com", Format: "Slack"}); got != FormatSlack {
+		t.Errorf("configured format should win, got %q", got)
+	}
+}
+
+func TestRenderChat(t *testing.T) {
+	body, contentType, err := Render(&conf.Destination{URL: "https://hooks.slack.com/services/x"}, alert{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var slack struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(body, &slack); err != nil || contentType != "application/json" {
+		t.Fatalf("invalid slack body %s: %v", body, err)
+	}
+	if want := "*项目群*\n小王: a &lt; b\n<http://127.0.0.1:5030/api/v1/chatlog?talker=x|chatlog>"; slack.Text != want {
+		t.Errorf("slack text = %q, want %q", slack.Text, want)
+	}
+
+	body, _, err = Render(&conf.Destination{URL: "https://discord.com/api/webhooks/1/abc"}, alert{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var discord struct {
+		Embeds []struct {
+			Title       string `json:"title"`
+			Description string `json:"description"`
+			URL         string `json:"url"`
+			Author      struct {
+				Name string `json:"name"`
+			} `json:"author"`
+		} `json:"embeds"`
+	}
+	if err := json.Unmarshal(body, &discord); err != nil || len(discord.Embeds) != 1 {
+		t.Fatalf("invalid discord body %s: %v", body, err)
+	}
+	if e := discord.Embeds[0]; e.Title != "项目群" || e.Description != "a < b" || e.Author.Name != "小王" || len(e.URL) == 0 {
+		t.Errorf("unexpected discord embed %+v", e)
+	}
+
+	// 模板优先于格式
+	body, _, _ = Render(&conf.Destination{URL: "https://hooks.slack.com/services/x", Template: "plain"}, alert{})
+	if string(body) != "plain" {
+		t.Errorf("template should override format, got %s", body)
+	}
+}
+
+func TestSendRetry(t *testing.T) {
+	SetSpool(t.TempDir())
+	defer SetSpool("")
+
+	var calls int
+	var ids []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		ids = append(ids, r.Header.Get(HeaderDelivery))
+		if got := r.Header.Get(HeaderSignature); got != Sign("secret", r.Header.Get(HeaderTimestamp), body) {
+			t.Errorf("signature = %q", got)
+		}
+		if calls < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	d := &conf.Destination{URL: srv.URL, Secret: "secret", Backoff: time.Millisecond}
+	if err := Send(d, map[string]string{"talker": "wxid_a"}); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 || ids[0] != ids[2] {
+		t.Errorf("calls = %d, ids = %v", calls, ids)
+	}
+	if r := Recent(); len(r) != 1 || r[0].Status != StatusDelivered || r[0].Attempts != 3 {
+		t.Errorf("recent = %+v", r[0])
+	}
+	if list, _ := DeadLetters(); len(list) != 0 {
+		t.Errorf("dead letters = %+v", list)
+	}
+}
+
+func TestDeadLetter(t *testing.T) {
+	SetSpool(t.TempDir())
+	defer SetSpool("")
+
+	status := http.StatusInternalServerError
+	var token string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = r.Header.Get("X-Token")
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	d := &conf.Destination{URL: srv.URL, Retries: 1, Backoff: time.Millisecond}
+	if err := Send(d, map[string]string{"talker": "wxid_a"}); err == nil {
+		t.Fatal("send to a failing server succeeded")
+	}
+	list, err := DeadLetters()
+	if err != nil || len(list) != 1 || list[0].Attempts != 2 || list[0].Status != StatusFailed {
+		t.Fatalf("dead letters = %+v, %v", list, err)
+	}
+
+	// 重新推送时使用 URL 相同的推送目标的请求头
+	status = http.StatusOK
+	dests := map[string]*conf.Destination{"relay": {URL: srv.URL, Headers: map[string]string{"X-Token": "t"}}}
+	if _, err := Redeliver(list[0].ID, dests); err != nil {
+		t.Fatal(err)
+	}
+	if token != "t" {
+		t.Errorf("redelivered without configured headers")
+	}
+	if list, _ := DeadLetters(); len(list) != 0 {
+		t.Errorf("dead letter not removed after redelivery")
+	}
+	if _, err := Redeliver("../../etc/passwd", dests); err == nil {
+		t.Error("invalid id accepted")
+	}
+}
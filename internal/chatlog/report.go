@@ -0,0 +1,107 @@
+package chatlog
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+	"github.com/DanielMao1/chatlog/internal/chatlog/database"
+	"github.com/DanielMao1/chatlog/internal/chatlog/report"
+	"github.com/DanielMao1/chatlog/internal/errors"
+	"github.com/DanielMao1/chatlog/internal/model"
+)
+
+// CommandReport 生成 year 年的年度报告，output 不为空时写为 HTML 文件
+func (m *Manager) CommandReport(configPath string, cmdConf map[string]any, year, top int, output string) (*report.Report, error) {
+
+	var err error
+	m.sc, m.scm, err = conf.LoadServiceConfig(configPath, cmdConf)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(m.sc.GetWorkDir()) == 0 {
+		return nil, errors.ConfigRequired("workDir")
+	}
+
+	m.db = database.NewService(m.sc)
+	if err := m.db.Start(); err != nil {
+		return nil, err
+	}
+	defer m.db.Stop()
+
+	r, err := report.Build(m.runCtx, &reportSource{db: m.db, names: make(map[string]string)}, year, top)
+	if err != nil {
+		return nil, err
+	}
+	if len(output) == 0 {
+		return r, nil
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.WriteHTML(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return r, f.Close()
+}
+
+// reportSource 从数据库读取年度报告所需的数据
+type reportSource struct {
+	db    *database.Service
+	names map[string]string // 已查询的显示名称
+}
+
+func (src *reportSource) Counts(ctx context.Context, start, end time.Time) ([]*report.Count, error) {
+	counts, err := src.db.CountMessages(ctx, start, end, "")
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*report.Count, 0, len(counts))
+	for _, c := range counts {
+		ret = append(ret, &report.Count{
+			Talker: c.Talker,
+			Day:    c.Day,
+			Media:  model.MediaType(c.Type, c.SubType),
+			Count:  c.Count,
+		})
+	}
+	return ret, nil
+}
+
+func (src *reportSource) Messages(ctx context.Context, talker string, start, end time.Time) ([]*report.Message, error) {
+	messages, err := src.db.GetMessages(ctx, start, end, talker, "", "", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*report.Message, 0, len(messages))
+	for _, m := range messages {
+		rm := &report.Message{Time: m.Time, IsSelf: m.IsSelf}
+		if m.Type == model.MessageTypeText {
+			rm.Text = m.Content
+		}
+		ret = append(ret, rm)
+	}
+	return ret, nil
+}
+
+func (src *reportSource) Name(ctx context.Context, talker string) string {
+	if name, ok := src.names[talker]; ok {
+		return name
+	}
+	name := talker
+	if strings.HasSuffix(talker, "@chatroom") {
+		if resp, err := src.db.GetChatRooms(ctx, talker, 1, 0); err == nil && len(resp.Items) > 0 && len(resp.Items[0].DisplayName()) != 0 {
+			name = resp.Items[0].DisplayName()
+		}
+	} else if resp, err := src.db.GetContacts(ctx, talker, 1, 0); err == nil && len(resp.Items) > 0 && len(resp.Items[0].DisplayName()) != 0 {
+		name = resp.Items[0].DisplayName()
+	}
+	src.names[talker] = name
+	return name
+}
@@ -0,0 +1,48 @@
+package report
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+	"slices"
+)
+
+//go:embed report.html
+var page string
+
+// mediaNames 报告中媒体类型的名称
+var mediaNames = map[string]string{
+	"image":     "图片",
+	"voice":     "语音",
+	"video":     "视频",
+	"animation": "表情包",
+	"file":      "文件",
+}
+
+var tmpl = template.Must(template.New("report").Funcs(template.FuncMap{
+	// pct 返回 n 占 total 的百分比，用于柱状图的长度
+	"pct": func(n, total int) int {
+		if total <= 0 {
+			return 0
+		}
+		return n * 100 / total
+	},
+	"max": func(values []int) int {
+		if len(values) == 0 {
+			return 0
+		}
+		return slices.Max(values)
+	},
+	"media": func(media string) string {
+		if name, ok := mediaNames[media]; ok {
+			return name
+		}
+		return media
+	},
+	"inc": func(i int) int { return i + 1 },
+}).Parse(page))
+
+// WriteHTML 将报告写为不依赖外部资源的 HTML 页面
+func (r *Report) WriteHTML(w io.Writer) error {
+	return tmpl.Execute(w, r)
+}
@@ -0,0 +1,297 @@
+// Package report 根据本地消息生成年度报告
+package report
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// DefaultTop 报告中默认列出的联系人与群聊数量
+	DefaultTop = 10
+
+	// TopEmoji 报告中列出的表情数量
+	TopEmoji = 10
+)
+
+// Count 按会话、日期和媒体类型聚合的消息数量
+type Count struct {
+	Talker string
+	Day    string // 本地时区的日期，格式为 2006-01-02
+	Media  string // 媒体类型，非多媒体消息为空
+	Count  int
+}
+
+// Message 参与统计表情与时段的一条消息
+type Message struct {
+	Time   time.Time
+	IsSelf bool
+	Text   string // 文本消息的内容，其他消息为空
+}
+
+// Source 提供生成报告所需的数据
+type Source interface {
+	// Counts 返回 [start, end] 内所有会话的消息数量
+	Counts(ctx context.Context, start, end time.Time) ([]*Count, error)
+
+	// Messages 返回聊天对象在 [start, end] 内的消息
+	Messages(ctx context.Context, talker string, start, end time.Time) ([]*Message, error)
+
+	// Name 返回聊天对象的显示名称
+	Name(ctx context.Context, talker string) string
+}
+
+// Report 一年的聊天报告
+type Report struct {
+	Year       int            `json:"year"`
+	Total      int            `json:"total"`      // 消息总数
+	Sent       int            `json:"sent"`       // 自己发送的消息数
+	Sessions   int            `json:"sessions"`   // 有消息的会话数
+	ActiveDays int            `json:"activeDays"` // 有消息的天数
+	Months     []int          `json:"months"`     // 每月的消息数，共 12 个月
+	Hours      []int          `json:"hours"`      // 每个小时的消息数，共 24 小时
+	Media      map[string]int `json:"media"`      // 多媒体消息数，key 为媒体类型
+	BusiestDay *Day           `json:"busiestDay"` // 消息最多的一天
+	Streak     *Streak        `json:"streak"`     // 连续有消息的最长天数
+	Contacts   []*Talker      `json:"contacts"`   // 消息最多的联系人
+	Rooms      []*Talker      `json:"rooms"`      // 消息最多的群聊
+	Emoji      []*Emoji       `json:"emoji"`      // 自己最常用的表情
+	LateNight  int            `json:"lateNight"`  // 凌晨 0 点到 5 点自己发送的消息数
+	Latest     string         `json:"latest"`     // 自己在凌晨发送消息的最晚时间，格式为 15:04
+}
+
+// Day 一天的消息数
+type Day struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+	Top   string `json:"top"` // 当天消息最多的聊天对象
+}
+
+// Streak 连续有消息的天数
+type Streak struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+	Days  int    `json:"days"`
+}
+
+// Talker 一个聊天对象的统计
+type Talker struct {
+	UserName string  `json:"userName"`
+	Name     string  `json:"name"`
+	Count    int     `json:"count"`
+	Streak   *Streak `json:"streak"` // 与该聊天对象连续聊天的最长天数
+}
+
+// Emoji 一个表情的使用次数
+type Emoji struct {
+	Text  string `json:"text"`
+	Count int    `json:"count"`
+}
+
+// emojiRegex 微信表情，如 [微笑]
+var emojiRegex = regexp.MustCompile(`\[[^\[\]\s]{1,6}\]`)
+
+// Build 统计 year 年的消息生成报告，top 为列出的联系人与群聊数量
+func Build(ctx context.Context, src Source, year int, top int) (*Report, error) {
+	if top <= 0 {
+		top = DefaultTop
+	}
+	start := time.Date(year, 1, 1, 0, 0, 0, 0, time.Local)
+	end := start.AddDate(1, 0, 0).Add(-time.Nanosecond)
+
+	counts, err := src.Counts(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Report{
+		Year:   year,
+		Months: make([]int, 12),
+		Hours:  make([]int, 24),
+		Media:  make(map[string]int),
+	}
+	days := make(map[string]int)
+	talkerDays := make(map[string]map[string]int)
+	byTalker := make(map[string]int)
+	for _, c := range counts {
+		t, err := time.ParseInLocation("2006-01-02", c.Day, time.Local)
+		if err != nil || t.Year() != year {
+			continue
+		}
+		r.Total += c.Count
+		r.Months[t.Month()-1] += c.Count
+		if len(c.Media) != 0 {
+			r.Media[c.Media] += c.Count
+		}
+		days[c.Day] += c.Count
+		byTalker[c.Talker] += c.Count
+		if talkerDays[c.Talker] == nil {
+			talkerDays[c.Talker] = make(map[string]int)
+		}
+		talkerDays[c.Talker][c.Day] += c.Count
+	}
+	r.Sessions = len(byTalker)
+	r.ActiveDays = len(days)
+	r.Streak = longestStreak(days)
+
+	for day, n := range days {
+		if r.BusiestDay == nil || n > r.BusiestDay.Count || (n == r.BusiestDay.Count && day < r.BusiestDay.Date) {
+			r.BusiestDay = &Day{Date: day, Count: n}
+		}
+	}
+	if r.BusiestDay != nil {
+		best := 0
+		for talker, d := range talkerDays {
+			if n := d[r.BusiestDay.Date]; n > best {
+				best = n
+				r.BusiestDay.Top = src.Name(ctx, talker)
+			}
+		}
+	}
+
+	for talker, n := range byTalker {
+		t := &Talker{UserName: talker, Count: n}
+		if strings.HasSuffix(talker, "@chatroom") {
+			r.Rooms = append(r.Rooms, t)
+		} else {
+			r.Contacts = append(r.Contacts, t)
+		}
+	}
+	r.Contacts = sortTalkers(r.Contacts, top)
+	r.Rooms = sortTalkers(r.Rooms, top)
+	for _, t := range append(r.Contacts, r.Rooms...) {
+		t.Name = src.Name(ctx, t.UserName)
+		t.Streak = longestStreak(talkerDays[t.UserName])
+	}
+
+	// 表情与时段需要读取消息内容，逐个会话读取以控制内存占用
+	emoji := make(map[string]int)
+	latest := -1
+	talkers := make([]string, 0, len(byTalker))
+	for talker := range byTalker {
+		talkers = append(talkers, talker)
+	}
+	sort.Strings(talkers)
+	for _, talker := range talkers {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		messages, err := src.Messages(ctx, talker, start, end)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", talker, err)
+		}
+		for _, m := range messages {
+			r.Hours[m.Time.Hour()]++
+			if !m.IsSelf {
+				continue
+			}
+			r.Sent++
+			if m.Time.Hour() < 5 {
+				r.LateNight++
+				if minutes := m.Time.Hour()*60 + m.Time.Minute(); minutes > latest {
+					latest = minutes
+				}
+			}
+			for _, e := range Emojis(m.Text) {
+				emoji[e]++
+			}
+		}
+	}
+	if latest >= 0 {
+		r.Latest = fmt.Sprintf("%02d:%02d", latest/60, latest%60)
+	}
+	for text, n := range emoji {
+		r.Emoji = append(r.Emoji, &Emoji{Text: text, Count: n})
+	}
+	sort.Slice(r.Emoji, func(i, j int) bool {
+		if r.Emoji[i].Count != r.Emoji[j].Count {
+			return r.Emoji[i].Count > r.Emoji[j].Count
+		}
+		return r.Emoji[i].Text < r.Emoji[j].Text
+	})
+	if len(r.Emoji) > TopEmoji {
+		r.Emoji = r.Emoji[:TopEmoji]
+	}
+
+	return r, nil
+}
+
+// Emojis 返回文本中的微信表情与 emoji
+func Emojis(text string) []string {
+	ret := emojiRegex.FindAllString(text, -1)
+	text = emojiRegex.ReplaceAllString(text, " ")
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		if !isEmoji(runes[i]) {
+			continue
+		}
+		// 连同后面的变体选择符与肤色修饰符作为一个表情
+		j := i + 1
+		for j < len(runes) && (runes[j] == 0xFE0F || (runes[j] >= 0x1F3FB && runes[j] <= 0x1F3FF)) {
+			j++
+		}
+		ret = append(ret, string(runes[i:j]))
+		i = j - 1
+	}
+	return ret
+}
+
+// isEmoji 判断字符是否为常见的 emoji
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F300 && r <= 0x1F5FF, // 符号与图形
+		r >= 0x1F600 && r <= 0x1F64F, // 表情
+		r >= 0x1F680 && r <= 0x1F6FF, // 交通与地图
+		r >= 0x1F900 && r <= 0x1FAFF, // 补充符号与图形
+		r >= 0x2600 && r <= 0x27BF:   // 杂项符号与装饰符号
+		return !(r >= 0x1F3FB && r <= 0x1F3FF) && unicode.IsGraphic(r)
+	}
+	return false
+}
+
+// longestStreak 返回连续有消息的最长天数，days 的 key 为 2006-01-02
+func longestStreak(days map[string]int) *Streak {
+	if len(days) == 0 {
+		return nil
+	}
+	dates := make([]string, 0, len(days))
+	for day := range days {
+		dates = append(dates, day)
+	}
+	sort.Strings(dates)
+
+	best := &Streak{Start: dates[0], End: dates[0], Days: 1}
+	cur := *best
+	for i := 1; i < len(dates); i++ {
+		prev, _ := time.Parse("2006-01-02", dates[i-1])
+		if prev.AddDate(0, 0, 1).Format("2006-01-02") == dates[i] {
+			cur.End = dates[i]
+			cur.Days++
+		} else {
+			cur = Streak{Start: dates[i], End: dates[i], Days: 1}
+		}
+		if cur.Days > best.Days {
+			*best = cur
+		}
+	}
+	return best
+}
+
+// sortTalkers 按消息数量降序排序，并截取前 top 项
+func sortTalkers(talkers []*Talker, top int) []*Talker {
+	sort.Slice(talkers, func(i, j int) bool {
+		if talkers[i].Count != talkers[j].Count {
+			return talkers[i].Count > talkers[j].Count
+		}
+		return talkers[i].UserName < talkers[j].UserName
+	})
+	if len(talkers) > top {
+		talkers = talkers[:top]
+	}
+	return talkers
+}
@@ -0,0 +1,98 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testSource struct {
+	counts   []*Count
+	messages map[string][]*Message
+}
+
+func (s *testSource) Counts(ctx context.Context, start, end time.Time) ([]*Count, error) {
+	return s.counts, nil
+}
+
+func (s *testSource) Messages(ctx context.Context, talker string, start, end time.Time) ([]*Message, error) {
+	return s.messages[talker], nil
+}
+
+func (s *testSource) Name(ctx context.Context, talker string) string {
+	return strings.ToUpper(talker)
+}
+
+func TestBuild(t *testing.T) {
+	at := func(s string) time.Time {
+		tm, _ := time.ParseInLocation("2006-01-02 15:04", s, time.Local)
+		return tm
+	}
+	src := &testSource{
+		counts: []*Count{
+			{Talker: "alice", Day: "2024-01-30", Count: 3},
+			{Talker: "alice", Day: "2024-01-31", Count: 2},
+			{Talker: "alice", Day: "2024-02-01", Count: 1, Media: "image"},
+			{Talker: "bob", Day: "2024-02-01", Count: 1},
+			{Talker: "team@chatroom", Day: "2024-03-05", Count: 10},
+			{Talker: "alice", Day: "2023-12-31", Count: 100},
+		},
+		messages: map[string][]*Message{
+			"alice": {
+				{Time: at("2024-01-30 02:30"), IsSelf: true, Text: "晚安[月亮]🌙"},
+				{Time: at("2024-01-30 03:15"), IsSelf: true, Text: "[月亮]"},
+				{Time: at("2024-01-31 10:00"), Text: "👍🏻"},
+			},
+		},
+	}
+
+	r, err := Build(context.Background(), src, 2024, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Total != 17 || r.Sessions != 3 || r.ActiveDays != 4 || r.Sent != 2 {
+		t.Errorf("Total, Sessions, ActiveDays, Sent = %d, %d, %d, %d", r.Total, r.Sessions, r.ActiveDays, r.Sent)
+	}
+	if r.Months[0] != 5 || r.Months[1] != 2 || r.Months[2] != 10 || r.Media["image"] != 1 {
+		t.Errorf("Months = %v, Media = %v", r.Months, r.Media)
+	}
+	if want := (&Day{Date: "2024-03-05", Count: 10, Top: "TEAM@CHATROOM"}); !reflect.DeepEqual(r.BusiestDay, want) {
+		t.Errorf("BusiestDay = %+v, want %+v", r.BusiestDay, want)
+	}
+	if want := (&Streak{Start: "2024-01-30", End: "2024-02-01", Days: 3}); !reflect.DeepEqual(r.Streak, want) {
+		t.Errorf("Streak = %+v, want %+v", r.Streak, want)
+	}
+	if len(r.Contacts) != 1 || r.Contacts[0].Name != "ALICE" || r.Contacts[0].Count != 6 || r.Contacts[0].Streak.Days != 3 {
+		t.Errorf("Contacts = %+v", r.Contacts)
+	}
+	if len(r.Rooms) != 1 || r.Rooms[0].Count != 10 {
+		t.Errorf("Rooms = %+v", r.Rooms)
+	}
+	if want := []*Emoji{{"[月亮]", 2}, {"🌙", 1}}; !reflect.DeepEqual(r.Emoji, want) {
+		t.Errorf("Emoji = %+v, want %+v", r.Emoji, want)
+	}
+	if r.LateNight != 2 || r.Latest != "03:15" {
+		t.Errorf("LateNight, Latest = %d, %q", r.LateNight, r.Latest)
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteHTML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"2024 年度报告", "2024-03-05", "ALICE", "[月亮] × 2", "图片"} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("HTML does not contain %q", s)
+		}
+	}
+}
+
+func TestEmojis(t *testing.T) {
+	got := Emojis("好的[OK]👍🏻❤️ 123 [图片")
+	want := []string{"[OK]", "👍🏻", "❤️"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Emojis = %q, want %q", got, want)
+	}
+}
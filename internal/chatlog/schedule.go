@@ -0,0 +1,337 @@
+package chatlog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/backup"
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+	"github.com/DanielMao1/chatlog/internal/chatlog/mail"
+	"github.com/DanielMao1/chatlog/internal/chatlog/schedule"
+	"github.com/DanielMao1/chatlog/internal/chatlog/summarize"
+
+	"github.com/rs/zerolog/log"
+)
+
+// 定时任务名称
+const (
+	JobExport  = "export"
+	JobBackup  = "backup"
+	JobSummary = "summary"
+	JobEmail   = "email"
+	JobVault   = "vault"
+)
+
+// SummaryPeriod 定时总结推送的消息时长
+const SummaryPeriod = 24 * time.Hour
+
+// startScheduler 按配置启动定时任务，配置修改后重新调用以替换任务
+func (m *Manager) startScheduler() {
+	jobs := m.scheduleJobs(m.ctx.GetSchedule())
+	if m.scheduler == nil {
+		if len(jobs) == 0 {
+			return
+		}
+		m.scheduler = schedule.New()
+	}
+	m.scheduler.Start(jobs)
+}
+
+// scheduleJobs 返回配置中开启的定时任务，执行时间无效的任务不启动
+func (m *Manager) scheduleJobs(s *conf.Schedule) []*schedule.Job {
+	if s == nil {
+		return nil
+	}
+	var jobs []*schedule.Job
+	add := func(name, clock string, weekday int, run func() error) {
+		if len(clock) == 0 {
+			return
+		}
+		at, err := schedule.ParseClock(clock)
+		if err == nil && (weekday < schedule.Daily || weekday > 6) {
+			err = fmt.Errorf("invalid weekday %d", weekday)
+		}
+		if err != nil {
+			log.Warn().Err(err).Str("job", name).Msg("skip scheduled job")
+			return
+		}
+		jobs = append(jobs, &schedule.Job{Name: name, At: at, Weekday: weekday, Run: run})
+	}
+	if j := s.Export; j != nil {
+		add(JobExport, j.At, schedule.Daily, func() error { return m.runExportJob(j) })
+	}
+	if j := s.Backup; j != nil {
+		add(JobBackup, j.At, j.Weekday, func() error { return m.runBackupJob(j) })
+	}
+	if j := s.Summary; j != nil {
+		add(JobSummary, j.At, schedule.Daily, func() error { return m.runSummaryJob(j) })
+	}
+	if j := s.Email; j != nil {
+		add(JobEmail, j.At, schedule.Daily, func() error { return m.runEmailJob(j) })
+	}
+	if j := s.Vault; j != nil {
+		add(JobVault, j.At, schedule.Daily, func() error { return m.runVaultJob(j) })
+	}
+	return jobs
+}
+
+// ScheduleStatus 返回定时任务的下一次执行时间与最近一次执行结果
+func (m *Manager) ScheduleStatus() []schedule.Status {
+	if m.scheduler == nil {
+		return nil
+	}
+	return m.scheduler.Status()
+}
+
+// HasScheduledJobs 是否有开启的定时任务
+func (m *Manager) HasScheduledJobs() bool {
+	return m.scheduler != nil && m.scheduler.HasJobs()
+}
+
+// SetExportJob 设置每天导出前一天聊天记录的任务，at 为空时关闭
+func (m *Manager) SetExportJob(at, talkers, format, dir string) error {
+	var job *conf.ExportJob
+	if at = strings.TrimSpace(at); len(at) != 0 {
+		if _, err := schedule.ParseClock(at); err != nil {
+			return err
+		}
+		job = &conf.ExportJob{At: at, Talkers: splitTalkers(talkers), Format: format, Dir: strings.TrimSpace(dir)}
+		if len(job.Talkers) == 0 {
+			return fmt.Errorf("at least one talker is required")
+		}
+		if len(job.Format) == 0 {
+			job.Format = ExportText
+		}
+		if !slices.Contains(ExportFormats, job.Format) {
+			return fmt.Errorf("invalid format %q, use %s", format, strings.Join(ExportFormats, ", "))
+		}
+	}
+	return m.updateSchedule(func(s *conf.Schedule) { s.Export = job })
+}
+
+// SetBackupJob 设置每周备份的任务，weekday 0 为星期日，at 为空时关闭
+func (m *Manager) SetBackupJob(at string, weekday int, dir, keep string) error {
+	var job *conf.BackupJob
+	if at = strings.TrimSpace(at); len(at) != 0 {
+		if _, err := schedule.ParseClock(at); err != nil {
+			return err
+		}
+		if weekday < 0 || weekday > 6 {
+			return fmt.Errorf("invalid weekday %d", weekday)
+		}
+		job = &conf.BackupJob{At: at, Weekday: weekday, Dir: strings.TrimSpace(dir)}
+		if keep = strings.TrimSpace(keep); len(keep) != 0 {
+			n, err := strconv.Atoi(keep)
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid keep %q, use a number, 0 to keep all", keep)
+			}
+			job.Keep = n
+		}
+	}
+	return m.updateSchedule(func(s *conf.Schedule) { s.Backup = job })
+}
+
+// SetSummaryJob 设置每天总结并推送的任务，to 为空时使用 webhook，at 为空时关闭
+func (m *Manager) SetSummaryJob(at, talkers, to string) error {
+	var job *conf.SummaryJob
+	if at = strings.TrimSpace(at); len(at) != 0 {
+		if _, err := schedule.ParseClock(at); err != nil {
+			return err
+		}
+		job = &conf.SummaryJob{At: at, Talkers: splitTalkers(talkers), To: strings.TrimSpace(to)}
+		if len(job.Talkers) == 0 {
+			return fmt.Errorf("at least one talker is required")
+		}
+		if len(job.To) == 0 {
+			job.To = summarize.ToWebhook
+		}
+		dest, err := summarize.Target(job.To, m.ctx.GetSummarize(), m.ctx.GetDestinations())
+		if err != nil {
+			return err
+		}
+		if dest == nil {
+			return fmt.Errorf("scheduled summary needs a target, use webhook, a destination name or a URL")
+		}
+	}
+	return m.updateSchedule(func(s *conf.Schedule) { s.Summary = job })
+}
+
+// SetEmailJob 设置每天发送邮件摘要的任务，to 为逗号分隔的收件人，为空时使用 smtp.to，at 为空时关闭
+func (m *Manager) SetEmailJob(at, talkers, to string) error {
+	var job *conf.EmailJob
+	if at = strings.TrimSpace(at); len(at) != 0 {
+		if _, err := schedule.ParseClock(at); err != nil {
+			return err
+		}
+		job = &conf.EmailJob{At: at, Talkers: splitTalkers(talkers), To: splitTalkers(to)}
+		if len(job.Talkers) == 0 {
+			return fmt.Errorf("at least one talker is required")
+		}
+		c := m.ctx.GetSMTP()
+		if c == nil || len(c.Host) == 0 {
+			return fmt.Errorf("smtp.host is not configured")
+		}
+		if len(job.To) == 0 && len(c.To) == 0 {
+			return fmt.Errorf("at least one recipient is required, set it here or in smtp.to")
+		}
+	}
+	return m.updateSchedule(func(s *conf.Schedule) { s.Email = job })
+}
+
+// SetVaultJob 设置每天同步笔记库的任务，dir 为空时同步到工作目录旁的 vault 目录，git 为 true 时同步后提交到 git 仓库，at 为空时关闭
+func (m *Manager) SetVaultJob(at, talkers, dir string, git bool) error {
+	var job *conf.VaultJob
+	if at = strings.TrimSpace(at); len(at) != 0 {
+		if _, err := schedule.ParseClock(at); err != nil {
+			return err
+		}
+		job = &conf.VaultJob{At: at, Talkers: splitTalkers(talkers), Dir: strings.TrimSpace(dir), Git: git}
+		if len(job.Talkers) == 0 {
+			return fmt.Errorf("at least one talker is required")
+		}
+	}
+	return m.updateSchedule(func(s *conf.Schedule) { s.Vault = job })
+}
+
+// updateSchedule 修改定时任务配置，保存后重新启动调度
+func (m *Manager) updateSchedule(update func(s *conf.Schedule)) error {
+	s := &conf.Schedule{}
+	if old := m.ctx.GetSchedule(); old != nil {
+		*s = *old
+	}
+	update(s)
+	if err := m.ctx.SetSchedule(s); err != nil {
+		return err
+	}
+	m.startScheduler()
+	return nil
+}
+
+// runExportJob 将每个聊天对象前一天的消息导出到单独的文件
+func (m *Manager) runExportJob(j *conf.ExportJob) error {
+	dir := j.Dir
+	if len(dir) == 0 {
+		if len(m.ctx.WorkDir) == 0 {
+			return fmt.Errorf("work dir is not configured")
+		}
+		dir = filepath.Join(filepath.Dir(m.ctx.WorkDir), "export")
+	}
+	date := time.Now().AddDate(0, 0, -1).Format("20060102")
+	var errs []error
+	for _, talker := range j.Talkers {
+		path := filepath.Join(dir, fmt.Sprintf("%s_%s.%s", safeFileName(talker), date, ExportExt(j.Format)))
+		count, err := m.Export(talker, "yesterday", j.Format, path, j.Translate, j.Script)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", talker, err))
+			continue
+		}
+		log.Info().Str("talker", talker).Int("count", count).Str("file", path).Msg("scheduled export finished")
+	}
+	return errors.Join(errs...)
+}
+
+// runBackupJob 备份当前账号的工作目录与配置文件
+func (m *Manager) runBackupJob(j *conf.BackupJob) error {
+	workDir := m.ctx.WorkDir
+	if len(workDir) == 0 {
+		return fmt.Errorf("work dir is not configured")
+	}
+	dir := j.Dir
+	if len(dir) == 0 {
+		dir = filepath.Join(filepath.Dir(workDir), "backup")
+	}
+	// 密码从环境变量读取，不保存在配置文件中；上传到远程存储时必须设置
+	result, err := backup.Create(backup.Options{
+		WorkDir:   workDir,
+		ConfigDir: m.ctx.GetConfigDir(),
+		OutputDir: dir,
+		Password:  os.Getenv(backup.EnvPassword),
+		Keep:      j.Keep,
+		Remote:    m.ctx.GetBackupRemote(),
+	}, &backup.Manifest{
+		Account:     m.ctx.Account,
+		Platform:    m.ctx.Platform,
+		Version:     m.ctx.Version,
+		FullVersion: m.ctx.FullVersion,
+		DataDir:     m.ctx.DataDir,
+		DataKey:     m.ctx.DataKey,
+		ImgKey:      m.ctx.ImgKey,
+		WorkDir:     workDir,
+	})
+	if err != nil {
+		return err
+	}
+	log.Info().Str("file", result.File).Int64("size", result.Size).Str("uploaded", result.Uploaded).Msg("scheduled backup finished")
+	return nil
+}
+
+// runSummaryJob 总结每个聊天对象最近一天的消息并推送
+func (m *Manager) runSummaryJob(j *conf.SummaryJob) error {
+	var errs []error
+	for _, talker := range j.Talkers {
+		if _, err := m.Summarize(talker, SummaryPeriod, j.To); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", talker, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runEmailJob 总结每个聊天对象最近一天的消息，合并为一封邮件发送，没有消息的聊天对象不列出
+func (m *Manager) runEmailJob(j *conf.EmailJob) error {
+	if m.db.GetDB() == nil {
+		if err := m.db.Start(); err != nil {
+			return err
+		}
+	}
+	var errs []error
+	payloads := make([]*summarize.Payload, 0, len(j.Talkers))
+	for _, talker := range j.Talkers {
+		payload, err := m.buildSummary(m.ctx.GetLLM(), talker, SummaryPeriod)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", talker, err))
+			continue
+		}
+		if payload != nil {
+			payloads = append(payloads, payload)
+		}
+	}
+
+	c := m.ctx.GetSMTP()
+	if c == nil {
+		return errors.Join(append(errs, fmt.Errorf("smtp is not configured"))...)
+	}
+	msg := summarize.Email(payloads, time.Now())
+	msg.To = j.To
+	if err := mail.Send(c, msg); err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	log.Info().Int("talkers", len(payloads)).Msg("scheduled email digest sent")
+	return errors.Join(errs...)
+}
+
+// splitTalkers 解析逗号分隔的聊天对象
+func splitTalkers(text string) []string {
+	var talkers []string
+	for _, t := range strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == '，' }) {
+		if t = strings.TrimSpace(t); len(t) != 0 {
+			talkers = append(talkers, t)
+		}
+	}
+	return talkers
+}
+
+// safeFileName 替换文件名中不允许的字符
+func safeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+}
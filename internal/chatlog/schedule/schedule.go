@@ -0,0 +1,200 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Daily 每天执行的任务使用的 Weekday
+const Daily = -1
+
+// Job 定时任务
+type Job struct {
+	Name string
+	// At 执行时间，从零点开始的时长
+	At time.Duration
+	// Weekday 每周执行的星期，Daily 表示每天执行
+	Weekday int
+	Run     func() error
+}
+
+// Next 返回 after 之后下一次执行的时间
+func (j *Job) Next(after time.Time) time.Time {
+	day := time.Date(after.Year(), after.Month(), after.Day(), 0, 0, 0, 0, after.Location())
+	for i := 0; i <= 7; i++ {
+		d := day.AddDate(0, 0, i)
+		if j.Weekday != Daily && int(d.Weekday()) != j.Weekday {
+			continue
+		}
+		// 按日期加时长，夏令时切换当天也落在同一个钟点
+		t := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, d.Location()).Add(j.At)
+		if t.After(after) {
+			return t
+		}
+	}
+	return day.AddDate(0, 0, 8).Add(j.At)
+}
+
+// ParseClock 解析 HH:MM 形式的时间，返回从零点开始的时长
+func ParseClock(s string) (time.Duration, error) {
+	h, m, ok := strings.Cut(strings.TrimSpace(s), ":")
+	hour, err1 := strconv.Atoi(h)
+	minute, err2 := strconv.Atoi(m)
+	if !ok || err1 != nil || err2 != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return 0, fmt.Errorf("invalid time %q, use HH:MM such as 03:00", s)
+	}
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute, nil
+}
+
+// Status 任务的执行情况
+type Status struct {
+	Name    string
+	Next    time.Time
+	LastRun time.Time
+	LastErr error
+}
+
+// Scheduler 按时间依次执行定时任务，同一时间只执行一个任务
+type Scheduler struct {
+	mu     sync.Mutex
+	jobs   []*Job
+	status map[string]*Status
+	reset  chan struct{}
+	stop   chan struct{}
+	now    func() time.Time
+}
+
+func New() *Scheduler {
+	return &Scheduler{
+		status: make(map[string]*Status),
+		now:    time.Now,
+	}
+}
+
+// Start 替换当前的任务并开始调度，已在运行时重新计算下一次执行的时间
+func (s *Scheduler) Start(jobs []*Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = jobs
+	now := s.now()
+	status := make(map[string]*Status, len(jobs))
+	for _, j := range jobs {
+		st := &Status{Name: j.Name}
+		if old, ok := s.status[j.Name]; ok {
+			st.LastRun, st.LastErr = old.LastRun, old.LastErr
+		}
+		st.Next = j.Next(now)
+		status[j.Name] = st
+	}
+	s.status = status
+
+	if s.stop == nil {
+		s.stop = make(chan struct{})
+		s.reset = make(chan struct{}, 1)
+		go s.loop(s.stop, s.reset)
+		return
+	}
+	select {
+	case s.reset <- struct{}{}:
+	default:
+	}
+}
+
+// Stop 停止调度，正在执行的任务会执行完
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+}
+
+// HasJobs 是否有需要调度的任务
+func (s *Scheduler) HasJobs() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.jobs) != 0
+}
+
+// Status 返回各任务的执行情况，按下一次执行的时间排序
+func (s *Scheduler) Status() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make([]Status, 0, len(s.status))
+	for _, st := range s.status {
+		result = append(result, *st)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Next.Before(result[j].Next)
+	})
+	return result
+}
+
+func (s *Scheduler) loop(stop, reset chan struct{}) {
+	for {
+		job, at := s.next()
+		var timer *time.Timer
+		var wait <-chan time.Time
+		if job != nil {
+			timer = time.NewTimer(at.Sub(s.now()))
+			wait = timer.C
+		}
+		select {
+		case <-stop:
+		case <-reset:
+		case <-wait:
+			s.run(job)
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		select {
+		case <-stop:
+			return
+		default:
+		}
+	}
+}
+
+// next 返回最早需要执行的任务
+func (s *Scheduler) next() (*Job, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var job *Job
+	var at time.Time
+	for _, j := range s.jobs {
+		if next := s.status[j.Name].Next; job == nil || next.Before(at) {
+			job, at = j, next
+		}
+	}
+	return job, at
+}
+
+func (s *Scheduler) run(job *Job) {
+	start := s.now()
+	log.Info().Str("job", job.Name).Msg("scheduled job started")
+	err := job.Run()
+	if err != nil {
+		log.Err(err).Str("job", job.Name).Msg("scheduled job failed")
+	} else {
+		log.Info().Str("job", job.Name).Dur("elapsed", s.now().Sub(start)).Msg("scheduled job finished")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 执行期间任务可能已被替换，按当前的任务计算下一次执行的时间
+	for _, j := range s.jobs {
+		if j.Name == job.Name {
+			st := s.status[j.Name]
+			st.LastRun, st.LastErr = start, err
+			st.Next = j.Next(s.now())
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseClock(t *testing.T) {
+	if d, err := ParseClock("03:30"); err != nil || d != 3*time.Hour+30*time.Minute {
+		t.Errorf("ParseClock(03:30) = %v, %v", d, err)
+	}
+	for _, s := range []string{"", "3", "24:00", "12:60", "a:b"} {
+		if _, err := ParseClock(s); err == nil {
+			t.Errorf("ParseClock(%q) should fail", s)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	// 2024-01-03 是星期三
+	now := time.Date(2024, 1, 3, 10, 0, 0, 0, time.Local)
+	tests := []struct {
+		job  Job
+		want time.Time
+	}{
+		{Job{At: 12 * time.Hour, Weekday: Daily}, time.Date(2024, 1, 3, 12, 0, 0, 0, time.Local)},
+		{Job{At: 3 * time.Hour, Weekday: Daily}, time.Date(2024, 1, 4, 3, 0, 0, 0, time.Local)},
+		{Job{At: 10 * time.Hour, Weekday: Daily}, time.Date(2024, 1, 4, 10, 0, 0, 0, time.Local)},
+		{Job{At: 3 * time.Hour, Weekday: int(time.Sunday)}, time.Date(2024, 1, 7, 3, 0, 0, 0, time.Local)},
+		{Job{At: 12 * time.Hour, Weekday: int(time.Wednesday)}, time.Date(2024, 1, 3, 12, 0, 0, 0, time.Local)},
+		{Job{At: 3 * time.Hour, Weekday: int(time.Wednesday)}, time.Date(2024, 1, 10, 3, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		if got := tt.job.Next(now); !got.Equal(tt.want) {
+			t.Errorf("Next(at=%s, weekday=%d) = %s, want %s", tt.job.At, tt.job.Weekday, got, tt.want)
+		}
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	now := time.Now()
+	s := New()
+	done := make(chan struct{})
+	job := &Job{Name: "test", Weekday: Daily, Run: func() error {
+		close(done)
+		return nil
+	}}
+	// 执行时间设为 now 之后片刻，当天的时长
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	job.At = now.Add(50 * time.Millisecond).Sub(day)
+	if job.At >= 24*time.Hour {
+		t.Skip("too close to midnight")
+	}
+	s.Start([]*Job{job})
+	defer s.Stop()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("job did not run")
+	}
+	// 任务返回后才更新执行情况
+	var st []Status
+	for i := 0; i < 100; i++ {
+		if st = s.Status(); len(st) == 1 && !st[0].LastRun.IsZero() {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(st) != 1 || st[0].LastRun.IsZero() || !st[0].Next.After(now.Add(time.Hour)) {
+		t.Errorf("unexpected status %+v", st)
+	}
+}
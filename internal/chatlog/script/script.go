@@ -0,0 +1,130 @@
+// Package script 运行用户编写的短脚本，在导出与 webhook 推送前过滤、改写消息或改变推送目标
+//
+// 脚本使用 Go 的 text/template 语法，数据为消息序列化为 JSON 后的对象，字段名与 JSON 一致，如 .content、.talker。
+// 脚本的输出被忽略，通过以下函数产生效果：
+//
+//	drop                  不导出、不推送这条消息
+//	set "content" value   修改字段，可以用 . 分隔修改嵌套的字段，如 "contents.title"
+//	route "name" ...      推送到这些推送目标，代替 webhook 配置的地址，只在 webhook 中有效
+//
+// 另外提供 contains、hasPrefix、hasSuffix、lower、upper、trim、replace、split、join、match（正则匹配）与 json 函数。
+package script
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// Result 对一条消息运行脚本的结果
+type Result struct {
+	Drop   bool     // 丢弃这条消息
+	Routes []string // 推送到这些推送目标，为空时使用默认的目标
+}
+
+// Script 编译后的脚本，可以在多个 goroutine 中使用
+type Script struct {
+	tmpl *template.Template
+
+	// mu 保护一次运行中的状态，同时只运行一次
+	mu      sync.Mutex
+	data    map[string]any
+	result  *Result
+	changed bool
+}
+
+// Compile 编译脚本，src 为空时返回 nil
+func Compile(src string) (*Script, error) {
+	if len(strings.TrimSpace(src)) == 0 {
+		return nil, nil
+	}
+	s := &Script{}
+	funcs := template.FuncMap{
+		"drop": func() string {
+			s.result.Drop = true
+			return ""
+		},
+		"set": func(key string, value any) (string, error) {
+			if err := setPath(s.data, key, value); err != nil {
+				return "", err
+			}
+			s.changed = true
+			return "", nil
+		},
+		"route": func(names ...string) string {
+			s.result.Routes = append(s.result.Routes, names...)
+			return ""
+		},
+		"contains":  strings.Contains,
+		"hasPrefix": strings.HasPrefix,
+		"hasSuffix": strings.HasSuffix,
+		"lower":     strings.ToLower,
+		"upper":     strings.ToUpper,
+		"trim":      strings.TrimSpace,
+		"replace":   strings.ReplaceAll,
+		"split":     strings.Split,
+		"join":      func(elems []string, sep string) string { return strings.Join(elems, sep) },
+		"match":     regexp.MatchString,
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}
+	tmpl, err := template.New("script").Option("missingkey=zero").Funcs(funcs).Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("invalid script: %v", err)
+	}
+	s.tmpl = tmpl
+	return s, nil
+}
+
+// Run 对一个值运行脚本，v 为指向结构体或 map 的指针，脚本中 set 的修改写回 v
+func (s *Script) Run(v any) (*Result, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data, s.result, s.changed = m, &Result{}, false
+	defer func() { s.data = nil }()
+	if err := s.tmpl.Execute(io.Discard, m); err != nil {
+		return nil, fmt.Errorf("run script failed: %v", err)
+	}
+	if s.changed {
+		if data, err = json.Marshal(m); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			return nil, fmt.Errorf("script set an invalid value: %v", err)
+		}
+	}
+	return s.result, nil
+}
+
+// setPath 按 . 分隔的路径设置 map 中的值，中间的对象不存在时创建
+func setPath(m map[string]any, key string, value any) error {
+	parts := strings.Split(key, ".")
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := m[p].(map[string]any)
+		if !ok {
+			if m[p] != nil {
+				return fmt.Errorf("set %s: %s is not an object", key, p)
+			}
+			next = make(map[string]any)
+			m[p] = next
+		}
+		m = next
+	}
+	m[parts[len(parts)-1]] = value
+	return nil
+}
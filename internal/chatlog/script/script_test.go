@@ -0,0 +1,56 @@
+package script
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type message struct {
+	Talker     string         `json:"talker"`
+	SenderName string         `json:"senderName"`
+	Time       time.Time      `json:"time"`
+	Type       int64          `json:"type"`
+	Content    string         `json:"content"`
+	Contents   map[string]any `json:"contents,omitempty"`
+}
+
+func TestRun(t *testing.T) {
+	s, err := Compile(`
+{{- if contains .content "广告"}}{{drop}}{{end}}
+{{- if match "1[3-9][0-9]{9}" .content}}{{set "content" (replace .content "13800000000" "***")}}{{end}}
+{{- if eq .talker "12345@chatroom"}}{{route "slack" "archive"}}{{end}}
+{{- set "contents.from" (printf "%s@%s" .senderName .talker)}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	m := &message{Talker: "12345@chatroom", SenderName: "Alice", Time: ts, Type: 1, Content: "电话 13800000000"}
+	r, err := s.Run(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Drop || strings.Join(r.Routes, ",") != "slack,archive" {
+		t.Errorf("unexpected result %+v", r)
+	}
+	if m.Content != "电话 ***" || m.Contents["from"] != "Alice@12345@chatroom" || !m.Time.Equal(ts) || m.Type != 1 {
+		t.Errorf("unexpected message %+v", m)
+	}
+
+	r, err = s.Run(&message{Talker: "wxid_a", Content: "广告：点击领取"})
+	if err != nil || !r.Drop || len(r.Routes) != 0 {
+		t.Errorf("got %+v, %v, want dropped", r, err)
+	}
+
+	if _, err := Compile("{{if}}"); err == nil {
+		t.Error("invalid script compiled")
+	}
+	if s, err := Compile("  "); s != nil || err != nil {
+		t.Errorf("empty script: %v, %v", s, err)
+	}
+	bad, _ := Compile(`{{set "content.x" 1}}`)
+	if _, err := bad.Run(&message{Content: "x"}); err == nil {
+		t.Error("set into a string succeeded")
+	}
+}
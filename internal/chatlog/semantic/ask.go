@@ -0,0 +1,88 @@
+package semantic
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/llm"
+)
+
+// AskPrompt 问答的系统提示词，要求大模型只根据提供的聊天记录回答并标注引用
+const AskPrompt = `你是聊天记录问答助手。用户会提供若干段编号的微信聊天记录和一个问题，请只根据这些聊天记录用中文简洁地回答问题。
+回答中用 [编号] 标注依据的聊天记录，如 [1][3]；聊天记录中没有相关内容时直接说明找不到，不要编造。`
+
+// Citation 回答引用的一段聊天记录
+type Citation struct {
+	ID      int       `json:"id"` // 提示词中的编号
+	Talker  string    `json:"talker"`
+	Name    string    `json:"name"`
+	Seq     int64     `json:"seq"` // 第一条与最后一条消息的序号
+	LastSeq int64     `json:"lastSeq"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Text    string    `json:"text"`
+	Score   float32   `json:"score"`
+}
+
+// Answer 问答结果
+type Answer struct {
+	Question  string      `json:"question"`
+	Answer    string      `json:"answer"`
+	Citations []*Citation `json:"citations"` // 回答中引用的聊天记录，没有标注引用时为检索到的全部记录
+	Provider  string      `json:"provider"`
+}
+
+var citationRegexp = regexp.MustCompile(`\[(\d+)\]`)
+
+// Ask 检索与问题相关的聊天记录，交给大模型生成回答，talker 不为空时只检索该聊天对象
+func (s *Searcher) Ask(ctx context.Context, p llm.Provider, question, talker string, limit int) (*Answer, error) {
+	hits, err := s.Search(ctx, question, talker, limit)
+	if err != nil {
+		return nil, err
+	}
+	answer := &Answer{Question: question, Provider: p.Name()}
+	if len(hits) == 0 {
+		answer.Answer = "索引中没有相关的聊天记录。"
+		return answer, nil
+	}
+
+	var buf strings.Builder
+	for i, h := range hits {
+		fmt.Fprintf(&buf, "[%d] %s %s ~ %s\n%s\n\n", i+1, h.Name, h.Start.Format("2006-01-02 15:04"), h.End.Format("15:04"), h.Text)
+	}
+	buf.WriteString("问题：" + question)
+
+	reply, err := p.Complete(ctx, AskPrompt, buf.String())
+	if err != nil {
+		return nil, err
+	}
+	answer.Answer = strings.TrimSpace(reply)
+
+	cited := make(map[int]bool)
+	for _, m := range citationRegexp.FindAllStringSubmatch(reply, -1) {
+		if id, err := strconv.Atoi(m[1]); err == nil && id >= 1 && id <= len(hits) {
+			cited[id] = true
+		}
+	}
+	for i, h := range hits {
+		if len(cited) != 0 && !cited[i+1] {
+			continue
+		}
+		answer.Citations = append(answer.Citations, &Citation{
+			ID:      i + 1,
+			Talker:  h.Talker,
+			Name:    h.Name,
+			Seq:     h.Seq,
+			LastSeq: h.LastSeq,
+			Start:   h.Start,
+			End:     h.End,
+			Text:    h.Text,
+			Score:   h.Score,
+		})
+	}
+	return answer, nil
+}
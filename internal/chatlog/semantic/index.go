@@ -0,0 +1,184 @@
+package semantic
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// IndexFile 工作目录中的向量索引文件
+const IndexFile = "semantic.idx"
+
+// Doc 索引中的一段聊天记录，由同一聊天对象时间相近的若干条消息组成
+type Doc struct {
+	Talker  string    `json:"talker"`
+	Name    string    `json:"name"` // 聊天对象名称
+	Seq     int64     `json:"seq"`  // 第一条消息的序号
+	LastSeq int64     `json:"lastSeq"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Text    string    `json:"text"`
+	Vector  []float32 `json:"-"` // 归一化后的向量
+}
+
+// Hit 语义搜索结果
+type Hit struct {
+	*Doc
+	Score float32 `json:"score"` // 余弦相似度
+}
+
+// Cursor 聊天对象已建立索引的最后一条消息
+type Cursor struct {
+	Seq  int64
+	Time time.Time
+}
+
+// Index 保存在工作目录中的向量索引，搜索时逐条计算相似度
+type Index struct {
+	mu      sync.RWMutex
+	model   string
+	docs    []*Doc
+	cursors map[string]Cursor
+}
+
+// indexFile 索引文件的内容
+type indexFile struct {
+	Model   string
+	Docs    []*Doc
+	Cursors map[string]Cursor
+}
+
+// Load 读取索引文件，文件不存在或 model 与索引使用的模型不同时返回空索引
+func Load(path, model string) (*Index, error) {
+	x := &Index{model: model, cursors: make(map[string]Cursor)}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return x, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var data indexFile
+	if err := gob.NewDecoder(f).Decode(&data); err != nil {
+		return nil, fmt.Errorf("invalid index file %s: %v", path, err)
+	}
+	if data.Model != model {
+		// 不同模型的向量无法比较，重新建立索引
+		return x, nil
+	}
+	x.docs = data.Docs
+	if data.Cursors != nil {
+		x.cursors = data.Cursors
+	}
+	return x, nil
+}
+
+// Save 写入索引文件，先写入临时文件再重命名
+func (x *Index) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	x.mu.RLock()
+	data := indexFile{Model: x.model, Docs: x.docs, Cursors: x.cursors}
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err == nil {
+		err = gob.NewEncoder(f).Encode(&data)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	x.mu.RUnlock()
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Len 返回索引中的记录数量
+func (x *Index) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.docs)
+}
+
+// Cursor 返回聊天对象已建立索引的最后一条消息
+func (x *Index) Cursor(talker string) Cursor {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.cursors[talker]
+}
+
+// Add 添加记录并更新聊天对象的索引进度
+func (x *Index) Add(talker string, docs []*Doc, c Cursor) {
+	for _, d := range docs {
+		normalize(d.Vector)
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.docs = append(x.docs, docs...)
+	x.cursors[talker] = c
+}
+
+// Search 返回与向量最相似的 limit 条记录，talker 不为空时只搜索该聊天对象
+func (x *Index) Search(vector []float32, talker string, limit int) []*Hit {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	q := append([]float32(nil), vector...)
+	normalize(q)
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	hits := make([]*Hit, 0, limit+1)
+	for _, d := range x.docs {
+		if (len(talker) != 0 && d.Talker != talker) || len(d.Vector) != len(q) {
+			continue
+		}
+		score := dot(q, d.Vector)
+		if len(hits) == limit && score <= hits[len(hits)-1].Score {
+			continue
+		}
+		// 按相似度插入，只保留前 limit 条
+		i := sort.Search(len(hits), func(i int) bool { return hits[i].Score < score })
+		hits = append(hits, nil)
+		copy(hits[i+1:], hits[i:])
+		hits[i] = &Hit{Doc: d, Score: score}
+		if len(hits) > limit {
+			hits = hits[:limit]
+		}
+	}
+	return hits
+}
+
+func dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// normalize 归一化向量，之后点积即为余弦相似度
+func normalize(v []float32) {
+	var sum float64
+	for _, f := range v {
+		sum += float64(f) * float64(f)
+	}
+	if sum == 0 {
+		return
+	}
+	n := float32(math.Sqrt(sum))
+	for i := range v {
+		v[i] /= n
+	}
+}
@@ -0,0 +1,209 @@
+package semantic
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/llm"
+)
+
+const (
+	// DefaultLimit 默认返回的搜索结果数量
+	DefaultLimit = 10
+
+	// DefaultInterval 默认更新索引的间隔
+	DefaultInterval = 10 * time.Minute
+
+	// ChunkGap 相邻消息间隔超过该时长时分为不同的记录
+	ChunkGap = 10 * time.Minute
+
+	// ChunkMessages、ChunkChars 每条记录最多包含的消息数量与字数
+	ChunkMessages = 10
+	ChunkChars    = 500
+
+	// BatchSize 每次请求向量模型的记录数量
+	BatchSize = 32
+)
+
+// Message 建立索引的一条消息
+type Message struct {
+	Talker     string
+	TalkerName string
+	Seq        int64
+	Time       time.Time
+	Sender     string
+	Text       string
+}
+
+// Source 提供建立索引的聊天对象与消息
+type Source interface {
+	// Talkers 返回需要建立索引的聊天对象
+	Talkers(ctx context.Context) ([]string, error)
+	// Messages 返回聊天对象从 since 开始按时间排列的消息
+	Messages(ctx context.Context, talker string, since time.Time) ([]*Message, error)
+}
+
+// Searcher 维护工作目录中的向量索引，定期为新消息建立索引，并按语义搜索聊天记录
+type Searcher struct {
+	path     string
+	embedder llm.Embedder
+	source   Source
+
+	// mu 保证同时只有一次索引更新
+	mu    sync.Mutex
+	index *Index
+}
+
+// New 读取 path 中的索引，模型变化时重新建立索引
+func New(path string, embedder llm.Embedder, source Source) (*Searcher, error) {
+	index, err := Load(path, embedder.Name())
+	if err != nil {
+		return nil, err
+	}
+	return &Searcher{path: path, embedder: embedder, source: source, index: index}, nil
+}
+
+// Run 立即更新一次索引，之后每隔 interval 更新，直到 ctx 结束
+func (s *Searcher) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	for {
+		if n, err := s.Update(ctx); err != nil && ctx.Err() == nil {
+			log.Warn().Err(err).Msg("update semantic index failed")
+		} else if n > 0 {
+			log.Info().Int("added", n).Int("total", s.index.Len()).Msg("semantic index updated")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Update 为新消息建立索引，返回新增的记录数量，每个聊天对象完成后保存索引
+func (s *Searcher) Update(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	talkers, err := s.source.Talkers(ctx)
+	if err != nil {
+		return 0, err
+	}
+	added := 0
+	for _, talker := range talkers {
+		if err := ctx.Err(); err != nil {
+			return added, err
+		}
+		cursor := s.index.Cursor(talker)
+		messages, err := s.source.Messages(ctx, talker, cursor.Time)
+		if err != nil {
+			log.Debug().Err(err).Str("talker", talker).Msg("get messages for semantic index failed")
+			continue
+		}
+		fresh := messages[:0]
+		for _, msg := range messages {
+			if msg.Seq > cursor.Seq {
+				fresh = append(fresh, msg)
+			}
+		}
+		if len(fresh) == 0 {
+			continue
+		}
+
+		docs := Chunk(fresh)
+		for i := 0; i < len(docs); i += BatchSize {
+			batch := docs[i:min(i+BatchSize, len(docs))]
+			texts := make([]string, len(batch))
+			for j, d := range batch {
+				texts[j] = d.Text
+			}
+			vectors, err := s.embedder.Embed(ctx, texts)
+			if err != nil {
+				return added, fmt.Errorf("%s: %w", talker, err)
+			}
+			for j, d := range batch {
+				d.Vector = vectors[j]
+			}
+		}
+		last := fresh[len(fresh)-1]
+		s.index.Add(talker, docs, Cursor{Seq: last.Seq, Time: last.Time})
+		added += len(docs)
+		if err := s.index.Save(s.path); err != nil {
+			return added, err
+		}
+	}
+	return added, nil
+}
+
+// Search 按语义搜索聊天记录，talker 不为空时只搜索该聊天对象
+func (s *Searcher) Search(ctx context.Context, query, talker string, limit int) ([]*Hit, error) {
+	query = strings.TrimSpace(query)
+	if len(query) == 0 {
+		return nil, fmt.Errorf("query is required")
+	}
+	vectors, err := s.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) == 0 {
+		return nil, fmt.Errorf("empty embedding from %s", s.embedder.Name())
+	}
+	return s.index.Search(vectors[0], talker, limit), nil
+}
+
+// Len 返回索引中的记录数量
+func (s *Searcher) Len() int {
+	return s.index.Len()
+}
+
+// Chunk 将同一聊天对象按时间排列的消息合并为记录，相邻消息间隔较长或记录过长时分开，没有文字的消息跳过
+func Chunk(messages []*Message) []*Doc {
+	var docs []*Doc
+	var cur *Doc
+	var lines []string
+	count, chars := 0, 0
+	flush := func() {
+		if cur != nil {
+			cur.Text = strings.Join(lines, "\n")
+			docs = append(docs, cur)
+		}
+		cur, lines, count, chars = nil, nil, 0, 0
+	}
+	for _, msg := range messages {
+		text := strings.TrimSpace(msg.Text)
+		if len(text) == 0 {
+			continue
+		}
+		line := text
+		if len(msg.Sender) != 0 {
+			line = msg.Sender + ": " + text
+		}
+		if r := []rune(line); len(r) > ChunkChars {
+			line = string(r[:ChunkChars])
+		}
+		n := len([]rune(line))
+		if cur != nil && (msg.Time.Sub(cur.End) > ChunkGap || count >= ChunkMessages || chars+n > ChunkChars) {
+			flush()
+		}
+		if cur == nil {
+			name := msg.TalkerName
+			if len(name) == 0 {
+				name = msg.Talker
+			}
+			cur = &Doc{Talker: msg.Talker, Name: name, Seq: msg.Seq, Start: msg.Time}
+		}
+		cur.LastSeq, cur.End = msg.Seq, msg.Time
+		lines = append(lines, line)
+		count++
+		chars += n
+	}
+	flush()
+	return docs
+}
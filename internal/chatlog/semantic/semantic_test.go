@@ -0,0 +1,157 @@
+package semantic
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeEmbedder 按文本中出现的关键字生成向量
+type fakeEmbedder struct{ calls int }
+
+func (e *fakeEmbedder) Name() string { return "fake/model" }
+
+func (e *fakeEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.calls++
+	ret := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := []float32{0.1, 0.1, 0.1}
+		if strings.Contains(text, "押金") || strings.Contains(text, "房子") {
+			v[0] = 1
+		}
+		if strings.Contains(text, "会议") || strings.Contains(text, "开会") {
+			v[1] = 1
+		}
+		ret[i] = v
+	}
+	return ret, nil
+}
+
+type fakeSource map[string][]*Message
+
+func (s fakeSource) Talkers(ctx context.Context) ([]string, error) {
+	return []string{"wxid_a", "wxid_b"}, nil
+}
+
+func (s fakeSource) Messages(ctx context.Context, talker string, since time.Time) ([]*Message, error) {
+	var ret []*Message
+	for _, m := range s[talker] {
+		if !m.Time.Before(since) {
+			ret = append(ret, m)
+		}
+	}
+	return ret, nil
+}
+
+func TestChunk(t *testing.T) {
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.Local)
+	var messages []*Message
+	for i := 0; i < ChunkMessages+2; i++ {
+		messages = append(messages, &Message{Talker: "wxid_a", Seq: int64(i + 1), Time: start.Add(time.Duration(i) * time.Minute), Sender: "小王", Text: "消息"})
+	}
+	messages = append(messages,
+		&Message{Talker: "wxid_a", Seq: 100, Time: start.Add(time.Hour), Text: " "},
+		&Message{Talker: "wxid_a", Seq: 101, Time: start.Add(time.Hour), Sender: "小李", Text: "一小时后"},
+	)
+	docs := Chunk(messages)
+	if len(docs) != 3 {
+		t.Fatalf("Chunk() returned %d docs, want 3", len(docs))
+	}
+	if docs[0].Seq != 1 || docs[0].LastSeq != ChunkMessages || strings.Count(docs[0].Text, "\n") != ChunkMessages-1 {
+		t.Errorf("unexpected first doc %+v", docs[0])
+	}
+	if docs[2].Text != "小李: 一小时后" || docs[2].Name != "wxid_a" {
+		t.Errorf("unexpected last doc %+v", docs[2])
+	}
+}
+
+func TestSearcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), IndexFile)
+	day := time.Date(2024, 3, 1, 9, 0, 0, 0, time.Local)
+	src := fakeSource{
+		"wxid_a": {
+			{Talker: "wxid_a", TalkerName: "房东", Seq: 1, Time: day, Sender: "房东", Text: "押金下周退给你"},
+			{Talker: "wxid_a", TalkerName: "房东", Seq: 2, Time: day.Add(time.Hour), Sender: "我", Text: "好的"},
+		},
+		"wxid_b": {
+			{Talker: "wxid_b", TalkerName: "同事", Seq: 1, Time: day, Sender: "同事", Text: "明天开会"},
+		},
+	}
+	e := &fakeEmbedder{}
+	s, err := New(path, e, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := s.Update(context.Background())
+	if err != nil || n != 3 {
+		t.Fatalf("Update() = %d, %v, want 3", n, err)
+	}
+
+	hits, err := s.Search(context.Background(), "房子的押金", "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 2 || hits[0].Talker != "wxid_a" || !strings.Contains(hits[0].Text, "押金") {
+		t.Errorf("unexpected hits %+v", hits)
+	}
+	if hits, _ := s.Search(context.Background(), "押金", "wxid_b", 5); len(hits) != 1 || hits[0].Talker != "wxid_b" {
+		t.Errorf("talker filter not applied: %+v", hits)
+	}
+
+	// 重新加载后不重复建立索引，新消息追加到索引
+	src["wxid_b"] = append(src["wxid_b"], &Message{Talker: "wxid_b", Seq: 2, Time: day.Add(2 * time.Hour), Sender: "同事", Text: "会议改期"})
+	s, err = New(path, e, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.Update(context.Background()); err != nil || n != 1 || s.Len() != 4 {
+		t.Errorf("Update() after reload = %d, %v, len %d, want 1 and 4", n, err, s.Len())
+	}
+
+	// 模型变化时重新建立索引
+	x, err := Load(path, "other/model")
+	if err != nil || x.Len() != 0 {
+		t.Errorf("index for another model should be empty, got %d, %v", x.Len(), err)
+	}
+}
+
+type fakeProvider struct{ prompt string }
+
+func (p *fakeProvider) Name() string { return "fake/chat" }
+
+func (p *fakeProvider) Complete(ctx context.Context, system, prompt string) (string, error) {
+	p.prompt = prompt
+	return "房东说押金下周退 [1]。", nil
+}
+
+func TestAsk(t *testing.T) {
+	day := time.Date(2024, 3, 1, 9, 0, 0, 0, time.Local)
+	src := fakeSource{
+		"wxid_a": {{Talker: "wxid_a", TalkerName: "房东", Seq: 7, Time: day, Sender: "房东", Text: "押金下周退给你"}},
+		"wxid_b": {{Talker: "wxid_b", TalkerName: "同事", Seq: 1, Time: day, Sender: "同事", Text: "明天开会"}},
+	}
+	s, err := New(filepath.Join(t.TempDir(), IndexFile), &fakeEmbedder{}, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Update(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &fakeProvider{}
+	answer, err := s.Ask(context.Background(), p, "押金什么时候退", "", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(p.prompt, "[1] 房东 2024-03-01 09:00") || !strings.HasSuffix(p.prompt, "问题：押金什么时候退") {
+		t.Errorf("unexpected prompt:\n%s", p.prompt)
+	}
+	if len(answer.Citations) != 1 || answer.Citations[0].Seq != 7 || !answer.Citations[0].Start.Equal(day) {
+		t.Errorf("unexpected citations %+v", answer.Citations)
+	}
+	if answer.Provider != "fake/chat" || !strings.Contains(answer.Answer, "押金") {
+		t.Errorf("unexpected answer %+v", answer)
+	}
+}
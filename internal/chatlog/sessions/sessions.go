@@ -0,0 +1,91 @@
+package sessions
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/model"
+)
+
+// StateFile 记录上次运行时间的文件，位于工作目录下
+const StateFile = ".chatlog-sessions.json"
+
+// Item 会话的最近活动
+type Item struct {
+	UserName string    `json:"userName"`
+	Name     string    `json:"name"`
+	LastTime time.Time `json:"lastTime"`
+	Content  string    `json:"content"`
+	New      int       `json:"new"` // since 之后对方发送的消息数
+}
+
+// Result sessions 命令的结果
+type Result struct {
+	Since time.Time `json:"since"` // 新消息的起始时间，为零值表示首次运行，没有可比较的时间
+	Now   time.Time `json:"now"`
+	Items []*Item   `json:"items"`
+}
+
+// DB 统计新消息需要的查询接口
+type DB interface {
+	GetMessages(ctx context.Context, start, end time.Time, talker string, sender string, keyword string, limit, offset int) ([]*model.Message, error)
+}
+
+type state struct {
+	LastRun time.Time `json:"lastRun"`
+}
+
+// LastRun 读取工作目录中记录的上次运行时间，没有记录时返回零值
+func LastRun(workDir string) time.Time {
+	var s state
+	if b, err := os.ReadFile(filepath.Join(workDir, StateFile)); err == nil {
+		json.Unmarshal(b, &s)
+	}
+	return s.LastRun
+}
+
+// SaveLastRun 记录本次运行时间，作为下次统计新消息的起点
+func SaveLastRun(workDir string, t time.Time) error {
+	b, err := json.Marshal(state{LastRun: t})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(workDir, StateFile), b, 0644)
+}
+
+// Build 按最近活动时间排列会话，并统计 since 之后的新消息数
+// since 为零值时不统计；只统计活动时间晚于 since 的会话，避免逐个查询全部会话
+func Build(ctx context.Context, db DB, list []*model.Session, since, now time.Time) (*Result, error) {
+	ret := &Result{Since: since, Now: now, Items: make([]*Item, 0, len(list))}
+	for _, s := range list {
+		item := &Item{
+			UserName: s.UserName,
+			Name:     s.NickName,
+			LastTime: s.NTime,
+			Content:  s.Content,
+		}
+		if len(item.Name) == 0 {
+			item.Name = s.UserName
+		}
+		if !since.IsZero() && s.NTime.After(since) {
+			messages, err := db.GetMessages(ctx, since, now, s.UserName, "", "", 0, 0)
+			if err != nil {
+				return nil, err
+			}
+			for _, msg := range messages {
+				if !msg.IsSelf && msg.Time.After(since) {
+					item.New++
+				}
+			}
+		}
+		ret.Items = append(ret.Items, item)
+	}
+	sort.SliceStable(ret.Items, func(i, j int) bool {
+		return ret.Items[i].LastTime.After(ret.Items[j].LastTime)
+	})
+	return ret, nil
+}
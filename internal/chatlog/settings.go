@@ -0,0 +1,196 @@
+package chatlog
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+	"github.com/DanielMao1/chatlog/pkg/util"
+)
+
+const (
+	// MinAutoDecryptInterval 自动解密等待时间的下限，过短时微信仍在写入就会开始解密
+	MinAutoDecryptInterval = 100 * time.Millisecond
+	// MaxAutoDecryptInterval 自动解密等待时间的上限
+	MaxAutoDecryptInterval = 10 * time.Minute
+)
+
+// SetHTTPAddr 设置 HTTP 服务地址，支持端口号、host:port 与 http:// 开头的地址
+// HTTP 服务正在运行时以新地址重新启动
+func (m *Manager) SetHTTPAddr(text string) error {
+	text = strings.TrimSpace(text)
+	var addr string
+	if util.IsNumeric(text) {
+		addr = fmt.Sprintf("127.0.0.1:%s", text)
+	} else if strings.HasPrefix(text, "http://") {
+		addr = strings.TrimPrefix(text, "http://")
+	} else if strings.HasPrefix(text, "https://") {
+		addr = strings.TrimPrefix(text, "https://")
+	} else {
+		addr = text
+	}
+	addr = strings.TrimSuffix(addr, "/")
+
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid http address %q, use port or host:port", text)
+	}
+	if p, err := strconv.Atoi(port); err != nil || p <= 0 || p > 65535 {
+		return fmt.Errorf("invalid port %q", port)
+	}
+
+	if addr == m.ctx.GetHTTPAddr() {
+		return nil
+	}
+	m.ctx.SetHTTPAddr(addr)
+	if m.ctx.HTTPEnabled {
+		return m.restartService()
+	}
+	return nil
+}
+
+// SetWorkDir 设置工作目录，不能与数据目录相同；正在使用的数据库会重新打开
+func (m *Manager) SetWorkDir(dir string) error {
+	dir = strings.TrimSpace(dir)
+	if len(dir) == 0 {
+		return fmt.Errorf("work dir is required")
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if fi, err := os.Stat(dir); err == nil && !fi.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if len(m.ctx.DataDir) != 0 && filepath.Clean(m.ctx.DataDir) == dir {
+		return fmt.Errorf("work dir must be different from data dir")
+	}
+
+	if dir == m.ctx.WorkDir {
+		return nil
+	}
+	m.ctx.SetWorkDir(dir)
+	if m.ctx.HTTPEnabled {
+		return m.restartService()
+	}
+	if m.db.GetDB() != nil {
+		m.db.Stop()
+	}
+	return nil
+}
+
+// SetAuthToken 设置 HTTP 接口的访问令牌，为空时不校验，立即对运行中的服务生效
+func (m *Manager) SetAuthToken(token string) error {
+	token = strings.TrimSpace(token)
+	if strings.ContainsAny(token, " \t\r\n") {
+		return fmt.Errorf("auth token must not contain spaces")
+	}
+	return m.ctx.SetAuthToken(token)
+}
+
+// SetAutoDecryptInterval 设置自动解密等待数据库停止写入的时间，纯数字按秒计算，0 恢复默认值
+func (m *Manager) SetAutoDecryptInterval(text string) error {
+	text = strings.TrimSpace(text)
+	if util.IsNumeric(text) {
+		text += "s"
+	}
+	interval, err := time.ParseDuration(text)
+	if err != nil {
+		return fmt.Errorf("invalid interval %q, use a duration such as 2s or 500ms", text)
+	}
+	if interval != 0 && (interval < MinAutoDecryptInterval || interval > MaxAutoDecryptInterval) {
+		return fmt.Errorf("interval must be between %s and %s", MinAutoDecryptInterval, MaxAutoDecryptInterval)
+	}
+	return m.ctx.SetAutoDecryptInterval(interval)
+}
+
+// SetDestination 添加或修改推送目标，headers 为 "Key: Value" 形式、以分号分隔，timeout 为空时使用默认值
+// 新的推送目标立即用于总结推送，webhook 在下次启动 HTTP 服务时生效
+func (m *Manager) SetDestination(name, rawURL, headers, timeout string) error {
+	name = strings.TrimSpace(name)
+	if len(name) == 0 {
+		return fmt.Errorf("destination name is required")
+	}
+	// 配置键以 . 分隔层级
+	if strings.ContainsAny(name, ". \t") {
+		return fmt.Errorf("destination name must not contain dots or spaces")
+	}
+
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return fmt.Errorf("invalid url %q, use http:// or https://", rawURL)
+	}
+
+	dest := &conf.Destination{URL: u.String()}
+	if old, ok := m.ctx.GetDestinations()[name]; ok && old != nil {
+		dest.Template, dest.Format, dest.Script = old.Template, old.Format, old.Script
+		dest.Secret, dest.Retries, dest.Backoff = old.Secret, old.Retries, old.Backoff
+	}
+	if dest.Headers, err = ParseHeaders(headers); err != nil {
+		return err
+	}
+	if timeout = strings.TrimSpace(timeout); len(timeout) != 0 {
+		if dest.Timeout, err = time.ParseDuration(timeout); err != nil || dest.Timeout <= 0 {
+			return fmt.Errorf("invalid timeout %q, use a duration such as 10s", timeout)
+		}
+	}
+	return m.ctx.SetDestination(name, dest)
+}
+
+// RemoveDestination 删除推送目标
+func (m *Manager) RemoveDestination(name string) error {
+	return m.ctx.SetDestination(name, nil)
+}
+
+// restartService 停止后重新启动 HTTP 服务，使新的地址或工作目录生效
+func (m *Manager) restartService() error {
+	if err := m.stopService(); err != nil {
+		return err
+	}
+	if err := m.StartService(); err != nil {
+		m.StopService()
+		return err
+	}
+	return nil
+}
+
+// ParseHeaders 解析 "Key: Value; Key2: Value2" 形式的请求头
+func ParseHeaders(text string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, part := range strings.Split(text, ";") {
+		if len(strings.TrimSpace(part)) == 0 {
+			continue
+		}
+		k, v, ok := strings.Cut(part, ":")
+		k = strings.TrimSpace(k)
+		if !ok || len(k) == 0 || strings.ContainsAny(k, " \t") {
+			return nil, fmt.Errorf("invalid header %q, use Key: Value", strings.TrimSpace(part))
+		}
+		headers[k] = strings.TrimSpace(v)
+	}
+	if len(headers) == 0 {
+		return nil, nil
+	}
+	return headers, nil
+}
+
+// FormatHeaders 将请求头格式化为 ParseHeaders 可以解析的形式
+func FormatHeaders(headers map[string]string) string {
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+": "+headers[k])
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,42 @@
+package chatlog
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/DanielMao1/chatlog/internal/ui/chat"
+)
+
+// StateFile 保存终端界面状态的文件，位于配置目录
+const StateFile = "tui-state.json"
+
+// UIState 终端界面的状态，退出时保存，下次启动时恢复
+type UIState struct {
+	// Account 保存状态时的账号，切换到其他账号后不恢复浏览状态
+	Account string `json:"account"`
+	// Browser 退出时打开的消息浏览页面，未打开时为 nil
+	Browser *chat.State `json:"browser,omitempty"`
+}
+
+// LoadUIState 读取配置目录中保存的界面状态，文件不存在或无法解析时返回 nil
+func LoadUIState(dir string) *UIState {
+	b, err := os.ReadFile(filepath.Join(dir, StateFile))
+	if err != nil {
+		return nil
+	}
+	var s UIState
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil
+	}
+	return &s
+}
+
+// SaveUIState 将界面状态写入配置目录
+func SaveUIState(dir string, s *UIState) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, StateFile), b, 0600)
+}
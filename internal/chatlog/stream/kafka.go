@@ -0,0 +1,545 @@
+package stream
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+)
+
+// 使用的 Kafka 接口与版本，Kafka 0.11 起支持，4.0 仍然支持
+const (
+	apiProduce          = 0
+	apiMetadata         = 3
+	apiSaslHandshake    = 17
+	apiSaslAuthenticate = 36
+
+	versionProduce          = 3
+	versionMetadata         = 4
+	versionSaslHandshake    = 1
+	versionSaslAuthenticate = 0
+)
+
+const (
+	kafkaClientID = "chatlog"
+
+	// kafkaAcks 等待所有同步副本写入后才确认
+	kafkaAcks = -1
+
+	// errLeaderNotAvailable 自动创建主题后分区还没有 leader
+	errLeaderNotAvailable = 5
+
+	// metadataRetries 主题正在创建时获取元数据的重试次数
+	metadataRetries = 5
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// kafkaErrors 常见的错误码
+var kafkaErrors = map[int16]string{
+	3:  "unknown topic or partition",
+	5:  "leader not available",
+	6:  "not leader or follower",
+	7:  "request timed out",
+	10: "message too large",
+	19: "not enough replicas",
+	29: "topic authorization failed",
+	33: "unsupported sasl mechanism",
+	35: "unsupported version",
+	58: "sasl authentication failed",
+}
+
+func kafkaError(code int16) error {
+	if msg, ok := kafkaErrors[code]; ok {
+		return fmt.Errorf("kafka error: %s", msg)
+	}
+	return fmt.Errorf("kafka error code %d", code)
+}
+
+// kafkaPartition 分区与其 leader
+type kafkaPartition struct {
+	id     int32
+	leader int32
+}
+
+// kafkaClient 只发布消息的 Kafka 客户端，按主题缓存分区的 leader，出错时整体断开重建
+type kafkaClient struct {
+	conf      *conf.Stream
+	bootstrap *kafkaBroker
+	addrs     map[int32]string // broker 的地址
+	brokers   map[int32]*kafkaBroker
+	topics    map[string][]kafkaPartition
+	next      atomic.Uint32 // 没有键的消息轮流发布到各分区
+}
+
+// kafkaBroker 与一个 broker 的连接
+type kafkaBroker struct {
+	conn net.Conn
+	r    *bufio.Reader
+	corr int32
+}
+
+// dialKafka 连接第一个可用的服务器，用于获取元数据
+func dialKafka(ctx context.Context, c *conf.Stream) (*kafkaClient, error) {
+	var errs []error
+	for _, server := range c.Servers {
+		b, err := dialKafkaBroker(ctx, c, server)
+		if err == nil {
+			return &kafkaClient{
+				conf:      c,
+				bootstrap: b,
+				addrs:     make(map[int32]string),
+				brokers:   make(map[int32]*kafkaBroker),
+				topics:    make(map[string][]kafkaPartition),
+			}, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", server, err))
+	}
+	return nil, errors.Join(errs...)
+}
+
+func dialKafkaBroker(ctx context.Context, c *conf.Stream, addr string) (*kafkaBroker, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "9092")
+	}
+	dialer := &net.Dialer{Timeout: Timeout}
+	var conn net.Conn
+	var err error
+	if c.TLS {
+		host, _, _ := net.SplitHostPort(addr)
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	b := &kafkaBroker{conn: conn, r: bufio.NewReader(conn)}
+	if len(c.Username) != 0 {
+		if err := b.authenticate(ctx, c.Username, c.Password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// authenticate 使用 SASL PLAIN 认证
+func (b *kafkaBroker) authenticate(ctx context.Context, username, password string) error {
+	var req kafkaWriter
+	req.string("PLAIN")
+	resp, err := b.roundTrip(ctx, apiSaslHandshake, versionSaslHandshake, req)
+	if err != nil {
+		return err
+	}
+	r := kafkaReader{b: resp}
+	if code := r.int16(); code != 0 {
+		return kafkaError(code)
+	}
+
+	req = req[:0]
+	req.bytes([]byte("\x00" + username + "\x00" + password))
+	if resp, err = b.roundTrip(ctx, apiSaslAuthenticate, versionSaslAuthenticate, req); err != nil {
+		return err
+	}
+	r = kafkaReader{b: resp}
+	code := r.int16()
+	msg := r.nullableString()
+	if r.err != nil {
+		return r.err
+	}
+	if code != 0 {
+		if len(msg) != 0 {
+			return fmt.Errorf("%w: %s", kafkaError(code), msg)
+		}
+		return kafkaError(code)
+	}
+	return nil
+}
+
+// roundTrip 发送请求并读取对应的响应，返回响应头之后的内容
+func (b *kafkaBroker) roundTrip(ctx context.Context, api, version int16, body []byte) ([]byte, error) {
+	b.corr++
+	var req kafkaWriter
+	req.int32(0)
+	req.int16(api)
+	req.int16(version)
+	req.int32(b.corr)
+	req.string(kafkaClientID)
+	req = append(req, body...)
+	binary.BigEndian.PutUint32(req, uint32(len(req)-4))
+
+	deadline := time.Now().Add(Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	b.conn.SetDeadline(deadline)
+	defer b.conn.SetDeadline(time.Time{})
+	if _, err := b.conn.Write(req); err != nil {
+		return nil, err
+	}
+	var size [4]byte
+	if _, err := io.ReadFull(b.r, size[:]); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, binary.BigEndian.Uint32(size[:]))
+	if _, err := io.ReadFull(b.r, resp); err != nil {
+		return nil, err
+	}
+	if len(resp) < 4 || int32(binary.BigEndian.Uint32(resp)) != b.corr {
+		return nil, fmt.Errorf("unexpected kafka response")
+	}
+	return resp[4:], nil
+}
+
+// publish 将消息按键分到主题的各分区，每个 leader 一个 Produce 请求
+func (k *kafkaClient) publish(ctx context.Context, topic string, records []Record) error {
+	partitions, err := k.partitions(ctx, topic)
+	if err != nil {
+		return err
+	}
+	byPartition := make(map[kafkaPartition][]Record)
+	for _, rec := range records {
+		var p kafkaPartition
+		if rec.Key == nil {
+			p = partitions[int(k.next.Add(1))%len(partitions)]
+		} else {
+			p = partitions[partitionOf(rec.Key, len(partitions))]
+		}
+		byPartition[p] = append(byPartition[p], rec)
+	}
+	byLeader := make(map[int32]map[int32][]Record)
+	for p, recs := range byPartition {
+		if byLeader[p.leader] == nil {
+			byLeader[p.leader] = make(map[int32][]Record)
+		}
+		byLeader[p.leader][p.id] = recs
+	}
+
+	now := time.Now()
+	for leader, recs := range byLeader {
+		b, err := k.broker(ctx, leader)
+		if err != nil {
+			return err
+		}
+		var req kafkaWriter
+		req.int16(-1) // transactional_id 为 null
+		req.int16(kafkaAcks)
+		req.int32(int32(Timeout / time.Millisecond))
+		req.int32(1)
+		req.string(topic)
+		req.int32(int32(len(recs)))
+		for id, list := range recs {
+			req.int32(id)
+			req.bytes(recordBatch(list, now))
+		}
+		resp, err := b.roundTrip(ctx, apiProduce, versionProduce, req)
+		if err != nil {
+			return err
+		}
+		if err := produceError(resp); err != nil {
+			return fmt.Errorf("%s: %w", topic, err)
+		}
+	}
+	return nil
+}
+
+// partitions 返回主题的分区，自动创建主题时等待分区选出 leader
+func (k *kafkaClient) partitions(ctx context.Context, topic string) ([]kafkaPartition, error) {
+	if p, ok := k.topics[topic]; ok {
+		return p, nil
+	}
+	for i := 0; ; i++ {
+		var req kafkaWriter
+		req.int32(1)
+		req.string(topic)
+		req.int8(1) // allow_auto_topic_creation
+		resp, err := k.bootstrap.roundTrip(ctx, apiMetadata, versionMetadata, req)
+		if err != nil {
+			return nil, err
+		}
+		partitions, code, err := k.parseMetadata(resp, topic)
+		if err != nil {
+			return nil, err
+		}
+		if code == 0 && len(partitions) != 0 {
+			k.topics[topic] = partitions
+			return partitions, nil
+		}
+		if code != errLeaderNotAvailable || i >= metadataRetries {
+			if code == 0 {
+				return nil, fmt.Errorf("%s: no partitions with a leader", topic)
+			}
+			return nil, fmt.Errorf("%s: %w", topic, kafkaError(code))
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// parseMetadata 解析 Metadata v4 响应，记录 broker 地址，返回主题有 leader 的分区与主题的错误码
+func (k *kafkaClient) parseMetadata(resp []byte, topic string) ([]kafkaPartition, int16, error) {
+	r := kafkaReader{b: resp}
+	r.int32() // throttle_time_ms
+	for n := r.int32(); n > 0 && r.err == nil; n-- {
+		id := r.int32()
+		host := r.string()
+		port := r.int32()
+		r.nullableString() // rack
+		k.addrs[id] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	r.nullableString() // cluster_id
+	r.int32()          // controller_id
+
+	var ret []kafkaPartition
+	var topicCode int16 = -1
+	for n := r.int32(); n > 0 && r.err == nil; n-- {
+		code := r.int16()
+		name := r.string()
+		r.int8() // is_internal
+		var partitions []kafkaPartition
+		for m := r.int32(); m > 0 && r.err == nil; m-- {
+			pcode := r.int16()
+			id := r.int32()
+			leader := r.int32()
+			r.skipInt32s() // replica_nodes
+			r.skipInt32s() // isr_nodes
+			if pcode == 0 && leader >= 0 {
+				partitions = append(partitions, kafkaPartition{id: id, leader: leader})
+			}
+		}
+		if name == topic {
+			slices.SortFunc(partitions, func(a, b kafkaPartition) int { return int(a.id - b.id) })
+			ret, topicCode = partitions, code
+		}
+	}
+	if r.err != nil {
+		return nil, 0, fmt.Errorf("invalid kafka metadata: %w", r.err)
+	}
+	if topicCode == -1 {
+		return nil, 0, fmt.Errorf("%s: topic not in kafka metadata", topic)
+	}
+	return ret, topicCode, nil
+}
+
+// broker 返回与 broker 的连接，没有连接时按元数据中的地址连接
+func (k *kafkaClient) broker(ctx context.Context, id int32) (*kafkaBroker, error) {
+	if b, ok := k.brokers[id]; ok {
+		return b, nil
+	}
+	addr, ok := k.addrs[id]
+	if !ok {
+		return nil, fmt.Errorf("kafka broker %d not in metadata", id)
+	}
+	b, err := dialKafkaBroker(ctx, k.conf, addr)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", addr, err)
+	}
+	k.brokers[id] = b
+	return b, nil
+}
+
+func (k *kafkaClient) close() error {
+	k.bootstrap.conn.Close()
+	for _, b := range k.brokers {
+		b.conn.Close()
+	}
+	return nil
+}
+
+// produceError 返回 Produce v3 响应中第一个分区的错误
+func produceError(resp []byte) error {
+	r := kafkaReader{b: resp}
+	for n := r.int32(); n > 0 && r.err == nil; n-- {
+		r.string()
+		for m := r.int32(); m > 0 && r.err == nil; m-- {
+			r.int32() // partition
+			code := r.int16()
+			r.int64() // base_offset
+			r.int64() // log_append_time_ms
+			if r.err == nil && code != 0 {
+				return kafkaError(code)
+			}
+		}
+	}
+	if r.err != nil {
+		return fmt.Errorf("invalid kafka produce response: %w", r.err)
+	}
+	return nil
+}
+
+// recordBatch 编码 record batch v2，不压缩，不使用幂等与事务
+func recordBatch(records []Record, now time.Time) []byte {
+	ts := now.UnixMilli()
+	var b kafkaWriter
+	b.int64(0) // base_offset
+	b.int32(0) // batch_length，稍后填入
+	b.int32(-1)
+	b.int8(2)  // magic
+	b.int32(0) // crc，稍后填入
+	crcStart := len(b)
+	b.int16(0) // attributes
+	b.int32(int32(len(records) - 1))
+	b.int64(ts)
+	b.int64(ts)
+	b.int64(-1) // producer_id
+	b.int16(-1) // producer_epoch
+	b.int32(-1) // base_sequence
+	b.int32(int32(len(records)))
+	for i, rec := range records {
+		var body kafkaWriter
+		body.int8(0) // attributes
+		body.varint(0)
+		body.varint(int64(i))
+		if rec.Key == nil {
+			body.varint(-1)
+		} else {
+			body.varint(int64(len(rec.Key)))
+			body = append(body, rec.Key...)
+		}
+		body.varint(int64(len(rec.Value)))
+		body = append(body, rec.Value...)
+		body.varint(0) // headers
+		b.varint(int64(len(body)))
+		b = append(b, body...)
+	}
+	binary.BigEndian.PutUint32(b[8:], uint32(len(b)-12))
+	binary.BigEndian.PutUint32(b[crcStart-4:], crc32.Checksum(b[crcStart:], castagnoli))
+	return b
+}
+
+// partitionOf 与 Kafka Java 客户端默认的分区方式相同，同一个键总是分到同一分区
+func partitionOf(key []byte, n int) int {
+	return int(murmur2(key)&0x7fffffff) % n
+}
+
+// murmur2 Kafka 计算键的分区使用的哈希
+func murmur2(data []byte) int32 {
+	const m = 0x5bd1e995
+	length := len(data)
+	h := uint32(0x9747b28c) ^ uint32(length)
+	for i := 0; i+4 <= length; i += 4 {
+		k := binary.LittleEndian.Uint32(data[i:])
+		k *= m
+		k ^= k >> 24
+		k *= m
+		h *= m
+		h ^= k
+	}
+	tail := data[length&^3:]
+	switch len(tail) {
+	case 3:
+		h ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(tail[0])
+		h *= m
+	}
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return int32(h)
+}
+
+// kafkaWriter 按 Kafka 协议编码请求
+type kafkaWriter []byte
+
+func (w *kafkaWriter) int8(v int8)   { *w = append(*w, byte(v)) }
+func (w *kafkaWriter) int16(v int16) { *w = binary.BigEndian.AppendUint16(*w, uint16(v)) }
+func (w *kafkaWriter) int32(v int32) { *w = binary.BigEndian.AppendUint32(*w, uint32(v)) }
+func (w *kafkaWriter) int64(v int64) { *w = binary.BigEndian.AppendUint64(*w, uint64(v)) }
+func (w *kafkaWriter) varint(v int64) {
+	*w = binary.AppendVarint(*w, v)
+}
+
+func (w *kafkaWriter) string(s string) {
+	w.int16(int16(len(s)))
+	*w = append(*w, s...)
+}
+
+func (w *kafkaWriter) bytes(b []byte) {
+	w.int32(int32(len(b)))
+	*w = append(*w, b...)
+}
+
+// kafkaReader 按 Kafka 协议解码响应，数据不足时记录错误并返回零值
+type kafkaReader struct {
+	b   []byte
+	err error
+}
+
+func (r *kafkaReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.b) < n {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *kafkaReader) int8() int8 {
+	if b := r.next(1); b != nil {
+		return int8(b[0])
+	}
+	return 0
+}
+
+func (r *kafkaReader) int16() int16 {
+	if b := r.next(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (r *kafkaReader) int32() int32 {
+	if b := r.next(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (r *kafkaReader) int64() int64 {
+	if b := r.next(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+func (r *kafkaReader) string() string {
+	return string(r.next(int(r.int16())))
+}
+
+func (r *kafkaReader) nullableString() string {
+	n := r.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(r.next(int(n)))
+}
+
+func (r *kafkaReader) skipInt32s() {
+	if n := r.int32(); n > 0 {
+		r.next(int(n) * 4)
+	}
+}
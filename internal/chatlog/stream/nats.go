@@ -0,0 +1,237 @@
+package stream
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+	"github.com/DanielMao1/chatlog/pkg/version"
+)
+
+// natsInfo 服务器连接后发送的 INFO
+type natsInfo struct {
+	TLSRequired bool  `json:"tls_required"`
+	MaxPayload  int64 `json:"max_payload"`
+}
+
+// natsConnect 客户端发送的 CONNECT
+type natsConnect struct {
+	Verbose  bool   `json:"verbose"`
+	Pedantic bool   `json:"pedantic"`
+	Name     string `json:"name"`
+	Lang     string `json:"lang"`
+	Version  string `json:"version"`
+	Protocol int    `json:"protocol"`
+	User     string `json:"user,omitempty"`
+	Pass     string `json:"pass,omitempty"`
+	Token    string `json:"auth_token,omitempty"`
+}
+
+// natsConn 只发布消息的 NATS 连接
+type natsConn struct {
+	conn       net.Conn
+	maxPayload int64
+
+	// wmu 保证同时只有一个协议行在写入
+	wmu   sync.Mutex
+	pongs chan struct{}
+	done  chan struct{}
+	once  sync.Once
+	err   error
+}
+
+// dialNATS 依次尝试连接配置的服务器
+func dialNATS(ctx context.Context, c *conf.Stream) (*natsConn, error) {
+	var errs []error
+	for _, server := range c.Servers {
+		nc, err := dialNATSServer(ctx, c, server)
+		if err == nil {
+			return nc, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", server, err))
+	}
+	return nil, errors.Join(errs...)
+}
+
+func dialNATSServer(ctx context.Context, c *conf.Stream, server string) (*natsConn, error) {
+	if !strings.Contains(server, "://") {
+		server = "nats://" + server
+	}
+	u, err := url.Parse(server)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nats server: %v", err)
+	}
+	useTLS := c.TLS
+	switch u.Scheme {
+	case "nats":
+	case "tls":
+		useTLS = true
+	default:
+		return nil, fmt.Errorf("unsupported nats server scheme: %s", u.Scheme)
+	}
+	host := u.Host
+	if len(u.Port()) == 0 {
+		host = net.JoinHostPort(u.Hostname(), "4222")
+	}
+
+	conn, err := (&net.Dialer{Timeout: Timeout}).DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(Timeout))
+	r := bufio.NewReader(conn)
+	line, err := readLine(r)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	var info natsInfo
+	if op, args, _ := strings.Cut(line, " "); op != "INFO" || json.Unmarshal([]byte(args), &info) != nil {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected nats greeting: %s", line)
+	}
+	if useTLS || info.TLSRequired {
+		tc := tls.Client(conn, &tls.Config{ServerName: u.Hostname()})
+		if err := tc.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn, r = tc, bufio.NewReader(tc)
+	}
+
+	connect := natsConnect{Name: "chatlog", Lang: "go", Version: version.Version, Protocol: 1, User: c.Username, Pass: c.Password, Token: c.Token}
+	if u.User != nil {
+		connect.User = u.User.Username()
+		connect.Pass, _ = u.User.Password()
+	}
+	data, _ := json.Marshal(connect)
+	if _, err := conn.Write([]byte("CONNECT " + string(data) + "\r\nPING\r\n")); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// 认证失败时服务器返回 -ERR 并断开连接
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if line == "PONG" {
+			break
+		}
+		if strings.HasPrefix(line, "-ERR") {
+			conn.Close()
+			return nil, natsError(line)
+		}
+	}
+	conn.SetDeadline(time.Time{})
+
+	nc := &natsConn{conn: conn, maxPayload: info.MaxPayload, pongs: make(chan struct{}, 1), done: make(chan struct{})}
+	go nc.read(r)
+	return nc, nil
+}
+
+// publish 发布消息后发送 PING，收到 PONG 时服务器已处理之前的所有消息
+func (nc *natsConn) publish(ctx context.Context, subject string, records []Record) error {
+	var b []byte
+	for _, rec := range records {
+		if nc.maxPayload > 0 && int64(len(rec.Value)) > nc.maxPayload {
+			return fmt.Errorf("message of %d bytes exceeds nats max_payload %d", len(rec.Value), nc.maxPayload)
+		}
+		b = append(b, "PUB "+subject+" "+strconv.Itoa(len(rec.Value))+"\r\n"...)
+		b = append(b, rec.Value...)
+		b = append(b, "\r\n"...)
+	}
+	b = append(b, "PING\r\n"...)
+	if err := nc.write(b); err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(Timeout)
+	defer timer.Stop()
+	select {
+	case <-nc.pongs:
+		return nil
+	case <-nc.done:
+		return nc.err
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return fmt.Errorf("nats server did not respond in %s", Timeout)
+	}
+}
+
+func (nc *natsConn) close() error {
+	nc.shutdown(net.ErrClosed)
+	return nil
+}
+
+func (nc *natsConn) shutdown(err error) {
+	nc.once.Do(func() {
+		nc.err = err
+		nc.conn.Close()
+		close(nc.done)
+	})
+}
+
+func (nc *natsConn) write(b []byte) error {
+	nc.wmu.Lock()
+	defer nc.wmu.Unlock()
+	select {
+	case <-nc.done:
+		return nc.err
+	default:
+	}
+	nc.conn.SetWriteDeadline(time.Now().Add(Timeout))
+	if _, err := nc.conn.Write(b); err != nil {
+		nc.shutdown(err)
+		return err
+	}
+	return nil
+}
+
+// read 读取服务器的协议行，回复服务器的 PING
+func (nc *natsConn) read(r *bufio.Reader) {
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			nc.shutdown(err)
+			return
+		}
+		switch {
+		case line == "PING":
+			nc.write([]byte("PONG\r\n"))
+		case line == "PONG":
+			select {
+			case nc.pongs <- struct{}{}:
+			default:
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			nc.shutdown(natsError(line))
+			return
+		}
+	}
+}
+
+// readLine 读取一行协议，去掉结尾的 \r\n
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func natsError(line string) error {
+	return fmt.Errorf("nats error: %s", strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")), "'"))
+}
@@ -0,0 +1,204 @@
+// Package stream 将新消息与解密事件发布到 Kafka 或 NATS，供归档、合规等下游系统作为事件流消费
+//
+// 只实现发布需要的协议：Kafka 使用 Metadata v4 与 Produce v3（record batch v2），支持 TLS 与 SASL PLAIN；
+// NATS 使用文本协议的 CONNECT 与 PUB，每批消息后等待 PONG 确认服务器已收到。
+package stream
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+)
+
+const (
+	TypeKafka = "kafka"
+	TypeNATS  = "nats"
+
+	// DefaultTopic 未配置 stream.topic 时的主题前缀
+	DefaultTopic = "chatlog"
+
+	// Timeout 连接与等待服务器确认的超时
+	Timeout = 10 * time.Second
+
+	// QueueSize 异步发布时排队的最大批次数，超过时丢弃最早的批次
+	QueueSize = 256
+)
+
+// 解密事件
+const (
+	DecryptStarted  = "started"
+	DecryptFinished = "finished"
+	DecryptFailed   = "failed"
+)
+
+// Record 一条待发布的消息，Key 决定 Kafka 的分区，同一聊天对象的消息在同一分区中保持顺序
+type Record struct {
+	Key   []byte
+	Value []byte
+}
+
+// DecryptEvent 解密生命周期事件，发布到 <topic>.decrypt
+type DecryptEvent struct {
+	Event  string    `json:"event"`            // started、finished 或 failed
+	Auto   bool      `json:"auto"`             // 是否为自动解密
+	File   string    `json:"file,omitempty"`   // 自动解密的数据库
+	Files  int       `json:"files,omitempty"`  // 全部解密时需要解密的数据库数量
+	Failed int       `json:"failed,omitempty"` // 解密失败的数据库数量
+	Error  string    `json:"error,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// conn 与服务器的连接
+type conn interface {
+	publish(ctx context.Context, subject string, records []Record) error
+	close() error
+}
+
+// batch 排队等待异步发布的一批消息
+type batch struct {
+	subject string
+	records []Record
+}
+
+// Publisher 发布消息，第一次发布时才连接服务器，发布失败时重新连接并重试一次
+type Publisher struct {
+	conf *conf.Stream
+
+	// mu 保证同时只有一批消息在发布
+	mu   sync.Mutex
+	conn conn
+
+	// qmu 保护异步发布的队列
+	qmu     sync.Mutex
+	queue   []batch
+	running bool
+}
+
+// New 创建发布者，配置无效时返回错误
+func New(c *conf.Stream) (*Publisher, error) {
+	if c == nil || len(c.Servers) == 0 {
+		return nil, fmt.Errorf("stream.servers is not configured")
+	}
+	switch c.Type {
+	case TypeKafka, TypeNATS:
+	default:
+		return nil, fmt.Errorf("unsupported stream.type: %q, use kafka or nats", c.Type)
+	}
+	return &Publisher{conf: c}, nil
+}
+
+// Topic 返回 stream.topic 配置的主题前缀
+func Topic(c *conf.Stream) string {
+	if c == nil || len(c.Topic) == 0 {
+		return DefaultTopic
+	}
+	return c.Topic
+}
+
+// MessageSubject 返回聊天对象的新消息发布的主题
+// Kafka 的所有新消息发布到同一主题并以聊天对象为键，NATS 每个聊天对象一个主题，聊天对象中的通配符与分隔符替换为 _
+func MessageSubject(c *conf.Stream, talker string) string {
+	if c != nil && c.Type == TypeKafka {
+		return Topic(c) + ".messages"
+	}
+	return Topic(c) + ".messages." + strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', ' ', '\t', '\r', '\n':
+			return '_'
+		}
+		return r
+	}, talker)
+}
+
+// DecryptSubject 返回解密事件发布的主题
+func DecryptSubject(c *conf.Stream) string {
+	return Topic(c) + ".decrypt"
+}
+
+// Publish 发布一批消息，返回时服务器已确认收到
+func (p *Publisher) Publish(ctx context.Context, subject string, records ...Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var err error
+	for i := 0; i < 2; i++ {
+		if p.conn == nil {
+			if p.conn, err = p.dial(ctx); err != nil {
+				return err
+			}
+		}
+		if err = p.conn.publish(ctx, subject, records); err == nil {
+			return nil
+		}
+		p.conn.close()
+		p.conn = nil
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return err
+}
+
+// Go 在后台按顺序发布一批消息，不等待结果，失败时只记录日志
+func (p *Publisher) Go(subject string, records ...Record) {
+	p.qmu.Lock()
+	defer p.qmu.Unlock()
+	if len(p.queue) >= QueueSize {
+		log.Warn().Str("subject", p.queue[0].subject).Msg("stream queue is full, drop the oldest batch")
+		p.queue = p.queue[1:]
+	}
+	p.queue = append(p.queue, batch{subject: subject, records: records})
+	if !p.running {
+		p.running = true
+		go p.drain()
+	}
+}
+
+// drain 发布队列中的消息，队列为空时退出
+func (p *Publisher) drain() {
+	for {
+		p.qmu.Lock()
+		if len(p.queue) == 0 {
+			p.running = false
+			p.qmu.Unlock()
+			return
+		}
+		b := p.queue[0]
+		p.queue = p.queue[1:]
+		p.qmu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+		if err := p.Publish(ctx, b.subject, b.records...); err != nil {
+			log.Warn().Err(err).Str("subject", b.subject).Msgf("publish to %s failed", p.conf.Type)
+		}
+		cancel()
+	}
+}
+
+// Close 关闭连接，之后发布时重新连接
+func (p *Publisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conn == nil {
+		return nil
+	}
+	err := p.conn.close()
+	p.conn = nil
+	return err
+}
+
+func (p *Publisher) dial(ctx context.Context) (conn, error) {
+	if p.conf.Type == TypeKafka {
+		return dialKafka(ctx, p.conf)
+	}
+	return dialNATS(ctx, p.conf)
+}
@@ -0,0 +1,344 @@
+package stream
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+)
+
+// published 模拟服务器收到的消息
+type published struct {
+	Subject string
+	Key     string
+	Value   string
+}
+
+// fakeNATS 模拟 NATS 服务器，token 不为空时要求认证
+func fakeNATS(t *testing.T, token string) (string, <-chan published) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	ch := make(chan published, 16)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.Write([]byte(`INFO {"server_id":"test","max_payload":1024}` + "\r\n"))
+				r := bufio.NewReader(conn)
+				for {
+					line, err := readLine(r)
+					if err != nil {
+						return
+					}
+					op, args, _ := strings.Cut(line, " ")
+					switch op {
+					case "CONNECT":
+						if len(token) != 0 && !strings.Contains(args, `"auth_token":"`+token+`"`) {
+							conn.Write([]byte("-ERR 'Authorization Violation'\r\n"))
+							return
+						}
+					case "PING":
+						conn.Write([]byte("PONG\r\n"))
+					case "PUB":
+						f := strings.Fields(args)
+						n, _ := strconv.Atoi(f[len(f)-1])
+						payload := make([]byte, n+2)
+						io.ReadFull(r, payload)
+						ch <- published{Subject: f[0], Value: string(payload[:n])}
+					}
+				}
+			}()
+		}
+	}()
+	return l.Addr().String(), ch
+}
+
+func TestNATS(t *testing.T) {
+	addr, ch := fakeNATS(t, "secret")
+	c := &conf.Stream{Type: TypeNATS, Servers: []string{"nats://" + addr}, Token: "secret"}
+	p, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	subject := MessageSubject(c, "12345@chatroom")
+	if err := p.Publish(context.Background(), subject, Record{Value: []byte(`{"seq":1}`)}, Record{Value: []byte(`{"seq":2}`)}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`{"seq":1}`, `{"seq":2}`} {
+		if got := <-ch; got.Subject != "chatlog.messages.12345@chatroom" || got.Value != want {
+			t.Errorf("got %+v, want %s", got, want)
+		}
+	}
+	if err := p.Publish(context.Background(), subject, Record{Value: make([]byte, 2048)}); err == nil || !strings.Contains(err.Error(), "max_payload") {
+		t.Errorf("got %v, want max_payload error", err)
+	}
+
+	bad, _ := New(&conf.Stream{Type: TypeNATS, Servers: []string{addr}, Token: "wrong"})
+	if err := bad.Publish(context.Background(), subject, Record{Value: []byte("x")}); err == nil || !strings.Contains(err.Error(), "Authorization Violation") {
+		t.Errorf("got %v, want authorization error", err)
+	}
+}
+
+// fakeKafka 模拟只有一个 broker 的 Kafka，主题有 partitions 个分区，username 不为空时要求 SASL PLAIN 认证
+func fakeKafka(t *testing.T, partitions int, username, password string) (string, <-chan published) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	host, portStr, _ := net.SplitHostPort(l.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	ch := make(chan published, 16)
+
+	handle := func(api int16, r *kafkaReader) kafkaWriter {
+		var w kafkaWriter
+		switch api {
+		case apiSaslHandshake:
+			if r.string() != "PLAIN" {
+				w.int16(33)
+			} else {
+				w.int16(0)
+			}
+			w.int32(1)
+			w.string("PLAIN")
+		case apiSaslAuthenticate:
+			auth := r.next(int(r.int32()))
+			if string(auth) != "\x00"+username+"\x00"+password {
+				w.int16(58)
+				w.string("invalid credentials")
+			} else {
+				w.int16(0)
+				w.int16(-1)
+			}
+			w.int32(0)
+		case apiMetadata:
+			r.int32()
+			topic := r.string()
+			w.int32(0)
+			w.int32(1)
+			w.int32(0)
+			w.string(host)
+			w.int32(int32(port))
+			w.int16(-1)
+			w.int16(-1)
+			w.int32(0)
+			w.int32(1)
+			w.int16(0)
+			w.string(topic)
+			w.int8(0)
+			w.int32(int32(partitions))
+			for i := 0; i < partitions; i++ {
+				w.int16(0)
+				w.int32(int32(i))
+				w.int32(0)
+				w.int32(1)
+				w.int32(0)
+				w.int32(1)
+				w.int32(0)
+			}
+		case apiProduce:
+			r.int16()
+			if acks := r.int16(); acks != kafkaAcks {
+				t.Errorf("acks = %d", acks)
+			}
+			r.int32()
+			r.int32()
+			topic := r.string()
+			n := r.int32()
+			w.int32(1)
+			w.string(topic)
+			w.int32(n)
+			for ; n > 0; n-- {
+				id := r.int32()
+				batch := r.next(int(r.int32()))
+				for _, rec := range decodeBatch(t, batch) {
+					rec.Subject = topic + "/" + strconv.Itoa(int(id))
+					ch <- rec
+				}
+				w.int32(id)
+				w.int16(0)
+				w.int64(0)
+				w.int64(-1)
+			}
+			w.int32(0)
+		}
+		return w
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				authenticated := len(username) == 0
+				for {
+					var size [4]byte
+					if _, err := io.ReadFull(conn, size[:]); err != nil {
+						return
+					}
+					req := make([]byte, binary.BigEndian.Uint32(size[:]))
+					if _, err := io.ReadFull(conn, req); err != nil {
+						return
+					}
+					r := &kafkaReader{b: req}
+					api, _, corr := r.int16(), r.int16(), r.int32()
+					r.string()
+					if !authenticated && api != apiSaslHandshake && api != apiSaslAuthenticate {
+						return
+					}
+					body := handle(api, r)
+					if api == apiSaslAuthenticate && binary.BigEndian.Uint16(body) == 0 {
+						authenticated = true
+					}
+					var resp kafkaWriter
+					resp.int32(int32(4 + len(body)))
+					resp.int32(corr)
+					conn.Write(append(resp, body...))
+				}
+			}()
+		}
+	}()
+	return l.Addr().String(), ch
+}
+
+// decodeBatch 解码 record batch v2 并校验 crc
+func decodeBatch(t *testing.T, b []byte) []published {
+	t.Helper()
+	r := &kafkaReader{b: b}
+	r.int64()
+	if n := r.int32(); int(n) != len(b)-12 {
+		t.Errorf("batch length = %d, want %d", n, len(b)-12)
+	}
+	r.int32()
+	if magic := r.int8(); magic != 2 {
+		t.Errorf("magic = %d", magic)
+	}
+	if crc := uint32(r.int32()); crc != crc32.Checksum(b[21:], crc32.MakeTable(crc32.Castagnoli)) {
+		t.Errorf("crc mismatch")
+	}
+	r.next(2 + 4 + 8 + 8 + 8 + 2 + 4)
+	var ret []published
+	for n := r.int32(); n > 0; n-- {
+		length, m := binary.Varint(r.b)
+		rec := r.b[m : m+int(length)]
+		r.b = r.b[m+int(length):]
+		rec = rec[1:]
+		for i := 0; i < 2; i++ {
+			_, m := binary.Varint(rec)
+			rec = rec[m:]
+		}
+		var p published
+		for i, s := range []*string{&p.Key, &p.Value} {
+			l, m := binary.Varint(rec)
+			rec = rec[m:]
+			if l < 0 {
+				if i == 1 {
+					t.Errorf("null value")
+				}
+				continue
+			}
+			*s = string(rec[:l])
+			rec = rec[l:]
+		}
+		ret = append(ret, p)
+	}
+	return ret
+}
+
+func TestKafka(t *testing.T) {
+	addr, ch := fakeKafka(t, 3, "user", "pass")
+	c := &conf.Stream{Type: TypeKafka, Servers: []string{addr}, Username: "user", Password: "pass", Topic: "archive"}
+	p, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	subject := MessageSubject(c, "12345@chatroom")
+	if subject != "archive.messages" {
+		t.Errorf("subject = %q", subject)
+	}
+	records := []Record{
+		{Key: []byte("12345@chatroom"), Value: []byte(`{"seq":1}`)},
+		{Key: []byte("12345@chatroom"), Value: []byte(`{"seq":2}`)},
+	}
+	if err := p.Publish(context.Background(), subject, records...); err != nil {
+		t.Fatal(err)
+	}
+	want := "archive.messages/" + strconv.Itoa(partitionOf([]byte("12345@chatroom"), 3))
+	for _, v := range []string{`{"seq":1}`, `{"seq":2}`} {
+		got := <-ch
+		if got != (published{Subject: want, Key: "12345@chatroom", Value: v}) {
+			t.Errorf("got %+v, want %s in %s", got, v, want)
+		}
+	}
+
+	// 没有键的消息
+	if err := p.Publish(context.Background(), DecryptSubject(c), Record{Value: []byte(`{"event":"started"}`)}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-ch:
+		if !strings.HasPrefix(got.Subject, "archive.decrypt/") || got.Key != "" {
+			t.Errorf("got %+v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("decrypt event not published")
+	}
+
+	bad, _ := New(&conf.Stream{Type: TypeKafka, Servers: []string{addr}, Username: "user", Password: "wrong"})
+	if err := bad.Publish(context.Background(), subject, records...); err == nil || !strings.Contains(err.Error(), "invalid credentials") {
+		t.Errorf("got %v, want sasl error", err)
+	}
+}
+
+func TestMurmur2(t *testing.T) {
+	// 与 Kafka Java 客户端的测试数据相同
+	cases := map[string]int32{
+		"21":                         -973932308,
+		"foobar":                     -790332482,
+		"a-little-bit-long-string":   -985981536,
+		"a-little-bit-longer-string": -1486304829,
+		"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8": -58897971,
+		"abc": 479470107,
+	}
+	for s, want := range cases {
+		if got := murmur2([]byte(s)); got != want {
+			t.Errorf("murmur2(%q) = %d, want %d", s, got, want)
+		}
+	}
+}
+
+func TestMessageSubject(t *testing.T) {
+	if got := MessageSubject(nil, "wxid_a"); got != "chatlog.messages.wxid_a" {
+		t.Errorf("MessageSubject = %q", got)
+	}
+	if got := MessageSubject(&conf.Stream{Type: TypeNATS, Topic: "wx"}, "a.b*c>d e"); got != "wx.messages.a_b_c_d_e" {
+		t.Errorf("MessageSubject = %q", got)
+	}
+	if _, err := New(&conf.Stream{Type: "pulsar", Servers: []string{"x"}}); err == nil {
+		t.Error("unsupported type accepted")
+	}
+}
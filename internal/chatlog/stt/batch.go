@@ -0,0 +1,143 @@
+package stt
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultInterval 默认批量转写新语音的间隔
+const DefaultInterval = 10 * time.Minute
+
+// Voice 一条语音消息
+type Voice struct {
+	Talker string
+	Seq    int64
+	Time   time.Time
+	Key    string // 获取语音数据的键
+}
+
+// Source 提供语音消息与音频数据
+type Source interface {
+	// Voices 返回聊天对象从 since 开始按时间排列的语音消息
+	Voices(ctx context.Context, talker string, since time.Time) ([]*Voice, error)
+	// Audio 返回语音的音频数据与带扩展名的文件名
+	Audio(ctx context.Context, v *Voice) ([]byte, string, error)
+}
+
+// Service 转写语音消息并保存结果，同一条语音只转写一次
+type Service struct {
+	store       *Store
+	transcriber Transcriber
+	source      Source
+
+	// mu 保证同时只有一次批量转写
+	mu sync.Mutex
+}
+
+// NewService 创建转写服务
+func NewService(store *Store, transcriber Transcriber, source Source) *Service {
+	return &Service{store: store, transcriber: transcriber, source: source}
+}
+
+// Store 返回保存转写结果的数据库
+func (s *Service) Store() *Store {
+	return s.store
+}
+
+// Transcribe 返回语音的转写结果，没有保存的结果时转写并保存
+func (s *Service) Transcribe(ctx context.Context, v *Voice) (*Transcript, error) {
+	if t, err := s.store.Get(v.Talker, v.Seq); err != nil || t != nil {
+		return t, err
+	}
+	audio, name, err := s.source.Audio(ctx, v)
+	if err != nil {
+		return nil, err
+	}
+	return s.transcribe(ctx, v, audio, name)
+}
+
+// transcribe 转写音频并保存结果
+func (s *Service) transcribe(ctx context.Context, v *Voice, audio []byte, name string) (*Transcript, error) {
+	text, err := s.transcriber.Transcribe(ctx, audio, name)
+	if err != nil {
+		return nil, err
+	}
+	t := &Transcript{
+		Talker:   v.Talker,
+		Seq:      v.Seq,
+		Time:     v.Time,
+		Voice:    v.Key,
+		Text:     text,
+		Provider: s.transcriber.Name(),
+		Created:  time.Now(),
+	}
+	return t, s.store.Put(t)
+}
+
+// Run 每隔 interval 转写聊天对象的新语音，直到 ctx 结束
+func (s *Service) Run(ctx context.Context, talkers []string, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.Update(ctx, talkers); err != nil {
+			log.Debug().Err(err).Msg("transcribe voice messages failed")
+		} else if n > 0 {
+			log.Info().Int("count", n).Msg("voice messages transcribed")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Update 转写聊天对象上次批量转写之后的语音，返回新转写的数量
+// 读取不到音频数据的语音跳过；转写失败时停在该条语音，下次从该条继续
+func (s *Service) Update(ctx context.Context, talkers []string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, talker := range talkers {
+		seq, since, err := s.store.Cursor(talker)
+		if err != nil {
+			return count, err
+		}
+		voices, err := s.source.Voices(ctx, talker, since)
+		if err != nil {
+			return count, fmt.Errorf("%s: %w", talker, err)
+		}
+		for _, v := range voices {
+			if v.Seq <= seq {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return count, err
+			}
+			if t, err := s.store.Get(v.Talker, v.Seq); err != nil {
+				return count, err
+			} else if t == nil {
+				audio, name, err := s.source.Audio(ctx, v)
+				if err != nil {
+					log.Debug().Err(err).Str("talker", talker).Int64("seq", v.Seq).Msg("skip voice without audio")
+				} else if _, err := s.transcribe(ctx, v, audio, name); err != nil {
+					return count, fmt.Errorf("%s %d: %w", talker, v.Seq, err)
+				} else {
+					count++
+				}
+			}
+			if err := s.store.SetCursor(talker, v.Seq, v.Time); err != nil {
+				return count, err
+			}
+		}
+	}
+	return count, nil
+}
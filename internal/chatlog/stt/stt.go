@@ -0,0 +1,193 @@
+package stt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+)
+
+const (
+	ProviderNone    = "none"    // 不转写
+	ProviderOpenAI  = "openai"  // OpenAI 兼容的 /audio/transcriptions 接口
+	ProviderWhisper = "whisper" // 本地 whisper.cpp 可执行文件
+
+	// DefaultTimeout 未配置 timeout 时单条语音的转写超时
+	DefaultTimeout = 2 * time.Minute
+
+	// DefaultOpenAIModel openai 未配置 model 时使用的模型
+	DefaultOpenAIModel = "whisper-1"
+
+	// DefaultBinary whisper 未配置 binary 时在 PATH 中查找的可执行文件
+	DefaultBinary = "whisper-cli"
+)
+
+// Transcriber 语音转文字接口
+type Transcriber interface {
+	// Name 返回接口名称与模型，记录在转写结果中
+	Name() string
+	// Transcribe 转写音频，name 为带扩展名的文件名，用于识别音频格式
+	Transcribe(ctx context.Context, audio []byte, name string) (string, error)
+}
+
+// New 按配置创建转写接口，未配置或 provider 为 none 时返回 nil
+func New(c *conf.STT) (Transcriber, error) {
+	if c == nil {
+		return nil, nil
+	}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	switch strings.ToLower(strings.TrimSpace(c.Provider)) {
+	case "", ProviderNone:
+		return nil, nil
+	case ProviderOpenAI:
+		url := c.URL
+		if len(url) == 0 {
+			url = "https://api.openai.com/v1"
+		}
+		model := c.Model
+		if len(model) == 0 {
+			model = DefaultOpenAIModel
+		}
+		return &openAI{url: strings.TrimSuffix(url, "/"), model: model, apiKey: c.APIKey, language: c.Language,
+			client: &http.Client{Timeout: timeout}}, nil
+	case ProviderWhisper:
+		if len(c.Model) == 0 {
+			return nil, fmt.Errorf("stt.model is required for provider whisper, e.g. models/ggml-base.bin")
+		}
+		binary := c.Binary
+		if len(binary) == 0 {
+			binary = DefaultBinary
+		}
+		path, err := exec.LookPath(binary)
+		if err != nil {
+			return nil, fmt.Errorf("whisper.cpp binary %s not found: %v", binary, err)
+		}
+		return &whisper{binary: path, model: c.Model, language: c.Language, timeout: timeout}, nil
+	}
+	return nil, fmt.Errorf("invalid stt.provider %q, use openai, whisper or none", c.Provider)
+}
+
+// openAI OpenAI 兼容的 /audio/transcriptions 接口
+type openAI struct {
+	url      string
+	model    string
+	apiKey   string
+	language string
+	client   *http.Client
+}
+
+func (o *openAI) Name() string {
+	return ProviderOpenAI + "/" + o.model
+}
+
+func (o *openAI) Transcribe(ctx context.Context, audio []byte, name string) (string, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("file", name)
+	if err != nil {
+		return "", err
+	}
+	part.Write(audio)
+	w.WriteField("model", o.model)
+	w.WriteField("response_format", "json")
+	if len(o.language) != 0 {
+		w.WriteField("language", o.language)
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	url := o.url + "/audio/transcriptions"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	if len(o.apiKey) != 0 {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg := strings.TrimSpace(string(data))
+		if r := []rune(msg); len(r) > 200 {
+			msg = string(r[:200]) + "..."
+		}
+		return "", fmt.Errorf("post to %s failed, status code: %d, %s", url, resp.StatusCode, msg)
+	}
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return "", fmt.Errorf("invalid response from %s: %v", url, err)
+	}
+	return strings.TrimSpace(result.Text), nil
+}
+
+// whisper 调用本地 whisper.cpp 转写，音频写入临时文件，结果从标准输出读取
+type whisper struct {
+	binary   string
+	model    string
+	language string
+	timeout  time.Duration
+}
+
+func (w *whisper) Name() string {
+	return ProviderWhisper + "/" + filepath.Base(w.model)
+}
+
+func (w *whisper) Transcribe(ctx context.Context, audio []byte, name string) (string, error) {
+	f, err := os.CreateTemp("", "chatlog-stt-*"+filepath.Ext(name))
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(audio)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+	language := w.language
+	if len(language) == 0 {
+		language = "auto"
+	}
+	// -nt 不输出时间戳，-np 只输出转写结果
+	cmd := exec.CommandContext(ctx, w.binary, "-m", w.model, "-f", f.Name(), "-l", language, "-nt", "-np")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if r := []rune(msg); len(r) > 200 {
+			msg = string(r[len(r)-200:])
+		}
+		return "", fmt.Errorf("whisper.cpp failed: %v, %s", err, msg)
+	}
+	return strings.Join(strings.Fields(string(out)), " "), nil
+}
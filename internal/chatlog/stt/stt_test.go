@@ -0,0 +1,164 @@
+package stt
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+)
+
+// fakeTranscriber 返回音频内容作为转写结果
+type fakeTranscriber struct {
+	calls int
+	fail  bool
+}
+
+func (f *fakeTranscriber) Name() string { return "fake" }
+
+func (f *fakeTranscriber) Transcribe(ctx context.Context, audio []byte, name string) (string, error) {
+	f.calls++
+	if f.fail {
+		return "", fmt.Errorf("service unavailable")
+	}
+	return string(audio), nil
+}
+
+// fakeSource 每个聊天对象的语音，Key 为空的语音没有音频数据
+type fakeSource map[string][]*Voice
+
+func (f fakeSource) Voices(ctx context.Context, talker string, since time.Time) ([]*Voice, error) {
+	var ret []*Voice
+	for _, v := range f[talker] {
+		if !v.Time.Before(since) {
+			ret = append(ret, v)
+		}
+	}
+	return ret, nil
+}
+
+func (f fakeSource) Audio(ctx context.Context, v *Voice) ([]byte, string, error) {
+	if len(v.Key) == 0 {
+		return nil, "", fmt.Errorf("voice not found")
+	}
+	return []byte("text of " + v.Key), "voice.mp3", nil
+}
+
+func TestUpdate(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), TranscriptFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	ts := time.Unix(1700000000, 0)
+	source := fakeSource{"a": {
+		{Talker: "a", Seq: 1700000000000, Time: ts, Key: "v1"},
+		{Talker: "a", Seq: 1700000001000, Time: ts.Add(time.Second)},
+		{Talker: "a", Seq: 1700000002000, Time: ts.Add(2 * time.Second), Key: "v3"},
+	}}
+	transcriber := &fakeTranscriber{}
+	s := NewService(store, transcriber, source)
+
+	// 请求时转写的语音在批量转写时不再转写
+	if tr, err := s.Transcribe(context.Background(), source["a"][0]); err != nil || tr.Text != "text of v1" {
+		t.Fatalf("Transcribe = %+v, %v", tr, err)
+	}
+	n, err := s.Update(context.Background(), []string{"a"})
+	if err != nil || n != 1 || transcriber.calls != 2 {
+		t.Fatalf("Update = %d, %v, calls %d", n, err, transcriber.calls)
+	}
+	if n, err := s.Update(context.Background(), []string{"a"}); err != nil || n != 0 {
+		t.Fatalf("second Update = %d, %v", n, err)
+	}
+
+	// 转写失败时停在该条语音
+	source["a"] = append(source["a"], &Voice{Talker: "a", Seq: 1700000003000, Time: ts.Add(3 * time.Second), Key: "v4"})
+	transcriber.fail = true
+	if _, err := s.Update(context.Background(), []string{"a"}); err == nil {
+		t.Fatal("Update should fail")
+	}
+	transcriber.fail = false
+	if n, err := s.Update(context.Background(), []string{"a"}); err != nil || n != 1 {
+		t.Fatalf("retry Update = %d, %v", n, err)
+	}
+
+	list, err := store.Search("v3", "", 0)
+	if err != nil || len(list) != 1 || list[0].Seq != 1700000002000 || list[0].Provider != "fake" {
+		t.Fatalf("Search = %+v, %v", list, err)
+	}
+	if list, _ := store.Search("%", "", 0); len(list) != 0 {
+		t.Errorf("Search %% = %d results", len(list))
+	}
+	byseq, err := store.Range("a", ts, ts.Add(time.Hour))
+	if err != nil || len(byseq) != 3 {
+		t.Errorf("Range = %d, %v", len(byseq), err)
+	}
+}
+
+func TestOpenAI(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/transcriptions" || r.Header.Get("Authorization") != "Bearer key" {
+			http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		f, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(f)
+		fmt.Fprintf(w, `{"text":" %s %s %s %s "}`, header.Filename, data, r.FormValue("model"), r.FormValue("language"))
+	}))
+	defer srv.Close()
+
+	tr, err := New(&conf.STT{Provider: "openai", URL: srv.URL + "/v1", APIKey: "key", Language: "zh"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := tr.Transcribe(context.Background(), []byte("audio"), "voice.mp3")
+	if err != nil || text != "voice.mp3 audio whisper-1 zh" {
+		t.Errorf("Transcribe = %q, %v", text, err)
+	}
+
+	bad, _ := New(&conf.STT{Provider: "openai", URL: srv.URL + "/v1"})
+	if _, err := bad.Transcribe(context.Background(), []byte("audio"), "voice.mp3"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Transcribe error = %v", err)
+	}
+}
+
+func TestWhisper(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script")
+	}
+	// 模拟 whisper-cli，输出参数中的模型与语言
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "whisper-cli")
+	script := "#!/bin/sh\nwhile [ $# -gt 0 ]; do case $1 in -m) m=$2;; -l) l=$2;; -f) test -f \"$2\" || exit 1;; esac; shift; done\necho \"\"\necho \" $m $l \"\n"
+	if err := os.WriteFile(binary, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	tr, err := New(&conf.STT{Provider: "whisper", Binary: binary, Model: "ggml-base.bin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := tr.Transcribe(context.Background(), []byte("audio"), "voice.mp3")
+	if err != nil || text != "ggml-base.bin auto" {
+		t.Errorf("Transcribe = %q, %v", text, err)
+	}
+	if tr.Name() != "whisper/ggml-base.bin" {
+		t.Errorf("Name = %s", tr.Name())
+	}
+
+	if _, err := New(&conf.STT{Provider: "whisper"}); err == nil {
+		t.Error("whisper without model should fail")
+	}
+}
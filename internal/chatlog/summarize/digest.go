@@ -0,0 +1,86 @@
+package summarize
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+	"github.com/DanielMao1/chatlog/internal/chatlog/llm"
+)
+
+// DefaultPrompt 默认的总结提示词，要求大模型以 JSON 返回总结与重点
+const DefaultPrompt = `你是聊天记录整理助手。阅读用户提供的微信聊天记录，用简洁的中文写一段总结，概括讨论的主要话题、结论与待办事项，并列出最多 8 条重点。
+只输出 JSON，格式为 {"summary": "总结", "highlights": ["重点1", "重点2"]}，不要输出其他内容。`
+
+// Digest 使用大模型将按时间排列的消息改写为总结与重点，分享消息的标题附加在模型给出的重点之后
+// 消息超过 llm.max_chars 时只发送最近的部分
+func Digest(ctx context.Context, p llm.Provider, c *conf.LLM, payload *Payload) error {
+	system := DefaultPrompt
+	if c != nil && len(c.Prompt) != 0 {
+		system = c.Prompt
+	}
+	prompt := fmt.Sprintf("聊天对象：%s\n时间：%s 起，共 %d 条消息\n\n%s",
+		payload.Group, payload.Since, payload.MessageCount, Tail(payload.Summary, llm.MaxChars(c)))
+
+	reply, err := p.Complete(ctx, system, prompt)
+	if err != nil {
+		return err
+	}
+	summary, highlights := ParseDigest(reply)
+	if len(summary) == 0 {
+		return fmt.Errorf("empty summary from %s", p.Name())
+	}
+
+	seen := make(map[string]bool, len(highlights))
+	for _, h := range highlights {
+		seen[h] = true
+	}
+	for _, h := range payload.Highlights {
+		if !seen[h] {
+			highlights = append(highlights, h)
+			seen[h] = true
+		}
+	}
+	payload.Summary = summary
+	payload.Highlights = highlights
+	payload.Provider = p.Name()
+	return nil
+}
+
+// ParseDigest 解析大模型的回复，支持包含在 ``` 代码块中的 JSON，不是 JSON 时整个回复作为总结
+func ParseDigest(reply string) (string, []string) {
+	reply = strings.TrimSpace(reply)
+	text := reply
+	if start, end := strings.Index(text, "{"), strings.LastIndex(text, "}"); start >= 0 && end > start {
+		text = text[start : end+1]
+	}
+	var d struct {
+		Summary    string   `json:"summary"`
+		Highlights []string `json:"highlights"`
+	}
+	if err := json.Unmarshal([]byte(text), &d); err != nil || len(strings.TrimSpace(d.Summary)) == 0 {
+		return reply, []string{}
+	}
+	highlights := make([]string, 0, len(d.Highlights))
+	for _, h := range d.Highlights {
+		if h = strings.TrimSpace(h); len(h) != 0 {
+			highlights = append(highlights, h)
+		}
+	}
+	return strings.TrimSpace(d.Summary), highlights
+}
+
+// Tail 返回文本最后不超过 n 个字的部分，截断时从完整的一行开始
+func Tail(text string, n int) string {
+	r := []rune(text)
+	if n <= 0 || len(r) <= n {
+		return text
+	}
+	tail := string(r[len(r)-n:])
+	if i := strings.Index(tail, "\n"); i >= 0 && i+1 < len(tail) {
+		tail = tail[i+1:]
+	}
+	return tail
+}
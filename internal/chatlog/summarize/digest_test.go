@@ -0,0 +1,58 @@
+package summarize
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type fakeProvider struct {
+	reply  string
+	prompt string
+}
+
+func (p *fakeProvider) Name() string { return "fake/model" }
+
+func (p *fakeProvider) Complete(ctx context.Context, system, prompt string) (string, error) {
+	p.prompt = prompt
+	return p.reply, nil
+}
+
+func TestParseDigest(t *testing.T) {
+	summary, highlights := ParseDigest("```json\n{\"summary\": \"讨论了发布计划\", \"highlights\": [\"周五发布\", \" \"]}\n```")
+	if summary != "讨论了发布计划" || !reflect.DeepEqual(highlights, []string{"周五发布"}) {
+		t.Errorf("ParseDigest() = %q, %v", summary, highlights)
+	}
+	summary, highlights = ParseDigest("纯文本总结")
+	if summary != "纯文本总结" || len(highlights) != 0 {
+		t.Errorf("ParseDigest() of plain text = %q, %v", summary, highlights)
+	}
+}
+
+func TestTail(t *testing.T) {
+	text := "第一行\n第二行\n第三行"
+	if got := Tail(text, 100); got != text {
+		t.Errorf("Tail() should keep short text, got %q", got)
+	}
+	if got := Tail(text, 6); got != "第三行" {
+		t.Errorf("Tail() = %q, want 第三行", got)
+	}
+}
+
+func TestDigest(t *testing.T) {
+	p := &fakeProvider{reply: `{"summary": "总结", "highlights": ["重点", "设计文档"]}`}
+	payload := &Payload{Group: "项目群", Summary: "[10:00] 张三: 设计文档", Highlights: []string{"设计文档", "周报"}, MessageCount: 1}
+	if err := Digest(context.Background(), p, nil, payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Summary != "总结" || payload.Provider != "fake/model" {
+		t.Errorf("unexpected payload %+v", payload)
+	}
+	if !reflect.DeepEqual(payload.Highlights, []string{"重点", "设计文档", "周报"}) {
+		t.Errorf("Highlights = %v", payload.Highlights)
+	}
+	if !strings.Contains(p.prompt, "项目群") || !strings.Contains(p.prompt, "张三: 设计文档") {
+		t.Errorf("prompt should contain the group and transcript, got %q", p.prompt)
+	}
+}
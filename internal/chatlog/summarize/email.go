@@ -0,0 +1,54 @@
+package summarize
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/mail"
+)
+
+// EmailHighlights 邮件摘要中每个聊天对象最多列出的重点、链接与文件数量
+const EmailHighlights = 10
+
+// Email 将多个聊天对象的总结整理为一封邮件，包含纯文本与 HTML 两种格式
+func Email(payloads []*Payload, date time.Time) *mail.Message {
+	subject := fmt.Sprintf("聊天摘要 %s", date.Format("2006-01-02"))
+
+	var text, body strings.Builder
+	body.WriteString(`<html><body style="font-family: sans-serif">`)
+	body.WriteString("<h2>" + html.EscapeString(subject) + "</h2>")
+	if len(payloads) == 0 {
+		text.WriteString("过去一天没有新消息。\n")
+		body.WriteString("<p>过去一天没有新消息。</p>")
+	}
+	for _, p := range payloads {
+		title := fmt.Sprintf("%s（%d 条消息）", p.Group, p.MessageCount)
+		text.WriteString("## " + title + "\n\n" + p.Summary + "\n")
+		body.WriteString("<h3>" + html.EscapeString(title) + "</h3>")
+		body.WriteString(`<p style="white-space: pre-wrap">` + html.EscapeString(p.Summary) + "</p>")
+
+		highlights := p.Highlights
+		if len(highlights) > EmailHighlights {
+			highlights = highlights[:EmailHighlights]
+		}
+		if len(highlights) != 0 {
+			text.WriteString("\n重点：\n")
+			body.WriteString("<p>重点：</p><ul>")
+			for _, h := range highlights {
+				text.WriteString("- " + h + "\n")
+				body.WriteString("<li>" + html.EscapeString(h) + "</li>")
+			}
+			body.WriteString("</ul>")
+		}
+		text.WriteString("\n")
+	}
+	body.WriteString("</body></html>")
+
+	return &mail.Message{
+		Subject: subject,
+		Text:    strings.TrimSpace(text.String()),
+		HTML:    body.String(),
+	}
+}
@@ -0,0 +1,31 @@
+package summarize
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmail(t *testing.T) {
+	highlights := make([]string, 0, EmailHighlights+2)
+	for i := 0; i < EmailHighlights+2; i++ {
+		highlights = append(highlights, fmt.Sprintf("链接 %d", i))
+	}
+	msg := Email([]*Payload{
+		{Group: "项目群", Summary: "讨论了 <发布> 计划", Highlights: highlights, MessageCount: 12},
+	}, time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local))
+
+	if msg.Subject != "聊天摘要 2024-03-01" {
+		t.Errorf("Subject = %q", msg.Subject)
+	}
+	if !strings.Contains(msg.Text, "项目群（12 条消息）") || !strings.Contains(msg.Text, "讨论了 <发布> 计划") {
+		t.Errorf("unexpected text body:\n%s", msg.Text)
+	}
+	if !strings.Contains(msg.HTML, "讨论了 &lt;发布&gt; 计划") {
+		t.Errorf("html body should be escaped:\n%s", msg.HTML)
+	}
+	if strings.Contains(msg.Text, fmt.Sprintf("链接 %d", EmailHighlights)) {
+		t.Errorf("highlights should be limited to %d", EmailHighlights)
+	}
+}
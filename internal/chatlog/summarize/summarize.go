@@ -0,0 +1,132 @@
+package summarize
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+	"github.com/DanielMao1/chatlog/internal/chatlog/push"
+	"github.com/DanielMao1/chatlog/internal/model"
+)
+
+const (
+	ToStdout  = "stdout"  // 输出到标准输出
+	ToWebhook = "webhook" // 推送到配置文件中 summarize 指定的地址
+)
+
+// Payload 推送给接收端的总结内容
+type Payload struct {
+	Source       string   `json:"source"`
+	Talker       string   `json:"talker"`
+	Group        string   `json:"group"` // 聊天对象名称
+	Since        string   `json:"since"`
+	Summary      string   `json:"summary"`
+	Highlights   []string `json:"highlights"`
+	MessageCount int      `json:"message_count"`
+	TS           string   `json:"ts"`
+	// Provider 生成总结的大模型，未使用大模型时为空，Summary 为按时间排列的消息
+	Provider string `json:"provider,omitempty"`
+	// Link 在 HTTP 服务中查看这段聊天记录的地址，未开启 HTTP 服务时为空
+	Link string `json:"link,omitempty"`
+}
+
+// ChatMessage 转换为 Slack、Discord 消息，重点列在总结之后
+func (p *Payload) ChatMessage() *push.Message {
+	text := p.Summary
+	if len(p.Highlights) != 0 {
+		text += "\n\n• " + strings.Join(p.Highlights, "\n• ")
+	}
+	return &push.Message{
+		Title: fmt.Sprintf("%s（%d 条消息）", p.Group, p.MessageCount),
+		Text:  text,
+		Link:  p.Link,
+	}
+}
+
+// Build 将一段时间内的消息整理为按时间排列的文本，并提取分享消息的标题作为重点
+func Build(talker, name string, since time.Time, messages []*model.Message, now time.Time) *Payload {
+	multiDay := since.Format("2006-01-02") != now.Format("2006-01-02")
+
+	var buf strings.Builder
+	highlights := make([]string, 0)
+	for _, msg := range messages {
+		timeFormat := "15:04"
+		if multiDay {
+			timeFormat = "01-02 15:04"
+		}
+		buf.WriteString("[" + msg.Time.Format(timeFormat) + "] ")
+		if msg.IsChatRoom {
+			sender := msg.SenderName
+			if len(sender) == 0 {
+				sender = msg.Sender
+			}
+			if msg.IsSelf {
+				sender = "我"
+			}
+			buf.WriteString(sender + ": ")
+		}
+		buf.WriteString(msg.PlainTextContent())
+		buf.WriteString("\n")
+
+		// 链接、文件等分享消息的标题
+		if msg.Type == model.MessageTypeShare && msg.Contents != nil {
+			if title, ok := msg.Contents["title"].(string); ok && title != "" {
+				highlights = append(highlights, title)
+			}
+		}
+	}
+
+	if len(name) == 0 {
+		name = talker
+	}
+	return &Payload{
+		Source:       "wechat",
+		Talker:       talker,
+		Group:        name,
+		Since:        since.Format(time.RFC3339),
+		Summary:      strings.TrimSpace(buf.String()),
+		Highlights:   highlights,
+		MessageCount: len(messages),
+		TS:           now.Format(time.RFC3339),
+	}
+}
+
+// ParseSince 解析时间范围，除 time.ParseDuration 支持的格式外，还支持以天为单位，如 7d
+func ParseSince(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration: %s", s)
+	}
+	return d, nil
+}
+
+// Target 解析推送目标，返回 nil 表示输出到标准输出
+// to 为 webhook 时使用 summarize 配置，也可以是 destinations 中的名称或直接指定的 URL
+func Target(to string, c *conf.Summarize, dests map[string]*conf.Destination) (*conf.Destination, error) {
+	switch {
+	case len(to) == 0 || to == ToStdout:
+		return nil, nil
+	case to == ToWebhook:
+		if c != nil && len(c.Destination) != 0 {
+			return push.Resolve(c.Destination, dests)
+		}
+		if c == nil || len(c.URL) == 0 {
+			return nil, fmt.Errorf("summarize.url is not configured")
+		}
+		return &conf.Destination{URL: c.URL, Headers: c.Headers}, nil
+	case strings.HasPrefix(to, "http://") || strings.HasPrefix(to, "https://"):
+		// 配置的请求头可能包含鉴权信息，不发送给其他地址
+		return &conf.Destination{URL: to}, nil
+	}
+	if _, ok := dests[to]; ok {
+		return push.Resolve(to, dests)
+	}
+	return nil, fmt.Errorf("invalid target: %s, use stdout, webhook, a destination name or a URL", to)
+}
@@ -0,0 +1,149 @@
+package chatlog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/telegram"
+	"github.com/DanielMao1/chatlog/internal/i18n"
+	"github.com/DanielMao1/chatlog/internal/model"
+)
+
+const (
+	// TelegramRecentPeriod /recent 查询的消息时长
+	TelegramRecentPeriod = 7 * 24 * time.Hour
+
+	// TelegramDefaultLimit /recent 与 /search 默认返回的消息数量
+	TelegramDefaultLimit = 20
+
+	// TelegramMaxLimit /recent 最多返回的消息数量
+	TelegramMaxLimit = 100
+)
+
+// startTelegram 配置了 telegram.token 时在后台启动 Telegram 机器人
+func (m *Manager) startTelegram() {
+	c := m.ctx.GetTelegram()
+	if c == nil || len(c.Token) == 0 {
+		return
+	}
+	bot, err := telegram.New(c, m.telegramCommands())
+	if err != nil {
+		log.Warn().Err(err).Msg("start telegram bot failed")
+		return
+	}
+	m.bot = bot
+	go bot.Run(m.runCtx)
+	log.Info().Int64("chat_id", c.ChatID).Msg("telegram bot started")
+}
+
+// telegramCommands 返回 Telegram 机器人支持的命令
+func (m *Manager) telegramCommands() map[string]*telegram.Command {
+	return map[string]*telegram.Command{
+		"recent": {
+			Usage:   i18n.T("<聊天对象> [数量]"),
+			Help:    i18n.T("查看聊天对象最近 7 天的消息"),
+			Handler: m.telegramRecent,
+		},
+		"search": {
+			Usage:   i18n.T("<关键字>"),
+			Help:    i18n.T("在所有会话中搜索消息"),
+			Handler: m.telegramSearch,
+		},
+		"sessions": {
+			Help:    i18n.T("查看最近的会话"),
+			Handler: m.telegramSessions,
+		},
+	}
+}
+
+// telegramRecent 返回聊天对象最近的消息，参数为聊天对象与可选的数量
+func (m *Manager) telegramRecent(args string) (string, error) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return "", i18n.Errorf("请指定聊天对象")
+	}
+	talker, limit := fields[0], TelegramDefaultLimit
+	if len(fields) > 1 {
+		n, err := strconv.Atoi(fields[1])
+		if err != nil || n <= 0 {
+			return "", i18n.Errorf("无效的数量: %s", fields[1])
+		}
+		limit = min(n, TelegramMaxLimit)
+	}
+	now := time.Now()
+	messages, err := m.BrowseMessages(talker, now.Add(-TelegramRecentPeriod), now)
+	if err != nil {
+		return "", err
+	}
+	if len(messages) > limit {
+		messages = messages[len(messages)-limit:]
+	}
+	return telegramMessages(messages, false), nil
+}
+
+// telegramSearch 返回在所有会话中搜索到的最近的消息
+func (m *Manager) telegramSearch(args string) (string, error) {
+	if len(args) == 0 {
+		return "", i18n.Errorf("请指定关键字")
+	}
+	messages, err := m.SearchMessages(args, TelegramDefaultLimit)
+	if err != nil {
+		return "", err
+	}
+	return telegramMessages(messages, true), nil
+}
+
+// telegramSessions 返回最近的会话与最后一条消息
+func (m *Manager) telegramSessions(string) (string, error) {
+	sessions, err := m.BrowseSessions("", 10, 0)
+	if err != nil {
+		return "", err
+	}
+	var buf strings.Builder
+	for _, s := range sessions {
+		name := s.NickName
+		if len(name) == 0 {
+			name = s.UserName
+		}
+		fmt.Fprintf(&buf, "%s (%s) %s\n%s\n\n", name, s.UserName, s.NTime.Format("01-02 15:04"), snippet(s.Content, NotifySnippetLength))
+	}
+	return buf.String(), nil
+}
+
+// telegramMessages 将消息整理为每行一条的文本，withTalker 时附加聊天对象名称
+func telegramMessages(messages []*model.Message, withTalker bool) string {
+	var buf strings.Builder
+	for _, msg := range messages {
+		buf.WriteString("[" + msg.Time.Format("01-02 15:04") + "] ")
+		if withTalker {
+			name := msg.TalkerName
+			if len(name) == 0 {
+				name = msg.Talker
+			}
+			buf.WriteString(name + " - ")
+		}
+		sender := msg.SenderName
+		if len(sender) == 0 {
+			sender = msg.Sender
+		}
+		if msg.IsSelf {
+			sender = i18n.T("我")
+		}
+		buf.WriteString(sender + ": " + snippet(msg.PlainTextContent(), 200) + "\n")
+	}
+	return buf.String()
+}
+
+// sendTelegram 通过 Telegram 机器人发送新消息通知，未启动机器人时忽略
+func (m *Manager) sendTelegram(n *Notification) {
+	if m.bot == nil {
+		return
+	}
+	if err := m.bot.Send(m.runCtx, n.Title+"\n"+n.Snippet); err != nil {
+		log.Debug().Err(err).Msg("send telegram notification failed")
+	}
+}
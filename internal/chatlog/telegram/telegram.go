@@ -0,0 +1,246 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+)
+
+const (
+	// DefaultURL 默认的 Bot API 地址
+	DefaultURL = "https://api.telegram.org"
+
+	// DefaultTimeout 默认的长轮询等待时间
+	DefaultTimeout = 30 * time.Second
+
+	// MaxMessageLength Telegram 单条消息的最大字数，超出时分多条发送
+	MaxMessageLength = 4096
+
+	// retryDelay 获取消息失败后重试的间隔
+	retryDelay = 5 * time.Second
+)
+
+// Command 处理机器人命令，args 为命令后的文本，返回回复的内容
+type Command struct {
+	Usage   string // 命令的参数说明，如 <keyword>
+	Help    string
+	Handler func(args string) (string, error)
+}
+
+// Bot Telegram 机器人，通过长轮询接收命令，只响应配置的主人会话
+type Bot struct {
+	c        *conf.Telegram
+	client   *http.Client
+	commands map[string]*Command
+	offset   int64
+}
+
+// New 创建机器人，commands 的键为不带 / 的命令名称
+func New(c *conf.Telegram, commands map[string]*Command) (*Bot, error) {
+	if c == nil || len(c.Token) == 0 {
+		return nil, fmt.Errorf("telegram.token is not configured")
+	}
+	return &Bot{
+		c:        c,
+		client:   &http.Client{Timeout: timeout(c) + 10*time.Second},
+		commands: commands,
+	}, nil
+}
+
+// Run 持续接收并处理命令，直到 ctx 结束
+func (b *Bot) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		if err := b.Poll(ctx); err != nil && ctx.Err() == nil {
+			log.Debug().Err(err).Msg("get telegram updates failed")
+			select {
+			case <-ctx.Done():
+			case <-time.After(retryDelay):
+			}
+		}
+	}
+}
+
+// Poll 获取一次新消息并处理其中的命令
+func (b *Bot) Poll(ctx context.Context) error {
+	var updates []struct {
+		UpdateID int64 `json:"update_id"`
+		Message  *struct {
+			Chat struct {
+				ID int64 `json:"id"`
+			} `json:"chat"`
+			Text string `json:"text"`
+		} `json:"message"`
+	}
+	err := b.call(ctx, "getUpdates", map[string]any{
+		"offset":          b.offset,
+		"timeout":         int(timeout(b.c).Seconds()),
+		"allowed_updates": []string{"message"},
+	}, &updates)
+	if err != nil {
+		return err
+	}
+
+	for _, u := range updates {
+		b.offset = u.UpdateID + 1
+		if u.Message == nil || !strings.HasPrefix(u.Message.Text, "/") {
+			continue
+		}
+		chatID := u.Message.Chat.ID
+		if b.c.ChatID == 0 {
+			// 未配置主人时只告知会话 ID，不执行命令
+			b.reply(ctx, chatID, fmt.Sprintf("chatlog: set telegram.chat_id to %d to use this bot", chatID))
+			continue
+		}
+		if chatID != b.c.ChatID {
+			log.Debug().Int64("chat_id", chatID).Msg("ignore telegram message from other chat")
+			continue
+		}
+		b.reply(ctx, chatID, b.handle(u.Message.Text))
+	}
+	return nil
+}
+
+// handle 执行命令，返回回复的内容
+func (b *Bot) handle(text string) string {
+	name, args, _ := strings.Cut(strings.TrimSpace(text), " ")
+	// 群组中的命令可能带有机器人名称，如 /search@chatlog_bot
+	name, _, _ = strings.Cut(strings.TrimPrefix(name, "/"), "@")
+	cmd, ok := b.commands[name]
+	if !ok {
+		return b.Help()
+	}
+	reply, err := cmd.Handler(strings.TrimSpace(args))
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	if len(strings.TrimSpace(reply)) == 0 {
+		return "no result"
+	}
+	return reply
+}
+
+// Help 返回命令列表
+func (b *Bot) Help() string {
+	names := make([]string, 0, len(b.commands))
+	for name := range b.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf strings.Builder
+	buf.WriteString("chatlog commands:\n")
+	for _, name := range names {
+		cmd := b.commands[name]
+		buf.WriteString("/" + name)
+		if len(cmd.Usage) != 0 {
+			buf.WriteString(" " + cmd.Usage)
+		}
+		buf.WriteString(" - " + cmd.Help + "\n")
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// Send 向主人会话发送消息，用于新消息通知
+func (b *Bot) Send(ctx context.Context, text string) error {
+	if b.c.ChatID == 0 {
+		return fmt.Errorf("telegram.chat_id is not configured")
+	}
+	for _, part := range Split(text, MaxMessageLength) {
+		if err := b.call(ctx, "sendMessage", map[string]any{"chat_id": b.c.ChatID, "text": part}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reply 回复命令，失败时只记录日志
+func (b *Bot) reply(ctx context.Context, chatID int64, text string) {
+	for _, part := range Split(text, MaxMessageLength) {
+		if err := b.call(ctx, "sendMessage", map[string]any{"chat_id": chatID, "text": part}, nil); err != nil {
+			log.Debug().Err(err).Msg("send telegram message failed")
+			return
+		}
+	}
+}
+
+// call 调用 Bot API，result 为 nil 时忽略返回结果
+func (b *Bot) call(ctx context.Context, method string, params map[string]any, result any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	base := b.c.URL
+	if len(base) == 0 {
+		base = DefaultURL
+	}
+	url := strings.TrimRight(base, "/") + "/bot" + b.c.Token + "/" + method
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		// 错误信息中的地址包含 token，不返回给调用方
+		return fmt.Errorf("telegram %s request failed", method)
+	}
+	defer resp.Body.Close()
+
+	var r struct {
+		OK          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return fmt.Errorf("telegram %s: status code %d", method, resp.StatusCode)
+	}
+	if !r.OK {
+		return fmt.Errorf("telegram %s: %s", method, r.Description)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(r.Result, result)
+}
+
+// Split 按行将文本分为不超过 n 个字的多段，单行超长时截断
+func Split(text string, n int) []string {
+	var parts []string
+	var buf []rune
+	for _, line := range strings.Split(text, "\n") {
+		r := []rune(line)
+		if len(r) > n {
+			r = r[:n]
+		}
+		if len(buf) != 0 && len(buf)+1+len(r) > n {
+			parts = append(parts, string(buf))
+			buf = buf[:0]
+		}
+		if len(buf) != 0 {
+			buf = append(buf, '\n')
+		}
+		buf = append(buf, r...)
+	}
+	if len(buf) != 0 || len(parts) == 0 {
+		parts = append(parts, string(buf))
+	}
+	return parts
+}
+
+// timeout 返回长轮询等待时间
+func timeout(c *conf.Telegram) time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return DefaultTimeout
+}
@@ -0,0 +1,92 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+)
+
+// fakeAPI 返回固定的 getUpdates 结果，并记录 sendMessage 发送的消息
+func fakeAPI(t *testing.T, updates string) (*httptest.Server, *[]map[string]any) {
+	t.Helper()
+	var sent []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/bottoken/") {
+			http.NotFound(w, r)
+			return
+		}
+		switch strings.TrimPrefix(r.URL.Path, "/bottoken/") {
+		case "getUpdates":
+			w.Write([]byte(`{"ok": true, "result": ` + updates + `}`))
+		case "sendMessage":
+			var params map[string]any
+			json.NewDecoder(r.Body).Decode(&params)
+			sent = append(sent, params)
+			w.Write([]byte(`{"ok": true, "result": {}}`))
+		default:
+			w.Write([]byte(`{"ok": false, "description": "Not Found"}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &sent
+}
+
+func TestPoll(t *testing.T) {
+	srv, sent := fakeAPI(t, `[
+		{"update_id": 10, "message": {"chat": {"id": 42}, "text": "/search@chatlog_bot 周报"}},
+		{"update_id": 11, "message": {"chat": {"id": 7}, "text": "/search 周报"}},
+		{"update_id": 12, "message": {"chat": {"id": 42}, "text": "/unknown"}}
+	]`)
+	var got string
+	b, err := New(&conf.Telegram{Token: "token", ChatID: 42, URL: srv.URL}, map[string]*Command{
+		"search": {Usage: "<keyword>", Help: "search messages", Handler: func(args string) (string, error) {
+			got = args
+			return "result", nil
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got != "周报" {
+		t.Errorf("handler args = %q", got)
+	}
+	if b.offset != 13 {
+		t.Errorf("offset = %d, want 13", b.offset)
+	}
+	// 其他会话的消息不处理，未知命令回复帮助
+	if len(*sent) != 2 || (*sent)[0]["text"] != "result" || !strings.Contains((*sent)[1]["text"].(string), "/search <keyword>") {
+		t.Errorf("unexpected replies %v", *sent)
+	}
+}
+
+func TestPollWithoutOwner(t *testing.T) {
+	srv, sent := fakeAPI(t, `[{"update_id": 1, "message": {"chat": {"id": 42}, "text": "/start"}}]`)
+	b, _ := New(&conf.Telegram{Token: "token", URL: srv.URL}, nil)
+	if err := b.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(*sent) != 1 || !strings.Contains((*sent)[0]["text"].(string), "telegram.chat_id to 42") {
+		t.Errorf("unexpected replies %v", *sent)
+	}
+	if err := b.Send(context.Background(), "hi"); err == nil {
+		t.Error("Send without chat_id should fail")
+	}
+}
+
+func TestSplit(t *testing.T) {
+	parts := Split("aaa\nbbb\ncc", 7)
+	if len(parts) != 2 || parts[0] != "aaa\nbbb" || parts[1] != "cc" {
+		t.Errorf("Split() = %q", parts)
+	}
+	if parts := Split(strings.Repeat("长", 10), 4); len(parts) != 1 || parts[0] != "长长长长" {
+		t.Errorf("Split() of long line = %q", parts)
+	}
+}
@@ -0,0 +1,50 @@
+package chatlog
+
+import (
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+	"github.com/DanielMao1/chatlog/internal/chatlog/translate"
+	"github.com/DanielMao1/chatlog/internal/i18n"
+	"github.com/DanielMao1/chatlog/internal/model"
+)
+
+// translateConfig 返回当前使用的翻译与大模型配置，命令行模式使用服务配置，TUI 模式使用账号配置
+func (m *Manager) translateConfig() (*conf.Translate, *conf.LLM) {
+	if m.sc != nil {
+		return m.sc.GetTranslate(), m.sc.GetLLM()
+	}
+	return m.ctx.GetTranslate(), m.ctx.GetLLM()
+}
+
+// translateMessages 将文本消息翻译为目标语言，译文保存在 Contents["translation"]
+func (m *Manager) translateMessages(messages []*model.Message, target string) error {
+	t, err := translate.New(m.translateConfig())
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		return i18n.Errorf("未配置翻译接口，请先配置 translate")
+	}
+	texts := make([]string, 0)
+	index := make([]*model.Message, 0)
+	for _, msg := range messages {
+		if msg.Type == model.MessageTypeText {
+			texts = append(texts, msg.Content)
+			index = append(index, msg)
+		}
+	}
+	translated, err := translate.Texts(m.runCtx, t, texts, target)
+	if err != nil {
+		return i18n.Errorf("翻译失败: %v", err)
+	}
+	for i, tr := range translated {
+		if len(tr) == 0 {
+			continue
+		}
+		msg := index[i]
+		if msg.Contents == nil {
+			msg.Contents = make(map[string]interface{})
+		}
+		msg.Contents["translation"] = tr
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package translate
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const (
+	// BatchSize、BatchChars 每次请求翻译的最大文本数量与字数
+	BatchSize  = 50
+	BatchChars = 4000
+
+	// CacheSize 缓存的译文数量，超出时清空
+	CacheSize = 10000
+)
+
+// languages 大模型提示词中使用的语言名称
+var languages = map[string]string{
+	"en": "English",
+	"zh": "Simplified Chinese",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"fr": "French",
+	"de": "German",
+	"es": "Spanish",
+	"pt": "Portuguese",
+	"ru": "Russian",
+	"it": "Italian",
+	"vi": "Vietnamese",
+	"th": "Thai",
+	"id": "Indonesian",
+}
+
+// LanguageName 返回语言代码对应的英文名称，未知的代码原样返回
+func LanguageName(code string) string {
+	lower := strings.ToLower(code)
+	switch lower {
+	case "zh-tw", "zh-hk", "zh-hant":
+		return "Traditional Chinese"
+	}
+	if name, ok := languages[base(lower)]; ok {
+		return name
+	}
+	return code
+}
+
+// base 返回语言代码中地区之前的部分，如 zh-CN 返回 zh
+func base(code string) string {
+	if i := strings.IndexAny(code, "-_"); i > 0 {
+		return code[:i]
+	}
+	return code
+}
+
+// Needs 判断文本是否需要翻译为目标语言
+// 没有文字的文本（表情、数字、链接）不翻译；目标为中文时不翻译含有汉字的文本，目标为英文时不翻译只含拉丁字母的文本
+func Needs(text, target string) bool {
+	letters, han, other := false, false, false
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters = true
+		switch {
+		case unicode.Is(unicode.Han, r):
+			han = true
+		case !unicode.Is(unicode.Latin, r):
+			other = true
+		}
+	}
+	if !letters || (strings.Contains(text, "://") && len(strings.Fields(text)) == 1) {
+		return false
+	}
+	switch base(strings.ToLower(target)) {
+	case "zh":
+		return !han
+	case "en":
+		return han || other
+	}
+	return true
+}
+
+// cache 已翻译的文本，键为 目标语言 + 原文
+var cache = struct {
+	sync.Mutex
+	m map[string]string
+}{m: make(map[string]string)}
+
+// Texts 将 texts 翻译为目标语言，返回的译文与 texts 一一对应，不需要翻译的文本译文为空
+// 相同的文本只翻译一次，按 BatchSize、BatchChars 分批请求，译文缓存在内存中
+func Texts(ctx context.Context, t Translator, texts []string, target string) ([]string, error) {
+	ret := make([]string, len(texts))
+	pending := make(map[string][]int)
+	var order []string
+	cache.Lock()
+	for i, text := range texts {
+		text = strings.TrimSpace(text)
+		if !Needs(text, target) {
+			continue
+		}
+		if tr, ok := cache.m[target+"\x00"+text]; ok {
+			ret[i] = tr
+			continue
+		}
+		if _, ok := pending[text]; !ok {
+			order = append(order, text)
+		}
+		pending[text] = append(pending[text], i)
+	}
+	cache.Unlock()
+
+	for len(order) > 0 {
+		n, chars := 0, 0
+		for n < len(order) && n < BatchSize && (n == 0 || chars+len([]rune(order[n])) <= BatchChars) {
+			chars += len([]rune(order[n]))
+			n++
+		}
+		batch := order[:n]
+		order = order[n:]
+
+		translated, err := t.Translate(ctx, batch, target)
+		if err != nil {
+			return nil, err
+		}
+		cache.Lock()
+		if len(cache.m)+len(batch) > CacheSize {
+			cache.m = make(map[string]string)
+		}
+		for j, text := range batch {
+			tr := strings.TrimSpace(translated[j])
+			cache.m[target+"\x00"+text] = tr
+			for _, i := range pending[text] {
+				ret[i] = tr
+			}
+		}
+		cache.Unlock()
+	}
+	return ret, nil
+}
@@ -0,0 +1,224 @@
+package translate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+	"github.com/DanielMao1/chatlog/internal/chatlog/llm"
+)
+
+const (
+	ProviderNone           = "none"           // 不翻译
+	ProviderLLM            = "llm"            // 使用 llm 配置的大模型
+	ProviderDeepL          = "deepl"          // DeepL API
+	ProviderLibreTranslate = "libretranslate" // 自建的 LibreTranslate 服务
+
+	// DefaultTimeout 未配置 timeout 时的请求超时
+	DefaultTimeout = time.Minute
+)
+
+// Translator 翻译接口
+type Translator interface {
+	// Name 返回接口名称，记录在日志中
+	Name() string
+	// Translate 将 texts 翻译为目标语言，返回的译文与 texts 一一对应
+	Translate(ctx context.Context, texts []string, target string) ([]string, error)
+}
+
+// New 按配置创建翻译接口，未配置或 provider 为 none 时返回 nil
+// provider 为 llm 时使用 lc 配置的大模型
+func New(c *conf.Translate, lc *conf.LLM) (Translator, error) {
+	if c == nil {
+		return nil, nil
+	}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+
+	switch strings.ToLower(strings.TrimSpace(c.Provider)) {
+	case "", ProviderNone:
+		return nil, nil
+	case ProviderLLM:
+		provider, err := llm.New(lc)
+		if err != nil {
+			return nil, err
+		}
+		if provider == nil {
+			return nil, fmt.Errorf("llm is not configured, translate.provider llm uses the llm config")
+		}
+		return &llmTranslator{provider: provider}, nil
+	case ProviderDeepL:
+		if len(c.APIKey) == 0 {
+			return nil, fmt.Errorf("translate.api_key is required for provider deepl")
+		}
+		url := c.URL
+		if len(url) == 0 {
+			// 免费版的密钥以 :fx 结尾
+			url = "https://api.deepl.com/v2"
+			if strings.HasSuffix(c.APIKey, ":fx") {
+				url = "https://api-free.deepl.com/v2"
+			}
+		}
+		return &deepL{url: strings.TrimSuffix(url, "/"), apiKey: c.APIKey, client: client}, nil
+	case ProviderLibreTranslate:
+		url := c.URL
+		if len(url) == 0 {
+			url = "http://localhost:5000"
+		}
+		return &libreTranslate{url: strings.TrimSuffix(url, "/"), apiKey: c.APIKey, client: client}, nil
+	}
+	return nil, fmt.Errorf("invalid translate.provider %q, use llm, deepl, libretranslate or none", c.Provider)
+}
+
+// llmTranslator 由大模型翻译，一次发送一组文本，要求按 JSON 数组返回
+type llmTranslator struct {
+	provider llm.Provider
+}
+
+func (t *llmTranslator) Name() string {
+	return ProviderLLM + "/" + t.provider.Name()
+}
+
+func (t *llmTranslator) Translate(ctx context.Context, texts []string, target string) ([]string, error) {
+	input, err := json.Marshal(texts)
+	if err != nil {
+		return nil, err
+	}
+	system := fmt.Sprintf("You are a translator. Translate every string in the JSON array from the user into %s. "+
+		"Keep names, links and emoji unchanged. Reply with only a JSON array of strings with the same length and order, without explanations.", LanguageName(target))
+	reply, err := t.provider.Complete(ctx, system, string(input))
+	if err != nil {
+		return nil, err
+	}
+	// 模型可能在数组前后输出代码块标记或说明
+	start, end := strings.Index(reply, "["), strings.LastIndex(reply, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("invalid response from %s: no JSON array", t.Name())
+	}
+	var ret []string
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &ret); err != nil {
+		return nil, fmt.Errorf("invalid response from %s: %v", t.Name(), err)
+	}
+	if len(ret) != len(texts) {
+		return nil, fmt.Errorf("invalid response from %s: %d translations for %d texts", t.Name(), len(ret), len(texts))
+	}
+	return ret, nil
+}
+
+// deepL DeepL API，https://developers.deepl.com/docs/api-reference/translate
+type deepL struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+func (t *deepL) Name() string {
+	return ProviderDeepL
+}
+
+func (t *deepL) Translate(ctx context.Context, texts []string, target string) ([]string, error) {
+	// DeepL 的目标语言需要区分英语与葡萄牙语的地区，中文使用简体
+	lang := strings.ToUpper(target)
+	switch lang {
+	case "EN":
+		lang = "EN-US"
+	case "PT":
+		lang = "PT-BR"
+	case "ZH", "ZH-CN":
+		lang = "ZH-HANS"
+	case "ZH-TW", "ZH-HK":
+		lang = "ZH-HANT"
+	}
+	var resp struct {
+		Translations []struct {
+			Text string `json:"text"`
+		} `json:"translations"`
+	}
+	err := postJSON(ctx, t.client, t.url+"/translate", map[string]string{"Authorization": "DeepL-Auth-Key " + t.apiKey},
+		map[string]any{"text": texts, "target_lang": lang}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Translations) != len(texts) {
+		return nil, fmt.Errorf("invalid response from %s: %d translations for %d texts", t.Name(), len(resp.Translations), len(texts))
+	}
+	ret := make([]string, len(texts))
+	for i, tr := range resp.Translations {
+		ret[i] = tr.Text
+	}
+	return ret, nil
+}
+
+// libreTranslate LibreTranslate 服务，https://github.com/LibreTranslate/LibreTranslate
+type libreTranslate struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+func (t *libreTranslate) Name() string {
+	return ProviderLibreTranslate
+}
+
+func (t *libreTranslate) Translate(ctx context.Context, texts []string, target string) ([]string, error) {
+	req := map[string]any{"q": texts, "source": "auto", "target": strings.ToLower(target), "format": "text"}
+	if len(t.apiKey) != 0 {
+		req["api_key"] = t.apiKey
+	}
+	var resp struct {
+		TranslatedText []string `json:"translatedText"`
+	}
+	if err := postJSON(ctx, t.client, t.url+"/translate", nil, req, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.TranslatedText) != len(texts) {
+		return nil, fmt.Errorf("invalid response from %s: %d translations for %d texts", t.Name(), len(resp.TranslatedText), len(texts))
+	}
+	return resp.TranslatedText, nil
+}
+
+// postJSON 发送 JSON 请求并解析 JSON 响应，非 2xx 响应视为失败并附带响应内容
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body, out any) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg := strings.TrimSpace(string(data))
+		if r := []rune(msg); len(r) > 200 {
+			msg = string(r[:200]) + "..."
+		}
+		return fmt.Errorf("post to %s failed, status code: %d, %s", url, resp.StatusCode, msg)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("invalid response from %s: %v", url, err)
+	}
+	return nil
+}
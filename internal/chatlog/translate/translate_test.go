@@ -0,0 +1,158 @@
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+)
+
+func TestNeeds(t *testing.T) {
+	tests := []struct {
+		text   string
+		target string
+		want   bool
+	}{
+		{"你好", "en", true},
+		{"hello", "en", false},
+		{"café", "en", false},
+		{"привет", "en-US", true},
+		{"hello", "zh", true},
+		{"ok 好的", "zh-CN", false},
+		{"hello", "fr", true},
+		{"😀 123", "en", false},
+		{"https://example.com/你好", "en", false},
+	}
+	for _, tt := range tests {
+		if got := Needs(tt.text, tt.target); got != tt.want {
+			t.Errorf("Needs(%q, %q) = %v, want %v", tt.text, tt.target, got, tt.want)
+		}
+	}
+}
+
+// upper 记录每批的文本，译文为原文加上目标语言
+type upper struct {
+	batches [][]string
+}
+
+func (u *upper) Name() string { return "upper" }
+
+func (u *upper) Translate(ctx context.Context, texts []string, target string) ([]string, error) {
+	u.batches = append(u.batches, texts)
+	ret := make([]string, len(texts))
+	for i, text := range texts {
+		ret[i] = target + ":" + text
+	}
+	return ret, nil
+}
+
+func TestTexts(t *testing.T) {
+	u := &upper{}
+	texts := []string{"你好", "hello", " 你好 ", "", "再见"}
+	for i := 0; i < BatchSize; i++ {
+		texts = append(texts, fmt.Sprintf("第%d条", i))
+	}
+	ret, err := Texts(context.Background(), u, texts, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret[0] != "en:你好" || ret[1] != "" || ret[2] != "en:你好" || ret[3] != "" || ret[4] != "en:再见" {
+		t.Errorf("Texts = %q", ret[:5])
+	}
+	if len(u.batches) != 2 || len(u.batches[0]) != BatchSize || len(u.batches[1]) != 2 {
+		t.Errorf("batches = %d", len(u.batches))
+	}
+
+	// 已翻译的文本从缓存读取
+	u.batches = nil
+	if ret, err := Texts(context.Background(), u, []string{"再见"}, "en"); err != nil || ret[0] != "en:再见" || len(u.batches) != 0 {
+		t.Errorf("cached Texts = %q, %v, %d batches", ret, err, len(u.batches))
+	}
+}
+
+func TestLLM(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		var texts []string
+		json.Unmarshal([]byte(req.Messages[1].Content), &texts)
+		for i := range texts {
+			texts[i] = strings.ToUpper(texts[i])
+		}
+		b, _ := json.Marshal(texts)
+		reply, _ := json.Marshal("```json\n" + string(b) + "\n```")
+		if !strings.Contains(req.Messages[0].Content, "English") {
+			reply = []byte(`"wrong language"`)
+		}
+		fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","content":%s}}]}`, reply)
+	}))
+	defer srv.Close()
+
+	tr, err := New(&conf.Translate{Provider: "llm"}, &conf.LLM{Provider: "openai", URL: srv.URL, Model: "m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret, err := tr.Translate(context.Background(), []string{"a", "b]"}, "en")
+	if err != nil || len(ret) != 2 || ret[0] != "A" || ret[1] != "B]" {
+		t.Errorf("Translate = %q, %v", ret, err)
+	}
+
+	if _, err := New(&conf.Translate{Provider: "llm"}, nil); err == nil {
+		t.Error("llm without config should fail")
+	}
+}
+
+func TestDeepL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Text   []string `json:"text"`
+			Target string   `json:"target_lang"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if r.Header.Get("Authorization") != "DeepL-Auth-Key key:fx" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		fmt.Fprintf(w, `{"translations":[{"detected_source_language":"ZH","text":"%s %s"}]}`, req.Target, req.Text[0])
+	}))
+	defer srv.Close()
+
+	tr, err := New(&conf.Translate{Provider: "deepl", URL: srv.URL, APIKey: "key:fx"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret, err := tr.Translate(context.Background(), []string{"你好"}, "en")
+	if err != nil || ret[0] != "EN-US 你好" {
+		t.Errorf("Translate = %q, %v", ret, err)
+	}
+}
+
+func TestLibreTranslate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Q      []string `json:"q"`
+			Target string   `json:"target"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(map[string]any{"translatedText": []string{req.Target + " " + req.Q[0], req.Q[1]}})
+	}))
+	defer srv.Close()
+
+	tr, err := New(&conf.Translate{Provider: "libretranslate", URL: srv.URL + "/"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret, err := tr.Translate(context.Background(), []string{"你好", "再见"}, "EN")
+	if err != nil || ret[0] != "en 你好" || ret[1] != "再见" {
+		t.Errorf("Translate = %q, %v", ret, err)
+	}
+}
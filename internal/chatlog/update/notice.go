@@ -0,0 +1,56 @@
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// CheckInterval 被动检查新版本的最小间隔
+	CheckInterval = 24 * time.Hour
+
+	// EnvNoUpdateCheck 设置后不再被动检查新版本
+	EnvNoUpdateCheck = "CHATLOG_NO_UPDATE_CHECK"
+
+	cacheFile = "update-check.json"
+)
+
+type cache struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Latest    string    `json:"latest"`
+}
+
+// Check 被动检查是否有新版本，有新版本时返回其版本号
+// 结果缓存在 dir 中，CheckInterval 内不会重复请求；开发版本或设置了 CHATLOG_NO_UPDATE_CHECK 时不检查
+func Check(ctx context.Context, dir string, current string) (string, error) {
+	if len(os.Getenv(EnvNoUpdateCheck)) != 0 {
+		return "", nil
+	}
+	if !Valid(current) {
+		return "", nil
+	}
+
+	path := filepath.Join(dir, cacheFile)
+	var c cache
+	if b, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(b, &c)
+	}
+	if time.Since(c.CheckedAt) >= CheckInterval {
+		rel, err := Latest(ctx)
+		if err != nil {
+			return "", err
+		}
+		c = cache{CheckedAt: time.Now(), Latest: rel.Version()}
+		if b, err := json.Marshal(c); err == nil {
+			os.WriteFile(path, b, 0644)
+		}
+	}
+
+	if Newer(current, c.Latest) {
+		return c.Latest, nil
+	}
+	return "", nil
+}
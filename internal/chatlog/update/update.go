@@ -0,0 +1,317 @@
+package update
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Repo = "DanielMao1/chatlog"
+
+	// ChecksumsFile 发布中的校验和文件，与 .goreleaser.yaml 中的 checksum.name_template 一致
+	ChecksumsFile = "checksums.txt"
+
+	// maxDownloadSize 下载文件的大小上限
+	maxDownloadSize = 256 << 20
+)
+
+// APIURL 获取最新发布的接口地址
+var APIURL = "https://api.github.com/repos/" + Repo + "/releases/latest"
+
+// Release GitHub 发布信息
+type Release struct {
+	TagName     string    `json:"tag_name"`
+	HTMLURL     string    `json:"html_url"`
+	PublishedAt time.Time `json:"published_at"`
+	Assets      []Asset   `json:"assets"`
+}
+
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+	Size int64  `json:"size"`
+}
+
+// Version 返回去掉 v 前缀的版本号
+func (r *Release) Version() string {
+	return strings.TrimPrefix(r.TagName, "v")
+}
+
+func (r *Release) asset(name string) *Asset {
+	for i := range r.Assets {
+		if r.Assets[i].Name == name {
+			return &r.Assets[i]
+		}
+	}
+	return nil
+}
+
+// Latest 获取最新的正式发布
+func Latest(ctx context.Context) (*Release, error) {
+	resp, err := get(ctx, APIURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var rel Release
+	if err := json.NewDecoder(resp.Body).Decode(&rel); err != nil {
+		return nil, fmt.Errorf("invalid release info: %v", err)
+	}
+	if len(rel.TagName) == 0 {
+		return nil, fmt.Errorf("no release found")
+	}
+	return &rel, nil
+}
+
+// Newer 判断 latest 是否比 current 新，current 无法解析（如开发版本）时返回 false
+func Newer(current, latest string) bool {
+	c, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	l, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	for i := 0; i < 3; i++ {
+		if c.nums[i] != l.nums[i] {
+			return l.nums[i] > c.nums[i]
+		}
+	}
+	// 同版本号时正式版比预发布版新
+	switch {
+	case c.pre == l.pre:
+		return false
+	case len(l.pre) == 0:
+		return true
+	case len(c.pre) == 0:
+		return false
+	}
+	return l.pre > c.pre
+}
+
+// Valid 判断版本号能否比较，开发版本 (dev) 等返回 false
+func Valid(v string) bool {
+	_, ok := parseVersion(v)
+	return ok
+}
+
+type semVersion struct {
+	nums [3]int
+	pre  string
+}
+
+// parseVersion 解析 1.2.3、v1.2.3-rc1 形式的版本号
+func parseVersion(v string) (semVersion, bool) {
+	var ret semVersion
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	v, _, _ = strings.Cut(v, "+")
+	v, ret.pre, _ = strings.Cut(v, "-")
+	parts := strings.Split(v, ".")
+	if len(parts) == 0 || len(parts) > 3 {
+		return ret, false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return ret, false
+		}
+		ret.nums[i] = n
+	}
+	return ret, true
+}
+
+// AssetName 返回当前平台的发布包名称，与 .goreleaser.yaml 中的 archives.name_template 一致
+func AssetName(version, goos, goarch string) string {
+	ext := ".tar.gz"
+	if goos == "windows" {
+		ext = ".zip"
+	}
+	return fmt.Sprintf("chatlog_%s_%s_%s%s", version, goos, goarch, ext)
+}
+
+// Download 下载当前平台的发布包，校验 sha256 后返回其中的可执行文件
+// 发布只提供 checksums.txt，没有签名，校验只能发现下载损坏或被篡改的发布包
+func Download(ctx context.Context, rel *Release) ([]byte, error) {
+	name := AssetName(rel.Version(), runtime.GOOS, runtime.GOARCH)
+	asset := rel.asset(name)
+	if asset == nil {
+		return nil, fmt.Errorf("release %s has no package for %s/%s", rel.TagName, runtime.GOOS, runtime.GOARCH)
+	}
+	checksums := rel.asset(ChecksumsFile)
+	if checksums == nil {
+		return nil, fmt.Errorf("release %s has no %s", rel.TagName, ChecksumsFile)
+	}
+
+	sums, err := download(ctx, checksums.URL)
+	if err != nil {
+		return nil, err
+	}
+	want, err := findChecksum(sums, name)
+	if err != nil {
+		return nil, err
+	}
+
+	archive, err := download(ctx, asset.URL)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(archive)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return nil, fmt.Errorf("checksum mismatch for %s: got %s, want %s", name, got, want)
+	}
+
+	return extract(name, archive)
+}
+
+// findChecksum 从 checksums.txt（"<sha256>  <文件名>" 每行一个）中查找文件的校验和
+func findChecksum(sums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("no checksum for %s in %s", name, ChecksumsFile)
+}
+
+// extract 从发布包中取出 chatlog 可执行文件
+func extract(name string, archive []byte) ([]byte, error) {
+	binary := "chatlog"
+	if strings.HasSuffix(name, ".zip") {
+		binary = "chatlog.exe"
+		zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			if path.Base(f.Name) != binary {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			return io.ReadAll(io.LimitReader(rc, maxDownloadSize))
+		}
+		return nil, fmt.Errorf("%s not found in %s", binary, name)
+	}
+
+	gr, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag == tar.TypeReg && path.Base(hdr.Name) == binary {
+			return io.ReadAll(io.LimitReader(tr, maxDownloadSize))
+		}
+	}
+	return nil, fmt.Errorf("%s not found in %s", binary, name)
+}
+
+// Replace 用新的可执行文件替换 exe
+// 运行中的程序在 Windows 上无法覆盖，先将旧文件重命名为 .old，下次更新时再删除
+func Replace(exe string, binary []byte) error {
+	info, err := os.Stat(exe)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".chatlog-update-*")
+	if err != nil {
+		return fmt.Errorf("can not write to %s: %v", filepath.Dir(exe), err)
+	}
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	tmp.Close()
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()|0111); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	old := exe + ".old"
+	os.Remove(old)
+	if err := os.Rename(exe, old); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), exe); err != nil {
+		os.Rename(old, exe)
+		os.Remove(tmp.Name())
+		return err
+	}
+	os.Remove(old)
+	return nil
+}
+
+func download(ctx context.Context, url string) ([]byte, error) {
+	resp, err := get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxDownloadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > maxDownloadSize {
+		return nil, fmt.Errorf("%s is too large", url)
+	}
+	return b, nil
+}
+
+func get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "chatlog-updater")
+	if strings.HasPrefix(url, "https://api.github.com/") {
+		req.Header.Set("Accept", "application/vnd.github+json")
+		// 设置 GITHUB_TOKEN 可以避免匿名请求的频率限制
+		if token := os.Getenv("GITHUB_TOKEN"); len(token) != 0 {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s failed, status code: %d", url, resp.StatusCode)
+	}
+	return resp, nil
+}
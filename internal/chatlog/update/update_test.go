@@ -0,0 +1,86 @@
+package update
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+func TestNewer(t *testing.T) {
+	tests := []struct {
+		current, latest string
+		want            bool
+	}{
+		{"0.1.0", "0.1.1", true},
+		{"v0.2.0", "0.10.0", true},
+		{"1.0.0", "1.0.0", false},
+		{"1.1.0", "1.0.9", false},
+		{"1.0.0-rc1", "1.0.0", true},
+		{"1.0.0", "1.0.0-rc1", false},
+		{"(dev)", "1.0.0", false},
+	}
+	for _, tt := range tests {
+		if got := Newer(tt.current, tt.latest); got != tt.want {
+			t.Errorf("Newer(%q, %q) = %v, want %v", tt.current, tt.latest, got, tt.want)
+		}
+	}
+}
+
+func TestDownload(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("tar.gz package only")
+	}
+	binary := []byte("#!/bin/sh\necho chatlog\n")
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	tw.WriteHeader(&tar.Header{Name: "chatlog", Mode: 0755, Size: int64(len(binary)), Typeflag: tar.TypeReg})
+	tw.Write(binary)
+	tw.Close()
+	gw.Close()
+	archive := buf.Bytes()
+
+	name := AssetName("1.2.3", runtime.GOOS, runtime.GOARCH)
+	sum := sha256.Sum256(archive)
+	checksums := fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), name)
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	mux.HandleFunc("/latest", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(Release{TagName: "v1.2.3", Assets: []Asset{
+			{Name: name, URL: srv.URL + "/archive"},
+			{Name: ChecksumsFile, URL: srv.URL + "/checksums"},
+		}})
+	})
+	mux.HandleFunc("/archive", func(w http.ResponseWriter, r *http.Request) { w.Write(archive) })
+	mux.HandleFunc("/checksums", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(checksums)) })
+	APIURL = srv.URL + "/latest"
+
+	rel, err := Latest(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Download(context.Background(), rel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, binary) {
+		t.Fatalf("unexpected binary: %q", got)
+	}
+
+	archive = append([]byte{}, archive...)
+	archive[len(archive)-1] ^= 0xff
+	if _, err := Download(context.Background(), rel); err == nil {
+		t.Fatal("expected checksum mismatch")
+	}
+}
@@ -0,0 +1,151 @@
+package chatlog
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+	"github.com/DanielMao1/chatlog/internal/chatlog/vault"
+	"github.com/DanielMao1/chatlog/internal/i18n"
+	"github.com/DanielMao1/chatlog/internal/model"
+)
+
+// SyncVault 将聊天对象的新消息追加到 Obsidian、Logseq 等笔记库中，每个聊天对象每天一篇笔记
+// media 为 true 时图片与文件复制到笔记旁，否则只写入文字；首次同步时写入全部历史消息
+func (m *Manager) SyncVault(dir string, talkers []string, media bool) (*vault.Result, error) {
+	if m.db.GetDB() == nil {
+		if err := m.db.Start(); err != nil {
+			return nil, i18n.Errorf("数据库未启动: %v", err)
+		}
+	}
+	v, err := vault.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	total := &vault.Result{}
+	var errs []error
+	for _, talker := range talkers {
+		start := v.Since(talker)
+		if start.IsZero() {
+			start = time.Unix(0, 0)
+		}
+		messages, err := m.db.GetMessages(m.runCtx, start, time.Now(), talker, "", "", 0, 0)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", talker, err))
+			continue
+		}
+		entries := make([]*vault.Entry, 0, len(messages))
+		for _, msg := range messages {
+			entries = append(entries, m.vaultEntry(msg, media))
+		}
+		result, err := v.Append(talker, entries)
+		total.Files += result.Files
+		total.Messages += result.Messages
+		total.Media += result.Media
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", talker, err))
+		}
+	}
+	if err := v.Save(); err != nil {
+		errs = append(errs, err)
+	}
+	return total, errors.Join(errs...)
+}
+
+// vaultEntry 将消息转换为笔记中的一条记录，图片解密后复制，文件直接复制，找不到媒体文件或 media 为 false 时只写入文字
+func (m *Manager) vaultEntry(msg *model.Message, media bool) *vault.Entry {
+	name := msg.TalkerName
+	if len(name) == 0 {
+		name = msg.Talker
+	}
+	sender := msg.SenderName
+	if len(sender) == 0 {
+		sender = msg.Sender
+	}
+	if msg.IsSelf {
+		sender = i18n.T("我")
+	}
+	e := &vault.Entry{
+		Talker: msg.Talker,
+		Name:   name,
+		Seq:    msg.Seq,
+		Time:   msg.Time,
+		Sender: sender,
+		Text:   msg.PlainTextContent(),
+	}
+	if !media {
+		return e
+	}
+
+	switch {
+	case msg.Type == model.MessageTypeImage:
+		data, err := m.BrowseImage(msg)
+		if err != nil {
+			log.Debug().Err(err).Int64("seq", msg.Seq).Msg("image for vault not found")
+			break
+		}
+		base := fmt.Sprintf("%d", msg.Seq)
+		if md5, ok := msg.Contents["md5"].(string); ok && len(md5) != 0 {
+			base = md5
+		}
+		e.Media = &vault.Media{Name: base + imageExt(data), Image: true, Data: data}
+	case msg.Type == model.MessageTypeShare && msg.SubType == model.MessageSubTypeFile:
+		path, err := m.MediaPath(msg)
+		if err != nil {
+			log.Debug().Err(err).Int64("seq", msg.Seq).Msg("file for vault not found")
+			break
+		}
+		name, _ := msg.Contents["title"].(string)
+		if len(name) == 0 {
+			name = filepath.Base(path)
+		}
+		e.Media = &vault.Media{Name: name, Path: path}
+	}
+	return e
+}
+
+// imageExt 按图片内容返回扩展名
+func imageExt(data []byte) string {
+	switch http.DetectContentType(data) {
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	}
+	return ".jpg"
+}
+
+// runVaultJob 将聊天对象的新消息同步到笔记库，未配置目录时同步到工作目录旁的 vault 目录
+// 开启 git 时同步后提交到 git 仓库
+func (m *Manager) runVaultJob(j *conf.VaultJob) error {
+	dir := j.Dir
+	if len(dir) == 0 {
+		if len(m.ctx.WorkDir) == 0 {
+			return fmt.Errorf("work dir is not configured")
+		}
+		dir = filepath.Join(filepath.Dir(m.ctx.WorkDir), "vault")
+	}
+	result, err := m.SyncVault(dir, j.Talkers, !j.Git)
+	if result != nil {
+		log.Info().Str("dir", dir).Int("files", result.Files).Int("messages", result.Messages).Int("media", result.Media).Msg("vault sync finished")
+	}
+	if err != nil || !j.Git {
+		return err
+	}
+	commit, err := vault.Commit(dir, VaultCommitMessage(result, time.Now()))
+	if err != nil {
+		return err
+	}
+	if len(commit) != 0 {
+		log.Info().Str("dir", dir).Str("commit", commit).Msg("vault committed")
+	}
+	return nil
+}
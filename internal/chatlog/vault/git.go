@@ -0,0 +1,87 @@
+package vault
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// GitBinary 提交笔记库使用的 git 命令
+var GitBinary = "git"
+
+// 仓库没有配置提交者时使用的名称与邮箱
+const (
+	GitUserName  = "chatlog"
+	GitUserEmail = "chatlog@localhost"
+)
+
+// Commit 将笔记库目录中的改动提交到 git 仓库，目录不是 git 仓库时先初始化
+// 没有改动时不提交，返回空字符串；否则返回新提交的 ID
+// 每次提交都包含上一次提交的哈希，历史记录被修改时可以通过 git log、git fsck 发现
+func Commit(dir, message string) (string, error) {
+	if _, err := exec.LookPath(GitBinary); err != nil {
+		return "", fmt.Errorf("git is not installed: %w", err)
+	}
+	// 笔记库在其他仓库中时单独初始化，不提交到外层的仓库
+	if top, err := git(dir, nil, "rev-parse", "--show-toplevel"); err != nil || !samePath(top, dir) {
+		if _, err := git(dir, nil, "init", "-q"); err != nil {
+			return "", err
+		}
+	}
+	if _, err := git(dir, nil, "add", "-A"); err != nil {
+		return "", err
+	}
+	status, err := git(dir, nil, "status", "--porcelain")
+	if err != nil {
+		return "", err
+	}
+	if len(status) == 0 {
+		return "", nil
+	}
+
+	// 使用用户自己的提交者与签名配置，没有配置时使用默认的提交者，避免提交失败
+	var env []string
+	if name, _ := git(dir, nil, "config", "user.name"); len(name) == 0 {
+		env = append(env, "GIT_AUTHOR_NAME="+GitUserName, "GIT_COMMITTER_NAME="+GitUserName)
+	}
+	if email, _ := git(dir, nil, "config", "user.email"); len(email) == 0 {
+		env = append(env, "GIT_AUTHOR_EMAIL="+GitUserEmail, "GIT_COMMITTER_EMAIL="+GitUserEmail)
+	}
+	if _, err := git(dir, env, "commit", "-q", "-m", message); err != nil {
+		return "", err
+	}
+	return git(dir, nil, "rev-parse", "HEAD")
+}
+
+// git 在目录中执行 git 命令，env 为追加的环境变量，返回去掉首尾空白的输出
+func git(dir string, env []string, args ...string) (string, error) {
+	cmd := exec.Command(GitBinary, args...)
+	cmd.Dir = dir
+	if len(env) != 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); len(msg) != 0 {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// samePath 判断两个路径是否指向同一个目录
+func samePath(a, b string) bool {
+	ia, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	ib, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(ia, ib)
+}
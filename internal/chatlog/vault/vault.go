@@ -0,0 +1,269 @@
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// StateFile 笔记库中记录每个聊天对象同步进度的文件
+	StateFile = ".chatlog-vault.json"
+
+	// AssetsDir 每个聊天对象目录中存放图片与文件的子目录
+	AssetsDir = "assets"
+)
+
+// Media 消息附带的图片或文件，Data 与 Path 二选一
+type Media struct {
+	Name  string // 复制到 assets 目录中的文件名
+	Image bool   // 图片以内嵌方式显示
+	Data  []byte
+	Path  string
+}
+
+// Entry 写入笔记的一条消息
+type Entry struct {
+	Talker string
+	Name   string // 聊天对象名称
+	Seq    int64
+	Time   time.Time
+	Sender string
+	Text   string
+	Media  *Media
+}
+
+// Result 一次同步的结果
+type Result struct {
+	Files    int `json:"files"`    // 新建或追加的笔记数量
+	Messages int `json:"messages"` // 新写入的消息数量
+	Media    int `json:"media"`    // 复制的图片与文件数量
+}
+
+// cursor 聊天对象的同步进度
+type cursor struct {
+	Folder string    `json:"folder"` // 聊天对象在笔记库中的目录，首次同步时按名称确定，之后不随改名变化
+	Seq    int64     `json:"seq"`
+	Time   time.Time `json:"time"`
+}
+
+// Vault Obsidian、Logseq 等笔记软件的笔记库，每个聊天对象每天一篇 Markdown 笔记
+type Vault struct {
+	dir   string
+	state map[string]*cursor
+}
+
+// Open 打开笔记库目录并读取同步进度，目录不存在时创建
+func Open(dir string) (*Vault, error) {
+	if len(dir) == 0 {
+		return nil, fmt.Errorf("vault dir is required")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	v := &Vault{dir: dir, state: make(map[string]*cursor)}
+	data, err := os.ReadFile(filepath.Join(dir, StateFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if len(data) != 0 {
+		if err := json.Unmarshal(data, &v.state); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", StateFile, err)
+		}
+	}
+	return v, nil
+}
+
+// Since 返回聊天对象已同步的最后一条消息的时间，未同步过时为零值
+func (v *Vault) Since(key string) time.Time {
+	if c, ok := v.state[key]; ok {
+		return c.Time
+	}
+	return time.Time{}
+}
+
+// Append 将聊天对象的新消息按天追加到笔记中，已同步过的消息跳过，entries 需按时间排列
+// key 为配置中的聊天对象，用于记录同步进度
+func (v *Vault) Append(key string, entries []*Entry) (*Result, error) {
+	result := &Result{}
+	c := v.state[key]
+	if c == nil {
+		if len(entries) == 0 {
+			return result, nil
+		}
+		name := entries[0].Name
+		if len(name) == 0 {
+			name = entries[0].Talker
+		}
+		c = &cursor{Folder: safeName(name)}
+	}
+
+	var f *os.File
+	day := ""
+	closeFile := func() error {
+		if f == nil {
+			return nil
+		}
+		err := f.Close()
+		f = nil
+		return err
+	}
+	defer closeFile()
+
+	folder := filepath.Join(v.dir, c.Folder)
+	for _, e := range entries {
+		if e.Seq <= c.Seq && !e.Time.After(c.Time) {
+			continue
+		}
+		if d := e.Time.Format("2006-01-02"); d != day || f == nil {
+			if err := closeFile(); err != nil {
+				return result, err
+			}
+			var err error
+			if f, err = openNote(folder, d, e); err != nil {
+				return result, err
+			}
+			day = d
+			result.Files++
+		}
+
+		line := formatEntry(e)
+		if e.Media != nil {
+			link, err := copyMedia(folder, e.Media)
+			if err != nil {
+				line += "\n  (" + e.Media.Name + ": " + err.Error() + ")"
+			} else {
+				line += "\n  " + link
+				result.Media++
+			}
+		}
+		if _, err := f.WriteString(line + "\n"); err != nil {
+			return result, err
+		}
+		c.Seq, c.Time = e.Seq, e.Time
+		result.Messages++
+	}
+	if err := closeFile(); err != nil {
+		return result, err
+	}
+	v.state[key] = c
+	return result, nil
+}
+
+// Save 写入同步进度
+func (v *Vault) Save() error {
+	data, err := json.MarshalIndent(v.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(v.dir, StateFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(v.dir, StateFile))
+}
+
+// openNote 打开当天的笔记用于追加，不存在时创建并写入 YAML frontmatter
+func openNote(folder, day string, e *Entry) (*os.File, error) {
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(folder, day+".md")
+	_, statErr := os.Stat(path)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if errors.Is(statErr, os.ErrNotExist) {
+		name := e.Name
+		if len(name) == 0 {
+			name = e.Talker
+		}
+		header := fmt.Sprintf("---\ntalker: %s\nname: %s\ndate: %s\ntags: [chatlog]\n---\n\n# %s %s\n\n",
+			yamlString(e.Talker), yamlString(name), day, name, day)
+		if _, err := f.WriteString(header); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// formatEntry 将消息写为列表项，多行消息的后续行缩进到列表项中
+func formatEntry(e *Entry) string {
+	text := strings.ReplaceAll(strings.TrimRight(e.Text, "\n"), "\n", "\n  ")
+	return fmt.Sprintf("- %s **%s**: %s", e.Time.Format("15:04"), e.Sender, text)
+}
+
+// copyMedia 将图片或文件复制到 assets 目录，返回笔记中的链接，同名文件已存在时不重复复制
+func copyMedia(folder string, m *Media) (string, error) {
+	name := safeName(m.Name)
+	dir := filepath.Join(folder, AssetsDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, name)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if err := writeMedia(path, m); err != nil {
+			return "", err
+		}
+	}
+
+	link := AssetsDir + "/" + url.PathEscape(name)
+	if m.Image {
+		return "![" + name + "](" + link + ")", nil
+	}
+	return "[" + name + "](" + link + ")", nil
+}
+
+// writeMedia 先写入临时文件再重命名，避免中断时留下不完整的文件
+func writeMedia(path string, m *Media) error {
+	tmp := path + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if len(m.Path) != 0 {
+		var in *os.File
+		if in, err = os.Open(m.Path); err == nil {
+			_, err = io.Copy(out, in)
+			in.Close()
+		}
+	} else {
+		_, err = out.Write(m.Data)
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// safeName 替换文件名中不允许的字符
+func safeName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|#^[]`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if len(name) == 0 || name == "." || name == ".." {
+		return "_"
+	}
+	return name
+}
+
+// yamlString 按 YAML 双引号字符串转义
+func yamlString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
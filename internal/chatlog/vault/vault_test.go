@@ -0,0 +1,110 @@
+package vault
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppend(t *testing.T) {
+	dir := t.TempDir()
+	at := func(day, hour int) time.Time { return time.Date(2024, 3, day, hour, 0, 0, 0, time.Local) }
+	entries := []*Entry{
+		{Talker: "123@chatroom", Name: "项目/群", Seq: 1, Time: at(1, 9), Sender: "小王", Text: "早\n今天开会"},
+		{Talker: "123@chatroom", Name: "项目/群", Seq: 2, Time: at(1, 10), Sender: "小李", Text: "[图片]",
+			Media: &Media{Name: "a b.jpg", Image: true, Data: []byte("jpg")}},
+		{Talker: "123@chatroom", Name: "项目/群", Seq: 3, Time: at(2, 9), Sender: "我", Text: "收到"},
+	}
+
+	v, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := v.Append("123@chatroom", entries[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Files != 1 || result.Messages != 2 || result.Media != 1 {
+		t.Errorf("unexpected first result %+v", result)
+	}
+	if err := v.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重新打开后只追加新消息
+	v, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if since := v.Since("123@chatroom"); !since.Equal(at(1, 10)) {
+		t.Errorf("Since() = %v", since)
+	}
+	result, err = v.Append("123@chatroom", entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Files != 1 || result.Messages != 1 {
+		t.Errorf("unexpected second result %+v", result)
+	}
+
+	note, err := os.ReadFile(filepath.Join(dir, "项目_群", "2024-03-01.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "---\ntalker: \"123@chatroom\"\nname: \"项目/群\"\ndate: 2024-03-01\ntags: [chatlog]\n---\n\n# 项目/群 2024-03-01\n\n" +
+		"- 09:00 **小王**: 早\n  今天开会\n" +
+		"- 10:00 **小李**: [图片]\n  ![a b.jpg](assets/a%20b.jpg)\n"
+	if string(note) != want {
+		t.Errorf("note = %q\nwant %q", note, want)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "项目_群", AssetsDir, "a b.jpg")); err != nil || string(data) != "jpg" {
+		t.Errorf("media not copied: %q, %v", data, err)
+	}
+	if note, _ := os.ReadFile(filepath.Join(dir, "项目_群", "2024-03-02.md")); !strings.HasSuffix(string(note), "- 09:00 **我**: 收到\n") {
+		t.Errorf("unexpected second note %q", note)
+	}
+}
+
+func TestCommit(t *testing.T) {
+	if _, err := exec.LookPath(GitBinary); err != nil {
+		t.Skip("git is not installed")
+	}
+	// 不使用测试环境中的全局配置，验证默认的提交者
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "2024-03-01.md"), []byte("- 09:00 **小王**: 早\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	first, err := Commit(dir, "chatlog 2024-03-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) == 0 {
+		t.Fatal("no commit created")
+	}
+
+	// 没有改动时不提交
+	if id, err := Commit(dir, "chatlog 2024-03-02"); err != nil || len(id) != 0 {
+		t.Errorf("got %q, %v, want no commit", id, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "2024-03-02.md"), []byte("- 09:00 **我**: 收到\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	second, err := Commit(dir, "chatlog 2024-03-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent, err := git(dir, nil, "rev-parse", "HEAD^")
+	if err != nil || parent != first || second == first {
+		t.Errorf("got parent %q (%v), want %q", parent, err, first)
+	}
+	if author, _ := git(dir, nil, "log", "-1", "--format=%an <%ae>"); author != GitUserName+" <"+GitUserEmail+">" {
+		t.Errorf("got author %q", author)
+	}
+}
@@ -0,0 +1,102 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog/log"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+	"github.com/DanielMao1/chatlog/internal/chatlog/mqtt"
+	"github.com/DanielMao1/chatlog/internal/wechatdb"
+)
+
+// MQTTWebhook 将新消息发布到 MQTT，每个聊天对象一个主题，负载为消息的 JSON
+// 第一次有新消息时才连接服务器，连接断开后在下次发布时重新连接
+type MQTTWebhook struct {
+	ctx  context.Context
+	conf *conf.MQTT
+	feed *wechatdb.Feed
+
+	// mu 保证新消息按顺序发布
+	mu     sync.Mutex
+	client *mqtt.Client
+}
+
+func NewMQTTWebhook(ctx context.Context, conf *conf.MQTT, db *wechatdb.DB) *MQTTWebhook {
+	talker := ""
+	if len(conf.Talkers) == 1 {
+		talker = conf.Talkers[0]
+	}
+	m := &MQTTWebhook{
+		ctx:  ctx,
+		conf: conf,
+		feed: db.NewFeed(talker, time.Now()),
+	}
+	go func() {
+		<-ctx.Done()
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.client != nil {
+			m.client.Close()
+			m.client = nil
+		}
+	}()
+	return m
+}
+
+func (m *MQTTWebhook) Do(event fsnotify.Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.ctx.Err() != nil {
+		return
+	}
+
+	messages, err := m.feed.Next(m.ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("get messages for mqtt failed")
+		return
+	}
+
+	published := 0
+	for _, message := range messages {
+		if len(m.conf.Talkers) > 1 && !slices.Contains(m.conf.Talkers, message.Talker) {
+			continue
+		}
+		message.Content = message.PlainTextContent()
+		payload, err := json.Marshal(message)
+		if err != nil {
+			continue
+		}
+		if err := m.publish(mqtt.MessageTopic(m.conf, message.Talker), payload); err != nil {
+			log.Error().Err(err).Str("broker", m.conf.Broker).Msg("publish message to mqtt failed")
+			return
+		}
+		published++
+	}
+	if published > 0 {
+		log.Debug().Msgf("published %d messages to mqtt", published)
+	}
+}
+
+// publish 发布一条消息，未连接或连接已断开时先连接，失败时重试一次
+func (m *MQTTWebhook) publish(topic string, payload []byte) error {
+	var err error
+	for i := 0; i < 2; i++ {
+		if m.client == nil || m.client.Err() != nil {
+			if m.client, err = mqtt.Dial(m.ctx, m.conf, nil); err != nil {
+				return err
+			}
+		}
+		if err = m.client.Publish(m.ctx, topic, payload, false); err == nil {
+			return nil
+		}
+		m.client.Close()
+		m.client = nil
+	}
+	return err
+}
@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog/log"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+	"github.com/DanielMao1/chatlog/internal/chatlog/stream"
+	"github.com/DanielMao1/chatlog/internal/wechatdb"
+)
+
+// StreamWebhook 将新消息发布到 Kafka 或 NATS，负载为消息的 JSON，以聊天对象为键
+type StreamWebhook struct {
+	ctx       context.Context
+	conf      *conf.Stream
+	publisher *stream.Publisher
+	feed      *wechatdb.Feed
+
+	// mu 保证新消息按顺序发布
+	mu sync.Mutex
+}
+
+func NewStreamWebhook(ctx context.Context, conf *conf.Stream, publisher *stream.Publisher, db *wechatdb.DB) *StreamWebhook {
+	talker := ""
+	if len(conf.Talkers) == 1 {
+		talker = conf.Talkers[0]
+	}
+	s := &StreamWebhook{
+		ctx:       ctx,
+		conf:      conf,
+		publisher: publisher,
+		feed:      db.NewFeed(talker, time.Now()),
+	}
+	go func() {
+		<-ctx.Done()
+		publisher.Close()
+	}()
+	return s
+}
+
+func (s *StreamWebhook) Do(event fsnotify.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx.Err() != nil {
+		return
+	}
+
+	messages, err := s.feed.Next(s.ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("get messages for stream failed")
+		return
+	}
+
+	// 按主题分批发布，NATS 每个聊天对象一个主题
+	subjects := make([]string, 0)
+	batches := make(map[string][]stream.Record)
+	for _, message := range messages {
+		if len(s.conf.Talkers) > 1 && !slices.Contains(s.conf.Talkers, message.Talker) {
+			continue
+		}
+		message.Content = message.PlainTextContent()
+		payload, err := json.Marshal(message)
+		if err != nil {
+			continue
+		}
+		subject := stream.MessageSubject(s.conf, message.Talker)
+		if _, ok := batches[subject]; !ok {
+			subjects = append(subjects, subject)
+		}
+		batches[subject] = append(batches[subject], stream.Record{Key: []byte(message.Talker), Value: payload})
+	}
+
+	published := 0
+	for _, subject := range subjects {
+		if err := s.publisher.Publish(s.ctx, subject, batches[subject]...); err != nil {
+			log.Error().Err(err).Str("type", s.conf.Type).Msg("publish messages to stream failed")
+			return
+		}
+		published += len(batches[subject])
+	}
+	if published > 0 {
+		log.Debug().Msgf("published %d messages to %s", published, s.conf.Type)
+	}
+}
@@ -1,21 +1,25 @@
 package webhook
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"net/http"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/rs/zerolog/log"
 
 	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+	"github.com/DanielMao1/chatlog/internal/chatlog/push"
+	"github.com/DanielMao1/chatlog/internal/chatlog/script"
+	"github.com/DanielMao1/chatlog/internal/chatlog/stream"
+	"github.com/DanielMao1/chatlog/internal/model"
 	"github.com/DanielMao1/chatlog/internal/wechatdb"
 )
 
 type Config interface {
 	GetWebhook() *conf.Webhook
+	GetDestinations() map[string]*conf.Destination
+	GetMQTT() *conf.MQTT
+	GetStream() *conf.Stream
 }
 
 type Webhook interface {
@@ -24,12 +28,26 @@ type Webhook interface {
 
 type Service struct {
 	config *conf.Webhook
+	dests  map[string]*conf.Destination
 	hooks  map[string][]*conf.WebhookItem
+	mqtt   *conf.MQTT   // 配置了 mqtt.messages 时发布新消息
+	stream *conf.Stream // 配置了 stream.messages 时发布新消息
 }
 
 func New(config Config) *Service {
 	s := &Service{
 		config: config.GetWebhook(),
+		dests:  config.GetDestinations(),
+	}
+	if m := config.GetMQTT(); m != nil && m.Messages && len(m.Broker) != 0 {
+		s.mqtt = m
+	}
+	if c := config.GetStream(); c != nil && c.Messages {
+		if _, err := stream.New(c); err != nil {
+			log.Error().Err(err).Msg("skip stream")
+		} else {
+			s.stream = c
+		}
 	}
 
 	if s.config == nil {
@@ -44,6 +62,12 @@ func New(config Config) *Service {
 		if item.Type == "" {
 			item.Type = "message"
 		}
+		if len(item.Destination) != 0 {
+			if _, err := push.Resolve(item.Destination, s.dests); err != nil {
+				log.Error().Err(err).Msgf("skip webhook")
+				continue
+			}
+		}
 		switch item.Type {
 		case "message":
 			if hooks["message"] == nil {
@@ -61,22 +85,54 @@ func New(config Config) *Service {
 
 func (s *Service) GetHooks(ctx context.Context, db *wechatdb.DB) []*Group {
 
-	if len(s.hooks) == 0 {
+	if len(s.hooks) == 0 && s.mqtt == nil && s.stream == nil {
 		return nil
 	}
 
+	var delayMs int64
+	if s.config != nil {
+		delayMs = s.config.DelayMs
+	}
+
 	groups := make([]*Group, 0)
 	for group, items := range s.hooks {
 		hooks := make([]Webhook, 0)
 		for _, item := range items {
-			hooks = append(hooks, NewMessageWebhook(item, db, s.config.Host))
+			hooks = append(hooks, NewMessageWebhook(ctx, item, s.destination(item), s.dests, db, s.config.Host))
 		}
-		groups = append(groups, NewGroup(ctx, group, hooks, s.config.DelayMs))
+		if group == "message" {
+			hooks = append(hooks, s.publishers(ctx, db)...)
+		}
+		groups = append(groups, NewGroup(ctx, group, hooks, delayMs))
+	}
+	if _, ok := s.hooks["message"]; !ok && (s.mqtt != nil || s.stream != nil) {
+		groups = append(groups, NewGroup(ctx, "message", s.publishers(ctx, db), delayMs))
 	}
 
 	return groups
 }
 
+// publishers 返回将新消息发布到 MQTT、Kafka 或 NATS 的 webhook
+func (s *Service) publishers(ctx context.Context, db *wechatdb.DB) []Webhook {
+	var hooks []Webhook
+	if s.mqtt != nil {
+		hooks = append(hooks, NewMQTTWebhook(ctx, s.mqtt, db))
+	}
+	if s.stream != nil {
+		publisher, _ := stream.New(s.stream)
+		hooks = append(hooks, NewStreamWebhook(ctx, s.stream, publisher, db))
+	}
+	return hooks
+}
+
+// destination 返回 webhook 的推送目标，未引用 destinations 时使用 url
+func (s *Service) destination(item *conf.WebhookItem) *conf.Destination {
+	if len(item.Destination) != 0 {
+		return s.dests[item.Destination]
+	}
+	return &conf.Destination{URL: item.URL}
+}
+
 type Group struct {
 	ctx     context.Context
 	group   string
@@ -138,26 +194,36 @@ func (g *Group) do(event fsnotify.Event) {
 }
 
 type MessageWebhook struct {
+	ctx      context.Context
 	host     string
 	conf     *conf.WebhookItem
-	client   *http.Client
+	dest     *conf.Destination
+	dests    map[string]*conf.Destination // 脚本通过 route 引用的推送目标
+	script   *script.Script
 	db       *wechatdb.DB
 	lastTime time.Time
 }
 
-func NewMessageWebhook(conf *conf.WebhookItem, db *wechatdb.DB, host string) *MessageWebhook {
+func NewMessageWebhook(ctx context.Context, conf *conf.WebhookItem, dest *conf.Destination, dests map[string]*conf.Destination, db *wechatdb.DB, host string) *MessageWebhook {
 	m := &MessageWebhook{
+		ctx:      ctx,
 		host:     host,
 		conf:     conf,
-		client:   &http.Client{Timeout: time.Second * 10},
+		dest:     dest,
+		dests:    dests,
 		db:       db,
 		lastTime: time.Now(),
 	}
+	sc, err := script.Compile(dest.Script)
+	if err != nil {
+		log.Error().Err(err).Msgf("webhook script disabled")
+	}
+	m.script = sc
 	return m
 }
 
 func (m *MessageWebhook) Do(event fsnotify.Event) {
-	messages, err := m.db.GetMessages(m.lastTime, time.Now().Add(time.Minute*10), m.conf.Talker, m.conf.Sender, m.conf.Keyword, 0, 0)
+	messages, err := m.db.GetMessages(m.ctx, m.lastTime, time.Now().Add(time.Minute*10), m.conf.Talker, m.conf.Sender, m.conf.Keyword, 0, 0)
 	if err != nil {
 		log.Error().Err(err).Msgf("get messages failed")
 		return
@@ -174,26 +240,52 @@ func (m *MessageWebhook) Do(event fsnotify.Event) {
 		message.Content = message.PlainTextContent()
 	}
 
-	ret := map[string]any{
-		"talker":   m.conf.Talker,
-		"sender":   m.conf.Sender,
-		"keyword":  m.conf.Keyword,
-		"lastTime": m.lastTime.Format(time.DateTime),
-		"length":   len(messages),
-		"messages": messages,
-	}
-	body, _ := json.Marshal(ret)
-	req, _ := http.NewRequest("POST", m.conf.URL, bytes.NewBuffer(body))
-	req.Header.Set("Content-Type", "application/json")
+	for dest, messages := range m.route(messages) {
+		ret := map[string]any{
+			"talker":   m.conf.Talker,
+			"sender":   m.conf.Sender,
+			"keyword":  m.conf.Keyword,
+			"lastTime": m.lastTime.Format(time.DateTime),
+			"length":   len(messages),
+			"messages": messages,
+		}
 
-	log.Info().Msgf("post messages to %s, body: %s", m.conf.URL, string(body))
-	resp, err := m.client.Do(req)
-	if err != nil {
-		log.Error().Err(err).Msgf("post messages failed")
+		log.Info().Msgf("post %d messages to %s", len(messages), dest.URL)
+		if err := push.Send(dest, ret); err != nil {
+			log.Error().Err(err).Msgf("post messages failed")
+		}
 	}
-	defer resp.Body.Close()
+}
 
-	if resp.StatusCode != http.StatusOK {
-		log.Error().Msgf("post messages failed, status code: %d", resp.StatusCode)
+// route 对每条消息运行推送目标的脚本，按脚本选择的推送目标分组，丢弃的消息不推送
+// 脚本出错时按原样推送到默认的目标
+func (m *MessageWebhook) route(messages []*model.Message) map[*conf.Destination][]*model.Message {
+	if m.script == nil {
+		return map[*conf.Destination][]*model.Message{m.dest: messages}
+	}
+	ret := make(map[*conf.Destination][]*model.Message)
+	for _, message := range messages {
+		r, err := m.script.Run(message)
+		if err != nil {
+			log.Warn().Err(err).Str("talker", message.Talker).Int64("seq", message.Seq).Msg("webhook script failed")
+			ret[m.dest] = append(ret[m.dest], message)
+			continue
+		}
+		if r.Drop {
+			continue
+		}
+		if len(r.Routes) == 0 {
+			ret[m.dest] = append(ret[m.dest], message)
+			continue
+		}
+		for _, name := range r.Routes {
+			dest, err := push.Resolve(name, m.dests)
+			if err != nil {
+				log.Warn().Err(err).Msg("webhook script route skipped")
+				continue
+			}
+			ret[dest] = append(ret[dest], message)
+		}
 	}
+	return ret
 }
@@ -0,0 +1,172 @@
+package wechat
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/push"
+)
+
+// LagCheckInterval 检查解密落后时间并决定是否告警的间隔
+var LagCheckInterval = 30 * time.Second
+
+// 解密落后告警的状态
+const (
+	LagAlerting = "alerting" // 落后时间超过阈值
+	LagResolved = "resolved" // 恢复到阈值以内
+)
+
+// LagAlert 解密落后告警，推送到推送目标时序列化为 JSON
+type LagAlert struct {
+	Status    string    `json:"status"`
+	Lag       float64   `json:"lag"`       // 落后的秒数
+	Threshold float64   `json:"threshold"` // 告警阈值的秒数
+	Files     []string  `json:"files"`     // 尚未解密的数据库，相对数据目录
+	DataDir   string    `json:"data_dir"`
+	Time      time.Time `json:"time"`
+}
+
+// ChatMessage 转换为 Slack、Discord 消息
+func (a *LagAlert) ChatMessage() *push.Message {
+	lag := time.Duration(a.Lag * float64(time.Second)).Round(time.Second)
+	if a.Status == LagResolved {
+		return &push.Message{Title: "chatlog: auto decrypt caught up", Text: fmt.Sprintf("%s is %s behind, back under the threshold", a.DataDir, lag)}
+	}
+	return &push.Message{
+		Title: "chatlog: auto decrypt is behind",
+		Text:  fmt.Sprintf("%s is %s behind (threshold %s), pending: %v", a.DataDir, lag, time.Duration(a.Threshold*float64(time.Second)), a.Files),
+	}
+}
+
+// DecryptLag 返回工作目录落后数据目录的时间，即最早一次尚未解密的数据库写入距今的时间，全部解密完成时为 0
+// 未开启自动解密时 ok 为 false；解密失败时持续增长直到解密成功，按单调时钟计算，不受系统时间调整影响
+func (s *Service) DecryptLag() (lag time.Duration, ok bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.autoCtx == nil {
+		return 0, false
+	}
+	for _, since := range s.pendingSince {
+		lag = max(lag, time.Since(since))
+	}
+	return lag, true
+}
+
+// pendingFiles 返回尚未解密的数据库，按落后时间从长到短排列
+func (s *Service) pendingFiles() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	files := make([]string, 0, len(s.pendingSince))
+	for dbFile := range s.pendingSince {
+		files = append(files, dbFile)
+	}
+	slices.SortFunc(files, func(a, b string) int { return s.pendingSince[a].Compare(s.pendingSince[b]) })
+	for i, dbFile := range files {
+		if rel, err := filepath.Rel(s.conf.GetDataDir(), dbFile); err == nil {
+			files[i] = filepath.ToSlash(rel)
+		}
+	}
+	return files
+}
+
+// markPending 记录数据库有尚未解密的写入，已有记录时保留更早的时间
+func (s *Service) markPending(dbFile string, since time.Time) {
+	if _, ok := s.pendingSince[dbFile]; !ok {
+		s.pendingSince[dbFile] = since
+	}
+}
+
+// markDecrypted 数据库在 start 开始的解密成功，解密期间没有新的写入时不再落后
+func (s *Service) markDecrypted(dbFile string, start time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if last, ok := s.lastEvents[dbFile]; ok && last.After(start) {
+		s.pendingSince[dbFile] = last
+		return
+	}
+	delete(s.pendingSince, dbFile)
+}
+
+// seedPending 开启自动解密时，源数据库比解密结果新的数据库从源数据库的修改时间开始计算落后时间
+func (s *Service) seedPending() {
+	dbFiles, err := s.listDBFiles()
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, dbFile := range dbFiles {
+		if unchanged(dbFile, s.targetPath(dbFile)) {
+			continue
+		}
+		since := now
+		if fi, err := os.Stat(dbFile); err == nil && fi.ModTime().Before(now) {
+			// 保留 now 的单调时钟读数
+			since = now.Add(-now.Sub(fi.ModTime()))
+		}
+		s.markPending(dbFile, since)
+	}
+}
+
+// watchLag 配置了 decrypt_lag_alert 时定期检查落后时间，超过阈值时推送一次告警，恢复后推送一次恢复通知
+func (s *Service) watchLag(ctx context.Context) {
+	ticker := time.NewTicker(LagCheckInterval)
+	defer ticker.Stop()
+
+	alerting := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		c := s.conf.GetDecryptLagAlert()
+		if c == nil || len(c.Destination) == 0 {
+			alerting = false
+			continue
+		}
+		lag, ok := s.DecryptLag()
+		if !ok {
+			continue
+		}
+		threshold := c.GetThreshold()
+		var status string
+		switch {
+		case !alerting && lag >= threshold:
+			status, alerting = LagAlerting, true
+		case alerting && lag < threshold:
+			status, alerting = LagResolved, false
+		default:
+			continue
+		}
+
+		alert := &LagAlert{
+			Status:    status,
+			Lag:       lag.Seconds(),
+			Threshold: threshold.Seconds(),
+			Files:     s.pendingFiles(),
+			DataDir:   s.conf.GetDataDir(),
+			Time:      time.Now(),
+		}
+		event := log.Warn()
+		if status == LagResolved {
+			event = log.Info()
+		}
+		event.Str("status", status).Dur("lag", lag).Strs("files", alert.Files).Msg("decrypt lag")
+		dest, err := push.Resolve(c.Destination, s.conf.GetDestinations())
+		if err == nil {
+			err = push.Send(dest, alert)
+		}
+		if err != nil {
+			log.Err(err).Str("destination", c.Destination).Msg("failed to send decrypt lag alert")
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package wechat
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/DanielMao1/chatlog/internal/errors"
+	"github.com/DanielMao1/chatlog/internal/wechat/decrypt"
+	"github.com/DanielMao1/chatlog/internal/wechat/decrypt/common"
+)
+
+// 解密计划中数据库的处理方式
+const (
+	ActionDecrypt = "decrypt" // 解密
+	ActionCopy    = "copy"    // 未加密，直接复制
+	ActionSkip    = "skip"    // 解密结果比源数据库新，跳过
+	ActionFail    = "fail"    // 没有可用的密钥或无法读取
+)
+
+// PlanItem 解密计划中的一个数据库
+type PlanItem struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Size   int64  `json:"size"`
+	Key    string `json:"key,omitempty"` // 使用的密钥，raw 或 derived #n
+	Action string `json:"action"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// derivedKeyValidator 支持派生密钥的解密器（4.0 版本）
+type derivedKeyValidator interface {
+	ValidateDerivedKey(page1 []byte, key []byte) bool
+}
+
+// PlanDecrypt 列出 DecryptDBFiles 将要处理的数据库及使用的密钥，不写入任何文件
+func (s *Service) PlanDecrypt(force bool) ([]*PlanItem, error) {
+	decryptor, err := decrypt.NewDecryptor(s.conf.GetPlatform(), s.conf.GetVersion())
+	if err != nil {
+		return nil, err
+	}
+	dbFiles, err := s.listDBFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*PlanItem, 0, len(dbFiles))
+	for _, dbFile := range dbFiles {
+		item := &PlanItem{
+			Source: dbFile,
+			Target: s.targetPath(dbFile),
+			Action: ActionDecrypt,
+		}
+		if info, err := os.Stat(dbFile); err == nil {
+			item.Size = info.Size()
+		}
+
+		item.Key, err = matchKey(decryptor, dbFile, s.conf.GetDataKey())
+		switch {
+		case err == errors.ErrAlreadyDecrypted:
+			item.Action = ActionCopy
+			item.Reason = "not encrypted"
+		case err != nil:
+			item.Action = ActionFail
+			item.Reason = err.Error()
+		}
+		if !force && unchanged(dbFile, item.Target) {
+			item.Action = ActionSkip
+			item.Reason = "unchanged since last decryption"
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// matchKey 读取数据库首页，返回可以解密该数据库的密钥描述
+func matchKey(decryptor decrypt.Decryptor, dbFile string, dataKey string) (string, error) {
+	dbInfo, err := common.OpenDBFile(dbFile, decryptor.GetPageSize())
+	if err != nil {
+		return "", err
+	}
+
+	keys, isDerived := strings.CutPrefix(dataKey, "derived:")
+	if !isDerived {
+		key, err := hex.DecodeString(dataKey)
+		if err != nil {
+			return "", errors.DecodeKeyFailed(err)
+		}
+		if !decryptor.Validate(dbInfo.FirstPage, key) {
+			return "", errors.ErrDecryptIncorrectKey
+		}
+		return "raw", nil
+	}
+
+	validator, ok := decryptor.(derivedKeyValidator)
+	if !ok {
+		return "", fmt.Errorf("derived keys are not supported by %s", decryptor.GetVersion())
+	}
+	for i, k := range strings.Split(keys, ",") {
+		if b, err := hex.DecodeString(k); err == nil && validator.ValidateDerivedKey(dbInfo.FirstPage, b) {
+			return fmt.Sprintf("derived #%d", i+1), nil
+		}
+	}
+	return "", errors.ErrDecryptIncorrectKey
+}
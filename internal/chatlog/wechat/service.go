@@ -2,25 +2,40 @@ package wechat
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/rs/zerolog/log"
 
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+	"github.com/DanielMao1/chatlog/internal/chatlog/decrypted"
+	"github.com/DanielMao1/chatlog/internal/chatlog/merge"
+	"github.com/DanielMao1/chatlog/internal/chatlog/stream"
 	"github.com/DanielMao1/chatlog/internal/errors"
 	"github.com/DanielMao1/chatlog/internal/wechat"
 	"github.com/DanielMao1/chatlog/internal/wechat/decrypt"
+	"github.com/DanielMao1/chatlog/internal/wechat/key/scan"
 	"github.com/DanielMao1/chatlog/pkg/filemonitor"
+	"github.com/DanielMao1/chatlog/pkg/progress"
 	"github.com/DanielMao1/chatlog/pkg/util"
+	"github.com/DanielMao1/chatlog/pkg/util/zstd"
 )
 
 var (
 	DebounceTime = 1 * time.Second
 	MaxWaitTime  = 10 * time.Second
+	// StableTime 数据库最后一次修改后等待多久才开始解密
+	StableTime = 500 * time.Millisecond
+	// DecryptRetries 解密时页面 HMAC 校验失败的重试次数
+	DecryptRetries = 3
 )
 
 type Service struct {
@@ -29,6 +44,16 @@ type Service struct {
 	pendingActions map[string]bool
 	mutex          sync.Mutex
 	fm             *filemonitor.FileMonitor
+	// autoCtx 自动解密的上下文，停止自动解密时取消，进行中的解密随之停止
+	autoCtx    context.Context
+	autoCancel context.CancelFunc
+	// lastDecrypt 最近一次自动解密成功的时间
+	lastDecrypt time.Time
+	// pendingSince 有尚未解密的写入的数据库，及其中最早一次写入的时间，用于计算解密落后时间
+	pendingSince map[string]time.Time
+	// events 配置了 stream.decrypt 时发布解密事件，events 对应的配置为 eventsConf
+	events     *stream.Publisher
+	eventsConf *conf.Stream
 }
 
 type Config interface {
@@ -37,13 +62,28 @@ type Config interface {
 	GetWorkDir() string
 	GetPlatform() string
 	GetVersion() int
+	GetJobs() int
+	GetAutoDecryptInterval() time.Duration
+	GetStream() *conf.Stream
+	GetKeyScan() *conf.KeyScan
+	GetCompressWorkDir() bool
+	GetDecryptOnly() []string
+	GetDecryptLagAlert() *conf.DecryptLagAlert
+	GetDestinations() map[string]*conf.Destination
 }
 
 func NewService(conf Config) *Service {
+	// 之后获取密钥时使用配置的扫描参数
+	var opts scan.Options
+	if c := conf.GetKeyScan(); c != nil {
+		opts = scan.Options{Workers: c.Workers, QueueSize: c.QueueSize, ChunkSize: c.ChunkSize * 1024 * 1024, Overlap: c.Overlap}
+	}
+	scan.Set(opts)
 	return &Service{
 		conf:           conf,
 		lastEvents:     make(map[string]time.Time),
 		pendingActions: make(map[string]bool),
+		pendingSince:   make(map[string]time.Time),
 	}
 }
 
@@ -77,14 +117,22 @@ func (s *Service) StartAutoDecrypt() error {
 
 	s.fm = filemonitor.NewFileMonitor()
 	s.fm.AddGroup(dbGroup)
+	s.mutex.Lock()
+	s.autoCtx, s.autoCancel = context.WithCancel(context.Background())
+	ctx := s.autoCtx
+	s.mutex.Unlock()
 	if err := s.fm.Start(); err != nil {
 		log.Debug().Err(err).Msg("failed to start file monitor")
+		s.cancelAuto()
 		return err
 	}
+	s.seedPending()
+	go s.watchLag(ctx)
 	return nil
 }
 
 func (s *Service) StopAutoDecrypt() error {
+	s.cancelAuto()
 	if s.fm != nil {
 		if err := s.fm.Stop(); err != nil {
 			return err
@@ -106,14 +154,20 @@ func (s *Service) DecryptFileCallback(event fsnotify.Event) error {
 	if !(event.Op.Has(fsnotify.Write) || event.Op.Has(fsnotify.Create)) {
 		return nil
 	}
+	if !s.selected(event.Name) {
+		return nil
+	}
 
 	s.mutex.Lock()
-	s.lastEvents[event.Name] = time.Now()
+	now := time.Now()
+	s.lastEvents[event.Name] = now
+	s.markPending(event.Name, now)
 
-	if !s.pendingActions[event.Name] {
+	if !s.pendingActions[event.Name] && s.autoCtx != nil {
 		s.pendingActions[event.Name] = true
+		ctx := s.autoCtx
 		s.mutex.Unlock()
-		go s.waitAndProcess(event.Name)
+		go s.waitAndProcess(ctx, event.Name)
 	} else {
 		s.mutex.Unlock()
 	}
@@ -121,85 +175,496 @@ func (s *Service) DecryptFileCallback(event fsnotify.Event) error {
 	return nil
 }
 
-func (s *Service) waitAndProcess(dbFile string) {
+// LastAutoDecrypt 返回最近一次自动解密成功的时间，尚未解密时为零值
+func (s *Service) LastAutoDecrypt() time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.lastDecrypt
+}
+
+// cancelAuto 取消进行中的自动解密
+func (s *Service) cancelAuto() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.autoCancel != nil {
+		s.autoCancel()
+		s.autoCtx, s.autoCancel = nil, nil
+		clear(s.pendingSince)
+	}
+}
+
+// debounce 返回自动解密的等待时间，数据库在该时间内没有再次写入时才解密
+func (s *Service) debounce() time.Duration {
+	if d := s.conf.GetAutoDecryptInterval(); d > 0 {
+		return d
+	}
+	return DebounceTime
+}
+
+func (s *Service) waitAndProcess(ctx context.Context, dbFile string) {
 	start := time.Now()
+	debounce := s.debounce()
+	maxWait := max(MaxWaitTime, debounce)
+	wait := debounce
 	for {
-		time.Sleep(DebounceTime)
+		select {
+		case <-ctx.Done():
+			s.mutex.Lock()
+			s.pendingActions[dbFile] = false
+			s.mutex.Unlock()
+			return
+		case <-time.After(wait):
+		}
 
 		s.mutex.Lock()
 		lastEventTime := s.lastEvents[dbFile]
 		elapsed := time.Since(lastEventTime)
 		totalElapsed := time.Since(start)
 
-		if elapsed >= DebounceTime || totalElapsed >= MaxWaitTime {
+		if elapsed >= debounce || totalElapsed >= maxWait {
 			s.pendingActions[dbFile] = false
 			s.mutex.Unlock()
 
 			log.Debug().Msgf("Processing file: %s", dbFile)
-			s.DecryptDBFile(dbFile)
+			s.publishDecrypt(stream.DecryptEvent{Event: stream.DecryptStarted, Auto: true, File: s.relPath(dbFile)})
+			if err := s.DecryptDBFile(ctx, dbFile); err == nil {
+				s.reapplyMerged()
+				s.compressWorkDir()
+				s.mutex.Lock()
+				s.lastDecrypt = time.Now()
+				s.mutex.Unlock()
+				s.publishDecrypt(stream.DecryptEvent{Event: stream.DecryptFinished, Auto: true, File: s.relPath(dbFile)})
+			} else {
+				s.publishDecrypt(stream.DecryptEvent{Event: stream.DecryptFailed, Auto: true, File: s.relPath(dbFile), Error: err.Error()})
+			}
 			return
 		}
+		// 等到最后一次写入满 debounce 或达到 maxWait 时再检查
+		wait = min(debounce-elapsed, maxWait-totalElapsed)
 		s.mutex.Unlock()
 	}
 }
 
-func (s *Service) DecryptDBFile(dbFile string) error {
+// DecryptDBFile 解密单个数据库，ctx 取消时停止解密并保留工作目录中原有的结果
+func (s *Service) DecryptDBFile(ctx context.Context, dbFile string) error {
+	return s.decryptDBFile(ctx, dbFile, nil)
+}
+
+// decryptDBFile 解密单个数据库，tracker 不为空时按写入的字节数更新进度
+// 微信正在写入时等待数据库稳定后再解密，读取到写入中途的页面导致 HMAC 校验失败时重试，
+// 解密结果写入临时文件后整体替换工作目录中的数据库，读取方不会看到写了一半的数据库
+func (s *Service) decryptDBFile(ctx context.Context, dbFile string, tracker *progress.Tracker) error {
+	start := time.Now()
 
 	decryptor, err := decrypt.NewDecryptor(s.conf.GetPlatform(), s.conf.GetVersion())
 	if err != nil {
 		return err
 	}
 
-	output := filepath.Join(s.conf.GetWorkDir(), dbFile[len(s.conf.GetDataDir()):])
+	output := s.targetPath(dbFile)
 	if err := util.PrepareDir(filepath.Dir(output)); err != nil {
 		return err
 	}
 
 	outputTemp := output + ".tmp"
-	outputFile, err := os.Create(outputTemp)
+	for attempt := 0; ; attempt++ {
+		if err = waitStable(ctx, dbFile); err != nil {
+			break
+		}
+		err = decryptTo(ctx, decryptor, s.conf.GetDataKey(), dbFile, outputTemp, tracker)
+		if err == nil || attempt >= DecryptRetries || !errors.Is(err, errors.ErrDecryptHashVerificationFailed) {
+			break
+		}
+		log.Debug().Err(err).Msgf("%s changed while decrypting, retrying", dbFile)
+		if tracker != nil {
+			tracker.Restart(dbFile)
+		}
+	}
+	if err != nil {
+		os.Remove(outputTemp)
+		log.Err(err).Msgf("failed to decrypt %s", dbFile)
+		return err
+	}
+
+	if err := os.Rename(outputTemp, output); err != nil {
+		os.Remove(outputTemp)
+		return fmt.Errorf("failed to replace %s: %w", output, err)
+	}
+	if err := decrypted.Record(s.conf.GetWorkDir(), s.relPath(dbFile)); err != nil {
+		log.Debug().Err(err).Msgf("failed to record decrypted %s", output)
+	}
+	s.markDecrypted(dbFile, start)
+
+	log.Debug().Msgf("Decrypted %s to %s", dbFile, output)
+
+	return nil
+}
+
+// decryptTo 将数据库解密到 output，写入完成并同步到磁盘后返回
+func decryptTo(ctx context.Context, decryptor decrypt.Decryptor, key, dbFile, output string, tracker *progress.Tracker) error {
+	outputFile, err := os.Create(output)
 	if err != nil {
 		return fmt.Errorf("failed to create output file: %v", err)
 	}
-	defer func() {
-		outputFile.Close()
-		if err := os.Rename(outputTemp, output); err != nil {
-			log.Debug().Err(err).Msgf("failed to rename %s to %s", outputTemp, output)
+	defer outputFile.Close()
+
+	var w io.Writer = outputFile
+	if tracker != nil {
+		w = &progressWriter{w: outputFile, tracker: tracker, name: dbFile}
+	}
+
+	if err := decryptor.Decrypt(ctx, dbFile, key, w); err != nil {
+		if err != errors.ErrAlreadyDecrypted {
+			return err
+		}
+		data, err := os.ReadFile(dbFile)
+		if err != nil {
+			return err
 		}
-	}()
+		if _, err := outputFile.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := outputFile.Sync(); err != nil {
+		return err
+	}
+	return outputFile.Close()
+}
 
-	if err := decryptor.Decrypt(context.Background(), dbFile, s.conf.GetDataKey(), outputFile); err != nil {
-		if err == errors.ErrAlreadyDecrypted {
-			if data, err := os.ReadFile(dbFile); err == nil {
-				outputFile.Write(data)
-			}
+// waitStable 等待数据库在 StableTime 内没有再次修改，最多等待 MaxWaitTime，仍在写入时照常解密，由 HMAC 校验发现不完整的页面
+// 微信通过 -wal 文件写入，WAL 增长时主文件可能保持不变，因此同时检查 -wal 文件，修改时间或大小变化都视为修改
+func waitStable(ctx context.Context, dbFile string) error {
+	deadline := time.Now().Add(MaxWaitTime)
+	var prev dbState
+	var changed time.Time
+	for first := true; ; first = false {
+		st, err := statDB(dbFile)
+		if err != nil {
+			return errors.OpenFileFailed(dbFile, err)
+		}
+		// 修改时间的精度较低时，通过两次检查之间的大小变化发现写入
+		if !first && (st.size != prev.size || st.walSize != prev.walSize) {
+			changed = time.Now()
+		}
+		prev = st
+		age := time.Since(st.modTime)
+		if !changed.IsZero() {
+			age = min(age, time.Since(changed))
+		}
+		if age >= StableTime || age < 0 || time.Now().After(deadline) {
 			return nil
 		}
-		log.Err(err).Msgf("failed to decrypt %s", dbFile)
+		select {
+		case <-ctx.Done():
+			return errors.ErrDecryptOperationCanceled
+		case <-time.After(StableTime - age):
+		}
+	}
+}
+
+// dbState 数据库与 -wal 文件的状态，modTime 为两者中较新的修改时间，没有 -wal 文件时 walSize 为 -1
+type dbState struct {
+	modTime       time.Time
+	size, walSize int64
+}
+
+func statDB(dbFile string) (dbState, error) {
+	fi, err := os.Stat(dbFile)
+	if err != nil {
+		return dbState{}, err
+	}
+	st := dbState{modTime: fi.ModTime(), size: fi.Size(), walSize: -1}
+	if wal, err := os.Stat(dbFile + "-wal"); err == nil {
+		st.walSize = wal.Size()
+		if wal.ModTime().After(st.modTime) {
+			st.modTime = wal.ModTime()
+		}
+	}
+	return st, nil
+}
+
+// DecryptDBFiles 解密数据目录中的所有数据库，force 为 false 时跳过未变化的数据库
+// ctx 取消时不再开始新的数据库，进行中的解密随之停止，已解密完成的数据库保留
+func (s *Service) DecryptDBFiles(ctx context.Context, force bool) error {
+	dbFiles, err := s.listDBFiles()
+	if err != nil {
 		return err
 	}
 
-	log.Debug().Msgf("Decrypted %s to %s", dbFile, output)
+	pending := make([]string, 0, len(dbFiles))
+	for _, dbFile := range dbFiles {
+		if !force && unchanged(dbFile, s.targetPath(dbFile)) {
+			log.Debug().Msgf("skip unchanged %s", dbFile)
+			continue
+		}
+		pending = append(pending, dbFile)
+	}
+
+	jobs := util.Jobs(s.conf.GetJobs())
+	if err := s.checkSpace(pending, jobs); err != nil {
+		return err
+	}
+	log.Debug().Msgf("decrypting %d databases with %d jobs", len(pending), jobs)
+
+	var totalBytes int64
+	sizes := make(map[string]int64, len(pending))
+	for _, dbFile := range pending {
+		if fi, err := os.Stat(dbFile); err == nil {
+			sizes[dbFile] = fi.Size()
+			totalBytes += fi.Size()
+		}
+	}
+	tracker := progress.NewTracker(progress.StageDecrypt, "", len(pending), totalBytes)
+	defer tracker.Done()
+	if len(pending) != 0 {
+		s.publishDecrypt(stream.DecryptEvent{Event: stream.DecryptStarted, Files: len(pending)})
+	}
+
+	// 个别文件解密失败时跳过，全部失败时通常是密钥错误，返回第一个错误
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+		failed   int
+	)
+	ch := make(chan string)
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for dbFile := range ch {
+				tracker.Start(dbFile, sizes[dbFile])
+				err := s.decryptDBFile(ctx, dbFile, tracker)
+				tracker.Finish(dbFile)
+				if err != nil {
+					log.Debug().Msgf("DecryptDBFile %s failed: %v", dbFile, err)
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					failed++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+dispatch:
+	for _, dbFile := range pending {
+		select {
+		case ch <- dbFile:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(ch)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		s.publishDecrypt(stream.DecryptEvent{Event: stream.DecryptFailed, Files: len(pending), Failed: failed, Error: ctx.Err().Error()})
+		return errors.ErrDecryptOperationCanceled
+	}
+
+	if len(pending) != 0 && failed == len(pending) {
+		s.publishDecrypt(stream.DecryptEvent{Event: stream.DecryptFailed, Files: len(pending), Failed: failed, Error: firstErr.Error()})
+		return errors.DecryptFailed(firstErr)
+	}
+	if len(pending) != 0 {
+		s.reapplyMerged()
+		s.compressWorkDir()
+		s.publishDecrypt(stream.DecryptEvent{Event: stream.DecryptFinished, Files: len(pending), Failed: failed})
+	}
 
 	return nil
 }
 
-func (s *Service) DecryptDBFiles() error {
-	dbGroup, err := filemonitor.NewFileGroup("wechat", s.conf.GetDataDir(), `.*\.db$`, []string{"fts"})
+// reapplyMerged 解密覆盖了工作目录中的数据库，从存档重新合并 chatlog merge、import 写入的数据
+func (s *Service) reapplyMerged() {
+	ret, err := merge.Apply(s.conf.GetWorkDir(), s.conf.GetPlatform(), s.conf.GetVersion())
 	if err != nil {
-		return err
+		log.Err(err).Msg("failed to re-apply merged data")
+		return
 	}
+	if ret.Messages != 0 || ret.Records != 0 {
+		log.Debug().Msgf("re-applied merged data, %d messages, %d records", ret.Messages, ret.Records)
+	}
+}
 
-	dbFiles, err := dbGroup.List()
+// compressWorkDir 配置了 compress_workdir 时压缩工作目录中尚未压缩的解密数据库
+// 合并写入的数据库在写入前被解压，也在这里重新压缩
+func (s *Service) compressWorkDir() {
+	if !s.conf.GetCompressWorkDir() {
+		return
+	}
+	files, err := decrypted.Load(s.conf.GetWorkDir())
 	if err != nil {
-		return err
+		log.Err(err).Msg("failed to load decrypted databases")
+		return
+	}
+	for rel := range files {
+		path := filepath.Join(s.conf.GetWorkDir(), filepath.FromSlash(rel))
+		if err := zstd.CompressFile(path); err != nil && !os.IsNotExist(err) {
+			log.Err(err).Msgf("failed to compress %s", path)
+		}
 	}
+}
 
+func (s *Service) listDBFiles() ([]string, error) {
+	dbGroup, err := filemonitor.NewFileGroup("wechat", s.conf.GetDataDir(), `.*\.db$`, []string{"fts"})
+	if err != nil {
+		return nil, err
+	}
+	dbFiles, err := dbGroup.List()
+	if err != nil {
+		return nil, errors.DataDirInvalid(s.conf.GetDataDir(), err)
+	}
+	if len(dbFiles) == 0 {
+		return nil, errors.DataDirInvalid(s.conf.GetDataDir(), fmt.Errorf("no database found"))
+	}
+	if len(s.conf.GetDecryptOnly()) == 0 {
+		return dbFiles, nil
+	}
+	ret := make([]string, 0, len(dbFiles))
 	for _, dbFile := range dbFiles {
-		if err := s.DecryptDBFile(dbFile); err != nil {
-			log.Debug().Msgf("DecryptDBFile %s failed: %v", dbFile, err)
+		if s.selected(dbFile) {
+			ret = append(ret, dbFile)
+		}
+	}
+	if len(ret) == 0 {
+		return nil, errors.InvalidArg(fmt.Sprintf("decrypt_only %v matches no database", s.conf.GetDecryptOnly()))
+	}
+	return ret, nil
+}
+
+// selected 判断数据库是否在 decrypt_only 选择的范围内，未配置时选择全部数据库
+func (s *Service) selected(dbFile string) bool {
+	only := s.conf.GetDecryptOnly()
+	if len(only) == 0 {
+		return true
+	}
+	rel := filepath.ToSlash(s.relPath(dbFile))
+	for _, o := range only {
+		if len(o) != 0 && strings.Contains(rel, filepath.ToSlash(o)) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSpace 解密前检查工作目录所在磁盘的剩余空间，不足时在写入任何文件前返回错误，
+// 而不是解密到一半时写满磁盘；无法获取剩余空间时照常解密
+func (s *Service) checkSpace(pending []string, jobs int) error {
+	if len(pending) == 0 {
+		return nil
+	}
+	files := make([]spaceFile, 0, len(pending))
+	for _, dbFile := range pending {
+		fi, err := os.Stat(dbFile)
+		if err != nil {
 			continue
 		}
+		f := spaceFile{source: fi.Size()}
+		if ti, err := os.Stat(s.targetPath(dbFile)); err == nil {
+			f.target = ti.Size()
+		}
+		files = append(files, f)
 	}
+	need := spaceNeeded(files, jobs)
 
+	free, dir, err := util.FreeSpace(s.conf.GetWorkDir())
+	if err != nil {
+		log.Debug().Err(err).Msgf("failed to get free space of %s", s.conf.GetWorkDir())
+		return nil
+	}
+	if free < need {
+		return errors.InsufficientSpace(dir, util.ByteCountSI(need), util.ByteCountSI(free))
+	}
 	return nil
 }
+
+// spaceFile 待解密数据库的大小与工作目录中已有解密结果的大小
+type spaceFile struct {
+	source int64
+	target int64
+}
+
+// spaceNeeded 估算解密需要的剩余空间：解密结果与源数据库大小相同，先写入临时文件再替换已有的结果，
+// 替换后才释放旧文件，所以除了比旧文件增加的部分，还需要同时解密的 jobs 个最大的旧文件的空间
+func spaceNeeded(files []spaceFile, jobs int) int64 {
+	var need int64
+	replaced := make([]int64, 0, len(files))
+	for _, f := range files {
+		need += max(f.source-f.target, 0)
+		replaced = append(replaced, min(f.source, f.target))
+	}
+	slices.Sort(replaced)
+	for i := len(replaced) - 1; i >= 0 && i >= len(replaced)-jobs; i-- {
+		need += replaced[i]
+	}
+	return need
+}
+
+// progressWriter 按写入的字节数更新解密进度
+type progressWriter struct {
+	w       io.Writer
+	tracker *progress.Tracker
+	name    string
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.tracker.Add(w.name, int64(n))
+	return n, err
+}
+
+// targetPath 返回数据库解密后在工作目录中的路径
+func (s *Service) targetPath(dbFile string) string {
+	return filepath.Join(s.conf.GetWorkDir(), s.relPath(dbFile))
+}
+
+// relPath 返回数据库相对数据目录的路径
+func (s *Service) relPath(dbFile string) string {
+	return dbFile[len(s.conf.GetDataDir()):]
+}
+
+// unchanged 判断解密结果是否比源数据库新
+func unchanged(dbFile, target string) bool {
+	src, err := os.Stat(dbFile)
+	if err != nil {
+		return false
+	}
+	dst, err := os.Stat(target)
+	if err != nil {
+		return false
+	}
+	return !dst.ModTime().Before(src.ModTime())
+}
+
+// publishDecrypt 配置了 stream.decrypt 时在后台发布解密事件，配置变化后使用新的配置
+func (s *Service) publishDecrypt(e stream.DecryptEvent) {
+	c := s.conf.GetStream()
+	if c == nil || !c.Decrypt {
+		return
+	}
+	s.mutex.Lock()
+	if s.events == nil || s.eventsConf != c {
+		if s.events != nil {
+			s.events.Close()
+		}
+		p, err := stream.New(c)
+		if err != nil {
+			s.mutex.Unlock()
+			log.Debug().Err(err).Msg("skip decrypt event")
+			return
+		}
+		s.events, s.eventsConf = p, c
+	}
+	p := s.events
+	s.mutex.Unlock()
+
+	e.Time = time.Now()
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	p.Go(stream.DecryptSubject(c), stream.Record{Value: data})
+}
@@ -0,0 +1,130 @@
+package wechat
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/chatlog/conf"
+)
+
+func TestWaitStable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "message_0.db")
+	if err := os.WriteFile(path, []byte("a"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// 刚修改的数据库等待 StableTime 后才解密
+	start := time.Now()
+	if err := waitStable(context.Background(), path); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < StableTime/2 {
+		t.Errorf("returned after %s, want about %s", elapsed, StableTime)
+	}
+
+	// 较早修改的数据库不等待
+	old := time.Now().Add(-time.Minute)
+	os.Chtimes(path, old, old)
+	start = time.Now()
+	if err := waitStable(context.Background(), path); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > StableTime/2 {
+		t.Errorf("waited %s for a stable database", elapsed)
+	}
+
+	// 主文件没有变化，但 -wal 文件刚刚写入
+	if err := os.WriteFile(path+"-wal", []byte("wal"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	start = time.Now()
+	if err := waitStable(context.Background(), path); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < StableTime/2 {
+		t.Errorf("returned after %s while the wal was being written, want about %s", elapsed, StableTime)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	os.Chtimes(path, time.Now(), time.Now())
+	if err := waitStable(ctx, path); err == nil {
+		t.Error("expected error when canceled")
+	}
+}
+
+func TestSpaceNeeded(t *testing.T) {
+	tests := []struct {
+		files []spaceFile
+		jobs  int
+		want  int64
+	}{
+		// 首次解密，需要全部源数据库的大小
+		{[]spaceFile{{source: 100}, {source: 50}}, 2, 150},
+		// 替换已有结果，同时解密的文件需要临时保留旧文件
+		{[]spaceFile{{source: 100, target: 100}, {source: 50, target: 50}, {source: 30, target: 30}}, 1, 100},
+		{[]spaceFile{{source: 100, target: 100}, {source: 50, target: 50}, {source: 30, target: 30}}, 2, 150},
+		// 源数据库变大的部分加上同时替换的旧文件
+		{[]spaceFile{{source: 120, target: 100}, {source: 40, target: 50}}, 4, 160},
+		{nil, 4, 0},
+	}
+	for i, tt := range tests {
+		if got := spaceNeeded(tt.files, tt.jobs); got != tt.want {
+			t.Errorf("case %d: spaceNeeded = %d, want %d", i, got, tt.want)
+		}
+	}
+}
+
+func TestSelected(t *testing.T) {
+	dataDir := filepath.Join(t.TempDir(), "wxid_test")
+	s := NewService(&conf.ServerConfig{DataDir: dataDir, DecryptOnly: []string{"message", "contact/contact.db"}})
+	tests := map[string]bool{
+		"db_storage/message/message_0.db":   true,
+		"db_storage/contact/contact.db":     true,
+		"db_storage/contact/contact_fts.db": false,
+		"db_storage/session/session.db":     false,
+	}
+	for rel, want := range tests {
+		if got := s.selected(filepath.Join(dataDir, filepath.FromSlash(rel))); got != want {
+			t.Errorf("selected(%s) = %v, want %v", rel, got, want)
+		}
+	}
+}
+
+func TestDecryptLag(t *testing.T) {
+	dataDir := t.TempDir()
+	s := NewService(&conf.ServerConfig{DataDir: dataDir})
+	if _, ok := s.DecryptLag(); ok {
+		t.Error("DecryptLag ok without auto decrypt")
+	}
+	s.autoCtx, s.autoCancel = context.WithCancel(context.Background())
+	defer s.cancelAuto()
+
+	msg := filepath.Join(dataDir, "message_0.db")
+	contact := filepath.Join(dataDir, "contact.db")
+	now := time.Now()
+	s.markPending(msg, now.Add(-time.Minute))
+	s.markPending(msg, now)
+	s.markPending(contact, now.Add(-time.Second))
+	if lag, _ := s.DecryptLag(); lag < time.Minute {
+		t.Errorf("DecryptLag = %s, want at least 1m", lag)
+	}
+	if files := s.pendingFiles(); len(files) != 2 || files[0] != "message_0.db" {
+		t.Errorf("pendingFiles = %v", files)
+	}
+
+	// 解密期间有新的写入时仍然落后，从新的写入开始计算
+	s.lastEvents[msg] = now
+	s.markDecrypted(msg, now.Add(-time.Second))
+	if lag, _ := s.DecryptLag(); lag >= time.Minute {
+		t.Errorf("DecryptLag = %s after decrypting message_0.db", lag)
+	}
+	s.markDecrypted(msg, time.Now())
+	s.markDecrypted(contact, time.Now())
+	if lag, ok := s.DecryptLag(); !ok || lag != 0 {
+		t.Errorf("DecryptLag = %s, %v, want 0", lag, ok)
+	}
+}
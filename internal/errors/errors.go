@@ -14,6 +14,8 @@ type Error struct {
 	Message string   `json:"message"` // 错误消息
 	Cause   error    `json:"-"`       // 原始错误
 	Code    int      `json:"-"`       // HTTP Code
+	Exit    int      `json:"-"`       // 命令行退出码，0 表示未分类
+	Reason  string   `json:"-"`       // 错误码，见 reason.go，为空表示未分类
 	Stack   []string `json:"-"`       // 错误堆栈
 }
 
@@ -53,6 +55,12 @@ func (e *Error) WithStack() *Error {
 	return e
 }
 
+// WithExit 设置命令行退出码，见 exit.go
+func (e *Error) WithExit(code int) *Error {
+	e.Exit = code
+	return e
+}
+
 func New(cause error, code int, message string) *Error {
 	return &Error{
 		Message: message,
@@ -79,6 +87,8 @@ func Wrap(err error, message string, code int) *Error {
 			Message: message,
 			Cause:   appErr.Cause,
 			Code:    appErr.Code,
+			Exit:    appErr.Exit,
+			Reason:  appErr.Reason,
 			Stack:   appErr.Stack,
 		}
 	}
@@ -92,7 +102,7 @@ func GetCode(err error) int {
 	}
 
 	var appErr *Error
-	if errors.As(err, &appErr) {
+	if errors.As(err, &appErr) && appErr.Code != 0 {
 		return appErr.Code
 	}
 
@@ -110,13 +120,9 @@ func RootCause(err error) error {
 	return err
 }
 
+// Err 返回错误响应，error 为错误信息，reason 为错误码
 func Err(c *gin.Context, err error) {
-	if appErr, ok := err.(*Error); ok {
-		c.JSON(appErr.Code, appErr.Error())
-		return
-	}
-
-	c.JSON(http.StatusInternalServerError, err.Error())
+	c.JSON(GetCode(err), gin.H{"error": err.Error(), "reason": ReasonOf(err)})
 }
 
 func Is(err, target error) bool {
@@ -0,0 +1,66 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// 命令行退出码，供脚本和调度器区分失败类型
+const (
+	ExitOK              = 0
+	ExitFailure         = 1   // 未分类的错误
+	ExitUsage           = 2   // 命令行参数错误
+	ExitConfig          = 3   // 配置缺失或无效
+	ExitProcessNotFound = 4   // 未找到微信进程
+	ExitSIPEnabled      = 5   // macOS 未关闭 SIP，无法读取进程内存
+	ExitKeyInvalid      = 6   // 密钥无效或未找到有效密钥
+	ExitDecryptFailed   = 7   // 解密失败
+	ExitCanceled        = 130 // 被 Ctrl-C 中断
+)
+
+var exitNames = map[int]string{
+	ExitOK:              "ok",
+	ExitFailure:         "failure",
+	ExitUsage:           "usage",
+	ExitConfig:          "config_error",
+	ExitProcessNotFound: "process_not_found",
+	ExitSIPEnabled:      "sip_enabled",
+	ExitKeyInvalid:      "key_invalid",
+	ExitDecryptFailed:   "decrypt_failed",
+	ExitCanceled:        "canceled",
+}
+
+// ExitCode 返回错误对应的命令行退出码，错误链中最内层已分类的错误优先，如解密失败的原因是密钥错误时返回 ExitKeyInvalid
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	// 被取消的操作可能在各层被包装为其他错误，取消优先
+	if errors.Is(err, context.Canceled) {
+		return ExitCanceled
+	}
+	code := ExitFailure
+	for ; err != nil; err = errors.Unwrap(err) {
+		if appErr, ok := err.(*Error); ok && appErr.Exit != 0 {
+			code = appErr.Exit
+		}
+	}
+	return code
+}
+
+// ExitName 返回退出码的名称，用于结构化输出
+func ExitName(code int) string {
+	if name, ok := exitNames[code]; ok {
+		return name
+	}
+	return exitNames[ExitFailure]
+}
+
+func ConfigRequired(name string) *Error {
+	return Newf(nil, http.StatusBadRequest, "%s is required", name).WithExit(ExitConfig).WithReason(ReasonConfigRequired)
+}
+
+func ConfigInvalid(cause error) *Error {
+	return New(cause, http.StatusBadRequest, "invalid config").WithExit(ExitConfig).WithReason(ReasonConfigInvalid)
+}
@@ -0,0 +1,30 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, ExitOK},
+		{fmt.Errorf("other"), ExitFailure},
+		{ConfigRequired("workDir"), ExitConfig},
+		{fmt.Errorf("get key: %w", ErrSIPEnabled), ExitSIPEnabled},
+		{WeChatProcessNotFound(42), ExitProcessNotFound},
+		{DecryptFailed(fmt.Errorf("disk full")), ExitDecryptFailed},
+		{DecryptFailed(ErrDecryptIncorrectKey), ExitKeyInvalid},
+		{Wrap(ErrNoValidKey, "get key failed", 0), ExitKeyInvalid},
+		{fmt.Errorf("query: %w", context.Canceled), ExitCanceled},
+		{DecryptFailed(ErrDecryptOperationCanceled), ExitCanceled},
+	}
+	for _, tt := range tests {
+		if got := ExitCode(tt.err); got != tt.want {
+			t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
@@ -2,8 +2,18 @@ package errors
 
 import "net/http"
 
+var (
+	ErrUnauthorized = New(nil, http.StatusUnauthorized, "unauthorized").WithReason(ReasonUnauthorized)
+	ErrDBNotReady   = New(nil, http.StatusServiceUnavailable, "database is not ready").WithReason(ReasonDBNotReady)
+	ErrDBDecrypting = New(nil, http.StatusServiceUnavailable, "database is decrypting, please wait").WithReason(ReasonDBNotReady)
+)
+
 func InvalidArg(arg string) error {
-	return Newf(nil, http.StatusBadRequest, "invalid argument: %s", arg)
+	return Newf(nil, http.StatusBadRequest, "invalid argument: %s", arg).WithReason(ReasonInvalidArgument)
+}
+
+func DBStateError(msg string) error {
+	return Newf(nil, http.StatusServiceUnavailable, "database is error: %s", msg).WithReason(ReasonDBNotReady)
 }
 
 func HTTPShutDown(cause error) error {
@@ -54,7 +54,7 @@ func RecoveryMiddleware() gin.HandlerFunc {
 				log.Err(err).Msgf("PANIC RECOVERED\n%s", string(debug.Stack()))
 
 				// 返回 500 错误
-				c.JSON(http.StatusInternalServerError, err)
+				Err(c, err)
 				c.Abort()
 			}
 		}()
@@ -3,21 +3,25 @@ package errors
 import "net/http"
 
 func OpenFileFailed(path string, cause error) *Error {
-	return Newf(cause, http.StatusInternalServerError, "failed to open file: %s", path).WithStack()
+	return Newf(cause, http.StatusInternalServerError, "failed to open file: %s", path).WithReason(ReasonIOFailed).WithStack()
 }
 
 func StatFileFailed(path string, cause error) *Error {
-	return Newf(cause, http.StatusInternalServerError, "failed to stat file: %s", path).WithStack()
+	return Newf(cause, http.StatusInternalServerError, "failed to stat file: %s", path).WithReason(ReasonIOFailed).WithStack()
 }
 
 func ReadFileFailed(path string, cause error) *Error {
-	return Newf(cause, http.StatusInternalServerError, "failed to read file: %s", path).WithStack()
+	return Newf(cause, http.StatusInternalServerError, "failed to read file: %s", path).WithReason(ReasonIOFailed).WithStack()
 }
 
 func IncompleteRead(cause error) *Error {
-	return New(cause, http.StatusInternalServerError, "incomplete header read during decryption").WithStack()
+	return New(cause, http.StatusInternalServerError, "incomplete header read during decryption").WithReason(ReasonIOFailed).WithStack()
 }
 
 func WriteOutputFailed(cause error) *Error {
-	return New(cause, http.StatusInternalServerError, "failed to write output").WithStack()
+	return New(cause, http.StatusInternalServerError, "failed to write output").WithReason(ReasonIOFailed).WithStack()
+}
+
+func InsufficientSpace(path, need, free string) *Error {
+	return Newf(nil, http.StatusInsufficientStorage, "not enough disk space on %s: need %s, %s free; free up space, use another work dir, or decrypt part of the databases with --only", path, need, free).WithExit(ExitDecryptFailed).WithReason(ReasonInsufficientSpace)
 }
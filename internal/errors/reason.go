@@ -0,0 +1,56 @@
+package errors
+
+import (
+	"context"
+	"errors"
+)
+
+// 错误码，稳定的字符串，随错误从密钥获取、解密与数据库层传递到命令行的 JSON 输出与 HTTP 错误响应的 reason 字段，
+// 供脚本与客户端区分失败原因，只增加不修改
+const (
+	ReasonInternal            = "internal"             // 未分类的错误
+	ReasonInvalidArgument     = "invalid_argument"     // 请求或命令行参数无效
+	ReasonConfigRequired      = "config_required"      // 缺少必需的配置
+	ReasonConfigInvalid       = "config_invalid"       // 配置无效
+	ReasonDataDirInvalid      = "data_dir_invalid"     // 数据目录不存在或其中没有微信数据库
+	ReasonPlatformUnsupported = "platform_unsupported" // 不支持的平台或微信版本
+	ReasonProcessNotFound     = "process_not_found"    // 未找到微信进程
+	ReasonAccountNotFound     = "account_not_found"    // 未找到微信账号
+	ReasonAccountOffline      = "account_offline"      // 微信账号未登录
+	ReasonSIPEnabled          = "sip_enabled"          // macOS 未关闭 SIP
+	ReasonMemoryReadFailed    = "memory_read_failed"   // 读取进程内存失败
+	ReasonNoValidKey          = "no_valid_key"         // 进程内存中未找到有效密钥
+	ReasonInvalidKey          = "invalid_key"          // 密钥格式错误
+	ReasonIncorrectKey        = "incorrect_key"        // 密钥与数据库不匹配
+	ReasonAlreadyDecrypted    = "already_decrypted"    // 数据库已经是解密后的文件
+	ReasonDecryptFailed       = "decrypt_failed"       // 解密失败
+	ReasonCanceled            = "canceled"             // 操作被取消
+	ReasonIOFailed            = "io_failed"            // 读写文件失败
+	ReasonInsufficientSpace   = "insufficient_space"   // 工作目录所在磁盘空间不足
+	ReasonDBNotFound          = "db_not_found"         // 工作目录中没有需要的数据库
+	ReasonDBInitFailed        = "db_init_failed"       // 打开或初始化数据库失败
+	ReasonDBNotReady          = "db_not_ready"         // 数据库尚未就绪或正在解密
+	ReasonQueryFailed         = "query_failed"         // 查询数据库失败
+	ReasonNotFound            = "not_found"            // 聊天对象、联系人、群聊、媒体或时间范围不存在
+	ReasonUnauthorized        = "unauthorized"         // 认证失败
+)
+
+// WithReason 设置错误码
+func (e *Error) WithReason(reason string) *Error {
+	e.Reason = reason
+	return e
+}
+
+// ReasonOf 返回错误的错误码，与 ExitCode 相同，错误链中最内层已分类的错误优先
+func ReasonOf(err error) string {
+	if errors.Is(err, context.Canceled) {
+		return ReasonCanceled
+	}
+	reason := ReasonInternal
+	for ; err != nil; err = errors.Unwrap(err) {
+		if appErr, ok := err.(*Error); ok && len(appErr.Reason) != 0 {
+			reason = appErr.Reason
+		}
+	}
+	return reason
+}
@@ -0,0 +1,56 @@
+package errors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestReasonOf(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{fmt.Errorf("other"), ReasonInternal},
+		{fmt.Errorf("get key: %w", ErrSIPEnabled), ReasonSIPEnabled},
+		{Wrap(ErrNoValidKey, "get key failed", 0), ReasonNoValidKey},
+		{DecryptFailed(ErrDecryptIncorrectKey), ReasonIncorrectKey},
+		{DecryptFailed(fmt.Errorf("disk full")), ReasonDecryptFailed},
+		{DataDirInvalid("/tmp", os.ErrNotExist), ReasonDataDirInvalid},
+		{DBInitFailed(DBFileNotFound("/tmp", "message", nil)), ReasonDBNotFound},
+		{QueryFailed("select", context.Canceled), ReasonCanceled},
+		{InsufficientSpace("/tmp", "2.0 GB", "1.0 GB"), ReasonInsufficientSpace},
+	}
+	for _, tt := range tests {
+		if got := ReasonOf(tt.err); got != tt.want {
+			t.Errorf("ReasonOf(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+	if code := ExitCode(DataDirInvalid("/tmp", nil)); code != ExitConfig {
+		t.Errorf("data dir exit code %d", code)
+	}
+}
+
+func TestErr(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	Err(c, fmt.Errorf("query: %w", TalkerNotFound("wxid_x")))
+
+	var body struct {
+		Error  string `json:"error"`
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusNotFound || body.Reason != ReasonNotFound || body.Error != "query: talker not found: wxid_x" {
+		t.Fatalf("got %d %+v", w.Code, body)
+	}
+}
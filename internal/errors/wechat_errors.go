@@ -3,29 +3,43 @@ package errors
 import "net/http"
 
 var (
-	ErrAlreadyDecrypted              = New(nil, http.StatusBadRequest, "database file is already decrypted")
-	ErrDecryptHashVerificationFailed = New(nil, http.StatusBadRequest, "hash verification failed during decryption")
-	ErrDecryptIncorrectKey           = New(nil, http.StatusBadRequest, "incorrect decryption key")
-	ErrDecryptOperationCanceled      = New(nil, http.StatusBadRequest, "decryption operation was canceled")
-	ErrNoMemoryRegionsFound          = New(nil, http.StatusBadRequest, "no memory regions found")
-	ErrReadMemoryTimeout             = New(nil, http.StatusInternalServerError, "read memory timeout")
-	ErrWeChatOffline                 = New(nil, http.StatusBadRequest, "WeChat is offline")
-	ErrSIPEnabled                    = New(nil, http.StatusBadRequest, "SIP is enabled")
+	ErrAlreadyDecrypted              = New(nil, http.StatusBadRequest, "database file is already decrypted").WithReason(ReasonAlreadyDecrypted)
+	ErrDecryptHashVerificationFailed = New(nil, http.StatusBadRequest, "hash verification failed during decryption").WithExit(ExitKeyInvalid).WithReason(ReasonIncorrectKey)
+	ErrDecryptIncorrectKey           = New(nil, http.StatusBadRequest, "incorrect decryption key").WithExit(ExitKeyInvalid).WithReason(ReasonIncorrectKey)
+	ErrDecryptOperationCanceled      = New(nil, http.StatusBadRequest, "decryption operation was canceled").WithExit(ExitCanceled).WithReason(ReasonCanceled)
+	ErrNoMemoryRegionsFound          = New(nil, http.StatusBadRequest, "no memory regions found").WithReason(ReasonMemoryReadFailed)
+	ErrReadMemoryTimeout             = New(nil, http.StatusInternalServerError, "read memory timeout").WithReason(ReasonMemoryReadFailed)
+	ErrWeChatOffline                 = New(nil, http.StatusBadRequest, "WeChat is offline").WithReason(ReasonAccountOffline)
+	ErrSIPEnabled                    = New(nil, http.StatusBadRequest, "SIP is enabled").WithExit(ExitSIPEnabled).WithReason(ReasonSIPEnabled)
 	ErrValidatorNotSet               = New(nil, http.StatusBadRequest, "validator not set")
-	ErrNoValidKey                    = New(nil, http.StatusBadRequest, "no valid key found")
-	ErrWeChatDLLNotFound             = New(nil, http.StatusBadRequest, "WeChatWin.dll module not found")
+	ErrNoValidKey                    = New(nil, http.StatusBadRequest, "no valid key found").WithExit(ExitKeyInvalid).WithReason(ReasonNoValidKey)
+	ErrWeChatDLLNotFound             = New(nil, http.StatusBadRequest, "WeChatWin.dll module not found").WithReason(ReasonPlatformUnsupported)
+	ErrWeChatProcessNotFound         = New(nil, http.StatusNotFound, "wechat process not found").WithExit(ExitProcessNotFound).WithReason(ReasonProcessNotFound)
 )
 
 func PlatformUnsupported(platform string, version int) *Error {
-	return Newf(nil, http.StatusBadRequest, "unsupported platform: %s v%d", platform, version).WithStack()
+	return Newf(nil, http.StatusBadRequest, "unsupported platform: %s v%d", platform, version).WithReason(ReasonPlatformUnsupported).WithStack()
+}
+
+func WeChatProcessNotFound(pid int) *Error {
+	return Newf(nil, http.StatusNotFound, "wechat process not found: %d", pid).WithExit(ExitProcessNotFound).WithReason(ReasonProcessNotFound)
+}
+
+// DataDirInvalid 数据目录不存在，或其中没有当前平台与版本的微信数据库
+func DataDirInvalid(dataDir string, cause error) *Error {
+	return Newf(cause, http.StatusBadRequest, "invalid data dir: %s", dataDir).WithExit(ExitConfig).WithReason(ReasonDataDirInvalid)
+}
+
+func DecryptFailed(cause error) *Error {
+	return New(cause, http.StatusInternalServerError, "decrypt failed").WithExit(ExitDecryptFailed).WithReason(ReasonDecryptFailed)
 }
 
 func DecryptCreateCipherFailed(cause error) *Error {
-	return New(cause, http.StatusInternalServerError, "failed to create cipher").WithStack()
+	return New(cause, http.StatusInternalServerError, "failed to create cipher").WithReason(ReasonDecryptFailed).WithStack()
 }
 
 func DecodeKeyFailed(cause error) *Error {
-	return New(cause, http.StatusBadRequest, "failed to decode hex key").WithStack()
+	return New(cause, http.StatusBadRequest, "failed to decode hex key").WithExit(ExitKeyInvalid).WithReason(ReasonInvalidKey).WithStack()
 }
 
 func CreatePipeFileFailed(cause error) *Error {
@@ -45,19 +59,19 @@ func RunCmdFailed(cause error) *Error {
 }
 
 func ReadMemoryFailed(cause error) *Error {
-	return New(cause, http.StatusInternalServerError, "failed to read memory").WithStack()
+	return New(cause, http.StatusInternalServerError, "failed to read memory").WithReason(ReasonMemoryReadFailed).WithStack()
 }
 
 func OpenProcessFailed(cause error) *Error {
-	return New(cause, http.StatusInternalServerError, "failed to open process").WithStack()
+	return New(cause, http.StatusInternalServerError, "failed to open process").WithReason(ReasonMemoryReadFailed).WithStack()
 }
 
 func WeChatAccountNotFound(name string) *Error {
-	return Newf(nil, http.StatusBadRequest, "WeChat account not found: %s", name).WithStack()
+	return Newf(nil, http.StatusBadRequest, "WeChat account not found: %s", name).WithExit(ExitProcessNotFound).WithReason(ReasonAccountNotFound).WithStack()
 }
 
 func WeChatAccountNotOnline(name string) *Error {
-	return Newf(nil, http.StatusBadRequest, "WeChat account is not online: %s", name).WithStack()
+	return Newf(nil, http.StatusBadRequest, "WeChat account is not online: %s", name).WithReason(ReasonAccountOffline).WithStack()
 }
 
 func RefreshProcessStatusFailed(cause error) *Error {
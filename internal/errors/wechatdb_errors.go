@@ -6,27 +6,27 @@ import (
 )
 
 var (
-	ErrTalkerEmpty     = New(nil, http.StatusBadRequest, "talker empty").WithStack()
-	ErrKeyEmpty        = New(nil, http.StatusBadRequest, "key empty").WithStack()
-	ErrMediaNotFound   = New(nil, http.StatusNotFound, "media not found").WithStack()
-	ErrKeyLengthMust32 = New(nil, http.StatusBadRequest, "key length must be 32 bytes").WithStack()
+	ErrTalkerEmpty     = New(nil, http.StatusBadRequest, "talker empty").WithReason(ReasonInvalidArgument).WithStack()
+	ErrKeyEmpty        = New(nil, http.StatusBadRequest, "key empty").WithReason(ReasonInvalidArgument).WithStack()
+	ErrMediaNotFound   = New(nil, http.StatusNotFound, "media not found").WithReason(ReasonNotFound).WithStack()
+	ErrKeyLengthMust32 = New(nil, http.StatusBadRequest, "key length must be 32 bytes").WithReason(ReasonInvalidKey).WithStack()
 )
 
 // 数据库初始化相关错误
 func DBFileNotFound(path, pattern string, cause error) *Error {
-	return Newf(cause, http.StatusNotFound, "db file not found %s: %s", path, pattern).WithStack()
+	return Newf(cause, http.StatusNotFound, "db file not found %s: %s", path, pattern).WithReason(ReasonDBNotFound).WithStack()
 }
 
 func DBConnectFailed(path string, cause error) *Error {
-	return Newf(cause, http.StatusInternalServerError, "db connect failed: %s", path).WithStack()
+	return Newf(cause, http.StatusInternalServerError, "db connect failed: %s", path).WithReason(ReasonDBInitFailed).WithStack()
 }
 
 func DBInitFailed(cause error) *Error {
-	return New(cause, http.StatusInternalServerError, "db init failed").WithStack()
+	return New(cause, http.StatusInternalServerError, "db init failed").WithReason(ReasonDBInitFailed).WithStack()
 }
 
 func TalkerNotFound(talker string) *Error {
-	return Newf(nil, http.StatusNotFound, "talker not found: %s", talker).WithStack()
+	return Newf(nil, http.StatusNotFound, "talker not found: %s", talker).WithReason(ReasonNotFound).WithStack()
 }
 
 func DBCloseFailed(cause error) *Error {
@@ -34,27 +34,27 @@ func DBCloseFailed(cause error) *Error {
 }
 
 func QueryFailed(query string, cause error) *Error {
-	return Newf(cause, http.StatusInternalServerError, "query failed: %s", query).WithStack()
+	return Newf(cause, http.StatusInternalServerError, "query failed: %s", query).WithReason(ReasonQueryFailed).WithStack()
 }
 
 func ScanRowFailed(cause error) *Error {
-	return New(cause, http.StatusInternalServerError, "scan row failed").WithStack()
+	return New(cause, http.StatusInternalServerError, "scan row failed").WithReason(ReasonQueryFailed).WithStack()
 }
 
 func TimeRangeNotFound(start, end time.Time) *Error {
-	return Newf(nil, http.StatusNotFound, "time range not found: %s - %s", start, end).WithStack()
+	return Newf(nil, http.StatusNotFound, "time range not found: %s - %s", start, end).WithReason(ReasonNotFound).WithStack()
 }
 
 func MediaTypeUnsupported(_type string) *Error {
-	return Newf(nil, http.StatusBadRequest, "unsupported media type: %s", _type).WithStack()
+	return Newf(nil, http.StatusBadRequest, "unsupported media type: %s", _type).WithReason(ReasonInvalidArgument).WithStack()
 }
 
 func ChatRoomNotFound(key string) *Error {
-	return Newf(nil, http.StatusNotFound, "chat room not found: %s", key).WithStack()
+	return Newf(nil, http.StatusNotFound, "chat room not found: %s", key).WithReason(ReasonNotFound).WithStack()
 }
 
 func ContactNotFound(key string) *Error {
-	return Newf(nil, http.StatusNotFound, "contact not found: %s", key).WithStack()
+	return Newf(nil, http.StatusNotFound, "contact not found: %s", key).WithReason(ReasonNotFound).WithStack()
 }
 
 func InitCacheFailed(cause error) *Error {
@@ -62,5 +62,5 @@ func InitCacheFailed(cause error) *Error {
 }
 
 func FileGroupNotFound(name string) *Error {
-	return Newf(nil, http.StatusNotFound, "file group not found: %s", name).WithStack()
+	return Newf(nil, http.StatusNotFound, "file group not found: %s", name).WithReason(ReasonDBNotFound).WithStack()
 }
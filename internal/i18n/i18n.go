@@ -0,0 +1,117 @@
+package i18n
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// 界面文字与错误信息以简体中文写在代码中，并作为翻译的键，其他语言在 locales 中按中文原文查找
+// 缺少翻译时显示中文原文
+const (
+	ZhCN = "zh-CN"
+	EN   = "en"
+
+	// EnvLang 指定界面语言的环境变量，优先于系统区域设置
+	EnvLang = "CHATLOG_LANG"
+)
+
+// Languages 支持的语言
+var Languages = []string{ZhCN, EN}
+
+//go:embed locales/en.json
+var enJSON []byte
+
+var bundles = map[string]map[string]string{}
+
+var current atomic.Value
+
+func init() {
+	en := make(map[string]string)
+	if err := json.Unmarshal(enJSON, &en); err != nil {
+		panic(fmt.Sprintf("i18n: invalid en bundle: %v", err))
+	}
+	bundles[EN] = en
+	current.Store(Detect())
+}
+
+// Normalize 将 zh_CN.UTF-8、en-US 等语言标识规范为支持的语言，无法识别时返回空字符串
+func Normalize(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, ".@"); i >= 0 {
+		lang = lang[:i]
+	}
+	switch {
+	case len(lang) == 0:
+		return ""
+	case lang == "zh" || strings.HasPrefix(lang, "zh-") || strings.HasPrefix(lang, "zh_"):
+		return ZhCN
+	case lang == "en" || strings.HasPrefix(lang, "en-") || strings.HasPrefix(lang, "en_"):
+		return EN
+	}
+	return ""
+}
+
+// Detect 按 CHATLOG_LANG、LC_ALL、LC_MESSAGES、LANG 的顺序检测界面语言
+// 区域设置为其他非中文语言时使用英文，未设置或为 C/POSIX 时使用简体中文
+func Detect() string {
+	if lang := Normalize(os.Getenv(EnvLang)); len(lang) != 0 {
+		return lang
+	}
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		v := strings.TrimSpace(os.Getenv(env))
+		if len(v) == 0 {
+			continue
+		}
+		if v == "C" || v == "POSIX" || strings.HasPrefix(v, "C.") {
+			return ZhCN
+		}
+		if lang := Normalize(v); len(lang) != 0 {
+			return lang
+		}
+		return EN
+	}
+	return ZhCN
+}
+
+// Set 设置界面语言，为空时按环境检测
+func Set(lang string) error {
+	if len(strings.TrimSpace(lang)) == 0 {
+		current.Store(Detect())
+		return nil
+	}
+	l := Normalize(lang)
+	if len(l) == 0 {
+		return fmt.Errorf("unsupported language %q, use one of %s", lang, strings.Join(Languages, ", "))
+	}
+	current.Store(l)
+	return nil
+}
+
+// Lang 返回当前界面语言
+func Lang() string {
+	return current.Load().(string)
+}
+
+// T 返回 msg 在当前语言中的翻译
+func T(msg string) string {
+	if bundle, ok := bundles[Lang()]; ok {
+		if s, ok := bundle[msg]; ok && len(s) != 0 {
+			return s
+		}
+	}
+	return msg
+}
+
+// Tf 翻译格式字符串后格式化
+func Tf(format string, args ...any) string {
+	return fmt.Sprintf(T(format), args...)
+}
+
+// Errorf 翻译格式字符串后创建错误，支持 %w
+func Errorf(format string, args ...any) error {
+	return fmt.Errorf(T(format), args...)
+}
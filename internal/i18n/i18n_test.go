@@ -0,0 +1,111 @@
+package i18n
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		lang string
+		want string
+	}{
+		{"", ""},
+		{"zh", ZhCN},
+		{"zh-CN", ZhCN},
+		{"zh_CN.UTF-8", ZhCN},
+		{"zh_TW", ZhCN},
+		{"en", EN},
+		{"en_US.UTF-8", EN},
+		{"EN-gb", EN},
+		{"fr_FR", ""},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.lang); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		chatlog, lcAll, lang string
+		want                 string
+	}{
+		{"", "", "", ZhCN},
+		{"", "", "C", ZhCN},
+		{"", "", "en_US.UTF-8", EN},
+		{"", "", "fr_FR.UTF-8", EN},
+		{"", "zh_CN.UTF-8", "en_US.UTF-8", ZhCN},
+		{"en", "zh_CN.UTF-8", "", EN},
+	}
+	for _, tt := range tests {
+		t.Setenv(EnvLang, tt.chatlog)
+		t.Setenv("LC_ALL", tt.lcAll)
+		t.Setenv("LC_MESSAGES", "")
+		t.Setenv("LANG", tt.lang)
+		if got := Detect(); got != tt.want {
+			t.Errorf("Detect() with CHATLOG_LANG=%q LC_ALL=%q LANG=%q = %q, want %q", tt.chatlog, tt.lcAll, tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestT(t *testing.T) {
+	defer current.Store(Lang())
+
+	Set(ZhCN)
+	if got := T("取消"); got != "取消" {
+		t.Errorf("T(取消) in zh-CN = %q", got)
+	}
+	Set(EN)
+	if got := T("取消"); got != "Cancel" {
+		t.Errorf("T(取消) in en = %q", got)
+	}
+	if got := T("没有翻译的文字"); got != "没有翻译的文字" {
+		t.Errorf("T falls back to source, got %q", got)
+	}
+	if err := Set("fr"); err == nil {
+		t.Error("Set(fr) should fail")
+	}
+}
+
+// TestBundleComplete 检查代码中所有 T、Tf、Errorf 调用的文字都有英文翻译，且格式动词一致
+func TestBundleComplete(t *testing.T) {
+	call := regexp.MustCompile(`i18n\.(?:T|Tf|Errorf)\(("(?:[^"\\\n]|\\.)*")`)
+	verb := regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z%]`)
+
+	root := filepath.Join("..", "..")
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, m := range call.FindAllStringSubmatch(string(data), -1) {
+			msg, err := strconv.Unquote(m[1])
+			if err != nil {
+				t.Errorf("%s: %v", path, err)
+				continue
+			}
+			tr, ok := bundles[EN][msg]
+			if !ok {
+				t.Errorf("%s: missing en translation for %q", path, msg)
+				continue
+			}
+			if a, b := verb.FindAllString(msg, -1), verb.FindAllString(tr, -1); strings.Join(a, "") != strings.Join(b, "") {
+				t.Errorf("%s: format verbs of %q do not match translation %q", path, msg, tr)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
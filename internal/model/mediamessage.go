@@ -14,6 +14,12 @@ type MediaMsg struct {
 	App      App      `xml:"appmsg,omitempty"`
 	Emoji    Emoji    `xml:"emoji,omitempty"`
 	Location Location `xml:"location,omitempty"`
+	Voice    VoiceMsg `xml:"voicemsg,omitempty"`
+}
+
+// VoiceMsg 语音消息
+type VoiceMsg struct {
+	VoiceLength int `xml:"voicelength,attr"` // 语音时长，单位毫秒
 }
 
 type Image struct {
@@ -166,6 +166,10 @@ func (m *Message) ParseMediaInfo(data string) error {
 	switch m.Type {
 	case MessageTypeImage:
 		m.Contents["md5"] = msg.Image.MD5
+	case MessageTypeVoice:
+		if msg.Voice.VoiceLength > 0 {
+			m.Contents["voicelength"] = msg.Voice.VoiceLength
+		}
 	case MessageTypeVideo:
 		if msg.Video.Md5 != "" {
 			m.Contents["md5"] = msg.Video.Md5
@@ -345,6 +349,9 @@ func (m *Message) PlainText(showChatRoom bool, timeFormat string, host string) s
 func (m *Message) PlainTextContent() string {
 	switch m.Type {
 	case MessageTypeText:
+		if translation, ok := m.Contents["translation"].(string); ok && len(translation) != 0 {
+			return m.Content + "\n↳ " + translation
+		}
 		return m.Content
 	case MessageTypeImage:
 		keylist := make([]string, 0)
@@ -363,12 +370,20 @@ func (m *Message) PlainTextContent() string {
 				keylist = append(keylist, thumbpath)
 			}
 		}
-		return fmt.Sprintf("![图片](http://%s/image/%s)", m.Contents["host"], strings.Join(keylist, ","))
+		text := fmt.Sprintf("![图片](http://%s/image/%s)", m.Contents["host"], strings.Join(keylist, ","))
+		if ocr, ok := m.Contents["ocr"].(string); ok && len(ocr) != 0 {
+			text += " " + strings.Join(strings.Fields(ocr), " ")
+		}
+		return text
 	case MessageTypeVoice:
+		text := "[语音]"
 		if voice, ok := m.Contents["voice"]; ok {
-			return fmt.Sprintf("[语音](http://%s/voice/%s)", m.Contents["host"], voice)
+			text = fmt.Sprintf("[语音](http://%s/voice/%s)", m.Contents["host"], voice)
+		}
+		if transcript, ok := m.Contents["transcript"].(string); ok && len(transcript) != 0 {
+			text += " " + transcript
 		}
-		return "[语音]"
+		return text
 	case MessageTypeCard:
 		return "[名片]"
 	case MessageTypeVideo:
@@ -0,0 +1,104 @@
+package model
+
+import (
+	"sort"
+	"strconv"
+)
+
+// Stats 账号级别的统计信息
+type Stats struct {
+	Total      int            `json:"total"`      // 消息总数
+	Sessions   int            `json:"sessions"`   // 参与统计的会话数
+	ByYear     map[string]int `json:"byYear"`     // 按年统计，key 为 2006
+	ByMonth    map[string]int `json:"byMonth"`    // 按月统计，key 为 2006-01
+	Media      map[string]int `json:"media"`      // 多媒体消息统计，key 为媒体类型
+	TopContact []*StatsItem   `json:"topContact"` // 消息最多的联系人
+	TopRoom    []*StatsItem   `json:"topRoom"`    // 消息最多的群聊
+}
+
+// StatsItem 单个会话的统计信息
+type StatsItem struct {
+	UserName string `json:"userName"`
+	Name     string `json:"name"`
+	Count    int    `json:"count"`
+}
+
+func NewStats() *Stats {
+	return &Stats{
+		ByYear:     make(map[string]int),
+		ByMonth:    make(map[string]int),
+		Media:      make(map[string]int),
+		TopContact: make([]*StatsItem, 0),
+		TopRoom:    make([]*StatsItem, 0),
+	}
+}
+
+// MessageCount 按会话、日期和消息类型聚合的消息数量，由数据源在 SQL 中统计
+type MessageCount struct {
+	Talker  string
+	Day     string // 本地时区的日期，格式为 2006-01-02
+	Type    int64
+	SubType int64
+	Count   int
+}
+
+// Add 将一条消息计入统计
+func (s *Stats) Add(m *Message) {
+	s.Total++
+	s.ByYear[strconv.Itoa(m.Time.Year())]++
+	s.ByMonth[m.Time.Format("2006-01")]++
+	if media := m.MediaType(); media != "" {
+		s.Media[media]++
+	}
+}
+
+// AddCount 将一组聚合的消息数量计入统计
+func (s *Stats) AddCount(c *MessageCount) {
+	if len(c.Day) < len("2006-01-02") {
+		return
+	}
+	s.Total += c.Count
+	s.ByYear[c.Day[:4]] += c.Count
+	s.ByMonth[c.Day[:7]] += c.Count
+	if media := MediaType(c.Type, c.SubType); media != "" {
+		s.Media[media] += c.Count
+	}
+}
+
+// MediaType 返回消息对应的媒体类型，非多媒体消息返回空字符串
+func (m *Message) MediaType() string {
+	return MediaType(m.Type, m.SubType)
+}
+
+// MediaType 返回消息类型对应的媒体类型，非多媒体消息返回空字符串
+func MediaType(_type, subType int64) string {
+	switch _type {
+	case MessageTypeImage:
+		return "image"
+	case MessageTypeVoice:
+		return "voice"
+	case MessageTypeVideo:
+		return "video"
+	case MessageTypeAnimation:
+		return "animation"
+	case MessageTypeShare:
+		if subType == MessageSubTypeFile {
+			return "file"
+		}
+	}
+	return ""
+}
+
+// SortStatsItems 按消息数量降序排序，并截取前 top 项
+func SortStatsItems(items []*StatsItem, top int) []*StatsItem {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count == items[j].Count {
+			return items[i].UserName < items[j].UserName
+		}
+		return items[i].Count > items[j].Count
+	})
+	if top > 0 && len(items) > top {
+		items = items[:top]
+	}
+	return items
+}
@@ -0,0 +1,912 @@
+package chat
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/errors"
+	"github.com/DanielMao1/chatlog/internal/i18n"
+	"github.com/DanielMao1/chatlog/internal/model"
+	"github.com/DanielMao1/chatlog/internal/ui/keymap"
+	"github.com/DanielMao1/chatlog/internal/ui/style"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const (
+	Title = "chat"
+
+	// PageSize 每次向前加载的最少消息数
+	PageSize = 100
+
+	// Window 向前加载消息的初始时间窗口，没有消息时逐步扩大
+	Window = 7 * 24 * time.Hour
+
+	// MaxWindow 单次加载的最大时间窗口
+	MaxWindow = 365 * 24 * time.Hour
+
+	// HitContext 从搜索结果打开会话时，命中消息之后加载的时长
+	HitContext = 24 * time.Hour
+
+	// MinPaneWidth 会话列表与消息窗格的最小宽度
+	MinPaneWidth = 16
+
+	// ResizeStep 每次按键调整会话列表宽度的步长
+	ResizeStep = 4
+
+	// SelfIndent 自己发送的消息向右缩进的宽度，与对方的消息区分
+	SelfIndent = 8
+
+	// SessionPageSize 每次加载的会话数，选中列表末尾时加载下一页
+	SessionPageSize = 100
+)
+
+// Earliest 早于该时间不再加载消息
+var Earliest = time.Date(2011, 1, 1, 0, 0, 0, 0, time.Local)
+
+// Source 消息浏览的数据来源
+type Source interface {
+	BrowseSessions(keyword string, limit, offset int) ([]*model.Session, error)
+	BrowseMessages(talker string, start, end time.Time) ([]*model.Message, error)
+}
+
+// Browser 会话列表与消息阅读视图
+type Browser struct {
+	*tview.Flex
+	src      Source
+	queue    func(func())
+	setFocus func(tview.Primitive)
+	done     func()
+	pick     func(open func(talker, name string))
+	preview  func(msg *model.Message)
+	copy     func(msg *model.Message, path bool)
+	path     func(msg *model.Message) (string, error)
+	mention  func(talker, self string) []string
+	pinned   func() []string
+	pin      func(talker string, pin bool) error
+
+	body        *tview.Flex
+	sessionPane *tview.Flex
+	filter      *tview.InputField
+	list        *tview.List
+	messages    *tview.TextView
+
+	// dragging 是否正在拖动窗格之间的边框
+	dragging bool
+
+	sessions []*model.Session
+	// fetched 已从数据库加载的会话数，more 表示可能还有更多会话
+	fetched         int
+	more            bool
+	loadingSessions bool
+
+	talker   string
+	name     string
+	loaded   []*model.Message
+	start    time.Time
+	loading  bool
+	finished bool
+	// hit 从搜索结果打开时命中消息的序号，加载后高亮并滚动到该消息
+	hit int64
+	// paths 已展开显示媒体文件路径的消息，键为消息序号
+	paths map[int64]string
+	// kind 当前的消息类型筛选
+	kind Filter
+	// restore 恢复界面状态时首次加载后滚动到的行，-1 表示不需要恢复
+	restore int
+	// self 自己在当前会话中可能被 @ 的名称，mentioned 表示已经查询过
+	self      []string
+	mentioned bool
+}
+
+// New 创建消息浏览视图，queue 用于在 UI 线程中执行更新，done 在按 ESC 退出时调用
+func New(src Source, queue func(func()), setFocus func(tview.Primitive), done func()) *Browser {
+	b := &Browser{
+		Flex:     tview.NewFlex(),
+		src:      src,
+		queue:    queue,
+		setFocus: setFocus,
+		done:     done,
+		filter:   tview.NewInputField(),
+		list:     tview.NewList(),
+		messages: tview.NewTextView(),
+		restore:  -1,
+	}
+
+	b.filter.
+		SetLabel(i18n.T("搜索: ")).
+		SetFieldBackgroundColor(style.InputFieldBgColor).
+		SetDoneFunc(func(key tcell.Key) {
+			b.LoadSessions()
+			b.setFocus(b.list)
+		})
+
+	b.list.
+		ShowSecondaryText(true).
+		SetHighlightFullLine(true).
+		SetSelectedStyle(style.SelectedStyle).
+		SetSelectedFunc(func(index int, _ string, _ string, _ rune) {
+			if index < len(b.sessions) {
+				b.open(b.sessions[index])
+			}
+		}).
+		SetChangedFunc(func(index int, _ string, _ string, _ rune) {
+			if index >= len(b.sessions)-1 {
+				b.loadMoreSessions()
+			}
+		})
+
+	b.sessionPane = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(b.filter, 1, 0, false).
+		AddItem(b.list, 0, 1, true)
+	b.sessionPane.
+		SetBorder(true).
+		SetBorderColor(style.BorderColor).
+		SetTitle(i18n.T(" 会话 "))
+
+	b.messages.
+		SetDynamicColors(true).
+		SetRegions(true).
+		SetWrap(true).
+		SetScrollable(true).
+		SetBorder(true).
+		SetBorderColor(style.BorderColor).
+		SetTitle(i18n.T(" 消息 "))
+
+	help := tview.NewTextView().
+		SetDynamicColors(true).
+		SetTextAlign(tview.AlignCenter)
+	fmt.Fprintf(help,
+		i18n.T("[%s::b]%s[%s::b]: 打开会话  [%s::b]%s[%s::b]: 切换窗格  [%s::b]%s[%s::b]: 搜索  [%s::b]%s[%s::b]: 联系人  [%s::b]%s[%s::b]: 加载更早消息  [%s::b]%s/%s[%s::b]: 选择/预览图片  [%s::b]%s/%s[%s::b]: 复制消息/路径  [%s::b]%s[%s::b]: 显示路径  [%s::b]%s[%s::b]: 筛选  [%s::b]%s[%s::b]: 置顶  [%s::b]%s/%s[%s::b]: 调整宽度  [%s::b]%s[%s::b]: 返回"),
+		style.GetColorHex(style.MenuBgColor), tview.Escape(keymap.Label(keymap.Select)), style.GetColorHex(style.PageHeaderFgColor),
+		style.GetColorHex(style.MenuBgColor), tview.Escape(keymap.Label(keymap.SwitchPane)), style.GetColorHex(style.PageHeaderFgColor),
+		style.GetColorHex(style.MenuBgColor), tview.Escape(keymap.Label(keymap.Search)), style.GetColorHex(style.PageHeaderFgColor),
+		style.GetColorHex(style.MenuBgColor), tview.Escape(keymap.Label(keymap.Contacts)), style.GetColorHex(style.PageHeaderFgColor),
+		style.GetColorHex(style.MenuBgColor), tview.Escape(keymap.Label(keymap.Earlier)), style.GetColorHex(style.PageHeaderFgColor),
+		style.GetColorHex(style.MenuBgColor), tview.Escape(keymap.Label(keymap.NextImage)), tview.Escape(keymap.Label(keymap.Preview)), style.GetColorHex(style.PageHeaderFgColor),
+		style.GetColorHex(style.MenuBgColor), tview.Escape(keymap.Label(keymap.CopyMessage)), tview.Escape(keymap.Label(keymap.CopyMediaPath)), style.GetColorHex(style.PageHeaderFgColor),
+		style.GetColorHex(style.MenuBgColor), tview.Escape(keymap.Label(keymap.ShowPath)), style.GetColorHex(style.PageHeaderFgColor),
+		style.GetColorHex(style.MenuBgColor), tview.Escape(keymap.Label(keymap.Filter)), style.GetColorHex(style.PageHeaderFgColor),
+		style.GetColorHex(style.MenuBgColor), tview.Escape(keymap.Label(keymap.Pin)), style.GetColorHex(style.PageHeaderFgColor),
+		style.GetColorHex(style.MenuBgColor), tview.Escape(keymap.Label(keymap.Narrow)), tview.Escape(keymap.Label(keymap.Widen)), style.GetColorHex(style.PageHeaderFgColor),
+		style.GetColorHex(style.MenuBgColor), tview.Escape(keymap.Label(keymap.Back)), style.GetColorHex(style.PageHeaderFgColor),
+	)
+
+	b.body = tview.NewFlex().
+		AddItem(b.sessionPane, 0, 1, true).
+		AddItem(b.messages, 0, 2, false)
+
+	b.Flex.SetDirection(tview.FlexRow).
+		AddItem(b.body, 0, 1, true).
+		AddItem(help, 1, 0, false)
+
+	b.Flex.SetInputCapture(b.inputCapture)
+
+	return b
+}
+
+// SetPickFunc 设置按 c 时选择联系人或群聊的方式，选中后调用 open 打开对应的消息
+func (b *Browser) SetPickFunc(pick func(open func(talker, name string))) {
+	b.pick = pick
+}
+
+// SetPreviewFunc 设置预览图片消息的方式，未设置时不能预览
+func (b *Browser) SetPreviewFunc(preview func(msg *model.Message)) {
+	b.preview = preview
+}
+
+// SetCopyFunc 设置复制选中消息的方式，path 为 true 时复制媒体文件路径，否则复制消息文本
+func (b *Browser) SetCopyFunc(copy func(msg *model.Message, path bool)) {
+	b.copy = copy
+}
+
+// SetPathFunc 设置获取媒体文件路径的方式，未设置时不能显示路径
+func (b *Browser) SetPathFunc(path func(msg *model.Message) (string, error)) {
+	b.path = path
+}
+
+// SetMentionFunc 设置查询自己在会话中的名称的方式，用于筛选提到我的消息，self 为自己的微信 ID
+func (b *Browser) SetMentionFunc(mention func(talker, self string) []string) {
+	b.mention = mention
+}
+
+// SetPinFunc 设置读取与修改置顶会话的方式，置顶的会话显示在列表最前面，未设置时不能置顶
+func (b *Browser) SetPinFunc(pinned func() []string, pin func(talker string, pin bool) error) {
+	b.pinned = pinned
+	b.pin = pin
+}
+
+// Focus 默认聚焦会话列表
+func (b *Browser) Focus(delegate func(p tview.Primitive)) {
+	delegate(b.list)
+}
+
+func (b *Browser) inputCapture(event *tcell.EventKey) *tcell.EventKey {
+	if b.filter.HasFocus() {
+		if event.Key() == tcell.KeyEscape {
+			b.setFocus(b.list)
+			return nil
+		}
+		return event
+	}
+
+	switch {
+	case event.Key() == tcell.KeyBacktab || keymap.Match(event, keymap.SwitchPane):
+		if b.list.HasFocus() {
+			b.setFocus(b.messages)
+		} else {
+			b.setFocus(b.list)
+		}
+		return nil
+	case event.Key() == tcell.KeyEscape:
+		if b.messages.HasFocus() {
+			b.setFocus(b.list)
+			return nil
+		}
+		if b.done != nil {
+			b.done()
+		}
+		return nil
+	case keymap.Match(event, keymap.Search):
+		b.setFocus(b.filter)
+		return nil
+	case keymap.Match(event, keymap.Contacts) && b.pick != nil:
+		b.pick(b.Open)
+		return nil
+	case keymap.Match(event, keymap.Earlier) && b.messages.HasFocus():
+		b.loadEarlier()
+		return nil
+	case keymap.Match(event, keymap.NextImage) && b.messages.HasFocus():
+		b.nextImage()
+		return nil
+	case keymap.Match(event, keymap.Preview) && b.messages.HasFocus():
+		b.previewImage()
+		return nil
+	case keymap.Match(event, keymap.NextMessage) && b.messages.HasFocus():
+		b.selectMessage(1)
+		return nil
+	case keymap.Match(event, keymap.PrevMessage) && b.messages.HasFocus():
+		b.selectMessage(-1)
+		return nil
+	case (keymap.Match(event, keymap.CopyMessage) || keymap.Match(event, keymap.CopyMediaPath)) && b.messages.HasFocus():
+		if msg := b.Selected(); msg != nil && b.copy != nil {
+			b.copy(msg, keymap.Match(event, keymap.CopyMediaPath))
+		}
+		return nil
+	case keymap.Match(event, keymap.ShowPath) && b.messages.HasFocus():
+		b.togglePath()
+		return nil
+	case keymap.Match(event, keymap.Filter) && b.messages.HasFocus():
+		b.setFilter(b.kind.Next())
+		return nil
+	case keymap.Match(event, keymap.Pin) && b.pin != nil:
+		b.togglePin()
+		return nil
+	case keymap.Match(event, keymap.Narrow):
+		b.resize(b.currentWidth() - ResizeStep)
+		return nil
+	case keymap.Match(event, keymap.Widen):
+		b.resize(b.currentWidth() + ResizeStep)
+		return nil
+	}
+	return event
+}
+
+// MouseHandler 拖动会话列表与消息窗格之间的边框调整宽度，其余事件交给子组件处理
+func (b *Browser) MouseHandler() func(action tview.MouseAction, event *tcell.EventMouse, setFocus func(p tview.Primitive)) (consumed bool, capture tview.Primitive) {
+	return b.WrapMouseHandler(func(action tview.MouseAction, event *tcell.EventMouse, setFocus func(p tview.Primitive)) (consumed bool, capture tview.Primitive) {
+		x, y := event.Position()
+		bodyX, _, _, _ := b.body.GetRect()
+		if b.dragging {
+			switch action {
+			case tview.MouseMove:
+				b.resize(x - bodyX + 1)
+				return true, b
+			case tview.MouseLeftUp:
+				b.dragging = false
+				return true, nil
+			}
+		}
+		if action == tview.MouseLeftDown && b.onDivider(x, y) {
+			b.dragging = true
+			return true, b
+		}
+		return b.Flex.MouseHandler()(action, event, setFocus)
+	})
+}
+
+// onDivider 判断坐标是否位于两个窗格相邻的边框上
+func (b *Browser) onDivider(x, y int) bool {
+	px, py, pw, ph := b.sessionPane.GetRect()
+	mx, _, _, _ := b.messages.GetRect()
+	return y >= py && y < py+ph && (x == px+pw-1 || x == mx)
+}
+
+// currentWidth 返回会话列表当前的宽度
+func (b *Browser) currentWidth() int {
+	_, _, width, _ := b.sessionPane.GetRect()
+	return width
+}
+
+// resize 设置会话列表宽度，两个窗格均不小于 MinPaneWidth
+func (b *Browser) resize(width int) {
+	_, _, total, _ := b.body.GetRect()
+	if total < MinPaneWidth*2 {
+		return
+	}
+	b.body.ResizeItem(b.sessionPane, min(max(width, MinPaneWidth), total-MinPaneWidth), 0)
+}
+
+// LoadSessions 按搜索框中的关键字加载第一页会话
+func (b *Browser) LoadSessions() {
+	keyword := strings.TrimSpace(b.filter.GetText())
+	b.sessions = nil
+	b.fetched = 0
+	b.more = false
+	b.loadingSessions = true
+	b.list.Clear()
+	b.list.AddItem(i18n.T("加载中..."), "", 0, nil)
+
+	go func() {
+		sessions, err := b.src.BrowseSessions(keyword, SessionPageSize, 0)
+		b.queue(func() {
+			b.loadingSessions = false
+			if keyword != strings.TrimSpace(b.filter.GetText()) {
+				return
+			}
+			if err != nil {
+				b.list.Clear()
+				b.list.AddItem(i18n.T("加载会话失败"), tview.Escape(err.Error()), 0, nil)
+				return
+			}
+			b.sessions = sessions
+			b.fetched = len(sessions)
+			b.more = len(sessions) == SessionPageSize
+			b.list.SetCurrentItem(0)
+			b.showSessions()
+		})
+	}()
+}
+
+// loadMoreSessions 选中列表末尾时加载下一页会话，避免会话很多时一次加载全部
+func (b *Browser) loadMoreSessions() {
+	if !b.more || b.loadingSessions {
+		return
+	}
+	b.loadingSessions = true
+	keyword, offset := strings.TrimSpace(b.filter.GetText()), b.fetched
+
+	go func() {
+		sessions, err := b.src.BrowseSessions(keyword, SessionPageSize, offset)
+		b.queue(func() {
+			b.loadingSessions = false
+			if offset != b.fetched || keyword != strings.TrimSpace(b.filter.GetText()) {
+				return
+			}
+			if err != nil {
+				b.more = false
+				b.list.AddItem(i18n.T("加载会话失败"), tview.Escape(err.Error()), 0, nil)
+				return
+			}
+			b.sessions = append(b.sessions, sessions...)
+			b.fetched += len(sessions)
+			b.more = len(sessions) == SessionPageSize
+			b.showSessions()
+		})
+	}()
+}
+
+// showSessions 显示会话列表，置顶的会话按置顶顺序排在最前面并加上标记
+func (b *Browser) showSessions() {
+	current := b.list.GetCurrentItem()
+	b.list.Clear()
+	if len(b.sessions) == 0 {
+		b.list.AddItem(i18n.T("没有会话"), "", 0, nil)
+		return
+	}
+	var pinned []string
+	if b.pinned != nil {
+		pinned = b.pinned()
+	}
+	b.sessions = PinFirst(b.sessions, pinned)
+	order := make(map[string]bool, len(pinned))
+	for _, t := range pinned {
+		order[t] = true
+	}
+	for _, s := range b.sessions {
+		name := sessionName(s)
+		if order[s.UserName] {
+			name = "★ " + name
+		}
+		secondary := fmt.Sprintf("%s  %s", s.NTime.Format("2006-01-02 15:04"), oneLine(s.Content, 30))
+		b.list.AddItem(tview.Escape(name), tview.Escape(secondary), 0, nil)
+	}
+	if b.more {
+		b.list.AddItem(i18n.T("加载更多..."), "", 0, nil)
+	}
+	b.list.SetCurrentItem(current)
+}
+
+// togglePin 置顶或取消置顶会话，焦点在会话列表时为选中的会话，否则为正在查看的会话
+func (b *Browser) togglePin() {
+	talker := b.talker
+	if b.list.HasFocus() {
+		talker = ""
+		if i := b.list.GetCurrentItem(); i < len(b.sessions) {
+			talker = b.sessions[i].UserName
+		}
+	}
+	if len(talker) == 0 {
+		return
+	}
+	pinned := false
+	if b.pinned != nil {
+		for _, t := range b.pinned() {
+			pinned = pinned || t == talker
+		}
+	}
+	if err := b.pin(talker, !pinned); err != nil {
+		b.setTitle(i18n.T("置顶失败: ") + err.Error())
+		return
+	}
+	selected := ""
+	if i := b.list.GetCurrentItem(); i < len(b.sessions) {
+		selected = b.sessions[i].UserName
+	}
+	b.showSessions()
+	for i, s := range b.sessions {
+		if s.UserName == selected {
+			b.list.SetCurrentItem(i)
+			break
+		}
+	}
+}
+
+// PinFirst 将置顶的会话按置顶顺序移到最前面，其余会话保持原有顺序
+func PinFirst(sessions []*model.Session, pinned []string) []*model.Session {
+	if len(pinned) == 0 {
+		return sessions
+	}
+	byName := make(map[string]*model.Session, len(sessions))
+	for _, s := range sessions {
+		byName[s.UserName] = s
+	}
+	ret := make([]*model.Session, 0, len(sessions))
+	seen := make(map[string]bool, len(pinned))
+	for _, t := range pinned {
+		if s, ok := byName[t]; ok && !seen[t] {
+			ret = append(ret, s)
+			seen[t] = true
+		}
+	}
+	for _, s := range sessions {
+		if !seen[s.UserName] {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+// open 打开会话，加载最近的消息
+func (b *Browser) open(s *model.Session) {
+	b.Open(s.UserName, sessionName(s))
+}
+
+// Open 打开联系人或群聊，加载最近的消息
+func (b *Browser) Open(talker, name string) {
+	b.openAt(talker, name, time.Now().Add(time.Minute), 0)
+}
+
+// OpenMessage 打开消息所在的会话，加载该消息及之前的消息并滚动到该消息
+func (b *Browser) OpenMessage(msg *model.Message) {
+	start := msg.Time.Add(HitContext)
+	if now := time.Now().Add(time.Minute); start.After(now) {
+		start = now
+	}
+	b.openAt(msg.Talker, talkerName(msg), start, msg.Seq)
+}
+
+func (b *Browser) openAt(talker, name string, start time.Time, hit int64) {
+	b.talker = talker
+	b.name = name
+	b.loaded = nil
+	b.start = start
+	b.hit = hit
+	b.restore = -1
+	b.paths = make(map[int64]string)
+	b.self = nil
+	b.mentioned = false
+	b.finished = false
+	b.messages.Clear()
+	b.setFocus(b.messages)
+	b.loadEarlier()
+}
+
+// loadEarlier 加载已加载范围之前的消息，至少 PageSize 条或直到没有更早的消息
+func (b *Browser) loadEarlier() {
+	if len(b.talker) == 0 || b.loading || b.finished {
+		return
+	}
+	b.loading = true
+	b.setTitle(i18n.T("加载中..."))
+
+	talker, end := b.talker, b.start
+	go func() {
+		var (
+			msgs   []*model.Message
+			err    error
+			window = Window
+		)
+		start := end
+		for len(msgs) < PageSize && start.After(Earliest) {
+			start = end.Add(-window)
+			var page []*model.Message
+			page, err = b.src.BrowseMessages(talker, start, end.Add(-time.Second))
+			if err != nil && errors.GetCode(err) != http.StatusNotFound {
+				break
+			}
+			err = nil
+			msgs = append(page, msgs...)
+			end = start
+			if len(page) == 0 && window < MaxWindow {
+				window *= 2
+			}
+		}
+
+		b.queue(func() {
+			b.loading = false
+			if talker != b.talker {
+				return
+			}
+			if err != nil {
+				b.setTitle(i18n.T("加载失败: ") + err.Error())
+				return
+			}
+			b.start = start
+			b.finished = !start.After(Earliest)
+			b.loaded = append(msgs, b.loaded...)
+			b.resolveSelf()
+			b.render(len(msgs))
+		})
+	}()
+}
+
+// render 重新绘制消息，首次打开时滚动到最新消息，加载更早消息后滚动到顶部并保留选中的消息
+func (b *Browser) render(added int) {
+	selected := b.selectedIndex()
+	b.messages.SetText(b.text())
+	b.messages.Highlight()
+
+	b.updateTitle()
+	if len(b.loaded) != added {
+		if selected >= 0 {
+			b.messages.Highlight(messageRegion(selected + added))
+		}
+		b.messages.ScrollToBeginning()
+		return
+	}
+	if b.restore >= 0 {
+		b.messages.ScrollTo(b.restore, 0)
+		b.restore = -1
+		return
+	}
+	b.messages.ScrollToEnd()
+	if b.hit == 0 {
+		return
+	}
+	for i, msg := range b.loaded {
+		if msg.Seq == b.hit {
+			b.messages.Highlight(messageRegion(i)).ScrollToHighlight()
+			return
+		}
+	}
+}
+
+// redraw 重新绘制消息，保留滚动位置与选中的消息
+func (b *Browser) redraw() {
+	selected := b.selectedIndex()
+	row, col := b.messages.GetScrollOffset()
+	b.messages.SetText(b.text())
+	b.messages.Highlight()
+	if selected >= 0 {
+		b.messages.Highlight(messageRegion(selected))
+	}
+	b.messages.ScrollTo(row, col)
+}
+
+// updateTitle 显示会话名称与消息数，筛选时同时显示筛选条件与符合条件的消息数
+func (b *Browser) updateTitle() {
+	if b.kind == FilterAll {
+		b.setTitle(i18n.Tf("%s · %d 条消息", b.name, len(b.loaded)))
+		return
+	}
+	matched, self := 0, b.selfNames()
+	for _, msg := range b.loaded {
+		if b.kind.Match(msg, self) {
+			matched++
+		}
+	}
+	b.setTitle(i18n.Tf("%s · 筛选: %s · %d/%d 条消息", b.name, b.kind, matched, len(b.loaded)))
+}
+
+// setFilter 切换消息类型筛选，保留仍然可见的选中消息
+func (b *Browser) setFilter(kind Filter) {
+	b.kind = kind
+	selected := b.selectedIndex()
+	b.messages.SetText(b.text())
+	b.messages.Highlight()
+	b.updateTitle()
+	if selected >= 0 && b.visible(b.loaded[selected]) {
+		b.messages.Highlight(messageRegion(selected)).ScrollToHighlight()
+		return
+	}
+	b.messages.ScrollToEnd()
+}
+
+// visible 判断消息是否符合当前的筛选条件
+func (b *Browser) visible(msg *model.Message) bool {
+	return b.kind.Match(msg, b.selfNames())
+}
+
+// selfNames 返回自己在当前会话中可能被 @ 的名称，包括自己消息的群昵称与查询到的名称
+func (b *Browser) selfNames() []string {
+	names := append([]string{}, b.self...)
+	for _, msg := range b.loaded {
+		if msg.IsSelf && len(msg.SenderName) != 0 {
+			names = append(names, msg.SenderName)
+		}
+	}
+	return names
+}
+
+// resolveSelf 根据已加载的自己发送的消息查询自己在会话中的名称，每个会话只查询一次
+func (b *Browser) resolveSelf() {
+	if b.mention == nil || b.mentioned {
+		return
+	}
+	var self string
+	for _, msg := range b.loaded {
+		if msg.IsSelf && len(msg.Sender) != 0 {
+			self = msg.Sender
+			break
+		}
+	}
+	if len(self) == 0 {
+		return
+	}
+	b.mentioned = true
+	talker := b.talker
+	go func() {
+		names := b.mention(talker, self)
+		b.queue(func() {
+			if talker != b.talker {
+				return
+			}
+			b.self = names
+			if b.kind == FilterMention {
+				b.redraw()
+				b.updateTitle()
+			}
+		})
+	}()
+}
+
+// text 按时间顺序生成消息文本，每天之前显示日期分隔线，自己发送的消息向右缩进，引用的消息缩进显示在回复之前
+func (b *Browser) text() string {
+	buf := strings.Builder{}
+	if b.finished {
+		fmt.Fprintf(&buf, i18n.T("%s—— 没有更早的消息 ——[-]\n\n"), style.Tag(style.MutedColor))
+	} else {
+		fmt.Fprintf(&buf, i18n.T("%s—— 按 %s 加载更早的消息 ——[-]\n\n"), style.Tag(style.MutedColor), tview.Escape(keymap.Label(keymap.Earlier)))
+	}
+	var day string
+	self := b.selfNames()
+	shown := 0
+	for i, msg := range b.loaded {
+		if !b.kind.Match(msg, self) {
+			continue
+		}
+		shown++
+		if d := msg.Time.Format("2006-01-02"); d != day {
+			day = d
+			fmt.Fprintf(&buf, "%s—— %s ——[-]\n\n", style.Tag(style.MutedColor), d)
+		}
+		indent := ""
+		color := style.SuccessColor
+		if msg.IsSelf {
+			indent = strings.Repeat(" ", SelfIndent)
+			color = style.HighlightColor
+		}
+		fmt.Fprintf(&buf, "%s[%s::b]%s[-::-] %s%s[-]\n", indent, style.GetColorHex(color), tview.Escape(senderName(msg)),
+			style.Tag(style.MutedColor), msg.Time.Format("15:04:05"))
+		if refer := Refer(msg); refer != nil {
+			quote := fmt.Sprintf("%s: %s", senderName(refer), Content(refer))
+			for _, line := range strings.Split(quote, "\n") {
+				fmt.Fprintf(&buf, "%s  %s│ %s[-]\n", indent, style.Tag(style.MutedColor), tview.Escape(line))
+			}
+		}
+		// 每条消息作为可选中的区域，单击或按键选中后复制，图片消息可以预览
+		fmt.Fprintf(&buf, `["%s"]%s[""]`, messageRegion(i), tview.Escape(indentLines(Content(msg), indent)))
+		if path, ok := b.paths[msg.Seq]; ok {
+			fmt.Fprintf(&buf, "\n%s%s↳ %s[-]", indent, style.Tag(style.MutedColor), tview.Escape(path))
+		}
+		buf.WriteString("\n\n")
+	}
+	if shown == 0 && b.kind != FilterAll && len(b.loaded) != 0 {
+		fmt.Fprintf(&buf, i18n.T("%s已加载的消息中没有%s，按 %s 切换筛选[-]\n"), style.Tag(style.MutedColor), b.kind, tview.Escape(keymap.Label(keymap.Filter)))
+	}
+	return buf.String()
+}
+
+// togglePath 显示或隐藏选中的多媒体消息的文件路径
+func (b *Browser) togglePath() {
+	msg := b.Selected()
+	if msg == nil || b.path == nil || len(msg.MediaType()) == 0 {
+		return
+	}
+	if _, ok := b.paths[msg.Seq]; ok {
+		delete(b.paths, msg.Seq)
+		b.redraw()
+		return
+	}
+	talker := b.talker
+	go func() {
+		path, err := b.path(msg)
+		if err != nil {
+			path = i18n.T("无法获取文件路径: ") + err.Error()
+		}
+		b.queue(func() {
+			if talker != b.talker {
+				return
+			}
+			b.paths[msg.Seq] = path
+			b.redraw()
+		})
+	}()
+}
+
+// Selected 返回选中的消息，没有选中时返回 nil
+func (b *Browser) Selected() *model.Message {
+	if i := b.selectedIndex(); i >= 0 {
+		return b.loaded[i]
+	}
+	return nil
+}
+
+func (b *Browser) selectedIndex() int {
+	ids := b.messages.GetHighlights()
+	if len(ids) == 0 {
+		return -1
+	}
+	if i := messageIndex(ids[0]); i >= 0 && i < len(b.loaded) {
+		return i
+	}
+	return -1
+}
+
+// selectMessage 选中前后第 step 条消息，没有选中时选中最新的消息
+func (b *Browser) selectMessage(step int) {
+	if len(b.loaded) == 0 {
+		return
+	}
+	self := b.selfNames()
+	current := b.selectedIndex()
+	if current < 0 {
+		current, step = len(b.loaded), -1
+	}
+	for i := current + step; i >= 0 && i < len(b.loaded); i += step {
+		if b.kind.Match(b.loaded[i], self) {
+			b.messages.Highlight(messageRegion(i)).ScrollToHighlight()
+			return
+		}
+	}
+}
+
+// nextImage 选中当前消息之后的下一张图片，到末尾后从头开始
+func (b *Browser) nextImage() {
+	current := b.selectedIndex()
+	for n := 1; n <= len(b.loaded); n++ {
+		i := (current + n) % len(b.loaded)
+		if i >= 0 && b.loaded[i].Type == model.MessageTypeImage && b.visible(b.loaded[i]) {
+			b.messages.Highlight(messageRegion(i)).ScrollToHighlight()
+			return
+		}
+	}
+}
+
+// previewImage 预览选中的图片
+func (b *Browser) previewImage() {
+	if msg := b.Selected(); msg != nil && msg.Type == model.MessageTypeImage && b.preview != nil {
+		b.preview(msg)
+	}
+}
+
+func messageRegion(i int) string {
+	return fmt.Sprintf("msg-%d", i)
+}
+
+func messageIndex(region string) int {
+	var i int
+	if _, err := fmt.Sscanf(region, "msg-%d", &i); err != nil {
+		return -1
+	}
+	return i
+}
+
+func (b *Browser) setTitle(text string) {
+	b.messages.SetTitle(" " + tview.Escape(text) + " ")
+}
+
+// Content 返回消息在终端中显示的文本，多媒体消息显示为 [图片]、[语音 0:07]、[文件 report.pdf] 等标记
+func Content(msg *model.Message) string {
+	switch msg.Type {
+	case model.MessageTypeText:
+		return msg.Content
+	case model.MessageTypeImage:
+		return i18n.T("[图片]")
+	case model.MessageTypeVoice:
+		if ms, ok := msg.Contents["voicelength"].(int); ok && ms > 0 {
+			sec := (ms + 500) / 1000
+			return i18n.Tf("[语音 %d:%02d]", sec/60, sec%60)
+		}
+		return i18n.T("[语音]")
+	case model.MessageTypeVideo:
+		return i18n.T("[视频]")
+	case model.MessageTypeAnimation:
+		return i18n.T("[动画表情]")
+	case model.MessageTypeShare:
+		switch msg.SubType {
+		case model.MessageSubTypeFile:
+			return i18n.Tf("[文件 %s]", msg.Contents["title"])
+		case model.MessageSubTypeQuote:
+			// 引用的消息由 Refer 单独显示
+			return msg.Content
+		}
+	}
+	return msg.PlainTextContent()
+}
+
+// Refer 返回引用消息中被引用的消息，不是引用消息时返回 nil
+func Refer(msg *model.Message) *model.Message {
+	if msg.Type != model.MessageTypeShare || msg.SubType != model.MessageSubTypeQuote {
+		return nil
+	}
+	refer, _ := msg.Contents["refer"].(*model.Message)
+	return refer
+}
+
+// indentLines 为多行文本的每一行添加缩进
+func indentLines(text, indent string) string {
+	if len(indent) == 0 {
+		return text
+	}
+	return indent + strings.ReplaceAll(text, "\n", "\n"+indent)
+}
+
+func senderName(msg *model.Message) string {
+	if msg.IsSelf {
+		return i18n.T("我")
+	}
+	if len(msg.SenderName) != 0 {
+		return msg.SenderName
+	}
+	return msg.Sender
+}
+
+func sessionName(s *model.Session) string {
+	if len(s.NickName) != 0 {
+		return s.NickName
+	}
+	return s.UserName
+}
+
+func oneLine(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > n {
+		return string(r[:n]) + "..."
+	}
+	return s
+}
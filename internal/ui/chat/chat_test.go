@@ -0,0 +1,21 @@
+package chat
+
+import (
+	"testing"
+
+	"github.com/DanielMao1/chatlog/internal/model"
+)
+
+func TestPinFirst(t *testing.T) {
+	sessions := []*model.Session{{UserName: "a"}, {UserName: "b"}, {UserName: "c"}, {UserName: "d"}}
+	got := PinFirst(sessions, []string{"c", "missing", "a"})
+	want := []string{"c", "a", "b", "d"}
+	if len(got) != len(want) {
+		t.Fatalf("PinFirst returned %d sessions, want %d", len(got), len(want))
+	}
+	for i, s := range got {
+		if s.UserName != want[i] {
+			t.Errorf("PinFirst()[%d] = %q, want %q", i, s.UserName, want[i])
+		}
+	}
+}
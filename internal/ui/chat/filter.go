@@ -0,0 +1,83 @@
+package chat
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/DanielMao1/chatlog/internal/i18n"
+	"github.com/DanielMao1/chatlog/internal/model"
+)
+
+// Filter 消息浏览中按类型快速筛选消息
+type Filter int
+
+const (
+	FilterAll     Filter = iota // 全部消息
+	FilterFile                  // 只看文件
+	FilterImage                 // 只看图片
+	FilterLink                  // 只看链接
+	FilterMention               // 只看提到我的消息
+
+	filterCount
+)
+
+// MentionAll 群聊中 @ 所有人的文本
+const MentionAll = "@所有人"
+
+var linkRegexp = regexp.MustCompile(`(?i)https?://`)
+
+// Next 返回按键切换时的下一个筛选条件，最后一个之后回到全部消息
+func (f Filter) Next() Filter {
+	return (f + 1) % filterCount
+}
+
+// String 返回筛选条件的名称
+func (f Filter) String() string {
+	switch f {
+	case FilterFile:
+		return i18n.T("文件")
+	case FilterImage:
+		return i18n.T("图片")
+	case FilterLink:
+		return i18n.T("链接")
+	case FilterMention:
+		return i18n.T("提到我")
+	}
+	return i18n.T("全部")
+}
+
+// Match 判断消息是否符合筛选条件，self 为自己在会话中可能被 @ 的名称
+// 提到我的消息包括 @ 自己或所有人的群聊消息，以及回复自己的引用消息
+func (f Filter) Match(msg *model.Message, self []string) bool {
+	switch f {
+	case FilterFile:
+		return msg.Type == model.MessageTypeShare && msg.SubType == model.MessageSubTypeFile
+	case FilterImage:
+		return msg.Type == model.MessageTypeImage
+	case FilterLink:
+		if msg.Type == model.MessageTypeShare && (msg.SubType == model.MessageSubTypeLink || msg.SubType == model.MessageSubTypeLink2) {
+			return true
+		}
+		return msg.Type == model.MessageTypeText && linkRegexp.MatchString(msg.Content)
+	case FilterMention:
+		if msg.IsSelf {
+			return false
+		}
+		if refer := Refer(msg); refer != nil && refer.IsSelf {
+			return true
+		}
+		if !msg.IsChatRoom || !strings.Contains(msg.Content, "@") {
+			return false
+		}
+		if strings.Contains(msg.Content, MentionAll) {
+			return true
+		}
+		for _, name := range self {
+			if len(name) != 0 && strings.Contains(msg.Content, "@"+name) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
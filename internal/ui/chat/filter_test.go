@@ -0,0 +1,47 @@
+package chat
+
+import (
+	"testing"
+
+	"github.com/DanielMao1/chatlog/internal/model"
+)
+
+func TestFilterMatch(t *testing.T) {
+	self := []string{"小王"}
+	quote := &model.Message{Type: model.MessageTypeShare, SubType: model.MessageSubTypeQuote, IsChatRoom: true, Content: "收到",
+		Contents: map[string]interface{}{"refer": &model.Message{IsSelf: true, Content: "明天开会"}}}
+	tests := []struct {
+		name   string
+		filter Filter
+		msg    *model.Message
+		want   bool
+	}{
+		{"file", FilterFile, &model.Message{Type: model.MessageTypeShare, SubType: model.MessageSubTypeFile}, true},
+		{"file excludes link", FilterFile, &model.Message{Type: model.MessageTypeShare, SubType: model.MessageSubTypeLink}, false},
+		{"image", FilterImage, &model.Message{Type: model.MessageTypeImage}, true},
+		{"link share", FilterLink, &model.Message{Type: model.MessageTypeShare, SubType: model.MessageSubTypeLink2}, true},
+		{"link in text", FilterLink, &model.Message{Type: model.MessageTypeText, Content: "见 HTTPS://example.com/a"}, true},
+		{"text without link", FilterLink, &model.Message{Type: model.MessageTypeText, Content: "没有链接"}, false},
+		{"mention name", FilterMention, &model.Message{Type: model.MessageTypeText, IsChatRoom: true, Content: "@小王 看一下"}, true},
+		{"mention all", FilterMention, &model.Message{Type: model.MessageTypeText, IsChatRoom: true, Content: "@所有人 开会"}, true},
+		{"mention other", FilterMention, &model.Message{Type: model.MessageTypeText, IsChatRoom: true, Content: "@小李 看一下"}, false},
+		{"mention by self", FilterMention, &model.Message{Type: model.MessageTypeText, IsChatRoom: true, IsSelf: true, Content: "@所有人"}, false},
+		{"reply to self", FilterMention, quote, true},
+		{"all", FilterAll, &model.Message{Type: model.MessageTypeVoice}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Match(tt.msg, self); got != tt.want {
+			t.Errorf("%s: Match() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFilterNext(t *testing.T) {
+	f := FilterAll
+	for i := 0; i < int(filterCount); i++ {
+		f = f.Next()
+	}
+	if f != FilterAll {
+		t.Errorf("Next() should cycle back to FilterAll, got %v", f)
+	}
+}
@@ -0,0 +1,214 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DanielMao1/chatlog/internal/i18n"
+	"github.com/DanielMao1/chatlog/internal/model"
+	"github.com/DanielMao1/chatlog/internal/ui/keymap"
+	"github.com/DanielMao1/chatlog/internal/ui/style"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const (
+	SearchTitle = "search"
+
+	// MaxResults 搜索结果最多显示的消息数量
+	MaxResults = 200
+
+	// SnippetWidth 搜索结果中关键字前后显示的字数
+	SnippetWidth = 24
+)
+
+// Searcher 全文搜索的数据来源
+type Searcher interface {
+	SearchMessages(keyword string, limit int) ([]*model.Message, error)
+}
+
+// Search 在所有会话中搜索消息，选中结果后打开所在的会话
+type Search struct {
+	*tview.Flex
+	src      Searcher
+	queue    func(func())
+	setFocus func(tview.Primitive)
+	selected func(msg *model.Message)
+	done     func()
+
+	input *tview.InputField
+	list  *tview.List
+
+	results []*model.Message
+	seq     int
+}
+
+// NewSearch 创建搜索视图，selected 在结果中按 Enter 时调用，done 在按 ESC 退出时调用
+func NewSearch(src Searcher, queue func(func()), setFocus func(tview.Primitive), selected func(msg *model.Message), done func()) *Search {
+	s := &Search{
+		Flex:     tview.NewFlex(),
+		src:      src,
+		queue:    queue,
+		setFocus: setFocus,
+		selected: selected,
+		done:     done,
+		input:    tview.NewInputField(),
+		list:     tview.NewList(),
+	}
+
+	s.input.
+		SetLabel(i18n.T("搜索: ")).
+		SetPlaceholder(i18n.T("输入关键字后按 Enter 搜索所有会话")).
+		SetFieldBackgroundColor(style.InputFieldBgColor).
+		SetDoneFunc(func(key tcell.Key) {
+			if key == tcell.KeyEnter {
+				s.search()
+			}
+		})
+
+	s.list.
+		ShowSecondaryText(true).
+		SetHighlightFullLine(true).
+		SetSelectedStyle(style.SelectedStyle).
+		SetSelectedFunc(func(index int, _ string, _ string, _ rune) {
+			if index < len(s.results) && s.selected != nil {
+				s.selected(s.results[index])
+			}
+		})
+
+	results := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(s.input, 1, 0, true).
+		AddItem(s.list, 0, 1, false)
+	results.
+		SetBorder(true).
+		SetBorderColor(style.BorderColor).
+		SetTitle(i18n.T(" 搜索聊天记录 "))
+
+	help := tview.NewTextView().
+		SetDynamicColors(true).
+		SetTextAlign(tview.AlignCenter)
+	fmt.Fprintf(help,
+		i18n.T("[%s::b]%s[%s::b]: 搜索/打开  [%s::b]%s[%s::b]: 切换输入框与结果  [%s::b]%s[%s::b]: 返回"),
+		style.GetColorHex(style.MenuBgColor), tview.Escape(keymap.Label(keymap.Select)), style.GetColorHex(style.PageHeaderFgColor),
+		style.GetColorHex(style.MenuBgColor), tview.Escape(keymap.Label(keymap.SwitchPane)), style.GetColorHex(style.PageHeaderFgColor),
+		style.GetColorHex(style.MenuBgColor), tview.Escape(keymap.Label(keymap.Back)), style.GetColorHex(style.PageHeaderFgColor),
+	)
+
+	s.Flex.SetDirection(tview.FlexRow).
+		AddItem(results, 0, 1, true).
+		AddItem(help, 1, 0, false)
+
+	s.Flex.SetInputCapture(s.inputCapture)
+
+	return s
+}
+
+// Focus 默认聚焦搜索框
+func (s *Search) Focus(delegate func(p tview.Primitive)) {
+	delegate(s.input)
+}
+
+func (s *Search) inputCapture(event *tcell.EventKey) *tcell.EventKey {
+	switch {
+	case event.Key() == tcell.KeyEscape:
+		if s.done != nil {
+			s.done()
+		}
+		return nil
+	case event.Key() == tcell.KeyBacktab || event.Key() == tcell.KeyTab || (!s.input.HasFocus() && keymap.Match(event, keymap.SwitchPane)):
+		if s.input.HasFocus() {
+			s.setFocus(s.list)
+		} else {
+			s.setFocus(s.input)
+		}
+		return nil
+	case s.input.HasFocus() && event.Key() == tcell.KeyDown && len(s.results) != 0:
+		s.setFocus(s.list)
+		return nil
+	case !s.input.HasFocus() && keymap.Match(event, keymap.Search):
+		s.setFocus(s.input)
+		return nil
+	}
+	return event
+}
+
+// search 按搜索框中的关键字搜索，较早发起的搜索结果返回时丢弃
+func (s *Search) search() {
+	keyword := strings.TrimSpace(s.input.GetText())
+	if len(keyword) == 0 {
+		return
+	}
+	s.seq++
+	seq := s.seq
+	s.results = nil
+	s.list.Clear()
+	s.list.AddItem(i18n.T("搜索中..."), "", 0, nil)
+
+	go func() {
+		results, err := s.src.SearchMessages(keyword, MaxResults)
+		s.queue(func() {
+			if seq != s.seq {
+				return
+			}
+			s.list.Clear()
+			if err != nil {
+				s.list.AddItem(i18n.T("搜索失败"), tview.Escape(err.Error()), 0, nil)
+				return
+			}
+			s.results = results
+			if len(results) == 0 {
+				s.list.AddItem(i18n.T("没有找到包含关键字的消息"), "", 0, nil)
+				return
+			}
+			for _, msg := range results {
+				main := fmt.Sprintf("%s  %s", msg.Time.Format("2006-01-02 15:04"), talkerName(msg))
+				secondary := fmt.Sprintf("%s: %s", senderName(msg), Snippet(Content(msg), keyword, SnippetWidth))
+				s.list.AddItem(tview.Escape(main), tview.Escape(secondary), 0, nil)
+			}
+			if len(results) == MaxResults {
+				s.list.AddItem(i18n.Tf("只显示最近的 %d 条结果，请输入更多关键字", MaxResults), "", 0, nil)
+			}
+			s.setFocus(s.list)
+		})
+	}()
+}
+
+// Snippet 返回关键字前后各 width 个字符的单行文本，找不到关键字时返回开头部分
+func Snippet(content, keyword string, width int) string {
+	runes := []rune(strings.Join(strings.Fields(content), " "))
+	lower := []rune(strings.ToLower(string(runes)))
+	key := []rune(strings.ToLower(keyword))
+	if len(lower) != len(runes) {
+		// 少数字符转换大小写后长度改变，此时区分大小写查找
+		lower, key = runes, []rune(keyword)
+	}
+
+	at := -1
+	for i := 0; i+len(key) <= len(lower) && len(key) != 0; i++ {
+		if string(lower[i:i+len(key)]) == string(key) {
+			at = i
+			break
+		}
+	}
+	if at < 0 {
+		return oneLine(content, width*2)
+	}
+
+	start, end := max(at-width, 0), min(at+len(key)+width, len(runes))
+	snippet := string(runes[start:end])
+	if start > 0 {
+		snippet = "..." + snippet
+	}
+	if end < len(runes) {
+		snippet += "..."
+	}
+	return snippet
+}
+
+func talkerName(msg *model.Message) string {
+	if len(msg.TalkerName) != 0 {
+		return msg.TalkerName
+	}
+	return msg.Talker
+}
@@ -0,0 +1,21 @@
+package chat
+
+import "testing"
+
+func TestSnippet(t *testing.T) {
+	tests := []struct {
+		content, keyword string
+		width            int
+		want             string
+	}{
+		{"明天下午三点开会", "三点", 2, "...下午三点开会"},
+		{"hello World", "world", 3, "...lo World"},
+		{"第一行\n第二行 关键字 结尾", "关键字", 20, "第一行 第二行 关键字 结尾"},
+		{"没有匹配", "关键字", 2, "没有匹配"},
+	}
+	for _, tt := range tests {
+		if got := Snippet(tt.content, tt.keyword, tt.width); got != tt.want {
+			t.Errorf("Snippet(%q, %q, %d) = %q, want %q", tt.content, tt.keyword, tt.width, got, tt.want)
+		}
+	}
+}
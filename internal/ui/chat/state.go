@@ -0,0 +1,39 @@
+package chat
+
+import "strings"
+
+// State 消息浏览的界面状态，退出时保存，下次启动时恢复
+type State struct {
+	Talker  string `json:"talker"`
+	Name    string `json:"name"`
+	Keyword string `json:"keyword,omitempty"`
+	Filter  Filter `json:"filter,omitempty"`
+	// Scroll 消息窗格滚动到的行
+	Scroll int `json:"scroll,omitempty"`
+}
+
+// State 返回当前的界面状态，没有打开会话时只保存会话搜索关键字
+func (b *Browser) State() *State {
+	row, _ := b.messages.GetScrollOffset()
+	return &State{
+		Talker:  b.talker,
+		Name:    b.name,
+		Keyword: strings.TrimSpace(b.filter.GetText()),
+		Filter:  b.kind,
+		Scroll:  row,
+	}
+}
+
+// Restore 恢复界面状态：按关键字加载会话列表，打开上次查看的会话并在首次加载后滚动到上次的位置
+func (b *Browser) Restore(s *State) {
+	b.kind = s.Filter % filterCount
+	if len(s.Keyword) != 0 {
+		b.filter.SetText(s.Keyword)
+		b.LoadSessions()
+	}
+	if len(s.Talker) == 0 {
+		return
+	}
+	b.Open(s.Talker, s.Name)
+	b.restore = s.Scroll
+}
@@ -3,6 +3,8 @@ package footer
 import (
 	"fmt"
 
+	"github.com/DanielMao1/chatlog/internal/i18n"
+	"github.com/DanielMao1/chatlog/internal/ui/keymap"
 	"github.com/DanielMao1/chatlog/internal/ui/style"
 	"github.com/DanielMao1/chatlog/pkg/version"
 
@@ -18,6 +20,7 @@ type Footer struct {
 	title     string
 	copyRight *tview.TextView
 	help      *tview.TextView
+	text      string
 }
 
 func New() *Footer {
@@ -34,7 +37,8 @@ func New() *Footer {
 		SetTextAlign(tview.AlignLeft)
 	footer.copyRight.
 		SetBackgroundColor(tview.Styles.PrimitiveBackgroundColor)
-	footer.copyRight.SetText(fmt.Sprintf("[%s::b]%s[-:-:-]", style.GetColorHex(style.PageHeaderFgColor), fmt.Sprintf(" @ Sarv's Chatlog %s", version.Version)))
+	footer.text = fmt.Sprintf("[%s::b]%s[-:-:-]", style.GetColorHex(style.PageHeaderFgColor), fmt.Sprintf(" @ Sarv's Chatlog %s", version.Version))
+	footer.copyRight.SetText(footer.text)
 
 	footer.help.
 		SetDynamicColors(true).
@@ -44,12 +48,14 @@ func New() *Footer {
 		SetBackgroundColor(tview.Styles.PrimitiveBackgroundColor)
 
 	fmt.Fprintf(footer.help,
-		"[%s::b]↑/↓[%s::b]: 导航  [%s::b]←/→[%s::b]: 切换标签  [%s::b]Enter[%s::b]: 选择  [%s::b]ESC[%s::b]: 返回  [%s::b]Ctrl+C[%s::b]: 退出",
-		style.GetColorHex(style.MenuBgColor), style.GetColorHex(style.PageHeaderFgColor),
-		style.GetColorHex(style.MenuBgColor), style.GetColorHex(style.PageHeaderFgColor),
-		style.GetColorHex(style.MenuBgColor), style.GetColorHex(style.PageHeaderFgColor),
-		style.GetColorHex(style.MenuBgColor), style.GetColorHex(style.PageHeaderFgColor),
-		style.GetColorHex(style.MenuBgColor), style.GetColorHex(style.PageHeaderFgColor),
+		i18n.T("[%s::b]%s[%s::b]: 导航  [%s::b]%s[%s::b]: 切换标签  [%s::b]%s[%s::b]: 选择  [%s::b]%s[%s::b]: 返回  [%s::b]%s[%s::b]: 日志  [%s::b]%s[%s::b]: 按键  [%s::b]%s[%s::b]: 退出"),
+		style.GetColorHex(style.MenuBgColor), tview.Escape(keymap.Label(keymap.Up)+"/"+keymap.Label(keymap.Down)), style.GetColorHex(style.PageHeaderFgColor),
+		style.GetColorHex(style.MenuBgColor), tview.Escape(keymap.Label(keymap.Left)+"/"+keymap.Label(keymap.Right)), style.GetColorHex(style.PageHeaderFgColor),
+		style.GetColorHex(style.MenuBgColor), tview.Escape(keymap.Label(keymap.Select)), style.GetColorHex(style.PageHeaderFgColor),
+		style.GetColorHex(style.MenuBgColor), tview.Escape(keymap.Label(keymap.Back)), style.GetColorHex(style.PageHeaderFgColor),
+		style.GetColorHex(style.MenuBgColor), tview.Escape(keymap.Label(keymap.Log)), style.GetColorHex(style.PageHeaderFgColor),
+		style.GetColorHex(style.MenuBgColor), tview.Escape(keymap.Label(keymap.Help)), style.GetColorHex(style.PageHeaderFgColor),
+		style.GetColorHex(style.MenuBgColor), tview.Escape(keymap.Label(keymap.Quit)), style.GetColorHex(style.PageHeaderFgColor),
 	)
 
 	footer.
@@ -63,6 +69,11 @@ func (f *Footer) SetCopyRight(text string) {
 	f.copyRight.SetText(text)
 }
 
+// SetUpdate 在版本号后提示有新版本可用
+func (f *Footer) SetUpdate(latest string) {
+	f.copyRight.SetText(i18n.Tf("%s  [%s::b]新版本 %s 可用，运行 chatlog update 更新[-:-:-]", f.text, style.GetColorHex(style.PausedStatusFgColor), latest))
+}
+
 func (f *Footer) SetHelp(text string) {
 	f.help.SetText(text)
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
+	"github.com/DanielMao1/chatlog/internal/i18n"
 	"github.com/DanielMao1/chatlog/internal/ui/style"
 )
 
@@ -72,7 +73,7 @@ func NewForm(title string) *Form {
 	f.helpText.SetTextColor(style.DialogFgColor)
 	f.helpText.SetBackgroundColor(style.DialogBgColor)
 	fmt.Fprintf(f.helpText,
-		"[%s::b]Tab[%s::b]: 导航  [%s::b]Enter[%s::b]: 选择  [%s::b]ESC[%s::b]: 返回",
+		i18n.T("[%s::b]Tab[%s::b]: 导航  [%s::b]Enter[%s::b]: 选择  [%s::b]ESC[%s::b]: 返回"),
 		style.GetColorHex(style.MenuBgColor), style.GetColorHex(style.PageHeaderFgColor),
 		style.GetColorHex(style.MenuBgColor), style.GetColorHex(style.PageHeaderFgColor),
 		style.GetColorHex(style.MenuBgColor), style.GetColorHex(style.PageHeaderFgColor),
@@ -135,6 +136,24 @@ func (f *Form) AddCheckbox(label string, checked bool, changed func(checked bool
 	return f
 }
 
+// AddDropDown adds a drop-down field to the form.
+func (f *Form) AddDropDown(label string, options []string, initialOption int, selected func(option string, optionIndex int)) *Form {
+	// 下拉框宽度取最长的选项
+	width := 0
+	for _, option := range options {
+		width = max(width, len(option))
+	}
+	f.fields = append(f.fields, formField{
+		label:      label,
+		fieldWidth: width,
+	})
+
+	f.form.AddDropDown(label, options, initialOption, selected)
+	// 更新表单尺寸
+	f.recalculateSize()
+	return f
+}
+
 // SetCancelFunc sets the function to be called when the form is cancelled.
 func (f *Form) SetCancelFunc(handler func()) *Form {
 	f.cancelHandler = handler
@@ -2,7 +2,9 @@ package help
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/DanielMao1/chatlog/internal/i18n"
 	"github.com/DanielMao1/chatlog/internal/ui/style"
 
 	"github.com/rivo/tview"
@@ -19,6 +21,11 @@ const (
 • 按 [yellow]Enter[white] 选择菜单项
 • 按 [yellow]Esc[white] 返回上一级菜单
 • 按 [yellow]Ctrl+C[white] 退出程序
+• 支持鼠标：单击选择菜单项和会话，滚轮滚动列表与消息
+• 在任意界面按 [yellow]?[white] 查看当前可用的按键
+• 按 [yellow]F2[white] 展开或收起日志面板，展开后按 [yellow]F3[white] 切换显示的日志级别，获取密钥或解密失败时可查看原因
+• 底部状态栏显示账号、微信版本、工作目录大小、最近自动解密与新消息时间、HTTP 地址与每分钟请求数
+• 以上按键均可在配置文件的 [yellow]keys[white] 中自定义，如使用 vim 风格的 j/k 导航
 
 [green]使用步骤:[white]
 
@@ -32,15 +39,40 @@ const (
 [yellow]3. 解密数据[white]
    重新打开 chatlog，选择"解密数据"菜单项，程序会使用获取的密钥解密微信数据库文件。
    解密后的文件会保存到工作目录中（可在设置中修改）。
+   工作目录中已有数据时，可选择只解密有变化的数据库或全部重新解密。
 
 [yellow]4. 启动 HTTP 服务[white]
    选择"启动 HTTP 服务"菜单项，启动 HTTP 和 MCP 服务。
    启动后可以通过浏览器访问 http://localhost:5030 查看聊天记录。
 
-[yellow]5. 设置选项[white]
+[yellow]5. 浏览聊天记录[white]
+   选择"浏览聊天记录"菜单项，在左侧选择会话后按时间顺序阅读消息。
+   按 [yellow]/[white] 搜索会话，[yellow]c[white] 从联系人和群聊中选择，[yellow]Tab[white] 切换窗格，
+   在消息窗格中按 [yellow]b[white] 加载更早的消息。联系人可按备注、昵称、微信号或拼音首字母搜索。
+   拖动两个窗格之间的边框或按 [yellow]<[white] [yellow]>[white] 调整会话列表宽度。
+   单击图片或按 [yellow]i[white] 选中图片，按 [yellow]v[white] 预览，iTerm2、Kitty 及支持 sixel 的终端中显示原图。
+   单击消息或按 [yellow]n[white]/[yellow]p[white] 选中消息，按 [yellow]y[white] 复制消息文本，按 [yellow]Y[white] 复制图片、视频或文件的路径；任意界面按 [yellow]K[white]/[yellow]I[white] 复制数据密钥或图片密钥。
+
+   选择"搜索聊天记录"菜单项，输入关键字后按 [yellow]Enter[white] 在所有会话中搜索，选中结果后打开所在会话并定位到该消息。
+
+[yellow]6. 导出聊天记录[white]
+   选择"导出聊天记录"菜单项，选择联系人或群聊后填写时间范围（如 last-7d、2024-01-01~2024-01-31、all），
+   选择 txt、csv 或 json 格式与保存的文件，导出时显示进度。
+
+[yellow]7. 切换账号[white]
+   选择"切换账号"菜单项，列表中显示各账号的进程、密钥、工作目录与解密时间、HTTP 服务状态，
+   选中后切换，HTTP 服务与自动解密会在新账号上继续运行。
+
+[yellow]8. 设置选项[white]
    选择"设置"菜单项，可以配置:
    • HTTP 服务端口 - 更改 HTTP 服务的监听端口
    • 工作目录 - 更改解密数据的存储位置
+   • 访问令牌 - HTTP 接口与 MCP 请求需携带的令牌
+   • 自动解密间隔 - 数据库停止写入多久后开始解密
+   • 推送目标 - webhook 与总结推送使用的地址和请求头
+   • 定时任务 - 每日导出、每周备份与每日总结推送的时间和对象
+   • 界面语言 - 在配置文件中设置 [yellow]language[white] 为 zh-CN 或 en，或设置环境变量 CHATLOG_LANG
+   保存前会校验输入并写入配置文件，HTTP 服务运行时修改地址或工作目录会自动重启服务
 
 [green]HTTP API 使用:[white]
 • 聊天记录: [yellow]GET http://localhost:5030/api/v1/chatlog?time=2023-01-01&talker=wxid_xxx[white]
@@ -61,9 +93,99 @@ Chatlog 支持 Model Context Protocol，可与支持 MCP 的 AI 助手集成。
 [green]数据安全:[white]
 • 所有数据处理均在本地完成，不会上传到任何外部服务器
 • 请妥善保管解密后的数据，避免隐私泄露
+`
+
+	ContentEN = `[yellow]Chatlog Guide[white]
+
+[green]Basics:[white]
+• Use [yellow]←→[white] to switch between the main menu and this help page
+• Use [yellow]↑↓[white] to move between menu items
+• Press [yellow]Enter[white] to select a menu item
+• Press [yellow]Esc[white] to go back
+• Press [yellow]Ctrl+C[white] to quit
+• Mouse is supported: click to select menu items and chats, scroll lists and messages with the wheel
+• Press [yellow]?[white] on any screen to see the keys available there
+• Press [yellow]F2[white] to toggle the log panel and [yellow]F3[white] to change the log level, useful when getting the key or decrypting fails
+• The status bar at the bottom shows the account, WeChat version, work directory size, last auto decrypt and message times, and the HTTP address with requests per minute
+• All keys above can be customized under [yellow]keys[white] in the config file, e.g. vim-style j/k navigation
+
+[green]Getting started:[white]
+
+[yellow]1. Install the WeChat desktop client[white]
+
+[yellow]2. Migrate chat history from your phone[white]
+   On the phone, open [yellow]Me - Settings - General - Chat History Migration & Backup - Migrate - Migrate to Computer[white].
+   This copies the chat history from the phone to the computer.
+   It does not change the chat history on the phone.
+
+[yellow]3. Decrypt data[white]
+   Reopen chatlog and choose "Decrypt data". The key is used to decrypt the WeChat database files.
+   Decrypted files are saved to the work directory, which can be changed in Settings.
+   When the work directory already has data, choose to decrypt only changed databases or decrypt everything again.
+
+[yellow]4. Start the HTTP server[white]
+   Choose "Start HTTP server" to start the HTTP and MCP server.
+   Then open http://localhost:5030 in a browser to view the chat history.
+
+[yellow]5. Browse chat history[white]
+   Choose "Browse chat history", pick a chat on the left and read its messages in order.
+   Press [yellow]/[white] to search chats, [yellow]c[white] to pick from contacts and groups, [yellow]Tab[white] to switch panes,
+   and [yellow]b[white] in the message pane to load earlier messages. Contacts match remark, nickname, WeChat ID or pinyin initials.
+   Drag the border between the panes or press [yellow]<[white] [yellow]>[white] to resize the chat list.
+   Click an image or press [yellow]i[white] to select it and [yellow]v[white] to preview; iTerm2, Kitty and sixel terminals show the original image.
+   Click a message or press [yellow]n[white]/[yellow]p[white] to select it, [yellow]y[white] copies its text and [yellow]Y[white] the path of its image, video or file; press [yellow]K[white]/[yellow]I[white] anywhere to copy the data key or image key.
+
+   Choose "Search chat history", type a keyword and press [yellow]Enter[white] to search all chats; selecting a result opens its chat at that message.
+
+[yellow]6. Export chat history[white]
+   Choose "Export chat history", pick a contact or group, enter a time range (e.g. last-7d, 2024-01-01~2024-01-31, all),
+   then choose txt, csv or json and the output file. Progress is shown while exporting.
+
+[yellow]7. Switch account[white]
+   Choose "Switch account". The list shows each account's process, key, work directory, last decryption and HTTP server status.
+   After switching, the HTTP server and auto decryption keep running on the new account.
+
+[yellow]8. Settings[white]
+   Choose "Settings" to configure:
+   • HTTP address - the address the HTTP server listens on
+   • Work directory - where decrypted data is stored
+   • Auth token - token required by HTTP API and MCP requests
+   • Auto decrypt interval - how long to wait after the databases stop changing before decrypting
+   • Destinations - URLs and headers used by webhooks and summaries
+   • Scheduled jobs - when and what to export nightly, back up weekly and summarize daily
+   • Language - set [yellow]language[white] in the config file to zh-CN or en, or set CHATLOG_LANG
+   Input is validated before it is written to the config file; changing the address or work directory restarts a running HTTP server
+
+[green]HTTP API:[white]
+• Chat history: [yellow]GET http://localhost:5030/api/v1/chatlog?time=2023-01-01&talker=wxid_xxx[white]
+• Contacts: [yellow]GET http://localhost:5030/api/v1/contact[white]
+• Groups: [yellow]GET http://localhost:5030/api/v1/chatroom[white]
+• Sessions: [yellow]GET http://localhost:5030/api/v1/session[white]
+
+[green]MCP:[white]
+Chatlog supports the Model Context Protocol and works with MCP-capable AI assistants.
+Through MCP, assistants can query your chat history, contacts and groups directly.
+
+[green]FAQ:[white]
+• If getting the key fails, make sure WeChat is running
+• If decryption fails, check that the key was obtained correctly
+• If the HTTP server fails to start, check whether the port is in use
+• The data and work directories are saved and loaded again on the next start
+
+[green]Privacy:[white]
+• All data is processed locally and never uploaded to any external server
+• Keep decrypted data safe to protect your privacy
 `
 )
 
+// content 返回当前语言的帮助内容
+func content() string {
+	if i18n.Lang() == i18n.EN {
+		return ContentEN
+	}
+	return Content
+}
+
 type Help struct {
 	*tview.TextView
 	title string
@@ -81,9 +203,14 @@ func New() *Help {
 	help.SetTextAlign(tview.AlignLeft)
 	help.SetBorder(true)
 	help.SetBorderColor(style.BorderColor)
-	help.SetTitle(ShowTitle)
-
-	fmt.Fprint(help, Content)
+	help.SetTitle(i18n.T(ShowTitle))
+
+	// 按当前配色替换内容中的颜色标签
+	fmt.Fprint(help, strings.NewReplacer(
+		"[yellow]", style.Tag(style.HighlightColor),
+		"[green]", style.Tag(style.SuccessColor),
+		"[white]", "[-]",
+	).Replace(content()))
 
 	return help
 }
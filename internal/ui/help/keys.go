@@ -0,0 +1,178 @@
+package help
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DanielMao1/chatlog/internal/i18n"
+	"github.com/DanielMao1/chatlog/internal/ui/keymap"
+	"github.com/DanielMao1/chatlog/internal/ui/style"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const (
+	KeysTitle = "keys"
+
+	// keysWidth 按键说明窗口的宽度
+	keysWidth = 64
+)
+
+// 显示按键说明的界面
+const (
+	ScreenMenu    = "menu"    // 主菜单
+	ScreenHelp    = "help"    // 帮助页面
+	ScreenSubmenu = "submenu" // 子菜单，如设置
+	ScreenForm    = "form"    // 输入表单，如导出与设置项
+	ScreenPicker  = "picker"  // 选择联系人或群聊
+	ScreenBrowser = "browser" // 浏览聊天记录
+	ScreenSearch  = "search"  // 搜索聊天记录
+)
+
+// Hint 一个按键及其作用
+type Hint struct {
+	Key    string
+	Action string
+}
+
+// Hints 返回界面中可用的按键，最后附加所有界面通用的按键
+func Hints(screen string) []Hint {
+	key := keymap.Label
+	var hints []Hint
+	switch screen {
+	case ScreenMenu:
+		hints = []Hint{
+			{key(keymap.Up) + "/" + key(keymap.Down), i18n.T("选择菜单项")},
+			{key(keymap.Select), i18n.T("执行菜单项，如获取密钥、解密数据")},
+			{key(keymap.Left) + "/" + key(keymap.Right), i18n.T("切换主菜单与帮助页面")},
+		}
+	case ScreenHelp:
+		hints = []Hint{
+			{key(keymap.Up) + "/" + key(keymap.Down), i18n.T("滚动帮助内容")},
+			{key(keymap.Left) + "/" + key(keymap.Right), i18n.T("切换主菜单与帮助页面")},
+		}
+	case ScreenSubmenu:
+		hints = []Hint{
+			{key(keymap.Up) + "/" + key(keymap.Down), i18n.T("选择菜单项")},
+			{key(keymap.Select), i18n.T("打开选中的项")},
+			{key(keymap.Back), i18n.T("返回主菜单")},
+		}
+	case ScreenForm:
+		hints = []Hint{
+			{"Tab", i18n.T("切换输入框与按钮")},
+			{"Enter", i18n.T("确认输入或按下按钮")},
+			{"ESC", i18n.T("取消并返回")},
+		}
+	case ScreenPicker:
+		hints = []Hint{
+			{i18n.T("输入"), i18n.T("按备注、昵称、微信号或拼音首字母搜索")},
+			{key(keymap.Up) + "/" + key(keymap.Down), i18n.T("选择联系人或群聊")},
+			{key(keymap.Select), i18n.T("确认选择")},
+			{key(keymap.Back), i18n.T("返回")},
+		}
+	case ScreenBrowser:
+		hints = []Hint{
+			{key(keymap.Select), i18n.T("打开选中的会话")},
+			{key(keymap.SwitchPane), i18n.T("在会话与消息之间切换")},
+			{key(keymap.Search), i18n.T("搜索会话")},
+			{key(keymap.Contacts), i18n.T("从联系人和群聊中选择")},
+			{key(keymap.Earlier), i18n.T("加载更早的消息")},
+			{key(keymap.NextImage), i18n.T("选中下一张图片")},
+			{key(keymap.Preview), i18n.T("预览选中的图片")},
+			{key(keymap.NextMessage) + "/" + key(keymap.PrevMessage), i18n.T("选中下一条或上一条消息")},
+			{key(keymap.CopyMessage), i18n.T("复制选中消息的文本")},
+			{key(keymap.CopyMediaPath), i18n.T("复制选中图片、视频或文件的路径")},
+			{key(keymap.ShowPath), i18n.T("显示或隐藏选中图片、视频或文件的路径")},
+			{key(keymap.Filter), i18n.T("切换筛选：全部、文件、图片、链接、提到我")},
+			{key(keymap.Pin), i18n.T("置顶或取消置顶会话")},
+			{key(keymap.Narrow) + "/" + key(keymap.Widen), i18n.T("调整会话列表宽度")},
+			{key(keymap.Back), i18n.T("返回主菜单")},
+		}
+	case ScreenSearch:
+		hints = []Hint{
+			{"Enter", i18n.T("搜索关键字或打开选中的结果")},
+			{"Tab", i18n.T("在输入框与结果之间切换")},
+			{key(keymap.Search), i18n.T("回到输入框")},
+			{"ESC", i18n.T("返回主菜单")},
+		}
+	}
+	return append(hints,
+		Hint{key(keymap.Log), i18n.T("展开或收起日志面板")},
+		Hint{key(keymap.LogLevel), i18n.T("切换日志级别")},
+		Hint{key(keymap.Help), i18n.T("显示或关闭按键说明")},
+		Hint{key(keymap.CopyDataKey) + "/" + key(keymap.CopyImgKey), i18n.T("复制数据密钥或图片密钥")},
+		Hint{key(keymap.Quit), i18n.T("退出程序")},
+	)
+}
+
+// KeysText 返回按键说明的文本，按键右对齐到同一列
+func KeysText(hints []Hint) string {
+	width := 0
+	for _, h := range hints {
+		width = max(width, tview.TaggedStringWidth(tview.Escape(h.Key)))
+	}
+	var b strings.Builder
+	for _, h := range hints {
+		pad := strings.Repeat(" ", width-tview.TaggedStringWidth(tview.Escape(h.Key)))
+		fmt.Fprintf(&b, " %s[%s::b]%s[-::-]  %s\n", pad, style.GetColorHex(style.MenuBgColor), tview.Escape(h.Key), tview.Escape(h.Action))
+	}
+	return b.String()
+}
+
+// Keys 居中显示的按键说明，按 ESC、Enter 或帮助键关闭
+type Keys struct {
+	*tview.Flex
+	view *tview.TextView
+}
+
+// NewKeys 创建按键说明窗口，done 在关闭时调用
+func NewKeys(hints []Hint, done func()) *Keys {
+	view := tview.NewTextView().
+		SetDynamicColors(true).
+		SetText(KeysText(hints))
+	view.SetBorder(true).
+		SetBorderColor(style.BorderColor).
+		SetTitle(i18n.Tf(" 按键说明 (%s 关闭) ", keymap.Label(keymap.Back)))
+	view.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape || event.Key() == tcell.KeyEnter || keymap.Match(event, keymap.Help) {
+			done()
+			return nil
+		}
+		return event
+	})
+
+	height := len(hints) + 2
+	return &Keys{
+		Flex: tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+				AddItem(nil, 0, 1, false).
+				AddItem(view, height, 0, true).
+				AddItem(nil, 0, 1, false), keysWidth, 0, true).
+			AddItem(nil, 0, 1, false),
+		view: view,
+	}
+}
+
+// Focus 聚焦按键说明，以便接收关闭的按键
+func (k *Keys) Focus(delegate func(p tview.Primitive)) {
+	delegate(k.view)
+}
+
+// Step 首次运行引导中的一步
+type Step struct {
+	Title string
+	Text  string
+}
+
+// Tour 返回首次运行时的引导步骤
+func Tour() []Step {
+	return []Step{
+		{i18n.T("欢迎使用 Chatlog"), i18n.T("接下来用几步介绍如何解密并查看微信聊天记录，可以随时跳过。")},
+		{i18n.T("1. 获取密钥"), i18n.T("保持微信登录并运行，在主菜单选择「获取密钥」，程序会从微信进程中读取数据库密钥。")},
+		{i18n.T("2. 解密数据"), i18n.T("获取密钥后选择「解密数据」，解密后的数据库保存在工作目录中；选择「开启自动解密」后新消息会自动解密。")},
+		{i18n.T("3. 查看聊天记录"), i18n.T("选择「浏览聊天记录」或「搜索聊天记录」直接在终端中阅读，或「启动 HTTP 服务」后通过浏览器与 MCP 访问。")},
+		{i18n.T("随时查看按键"), i18n.Tf("在任意界面按 %s 查看当前可用的按键，按 %s 可以查看日志了解失败的原因。", keymap.Label(keymap.Help), keymap.Label(keymap.Log))},
+	}
+}
@@ -0,0 +1,269 @@
+package keymap
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// 可以自定义按键的操作
+const (
+	Quit       = "quit"        // 退出程序
+	Up         = "up"          // 向上移动
+	Down       = "down"        // 向下移动
+	Left       = "left"        // 上一个标签页
+	Right      = "right"       // 下一个标签页
+	Select     = "select"      // 确认选择
+	Back       = "back"        // 返回上一级
+	Log        = "log"         // 展开或收起日志面板
+	LogLevel   = "log_level"   // 切换日志级别
+	Help       = "help"        // 显示当前界面的按键说明
+	Search     = "search"      // 浏览聊天记录时搜索会话
+	Contacts   = "contacts"    // 浏览聊天记录时选择联系人或群聊
+	Earlier    = "earlier"     // 加载更早的消息
+	SwitchPane = "switch_pane" // 在会话与消息窗格之间切换
+	Narrow     = "narrow"      // 缩小会话列表
+	Widen      = "widen"       // 加宽会话列表
+	NextImage  = "next_image"  // 选中下一张图片
+	Preview    = "preview"     // 预览选中的图片
+
+	NextMessage   = "next_message"    // 选中下一条消息
+	PrevMessage   = "prev_message"    // 选中上一条消息
+	CopyMessage   = "copy_message"    // 复制选中消息的文本
+	CopyMediaPath = "copy_media_path" // 复制选中消息的媒体文件路径
+	ShowPath      = "show_path"       // 显示或隐藏选中消息的媒体文件路径
+	Filter        = "filter"          // 切换消息类型筛选
+	Pin           = "pin"             // 置顶或取消置顶会话
+	CopyDataKey   = "copy_data_key"   // 复制数据密钥
+	CopyImgKey    = "copy_img_key"    // 复制图片密钥
+)
+
+// Key 一个按键，Key 为 tcell.KeyRune 时使用 Rune
+type Key struct {
+	Key  tcell.Key
+	Rune rune
+	Alt  bool
+}
+
+// Defaults 默认按键
+var Defaults = map[string]string{
+	Quit:       "ctrl+c",
+	Up:         "up",
+	Down:       "down",
+	Left:       "left",
+	Right:      "right",
+	Select:     "enter",
+	Back:       "esc",
+	Log:        "f2",
+	LogLevel:   "f3",
+	Help:       "?",
+	Search:     "/",
+	Contacts:   "c",
+	Earlier:    "b",
+	SwitchPane: "tab",
+	Narrow:     "<",
+	Widen:      ">",
+	NextImage:  "i",
+	Preview:    "v",
+
+	NextMessage:   "n",
+	PrevMessage:   "p",
+	CopyMessage:   "y",
+	CopyMediaPath: "Y",
+	ShowPath:      "o",
+	Filter:        "f",
+	Pin:           "*",
+	CopyDataKey:   "K",
+	CopyImgKey:    "I",
+}
+
+// navigation 导航操作由界面组件按默认按键处理，自定义按键会转换为默认按键
+var navigation = []string{Up, Down, Left, Right, Select, Back}
+
+var (
+	bindings = mustParse(Defaults)
+	names    = keyNames()
+)
+
+// Load 用 keys 中的按键替换对应操作的默认按键，多个按键用逗号分隔，如 "k, up"
+// 需在创建界面组件之前调用
+func Load(keys map[string]string) error {
+	merged := make(map[string]string, len(Defaults))
+	for action, value := range Defaults {
+		merged[action] = value
+	}
+	for action, value := range keys {
+		action = strings.ToLower(action)
+		if _, ok := Defaults[action]; !ok {
+			return fmt.Errorf("unknown key action %q, available: %s", action, strings.Join(Actions(), ", "))
+		}
+		merged[action] = value
+	}
+	parsed, err := parse(merged)
+	if err != nil {
+		return err
+	}
+	bindings = parsed
+	return nil
+}
+
+// Actions 返回所有可以自定义按键的操作
+func Actions() []string {
+	actions := make([]string, 0, len(Defaults))
+	for action := range Defaults {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	return actions
+}
+
+// Match 判断按键是否绑定到 action
+func Match(event *tcell.EventKey, action string) bool {
+	for _, key := range bindings[action] {
+		if key.Match(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// Translate 将绑定到导航操作的自定义按键转换为默认按键，交给界面组件处理
+// typing 为 true 时正在输入文字，不转换字符按键
+func Translate(event *tcell.EventKey, typing bool) *tcell.EventKey {
+	if typing && event.Key() == tcell.KeyRune {
+		return event
+	}
+	for _, action := range navigation {
+		if !Match(event, action) {
+			continue
+		}
+		target, _ := ParseKey(Defaults[action])
+		if target.Match(event) {
+			return event
+		}
+		return tcell.NewEventKey(target.Key, target.Rune, tcell.ModNone)
+	}
+	return event
+}
+
+// Label 返回操作的第一个按键，用于帮助信息
+func Label(action string) string {
+	keys := bindings[action]
+	if len(keys) == 0 {
+		return ""
+	}
+	return keys[0].String()
+}
+
+// ParseKey 解析按键，如 "j"、"ctrl+n"、"alt+x"、"f2"、"esc"、"enter"、"up"
+func ParseKey(s string) (Key, error) {
+	s = strings.TrimSpace(s)
+	if utf8.RuneCountInString(s) == 1 {
+		r, _ := utf8.DecodeRuneInString(s)
+		return Key{Key: tcell.KeyRune, Rune: r}, nil
+	}
+
+	lower := strings.ToLower(s)
+	key := Key{}
+	if rest, ok := strings.CutPrefix(lower, "alt+"); ok {
+		key.Alt = true
+		lower = rest
+		if utf8.RuneCountInString(lower) == 1 {
+			key.Key = tcell.KeyRune
+			key.Rune, _ = utf8.DecodeRuneInString(s[len("alt+"):])
+			return key, nil
+		}
+	}
+	if rest, ok := strings.CutPrefix(lower, "ctrl+"); ok && len(rest) == 1 && rest[0] >= 'a' && rest[0] <= 'z' {
+		key.Key = tcell.KeyCtrlA + tcell.Key(rest[0]-'a')
+		return key, nil
+	}
+	if lower == "space" {
+		key.Key, key.Rune = tcell.KeyRune, ' '
+		return key, nil
+	}
+	k, ok := names[strings.ReplaceAll(lower, "+", "-")]
+	if !ok {
+		return Key{}, fmt.Errorf("invalid key %q", s)
+	}
+	key.Key = k
+	return key, nil
+}
+
+// Match 判断事件是否为该按键
+func (k Key) Match(event *tcell.EventKey) bool {
+	if k.Alt != (event.Modifiers()&tcell.ModAlt != 0) {
+		return false
+	}
+	if k.Key == tcell.KeyRune {
+		return event.Key() == tcell.KeyRune && event.Rune() == k.Rune
+	}
+	return event.Key() == k.Key
+}
+
+func (k Key) String() string {
+	var name string
+	switch {
+	case k.Key == tcell.KeyRune && k.Rune == ' ':
+		name = "Space"
+	case k.Key == tcell.KeyRune:
+		name = string(k.Rune)
+	case k.Key == tcell.KeyUp:
+		name = "↑"
+	case k.Key == tcell.KeyDown:
+		name = "↓"
+	case k.Key == tcell.KeyLeft:
+		name = "←"
+	case k.Key == tcell.KeyRight:
+		name = "→"
+	case k.Key == tcell.KeyEscape:
+		name = "ESC"
+	case len(tcell.KeyNames[k.Key]) != 0:
+		name = strings.ReplaceAll(tcell.KeyNames[k.Key], "-", "+")
+	default:
+		name = fmt.Sprintf("Ctrl+%c", 'A'+rune(k.Key-tcell.KeyCtrlA))
+	}
+	if k.Alt {
+		return "Alt+" + name
+	}
+	return name
+}
+
+func parse(keys map[string]string) (map[string][]Key, error) {
+	result := make(map[string][]Key, len(keys))
+	for action, value := range keys {
+		for _, s := range strings.Split(value, ",") {
+			if len(strings.TrimSpace(s)) == 0 {
+				continue
+			}
+			key, err := ParseKey(s)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", action, err)
+			}
+			result[action] = append(result[action], key)
+		}
+	}
+	return result, nil
+}
+
+func mustParse(keys map[string]string) map[string][]Key {
+	result, err := parse(keys)
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+// keyNames 返回小写的按键名称，如 "esc"、"f2"、"pgdn"
+func keyNames() map[string]tcell.Key {
+	result := make(map[string]tcell.Key, len(tcell.KeyNames))
+	for k, name := range tcell.KeyNames {
+		result[strings.ToLower(name)] = k
+	}
+	result["escape"] = tcell.KeyEscape
+	result["return"] = tcell.KeyEnter
+	return result
+}
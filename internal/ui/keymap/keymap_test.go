@@ -0,0 +1,62 @@
+package keymap
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Key
+		name string
+	}{
+		{"j", Key{Key: tcell.KeyRune, Rune: 'j'}, "j"},
+		{"ctrl+n", Key{Key: tcell.KeyCtrlN}, "Ctrl+N"},
+		{"Alt+X", Key{Key: tcell.KeyRune, Rune: 'X', Alt: true}, "Alt+X"},
+		{"F2", Key{Key: tcell.KeyF2}, "F2"},
+		{"esc", Key{Key: tcell.KeyEscape}, "ESC"},
+		{"enter", Key{Key: tcell.KeyEnter}, "Enter"},
+		{"up", Key{Key: tcell.KeyUp}, "↑"},
+		{"space", Key{Key: tcell.KeyRune, Rune: ' '}, "Space"},
+	}
+	for _, tt := range tests {
+		got, err := ParseKey(tt.in)
+		if err != nil {
+			t.Errorf("ParseKey(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want || got.String() != tt.name {
+			t.Errorf("ParseKey(%q) = %+v (%s), want %+v (%s)", tt.in, got, got, tt.want, tt.name)
+		}
+	}
+	if _, err := ParseKey("hyper+q"); err == nil {
+		t.Error("expected error for invalid key")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	defer Load(nil)
+
+	if err := Load(map[string]string{"down": "j, down", "quit": "q"}); err != nil {
+		t.Fatal(err)
+	}
+	j := tcell.NewEventKey(tcell.KeyRune, 'j', tcell.ModNone)
+	if got := Translate(j, false); got.Key() != tcell.KeyDown {
+		t.Errorf("j translated to %v, want down", got.Key())
+	}
+	if got := Translate(j, true); got != j {
+		t.Error("runes should not be translated while typing")
+	}
+	if !Match(tcell.NewEventKey(tcell.KeyRune, 'q', tcell.ModNone), Quit) || Match(tcell.NewEventKey(tcell.KeyCtrlC, 0, tcell.ModCtrl), Quit) {
+		t.Error("quit key not replaced")
+	}
+	if Label(Down) != "j" {
+		t.Errorf("Label(down) = %s", Label(Down))
+	}
+
+	if err := Load(map[string]string{"jump": "x"}); err == nil {
+		t.Error("expected error for unknown action")
+	}
+}
@@ -0,0 +1,104 @@
+package logview
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/DanielMao1/chatlog/internal/i18n"
+	"github.com/DanielMao1/chatlog/internal/ui/keymap"
+	"github.com/DanielMao1/chatlog/internal/ui/style"
+	"github.com/DanielMao1/chatlog/pkg/logbuf"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/rs/zerolog"
+)
+
+const (
+	Title = "log"
+
+	// Height 日志面板展开时的高度
+	Height = 12
+)
+
+// Levels 依次切换的过滤级别
+var Levels = []zerolog.Level{zerolog.DebugLevel, zerolog.InfoLevel, zerolog.WarnLevel, zerolog.ErrorLevel}
+
+// LogView 显示缓冲区中的日志，只显示不低于过滤级别的日志
+type LogView struct {
+	*tview.TextView
+	buf     *logbuf.Buffer
+	level   zerolog.Level
+	version uint64
+	writer  zerolog.ConsoleWriter
+	out     *bytes.Buffer
+}
+
+func New(buf *logbuf.Buffer) *LogView {
+	out := &bytes.Buffer{}
+	v := &LogView{
+		TextView: tview.NewTextView(),
+		buf:      buf,
+		level:    zerolog.InfoLevel,
+		out:      out,
+		writer:   zerolog.ConsoleWriter{Out: out, NoColor: true, TimeFormat: "15:04:05"},
+	}
+
+	v.SetDynamicColors(true).
+		SetScrollable(true).
+		SetWrap(true).
+		SetBorder(true).
+		SetBorderColor(style.BorderColor)
+	v.updateTitle()
+
+	return v
+}
+
+// NextLevel 切换到下一个过滤级别并重新显示日志
+func (v *LogView) NextLevel() {
+	for i, level := range Levels {
+		if level == v.level {
+			v.level = Levels[(i+1)%len(Levels)]
+			break
+		}
+	}
+	v.updateTitle()
+	v.Refresh(true)
+}
+
+// Refresh 有新日志或 force 时重新显示日志，并滚动到最新一条
+func (v *LogView) Refresh(force bool) {
+	version := v.buf.Version()
+	if !force && version == v.version {
+		return
+	}
+	v.version = version
+
+	text := strings.Builder{}
+	for _, e := range v.buf.Entries(v.level) {
+		v.out.Reset()
+		if _, err := v.writer.Write(e.Data); err != nil {
+			v.out.Write(e.Data)
+		}
+		fmt.Fprintf(&text, "%s%s[-]\n", style.Tag(levelColor(e.Level)), tview.Escape(strings.TrimRight(v.out.String(), "\n")))
+	}
+	v.SetText(text.String())
+	v.ScrollToEnd()
+}
+
+func (v *LogView) updateTitle() {
+	v.SetTitle(i18n.Tf(" 日志 (%s 及以上，%s 切换级别，%s 收起) ", v.level, keymap.Label(keymap.LogLevel), keymap.Label(keymap.Log)))
+}
+
+func levelColor(level zerolog.Level) tcell.Color {
+	switch level {
+	case zerolog.DebugLevel, zerolog.TraceLevel:
+		return style.MutedColor
+	case zerolog.WarnLevel:
+		return style.PausedStatusFgColor
+	case zerolog.ErrorLevel, zerolog.FatalLevel, zerolog.PanicLevel:
+		return style.PrgBarCritColor
+	}
+	return style.FgColor
+}
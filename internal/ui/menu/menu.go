@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sort"
 
+	"github.com/DanielMao1/chatlog/internal/i18n"
 	"github.com/DanielMao1/chatlog/internal/ui/style"
 
 	"github.com/gdamore/tcell/v2"
@@ -41,6 +42,7 @@ func New(title string) *Menu {
 	menu.table.SetBackgroundColor(style.BgColor)
 	menu.table.SetTitleColor(style.FgColor)
 	menu.table.SetFixed(1, 0)
+	menu.table.SetSelectedStyle(style.SelectedStyle)
 	menu.table.Select(1, 0).SetSelectedFunc(func(row, column int) {
 		if row == 0 {
 			return // 忽略表头
@@ -60,14 +62,14 @@ func New(title string) *Menu {
 }
 
 func (m *Menu) setTableHeader() {
-	m.table.SetCell(0, 0, tview.NewTableCell(fmt.Sprintf("[black::b]%s", "命令")).
+	m.table.SetCell(0, 0, tview.NewTableCell(fmt.Sprintf("[%s::b]%s", style.GetColorHex(style.MenuHeaderFgColor), i18n.T("命令"))).
 		SetExpansion(1).
 		SetBackgroundColor(style.PageHeaderBgColor).
 		SetTextColor(style.PageHeaderFgColor).
 		SetAlign(tview.AlignLeft).
 		SetSelectable(false))
 
-	m.table.SetCell(0, 1, tview.NewTableCell(fmt.Sprintf("[black::b]%s", "说明")).
+	m.table.SetCell(0, 1, tview.NewTableCell(fmt.Sprintf("[%s::b]%s", style.GetColorHex(style.MenuHeaderFgColor), i18n.T("说明"))).
 		SetExpansion(2).
 		SetBackgroundColor(style.PageHeaderBgColor).
 		SetTextColor(style.PageHeaderFgColor).
@@ -103,12 +105,14 @@ func (m *Menu) refresh() {
 			SetTextColor(style.FgColor).
 			SetBackgroundColor(style.BgColor).
 			SetReference(item).
-			SetAlign(tview.AlignLeft))
+			SetAlign(tview.AlignLeft).
+			SetClickedFunc(clicked(item)))
 		m.table.SetCell(row, 1, tview.NewTableCell(item.Description).
 			SetTextColor(style.FgColor).
 			SetBackgroundColor(style.BgColor).
 			SetReference(item).
-			SetAlign(tview.AlignLeft))
+			SetAlign(tview.AlignLeft).
+			SetClickedFunc(clicked(item)))
 		row++
 	}
 
@@ -147,6 +151,26 @@ func (m *Menu) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.
 	})
 }
 
+// MouseHandler 将鼠标事件传递给表格
+func (m *Menu) MouseHandler() func(action tview.MouseAction, event *tcell.EventMouse, setFocus func(p tview.Primitive)) (consumed bool, capture tview.Primitive) {
+	return m.WrapMouseHandler(func(action tview.MouseAction, event *tcell.EventMouse, setFocus func(p tview.Primitive)) (consumed bool, capture tview.Primitive) {
+		if handler := m.table.MouseHandler(); handler != nil {
+			return handler(action, event, setFocus)
+		}
+		return false, nil
+	})
+}
+
+// clicked 返回单击菜单项时的处理函数，单击与按 Enter 效果相同
+func clicked(item *Item) func() bool {
+	return func() bool {
+		if item.Selected != nil {
+			item.Selected(item)
+		}
+		return false
+	}
+}
+
 type SortItems []*Item
 
 func (l SortItems) Len() int {
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"sort"
 
+	"github.com/DanielMao1/chatlog/internal/i18n"
+	"github.com/DanielMao1/chatlog/internal/ui/keymap"
 	"github.com/DanielMao1/chatlog/internal/ui/style"
 
 	"github.com/gdamore/tcell/v2"
@@ -52,6 +54,7 @@ func NewSubMenu(title string) *SubMenu {
 	subMenu.table.SetBackgroundColor(style.DialogBgColor)
 	subMenu.table.SetTitleColor(style.DialogFgColor)
 	subMenu.table.SetFixed(1, 1)
+	subMenu.table.SetSelectedStyle(style.SelectedStyle)
 
 	subMenu.table.Select(1, 0).SetSelectedFunc(func(row, column int) {
 		if row == 0 {
@@ -73,10 +76,10 @@ func NewSubMenu(title string) *SubMenu {
 	helpText.SetTextColor(style.DialogFgColor)
 	helpText.SetBackgroundColor(style.DialogBgColor)
 	fmt.Fprintf(helpText,
-		"[%s::b]↑/↓[%s::b]: 导航  [%s::b]Enter[%s::b]: 选择  [%s::b]ESC[%s::b]: 返回",
-		style.GetColorHex(style.MenuBgColor), style.GetColorHex(style.PageHeaderFgColor),
-		style.GetColorHex(style.MenuBgColor), style.GetColorHex(style.PageHeaderFgColor),
-		style.GetColorHex(style.MenuBgColor), style.GetColorHex(style.PageHeaderFgColor),
+		i18n.T("[%s::b]%s[%s::b]: 导航  [%s::b]%s[%s::b]: 选择  [%s::b]%s[%s::b]: 返回"),
+		style.GetColorHex(style.MenuBgColor), tview.Escape(keymap.Label(keymap.Up)+"/"+keymap.Label(keymap.Down)), style.GetColorHex(style.PageHeaderFgColor),
+		style.GetColorHex(style.MenuBgColor), tview.Escape(keymap.Label(keymap.Select)), style.GetColorHex(style.PageHeaderFgColor),
+		style.GetColorHex(style.MenuBgColor), tview.Escape(keymap.Label(keymap.Back)), style.GetColorHex(style.PageHeaderFgColor),
 	)
 
 	// 布局
@@ -99,14 +102,14 @@ func NewSubMenu(title string) *SubMenu {
 }
 
 func (m *SubMenu) setTableHeader() {
-	m.table.SetCell(0, 0, tview.NewTableCell(fmt.Sprintf("[%s::b]%s", style.GetColorHex(style.TableHeaderFgColor), "命令")).
+	m.table.SetCell(0, 0, tview.NewTableCell(fmt.Sprintf("[%s::b]%s", style.GetColorHex(style.TableHeaderFgColor), i18n.T("命令"))).
 		SetExpansion(1).
 		SetBackgroundColor(style.TableHeaderBgColor).
 		SetTextColor(style.TableHeaderFgColor).
 		SetAlign(tview.AlignLeft).
 		SetSelectable(false))
 
-	m.table.SetCell(0, 1, tview.NewTableCell(fmt.Sprintf("[%s::b]%s", style.GetColorHex(style.TableHeaderFgColor), "说明")).
+	m.table.SetCell(0, 1, tview.NewTableCell(fmt.Sprintf("[%s::b]%s", style.GetColorHex(style.TableHeaderFgColor), i18n.T("说明"))).
 		SetExpansion(1).
 		SetBackgroundColor(style.TableHeaderBgColor).
 		SetTextColor(style.TableHeaderFgColor).
@@ -146,18 +149,17 @@ func (m *SubMenu) refresh() {
 			SetTextColor(style.DialogFgColor).
 			SetBackgroundColor(style.DialogBgColor).
 			SetReference(item).
-			SetAlign(tview.AlignLeft))
+			SetAlign(tview.AlignLeft).
+			SetClickedFunc(clicked(item)))
 		m.table.SetCell(row, 1, tview.NewTableCell(item.Description).
 			SetTextColor(style.DialogFgColor).
 			SetBackgroundColor(style.DialogBgColor).
 			SetReference(item).
-			SetAlign(tview.AlignLeft))
-		if len(item.Name) > col1Width {
-			col1Width = len(item.Name)
-		}
-		if len(item.Description) > col2Width {
-			col2Width = len(item.Description)
-		}
+			SetAlign(tview.AlignLeft).
+			SetClickedFunc(clicked(item)))
+		// 按显示宽度计算，忽略颜色标签
+		col1Width = max(col1Width, tview.TaggedStringWidth(item.Name))
+		col2Width = max(col2Width, tview.TaggedStringWidth(item.Description))
 		row++
 	}
 
@@ -223,6 +225,19 @@ func (m *SubMenu) InputHandler() func(event *tcell.EventKey, setFocus func(p tvi
 	})
 }
 
+// MouseHandler 将鼠标事件传递给表格
+func (m *SubMenu) MouseHandler() func(action tview.MouseAction, event *tcell.EventMouse, setFocus func(p tview.Primitive)) (consumed bool, capture tview.Primitive) {
+	return m.WrapMouseHandler(func(action tview.MouseAction, event *tcell.EventMouse, setFocus func(p tview.Primitive)) (consumed bool, capture tview.Primitive) {
+		if !m.InRect(event.Position()) {
+			return false, nil
+		}
+		if handler := m.table.MouseHandler(); handler != nil {
+			consumed, capture = handler(action, event, setFocus)
+		}
+		return true, capture
+	})
+}
+
 func EmptyBoxSpace(bgColor tcell.Color) *tview.Box {
 	box := tview.NewBox()
 	box.SetBackgroundColor(bgColor)
@@ -0,0 +1,306 @@
+package picker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/DanielMao1/chatlog/internal/i18n"
+	"github.com/DanielMao1/chatlog/internal/model"
+	"github.com/DanielMao1/chatlog/internal/ui/keymap"
+	"github.com/DanielMao1/chatlog/internal/ui/style"
+	"github.com/DanielMao1/chatlog/pkg/util"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const (
+	Title = "picker"
+
+	// MaxItems 列表每次显示的匹配项数量，选中列表末尾时继续显示下一页
+	MaxItems = 200
+)
+
+// Item 可选择的联系人或群聊
+type Item struct {
+	UserName   string
+	Name       string
+	Alias      string
+	Remark     string
+	NickName   string
+	IsChatRoom bool
+	// Pinned 是否为置顶的聊天对象
+	Pinned bool
+
+	// 匹配使用的小写文本与拼音首字母，拼音首字母在首次按拼音匹配时计算
+	keys     []string
+	initials []string
+	pinyin   bool
+}
+
+// ContactItem 由联系人创建选择项
+func ContactItem(c *model.Contact) *Item {
+	return newItem(&Item{
+		UserName: c.UserName,
+		Name:     c.DisplayName(),
+		Alias:    c.Alias,
+		Remark:   c.Remark,
+		NickName: c.NickName,
+	})
+}
+
+// ChatRoomItem 由群聊创建选择项
+func ChatRoomItem(c *model.ChatRoom) *Item {
+	return newItem(&Item{
+		UserName:   c.Name,
+		Name:       c.DisplayName(),
+		Remark:     c.Remark,
+		NickName:   c.NickName,
+		IsChatRoom: true,
+	})
+}
+
+func newItem(item *Item) *Item {
+	for _, s := range []string{item.Remark, item.NickName, item.Alias, item.UserName} {
+		if len(s) == 0 {
+			continue
+		}
+		item.keys = append(item.keys, strings.ToLower(s))
+	}
+	return item
+}
+
+// loadInitials 计算备注与昵称的拼音首字母，联系人很多时避免在打开列表时全部计算
+func (i *Item) loadInitials() {
+	if i.pinyin {
+		return
+	}
+	i.pinyin = true
+	for _, s := range []string{i.Remark, i.NickName} {
+		if initials := util.PinyinInitials(s); len(initials) != 0 {
+			i.initials = append(i.initials, initials)
+		}
+	}
+}
+
+// PinFirst 将置顶的聊天对象按置顶顺序移到最前面并标记，其余选择项保持原有顺序
+func PinFirst(items []*Item, pinned []string) []*Item {
+	if len(pinned) == 0 {
+		return items
+	}
+	byName := make(map[string]*Item, len(items))
+	for _, item := range items {
+		byName[item.UserName] = item
+	}
+	ret := make([]*Item, 0, len(items))
+	for _, t := range pinned {
+		if item, ok := byName[t]; ok && !item.Pinned {
+			item.Pinned = true
+			ret = append(ret, item)
+		}
+	}
+	for _, item := range items {
+		if !item.Pinned {
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
+
+// Match 返回选择项与查询的匹配程度，0 为前缀匹配，1 为包含，2 为拼音首字母匹配，-1 为不匹配
+func (i *Item) Match(query string) int {
+	if len(query) == 0 {
+		return 0
+	}
+	query = strings.ToLower(query)
+	score := -1
+	for _, key := range i.keys {
+		if strings.HasPrefix(key, query) {
+			return 0
+		}
+		if strings.Contains(key, query) {
+			score = 1
+		}
+	}
+	if score >= 0 {
+		return score
+	}
+	i.loadInitials()
+	for _, initials := range i.initials {
+		if strings.Contains(initials, query) {
+			return 2
+		}
+	}
+	return -1
+}
+
+// Filter 返回与查询匹配的选择项，按匹配程度排序，同等匹配保持原有顺序
+func Filter(items []*Item, query string) []*Item {
+	query = strings.TrimSpace(query)
+	type scored struct {
+		item  *Item
+		score int
+	}
+	matched := make([]scored, 0)
+	for _, item := range items {
+		if score := item.Match(query); score >= 0 {
+			matched = append(matched, scored{item, score})
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].score < matched[j].score
+	})
+	ret := make([]*Item, 0, len(matched))
+	for _, m := range matched {
+		ret = append(ret, m.item)
+	}
+	return ret
+}
+
+// Picker 支持增量搜索的联系人与群聊列表
+type Picker struct {
+	*tview.Flex
+	input    *tview.InputField
+	list     *tview.List
+	items    []*Item
+	filtered []*Item
+	// shown 列表中已显示的匹配项数量
+	shown    int
+	selected func(*Item)
+	cancel   func()
+}
+
+// New 创建选择列表，selected 在按 Enter 选中时调用，cancel 在按 ESC 时调用
+func New(title string, selected func(*Item), cancel func()) *Picker {
+	p := &Picker{
+		Flex:     tview.NewFlex(),
+		input:    tview.NewInputField(),
+		list:     tview.NewList(),
+		selected: selected,
+		cancel:   cancel,
+	}
+
+	p.input.
+		SetLabel(i18n.T("搜索: ")).
+		SetPlaceholder(i18n.T("备注、昵称、微信号或拼音首字母")).
+		SetFieldBackgroundColor(style.InputFieldBgColor).
+		SetChangedFunc(func(text string) {
+			p.refresh()
+		})
+	p.input.SetInputCapture(p.inputCapture)
+
+	p.list.
+		ShowSecondaryText(false).
+		SetHighlightFullLine(true).
+		SetSelectedStyle(style.SelectedStyle).
+		SetChangedFunc(func(index int, _ string, _ string, _ rune) {
+			if index >= p.shown-1 {
+				p.showMore()
+			}
+		})
+
+	help := tview.NewTextView().
+		SetDynamicColors(true).
+		SetTextAlign(tview.AlignCenter)
+	fmt.Fprintf(help,
+		i18n.T("[%s::b]输入[%s::b]: 搜索  [%s::b]%s[%s::b]: 导航  [%s::b]%s[%s::b]: 选择  [%s::b]%s[%s::b]: 返回"),
+		style.GetColorHex(style.MenuBgColor), style.GetColorHex(style.PageHeaderFgColor),
+		style.GetColorHex(style.MenuBgColor), tview.Escape(keymap.Label(keymap.Up)+"/"+keymap.Label(keymap.Down)), style.GetColorHex(style.PageHeaderFgColor),
+		style.GetColorHex(style.MenuBgColor), tview.Escape(keymap.Label(keymap.Select)), style.GetColorHex(style.PageHeaderFgColor),
+		style.GetColorHex(style.MenuBgColor), tview.Escape(keymap.Label(keymap.Back)), style.GetColorHex(style.PageHeaderFgColor),
+	)
+
+	p.Flex.SetDirection(tview.FlexRow).
+		AddItem(p.input, 1, 0, true).
+		AddItem(p.list, 0, 1, false).
+		AddItem(help, 1, 0, false)
+	p.Flex.
+		SetBorder(true).
+		SetBorderColor(style.DialogBorderColor).
+		SetTitle(fmt.Sprintf(" [::b]%s ", title))
+
+	p.list.AddItem(i18n.T("加载中..."), "", 0, nil)
+
+	return p
+}
+
+// SetItems 设置可选择的联系人与群聊
+func (p *Picker) SetItems(items []*Item) {
+	p.items = items
+	p.refresh()
+}
+
+// SetError 在列表中显示加载失败的原因
+func (p *Picker) SetError(err error) {
+	p.list.Clear()
+	p.list.AddItem(i18n.T("加载失败: ")+tview.Escape(err.Error()), "", 0, nil)
+}
+
+func (p *Picker) refresh() {
+	p.filtered = Filter(p.items, p.input.GetText())
+	p.shown = 0
+	p.list.Clear()
+	if len(p.filtered) == 0 {
+		p.list.AddItem(i18n.T("没有匹配的联系人或群聊"), "", 0, nil)
+		return
+	}
+	p.showMore()
+}
+
+// showMore 在列表末尾显示下一页匹配项，还有更多匹配项时最后一行显示剩余数量
+func (p *Picker) showMore() {
+	if p.shown >= len(p.filtered) {
+		return
+	}
+	// 添加列表项会触发 SetChangedFunc，先更新已显示的数量
+	start, end := p.shown, min(p.shown+MaxItems, len(p.filtered))
+	p.shown = end
+	if start > 0 {
+		p.list.RemoveItem(start)
+	}
+	for _, item := range p.filtered[start:end] {
+		p.list.AddItem(tview.Escape(label(item)), "", 0, nil)
+	}
+	if rest := len(p.filtered) - end; rest > 0 {
+		p.list.AddItem(i18n.Tf("... 还有 %d 项，继续向下选择或输入更多关键字", rest), "", 0, nil)
+	}
+}
+
+func label(item *Item) string {
+	kind := i18n.T("联系人")
+	if item.IsChatRoom {
+		kind = i18n.T("群聊")
+	}
+	name := item.Name
+	if len(item.Remark) != 0 && len(item.NickName) != 0 && item.Remark != item.NickName {
+		name = fmt.Sprintf("%s (%s)", item.Remark, item.NickName)
+	}
+	if item.Pinned {
+		name = "★ " + name
+	}
+	return fmt.Sprintf("[%s] %s  %s", kind, name, item.UserName)
+}
+
+// inputCapture 焦点保持在搜索框，方向键与 Enter 操作列表
+func (p *Picker) inputCapture(event *tcell.EventKey) *tcell.EventKey {
+	switch event.Key() {
+	case tcell.KeyUp, tcell.KeyDown, tcell.KeyPgUp, tcell.KeyPgDn:
+		if handler := p.list.InputHandler(); handler != nil {
+			handler(event, nil)
+		}
+		return nil
+	case tcell.KeyEnter:
+		index := p.list.GetCurrentItem()
+		if index < p.shown && p.selected != nil {
+			p.selected(p.filtered[index])
+		}
+		return nil
+	case tcell.KeyEscape:
+		if p.cancel != nil {
+			p.cancel()
+		}
+		return nil
+	}
+	return event
+}
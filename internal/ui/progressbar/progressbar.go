@@ -0,0 +1,114 @@
+package progressbar
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/i18n"
+	"github.com/DanielMao1/chatlog/internal/ui/style"
+	"github.com/DanielMao1/chatlog/pkg/progress"
+	"github.com/DanielMao1/chatlog/pkg/util"
+)
+
+const (
+	// Width 进度条宽度，模态框宽度为屏幕的 1/3，进度条与说明分行显示
+	Width = 20
+
+	// MinElapsed 开始计算剩余时间前的最短耗时
+	MinElapsed = 2 * time.Second
+)
+
+// Bar 返回宽度为 width 的进度条
+func Bar(done, total int64, width int) string {
+	filled := 0
+	if total > 0 {
+		filled = int(done * int64(width) / total)
+	}
+	filled = min(max(filled, 0), width)
+	return fmt.Sprintf("[%s]%s[%s]%s[-]",
+		style.GetColorHex(style.PrgBarColor), strings.Repeat(style.ProgressBarCell, filled),
+		style.GetColorHex(style.PrgBgColor), strings.Repeat(style.ProgressBarEmptyCell, width-filled))
+}
+
+// Percent 返回百分比文本
+func Percent(done, total int64) string {
+	if total <= 0 {
+		return "--%"
+	}
+	return fmt.Sprintf("%d%%", min(done*100/total, 100))
+}
+
+// ETA 按已耗时与完成比例估算剩余时间，无法估算时返回空字符串
+func ETA(elapsed time.Duration, done, total int64) string {
+	if elapsed < MinElapsed || done <= 0 || total <= 0 || done >= total {
+		return ""
+	}
+	remain := time.Duration(float64(elapsed) * float64(total-done) / float64(done))
+	return i18n.Tf("剩余约 %s", remain.Round(time.Second))
+}
+
+// View 汇总解密、获取密钥与导出的进度事件，生成模态框中显示的文本
+type View struct {
+	start   time.Time
+	decrypt *progress.Event
+	scan    *progress.Event
+	derived *progress.Event
+	export  *progress.Event
+}
+
+func New() *View {
+	return &View{start: time.Now()}
+}
+
+// Update 记录进度事件
+func (v *View) Update(e progress.Event) {
+	switch {
+	case e.Stage == progress.StageDecrypt:
+		v.decrypt = &e
+	case e.Stage == progress.StageKey && e.Phase == progress.PhaseDerived:
+		v.derived = &e
+	case e.Stage == progress.StageKey:
+		v.scan = &e
+	case e.Stage == progress.StageExport:
+		v.export = &e
+	}
+}
+
+// Text 返回标题与各阶段进度
+func (v *View) Text(title string) string {
+	elapsed := time.Since(v.start)
+	buf := strings.Builder{}
+	buf.WriteString(title)
+	buf.WriteString("\n")
+
+	if e := v.decrypt; e != nil {
+		fmt.Fprintf(&buf, i18n.T("\n已完成 %d/%d 个数据库\n"), e.Count, e.Total)
+		if len(e.Name) != 0 && !e.Finished {
+			fmt.Fprintf(&buf, "%s\n%s\n%s  %s / %s\n", filepath.Base(e.Name),
+				Bar(e.Done, e.Size, Width), Percent(e.Done, e.Size), util.ByteCountSI(e.Done), util.ByteCountSI(e.Size))
+		}
+		fmt.Fprintf(&buf, i18n.T("总进度\n%s\n%s  %s\n"), Bar(e.Bytes, e.TotalBytes, Width), Percent(e.Bytes, e.TotalBytes), ETA(elapsed, e.Bytes, e.TotalBytes))
+	}
+
+	if e := v.scan; e != nil {
+		if e.Total > 0 {
+			fmt.Fprintf(&buf, i18n.T("\n已扫描内存区域 %d/%d\n%s\n%s  %s\n"), e.Count, e.Total,
+				Bar(e.Bytes, e.TotalBytes, Width), Percent(e.Bytes, e.TotalBytes), ETA(elapsed, e.Bytes, e.TotalBytes))
+		} else {
+			fmt.Fprintf(&buf, i18n.T("\n已扫描内存区域 %d 个，%s\n"), e.Count, util.ByteCountSI(e.Bytes))
+		}
+	}
+
+	if e := v.derived; e != nil {
+		fmt.Fprintf(&buf, i18n.T("已找到派生密钥 %d/%d\n"), e.Found, e.Expected)
+	}
+
+	if e := v.export; e != nil && e.Size > 0 {
+		fmt.Fprintf(&buf, i18n.T("\n已导出 %d/%d 条消息\n%s\n%s  %s\n"), e.Done, e.Size,
+			Bar(e.Done, e.Size, Width), Percent(e.Done, e.Size), ETA(elapsed, e.Done, e.Size))
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,143 @@
+package statusbar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/i18n"
+	"github.com/DanielMao1/chatlog/internal/ui/style"
+
+	"github.com/rivo/tview"
+)
+
+const (
+	Title = "statusbar"
+
+	// Height 状态栏高度
+	Height = 1
+
+	separator = " │ "
+
+	// MessageDuration 提示信息显示的时长
+	MessageDuration = 3 * time.Second
+)
+
+// Status 状态栏显示的内容
+type Status struct {
+	Account   string
+	Version   string
+	WorkUsage string
+
+	AutoDecrypt bool
+	// LastDecrypt 最近一次自动解密完成的时间
+	LastDecrypt time.Time
+	// LastSession 最近一条新消息的时间
+	LastSession time.Time
+
+	HTTPEnabled bool
+	HTTPAddr    string
+	// Requests 最近一分钟内 HTTP 服务处理的请求数
+	Requests int
+}
+
+// StatusBar 显示在所有页面底部的状态栏
+type StatusBar struct {
+	*tview.TextView
+	title string
+
+	// message 临时显示的提示信息，until 之后恢复显示状态
+	message string
+	until   time.Time
+}
+
+func New() *StatusBar {
+	bar := &StatusBar{
+		TextView: tview.NewTextView(),
+		title:    Title,
+	}
+	bar.SetDynamicColors(true).
+		SetWrap(false).
+		SetTextAlign(tview.AlignLeft)
+	return bar
+}
+
+// Update 更新状态栏内容，now 用于决定时间的显示格式
+func (bar *StatusBar) Update(s Status, now time.Time) {
+	if len(bar.message) != 0 && now.Before(bar.until) {
+		bar.SetText(messageText(bar.message))
+		return
+	}
+	bar.message = ""
+	bar.SetText(Text(s, now))
+}
+
+// SetMessage 在状态栏临时显示提示信息，MessageDuration 后的下一次 Update 恢复显示状态
+func (bar *StatusBar) SetMessage(message string) {
+	bar.message = message
+	bar.until = time.Now().Add(MessageDuration)
+	bar.SetText(messageText(message))
+}
+
+func messageText(message string) string {
+	return " " + style.Tag(style.SuccessColor) + tview.Escape(message) + "[-]"
+}
+
+// Text 返回状态栏文本
+func Text(s Status, now time.Time) string {
+	label := style.Tag(style.PageHeaderFgColor)
+	muted := style.Tag(style.MutedColor)
+	ok := style.Tag(style.SuccessColor)
+
+	field := func(name, value string) string {
+		return fmt.Sprintf("%s%s[-] %s", label, name, value)
+	}
+	or := func(value, empty string) string {
+		if len(value) == 0 {
+			return muted + empty + "[-]"
+		}
+		return tview.Escape(value)
+	}
+
+	parts := []string{
+		field(i18n.T("账号"), or(s.Account, i18n.T("未选择"))),
+		field(i18n.T("微信"), or(s.Version, "-")),
+		field(i18n.T("工作目录"), or(s.WorkUsage, "-")),
+	}
+
+	switch {
+	case !s.AutoDecrypt:
+		parts = append(parts, field(i18n.T("自动解密"), muted+i18n.T("未开启")+"[-]"))
+	case s.LastDecrypt.IsZero():
+		parts = append(parts, field(i18n.T("自动解密"), ok+i18n.T("等待新数据")+"[-]"))
+	default:
+		parts = append(parts, field(i18n.T("自动解密"), ok+FormatTime(s.LastDecrypt, now)+"[-]"))
+	}
+
+	if s.LastSession.Unix() > 1000000000 {
+		parts = append(parts, field(i18n.T("新消息"), FormatTime(s.LastSession, now)))
+	} else {
+		parts = append(parts, field(i18n.T("新消息"), muted+"-[-]"))
+	}
+
+	if s.HTTPEnabled {
+		parts = append(parts, field("HTTP", fmt.Sprintf("%s%s[-] %s", ok, tview.Escape(s.HTTPAddr), i18n.Tf("%d 次/分钟", s.Requests))))
+	} else {
+		parts = append(parts, field("HTTP", muted+i18n.T("未启动")+"[-]"))
+	}
+
+	return " " + strings.Join(parts, muted+separator+"[-]")
+}
+
+// FormatTime 当天的时间只显示时分秒，更早的显示日期
+func FormatTime(t, now time.Time) string {
+	y1, m1, d1 := t.Date()
+	y2, m2, d2 := now.Date()
+	if y1 == y2 && m1 == m2 && d1 == d2 {
+		return t.Format("15:04:05")
+	}
+	if y1 == y2 {
+		return t.Format("01-02 15:04")
+	}
+	return t.Format("2006-01-02 15:04")
+}
@@ -74,5 +74,8 @@ func GetColorName(color tcell.Color) string {
 
 // GetColorHex returns convert tcell color to its hex useful for textview primitives.
 func GetColorHex(color tcell.Color) string {
-	return fmt.Sprintf("#%x", color.Hex())
+	if color == tcell.ColorDefault {
+		return "-"
+	}
+	return fmt.Sprintf("#%06x", color.Hex())
 }
@@ -3,6 +3,8 @@
 package style
 
 import (
+	"fmt"
+
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 )
@@ -66,16 +68,21 @@ func GetColorName(color tcell.Color) string {
 			return name
 		}
 	}
-	return ""
+	// 自定义配色中的 RGB 颜色没有名称
+	return fmt.Sprintf("#%06x", color.Hex())
 }
 
 // GetColorHex shall returns convert tcell color to its hex useful for textview primitives,
 // however, for windows nodes it will return color name.
 func GetColorHex(color tcell.Color) string {
+	if color == tcell.ColorDefault {
+		return "-"
+	}
 	for name, c := range tcell.ColorNames {
 		if c == color {
 			return name
 		}
 	}
-	return ""
+	// 自定义配色中的 RGB 颜色没有名称
+	return fmt.Sprintf("#%06x", color.Hex())
 }
@@ -0,0 +1,214 @@
+package style
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const (
+	ThemeDark         = "dark"
+	ThemeLight        = "light"
+	ThemeHighContrast = "high-contrast"
+	ThemeNoColor      = "no-color"
+
+	// EnvNoColor 设置后不使用任何颜色，见 https://no-color.org
+	EnvNoColor = "NO_COLOR"
+)
+
+// 配色中的颜色角色
+const (
+	RoleFg        = "fg"        // 正文
+	RoleBg        = "bg"        // 背景
+	RoleBorder    = "border"    // 边框
+	RoleAccent    = "accent"    // 菜单、表头、按钮背景
+	RoleAccentFg  = "accent_fg" // 强调色背景上的文字
+	RoleMuted     = "muted"     // 标签、日期等次要文字
+	RoleHighlight = "highlight" // 快捷键、自己发送的消息
+	RoleSuccess   = "success"   // 运行中、已开启、联系人名称
+	RoleWarning   = "warning"   // 提示、进度条
+	RoleError     = "error"     // 错误
+	RoleDialogBg  = "dialog_bg" // 对话框背景
+	RoleInputBg   = "input_bg"  // 输入框背景
+)
+
+// Palette 颜色角色到颜色的映射，未设置的角色保持默认配色
+type Palette map[string]tcell.Color
+
+var (
+	// HighlightColor 快捷键等高亮文字
+	HighlightColor = tcell.ColorYellow
+	// SuccessColor 运行状态等文字
+	SuccessColor = tcell.ColorGreen
+	// MutedColor 次要文字
+	MutedColor = tcell.ColorGray
+	// MenuHeaderFgColor 主菜单表头文字
+	MenuHeaderFgColor = tcell.ColorBlack
+	// SelectedStyle 列表与表格中选中行的样式
+	SelectedStyle = tcell.StyleDefault.Foreground(tview.Styles.PrimitiveBackgroundColor).Background(tview.Styles.PrimaryTextColor)
+	// ProgressBarEmptyCell 进度条未完成部分
+	ProgressBarEmptyCell = ProgressBarCell
+	// NoColor 是否禁用颜色
+	NoColor bool
+)
+
+// Themes 内置配色，dark 为各平台原有的默认配色
+var Themes = map[string]Palette{
+	ThemeDark: {},
+	ThemeLight: {
+		RoleFg:        tcell.ColorBlack,
+		RoleBg:        tcell.ColorWhite,
+		RoleBorder:    tcell.ColorSeaGreen,
+		RoleAccent:    tcell.ColorSeaGreen,
+		RoleAccentFg:  tcell.ColorWhite,
+		RoleMuted:     tcell.ColorDimGray,
+		RoleHighlight: tcell.ColorDarkBlue,
+		RoleSuccess:   tcell.ColorDarkGreen,
+		RoleWarning:   tcell.ColorDarkOrange,
+		RoleError:     tcell.ColorFireBrick,
+		RoleDialogBg:  tcell.ColorWhiteSmoke,
+		RoleInputBg:   tcell.ColorLightGray,
+	},
+	ThemeHighContrast: {
+		RoleFg:        tcell.ColorWhite,
+		RoleBg:        tcell.ColorBlack,
+		RoleBorder:    tcell.ColorWhite,
+		RoleAccent:    tcell.ColorYellow,
+		RoleAccentFg:  tcell.ColorBlack,
+		RoleMuted:     tcell.ColorSilver,
+		RoleHighlight: tcell.ColorYellow,
+		RoleSuccess:   tcell.ColorLime,
+		RoleWarning:   tcell.ColorYellow,
+		RoleError:     tcell.ColorRed,
+		RoleDialogBg:  tcell.ColorBlack,
+		RoleInputBg:   tcell.ColorNavy,
+	},
+}
+
+// Load 返回名称对应的配色，并用 colors 中的颜色覆盖，颜色可以是名称或 #rrggbb
+// 设置了 NO_COLOR 环境变量时忽略配置，返回 no-color
+func Load(name string, colors map[string]string) (string, Palette, error) {
+	if len(os.Getenv(EnvNoColor)) != 0 {
+		return ThemeNoColor, nil, nil
+	}
+	name = strings.ToLower(strings.TrimSpace(name))
+	if len(name) == 0 {
+		name = ThemeDark
+	}
+	if name == ThemeNoColor {
+		return name, nil, nil
+	}
+	base, ok := Themes[name]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown theme %q, available: %s", name, strings.Join(ThemeNames(), ", "))
+	}
+
+	palette := make(Palette, len(base)+len(colors))
+	for role, color := range base {
+		palette[role] = color
+	}
+	for role, value := range colors {
+		role = strings.ToLower(role)
+		if !validRole(role) {
+			return "", nil, fmt.Errorf("unknown color %q in theme colors", role)
+		}
+		color := tcell.GetColor(strings.ToLower(value))
+		if color == tcell.ColorDefault && !strings.EqualFold(value, "default") {
+			return "", nil, fmt.Errorf("invalid color %q for %s", value, role)
+		}
+		palette[role] = color
+	}
+	return name, palette, nil
+}
+
+// ThemeNames 返回所有可用的配色名称
+func ThemeNames() []string {
+	names := make([]string, 0, len(Themes)+1)
+	for name := range Themes {
+		names = append(names, name)
+	}
+	names = append(names, ThemeNoColor)
+	sort.Strings(names)
+	return names
+}
+
+func validRole(role string) bool {
+	switch role {
+	case RoleFg, RoleBg, RoleBorder, RoleAccent, RoleAccentFg, RoleMuted, RoleHighlight,
+		RoleSuccess, RoleWarning, RoleError, RoleDialogBg, RoleInputBg:
+		return true
+	}
+	return false
+}
+
+// Apply 应用配色，需在创建界面组件之前调用，palette 为 nil 时禁用颜色
+func Apply(palette Palette) {
+	if palette == nil {
+		applyNoColor()
+		return
+	}
+
+	set := func(role string, targets ...*tcell.Color) {
+		color, ok := palette[role]
+		if !ok {
+			return
+		}
+		for _, target := range targets {
+			*target = color
+		}
+	}
+	set(RoleFg, &FgColor, &DialogFgColor, &TerminalFgColor, &PrgBarEmptyColor,
+		&tview.Styles.PrimaryTextColor, &tview.Styles.TitleColor)
+	set(RoleBg, &BgColor, &TerminalBgColor, &tview.Styles.PrimitiveBackgroundColor)
+	set(RoleBorder, &BorderColor, &HelpHeaderFgColor, &tview.Styles.BorderColor, &tview.Styles.GraphicsColor)
+	set(RoleAccent, &MenuBgColor, &PageHeaderBgColor, &DialogBorderColor, &TableHeaderBgColor,
+		&ButtonBgColor, &tview.Styles.MoreContrastBackgroundColor)
+	set(RoleAccentFg, &PageHeaderFgColor, &TableHeaderFgColor, &MenuHeaderFgColor)
+	set(RoleMuted, &InfoBarItemFgColor, &DialogSubBoxBorderColor, &TerminalBorderColor, &PrgBgColor, &MutedColor)
+	set(RoleHighlight, &HighlightColor, &tview.Styles.SecondaryTextColor)
+	set(RoleSuccess, &RunningStatusFgColor, &PrgBarOKColor, &SuccessColor, &tview.Styles.TertiaryTextColor)
+	set(RoleWarning, &PausedStatusFgColor, &PrgBarColor, &PrgBarWarnColor)
+	set(RoleError, &ErrorDialogBgColor, &ErrorDialogButtonBgColor, &PrgBarCritColor)
+	set(RoleDialogBg, &DialogBgColor, &tview.Styles.ContrastBackgroundColor)
+	set(RoleInputBg, &InputFieldBgColor)
+
+	if _, ok := palette[RoleInputBg]; ok {
+		DropDownUnselected = tcell.StyleDefault.Background(InputFieldBgColor).Foreground(FgColor)
+	}
+	if _, ok := palette[RoleAccent]; ok {
+		DropDownSelected = tcell.StyleDefault.Background(MenuBgColor).Foreground(PageHeaderFgColor)
+	}
+	SelectedStyle = tcell.StyleDefault.Foreground(tview.Styles.PrimitiveBackgroundColor).Background(tview.Styles.PrimaryTextColor)
+}
+
+// applyNoColor 所有颜色使用终端默认值，选中行使用反色
+func applyNoColor() {
+	NoColor = true
+	for _, target := range []*tcell.Color{
+		&FgColor, &BgColor, &BorderColor, &HelpHeaderFgColor, &MenuBgColor, &PageHeaderBgColor, &PageHeaderFgColor,
+		&RunningStatusFgColor, &PausedStatusFgColor, &InfoBarItemFgColor,
+		&DialogBgColor, &DialogBorderColor, &DialogFgColor, &DialogSubBoxBorderColor, &ErrorDialogBgColor, &ErrorDialogButtonBgColor,
+		&TerminalFgColor, &TerminalBgColor, &TerminalBorderColor, &TableHeaderBgColor, &TableHeaderFgColor, &MenuHeaderFgColor,
+		&PrgBgColor, &PrgBarColor, &PrgBarEmptyColor, &PrgBarOKColor, &PrgBarWarnColor, &PrgBarCritColor,
+		&InputFieldBgColor, &ButtonBgColor, &HighlightColor, &SuccessColor, &MutedColor,
+		&tview.Styles.PrimitiveBackgroundColor, &tview.Styles.ContrastBackgroundColor, &tview.Styles.MoreContrastBackgroundColor,
+		&tview.Styles.BorderColor, &tview.Styles.TitleColor, &tview.Styles.GraphicsColor, &tview.Styles.PrimaryTextColor,
+		&tview.Styles.SecondaryTextColor, &tview.Styles.TertiaryTextColor, &tview.Styles.InverseTextColor,
+		&tview.Styles.ContrastSecondaryTextColor,
+	} {
+		*target = tcell.ColorDefault
+	}
+	DropDownUnselected = tcell.StyleDefault
+	DropDownSelected = tcell.StyleDefault.Reverse(true)
+	SelectedStyle = tcell.StyleDefault.Reverse(true)
+	ProgressBarEmptyCell = "·"
+}
+
+// Tag 返回颜色对应的 tview 颜色标签，如 [#ff0000]，禁用颜色时为 [-]
+func Tag(color tcell.Color) string {
+	return "[" + GetColorHex(color) + "]"
+}
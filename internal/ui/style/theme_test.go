@@ -0,0 +1,44 @@
+package style
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestLoad(t *testing.T) {
+	t.Setenv(EnvNoColor, "")
+
+	name, palette, err := Load("Light", map[string]string{"accent": "#112233"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != ThemeLight || palette[RoleAccent] != tcell.NewRGBColor(0x11, 0x22, 0x33) || palette[RoleFg] != tcell.ColorBlack {
+		t.Errorf("unexpected palette %s: %v", name, palette)
+	}
+	if Themes[ThemeLight][RoleAccent] != tcell.ColorSeaGreen {
+		t.Error("built-in theme modified")
+	}
+
+	if name, _, err := Load("", nil); err != nil || name != ThemeDark {
+		t.Errorf("default theme: %s %v", name, err)
+	}
+	if _, _, err := Load("solarized", nil); err == nil {
+		t.Error("expected error for unknown theme")
+	}
+	if _, _, err := Load(ThemeDark, map[string]string{"accent": "not-a-color"}); err == nil {
+		t.Error("expected error for invalid color")
+	}
+	if _, _, err := Load(ThemeDark, map[string]string{"shadow": "red"}); err == nil {
+		t.Error("expected error for unknown role")
+	}
+}
+
+func TestLoadNoColor(t *testing.T) {
+	t.Setenv(EnvNoColor, "1")
+
+	name, palette, err := Load(ThemeLight, nil)
+	if err != nil || name != ThemeNoColor || palette != nil {
+		t.Errorf("NO_COLOR not respected: %s %v %v", name, palette, err)
+	}
+}
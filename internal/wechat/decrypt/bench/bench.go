@@ -0,0 +1,357 @@
+// Package bench 解密热点路径的基准测试，go test -bench 与 chatlog bench 使用同一组用例
+//
+// 测试数据在内存中按各版本的参数生成，不需要微信数据：数据库页面使用随机密钥加密并计算 HMAC，
+// 图片按 3.x 的异或格式与 4.0 的 AES + 异或格式生成。
+package bench
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/DanielMao1/chatlog/internal/wechat/decrypt/common"
+	"github.com/DanielMao1/chatlog/internal/wechat/decrypt/darwin"
+	"github.com/DanielMao1/chatlog/pkg/bloom"
+	"github.com/DanielMao1/chatlog/pkg/util/dat2img"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Case 一个基准测试用例
+type Case struct {
+	Name  string
+	Bytes int64             // 每次操作处理的字节数，用于计算吞吐量，为 0 时不计算
+	Fn    func(n int) error // 执行 n 次操作，测试数据在创建用例时生成
+}
+
+// Result 一个用例的测试结果
+type Result struct {
+	Name        string  `json:"name"`
+	N           int     `json:"n"`
+	NsPerOp     int64   `json:"nsPerOp"`
+	MBPerSec    float64 `json:"mbPerSec,omitempty"`
+	BytesPerOp  int64   `json:"bytesPerOp"`
+	AllocsPerOp int64   `json:"allocsPerOp"`
+}
+
+// params 数据库版本的加密参数
+type params struct {
+	name     string
+	pageSize int
+	hmacSize int
+	hashFunc func() hash.Hash
+}
+
+// reserve 每页末尾 IV 与 HMAC 占用的字节数，按 AES 块大小对齐
+func (p params) reserve() int {
+	r := common.IVSize + p.hmacSize
+	if r%common.AESBlockSize != 0 {
+		r = (r/common.AESBlockSize + 1) * common.AESBlockSize
+	}
+	return r
+}
+
+var versions = []params{
+	{"v3", 4096, 20, sha1.New},        // Windows 3.x
+	{"darwin_v3", 1024, 20, sha1.New}, // macOS 3.x
+	{"v4", 4096, 64, sha512.New},      // 4.0
+}
+
+// Cases 返回所有用例，图片用例使用 256KB 的图片
+func Cases() []Case {
+	ret := make([]Case, 0)
+	for _, p := range versions {
+		ret = append(ret, pageCases(p)...)
+	}
+	ret = append(ret, derivedKeyCases()...)
+	ret = append(ret, dedupeCases()...)
+	ret = append(ret, imageCases(256*1024)...)
+	return ret
+}
+
+// Run 运行名称匹配 filter 的用例，filter 为空时运行全部用例
+func Run(filter string) ([]Result, error) {
+	var re *regexp.Regexp
+	if len(filter) != 0 {
+		var err error
+		if re, err = regexp.Compile(filter); err != nil {
+			return nil, fmt.Errorf("invalid filter: %w", err)
+		}
+	}
+	ret := make([]Result, 0)
+	for _, c := range Cases() {
+		if re != nil && !re.MatchString(c.Name) {
+			continue
+		}
+		// 先执行一次，测试数据有误时返回错误而不是空结果
+		if err := c.Fn(1); err != nil {
+			return nil, fmt.Errorf("%s: %w", c.Name, err)
+		}
+		r := testing.Benchmark(func(b *testing.B) {
+			b.ReportAllocs()
+			if c.Bytes > 0 {
+				b.SetBytes(c.Bytes)
+			}
+			c.Fn(b.N)
+		})
+		res := Result{
+			Name:        c.Name,
+			N:           r.N,
+			NsPerOp:     r.NsPerOp(),
+			BytesPerOp:  r.AllocedBytesPerOp(),
+			AllocsPerOp: r.AllocsPerOp(),
+		}
+		if c.Bytes > 0 && r.T > 0 {
+			res.MBPerSec = float64(r.Bytes) * float64(r.N) / 1e6 / r.T.Seconds()
+		}
+		ret = append(ret, res)
+	}
+	return ret, nil
+}
+
+// pageCases 页面解密与 HMAC 校验
+func pageCases(p params) []Case {
+	encKey, macKey := randomBytes(common.KeySize), randomBytes(common.KeySize)
+	page := encryptPage(p, encKey, macKey, 1, randomBytes(p.pageSize))
+	reserve := p.reserve()
+	page1 := encryptPage(p, encKey, macKey, 0, randomBytes(p.pageSize))
+	keys := func([]byte, []byte) ([]byte, []byte) { return encKey, macKey }
+
+	return []Case{
+		{
+			Name:  "decrypt_page/" + p.name,
+			Bytes: int64(p.pageSize),
+			Fn: func(n int) error {
+				for i := 0; i < n; i++ {
+					if _, err := common.DecryptPage(page, encKey, macKey, 1, p.hashFunc, p.hmacSize, reserve, p.pageSize); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			// 第一页的 HMAC 校验，不含 PBKDF2 派生密钥
+			Name:  "validate_hmac/" + p.name,
+			Bytes: int64(p.pageSize),
+			Fn: func(n int) error {
+				for i := 0; i < n; i++ {
+					if !common.ValidateKey(page1, encKey, page1[:common.SaltSize], p.hashFunc, p.hmacSize, reserve, p.pageSize, keys) {
+						return errors.New("hmac mismatch")
+					}
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// derivedKeyCases 4.0 从进程内存中搜索派生密钥时对每个候选的校验
+func derivedKeyCases() []Case {
+	d := darwin.NewV4Decryptor()
+	encKey := randomBytes(common.KeySize)
+	page1 := V4Database(encKey)
+	pm, keyed := d.FirstPageMAC(page1), d.NewKeyedHash(encKey)
+
+	return []Case{
+		{
+			Name: "validate_derived_key/v4",
+			Fn: func(n int) error {
+				for i := 0; i < n; i++ {
+					if !d.ValidateDerivedKey(page1, encKey) {
+						return errors.New("derived key rejected")
+					}
+				}
+				return nil
+			},
+		},
+		{
+			// 搜索密钥时第一页的数据按数据库缓存，同一候选的 HMAC 在多个数据库间复用
+			Name: "validate_derived_key/v4_cached",
+			Fn: func(n int) error {
+				for i := 0; i < n; i++ {
+					if !pm.ValidateDerivedKey(keyed) {
+						return errors.New("derived key rejected")
+					}
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// dedupeWorkers 并发记录候选密钥的协程数，与搜索密钥的常见并发数相同
+const dedupeWorkers = 8
+
+// dedupeCases 搜索密钥时多个协程同时记录已处理的候选密钥，集合的大小与误判率与 4.0 搜索派生密钥时相同
+// 候选中有一半是重复的，与进程内存中同一密钥多次出现的情况相近
+func dedupeCases() []Case {
+	keys := make([][]byte, 1<<14)
+	for i := range keys {
+		if i%2 == 1 {
+			keys[i] = keys[i-1]
+			continue
+		}
+		keys[i] = randomBytes(common.KeySize)
+	}
+	f := bloom.New(1<<22, 1e-6)
+
+	return []Case{
+		{
+			Name:  fmt.Sprintf("dedupe_keys/%d_workers", dedupeWorkers),
+			Bytes: common.KeySize,
+			Fn: func(n int) error {
+				var wg sync.WaitGroup
+				for w := 0; w < dedupeWorkers; w++ {
+					wg.Add(1)
+					go func(w int) {
+						defer wg.Done()
+						for i := w; i < n; i += dedupeWorkers {
+							f.TestAndAdd(keys[i%len(keys)])
+						}
+					}(w)
+				}
+				wg.Wait()
+				return nil
+			},
+		},
+	}
+}
+
+// imageCases 图片解密，不含需要 ffmpeg 的 wxgf 转换
+func imageCases(size int) []Case {
+	img := jpeg(size)
+	v3 := common.XorBytes(img, 0x5a)
+	v4 := encryptDatV4(img, dat2img.V4Format1.AesKey, dat2img.V4XorKey)
+
+	return []Case{
+		{
+			Name:  "dat2img/v3",
+			Bytes: int64(len(v3)),
+			Fn: func(n int) error {
+				for i := 0; i < n; i++ {
+					if _, _, err := dat2img.Dat2Image(v3); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			Name:  "dat2img/v4",
+			Bytes: int64(len(v4)),
+			Fn: func(n int) error {
+				for i := 0; i < n; i++ {
+					if _, _, err := dat2img.Dat2Image(v4); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// V4Database 返回只有一页的 4.0 数据库，encKey 为从进程内存中找到的派生密钥，供需要数据库文件的基准测试使用
+func V4Database(encKey []byte) []byte {
+	p := versions[2]
+	plain := randomBytes(p.pageSize)
+	macKey := pbkdf2.Key(encKey, common.XorBytes(plain[:common.SaltSize], 0x3a), 2, common.KeySize, sha512.New)
+	return encryptPage(p, encKey, macKey, 0, plain)
+}
+
+// encryptPage 按 SQLCipher 的格式加密一页，pageNum 从 0 开始，第 0 页开头的 salt 不加密
+func encryptPage(p params, encKey, macKey []byte, pageNum int64, plain []byte) []byte {
+	reserve := p.reserve()
+	page := make([]byte, p.pageSize)
+	copy(page, plain)
+	offset := 0
+	if pageNum == 0 {
+		offset = common.SaltSize
+	}
+	iv := page[p.pageSize-reserve : p.pageSize-reserve+common.IVSize]
+	rand.Read(iv)
+	block, _ := aes.NewCipher(encKey)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(page[offset:p.pageSize-reserve], page[offset:p.pageSize-reserve])
+
+	mac := hmac.New(p.hashFunc, macKey)
+	mac.Write(page[offset : p.pageSize-reserve+common.IVSize])
+	mac.Write(binary.LittleEndian.AppendUint32(nil, uint32(pageNum+1)))
+	copy(page[p.pageSize-reserve+common.IVSize:], mac.Sum(nil))
+	return page
+}
+
+// encryptDatV4 按 4.0 的格式加密图片：前 1KB 使用 AES-ECB，末尾 1KB 异或，中间不加密
+func encryptDatV4(img, aesKey []byte, xorKey byte) []byte {
+	const aesLen, xorLen = 1024, 1024
+	head := img[:aesLen]
+	padding := aes.BlockSize - len(head)%aes.BlockSize
+	padded := append(append([]byte{}, head...), make([]byte, padding)...)
+	for i := len(head); i < len(padded); i++ {
+		padded[i] = byte(padding)
+	}
+	block, _ := aes.NewCipher(aesKey)
+	for i := 0; i < len(padded); i += aes.BlockSize {
+		block.Encrypt(padded[i:i+aes.BlockSize], padded[i:i+aes.BlockSize])
+	}
+
+	out := append([]byte{}, dat2img.V4Format1.Header...)
+	out = append(out, 0x08, 0x07)
+	out = binary.LittleEndian.AppendUint32(out, aesLen)
+	out = binary.LittleEndian.AppendUint32(out, xorLen)
+	out = append(out, 0x01)
+	out = append(out, padded...)
+	out = append(out, img[aesLen:len(img)-xorLen]...)
+	return append(out, common.XorBytes(img[len(img)-xorLen:], xorKey)...)
+}
+
+// jpeg 返回 size 字节、以 JPG 文件头开始并以结束标记结尾的数据
+func jpeg(size int) []byte {
+	img := randomBytes(size)
+	copy(img, dat2img.JPG.Header)
+	copy(img[size-len(dat2img.JpgTail):], dat2img.JpgTail)
+	return img
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	rand.Read(b)
+	return b
+}
+
+// Regression 与基线相比变慢的用例
+type Regression struct {
+	Name     string  `json:"name"`
+	Baseline int64   `json:"baselineNsPerOp"`
+	Current  int64   `json:"currentNsPerOp"`
+	Ratio    float64 `json:"ratio"`
+}
+
+// Compare 比较每次操作的耗时，返回比基线慢 threshold 以上的用例，基线中没有的用例不比较
+func Compare(baseline, current []Result, threshold float64) []Regression {
+	base := make(map[string]int64, len(baseline))
+	for _, r := range baseline {
+		base[r.Name] = r.NsPerOp
+	}
+	ret := make([]Regression, 0)
+	for _, r := range current {
+		b, ok := base[r.Name]
+		if !ok || b <= 0 {
+			continue
+		}
+		ratio := float64(r.NsPerOp) / float64(b)
+		if ratio > 1+threshold {
+			ret = append(ret, Regression{Name: r.Name, Baseline: b, Current: r.NsPerOp, Ratio: ratio})
+		}
+	}
+	return ret
+}
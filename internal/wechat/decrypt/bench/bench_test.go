@@ -0,0 +1,40 @@
+package bench
+
+import (
+	"testing"
+)
+
+func BenchmarkHotPaths(b *testing.B) {
+	for _, c := range Cases() {
+		b.Run(c.Name, func(b *testing.B) {
+			b.ReportAllocs()
+			if c.Bytes > 0 {
+				b.SetBytes(c.Bytes)
+			}
+			if err := c.Fn(b.N); err != nil {
+				b.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestCases(t *testing.T) {
+	// 每个用例运行一次，确认生成的测试数据能被正确解密
+	for _, c := range Cases() {
+		t.Run(c.Name, func(t *testing.T) {
+			if err := c.Fn(1); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	baseline := []Result{{Name: "a", NsPerOp: 100}, {Name: "b", NsPerOp: 100}, {Name: "c", NsPerOp: 0}}
+	current := []Result{{Name: "a", NsPerOp: 115}, {Name: "b", NsPerOp: 130}, {Name: "c", NsPerOp: 50}, {Name: "d", NsPerOp: 1000}}
+
+	got := Compare(baseline, current, 0.2)
+	if len(got) != 1 || got[0].Name != "b" || got[0].Ratio != 1.3 {
+		t.Fatalf("got %+v", got)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"hash"
 	"io"
 	"os"
+	"sync"
 
 	"github.com/DanielMao1/chatlog/internal/errors"
 )
@@ -136,3 +137,64 @@ func DecryptPage(pageBuf []byte, encKey []byte, macKey []byte, pageNum int64, ha
 
 	return decryptedPage, nil
 }
+
+// FirstPageMAC 第一页中与密钥无关的部分，按数据库缓存后，验证每个候选的派生密钥只需派生 MAC 密钥并计算一次 HMAC
+type FirstPageMAC struct {
+	macSalt []byte // salt 异或 0x3a，派生 MAC 密钥的 salt
+	data    []byte // 参与 HMAC 计算的数据，末尾为页号
+	stored  []byte // 第一页中保存的 HMAC
+	scratch sync.Pool
+}
+
+// macScratch 验证一个候选密钥时使用的 HMAC 与缓冲区，多个扫描协程并发验证，放回池中复用
+type macScratch struct {
+	mac         *HMAC
+	u1, u2, sum []byte
+}
+
+// pbkdf2Block1 PBKDF2 第一个块的序号
+var pbkdf2Block1 = []byte{0, 0, 0, 1}
+
+// NewFirstPageMAC 预先计算第一页的 MAC salt 与参与 HMAC 计算的数据，页面不完整时返回 nil
+func NewFirstPageMAC(page1 []byte, hashFunc func() hash.Hash, hmacSize int, reserve int, pageSize int) *FirstPageMAC {
+	dataEnd := pageSize - reserve + IVSize
+	if len(page1) < pageSize || dataEnd+hmacSize > len(page1) {
+		return nil
+	}
+	data := make([]byte, 0, dataEnd-SaltSize+4)
+	data = append(data, page1[SaltSize:dataEnd]...)
+	data = binary.LittleEndian.AppendUint32(data, 1)
+	p := &FirstPageMAC{
+		macSalt: XorBytes(page1[:SaltSize], 0x3a),
+		data:    data,
+		stored:  page1[dataEnd : dataEnd+hmacSize],
+	}
+	p.scratch.New = func() any {
+		return &macScratch{mac: NewHMAC(hashFunc, nil)}
+	}
+	return p
+}
+
+// ValidateDerivedKey 验证已派生的加密密钥，keyed 为以该密钥为密钥的 HMAC，可以在验证多个数据库时复用
+// MAC 密钥为 PBKDF2(encKey, macSalt, 2 次迭代)，只需一个块，直接用 keyed 计算，验证过程不分配内存
+func (p *FirstPageMAC) ValidateDerivedKey(keyed hash.Hash) bool {
+	s := p.scratch.Get().(*macScratch)
+	defer p.scratch.Put(s)
+
+	keyed.Reset()
+	keyed.Write(p.macSalt)
+	keyed.Write(pbkdf2Block1)
+	s.u1 = keyed.Sum(s.u1[:0])
+	keyed.Reset()
+	keyed.Write(s.u1)
+	s.u2 = keyed.Sum(s.u2[:0])
+	macKey := s.u1[:KeySize]
+	for i := range macKey {
+		macKey[i] ^= s.u2[i]
+	}
+
+	s.mac.SetKey(macKey)
+	s.mac.Write(p.data)
+	s.sum = s.mac.Sum(s.sum[:0])
+	return hmac.Equal(s.sum, p.stored)
+}
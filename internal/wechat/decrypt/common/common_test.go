@@ -0,0 +1,52 @@
+package common
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/binary"
+	"testing"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+func TestFirstPageMAC(t *testing.T) {
+	const pageSize, hmacSize, reserve = 4096, 64, 80
+
+	// 用 PBKDF2 派生的 MAC 密钥生成第一页的 HMAC
+	encKey := make([]byte, KeySize)
+	page1 := make([]byte, pageSize)
+	rand.Read(encKey)
+	rand.Read(page1)
+	macKey := pbkdf2.Key(encKey, XorBytes(page1[:SaltSize], 0x3a), 2, KeySize, sha512.New)
+	dataEnd := pageSize - reserve + IVSize
+	mac := hmac.New(sha512.New, macKey)
+	mac.Write(page1[SaltSize:dataEnd])
+	mac.Write(binary.LittleEndian.AppendUint32(nil, 1))
+	copy(page1[dataEnd:], mac.Sum(nil))
+
+	derive := func(key, salt []byte) ([]byte, []byte) {
+		return key, pbkdf2.Key(key, XorBytes(salt, 0x3a), 2, KeySize, sha512.New)
+	}
+	if !ValidateKey(page1, encKey, page1[:SaltSize], sha512.New, hmacSize, reserve, pageSize, derive) {
+		t.Fatal("ValidateKey rejected the key")
+	}
+
+	pm := NewFirstPageMAC(page1, sha512.New, hmacSize, reserve, pageSize)
+	keyed := hmac.New(sha512.New, encKey)
+	// 同一个 keyed 可以重复使用
+	for i := 0; i < 2; i++ {
+		if !pm.ValidateDerivedKey(keyed) {
+			t.Fatal("FirstPageMAC rejected the key")
+		}
+	}
+
+	wrong := make([]byte, KeySize)
+	rand.Read(wrong)
+	if pm.ValidateDerivedKey(hmac.New(sha512.New, wrong)) {
+		t.Fatal("FirstPageMAC accepted a wrong key")
+	}
+	if NewFirstPageMAC(page1[:100], sha512.New, hmacSize, reserve, pageSize) != nil {
+		t.Fatal("incomplete page accepted")
+	}
+}
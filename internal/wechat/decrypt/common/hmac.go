@@ -0,0 +1,96 @@
+package common
+
+import (
+	"encoding"
+	"hash"
+)
+
+// HMAC 可以更换密钥的 HMAC，搜索派生密钥时每个候选密钥都要计算 HMAC，复用时更换密钥不分配内存
+// 哈希支持序列化时保存写入 ipad、opad 后的状态，Reset 时恢复状态，不用每次重新计算填充块
+type HMAC struct {
+	inner, outer hash.Hash
+	ipad, opad   []byte
+	sum          []byte // 内层哈希的结果
+
+	// 写入填充块后的状态，哈希不支持序列化时为 nil
+	innerState, outerState []byte
+	innerCodec, outerCodec stateCodec
+}
+
+// stateCodec 可以序列化状态的哈希，标准库的哈希都支持
+type stateCodec interface {
+	encoding.BinaryAppender
+	encoding.BinaryUnmarshaler
+}
+
+// NewHMAC 创建以 key 为密钥的 HMAC
+func NewHMAC(hashFunc func() hash.Hash, key []byte) *HMAC {
+	h := &HMAC{inner: hashFunc(), outer: hashFunc()}
+	h.ipad = make([]byte, h.inner.BlockSize())
+	h.opad = make([]byte, h.outer.BlockSize())
+	h.sum = make([]byte, 0, h.inner.Size())
+	h.innerCodec, _ = h.inner.(stateCodec)
+	h.outerCodec, _ = h.outer.(stateCodec)
+	h.SetKey(key)
+	return h
+}
+
+// SetKey 更换密钥并重置状态
+func (h *HMAC) SetKey(key []byte) {
+	if len(key) > len(h.ipad) {
+		h.outer.Reset()
+		h.outer.Write(key)
+		key = h.outer.Sum(h.sum[:0])
+	}
+	clear(h.ipad)
+	copy(h.ipad, key)
+	copy(h.opad, h.ipad)
+	for i := range h.ipad {
+		h.ipad[i] ^= 0x36
+		h.opad[i] ^= 0x5c
+	}
+
+	h.inner.Reset()
+	h.inner.Write(h.ipad)
+	h.outer.Reset()
+	h.outer.Write(h.opad)
+	if h.innerCodec != nil && h.outerCodec != nil {
+		var err error
+		if h.innerState, err = h.innerCodec.AppendBinary(h.innerState[:0]); err == nil {
+			h.outerState, err = h.outerCodec.AppendBinary(h.outerState[:0])
+		}
+		if err != nil {
+			h.innerCodec, h.outerCodec = nil, nil
+		}
+	}
+}
+
+func (h *HMAC) Reset() {
+	if h.innerCodec != nil && h.innerCodec.UnmarshalBinary(h.innerState) == nil {
+		return
+	}
+	h.inner.Reset()
+	h.inner.Write(h.ipad)
+}
+
+func (h *HMAC) Write(p []byte) (int, error) {
+	return h.inner.Write(p)
+}
+
+func (h *HMAC) Sum(b []byte) []byte {
+	h.sum = h.inner.Sum(h.sum[:0])
+	if h.outerCodec == nil || h.outerCodec.UnmarshalBinary(h.outerState) != nil {
+		h.outer.Reset()
+		h.outer.Write(h.opad)
+	}
+	h.outer.Write(h.sum)
+	return h.outer.Sum(b)
+}
+
+func (h *HMAC) Size() int {
+	return h.outer.Size()
+}
+
+func (h *HMAC) BlockSize() int {
+	return h.inner.BlockSize()
+}
@@ -0,0 +1,31 @@
+package common
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha512"
+	"hash"
+	"testing"
+)
+
+func TestHMAC(t *testing.T) {
+	msg := bytes.Repeat([]byte("chatlog"), 100)
+	for _, hashFunc := range []func() hash.Hash{sha1.New, sha512.New} {
+		h := NewHMAC(hashFunc, nil)
+		// 更换密钥后的结果与标准库相同，包括长于块大小的密钥
+		for _, key := range [][]byte{[]byte("key"), bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 200)} {
+			h.SetKey(key)
+			want := hmac.New(hashFunc, key)
+			for i := 0; i < 2; i++ {
+				h.Reset()
+				want.Reset()
+				h.Write(msg)
+				want.Write(msg)
+				if got := h.Sum(nil); !bytes.Equal(got, want.Sum(nil)) {
+					t.Fatalf("key %x: got %x", key, got)
+				}
+			}
+		}
+	}
+}
@@ -3,27 +3,42 @@ package decrypt
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
 
-	"github.com/rs/zerolog/log"
+	"github.com/DanielMao1/chatlog/internal/errors"
 	"github.com/DanielMao1/chatlog/internal/wechat/decrypt/common"
 	"github.com/DanielMao1/chatlog/pkg/util/dat2img"
+	"github.com/rs/zerolog/log"
 )
 
 type Validator struct {
-	platform        string
-	version         int
-	dbPath          string
-	decryptor       Decryptor
-	dbFile          *common.DBFile
-	extraDBFiles    []*common.DBFile // 额外的数据库文件，用于派生密钥验证
+	platform  string
+	version   int
+	dataDir   string
+	dbPath    string
+	decryptor Decryptor
+	dbFile    *common.DBFile
+	// 按数据库缓存的第一页 MAC salt 与 HMAC 数据，primaryMAC 对应 dbFile
+	primaryMAC *common.FirstPageMAC
+	// extras 额外的数据库文件，用于派生密钥验证，首次验证派生密钥时加载，Refresh 时追加新建的数据库
+	extras          atomic.Pointer[extraDBs]
+	loadOnce        sync.Once
+	refreshMu       sync.Mutex
 	imgKeyValidator *dat2img.AesKeyValidator
 	// 派生密钥搜索优化：跟踪已匹配的数据库，跳过已找到密钥的数据库
 	matchedDBs   sync.Map // index -> true (-1=primary, 0..N=extra)
 	matchedCount int32    // 已匹配数据库数量（atomic）
-	totalDBCount int      // 总数据库数量
+	// keyed 验证派生密钥时复用的 HMAC，更换密钥即可验证下一个候选
+	keyed sync.Pool
+}
+
+// extraDBs 额外数据库的快照，files 与 macs 一一对应，只追加不修改，序号即 matchedDBs 中的 index，扫描协程读取时无需加锁
+type extraDBs struct {
+	files []*common.DBFile
+	macs  []*common.FirstPageMAC
 }
 
 // NewValidator 创建一个仅用于验证的验证器
@@ -39,6 +54,9 @@ func NewValidatorWithFile(platform string, version int, dataDir string) (*Valida
 		return nil, err
 	}
 	d, err := common.OpenDBFile(dbPath, decryptor.GetPageSize())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errors.DataDirInvalid(dataDir, err)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -46,6 +64,7 @@ func NewValidatorWithFile(platform string, version int, dataDir string) (*Valida
 	validator := &Validator{
 		platform:  platform,
 		version:   version,
+		dataDir:   dataDir,
 		dbPath:    dbPath,
 		decryptor: decryptor,
 		dbFile:    d,
@@ -53,58 +72,112 @@ func NewValidatorWithFile(platform string, version int, dataDir string) (*Valida
 
 	if version == 4 {
 		validator.imgKeyValidator = dat2img.NewImgKeyValidator(dataDir)
+		if dm, ok := decryptor.(derivedKeyMAC); ok {
+			validator.primaryMAC = dm.FirstPageMAC(d.FirstPage)
+		}
+	}
 
-		// 扫描所有数据库文件用于派生密钥验证（不同数据库有不同的 salt/派生密钥）
-		dbStorageDir := filepath.Join(dataDir, "db_storage")
-		filepath.Walk(dbStorageDir, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return nil
-			}
-			if info.IsDir() {
-				if strings.Contains(info.Name(), "fts") {
-					return filepath.SkipDir
-				}
-				return nil
-			}
-			if !strings.HasSuffix(info.Name(), ".db") || strings.Contains(info.Name(), "fts") {
-				return nil
-			}
-			if path == dbPath {
-				return nil // 跳过已作为主数据库加载的文件
-			}
-			extraFile, err := common.OpenDBFile(path, decryptor.GetPageSize())
-			if err != nil {
-				log.Debug().Str("path", path).Err(err).Msg("Failed to open extra DB file for derived key validation")
-				return nil
+	return validator, nil
+}
+
+// Refresh 加载 db_storage 中尚未加载的数据库，用于派生密钥验证，返回新加载的数量
+// 首次调用加载所有数据库，之后只加载创建验证器后新建的数据库，未调用时在首次验证派生密钥时加载
+func (v *Validator) Refresh() int {
+	if v.version != 4 {
+		return 0
+	}
+	v.refreshMu.Lock()
+	defer v.refreshMu.Unlock()
+
+	next := &extraDBs{}
+	known := map[string]bool{v.dbPath: true}
+	if old := v.extras.Load(); old != nil {
+		next.files = slices.Clone(old.files)
+		next.macs = slices.Clone(old.macs)
+		for _, f := range old.files {
+			known[f.Path] = true
+		}
+	}
+	loaded := len(next.files)
+
+	// 扫描所有数据库文件用于派生密钥验证（不同数据库有不同的 salt/派生密钥）
+	dm, _ := v.decryptor.(derivedKeyMAC)
+	dbStorageDir := filepath.Join(v.dataDir, "db_storage")
+	filepath.Walk(dbStorageDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if strings.Contains(info.Name(), "fts") {
+				return filepath.SkipDir
 			}
-			validator.extraDBFiles = append(validator.extraDBFiles, extraFile)
 			return nil
-		})
-		validator.totalDBCount = len(validator.extraDBFiles) + 1
-		log.Debug().Int("count", validator.totalDBCount).Msg("Loaded database files for derived key validation")
-	}
+		}
+		if !strings.HasSuffix(info.Name(), ".db") || strings.Contains(info.Name(), "fts") {
+			return nil
+		}
+		if known[path] {
+			return nil // 跳过主数据库与已加载的文件
+		}
+		extraFile, err := common.OpenDBFile(path, v.decryptor.GetPageSize())
+		if err != nil {
+			log.Debug().Str("path", path).Err(err).Msg("Failed to open extra DB file for derived key validation")
+			return nil
+		}
+		var pm *common.FirstPageMAC
+		if dm != nil {
+			pm = dm.FirstPageMAC(extraFile.FirstPage)
+		}
+		next.files = append(next.files, extraFile)
+		next.macs = append(next.macs, pm)
+		return nil
+	})
+	v.extras.Store(next)
 
-	return validator, nil
+	added := len(next.files) - loaded
+	log.Debug().Int("count", len(next.files)+1).Int("added", added).Msg("Loaded database files for derived key validation")
+	return added
+}
+
+// derived 返回额外的数据库，尚未加载时先加载，非 4.0 版本返回 nil
+func (v *Validator) derived() *extraDBs {
+	v.loadOnce.Do(func() {
+		if v.extras.Load() == nil {
+			v.Refresh()
+		}
+	})
+	return v.extras.Load()
 }
 
 func (v *Validator) Validate(key []byte) bool {
 	return v.decryptor.Validate(v.dbFile.FirstPage, key)
 }
 
+// derivedKeyMAC 支持派生密钥的解密器，验证时复用按数据库缓存的第一页数据
+type derivedKeyMAC interface {
+	FirstPageMAC(page1 []byte) *common.FirstPageMAC
+	NewKeyedHash(key []byte) *common.HMAC
+}
+
 // ValidateDerivedKey 验证已派生的密钥（如果解密器支持）
 // 派生密钥是数据库专属的（因为每个数据库有不同的 salt），
 // 所以需要尝试所有未匹配的数据库文件，跳过已找到密钥的数据库
+// 候选密钥的 HMAC 在所有数据库间复用，每个数据库只需派生 MAC 密钥并计算一次第一页的 HMAC，验证过程不分配内存
 func (v *Validator) ValidateDerivedKey(key []byte) bool {
-	type derivedKeyValidator interface {
-		ValidateDerivedKey(page1 []byte, key []byte) bool
-	}
-	dv, ok := v.decryptor.(derivedKeyValidator)
-	if !ok {
+	dm, ok := v.decryptor.(derivedKeyMAC)
+	if !ok || len(key) != common.KeySize {
 		return false
 	}
+	keyed, _ := v.keyed.Get().(*common.HMAC)
+	if keyed == nil {
+		keyed = dm.NewKeyedHash(key)
+	} else {
+		keyed.SetKey(key)
+	}
+	defer v.keyed.Put(keyed)
 	// 先尝试主数据库（跳过已匹配的）
-	if _, matched := v.matchedDBs.Load(-1); !matched {
-		if dv.ValidateDerivedKey(v.dbFile.FirstPage, key) {
+	if _, matched := v.matchedDBs.Load(-1); !matched && v.primaryMAC != nil {
+		if v.primaryMAC.ValidateDerivedKey(keyed) {
 			if _, already := v.matchedDBs.LoadOrStore(-1, true); !already {
 				atomic.AddInt32(&v.matchedCount, 1)
 			}
@@ -112,11 +185,15 @@ func (v *Validator) ValidateDerivedKey(key []byte) bool {
 		}
 	}
 	// 再尝试未匹配的额外数据库文件
-	for i, extraDB := range v.extraDBFiles {
-		if _, matched := v.matchedDBs.Load(i); matched {
+	dbs := v.derived()
+	if dbs == nil {
+		return false
+	}
+	for i, pm := range dbs.macs {
+		if _, matched := v.matchedDBs.Load(i); matched || pm == nil {
 			continue
 		}
-		if dv.ValidateDerivedKey(extraDB.FirstPage, key) {
+		if pm.ValidateDerivedKey(keyed) {
 			if _, already := v.matchedDBs.LoadOrStore(i, true); !already {
 				atomic.AddInt32(&v.matchedCount, 1)
 			}
@@ -126,9 +203,43 @@ func (v *Validator) ValidateDerivedKey(key []byte) bool {
 	return false
 }
 
-// AllDerivedKeysFound 返回是否已为所有数据库找到派生密钥
+// DerivedKeyTarget 返回派生密钥对应的数据库文件路径，未匹配任何数据库时返回空字符串
+// 与 ValidateDerivedKey 不同，不会记录匹配状态
+func (v *Validator) DerivedKeyTarget(key []byte) string {
+	dm, ok := v.decryptor.(derivedKeyMAC)
+	if !ok || len(key) != common.KeySize {
+		return ""
+	}
+	keyed := dm.NewKeyedHash(key)
+	if v.primaryMAC != nil && v.primaryMAC.ValidateDerivedKey(keyed) {
+		return v.dbPath
+	}
+	dbs := v.derived()
+	if dbs == nil {
+		return ""
+	}
+	for i, pm := range dbs.macs {
+		if pm != nil && pm.ValidateDerivedKey(keyed) {
+			return dbs.files[i].Path
+		}
+	}
+	return ""
+}
+
+// AllDerivedKeysFound 返回是否已为所有数据库找到派生密钥，数据库尚未加载时返回 false
+// 扫描内存时频繁调用，不触发加载
 func (v *Validator) AllDerivedKeysFound() bool {
-	return v.totalDBCount > 0 && atomic.LoadInt32(&v.matchedCount) >= int32(v.totalDBCount)
+	dbs := v.extras.Load()
+	return dbs != nil && atomic.LoadInt32(&v.matchedCount) >= int32(len(dbs.files)+1)
+}
+
+// DerivedKeyCount 返回已找到派生密钥的数据库数量与需要派生密钥的数据库总数
+func (v *Validator) DerivedKeyCount() (int, int) {
+	dbs := v.derived()
+	if dbs == nil {
+		return int(atomic.LoadInt32(&v.matchedCount)), 0
+	}
+	return int(atomic.LoadInt32(&v.matchedCount)), len(dbs.files) + 1
 }
 
 func (v *Validator) ValidateImgKey(key []byte) bool {
@@ -138,7 +249,6 @@ func (v *Validator) ValidateImgKey(key []byte) bool {
 	return v.imgKeyValidator.Validate(key)
 }
 
-
 func GetSimpleDBFile(platform string, version int) string {
 	switch {
 	case platform == "windows" && version == 3:
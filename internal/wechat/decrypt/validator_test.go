@@ -0,0 +1,69 @@
+package decrypt
+
+import (
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DanielMao1/chatlog/internal/wechat/decrypt/bench"
+)
+
+func writeDB(t *testing.T, path string) []byte {
+	t.Helper()
+	key := make([]byte, 32)
+	rand.Read(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, bench.V4Database(key), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestValidatorLoadsDatabasesLazily(t *testing.T) {
+	dir := t.TempDir()
+	primary := writeDB(t, filepath.Join(dir, "db_storage", "message", "message_0.db"))
+	session := writeDB(t, filepath.Join(dir, "db_storage", "session", "session.db"))
+
+	v, err := NewValidator("darwin", 4, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.extras.Load() != nil {
+		t.Fatal("extra databases loaded before validating derived keys")
+	}
+
+	if !v.ValidateDerivedKey(session) {
+		t.Fatal("session key rejected")
+	}
+	if found, total := v.DerivedKeyCount(); found != 1 || total != 2 {
+		t.Fatalf("DerivedKeyCount = %d, %d, want 1, 2", found, total)
+	}
+
+	// 创建验证器后新建的数据库在 Refresh 后才能验证
+	contact := writeDB(t, filepath.Join(dir, "db_storage", "contact", "contact.db"))
+	if v.ValidateDerivedKey(contact) {
+		t.Fatal("contact key accepted before Refresh")
+	}
+	if n := v.Refresh(); n != 1 {
+		t.Fatalf("Refresh loaded %d databases, want 1", n)
+	}
+	if !v.ValidateDerivedKey(contact) {
+		t.Fatal("contact key rejected after Refresh")
+	}
+	if v.Refresh() != 0 {
+		t.Fatal("Refresh loaded a database twice")
+	}
+
+	if v.AllDerivedKeysFound() {
+		t.Fatal("all keys found without the primary key")
+	}
+	if !v.ValidateDerivedKey(primary) || !v.AllDerivedKeysFound() {
+		t.Fatal("all keys should be found")
+	}
+	if got := v.DerivedKeyTarget(contact); got != filepath.Join(dir, "db_storage", "contact", "contact.db") {
+		t.Fatalf("DerivedKeyTarget = %s", got)
+	}
+}
@@ -83,6 +83,16 @@ func (d *V4Decryptor) ValidateDerivedKey(page1 []byte, key []byte) bool {
 	return common.ValidateKey(page1, key, salt, d.hashFunc, d.hmacSize, d.reserve, d.pageSize, d.deriveDerivedKeys)
 }
 
+// FirstPageMAC 预先计算第一页验证派生密钥时与密钥无关的部分，页面不完整时返回 nil
+func (d *V4Decryptor) FirstPageMAC(page1 []byte) *common.FirstPageMAC {
+	return common.NewFirstPageMAC(page1, d.hashFunc, d.hmacSize, d.reserve, d.pageSize)
+}
+
+// NewKeyedHash 返回以 key 为密钥的 HMAC，供 FirstPageMAC.ValidateDerivedKey 使用
+func (d *V4Decryptor) NewKeyedHash(key []byte) *common.HMAC {
+	return common.NewHMAC(d.hashFunc, key)
+}
+
 // deriveDerivedKeys 从已派生的加密密钥生成 MAC 密钥（跳过 enc_key 的 PBKDF2）
 func (d *V4Decryptor) deriveDerivedKeys(encKey []byte, salt []byte) ([]byte, []byte) {
 	macSalt := common.XorBytes(salt, 0x3a)
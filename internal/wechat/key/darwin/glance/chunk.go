@@ -0,0 +1,87 @@
+package glance
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// MaxPooledBytes 缓冲池保留的空闲缓冲区总大小，超过时直接释放给 GC
+const MaxPooledBytes = 512 * 1024 * 1024
+
+// Chunk 一块待搜索的内存
+//
+// Data 指向读取内存区域的缓冲区，同一区域的多个 Chunk 共享一个缓冲区。worker 处理完后调用一次 Release，
+// 之后不能再访问 Data 及其子切片，缓冲区会被复用于后续的区域，需要保留的内容（如找到的密钥）应先复制。
+// 未调用 Release 时缓冲区只是不被复用，由 GC 回收。
+type Chunk struct {
+	Data []byte
+	buf  *buffer
+}
+
+// Release 归还 Chunk 所在的缓冲区，同一区域的所有 Chunk 都归还后缓冲区才会被复用
+func (c Chunk) Release() {
+	if c.buf != nil {
+		c.buf.release()
+	}
+}
+
+// buffer 一个内存区域的缓冲区，refs 为尚未归还的引用数
+type buffer struct {
+	data []byte
+	refs atomic.Int32
+	pool *bufferPool
+}
+
+func (b *buffer) retain() {
+	b.refs.Add(1)
+}
+
+func (b *buffer) release() {
+	if b.refs.Add(-1) == 0 {
+		b.pool.put(b.data)
+	}
+}
+
+// bufferPool 复用读取内存区域的缓冲区，避免每个区域重新分配并被 GC 扫描
+// 管道不能 mmap，只能读入缓冲区
+type bufferPool struct {
+	mu   sync.Mutex
+	free [][]byte
+	size int
+}
+
+// get 返回长度为 size 的缓冲区，引用数为 1，优先复用能容纳的最小空闲缓冲区
+func (p *bufferPool) get(size int) *buffer {
+	p.mu.Lock()
+	best := -1
+	for i, b := range p.free {
+		if cap(b) >= size && (best == -1 || cap(b) < cap(p.free[best])) {
+			best = i
+		}
+	}
+	var data []byte
+	if best != -1 {
+		data = p.free[best][:size]
+		p.size -= cap(data)
+		p.free = slices.Delete(p.free, best, best+1)
+	}
+	p.mu.Unlock()
+
+	if data == nil {
+		data = make([]byte, size)
+	}
+	b := &buffer{data: data, pool: p}
+	b.refs.Store(1)
+	return b
+}
+
+func (p *bufferPool) put(data []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.size+cap(data) > MaxPooledBytes {
+		return
+	}
+	p.free = append(p.free, data[:0])
+	p.size += cap(data)
+}
@@ -0,0 +1,89 @@
+package glance
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestProcessMemoryRegion(t *testing.T) {
+	g := NewGlance(0)
+	size := 3*MinChunkSize + 100
+	buf := g.pool.get(size)
+	for i := range buf.data {
+		buf.data[i] = byte(i)
+	}
+
+	ch := make(chan Chunk, 64)
+	if err := g.processMemoryRegion(context.Background(), buf, 0x1000, ch); err != nil {
+		t.Fatal(err)
+	}
+	close(ch)
+	buf.release()
+
+	// 所有字节都在某个 chunk 中，归还所有 chunk 前缓冲区不能被复用
+	covered := make([]bool, size)
+	var chunks []Chunk
+	for c := range ch {
+		off := cap(buf.data) - cap(c.Data)
+		for i := range c.Data {
+			covered[off+i] = true
+		}
+		chunks = append(chunks, c)
+	}
+	for i, ok := range covered {
+		if !ok {
+			t.Fatalf("byte %d not in any chunk", i)
+		}
+	}
+	if len(g.pool.free) != 0 {
+		t.Fatal("buffer reused before chunks were released")
+	}
+	for _, c := range chunks {
+		c.Release()
+	}
+	if len(g.pool.free) != 1 {
+		t.Fatalf("buffer not returned to pool, %d free", len(g.pool.free))
+	}
+
+	// 复用能容纳的缓冲区
+	if b := g.pool.get(MinChunkSize); cap(b.data) != cap(buf.data) || len(b.data) != MinChunkSize || len(g.pool.free) != 0 {
+		t.Errorf("pooled buffer not reused")
+	}
+}
+
+func TestReadRegionCarry(t *testing.T) {
+	g := NewGlance(0)
+	g.Overlap = 64
+	marker := []byte("boundary-spanning-marker")
+
+	// 标记跨越两个相邻区域的边界
+	first := make([]byte, 4096)
+	copy(first[len(first)-10:], marker)
+	second := make([]byte, 4096)
+	copy(second, marker[10:])
+
+	buf, err := g.readRegion(bytes.NewReader(first), 0x1000, len(first))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.release()
+	buf, err = g.readRegion(bytes.NewReader(second), 0x2000, len(second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buf.data) != g.Overlap+len(second) || !bytes.Contains(buf.data, marker) {
+		t.Fatalf("marker spanning adjacent regions not found, got %d bytes", len(buf.data))
+	}
+	buf.release()
+
+	// 不相邻的区域不带上一个区域的尾部
+	buf, err = g.readRegion(bytes.NewReader(second), 0x8000, len(second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buf.data) != len(second) {
+		t.Fatalf("non-adjacent region carried %d bytes", len(buf.data)-len(second))
+	}
+	buf.release()
+}
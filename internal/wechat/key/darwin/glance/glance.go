@@ -11,30 +11,46 @@ import (
 	"sync"
 	"time"
 
-	"github.com/rs/zerolog/log"
 	"github.com/DanielMao1/chatlog/internal/errors"
+	"github.com/DanielMao1/chatlog/internal/wechat/key/scan"
+	"github.com/DanielMao1/chatlog/pkg/progress"
+	"github.com/rs/zerolog/log"
 )
 
 // FIXME 按照 region 读取效率较低，512MB 内存读取耗时约 18s(darwin 24)
 
 const (
-	MaxWorkers        = 8
-	MinChunkSize      = 4 * 1024 * 1024 // 4MB
-	ChunkOverlapBytes = 1024            // Greater than all offsets
-	ChunkMultiplier   = 2               // Number of chunks = MaxWorkers * ChunkMultiplier
+	MinChunkSize    = scan.DefaultChunkSize // Default ChunkSize
+	ChunkMultiplier = 2                     // Number of chunks = Workers * ChunkMultiplier
 )
 
 type Glance struct {
 	PID        uint32
 	MemRegions []MemRegion
-	pipePath   string
-	data       []byte
+	// Overlap is the number of bytes a chunk shares with the previous one, so patterns and keys
+	// spanning a chunk boundary, or the boundary of two adjacent regions, are still found
+	Overlap int
+	// ChunkSize is the minimum size of the chunks a large region is split into
+	ChunkSize int
+	// Workers is the number of workers searching chunks, a large region is split into Workers * ChunkMultiplier chunks
+	Workers  int
+	pipePath string
+	data     []byte
+	pool     bufferPool
+
+	// tail is the last Overlap bytes of the previous region ending at tailEnd, carried into the next region if adjacent
+	tail    []byte
+	tailEnd uint64
 }
 
 func NewGlance(pid uint32) *Glance {
+	o := scan.Get()
 	return &Glance{
-		PID:      pid,
-		pipePath: filepath.Join(os.TempDir(), fmt.Sprintf("chatlog_pipe_%d", time.Now().UnixNano())),
+		PID:       pid,
+		Overlap:   o.Overlap,
+		ChunkSize: o.ChunkSize,
+		Workers:   o.Workers,
+		pipePath:  filepath.Join(os.TempDir(), fmt.Sprintf("chatlog_pipe_%d", time.Now().UnixNano())),
 	}
 }
 
@@ -130,9 +146,10 @@ func (g *Glance) Read() ([]byte, error) {
 }
 
 // Read2Chan reads memory regions and sends them to a channel in chunks
-// If a region is larger than MinChunkSize, it will be split into multiple chunks
+// If a region is larger than ChunkSize, it will be split into multiple chunks
 // This function processes regions as they are read (streaming), not waiting for all regions to complete
-func (g *Glance) Read2Chan(ctx context.Context, memoryChannel chan<- []byte) error {
+// Regions are read into pooled buffers, workers call Chunk.Release when done so the buffer can be reused
+func (g *Glance) Read2Chan(ctx context.Context, memoryChannel chan<- Chunk) error {
 	regions, err := GetVmmap(g.PID)
 	if err != nil {
 		return err
@@ -154,28 +171,32 @@ func (g *Glance) Read2Chan(ctx context.Context, memoryChannel chan<- []byte) err
 }
 
 // processMemoryRegion processes a single memory region and sends chunks to channel
-func (g *Glance) processMemoryRegion(ctx context.Context, memory []byte, regionStart uint64, memoryChannel chan<- []byte) error {
+// Each chunk sent holds a reference to buf, the caller's own reference is released by the caller
+func (g *Glance) processMemoryRegion(ctx context.Context, buf *buffer, regionStart uint64, memoryChannel chan<- Chunk) error {
+	memory := buf.data
 	totalSize := len(memory)
 
 	// If memory is small enough, send it as a single chunk
-	if totalSize <= MinChunkSize {
+	if totalSize <= g.ChunkSize {
+		buf.retain()
 		select {
-		case memoryChannel <- memory:
+		case memoryChannel <- Chunk{Data: memory, buf: buf}:
 			log.Debug().Msgf("Memory region 0x%x sent as a single chunk for analysis", regionStart)
 		case <-ctx.Done():
+			buf.release()
 			return ctx.Err()
 		}
 		return nil
 	}
 
 	// Split large regions into chunks
-	chunkCount := MaxWorkers * ChunkMultiplier
+	chunkCount := g.Workers * ChunkMultiplier
 
 	// Calculate chunk size based on fixed chunk count
 	chunkSize := totalSize / chunkCount
-	if chunkSize < MinChunkSize {
+	if chunkSize < g.ChunkSize {
 		// Reduce number of chunks if each would be too small
-		chunkCount = totalSize / MinChunkSize
+		chunkCount = totalSize / g.ChunkSize
 		if chunkCount == 0 {
 			chunkCount = 1
 		}
@@ -199,7 +220,7 @@ func (g *Glance) processMemoryRegion(ctx context.Context, memory []byte, regionS
 
 			// Add overlap area to catch patterns at chunk boundaries
 			if i > 0 {
-				start -= ChunkOverlapBytes
+				start -= g.Overlap
 				if start < 0 {
 					start = 0
 				}
@@ -216,9 +237,11 @@ func (g *Glance) processMemoryRegion(ctx context.Context, memory []byte, regionS
 				Str("region", fmt.Sprintf("0x%x", regionStart)).
 				Msg("Processing memory chunk")
 
+			buf.retain()
 			select {
-			case memoryChannel <- chunk:
+			case memoryChannel <- Chunk{Data: chunk, buf: buf}:
 			case <-ctx.Done():
+				buf.release()
 				return ctx.Err()
 			}
 		}
@@ -228,7 +251,7 @@ func (g *Glance) processMemoryRegion(ctx context.Context, memory []byte, regionS
 }
 
 // streamReadRegions uses a single lldb instance to read all memory regions and processes them as they arrive
-func (g *Glance) streamReadRegions(ctx context.Context, regions []MemRegion, memoryChannel chan<- []byte) error {
+func (g *Glance) streamReadRegions(ctx context.Context, regions []MemRegion, memoryChannel chan<- Chunk) error {
 	if len(regions) == 0 {
 		return nil
 	}
@@ -277,6 +300,13 @@ func (g *Glance) streamReadRegions(ctx context.Context, regions []MemRegion, mem
 	// Channel to signal when we should stop processing
 	processingErr := make(chan error, 1)
 
+	var totalBytes int64
+	for _, region := range regions {
+		totalBytes += int64(region.End - region.Start)
+	}
+	tracker := progress.NewTracker(progress.StageKey, progress.PhaseScan, len(regions), totalBytes)
+	defer tracker.Done()
+
 	// Process each region
 	for _, region := range regions {
 		select {
@@ -299,15 +329,17 @@ func (g *Glance) streamReadRegions(ctx context.Context, regions []MemRegion, mem
 		// Create the pipe for this region
 		if err := exec.Command("mkfifo", regionPipePath).Run(); err != nil {
 			log.Warn().Err(err).Msgf("Failed to create pipe for region 0x%x", region.Start)
+			tracker.Finish(fmt.Sprintf("0x%x", region.Start))
 			continue
 		}
+		tracker.Start(fmt.Sprintf("0x%x", region.Start), int64(readSize))
 
 		// WaitGroup for this single region
 		var regionWG sync.WaitGroup
 		regionWG.Add(1)
 
 		// Start goroutine to read from this region's pipe and process immediately
-		go func(pipePath string, regionStart uint64) {
+		go func(pipePath string, regionStart uint64, size int) {
 			defer regionWG.Done()
 			defer os.Remove(pipePath)
 
@@ -319,21 +351,24 @@ func (g *Glance) streamReadRegions(ctx context.Context, regions []MemRegion, mem
 			}
 			defer file.Close()
 
-			// Read all data from pipe
-			data, err := io.ReadAll(file)
+			buf, err := g.readRegion(file, regionStart, size)
 			if err != nil {
 				log.Warn().Err(err).Msgf("Failed to read from pipe for region 0x%x", regionStart)
 				return
 			}
+			defer buf.release()
+			if len(buf.data) == 0 {
+				return
+			}
 
 			// Process and send chunks immediately
-			if err := g.processMemoryRegion(ctx, data, regionStart, memoryChannel); err != nil {
+			if err := g.processMemoryRegion(ctx, buf, regionStart, memoryChannel); err != nil {
 				select {
 				case processingErr <- err:
 				default:
 				}
 			}
-		}(regionPipePath, region.Start)
+		}(regionPipePath, region.Start, int(readSize))
 
 		// Send memory read command for this region
 		memoryReadCmd := fmt.Sprintf("memory read --binary --force --outfile %s --count %d 0x%x\n",
@@ -343,11 +378,13 @@ func (g *Glance) streamReadRegions(ctx context.Context, regions []MemRegion, mem
 
 		if _, err := fmt.Fprint(stdin, memoryReadCmd); err != nil {
 			log.Warn().Err(err).Msgf("Failed to send memory read command for region 0x%x", region.Start)
+			tracker.Finish(fmt.Sprintf("0x%x", region.Start))
 			continue
 		}
 
 		// Wait for this region's processing to complete before moving to next region
 		regionWG.Wait()
+		tracker.Finish(fmt.Sprintf("0x%x", region.Start))
 	}
 
 	// Detach and quit
@@ -383,3 +420,34 @@ func (g *Glance) streamReadRegions(ctx context.Context, regions []MemRegion, mem
 
 	return nil
 }
+
+// readRegion reads a region of size bytes starting at regionStart into a pooled buffer
+// If the region directly follows the previous one, the buffer starts with the previous region's tail,
+// so keys spanning the two regions are in the first chunk. lldb writes less when part of the region is unreadable
+func (g *Glance) readRegion(r io.Reader, regionStart uint64, size int) (*buffer, error) {
+	var carry []byte
+	if len(g.tail) != 0 && g.tailEnd == regionStart {
+		carry = g.tail
+	}
+	buf := g.pool.get(len(carry) + size)
+	copy(buf.data, carry)
+	n, err := io.ReadFull(r, buf.data[len(carry):])
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		buf.release()
+		g.tail = g.tail[:0]
+		return nil, err
+	}
+	if n == 0 {
+		buf.data = buf.data[:0]
+	} else {
+		buf.data = buf.data[:len(carry)+n]
+	}
+
+	// Copy the tail out, the buffer is reused once workers release it
+	g.tail = g.tail[:0]
+	if n == size && g.Overlap > 0 {
+		g.tail = append(g.tail, buf.data[len(buf.data)-min(g.Overlap, len(buf.data)):]...)
+		g.tailEnd = regionStart + uint64(size)
+	}
+	return buf, nil
+}
@@ -2,9 +2,9 @@ package darwin
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"encoding/hex"
-	"runtime"
 	"sync"
 
 	"github.com/rs/zerolog/log"
@@ -12,13 +12,10 @@ import (
 	"github.com/DanielMao1/chatlog/internal/errors"
 	"github.com/DanielMao1/chatlog/internal/wechat/decrypt"
 	"github.com/DanielMao1/chatlog/internal/wechat/key/darwin/glance"
+	"github.com/DanielMao1/chatlog/internal/wechat/key/scan"
 	"github.com/DanielMao1/chatlog/internal/wechat/model"
 )
 
-const (
-	MaxWorkersV3 = 8
-)
-
 var V3KeyPatterns = []KeyPatternInfo{
 	{
 		Pattern: []byte{0x72, 0x74, 0x72, 0x65, 0x65, 0x5f, 0x69, 0x33, 0x32},
@@ -56,17 +53,12 @@ func (e *V3Extractor) Extract(ctx context.Context, proc *model.Process) (string,
 	defer cancel()
 
 	// Create channels for memory data and results
-	memoryChannel := make(chan []byte, 100)
+	opts := scan.Get()
+	memoryChannel := make(chan glance.Chunk, cmp.Or(opts.QueueSize, 100))
 	resultChannel := make(chan string, 1)
 
 	// Determine number of worker goroutines
-	workerCount := runtime.NumCPU()
-	if workerCount < 2 {
-		workerCount = 2
-	}
-	if workerCount > MaxWorkersV3 {
-		workerCount = MaxWorkersV3
-	}
+	workerCount := opts.Workers
 	log.Debug().Msgf("Starting %d workers for V3 key search", workerCount)
 
 	// Start consumer goroutines
@@ -112,7 +104,7 @@ func (e *V3Extractor) Extract(ctx context.Context, proc *model.Process) (string,
 }
 
 // findMemory searches for memory regions using Glance
-func (e *V3Extractor) findMemory(ctx context.Context, pid uint32, memoryChannel chan<- []byte) error {
+func (e *V3Extractor) findMemory(ctx context.Context, pid uint32, memoryChannel chan<- glance.Chunk) error {
 	// Initialize a Glance instance to read process memory
 	g := glance.NewGlance(pid)
 
@@ -121,17 +113,19 @@ func (e *V3Extractor) findMemory(ctx context.Context, pid uint32, memoryChannel
 }
 
 // worker processes memory regions to find V3 version key
-func (e *V3Extractor) worker(ctx context.Context, memoryChannel <-chan []byte, resultChannel chan<- string) {
+func (e *V3Extractor) worker(ctx context.Context, memoryChannel <-chan glance.Chunk, resultChannel chan<- string) {
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case memory, ok := <-memoryChannel:
+		case chunk, ok := <-memoryChannel:
 			if !ok {
 				return
 			}
 
-			if key, ok := e.SearchKey(ctx, memory); ok {
+			key, ok := e.SearchKey(ctx, chunk.Data)
+			chunk.Release()
+			if ok {
 				select {
 				case resultChannel <- key:
 				default:
@@ -2,9 +2,9 @@ package darwin
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"encoding/hex"
-	"runtime"
 	"strings"
 	"sync"
 
@@ -13,11 +13,10 @@ import (
 	"github.com/DanielMao1/chatlog/internal/errors"
 	"github.com/DanielMao1/chatlog/internal/wechat/decrypt"
 	"github.com/DanielMao1/chatlog/internal/wechat/key/darwin/glance"
+	"github.com/DanielMao1/chatlog/internal/wechat/key/scan"
 	"github.com/DanielMao1/chatlog/internal/wechat/model"
-)
-
-const (
-	MaxWorkers = 8
+	"github.com/DanielMao1/chatlog/pkg/bloom"
+	"github.com/DanielMao1/chatlog/pkg/progress"
 )
 
 var V4KeyPatterns = []KeyPatternInfo{
@@ -47,15 +46,27 @@ var V4ImgKeyPatterns = []KeyPatternInfo{
 	},
 }
 
+// 已处理的候选密钥用固定内存的布隆过滤器记录，超过容量时分片清空，只会重复验证
+// 误判时跳过一个未验证的候选密钥，密钥通常在内存中出现多次，按 1e-6 的误判率不影响找到密钥
+// 过滤器按分片加锁，多个 worker 同时去重时不会争用同一把锁
+const (
+	// ProcessedDerivedKeys 按 8 字节步长扫描的候选派生密钥数量上限，约占 16MB
+	ProcessedDerivedKeys = 1 << 22
+	// ProcessedPatternKeys 特征匹配到的候选数据与图片密钥数量上限，约占 4MB
+	ProcessedPatternKeys = 1 << 20
+	// ProcessedFPRate 已处理密钥的误判率
+	ProcessedFPRate = 1e-6
+)
+
 type V4Extractor struct {
-	validator              *decrypt.Validator
-	dataKeyPatterns        []KeyPatternInfo
-	derivedKeyPatterns     []KeyPatternInfo
-	imgKeyPatterns         []KeyPatternInfo
-	processedDataKeys      sync.Map // Thread-safe map for processed data keys
-	processedDerivedKeys   sync.Map // Thread-safe map for processed derived keys
-	processedImgKeys       sync.Map // Thread-safe map for processed image keys
-	foundDerivedKeys       sync.Map // Thread-safe map for validated derived keys: keyHex -> true
+	validator            *decrypt.Validator
+	dataKeyPatterns      []KeyPatternInfo
+	derivedKeyPatterns   []KeyPatternInfo
+	imgKeyPatterns       []KeyPatternInfo
+	processedDataKeys    *bloom.Filter // Fixed-memory set of processed data keys
+	processedDerivedKeys *bloom.Filter // Fixed-memory set of processed derived keys
+	processedImgKeys     *bloom.Filter // Fixed-memory set of processed image keys
+	foundDerivedKeys     sync.Map      // Thread-safe map for validated derived keys: keyHex -> true
 }
 
 func NewV4Extractor() *V4Extractor {
@@ -63,6 +74,10 @@ func NewV4Extractor() *V4Extractor {
 		dataKeyPatterns:    V4KeyPatterns,
 		derivedKeyPatterns: V4DerivedKeyPatterns,
 		imgKeyPatterns:     V4ImgKeyPatterns,
+
+		processedDataKeys:    bloom.New(ProcessedPatternKeys, ProcessedFPRate),
+		processedDerivedKeys: bloom.New(ProcessedDerivedKeys, ProcessedFPRate),
+		processedImgKeys:     bloom.New(ProcessedPatternKeys, ProcessedFPRate),
 	}
 }
 
@@ -79,23 +94,20 @@ func (e *V4Extractor) Extract(ctx context.Context, proc *model.Process) (string,
 	if e.validator == nil {
 		return "", "", errors.ErrValidatorNotSet
 	}
+	// 加载数据目录中的数据库，包括上次提取后新建的数据库
+	e.validator.Refresh()
 
 	// Create context to control all goroutines
 	searchCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	// Create channels for memory data and results
-	memoryChannel := make(chan []byte, 200)
+	opts := scan.Get()
+	memoryChannel := make(chan glance.Chunk, cmp.Or(opts.QueueSize, 200))
 	resultChannel := make(chan [2]string, 1)
 
 	// Determine number of worker goroutines
-	workerCount := runtime.NumCPU()
-	if workerCount < 2 {
-		workerCount = 2
-	}
-	if workerCount > MaxWorkers {
-		workerCount = MaxWorkers
-	}
+	workerCount := opts.Workers
 	log.Debug().Msgf("Starting %d workers for V4 key search", workerCount)
 
 	// Start consumer goroutines
@@ -175,7 +187,7 @@ func (e *V4Extractor) Extract(ctx context.Context, proc *model.Process) (string,
 }
 
 // findMemory searches for memory regions using Glance
-func (e *V4Extractor) findMemory(ctx context.Context, pid uint32, memoryChannel chan<- []byte) error {
+func (e *V4Extractor) findMemory(ctx context.Context, pid uint32, memoryChannel chan<- glance.Chunk) error {
 	// Initialize a Glance instance to read process memory
 	g := glance.NewGlance(pid)
 
@@ -184,7 +196,7 @@ func (e *V4Extractor) findMemory(ctx context.Context, pid uint32, memoryChannel
 }
 
 // worker processes memory regions to find V4 version key
-func (e *V4Extractor) worker(ctx context.Context, memoryChannel <-chan []byte, resultChannel chan<- [2]string) {
+func (e *V4Extractor) worker(ctx context.Context, memoryChannel <-chan glance.Chunk, resultChannel chan<- [2]string) {
 	// Track found keys (raw key only; derived keys go to foundDerivedKeys sync.Map)
 	var rawDataKey, imgKey string
 
@@ -192,7 +204,7 @@ func (e *V4Extractor) worker(ctx context.Context, memoryChannel <-chan []byte, r
 		select {
 		case <-ctx.Done():
 			return
-		case memory, ok := <-memoryChannel:
+		case chunk, ok := <-memoryChannel:
 			if !ok {
 				// Memory scanning complete, return whatever raw/img keys we found
 				if rawDataKey != "" || imgKey != "" {
@@ -204,38 +216,52 @@ func (e *V4Extractor) worker(ctx context.Context, memoryChannel <-chan []byte, r
 				return
 			}
 
-			// Search for derived keys (skip if all databases already matched)
-			if !e.validator.AllDerivedKeysFound() {
-				e.SearchAllDerivedKeys(ctx, memory)
+			stop := e.searchChunk(ctx, chunk.Data, &rawDataKey, &imgKey, resultChannel)
+			chunk.Release()
+			if stop {
+				return
 			}
+		}
+	}
+}
 
-			// Search for raw data key (older WeChat versions, only if no raw key found yet)
-			if rawDataKey == "" {
-				if key, ok := e.SearchKey(ctx, memory); ok {
-					rawDataKey = key
-					log.Debug().Msg("Raw data key found: " + key)
-					select {
-					case resultChannel <- [2]string{rawDataKey, imgKey}:
-					case <-ctx.Done():
-						return
-					}
-				}
+// searchChunk searches a memory chunk for derived, raw data and image keys, returns true when the search is cancelled
+// Keys are copied out as hex strings, memory is not retained after return
+func (e *V4Extractor) searchChunk(ctx context.Context, memory []byte, rawDataKey, imgKey *string, resultChannel chan<- [2]string) bool {
+	// Search for derived keys (skip if all databases already matched)
+	if !e.validator.AllDerivedKeysFound() {
+		if e.SearchAllDerivedKeys(ctx, memory) > 0 {
+			found, expected := e.validator.DerivedKeyCount()
+			progress.Publish(progress.Event{Stage: progress.StageKey, Phase: progress.PhaseDerived, Found: found, Expected: expected})
+		}
+	}
+
+	// Search for raw data key (older WeChat versions, only if no raw key found yet)
+	if *rawDataKey == "" {
+		if key, ok := e.SearchKey(ctx, memory); ok {
+			*rawDataKey = key
+			log.Debug().Msg("Raw data key found: " + key)
+			select {
+			case resultChannel <- [2]string{*rawDataKey, *imgKey}:
+			case <-ctx.Done():
+				return true
 			}
+		}
+	}
 
-			// Search for image key
-			if imgKey == "" {
-				if key, ok := e.SearchImgKey(ctx, memory); ok {
-					imgKey = key
-					log.Debug().Msg("Image key found: " + key)
-					select {
-					case resultChannel <- [2]string{rawDataKey, imgKey}:
-					case <-ctx.Done():
-						return
-					}
-				}
+	// Search for image key
+	if *imgKey == "" {
+		if key, ok := e.SearchImgKey(ctx, memory); ok {
+			*imgKey = key
+			log.Debug().Msg("Image key found: " + key)
+			select {
+			case resultChannel <- [2]string{*rawDataKey, *imgKey}:
+			case <-ctx.Done():
+				return true
 			}
 		}
 	}
+	return false
 }
 
 func (e *V4Extractor) SearchKey(ctx context.Context, memory []byte) (string, bool) {
@@ -281,12 +307,12 @@ func (e *V4Extractor) SearchKey(ctx context.Context, memory []byte) (string, boo
 
 				// Extract the key data, which is at the offset position and 32 bytes long
 				keyData := memory[keyOffset : keyOffset+32]
-				keyHex := hex.EncodeToString(keyData)
 
 				// Skip if we've already processed this key (thread-safe check)
-				if _, loaded := e.processedDataKeys.LoadOrStore(keyHex, true); loaded {
+				if e.processedDataKeys.TestAndAdd(keyData) {
 					continue
 				}
+				keyHex := hex.EncodeToString(keyData)
 
 				// Validate key against database header
 				if e.validator.Validate(keyData) {
@@ -352,12 +378,12 @@ func (e *V4Extractor) SearchImgKey(ctx context.Context, memory []byte) (string,
 
 				// Extract the key data, which is at the offset position and 16 bytes long
 				keyData := memory[keyOffset : keyOffset+16]
-				keyHex := hex.EncodeToString(keyData)
 
 				// Skip if we've already processed this key (thread-safe check)
-				if _, loaded := e.processedImgKeys.LoadOrStore(keyHex, true); loaded {
+				if e.processedImgKeys.TestAndAdd(keyData) {
 					continue
 				}
+				keyHex := hex.EncodeToString(keyData)
 
 				// Validate key using image key validator
 				if e.validator.ValidateImgKey(keyData) {
@@ -415,13 +441,13 @@ func (e *V4Extractor) SearchAllDerivedKeys(ctx context.Context, memory []byte) i
 			continue
 		}
 
-		keyHex := hex.EncodeToString(keyData)
-
-		if _, loaded := e.processedDerivedKeys.LoadOrStore(keyHex, true); loaded {
+		// Dedupe on the raw bytes, only keys that validate are hex-encoded
+		if e.processedDerivedKeys.TestAndAdd(keyData) {
 			continue
 		}
 
 		if e.validator.ValidateDerivedKey(keyData) {
+			keyHex := hex.EncodeToString(keyData)
 			e.foundDerivedKeys.Store(keyHex, true)
 			count++
 			log.Debug().
@@ -4,9 +4,14 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/DanielMao1/chatlog/internal/wechat/decrypt"
+	"github.com/DanielMao1/chatlog/internal/wechat/decrypt/bench"
+	"github.com/DanielMao1/chatlog/internal/wechat/key/darwin/glance"
 )
 
 // Known derived keys from WeChat 4.1.7 memory
@@ -155,10 +160,10 @@ func TestWorker_FindsDerivedKeyAndReports(t *testing.T) {
 	copy(memory[512:544], testSessionDerivedKey)
 
 	ctx := context.Background()
-	memCh := make(chan []byte, 1)
+	memCh := make(chan glance.Chunk, 1)
 	resultCh := make(chan [2]string, 1)
 
-	memCh <- memory
+	memCh <- glance.Chunk{Data: memory}
 	close(memCh)
 
 	ext.worker(ctx, memCh, resultCh)
@@ -170,3 +175,65 @@ func TestWorker_FindsDerivedKeyAndReports(t *testing.T) {
 		t.Fatalf("Worker should store derived key in foundDerivedKeys, expected %s", expectedKey)
 	}
 }
+
+// syntheticValidator 在临时目录中生成 dbs 个 4.0 数据库，返回验证器与第一个数据库的派生密钥
+func syntheticValidator(tb testing.TB, dbs int) (*decrypt.Validator, []byte) {
+	tb.Helper()
+	dir := tb.TempDir()
+	msgDir := filepath.Join(dir, "db_storage", "message")
+	if err := os.MkdirAll(msgDir, 0755); err != nil {
+		tb.Fatal(err)
+	}
+	var first []byte
+	for i := 0; i < dbs; i++ {
+		key := make([]byte, 32)
+		rand.Read(key)
+		if i == 0 {
+			first = key
+		}
+		name := fmt.Sprintf("message_%d.db", i)
+		if err := os.WriteFile(filepath.Join(msgDir, name), bench.V4Database(key), 0600); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	v, err := decrypt.NewValidator("darwin", 4, dir)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return v, first
+}
+
+func TestSearchDerivedKey_SyntheticDatabase(t *testing.T) {
+	v, key := syntheticValidator(t, 3)
+	ext := NewV4Extractor()
+	ext.SetValidate(v)
+
+	memory := make([]byte, 4096)
+	rand.Read(memory)
+	copy(memory[1032:1064], key)
+
+	found, ok := ext.SearchDerivedKey(context.Background(), memory)
+	if !ok || found != hex.EncodeToString(key) {
+		t.Fatalf("got %s, %v, want %x", found, ok, key)
+	}
+}
+
+// BenchmarkSearchAllDerivedKeys 每次操作为扫描一个候选密钥，候选不重复，都需要对每个数据库验证
+func BenchmarkSearchAllDerivedKeys(b *testing.B) {
+	v, _ := syntheticValidator(b, 4)
+	ext := NewV4Extractor()
+	ext.SetValidate(v)
+	ctx := context.Background()
+
+	// 先分配布隆过滤器，不计入每次操作的内存
+	warm := make([]byte, 16*1024)
+	rand.Read(warm)
+	ext.SearchAllDerivedKeys(ctx, warm)
+
+	memory := make([]byte, b.N*8+24)
+	rand.Read(memory)
+	b.ReportAllocs()
+	b.SetBytes(8)
+	b.ResetTimer()
+	ext.SearchAllDerivedKeys(ctx, memory)
+}
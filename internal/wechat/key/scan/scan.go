@@ -0,0 +1,83 @@
+// Package scan 保存扫描进程内存搜索密钥时的并发与内存参数，由配置或命令行参数设置，各平台的提取器共用
+package scan
+
+import (
+	"sync"
+
+	"github.com/DanielMao1/chatlog/pkg/util"
+)
+
+const (
+	// DefaultChunkSize 默认的内存块大小，大于该大小的内存区域拆分后并行搜索
+	DefaultChunkSize = 4 * 1024 * 1024
+	// MinChunkSize 内存块大小的下限，过小时重叠部分占比过高
+	MinChunkSize = 64 * 1024
+	// DefaultOverlap 默认的重叠字节数，大于密钥特征与密钥之间的最大偏移
+	DefaultOverlap = 1024
+	// MaxOverlap 重叠字节数的上限，不超过内存块大小下限的一半
+	MaxOverlap = MinChunkSize / 2
+)
+
+// Options 密钥扫描参数，零值表示使用默认值
+type Options struct {
+	// Workers 搜索密钥的并发数，0 表示按 CPU 核数（最多 util.MaxJobs）
+	Workers int
+	// QueueSize 读取后等待搜索的内存块数量，越大占用内存越多，0 表示使用提取器的默认值
+	QueueSize int
+	// ChunkSize 内存块大小（字节）
+	ChunkSize int
+	// Overlap 相邻内存块与相邻内存区域之间重叠的字节数，跨越边界的特征与密钥仍能被找到
+	Overlap int
+}
+
+var (
+	mu       sync.RWMutex
+	options  Options
+	override Options
+)
+
+// Set 设置配置中的密钥扫描参数
+func Set(o Options) {
+	mu.Lock()
+	defer mu.Unlock()
+	options = o
+}
+
+// Override 设置命令行参数中的密钥扫描参数，非零的参数覆盖配置
+func Override(o Options) {
+	mu.Lock()
+	defer mu.Unlock()
+	override = o
+}
+
+// Get 返回填充默认值后的密钥扫描参数
+func Get() Options {
+	mu.RLock()
+	o, ov := options, override
+	mu.RUnlock()
+
+	if ov.Workers != 0 {
+		o.Workers = ov.Workers
+	}
+	if ov.QueueSize != 0 {
+		o.QueueSize = ov.QueueSize
+	}
+	if ov.ChunkSize != 0 {
+		o.ChunkSize = ov.ChunkSize
+	}
+	if ov.Overlap != 0 {
+		o.Overlap = ov.Overlap
+	}
+
+	o.Workers = util.Jobs(o.Workers)
+	o.QueueSize = max(o.QueueSize, 0)
+	if o.ChunkSize <= 0 {
+		o.ChunkSize = DefaultChunkSize
+	}
+	o.ChunkSize = max(o.ChunkSize, MinChunkSize)
+	if o.Overlap <= 0 {
+		o.Overlap = DefaultOverlap
+	}
+	o.Overlap = min(o.Overlap, MaxOverlap)
+	return o
+}
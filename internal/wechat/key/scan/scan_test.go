@@ -0,0 +1,34 @@
+package scan
+
+import (
+	"testing"
+
+	"github.com/DanielMao1/chatlog/pkg/util"
+)
+
+func TestGet(t *testing.T) {
+	defer Set(Options{})
+	defer Override(Options{})
+
+	Set(Options{})
+	if o := Get(); o.Workers != util.Jobs(0) || o.QueueSize != 0 || o.ChunkSize != DefaultChunkSize || o.Overlap != DefaultOverlap {
+		t.Fatalf("unexpected defaults: %+v", o)
+	}
+
+	// 命令行参数只覆盖非零的配置
+	Set(Options{Workers: 4, QueueSize: 20, ChunkSize: 1024, Overlap: 256})
+	Override(Options{QueueSize: 50})
+	if o := Get(); o.Workers != 4 || o.QueueSize != 50 || o.ChunkSize != MinChunkSize || o.Overlap != 256 {
+		t.Fatalf("unexpected options: %+v", o)
+	}
+
+	Override(Options{Workers: 100})
+	if o := Get(); o.Workers != util.MaxJobs {
+		t.Fatalf("workers not capped: %d", o.Workers)
+	}
+
+	Override(Options{Overlap: 1 << 20})
+	if o := Get(); o.Overlap != MaxOverlap {
+		t.Fatalf("overlap not capped: %d", o.Overlap)
+	}
+}
@@ -0,0 +1,31 @@
+package windows
+
+// regionCarry carries the tail of the last region read into the buffer of the next adjacent region
+type regionCarry struct {
+	// overlap is the number of bytes carried from the end of a memory region into the next one
+	// when the two are adjacent, so patterns spanning the boundary of two regions are still found
+	overlap int
+	tail    []byte
+	end     uintptr
+}
+
+// buffer returns the buffer for a region of size bytes at addr and the part of it the region is read into
+func (c *regionCarry) buffer(addr, size uintptr) (memory, dst []byte) {
+	var carry []byte
+	if c.end == addr {
+		carry = c.tail
+	}
+	memory = make([]byte, uintptr(len(carry))+size)
+	copy(memory, carry)
+	return memory, memory[len(carry):]
+}
+
+// read records the region at addr that was read into memory, pass nil when it could not be read
+func (c *regionCarry) read(memory []byte, addr, size uintptr) {
+	if memory == nil || c.overlap <= 0 {
+		c.tail, c.end = nil, 0
+		return
+	}
+	c.tail = memory[len(memory)-min(c.overlap, len(memory)):]
+	c.end = addr + size
+}
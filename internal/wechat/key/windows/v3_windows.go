@@ -2,11 +2,11 @@ package windows
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
-	"runtime"
 	"sync"
 	"unsafe"
 
@@ -14,13 +14,13 @@ import (
 	"golang.org/x/sys/windows"
 
 	"github.com/DanielMao1/chatlog/internal/errors"
+	"github.com/DanielMao1/chatlog/internal/wechat/key/scan"
 	"github.com/DanielMao1/chatlog/internal/wechat/model"
 	"github.com/DanielMao1/chatlog/pkg/util"
 )
 
 const (
 	V3ModuleName = "WeChatWin.dll"
-	MaxWorkers   = 16
 )
 
 func (e *V3Extractor) Extract(ctx context.Context, proc *model.Process) (string, string, error) {
@@ -46,17 +46,12 @@ func (e *V3Extractor) Extract(ctx context.Context, proc *model.Process) (string,
 	defer cancel()
 
 	// Create channels for memory data and results
-	memoryChannel := make(chan []byte, 100)
+	opts := scan.Get()
+	memoryChannel := make(chan []byte, cmp.Or(opts.QueueSize, 100))
 	resultChannel := make(chan string, 1)
 
 	// Determine number of worker goroutines
-	workerCount := runtime.NumCPU()
-	if workerCount < 2 {
-		workerCount = 2
-	}
-	if workerCount > MaxWorkers {
-		workerCount = MaxWorkers
-	}
+	workerCount := opts.Workers
 	log.Debug().Msgf("Starting %d workers for V3 key search", workerCount)
 
 	// Start consumer goroutines
@@ -114,6 +109,7 @@ func (e *V3Extractor) findMemory(ctx context.Context, handle windows.Handle, pid
 	baseAddr := uintptr(module.ModBaseAddr)
 	endAddr := baseAddr + uintptr(module.ModBaseSize)
 	currentAddr := baseAddr
+	carry := regionCarry{overlap: scan.Get().Overlap}
 
 	for currentAddr < endAddr {
 		var mbi windows.MemoryBasicInformation
@@ -138,8 +134,13 @@ func (e *V3Extractor) findMemory(ctx context.Context, handle windows.Handle, pid
 			}
 
 			// Read writable memory region
-			memory := make([]byte, regionSize)
-			if err = windows.ReadProcessMemory(handle, currentAddr, &memory[0], regionSize, nil); err == nil {
+			// Adjacent regions share the tail of the previous one to catch patterns at region boundaries
+			memory, dst := carry.buffer(currentAddr, regionSize)
+			if err = windows.ReadProcessMemory(handle, currentAddr, &dst[0], regionSize, nil); err != nil {
+				memory = nil
+			}
+			carry.read(memory, currentAddr, regionSize)
+			if memory != nil {
 				select {
 				case memoryChannel <- memory:
 					log.Debug().Msgf("Memory region: 0x%X - 0x%X, size: %d bytes", currentAddr, currentAddr+regionSize, regionSize)
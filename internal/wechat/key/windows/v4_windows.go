@@ -2,9 +2,11 @@ package windows
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"encoding/binary"
 	"encoding/hex"
+	"fmt"
 	"runtime"
 	"sync"
 	"unsafe"
@@ -13,7 +15,9 @@ import (
 	"golang.org/x/sys/windows"
 
 	"github.com/DanielMao1/chatlog/internal/errors"
+	"github.com/DanielMao1/chatlog/internal/wechat/key/scan"
 	"github.com/DanielMao1/chatlog/internal/wechat/model"
+	"github.com/DanielMao1/chatlog/pkg/progress"
 )
 
 const (
@@ -37,17 +41,12 @@ func (e *V4Extractor) Extract(ctx context.Context, proc *model.Process) (string,
 	defer cancel()
 
 	// Create channels for memory data and results
-	memoryChannel := make(chan []byte, 100)
+	opts := scan.Get()
+	memoryChannel := make(chan []byte, cmp.Or(opts.QueueSize, 100))
 	resultChannel := make(chan [2]string, 1)
 
 	// Determine number of worker goroutines
-	workerCount := runtime.NumCPU()
-	if workerCount < 2 {
-		workerCount = 2
-	}
-	if workerCount > MaxWorkers {
-		workerCount = MaxWorkers
-	}
+	workerCount := opts.Workers
 	log.Debug().Msgf("Starting %d workers for V4 key search", workerCount)
 
 	// Start consumer goroutines
@@ -124,6 +123,11 @@ func (e *V4Extractor) findMemory(ctx context.Context, handle windows.Handle, mem
 	log.Debug().Msgf("Scanning memory regions from 0x%X to 0x%X", minAddr, maxAddr)
 
 	currentAddr := minAddr
+	carry := regionCarry{overlap: scan.Get().Overlap}
+
+	// 区域总数未知，只统计已扫描的区域与字节数
+	tracker := progress.NewTracker(progress.StageKey, progress.PhaseScan, 0, 0)
+	defer tracker.Done()
 
 	for currentAddr < maxAddr {
 		var memInfo windows.MemoryBasicInformation
@@ -147,8 +151,16 @@ func (e *V4Extractor) findMemory(ctx context.Context, handle windows.Handle, mem
 			}
 
 			// Read memory region
-			memory := make([]byte, regionSize)
-			if err = windows.ReadProcessMemory(handle, currentAddr, &memory[0], regionSize, nil); err == nil {
+			// Adjacent regions share the tail of the previous one to catch patterns at region boundaries
+			memory, dst := carry.buffer(currentAddr, regionSize)
+			if err = windows.ReadProcessMemory(handle, currentAddr, &dst[0], regionSize, nil); err != nil {
+				memory = nil
+			}
+			carry.read(memory, currentAddr, regionSize)
+			if memory != nil {
+				name := fmt.Sprintf("0x%X", currentAddr)
+				tracker.Start(name, int64(regionSize))
+				tracker.Finish(name)
 				select {
 				case memoryChannel <- memory:
 					log.Debug().Msgf("Memory region for analysis: 0x%X - 0x%X, size: %d bytes", currentAddr, currentAddr+regionSize, regionSize)
@@ -62,6 +62,8 @@ type DataSource struct {
 
 	talkerDBMap      map[string]string
 	user2DisplayName map[string]string
+	// shards 缓存每个消息数据库中聊天对象的 MD5，启动时不必打开所有消息数据库
+	shards *dbm.ShardIndex[[]string]
 }
 
 func New(path string) (*DataSource, error) {
@@ -70,6 +72,7 @@ func New(path string) (*DataSource, error) {
 		dbm:              dbm.NewDBManager(path),
 		talkerDBMap:      make(map[string]string),
 		user2DisplayName: make(map[string]string),
+		shards:           dbm.LoadShardIndex[[]string](path, Message),
 	}
 
 	for _, g := range Groups {
@@ -123,44 +126,62 @@ func (ds *DataSource) initMessageDbs() error {
 		}
 		return err
 	}
-	// 处理每个数据库文件
+	// 处理每个数据库文件，只打开缓存中没有或已经变化的数据库
 	talkerDBMap := make(map[string]string)
 	for _, filePath := range dbPaths {
-		db, err := ds.dbm.OpenDB(filePath)
-		if err != nil {
-			log.Err(err).Msgf("获取数据库 %s 失败", filePath)
-			continue
-		}
-
-		// 获取所有表名
-		rows, err := db.Query("SELECT name FROM sqlite_master WHERE type='table' AND name LIKE 'Chat_%'")
-		if err != nil {
-			log.Err(err).Msgf("数据库 %s 中没有 Chat 表", filePath)
-			continue
-		}
-
-		for rows.Next() {
-			var tableName string
-			if err := rows.Scan(&tableName); err != nil {
-				log.Err(err).Msgf("数据库 %s 扫描表名失败", filePath)
-				continue
-			}
-
-			// 从表名中提取可能的talker信息
-			talkerMd5 := extractTalkerFromTableName(tableName)
-			if talkerMd5 == "" {
+		talkers, ok := ds.shards.Get(filePath)
+		if !ok {
+			if talkers, err = ds.messageTalkers(filePath); err != nil {
 				continue
 			}
+			ds.shards.Put(filePath, talkers)
+		}
+		for _, talkerMd5 := range talkers {
 			talkerDBMap[talkerMd5] = filePath
 		}
-		rows.Close()
+	}
+	if err := ds.shards.Save(dbPaths); err != nil {
+		log.Debug().Err(err).Msg("failed to save message shard index")
 	}
 	ds.talkerDBMap = talkerDBMap
 	return nil
 }
 
+// messageTalkers 从消息数据库的 Chat 表名中提取聊天对象的 MD5
+func (ds *DataSource) messageTalkers(filePath string) ([]string, error) {
+	db, release, err := ds.dbm.OpenDB(filePath)
+	if err != nil {
+		log.Err(err).Msgf("获取数据库 %s 失败", filePath)
+		return nil, err
+	}
+	defer release()
+
+	// 获取所有表名
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type='table' AND name LIKE 'Chat_%'")
+	if err != nil {
+		log.Err(err).Msgf("数据库 %s 中没有 Chat 表", filePath)
+		return nil, err
+	}
+	defer rows.Close()
+
+	talkers := make([]string, 0)
+	for rows.Next() {
+		var tableName string
+		if err := rows.Scan(&tableName); err != nil {
+			log.Err(err).Msgf("数据库 %s 扫描表名失败", filePath)
+			continue
+		}
+
+		// 从表名中提取可能的talker信息
+		if talkerMd5 := extractTalkerFromTableName(tableName); talkerMd5 != "" {
+			talkers = append(talkers, talkerMd5)
+		}
+	}
+	return talkers, nil
+}
+
 func (ds *DataSource) initChatRoomDb() error {
-	db, err := ds.dbm.GetDB(ChatRoom)
+	db, release, err := ds.dbm.GetDB(ChatRoom)
 	if err != nil {
 		if strings.Contains(err.Error(), "db file not found") {
 			ds.user2DisplayName = make(map[string]string)
@@ -168,6 +189,7 @@ func (ds *DataSource) initChatRoomDb() error {
 		}
 		return err
 	}
+	defer release()
 
 	rows, err := db.Query("SELECT m_nsUsrName, IFNULL(nickname,\"\") FROM GroupMember")
 	if err != nil {
@@ -234,11 +256,12 @@ func (ds *DataSource) GetMessages(ctx context.Context, startTime, endTime time.T
 			continue
 		}
 
-		db, err := ds.dbm.OpenDB(dbPath)
+		db, release, err := ds.dbm.OpenDB(dbPath)
 		if err != nil {
 			log.Error().Msgf("数据库 %s 未打开", dbPath)
 			continue
 		}
+		defer release()
 
 		tableName := fmt.Sprintf("Chat_%s", talkerMd5)
 
@@ -349,6 +372,65 @@ func (ds *DataSource) GetMessages(ctx context.Context, startTime, endTime time.T
 	return filteredMessages, nil
 }
 
+// CountMessages 在 SQL 中按日期和消息类型聚合消息数量
+// darwinv3 没有子类型字段，分享消息通过 XML 中的 <type>6</type> 识别文件
+func (ds *DataSource) CountMessages(ctx context.Context, startTime, endTime time.Time, talker string) ([]*model.MessageCount, error) {
+	talkers := util.Str2List(talker, ",")
+	if len(talkers) == 0 {
+		return nil, errors.ErrTalkerEmpty
+	}
+
+	ret := make([]*model.MessageCount, 0)
+	for _, talkerItem := range talkers {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		_talkerMd5Bytes := md5.Sum([]byte(talkerItem))
+		talkerMd5 := hex.EncodeToString(_talkerMd5Bytes[:])
+		dbPath, ok := ds.talkerDBMap[talkerMd5]
+		if !ok {
+			continue
+		}
+
+		db, release, err := ds.dbm.OpenDB(dbPath)
+		if err != nil {
+			log.Error().Msgf("数据库 %s 未打开", dbPath)
+			continue
+		}
+		defer release()
+
+		query := fmt.Sprintf(`
+			SELECT strftime('%%Y-%%m-%%d', msgCreateTime, 'unixepoch', 'localtime') AS day, messageType,
+				CASE WHEN messageType = %d AND msgContent LIKE '%%<type>%d</type>%%' THEN %d ELSE 0 END AS sub_type,
+				COUNT(*)
+			FROM Chat_%s
+			WHERE msgCreateTime >= ? AND msgCreateTime <= ?
+			GROUP BY day, messageType, sub_type
+		`, model.MessageTypeShare, model.MessageSubTypeFile, model.MessageSubTypeFile, talkerMd5)
+
+		rows, err := db.QueryContext(ctx, query, startTime.Unix(), endTime.Unix())
+		if err != nil {
+			if strings.Contains(err.Error(), "no such table") {
+				continue
+			}
+			log.Err(err).Msgf("从数据库 %s 统计消息失败", dbPath)
+			continue
+		}
+		for rows.Next() {
+			c := &model.MessageCount{Talker: talkerItem}
+			if err := rows.Scan(&c.Day, &c.Type, &c.SubType, &c.Count); err != nil {
+				rows.Close()
+				return nil, errors.ScanRowFailed(err)
+			}
+			ret = append(ret, c)
+		}
+		rows.Close()
+	}
+
+	return ret, nil
+}
+
 // 从表名中提取 talker
 func extractTalkerFromTableName(tableName string) string {
 
@@ -390,10 +472,11 @@ func (ds *DataSource) GetContacts(ctx context.Context, key string, limit, offset
 	}
 
 	// 执行查询
-	db, err := ds.dbm.GetDB(Contact)
+	db, release, err := ds.dbm.GetDB(Contact)
 	if err != nil {
 		return nil, err
 	}
+	defer release()
 	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, errors.QueryFailed(query, err)
@@ -448,10 +531,11 @@ func (ds *DataSource) GetChatRooms(ctx context.Context, key string, limit, offse
 	}
 
 	// 执行查询
-	db, err := ds.dbm.GetDB(ChatRoom)
+	db, release, err := ds.dbm.GetDB(ChatRoom)
 	if err != nil {
 		return nil, err
 	}
+	defer release()
 	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, errors.QueryFailed(query, err)
@@ -549,10 +633,11 @@ func (ds *DataSource) GetSessions(ctx context.Context, key string, limit, offset
 	}
 
 	// 执行查询
-	db, err := ds.dbm.GetDB(Session)
+	db, release, err := ds.dbm.GetDB(Session)
 	if err != nil {
 		return nil, err
 	}
+	defer release()
 	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, errors.QueryFailed(query, err)
@@ -611,10 +696,11 @@ WHERE
     r.mediaMd5 = ?`
 	args := []interface{}{key}
 	// 执行查询
-	db, err := ds.dbm.GetDB(Media)
+	db, release, err := ds.dbm.GetDB(Media)
 	if err != nil {
 		return nil, err
 	}
+	defer release()
 	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, errors.QueryFailed(query, err)
@@ -18,6 +18,9 @@ type DataSource interface {
 	// 消息
 	GetMessages(ctx context.Context, startTime, endTime time.Time, talker string, sender string, keyword string, limit, offset int) ([]*model.Message, error)
 
+	// 消息统计，按会话、日期和消息类型聚合时间范围内的消息数量，talker 支持以英文逗号分隔多个
+	CountMessages(ctx context.Context, startTime, endTime time.Time, talker string) ([]*model.MessageCount, error)
+
 	// 联系人
 	GetContacts(ctx context.Context, key string, limit, offset int) ([]*model.Contact, error)
 
@@ -2,37 +2,99 @@ package dbm
 
 import (
 	"database/sql"
+	"fmt"
+	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
 	"sync"
-	"time"
+	"sync/atomic"
 
 	"github.com/fsnotify/fsnotify"
-	_ "github.com/mattn/go-sqlite3"
 	"github.com/rs/zerolog/log"
+	"github.com/shirou/gopsutil/v4/process"
 
 	"github.com/DanielMao1/chatlog/internal/errors"
 	"github.com/DanielMao1/chatlog/pkg/filecopy"
 	"github.com/DanielMao1/chatlog/pkg/filemonitor"
+	"github.com/DanielMao1/chatlog/pkg/util/zstd"
 )
 
+// DecompressDir 未设置 Options.DecompressDir 时，用户缓存目录中保存压缩数据库解压副本的目录
+const DecompressDir = "chatlog_zstd"
+
 type DBManager struct {
 	path    string
 	id      string
 	fm      *filemonitor.FileMonitor
 	fgs     map[string]*filemonitor.FileGroup
-	dbs     map[string]*sql.DB
+	dbs     map[string]*openDB
 	dbPaths map[string][]string
 	mutex   sync.RWMutex
+
+	// used 递增的使用序号，打开的数据库超过 MaxOpenDBs 时关闭最久未使用的
+	used atomic.Int64
+
+	// tempDir 本实例保存解压副本的目录，只有当前用户可以访问，第一次解压时创建，Close 时删除
+	tempDir  string
+	tempErr  error
+	tempOnce sync.Once
+}
+
+// openDB 打开的数据库与最后一次使用的序号
+type openDB struct {
+	db   *sql.DB
+	used atomic.Int64
+	// copy 压缩的数据库解压后的副本，关闭数据库时删除
+	copy string
+
+	// refs 取得数据库后尚未释放的调用方数量
+	refs atomic.Int64
+	// retired 数据库已被淘汰或替换，最后一个调用方释放后关闭
+	retired   atomic.Bool
+	closeOnce sync.Once
+}
+
+// acquire 增加引用并返回释放函数，需要持有 DBManager 的锁，数据库还在 dbs 中
+func (e *openDB) acquire() func() {
+	e.refs.Add(1)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if e.refs.Add(-1) == 0 && e.retired.Load() {
+				e.close()
+			}
+		})
+	}
+}
+
+// retire 从 dbs 中移除数据库后调用，没有调用方在使用时立即关闭，否则等最后一个调用方释放后关闭
+func (e *openDB) retire() {
+	e.retired.Store(true)
+	if e.refs.Load() == 0 {
+		e.close()
+	}
+}
+
+// close 关闭数据库并删除解压的副本
+func (e *openDB) close() {
+	e.closeOnce.Do(func() {
+		e.db.Close()
+		if len(e.copy) != 0 {
+			os.Remove(e.copy)
+		}
+	})
 }
 
 func NewDBManager(path string) *DBManager {
+	cleanDecompressDirs(getOptions().DecompressDir)
 	return &DBManager{
 		path:    path,
 		id:      filepath.Base(path),
 		fm:      filemonitor.NewFileMonitor(),
 		fgs:     make(map[string]*filemonitor.FileGroup),
-		dbs:     make(map[string]*sql.DB),
+		dbs:     make(map[string]*openDB),
 		dbPaths: make(map[string][]string),
 	}
 }
@@ -61,28 +123,38 @@ func (d *DBManager) AddCallback(group string, callback func(event fsnotify.Event
 	return nil
 }
 
-func (d *DBManager) GetDB(name string) (*sql.DB, error) {
+// GetDB 打开 name 对应的第一个数据库，用完后调用 release，见 OpenDB
+func (d *DBManager) GetDB(name string) (*sql.DB, func(), error) {
 	dbPaths, err := d.GetDBPath(name)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	return d.OpenDB(dbPaths[0])
 }
 
-func (d *DBManager) GetDBs(name string) ([]*sql.DB, error) {
+// GetDBs 打开 name 对应的所有数据库，用完后调用 release 释放全部数据库
+func (d *DBManager) GetDBs(name string) ([]*sql.DB, func(), error) {
 	dbPaths, err := d.GetDBPath(name)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	dbs := make([]*sql.DB, 0)
+	releases := make([]func(), 0, len(dbPaths))
+	release := func() {
+		for _, r := range releases {
+			r()
+		}
+	}
 	for _, file := range dbPaths {
-		db, err := d.OpenDB(file)
+		db, r, err := d.OpenDB(file)
 		if err != nil {
-			return nil, err
+			release()
+			return nil, nil, err
 		}
 		dbs = append(dbs, db)
+		releases = append(releases, r)
 	}
-	return dbs, nil
+	return dbs, release, nil
 }
 
 func (d *DBManager) GetDBPath(name string) ([]string, error) {
@@ -111,31 +183,127 @@ func (d *DBManager) GetDBPath(name string) ([]string, error) {
 	return dbPaths, nil
 }
 
-func (d *DBManager) OpenDB(path string) (*sql.DB, error) {
+// OpenDB 打开数据库，用完后（包括读完查询结果后）调用 release
+// 数据库被淘汰或替换后不再返回给新的调用方，已取得的调用方释放前不会关闭
+func (d *DBManager) OpenDB(path string) (*sql.DB, func(), error) {
 	d.mutex.RLock()
-	db, ok := d.dbs[path]
+	e, ok := d.dbs[path]
+	var release func()
+	if ok {
+		e.used.Store(d.used.Add(1))
+		release = e.acquire()
+	}
 	d.mutex.RUnlock()
 	if ok {
-		return db, nil
+		return e.db, release, nil
 	}
 	var err error
+	var copied string
 	tempPath := path
-	if runtime.GOOS == "windows" {
+	if zstd.IsCompressed(path) {
+		// 压缩的工作目录，解压后打开，解压的副本同时避免了 Windows 上的文件占用
+		copied, err = d.decompressedCopy(path)
+		if err != nil {
+			log.Err(err).Msgf("解压数据库 %s 失败", path)
+			return nil, nil, err
+		}
+		tempPath = copied
+	} else if runtime.GOOS == "windows" {
 		tempPath, err = filecopy.GetTempCopy(d.id, path)
 		if err != nil {
 			log.Err(err).Msgf("获取临时拷贝文件 %s 失败", path)
-			return nil, err
+			return nil, nil, err
 		}
 	}
-	db, err = sql.Open("sqlite3", tempPath)
+	db, err := open(tempPath, len(copied) != 0)
 	if err != nil {
 		log.Err(err).Msgf("连接数据库 %s 失败", path)
-		return nil, err
+		if len(copied) != 0 {
+			os.Remove(copied)
+		}
+		return nil, nil, err
 	}
+	e = &openDB{db: db, copy: copied}
 	d.mutex.Lock()
-	d.dbs[path] = db
-	d.mutex.Unlock()
-	return db, nil
+	defer d.mutex.Unlock()
+	if cur, ok := d.dbs[path]; ok {
+		// 其他查询同时打开了该数据库
+		e.close()
+		cur.used.Store(d.used.Add(1))
+		return cur.db, cur.acquire(), nil
+	}
+	e.used.Store(d.used.Add(1))
+	d.dbs[path] = e
+	release = e.acquire()
+	d.evict()
+	return db, release, nil
+}
+
+// evict 打开的数据库超过 MaxOpenDBs 时关闭最久未使用的，需要持有写锁
+func (d *DBManager) evict() {
+	limit := getOptions().MaxOpenDBs
+	for len(d.dbs) > limit {
+		var oldest string
+		var used int64
+		for path, e := range d.dbs {
+			if u := e.used.Load(); len(oldest) == 0 || u < used {
+				oldest, used = path, u
+			}
+		}
+		log.Debug().Msgf("close least recently used database %s", oldest)
+		d.dbs[oldest].retire()
+		delete(d.dbs, oldest)
+	}
+}
+
+// decompressedCopy 将压缩的数据库解压为新的副本，每次打开使用单独的副本，关闭数据库时删除
+func (d *DBManager) decompressedCopy(path string) (string, error) {
+	dir, err := d.decompressDir()
+	if err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(dir, filepath.Base(path)+".*")
+	if err != nil {
+		return "", err
+	}
+	dst := f.Name()
+	f.Close()
+	if err := zstd.DecompressFile(path, dst); err != nil {
+		os.Remove(dst)
+		return "", err
+	}
+	return dst, nil
+}
+
+// decompressDir 返回本实例保存解压副本的目录 <DecompressDir>/<pid>-<id>-*，第一次调用时创建
+func (d *DBManager) decompressDir() (string, error) {
+	d.tempOnce.Do(func() {
+		base := getOptions().DecompressDir
+		if d.tempErr = os.MkdirAll(base, 0700); d.tempErr != nil {
+			return
+		}
+		d.tempDir, d.tempErr = os.MkdirTemp(base, fmt.Sprintf("%d-%s-", os.Getpid(), d.id))
+	})
+	return d.tempDir, d.tempErr
+}
+
+// cleanDecompressDirs 删除已退出的进程留下的解压副本，目录名以进程号开头
+func cleanDecompressDirs(base string) {
+	entries, err := os.ReadDir(base)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		prefix, _, _ := strings.Cut(entry.Name(), "-")
+		pid, err := strconv.Atoi(prefix)
+		if err != nil || pid == os.Getpid() {
+			continue
+		}
+		if exists, err := process.PidExists(int32(pid)); err == nil && !exists {
+			log.Debug().Msgf("remove decompressed databases of exited process %d", pid)
+			os.RemoveAll(filepath.Join(base, entry.Name()))
+		}
+	}
 }
 
 func (d *DBManager) Callback(event fsnotify.Event) error {
@@ -144,13 +312,9 @@ func (d *DBManager) Callback(event fsnotify.Event) error {
 	}
 
 	d.mutex.Lock()
-	db, ok := d.dbs[event.Name]
-	if ok {
+	if e, ok := d.dbs[event.Name]; ok {
 		delete(d.dbs, event.Name)
-		go func(db *sql.DB) {
-			time.Sleep(time.Second * 5)
-			db.Close()
-		}(db)
+		e.retire()
 	}
 	d.mutex.Unlock()
 
@@ -166,8 +330,14 @@ func (d *DBManager) Stop() error {
 }
 
 func (d *DBManager) Close() error {
-	for _, db := range d.dbs {
-		db.Close()
+	d.mutex.Lock()
+	for path, e := range d.dbs {
+		e.retire()
+		delete(d.dbs, path)
+	}
+	d.mutex.Unlock()
+	if len(d.tempDir) != 0 {
+		os.RemoveAll(d.tempDir)
 	}
 	return d.fm.Stop()
 }
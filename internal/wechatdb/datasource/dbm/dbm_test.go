@@ -1,9 +1,15 @@
 package dbm
 
 import (
+	"bytes"
 	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 	"time"
+
+	"github.com/DanielMao1/chatlog/pkg/util/zstd"
 )
 
 func TestXxx(t *testing.T) {
@@ -21,7 +27,7 @@ func TestXxx(t *testing.T) {
 
 	i := 0
 	for {
-		db, err := d.GetDB("session")
+		db, release, err := d.GetDB("session")
 		if err != nil {
 			fmt.Println(err)
 			break
@@ -29,7 +35,9 @@ func TestXxx(t *testing.T) {
 
 		var username string
 		row := db.QueryRow(`SELECT username FROM SessionTable LIMIT 1`)
-		if err := row.Scan(&username); err != nil {
+		err = row.Scan(&username)
+		release()
+		if err != nil {
 			fmt.Printf("Error scanning row: %v\n", err)
 			time.Sleep(100 * time.Millisecond)
 			continue
@@ -40,3 +48,53 @@ func TestXxx(t *testing.T) {
 	}
 
 }
+
+func TestDecompressedCopy(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "zstd")
+	defer SetOptions(Options{})
+	SetOptions(Options{DecompressDir: base})
+
+	// 已退出的进程留下的副本在启动时删除
+	stale := filepath.Join(base, "999999999-wxid_test-1")
+	if err := os.MkdirAll(stale, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "wxid_test")
+	path := filepath.Join(dir, "message_0.db")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, bytes.Repeat([]byte("SQLite format 3\x00"), 1024), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := zstd.CompressFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDBManager(dir)
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale copies not removed: %v", err)
+	}
+	if _, release, err := d.OpenDB(path); err != nil {
+		t.Fatal(err)
+	} else {
+		release()
+	}
+	copies, _ := filepath.Glob(filepath.Join(base, "*", "message_0.db.*"))
+	if len(copies) != 1 {
+		t.Fatalf("copies %v", copies)
+	}
+	if runtime.GOOS != "windows" {
+		for _, p := range []string{copies[0], filepath.Dir(copies[0])} {
+			if fi, err := os.Stat(p); err != nil || fi.Mode().Perm()&0077 != 0 {
+				t.Errorf("%s is readable by other users: %v", p, fi.Mode())
+			}
+		}
+	}
+
+	d.Close()
+	if entries, _ := os.ReadDir(base); len(entries) != 0 {
+		t.Errorf("copies left after Close: %v", entries)
+	}
+}
@@ -0,0 +1,151 @@
+package dbm
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/rs/zerolog/log"
+)
+
+// DriverName 打开工作目录数据库使用的驱动，每个连接建立时按 Options 设置 busy_timeout、mmap_size 与 journal_mode
+const DriverName = "sqlite3_chatlog"
+
+const (
+	// DefaultMaxConns 每个数据库默认的最大连接数
+	DefaultMaxConns = 8
+	// DefaultMaxOpenDBs 默认同时打开的数据库数量
+	DefaultMaxOpenDBs = 16
+	// DefaultBusyTimeout 默认等待数据库锁的时间
+	DefaultBusyTimeout = 5 * time.Second
+	// DefaultMmapSize 默认的内存映射大小
+	DefaultMmapSize = 256 * 1024 * 1024
+	// ConnMaxIdleTime 空闲连接保留的时间
+	ConnMaxIdleTime = 5 * time.Minute
+)
+
+// Options 打开工作目录数据库的参数，零值表示使用默认值
+type Options struct {
+	// MaxConns 每个数据库的最大连接数，并发查询不超过该数量时互不等待
+	MaxConns int
+	// MaxOpenDBs 同时打开的数据库数量，超过时关闭最久未使用的，消息分片较多时限制打开的文件与占用的内存
+	MaxOpenDBs int
+	// BusyTimeout 数据库被锁定时等待的时间
+	BusyTimeout time.Duration
+	// MmapSize 内存映射的字节数，负数表示不使用内存映射
+	MmapSize int64
+	// JournalMode 日志模式，默认为空，以只读方式打开数据库，不修改解密结果的日志模式
+	// 设置为 wal、delete 等 SQLite 的日志模式时以读写方式打开，并将数据库切换到该模式，wal 会在数据库旁留下 -wal 与 -shm 文件
+	JournalMode string
+	// DecompressDir 压缩的数据库解压副本的目录，为空时使用用户缓存目录中的 chatlog_zstd
+	DecompressDir string
+}
+
+var (
+	optionsMu sync.RWMutex
+	options   Options
+)
+
+func init() {
+	sql.Register(DriverName, &sqlite3.SQLiteDriver{ConnectHook: connectHook})
+}
+
+// SetOptions 设置之后打开的数据库使用的参数
+func SetOptions(o Options) {
+	optionsMu.Lock()
+	defer optionsMu.Unlock()
+	options = o
+}
+
+// getOptions 返回填充默认值后的参数
+func getOptions() Options {
+	optionsMu.RLock()
+	o := options
+	optionsMu.RUnlock()
+	if o.MaxConns <= 0 {
+		o.MaxConns = DefaultMaxConns
+	}
+	if o.MaxOpenDBs <= 0 {
+		o.MaxOpenDBs = DefaultMaxOpenDBs
+	}
+	if o.BusyTimeout <= 0 {
+		o.BusyTimeout = DefaultBusyTimeout
+	}
+	if o.MmapSize == 0 {
+		o.MmapSize = DefaultMmapSize
+	}
+	if len(o.DecompressDir) == 0 {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			dir = os.TempDir()
+		}
+		o.DecompressDir = filepath.Join(dir, DecompressDir)
+	}
+	switch o.JournalMode = strings.ToLower(o.JournalMode); o.JournalMode {
+	case "delete", "truncate", "persist", "memory", "wal", "off":
+	default:
+		o.JournalMode = ""
+	}
+	return o
+}
+
+// execer 不启用 cgo 编译时 SQLiteConn 没有 Exec 方法
+type execer interface {
+	Exec(query string, args []driver.Value) (driver.Result, error)
+}
+
+// connectHook 设置新连接的 PRAGMA
+// 只有设置了 JournalMode 时才切换日志模式，切换需要写入文件头，只读的文件切换失败时仍使用原来的模式
+func connectHook(c *sqlite3.SQLiteConn) error {
+	conn, ok := any(c).(execer)
+	if !ok {
+		return nil
+	}
+	o := getOptions()
+	if _, err := conn.Exec(fmt.Sprintf("PRAGMA busy_timeout = %d", o.BusyTimeout.Milliseconds()), nil); err != nil {
+		return err
+	}
+	if _, err := conn.Exec(fmt.Sprintf("PRAGMA mmap_size = %d", max(o.MmapSize, 0)), nil); err != nil {
+		return err
+	}
+	if len(o.JournalMode) == 0 {
+		return nil
+	}
+	if _, err := conn.Exec("PRAGMA journal_mode = "+o.JournalMode, nil); err != nil {
+		log.Debug().Err(err).Msgf("failed to set journal_mode to %s", o.JournalMode)
+	}
+	return nil
+}
+
+// open 打开数据库并按 Options 设置连接池
+// chatlog 只读取工作目录中的数据库，默认以 mode=ro 打开；immutable 表示文件在打开期间不会被修改或替换（如解压的副本），
+// 以 immutable=1 打开，不再加锁与检查修改；设置了 JournalMode 时以读写方式打开
+func open(path string, immutable bool) (*sql.DB, error) {
+	o := getOptions()
+	params := "mode=ro"
+	switch {
+	case len(o.JournalMode) != 0:
+		params = "mode=rw"
+	case immutable:
+		params += "&immutable=1"
+	}
+	db, err := sql.Open(DriverName, "file:"+uriPath(path)+"?"+params)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(o.MaxConns)
+	db.SetMaxIdleConns(o.MaxConns)
+	db.SetConnMaxIdleTime(ConnMaxIdleTime)
+	return db, nil
+}
+
+// uriPath 转义路径中在 SQLite URI 文件名中有特殊含义的字符
+func uriPath(path string) string {
+	return strings.NewReplacer("%", "%25", "?", "%3f", "#", "%23").Replace(path)
+}
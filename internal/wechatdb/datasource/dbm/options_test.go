@@ -0,0 +1,88 @@
+package dbm
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// createDB 创建只有一行数据的数据库，使用默认的 rollback 日志模式
+func createDB(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "session.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE t (v INTEGER); INSERT INTO t VALUES (1)`); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestOpen(t *testing.T) {
+	defer SetOptions(Options{})
+	SetOptions(Options{MaxConns: 4, BusyTimeout: 2 * time.Second})
+
+	path := createDB(t)
+	db, err := open(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// 只读打开，不修改解密结果的日志模式
+	var mode string
+	var timeout int
+	if err := db.QueryRow(`PRAGMA journal_mode`).Scan(&mode); err != nil || mode != "delete" {
+		t.Fatalf("journal_mode %q, err %v", mode, err)
+	}
+	if err := db.QueryRow(`PRAGMA busy_timeout`).Scan(&timeout); err != nil || timeout != 2000 {
+		t.Fatalf("busy_timeout %d, err %v", timeout, err)
+	}
+	if _, err := db.Exec(`INSERT INTO t VALUES (2)`); err == nil {
+		t.Fatal("wrote to a database opened read-only")
+	}
+
+	// 并发查询使用多个连接
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var v int
+			if err := db.QueryRow(`SELECT v FROM t`).Scan(&v); err != nil || v != 1 {
+				t.Errorf("query: %d, %v", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := db.Stats().MaxOpenConnections; n != 4 {
+		t.Fatalf("max open connections %d", n)
+	}
+	db.Close()
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if _, err := os.Stat(path + suffix); !os.IsNotExist(err) {
+			t.Errorf("%s left next to the database: %v", suffix, err)
+		}
+	}
+}
+
+func TestOpenJournalMode(t *testing.T) {
+	defer SetOptions(Options{})
+	SetOptions(Options{JournalMode: "WAL"})
+
+	db, err := open(createDB(t), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var mode string
+	if err := db.QueryRow(`PRAGMA journal_mode`).Scan(&mode); err != nil || mode != "wal" {
+		t.Fatalf("journal_mode %q, err %v", mode, err)
+	}
+}
@@ -0,0 +1,116 @@
+package dbm
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ShardIndexFile 工作目录中缓存消息数据库分片信息的文件，name 为数据源的名称
+// 启动时只需要读取变化过的分片，不必逐个打开所有分片
+func ShardIndexFile(name string) string {
+	return ".chatlog-shards-" + name + ".json"
+}
+
+type shardEntry[T any] struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	Info    T         `json:"info"`
+}
+
+// ShardIndex 按文件大小与修改时间缓存每个分片的信息，分片被重新解密后缓存失效
+type ShardIndex[T any] struct {
+	dir     string
+	file    string
+	mu      sync.Mutex
+	entries map[string]*shardEntry[T] // 键为相对工作目录的路径
+	dirty   bool
+}
+
+// LoadShardIndex 读取工作目录中的分片缓存，文件不存在或已损坏时返回空的缓存
+func LoadShardIndex[T any](dir, name string) *ShardIndex[T] {
+	idx := &ShardIndex[T]{
+		dir:     dir,
+		file:    filepath.Join(dir, ShardIndexFile(name)),
+		entries: make(map[string]*shardEntry[T]),
+	}
+	if data, err := os.ReadFile(idx.file); err == nil {
+		if err := json.Unmarshal(data, &idx.entries); err != nil {
+			log.Debug().Err(err).Msgf("ignore broken shard index %s", idx.file)
+			idx.entries = make(map[string]*shardEntry[T])
+		}
+	}
+	return idx
+}
+
+// Get 返回分片的缓存信息，分片在缓存之后被修改时返回 false
+func (idx *ShardIndex[T]) Get(path string) (T, bool) {
+	var zero T
+	fi, err := os.Stat(path)
+	if err != nil {
+		return zero, false
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	e, ok := idx.entries[idx.key(path)]
+	if !ok || e.Size != fi.Size() || !e.ModTime.Equal(fi.ModTime()) {
+		return zero, false
+	}
+	return e.Info, true
+}
+
+// Put 缓存分片的信息
+func (idx *ShardIndex[T]) Put(path string, info T) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.entries[idx.key(path)] = &shardEntry[T]{Size: fi.Size(), ModTime: fi.ModTime(), Info: info}
+	idx.dirty = true
+}
+
+// Save 删除 paths 以外的分片后写入缓存文件，缓存没有变化时不写入
+func (idx *ShardIndex[T]) Save(paths []string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	keep := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		keep[idx.key(path)] = true
+	}
+	for key := range idx.entries {
+		if !keep[key] {
+			delete(idx.entries, key)
+			idx.dirty = true
+		}
+	}
+	if !idx.dirty {
+		return nil
+	}
+	data, err := json.Marshal(idx.entries)
+	if err != nil {
+		return err
+	}
+	tmp := idx.file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, idx.file); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	idx.dirty = false
+	return nil
+}
+
+func (idx *ShardIndex[T]) key(path string) string {
+	if rel, err := filepath.Rel(idx.dir, path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(path)
+}
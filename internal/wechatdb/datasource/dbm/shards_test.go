@@ -0,0 +1,140 @@
+package dbm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShardIndex(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "message_0.db")
+	b := filepath.Join(dir, "message_1.db")
+	for _, p := range []string{a, b} {
+		if err := os.WriteFile(p, []byte("db"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Unix(1700000000, 0)
+
+	idx := LoadShardIndex[time.Time](dir, "message")
+	if _, ok := idx.Get(a); ok {
+		t.Fatal("empty index returned a shard")
+	}
+	idx.Put(a, start)
+	idx.Put(b, start.Add(time.Hour))
+	if err := idx.Save([]string{a, b}); err != nil {
+		t.Fatal(err)
+	}
+
+	idx = LoadShardIndex[time.Time](dir, "message")
+	if v, ok := idx.Get(a); !ok || !v.Equal(start) {
+		t.Fatalf("got %v, %v", v, ok)
+	}
+
+	// 重新解密后缓存失效
+	if err := os.WriteFile(b, []byte("decrypted again"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := idx.Get(b); ok {
+		t.Fatal("modified shard is still cached")
+	}
+
+	// 不存在的分片从缓存中删除
+	if err := idx.Save([]string{b}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := LoadShardIndex[time.Time](dir, "message").Get(a); ok {
+		t.Fatal("removed shard is still cached")
+	}
+}
+
+func TestOpenDBEvict(t *testing.T) {
+	defer SetOptions(Options{})
+	SetOptions(Options{MaxOpenDBs: 2})
+
+	dir := t.TempDir()
+	d := NewDBManager(dir)
+	defer d.Close()
+	paths := make([]string, 3)
+	for i := range paths {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("message_%d.db", i))
+	}
+
+	first, release, err := d.OpenDB(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if _, release, err := d.OpenDB(paths[1]); err != nil {
+		t.Fatal(err)
+	} else {
+		release()
+	}
+	// 使用 paths[0] 后 paths[1] 成为最久未使用的
+	if db, release, _ := d.OpenDB(paths[0]); db != first {
+		t.Fatal("open database was not reused")
+	} else {
+		release()
+	}
+	if _, release, err := d.OpenDB(paths[2]); err != nil {
+		t.Fatal(err)
+	} else {
+		release()
+	}
+
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	if len(d.dbs) != 2 || d.dbs[paths[0]] == nil || d.dbs[paths[1]] != nil {
+		t.Fatalf("open databases: %v", d.dbs)
+	}
+}
+
+func TestOpenDBLease(t *testing.T) {
+	defer SetOptions(Options{})
+	SetOptions(Options{MaxOpenDBs: 1})
+
+	a, b := createDB(t), createDB(t)
+	d := NewDBManager(filepath.Dir(a))
+	defer d.Close()
+
+	db, release, err := d.OpenDB(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := db.Query(`SELECT v FROM t`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 打开另一个数据库后 a 被淘汰，已取得的调用方仍然可以使用
+	_, releaseB, err := d.OpenDB(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer releaseB()
+	d.mutex.RLock()
+	_, open := d.dbs[a]
+	d.mutex.RUnlock()
+	if open {
+		t.Fatal("database was not evicted")
+	}
+	if !rows.Next() {
+		t.Fatalf("rows of the evicted database: %v", rows.Err())
+	}
+	rows.Close()
+	var v int
+	if err := db.QueryRow(`SELECT v FROM t`).Scan(&v); err != nil || v != 1 {
+		t.Fatalf("query on the evicted database: %d, %v", v, err)
+	}
+
+	// 最后一个调用方释放后关闭，重复释放没有影响
+	release()
+	release()
+	if err := db.Ping(); err == nil || !strings.Contains(err.Error(), "closed") {
+		t.Fatalf("evicted database not closed after release: %v", err)
+	}
+}
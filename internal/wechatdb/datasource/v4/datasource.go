@@ -70,6 +70,8 @@ type DataSource struct {
 
 	// 消息数据库信息
 	messageInfos []MessageDBInfo
+	// shards 缓存每个消息数据库的开始时间，启动时不必打开所有消息数据库
+	shards *dbm.ShardIndex[time.Time]
 }
 
 func New(path string) (*DataSource, error) {
@@ -78,6 +80,7 @@ func New(path string) (*DataSource, error) {
 		path:         path,
 		dbm:          dbm.NewDBManager(path),
 		messageInfos: make([]MessageDBInfo, 0),
+		shards:       dbm.LoadShardIndex[time.Time](path, Message),
 	}
 
 	for _, g := range Groups {
@@ -122,25 +125,16 @@ func (ds *DataSource) initMessageDbs() error {
 		return err
 	}
 
-	// 处理每个数据库文件
+	// 处理每个数据库文件，只打开缓存中没有或已经变化的数据库
 	infos := make([]MessageDBInfo, 0)
 	for _, filePath := range dbPaths {
-		db, err := ds.dbm.OpenDB(filePath)
-		if err != nil {
-			log.Err(err).Msgf("获取数据库 %s 失败", filePath)
-			continue
-		}
-
-		// 获取 Timestamp 表中的开始时间
-		var startTime time.Time
-		var timestamp int64
-
-		row := db.QueryRow("SELECT timestamp FROM Timestamp LIMIT 1")
-		if err := row.Scan(&timestamp); err != nil {
-			log.Err(err).Msgf("获取数据库 %s 的时间戳失败", filePath)
-			continue
+		startTime, ok := ds.shards.Get(filePath)
+		if !ok {
+			if startTime, err = ds.messageStartTime(filePath); err != nil {
+				continue
+			}
+			ds.shards.Put(filePath, startTime)
 		}
-		startTime = time.Unix(timestamp, 0)
 
 		// 保存数据库信息
 		infos = append(infos, MessageDBInfo{
@@ -148,6 +142,9 @@ func (ds *DataSource) initMessageDbs() error {
 			StartTime: startTime,
 		})
 	}
+	if err := ds.shards.Save(dbPaths); err != nil {
+		log.Debug().Err(err).Msg("failed to save message shard index")
+	}
 
 	// 按照 StartTime 排序数据库文件
 	sort.Slice(infos, func(i, j int) bool {
@@ -170,6 +167,23 @@ func (ds *DataSource) initMessageDbs() error {
 	return nil
 }
 
+// messageStartTime 获取消息数据库 Timestamp 表中的开始时间
+func (ds *DataSource) messageStartTime(filePath string) (time.Time, error) {
+	db, release, err := ds.dbm.OpenDB(filePath)
+	if err != nil {
+		log.Err(err).Msgf("获取数据库 %s 失败", filePath)
+		return time.Time{}, err
+	}
+	defer release()
+	var timestamp int64
+	row := db.QueryRow("SELECT timestamp FROM Timestamp LIMIT 1")
+	if err := row.Scan(&timestamp); err != nil {
+		log.Err(err).Msgf("获取数据库 %s 的时间戳失败", filePath)
+		return time.Time{}, err
+	}
+	return time.Unix(timestamp, 0), nil
+}
+
 // getDBInfosForTimeRange 获取时间范围内的数据库信息
 func (ds *DataSource) getDBInfosForTimeRange(startTime, endTime time.Time) []MessageDBInfo {
 	var dbs []MessageDBInfo
@@ -220,11 +234,12 @@ func (ds *DataSource) GetMessages(ctx context.Context, startTime, endTime time.T
 			return nil, err
 		}
 
-		db, err := ds.dbm.OpenDB(dbInfo.FilePath)
+		db, release, err := ds.dbm.OpenDB(dbInfo.FilePath)
 		if err != nil {
 			log.Error().Msgf("数据库 %s 未打开", dbInfo.FilePath)
 			continue
 		}
+		defer release()
 
 		// 对每个talker进行查询
 		for _, talkerItem := range talkers {
@@ -363,6 +378,61 @@ func (ds *DataSource) GetMessages(ctx context.Context, startTime, endTime time.T
 	return filteredMessages, nil
 }
 
+// CountMessages 在 SQL 中按日期和消息类型聚合消息数量，local_type 低 32 位为消息类型，高 32 位为子类型
+func (ds *DataSource) CountMessages(ctx context.Context, startTime, endTime time.Time, talker string) ([]*model.MessageCount, error) {
+	talkers := util.Str2List(talker, ",")
+	if len(talkers) == 0 {
+		return nil, errors.ErrTalkerEmpty
+	}
+
+	ret := make([]*model.MessageCount, 0)
+	for _, dbInfo := range ds.getDBInfosForTimeRange(startTime, endTime) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		db, release, err := ds.dbm.OpenDB(dbInfo.FilePath)
+		if err != nil {
+			log.Error().Msgf("数据库 %s 未打开", dbInfo.FilePath)
+			continue
+		}
+		defer release()
+
+		for _, talkerItem := range talkers {
+			_talkerMd5Bytes := md5.Sum([]byte(talkerItem))
+			tableName := "Msg_" + hex.EncodeToString(_talkerMd5Bytes[:])
+
+			query := fmt.Sprintf(`
+				SELECT strftime('%%Y-%%m-%%d', create_time, 'unixepoch', 'localtime') AS day,
+					local_type & 4294967295 AS type, local_type >> 32 AS sub_type, COUNT(*)
+				FROM %s
+				WHERE create_time >= ? AND create_time <= ?
+				GROUP BY day, type, sub_type
+			`, tableName)
+
+			rows, err := db.QueryContext(ctx, query, startTime.Unix(), endTime.Unix())
+			if err != nil {
+				if strings.Contains(err.Error(), "no such table") {
+					continue
+				}
+				log.Err(err).Msgf("从数据库 %s 统计消息失败", dbInfo.FilePath)
+				continue
+			}
+			for rows.Next() {
+				c := &model.MessageCount{Talker: talkerItem}
+				if err := rows.Scan(&c.Day, &c.Type, &c.SubType, &c.Count); err != nil {
+					rows.Close()
+					return nil, errors.ScanRowFailed(err)
+				}
+				ret = append(ret, c)
+			}
+			rows.Close()
+		}
+	}
+
+	return ret, nil
+}
+
 // 联系人
 func (ds *DataSource) GetContacts(ctx context.Context, key string, limit, offset int) ([]*model.Contact, error) {
 	var query string
@@ -389,10 +459,11 @@ func (ds *DataSource) GetContacts(ctx context.Context, key string, limit, offset
 	}
 
 	// 执行查询
-	db, err := ds.dbm.GetDB(Contact)
+	db, release, err := ds.dbm.GetDB(Contact)
 	if err != nil {
 		return nil, err
 	}
+	defer release()
 	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, errors.QueryFailed(query, err)
@@ -426,10 +497,11 @@ func (ds *DataSource) GetChatRooms(ctx context.Context, key string, limit, offse
 	var args []interface{}
 
 	// 执行查询
-	db, err := ds.dbm.GetDB(Contact)
+	db, release, err := ds.dbm.GetDB(Contact)
 	if err != nil {
 		return nil, err
 	}
+	defer release()
 
 	if key != "" {
 		// 按照关键字查询
@@ -567,10 +639,11 @@ func (ds *DataSource) GetSessions(ctx context.Context, key string, limit, offset
 	}
 
 	// 执行查询
-	db, err := ds.dbm.GetDB(Session)
+	db, release, err := ds.dbm.GetDB(Session)
 	if err != nil {
 		return nil, err
 	}
+	defer release()
 	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, errors.QueryFailed(query, err)
@@ -646,10 +719,11 @@ func (ds *DataSource) GetMedia(ctx context.Context, _type string, key string) (*
 	args := []interface{}{key, key}
 
 	// 执行查询
-	db, err := ds.dbm.GetDB(Media)
+	db, release, err := ds.dbm.GetDB(Media)
 	if err != nil {
 		return nil, err
 	}
+	defer release()
 	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, errors.QueryFailed(query, err)
@@ -687,10 +761,11 @@ func (ds *DataSource) GetMedia(ctx context.Context, _type string, key string) (*
 }
 
 func (ds *DataSource) IsExist(_db string, table string) bool {
-	db, err := ds.dbm.GetDB(_db)
+	db, release, err := ds.dbm.GetDB(_db)
 	if err != nil {
 		return false
 	}
+	defer release()
 	var tableName string
 	query := "SELECT name FROM sqlite_master WHERE type='table' AND name=?;"
 	if err = db.QueryRow(query, table).Scan(&tableName); err != nil {
@@ -714,10 +789,11 @@ func (ds *DataSource) GetVoice(ctx context.Context, key string) (*model.Media, e
 	`
 	args := []interface{}{key}
 
-	dbs, err := ds.dbm.GetDBs(Voice)
+	dbs, release, err := ds.dbm.GetDBs(Voice)
 	if err != nil {
 		return nil, errors.DBConnectFailed("", err)
 	}
+	defer release()
 
 	for _, db := range dbs {
 		rows, err := db.QueryContext(ctx, query, args...)
@@ -0,0 +1,124 @@
+package v4
+
+import (
+	"context"
+	"crypto/md5"
+	"database/sql"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/model"
+)
+
+// newMessageDB 创建只包含统计所需表的 4.0 消息数据库，msgs 的键为会话名称
+func newMessageDB(t *testing.T, path string, start time.Time, msgs map[string][][2]int64) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	exec := func(query string, args ...any) {
+		t.Helper()
+		if _, err := db.Exec(query, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exec("CREATE TABLE Timestamp (timestamp INTEGER)")
+	exec("INSERT INTO Timestamp VALUES (?)", start.Unix())
+	exec("CREATE TABLE Name2Id (user_name TEXT PRIMARY KEY)")
+
+	seq := int64(0)
+	for talker, list := range msgs {
+		exec("INSERT OR IGNORE INTO Name2Id (user_name) VALUES (?)", talker)
+		sum := md5.Sum([]byte(talker))
+		table := "Msg_" + hex.EncodeToString(sum[:])
+		exec(`CREATE TABLE ` + table + ` (local_id INTEGER PRIMARY KEY AUTOINCREMENT, server_id INTEGER, local_type INTEGER,
+			sort_seq INTEGER, real_sender_id INTEGER, create_time INTEGER, status INTEGER,
+			message_content BLOB, packed_info_data BLOB)`)
+		for _, m := range list {
+			seq++
+			exec("INSERT INTO "+table+" (server_id, local_type, sort_seq, real_sender_id, create_time, status, message_content) VALUES (?, ?, ?, 1, ?, 0, '')",
+				seq, m[0], seq, m[1])
+		}
+	}
+}
+
+func TestCountMessages(t *testing.T) {
+	dir := t.TempDir()
+	day := func(month time.Month, d int) int64 {
+		return time.Date(2024, month, d, 12, 0, 0, 0, time.Local).Unix()
+	}
+	const file = 6<<32 | model.MessageTypeShare
+
+	newMessageDB(t, filepath.Join(dir, "db_storage/message/message_0.db"), time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local), map[string][][2]int64{
+		"wxid_a": {
+			{model.MessageTypeText, day(1, 1)},
+			{model.MessageTypeText, day(1, 1)},
+			{model.MessageTypeImage, day(2, 1)},
+			{file, day(2, 1)},
+			{model.MessageTypeText, day(5, 1)}, // 不在统计范围内
+		},
+		"123@chatroom": {
+			{model.MessageTypeVoice, day(1, 15)},
+		},
+	})
+
+	ds, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ds.Close()
+
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	end := time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)
+	counts, err := ds.CountMessages(ctx, start, end, "wxid_a,123@chatroom,wxid_missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats := model.NewStats()
+	byTalker := make(map[string]int)
+	for _, c := range counts {
+		stats.AddCount(c)
+		byTalker[c.Talker] += c.Count
+	}
+	if stats.Total != 5 {
+		t.Errorf("Total = %d, want 5", stats.Total)
+	}
+	if stats.ByMonth["2024-01"] != 3 || stats.ByMonth["2024-02"] != 2 || stats.ByYear["2024"] != 5 {
+		t.Errorf("unexpected ByMonth %v ByYear %v", stats.ByMonth, stats.ByYear)
+	}
+	if stats.Media["image"] != 1 || stats.Media["file"] != 1 || stats.Media["voice"] != 1 {
+		t.Errorf("unexpected Media %v", stats.Media)
+	}
+	if byTalker["wxid_a"] != 4 || byTalker["123@chatroom"] != 1 || byTalker["wxid_missing"] != 0 {
+		t.Errorf("unexpected per talker counts %v", byTalker)
+	}
+
+	// 与逐条读取消息的结果一致
+	messages, err := ds.GetMessages(ctx, start, end, "wxid_a", "", "", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != byTalker["wxid_a"] {
+		t.Errorf("GetMessages returned %d messages, CountMessages %d", len(messages), byTalker["wxid_a"])
+	}
+	media := 0
+	for _, m := range messages {
+		if m.MediaType() != "" {
+			media++
+		}
+	}
+	if media != stats.Media["image"]+stats.Media["file"] {
+		t.Errorf("GetMessages found %d media messages, CountMessages %v", media, stats.Media)
+	}
+}
@@ -69,6 +69,12 @@ type MessageDBInfo struct {
 	TalkerMap map[string]int
 }
 
+// shardInfo 缓存的消息数据库信息
+type shardInfo struct {
+	StartTime time.Time      `json:"startTime"`
+	TalkerMap map[string]int `json:"talkerMap"`
+}
+
 // DataSource 实现了 DataSource 接口
 type DataSource struct {
 	path string
@@ -76,6 +82,8 @@ type DataSource struct {
 
 	// 消息数据库信息
 	messageInfos []MessageDBInfo
+	// shards 缓存每个消息数据库的开始时间与聊天对象，启动时不必打开所有消息数据库
+	shards *dbm.ShardIndex[shardInfo]
 }
 
 // New 创建一个新的 WindowsV3DataSource
@@ -84,6 +92,7 @@ func New(path string) (*DataSource, error) {
 		path:         path,
 		dbm:          dbm.NewDBManager(path),
 		messageInfos: make([]MessageDBInfo, 0),
+		shards:       dbm.LoadShardIndex[shardInfo](path, Message),
 	}
 
 	for _, g := range Groups {
@@ -133,66 +142,24 @@ func (ds *DataSource) initMessageDbs() error {
 	// 处理每个数据库文件
 	infos := make([]MessageDBInfo, 0)
 	for _, filePath := range dbPaths {
-		db, err := ds.dbm.OpenDB(filePath)
-		if err != nil {
-			log.Err(err).Msgf("获取数据库 %s 失败", filePath)
-			continue
-		}
-
-		// 获取 DBInfo 表中的开始时间
-		var startTime time.Time
-
-		rows, err := db.Query("SELECT tableIndex, tableVersion, tableDesc FROM DBInfo")
-		if err != nil {
-			log.Err(err).Msgf("查询数据库 %s 的 DBInfo 表失败", filePath)
-			continue
-		}
-
-		for rows.Next() {
-			var tableIndex int
-			var tableVersion int64
-			var tableDesc string
-
-			if err := rows.Scan(&tableIndex, &tableVersion, &tableDesc); err != nil {
-				log.Err(err).Msg("扫描 DBInfo 行失败")
-				continue
-			}
-
-			// 查找描述为 "Start Time" 的记录
-			if strings.Contains(tableDesc, "Start Time") {
-				startTime = time.Unix(tableVersion/1000, (tableVersion%1000)*1000000)
-				break
-			}
-		}
-		rows.Close()
-
-		// 组织 TalkerMap
-		talkerMap := make(map[string]int)
-		rows, err = db.Query("SELECT UsrName FROM Name2ID")
-		if err != nil {
-			log.Err(err).Msgf("查询数据库 %s 的 Name2ID 表失败", filePath)
-			continue
-		}
-
-		i := 1
-		for rows.Next() {
-			var userName string
-			if err := rows.Scan(&userName); err != nil {
-				log.Err(err).Msg("扫描 Name2ID 行失败")
+		shard, ok := ds.shards.Get(filePath)
+		if !ok {
+			if shard, err = ds.messageShard(filePath); err != nil {
 				continue
 			}
-			talkerMap[userName] = i
-			i++
+			ds.shards.Put(filePath, shard)
 		}
-		rows.Close()
 
 		// 保存数据库信息
 		infos = append(infos, MessageDBInfo{
 			FilePath:  filePath,
-			StartTime: startTime,
-			TalkerMap: talkerMap,
+			StartTime: shard.StartTime,
+			TalkerMap: shard.TalkerMap,
 		})
 	}
+	if err := ds.shards.Save(dbPaths); err != nil {
+		log.Debug().Err(err).Msg("failed to save message shard index")
+	}
 
 	// 按照 StartTime 排序数据库文件
 	sort.Slice(infos, func(i, j int) bool {
@@ -215,6 +182,65 @@ func (ds *DataSource) initMessageDbs() error {
 	return nil
 }
 
+// messageShard 读取消息数据库的开始时间与聊天对象
+func (ds *DataSource) messageShard(filePath string) (shardInfo, error) {
+	db, release, err := ds.dbm.OpenDB(filePath)
+	if err != nil {
+		log.Err(err).Msgf("获取数据库 %s 失败", filePath)
+		return shardInfo{}, err
+	}
+	defer release()
+
+	// 获取 DBInfo 表中的开始时间
+	var startTime time.Time
+
+	rows, err := db.Query("SELECT tableIndex, tableVersion, tableDesc FROM DBInfo")
+	if err != nil {
+		log.Err(err).Msgf("查询数据库 %s 的 DBInfo 表失败", filePath)
+		return shardInfo{}, err
+	}
+
+	for rows.Next() {
+		var tableIndex int
+		var tableVersion int64
+		var tableDesc string
+
+		if err := rows.Scan(&tableIndex, &tableVersion, &tableDesc); err != nil {
+			log.Err(err).Msg("扫描 DBInfo 行失败")
+			continue
+		}
+
+		// 查找描述为 "Start Time" 的记录
+		if strings.Contains(tableDesc, "Start Time") {
+			startTime = time.Unix(tableVersion/1000, (tableVersion%1000)*1000000)
+			break
+		}
+	}
+	rows.Close()
+
+	// 组织 TalkerMap
+	talkerMap := make(map[string]int)
+	rows, err = db.Query("SELECT UsrName FROM Name2ID")
+	if err != nil {
+		log.Err(err).Msgf("查询数据库 %s 的 Name2ID 表失败", filePath)
+		return shardInfo{}, err
+	}
+
+	i := 1
+	for rows.Next() {
+		var userName string
+		if err := rows.Scan(&userName); err != nil {
+			log.Err(err).Msg("扫描 Name2ID 行失败")
+			continue
+		}
+		talkerMap[userName] = i
+		i++
+	}
+	rows.Close()
+
+	return shardInfo{StartTime: startTime, TalkerMap: talkerMap}, nil
+}
+
 // getDBInfosForTimeRange 获取时间范围内的数据库信息
 func (ds *DataSource) getDBInfosForTimeRange(startTime, endTime time.Time) []MessageDBInfo {
 	var dbs []MessageDBInfo
@@ -265,11 +291,12 @@ func (ds *DataSource) GetMessages(ctx context.Context, startTime, endTime time.T
 			return nil, err
 		}
 
-		db, err := ds.dbm.OpenDB(dbInfo.FilePath)
+		db, release, err := ds.dbm.OpenDB(dbInfo.FilePath)
 		if err != nil {
 			log.Error().Msgf("数据库 %s 未打开", dbInfo.FilePath)
 			continue
 		}
+		defer release()
 
 		// 对每个talker进行查询
 		for _, talkerItem := range talkers {
@@ -404,6 +431,64 @@ func (ds *DataSource) GetMessages(ctx context.Context, startTime, endTime time.T
 	return filteredMessages, nil
 }
 
+// CountMessages 在 SQL 中按日期和消息类型聚合消息数量
+func (ds *DataSource) CountMessages(ctx context.Context, startTime, endTime time.Time, talker string) ([]*model.MessageCount, error) {
+	talkers := util.Str2List(talker, ",")
+	if len(talkers) == 0 {
+		return nil, errors.ErrTalkerEmpty
+	}
+
+	ret := make([]*model.MessageCount, 0)
+	for _, dbInfo := range ds.getDBInfosForTimeRange(startTime, endTime) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		db, release, err := ds.dbm.OpenDB(dbInfo.FilePath)
+		if err != nil {
+			log.Error().Msgf("数据库 %s 未打开", dbInfo.FilePath)
+			continue
+		}
+		defer release()
+
+		for _, talkerItem := range talkers {
+			conditions := []string{"Sequence >= ? AND Sequence <= ?"}
+			args := []interface{}{startTime.Unix() * 1000, endTime.Unix() * 1000}
+			if talkerID, ok := dbInfo.TalkerMap[talkerItem]; ok {
+				conditions = append(conditions, "TalkerId = ?")
+				args = append(args, talkerID)
+			} else {
+				conditions = append(conditions, "StrTalker = ?")
+				args = append(args, talkerItem)
+			}
+
+			query := fmt.Sprintf(`
+				SELECT strftime('%%Y-%%m-%%d', CreateTime, 'unixepoch', 'localtime') AS day, Type, SubType, COUNT(*)
+				FROM MSG
+				WHERE %s
+				GROUP BY day, Type, SubType
+			`, strings.Join(conditions, " AND "))
+
+			rows, err := db.QueryContext(ctx, query, args...)
+			if err != nil {
+				log.Err(err).Msgf("从数据库 %s 统计消息失败", dbInfo.FilePath)
+				continue
+			}
+			for rows.Next() {
+				c := &model.MessageCount{Talker: talkerItem}
+				if err := rows.Scan(&c.Day, &c.Type, &c.SubType, &c.Count); err != nil {
+					rows.Close()
+					return nil, errors.ScanRowFailed(err)
+				}
+				ret = append(ret, c)
+			}
+			rows.Close()
+		}
+	}
+
+	return ret, nil
+}
+
 // GetContacts 实现获取联系人信息的方法
 func (ds *DataSource) GetContacts(ctx context.Context, key string, limit, offset int) ([]*model.Contact, error) {
 	var query string
@@ -429,10 +514,11 @@ func (ds *DataSource) GetContacts(ctx context.Context, key string, limit, offset
 	}
 
 	// 执行查询
-	db, err := ds.dbm.GetDB(Contact)
+	db, release, err := ds.dbm.GetDB(Contact)
 	if err != nil {
 		return nil, err
 	}
+	defer release()
 	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, errors.QueryFailed(query, err)
@@ -471,10 +557,11 @@ func (ds *DataSource) GetChatRooms(ctx context.Context, key string, limit, offse
 		args = []interface{}{key}
 
 		// 执行查询
-		db, err := ds.dbm.GetDB(Contact)
+		db, release, err := ds.dbm.GetDB(Contact)
 		if err != nil {
 			return nil, err
 		}
+		defer release()
 		rows, err := db.QueryContext(ctx, query, args...)
 		if err != nil {
 			return nil, errors.QueryFailed(query, err)
@@ -552,10 +639,11 @@ func (ds *DataSource) GetChatRooms(ctx context.Context, key string, limit, offse
 		}
 
 		// 执行查询
-		db, err := ds.dbm.GetDB(Contact)
+		db, release, err := ds.dbm.GetDB(Contact)
 		if err != nil {
 			return nil, err
 		}
+		defer release()
 		rows, err := db.QueryContext(ctx, query, args...)
 		if err != nil {
 			return nil, errors.QueryFailed(query, err)
@@ -610,10 +698,11 @@ func (ds *DataSource) GetSessions(ctx context.Context, key string, limit, offset
 	}
 
 	// 执行查询
-	db, err := ds.dbm.GetDB(Contact)
+	db, release, err := ds.dbm.GetDB(Contact)
 	if err != nil {
 		return nil, err
 	}
+	defer release()
 	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, errors.QueryFailed(query, err)
@@ -675,10 +764,11 @@ func (ds *DataSource) GetMedia(ctx context.Context, _type string, key string) (*
 		return nil, errors.MediaTypeUnsupported(_type)
 	}
 
-	db, err := ds.dbm.GetDB(dbType)
+	db, release, err := ds.dbm.GetDB(dbType)
 	if err != nil {
 		return nil, err
 	}
+	defer release()
 
 	query := fmt.Sprintf(`
         SELECT 
@@ -739,10 +829,11 @@ func (ds *DataSource) GetVoice(ctx context.Context, key string) (*model.Media, e
 	`
 	args := []interface{}{key}
 
-	dbs, err := ds.dbm.GetDBs(Voice)
+	dbs, release, err := ds.dbm.GetDBs(Voice)
 	if err != nil {
 		return nil, errors.DBConnectFailed("", err)
 	}
+	defer release()
 
 	for _, db := range dbs {
 		rows, err := db.QueryContext(ctx, query, args...)
@@ -0,0 +1,90 @@
+package wechatdb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/model"
+)
+
+// Feed 增量消息流，每次调用 Next 返回自上次调用以来的新消息
+type Feed struct {
+	db     *DB
+	talker string
+
+	mu       sync.Mutex
+	lastTime time.Time
+	// 与 lastTime 同一秒内已返回的消息，避免边界上的消息重复或丢失
+	seen map[string]bool
+}
+
+// NewFeed 创建增量消息流，talker 为空时订阅所有会话
+func (w *DB) NewFeed(talker string, since time.Time) *Feed {
+	return &Feed{
+		db:       w,
+		talker:   talker,
+		lastTime: since.Truncate(time.Second),
+		seen:     make(map[string]bool),
+	}
+}
+
+// Next 返回自上次调用以来的新消息，按时间排序
+func (f *Feed) Next(ctx context.Context) ([]*model.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	talker := f.talker
+	if talker == "" {
+		talkers, err := f.activeTalkers(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(talkers) == 0 {
+			return nil, nil
+		}
+		talker = strings.Join(talkers, ",")
+	}
+
+	messages, err := f.db.GetMessages(ctx, f.lastTime, time.Now().Add(time.Minute*10), talker, "", "", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*model.Message, 0, len(messages))
+	for _, msg := range messages {
+		if msg.Time.Before(f.lastTime) {
+			continue
+		}
+		key := fmt.Sprintf("%s:%d", msg.Talker, msg.Seq)
+		if f.seen[key] {
+			continue
+		}
+		if sec := msg.Time.Truncate(time.Second); sec.After(f.lastTime) {
+			f.lastTime = sec
+			f.seen = make(map[string]bool)
+		}
+		f.seen[key] = true
+		ret = append(ret, msg)
+	}
+
+	return ret, nil
+}
+
+// activeTalkers 返回最近会话时间不早于 lastTime 的会话
+func (f *Feed) activeTalkers(ctx context.Context) ([]string, error) {
+	resp, err := f.db.GetSessions(ctx, "", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	talkers := make([]string, 0)
+	for _, session := range resp.Items {
+		if session.NTime.Before(f.lastTime) {
+			continue
+		}
+		talkers = append(talkers, session.UserName)
+	}
+	return talkers, nil
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/DanielMao1/chatlog/internal/model"
+)
+
+// GetStats 统计时间范围内所有会话的消息，计数由数据源在 SQL 中聚合，不读取消息内容
+func (r *Repository) GetStats(ctx context.Context, startTime, endTime time.Time, top int) (*model.Stats, error) {
+	sessions, err := r.ds.GetSessions(ctx, "", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := model.NewStats()
+	if len(sessions) == 0 {
+		return stats, nil
+	}
+
+	talkers := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		talkers = append(talkers, session.UserName)
+	}
+	counts, err := r.ds.CountMessages(ctx, startTime, endTime, strings.Join(talkers, ","))
+	if err != nil {
+		return nil, err
+	}
+
+	byTalker := make(map[string]int)
+	for _, c := range counts {
+		stats.AddCount(c)
+		byTalker[c.Talker] += c.Count
+	}
+
+	contacts := make([]*model.StatsItem, 0)
+	rooms := make([]*model.StatsItem, 0)
+	for _, session := range sessions {
+		count := byTalker[session.UserName]
+		if count == 0 {
+			continue
+		}
+		stats.Sessions++
+
+		item := &model.StatsItem{
+			UserName: session.UserName,
+			Name:     r.displayName(session),
+			Count:    count,
+		}
+		if strings.HasSuffix(session.UserName, "@chatroom") {
+			rooms = append(rooms, item)
+		} else {
+			contacts = append(contacts, item)
+		}
+	}
+
+	stats.TopContact = model.SortStatsItems(contacts, top)
+	stats.TopRoom = model.SortStatsItems(rooms, top)
+
+	return stats, nil
+}
+
+// CountMessages 按会话、日期和消息类型统计时间范围内的消息数量，talker 为空时统计所有会话
+func (r *Repository) CountMessages(ctx context.Context, startTime, endTime time.Time, talker string) ([]*model.MessageCount, error) {
+	if len(talker) == 0 {
+		sessions, err := r.ds.GetSessions(ctx, "", 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(sessions) == 0 {
+			return []*model.MessageCount{}, nil
+		}
+		talkers := make([]string, 0, len(sessions))
+		for _, session := range sessions {
+			talkers = append(talkers, session.UserName)
+		}
+		talker = strings.Join(talkers, ",")
+	}
+	return r.ds.CountMessages(ctx, startTime, endTime, talker)
+}
+
+// displayName 获取会话的显示名称，优先使用备注
+func (r *Repository) displayName(session *model.Session) string {
+	if chatRoom, ok := r.chatRoomCache[session.UserName]; ok {
+		if name := chatRoom.DisplayName(); name != "" {
+			return name
+		}
+	}
+	if contact := r.getFullContact(session.UserName); contact != nil {
+		if name := contact.DisplayName(); name != "" {
+			return name
+		}
+	}
+	return session.NickName
+}
@@ -58,9 +58,7 @@ func (w *DB) Initialize() error {
 	return nil
 }
 
-func (w *DB) GetMessages(start, end time.Time, talker string, sender string, keyword string, limit, offset int) ([]*model.Message, error) {
-	ctx := context.Background()
-
+func (w *DB) GetMessages(ctx context.Context, start, end time.Time, talker string, sender string, keyword string, limit, offset int) ([]*model.Message, error) {
 	// 使用 repository 获取消息
 	messages, err := w.repo.GetMessages(ctx, start, end, talker, sender, keyword, limit, offset)
 	if err != nil {
@@ -74,9 +72,7 @@ type GetContactsResp struct {
 	Items []*model.Contact `json:"items"`
 }
 
-func (w *DB) GetContacts(key string, limit, offset int) (*GetContactsResp, error) {
-	ctx := context.Background()
-
+func (w *DB) GetContacts(ctx context.Context, key string, limit, offset int) (*GetContactsResp, error) {
 	contacts, err := w.repo.GetContacts(ctx, key, limit, offset)
 	if err != nil {
 		return nil, err
@@ -91,9 +87,7 @@ type GetChatRoomsResp struct {
 	Items []*model.ChatRoom `json:"items"`
 }
 
-func (w *DB) GetChatRooms(key string, limit, offset int) (*GetChatRoomsResp, error) {
-	ctx := context.Background()
-
+func (w *DB) GetChatRooms(ctx context.Context, key string, limit, offset int) (*GetChatRoomsResp, error) {
 	chatRooms, err := w.repo.GetChatRooms(ctx, key, limit, offset)
 	if err != nil {
 		return nil, err
@@ -108,9 +102,7 @@ type GetSessionsResp struct {
 	Items []*model.Session `json:"items"`
 }
 
-func (w *DB) GetSessions(key string, limit, offset int) (*GetSessionsResp, error) {
-	ctx := context.Background()
-
+func (w *DB) GetSessions(ctx context.Context, key string, limit, offset int) (*GetSessionsResp, error) {
 	// 使用 repository 获取会话列表
 	sessions, err := w.repo.GetSessions(ctx, key, limit, offset)
 	if err != nil {
@@ -122,8 +114,16 @@ func (w *DB) GetSessions(key string, limit, offset int) (*GetSessionsResp, error
 	}, nil
 }
 
-func (w *DB) GetMedia(_type string, key string) (*model.Media, error) {
-	return w.repo.GetMedia(context.Background(), _type, key)
+func (w *DB) GetStats(ctx context.Context, start, end time.Time, top int) (*model.Stats, error) {
+	return w.repo.GetStats(ctx, start, end, top)
+}
+
+func (w *DB) CountMessages(ctx context.Context, start, end time.Time, talker string) ([]*model.MessageCount, error) {
+	return w.repo.CountMessages(ctx, start, end, talker)
+}
+
+func (w *DB) GetMedia(ctx context.Context, _type string, key string) (*model.Media, error) {
+	return w.repo.GetMedia(ctx, _type, key)
 }
 
 func (w *DB) SetCallback(group string, callback func(event fsnotify.Event) error) error {
@@ -1,12 +1,9 @@
 package main
 
 import (
-	"log"
-
 	"github.com/DanielMao1/chatlog/cmd/chatlog"
 )
 
 func main() {
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
 	chatlog.Execute()
 }
@@ -0,0 +1,119 @@
+// Package bloom provides a fixed-memory set for remembering values already seen, such as candidate keys
+// checked while scanning process memory, where a map of every value would grow with the memory scanned
+package bloom
+
+import (
+	"hash/maphash"
+	"math"
+	"sync"
+)
+
+const (
+	// Shards is the number of independently locked shards
+	Shards = 64
+
+	// blockBits is the size of a block, all bits of a value are set in one block so a lookup touches one cache line
+	blockBits  = 512
+	blockWords = blockBits / 64
+)
+
+// Filter is a sharded, blocked bloom filter safe for concurrent use
+// Each shard holds at most capacity/Shards values and is cleared when full, so the false positive rate
+// never exceeds the rate the filter was sized for; values forgotten on clearing are only checked again
+type Filter struct {
+	seed   maphash.Seed
+	shards [Shards]shard
+	blocks int // blocks per shard
+	k      int // bits set per value
+	limit  int // values per shard before it is cleared
+}
+
+type shard struct {
+	mu    sync.Mutex
+	words []uint64
+	n     int
+}
+
+// New creates a filter for capacity values with false positive rate p, shards are allocated on first use
+func New(capacity int, p float64) *Filter {
+	limit := max(capacity/Shards, 1)
+	p = min(max(p, 1e-12), 0.5)
+	// m = -n ln p / (ln 2)^2, k = m/n ln 2; blocking raises the rate slightly, so give it 10% more bits
+	bits := math.Ceil(-float64(limit) * math.Log(p) / (math.Ln2 * math.Ln2) * 1.1)
+	k := int(math.Round(bits / float64(limit) * math.Ln2))
+	return &Filter{
+		seed:   maphash.MakeSeed(),
+		blocks: max(int(math.Ceil(bits/blockBits)), 1),
+		k:      min(max(k, 1), 32),
+		limit:  limit,
+	}
+}
+
+// Bytes returns the memory allocated by the filter when all shards are in use
+func (f *Filter) Bytes() int {
+	return Shards * f.blocks * blockWords * 8
+}
+
+// TestAndAdd adds a value and reports whether it was probably added before
+func (f *Filter) TestAndAdd(v []byte) bool {
+	h := maphash.Bytes(f.seed, v)
+	s := &f.shards[h%Shards]
+	block := int((h>>32)*uint64(f.blocks)>>32) * blockWords
+
+	// Each bit takes 9 fresh bits of a remixed hash, deriving them from two values as in double hashing
+	// repeats the same few patterns within a block and raises the false positive rate
+	var mask [blockWords]uint64
+	g, left := mix(h), 64
+	for i := 0; i < f.k; i++ {
+		if left < 9 {
+			g, left = mix(g+uint64(i)), 64
+		}
+		bit := g % blockBits
+		g, left = g>>9, left-9
+		mask[bit/64] |= 1 << (bit % 64)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.words == nil {
+		s.words = make([]uint64, f.blocks*blockWords)
+	}
+	words := s.words[block : block+blockWords]
+	seen := true
+	for i, m := range mask {
+		if words[i]&m != m {
+			seen = false
+		}
+	}
+	if seen {
+		return true
+	}
+	if s.n++; s.n > f.limit {
+		clear(s.words)
+		s.n = 1
+	}
+	for i, m := range mask {
+		words[i] |= m
+	}
+	return false
+}
+
+// Reset forgets all values and releases the memory of the shards
+func (f *Filter) Reset() {
+	for i := range f.shards {
+		s := &f.shards[i]
+		s.mu.Lock()
+		s.words, s.n = nil, 0
+		s.mu.Unlock()
+	}
+}
+
+// mix is the splitmix64 finalizer
+func mix(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
@@ -0,0 +1,72 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"sync/atomic"
+	"testing"
+)
+
+func key(i uint64) []byte {
+	b := make([]byte, 32)
+	binary.LittleEndian.PutUint64(b, i)
+	binary.LittleEndian.PutUint64(b[24:], ^i)
+	return b
+}
+
+func TestFalsePositiveRate(t *testing.T) {
+	const capacity, p = 1 << 18, 1e-4
+	// Values are not spread evenly over shards, leave some room so no shard is cleared
+	const n = capacity * 7 / 8
+	f := New(capacity, p)
+	for i := uint64(0); i < n; i++ {
+		f.TestAndAdd(key(i))
+	}
+	for i := uint64(0); i < n; i++ {
+		if !f.TestAndAdd(key(i)) {
+			t.Fatalf("value %d added but not seen", i)
+		}
+	}
+
+	// Values never added, near capacity the measured rate stays within twice the target
+	const trials = 1 << 20
+	var fp int
+	for i := uint64(0); i < trials; i++ {
+		if f.TestAndAdd(key(1<<40 + i)) {
+			fp++
+		}
+	}
+	rate := float64(fp) / trials
+	t.Logf("false positive rate %.2e with %d bytes", rate, f.Bytes())
+	if rate > 2*p {
+		t.Fatalf("false positive rate %.2e exceeds %.2e", rate, 2*p)
+	}
+}
+
+func TestClearWhenFull(t *testing.T) {
+	f := New(Shards*16, 1e-3)
+	for i := uint64(0); i < 100000; i++ {
+		f.TestAndAdd(key(i))
+	}
+	for i := range f.shards {
+		if f.shards[i].n > f.limit {
+			t.Fatalf("shard %d holds %d values, limit %d", i, f.shards[i].n, f.limit)
+		}
+	}
+	if f.TestAndAdd(key(1 << 50)) {
+		t.Fatal("new value reported as seen after clearing")
+	}
+}
+
+// BenchmarkTestAndAddParallel measures contention on the shard locks with all CPUs adding values
+func BenchmarkTestAndAddParallel(b *testing.B) {
+	f := New(1<<22, 1e-6)
+	var next atomic.Uint64
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		i := next.Add(1) << 40
+		for pb.Next() {
+			f.TestAndAdd(key(i))
+			i++
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package daemon
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/DanielMao1/chatlog/pkg/util"
+)
+
+var ErrNotSupported = errors.New("service is not supported on this platform")
+
+// Config 系统服务配置
+type Config struct {
+	Name        string   // 服务名称
+	DisplayName string   // 显示名称
+	Description string   // 服务描述
+	Exec        string   // 可执行文件路径
+	Args        []string // 启动参数
+	LogFile     string   // 日志文件路径
+}
+
+// Start 以脱离终端的后台进程重新运行当前程序，返回子进程 pid
+// 子进程的 stderr 写入 logFile，程序自身的日志应通过 args 指定写入同一文件
+func Start(args []string, logFile string) (int, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := util.PrepareDir(filepath.Dir(logFile)); err != nil {
+		return 0, err
+	}
+	f, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	cmd := exec.Command(exe, args...)
+	cmd.Stdout = f
+	cmd.Stderr = f
+	cmd.SysProcAttr = sysProcAttr()
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+	pid := cmd.Process.Pid
+	if err := cmd.Process.Release(); err != nil {
+		return 0, err
+	}
+	return pid, nil
+}
@@ -0,0 +1,14 @@
+//go:build windows
+
+package daemon
+
+import "syscall"
+
+const detachedProcess = 0x00000008
+
+func sysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{
+		CreationFlags: detachedProcess | syscall.CREATE_NEW_PROCESS_GROUP,
+		HideWindow:    true,
+	}
+}
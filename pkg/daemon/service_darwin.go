@@ -0,0 +1,95 @@
+//go:build darwin
+
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"text/template"
+)
+
+// 使用 LaunchAgent 而不是 LaunchDaemon，服务需要以当前用户身份访问微信数据目录
+var plistTemplate = template.Must(template.New("plist").Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>{{.Name}}</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>{{.Exec}}</string>
+{{- range .Args}}
+		<string>{{.}}</string>
+{{- end}}
+	</array>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<dict>
+		<key>SuccessfulExit</key>
+		<false/>
+	</dict>
+	<key>StandardOutPath</key>
+	<string>{{.LogFile}}</string>
+	<key>StandardErrorPath</key>
+	<string>{{.LogFile}}</string>
+</dict>
+</plist>
+`))
+
+func plistPath(name string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "Library", "LaunchAgents", name+".plist"), nil
+}
+
+// Install 生成 launchd plist 并加载，返回 plist 路径
+func Install(c *Config) (string, error) {
+	path, err := plistPath(c.Name)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := plistTemplate.Execute(&buf, c); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		return "", err
+	}
+
+	// 重复安装时先卸载旧的配置
+	exec.Command("launchctl", "unload", path).Run()
+	if out, err := exec.Command("launchctl", "load", "-w", path).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("launchctl load failed: %v %s", err, out)
+	}
+	return path, nil
+}
+
+// Uninstall 停止服务并删除 plist
+func Uninstall(name string) error {
+	path, err := plistPath(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("service not installed: %s", path)
+	}
+	if out, err := exec.Command("launchctl", "unload", "-w", path).CombinedOutput(); err != nil {
+		return fmt.Errorf("launchctl unload failed: %v %s", err, out)
+	}
+	return os.Remove(path)
+}
+
+// RunService 仅 Windows 需要与服务管理器交互，其他平台直接返回 false
+func RunService(name string, run func() error) (bool, error) {
+	return false, nil
+}
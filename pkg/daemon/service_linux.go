@@ -0,0 +1,102 @@
+//go:build linux
+
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+var unitTemplate = template.Must(template.New("unit").Funcs(template.FuncMap{"quote": quote}).Parse(`[Unit]
+Description={{.Description}}
+After=network.target
+
+[Service]
+Type=simple
+ExecStart={{quote .Exec}}{{range .Args}} {{quote .}}{{end}}
+Restart=on-failure
+RestartSec=5
+
+[Install]
+WantedBy=default.target
+`))
+
+func unitPath(name string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "systemd", "user", name+".service"), nil
+}
+
+func systemctl(args ...string) error {
+	args = append([]string{"--user"}, args...)
+	if out, err := exec.Command("systemctl", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("systemctl %s failed: %v %s", strings.Join(args, " "), err, out)
+	}
+	return nil
+}
+
+// Install 生成 systemd 用户服务并启用，返回 unit 文件路径
+// 需要开机即启动（而不是登录后启动）时，需执行 loginctl enable-linger
+func Install(c *Config) (string, error) {
+	path, err := unitPath(c.Name)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := unitTemplate.Execute(&buf, c); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		return "", err
+	}
+
+	if err := systemctl("daemon-reload"); err != nil {
+		return "", err
+	}
+	if err := systemctl("enable", "--now", c.Name); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Uninstall 停止并禁用服务，删除 unit 文件
+func Uninstall(name string) error {
+	path, err := unitPath(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("service not installed: %s", path)
+	}
+	if err := systemctl("disable", "--now", name); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	return systemctl("daemon-reload")
+}
+
+// RunService 仅 Windows 需要与服务管理器交互，其他平台直接返回 false
+func RunService(name string, run func() error) (bool, error) {
+	return false, nil
+}
+
+// quote 对包含空白字符的参数加引号，用于生成服务描述文件
+func quote(s string) string {
+	if strings.ContainsAny(s, " \t\"") {
+		return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+	}
+	return s
+}
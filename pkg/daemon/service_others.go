@@ -0,0 +1,15 @@
+//go:build !darwin && !linux && !windows
+
+package daemon
+
+func Install(c *Config) (string, error) {
+	return "", ErrNotSupported
+}
+
+func Uninstall(name string) error {
+	return ErrNotSupported
+}
+
+func RunService(name string, run func() error) (bool, error) {
+	return false, nil
+}
@@ -0,0 +1,104 @@
+//go:build windows
+
+package daemon
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// Install 注册并启动 Windows 服务，返回服务名称
+func Install(c *Config) (string, error) {
+	m, err := mgr.Connect()
+	if err != nil {
+		return "", err
+	}
+	defer m.Disconnect()
+
+	if s, err := m.OpenService(c.Name); err == nil {
+		s.Close()
+		return "", fmt.Errorf("service %s already exists", c.Name)
+	}
+
+	s, err := m.CreateService(c.Name, c.Exec, mgr.Config{
+		DisplayName: c.DisplayName,
+		Description: c.Description,
+		StartType:   mgr.StartAutomatic,
+	}, c.Args...)
+	if err != nil {
+		return "", err
+	}
+	defer s.Close()
+
+	if err := s.Start(); err != nil {
+		return "", err
+	}
+	return c.Name, nil
+}
+
+// Uninstall 停止并删除 Windows 服务
+func Uninstall(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("service %s not installed", name)
+	}
+	defer s.Close()
+
+	if status, err := s.Control(svc.Stop); err == nil {
+		for i := 0; i < 10 && status.State != svc.Stopped; i++ {
+			time.Sleep(time.Second)
+			if status, err = s.Query(); err != nil {
+				break
+			}
+		}
+	}
+	return s.Delete()
+}
+
+// RunService 由服务管理器启动时，以服务的方式运行 run，返回 true
+func RunService(name string, run func() error) (bool, error) {
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return false, err
+	}
+	return true, svc.Run(name, &handler{run: run})
+}
+
+type handler struct {
+	run func() error
+}
+
+func (h *handler) Execute(args []string, r <-chan svc.ChangeRequest, changes chan<- svc.Status) (bool, uint32) {
+	changes <- svc.Status{State: svc.StartPending}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- h.run() }()
+
+	changes <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for {
+		select {
+		case err := <-errCh:
+			if err != nil {
+				return true, 1
+			}
+			return false, 0
+		case c := <-r:
+			switch c.Cmd {
+			case svc.Interrogate:
+				changes <- c.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				changes <- svc.Status{State: svc.StopPending}
+				return false, 0
+			}
+		}
+	}
+}
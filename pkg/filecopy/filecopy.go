@@ -259,6 +259,11 @@ func (fm *FileCopyManager) findLatestCandidate(candidates []*indexCandidate) *in
 	return latest
 }
 
+// TempDir returns the directory where temporary copies of the current process are stored.
+func TempDir() string {
+	return filepath.Join(os.TempDir(), "filecopy_"+getProcessName())
+}
+
 // getManager returns the FileCopyManager instance for the specified instanceID.
 // Creates a new manager if one doesn't exist for this instanceID.
 func getManager(instanceID string) *FileCopyManager {
@@ -287,8 +292,7 @@ func getManager(instanceID string) *FileCopyManager {
 // newManager creates and initializes a new FileCopyManager instance for the specified instanceID.
 // It sets up the temporary directory and starts background cleanup routines with proper lifecycle management.
 func newManager(instanceID string) *FileCopyManager {
-	procName := getProcessName()
-	tempDir := filepath.Join(os.TempDir(), "filecopy_"+procName)
+	tempDir := TempDir()
 
 	// Create temporary directory with improved error handling
 	if err := os.MkdirAll(tempDir, 0755); err != nil {
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/rs/zerolog/log"
@@ -22,6 +23,8 @@ type FileMonitor struct {
 	stopCh     chan struct{}         // Stop signal
 	wg         sync.WaitGroup        // Wait group
 	isRunning  bool                  // Running state flag
+	polling    bool                  // Files are polled instead of watched
+	interval   time.Duration         // Poll interval
 	stateMutex sync.RWMutex          // State mutex
 }
 
@@ -36,6 +39,7 @@ func NewFileMonitor() *FileMonitor {
 		watchDirs: make(map[string]bool),
 		blacklist: []string{},
 		isRunning: false,
+		interval:  DefaultPollInterval,
 	}
 }
 
@@ -139,6 +143,7 @@ func (fm *FileMonitor) GetGroup(id string) (*FileGroup, bool) {
 }
 
 // Start starts the file monitor
+// Directories on file systems that do not report changes, or that can not be watched, are polled instead
 func (fm *FileMonitor) Start() error {
 	// Check if already running
 	fm.stateMutex.Lock()
@@ -147,17 +152,6 @@ func (fm *FileMonitor) Start() error {
 		return errors.New("file monitor is already running")
 	}
 
-	// Create new watcher
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		fm.stateMutex.Unlock()
-		return fmt.Errorf("failed to create watcher: %w", err)
-	}
-	fm.watcher = watcher
-
-	// Reset stop channel
-	fm.stopCh = make(chan struct{})
-
 	// Get groups to monitor (without holding the state lock)
 	fm.mutex.RLock()
 	groups := make([]*FileGroup, 0, len(fm.groups))
@@ -166,6 +160,17 @@ func (fm *FileMonitor) Start() error {
 	}
 	fm.mutex.RUnlock()
 
+	// Root directories must exist in both modes
+	for _, group := range groups {
+		if _, err := os.Stat(group.RootDir); err != nil {
+			fm.stateMutex.Unlock()
+			return fmt.Errorf("failed to setup watch for group '%s': %w", group.ID, err)
+		}
+	}
+
+	// Reset stop channel
+	fm.stopCh = make(chan struct{})
+
 	// Reset monitored directories
 	fm.mutex.Lock()
 	fm.watchDirs = make(map[string]bool)
@@ -173,19 +178,51 @@ func (fm *FileMonitor) Start() error {
 
 	// Mark as running before setting up watches
 	fm.isRunning = true
+	fm.polling = false
+	fm.stateMutex.Unlock()
+
+	for _, group := range groups {
+		if !eventsSupported(group.RootDir) {
+			log.Info().Str("dir", group.RootDir).Msg("File system does not report changes, polling instead")
+			fm.startPolling()
+			return nil
+		}
+	}
+
+	if err := fm.startWatching(groups); err != nil {
+		// e.g. the inotify watch limit is reached
+		log.Warn().Err(err).Msg("Failed to watch files, polling instead")
+		fm.startPolling()
+		return nil
+	}
+
+	// log.Info().Msg("File monitor started")
+	return nil
+}
+
+// startWatching creates the watcher and starts the watch loop
+func (fm *FileMonitor) startWatching(groups []*FileGroup) error {
+	// Create new watcher
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %w", err)
+	}
+	fm.stateMutex.Lock()
+	fm.watcher = watcher
 	fm.stateMutex.Unlock()
 
 	// Set up monitoring for all groups (without holding any locks)
 	for _, group := range groups {
 		if err := fm.setupWatchForGroup(group); err != nil {
 			// Clean up resources on failure
-			_ = fm.watcher.Close()
+			_ = watcher.Close()
 
-			// Reset running state
 			fm.stateMutex.Lock()
 			fm.watcher = nil
-			fm.isRunning = false
 			fm.stateMutex.Unlock()
+			fm.mutex.Lock()
+			fm.watchDirs = make(map[string]bool)
+			fm.mutex.Unlock()
 
 			return fmt.Errorf("failed to setup watch for group '%s': %w", group.ID, err)
 		}
@@ -194,8 +231,6 @@ func (fm *FileMonitor) Start() error {
 	// Start watch loop
 	fm.wg.Add(1)
 	go fm.watchLoop()
-
-	// log.Info().Msg("File monitor started")
 	return nil
 }
 
@@ -280,6 +315,10 @@ func (fm *FileMonitor) setupWatchForGroup(group *FileGroup) error {
 	if !fm.IsRunning() {
 		return errors.New("file monitor is not running")
 	}
+	// The poll loop lists the files of all groups by itself
+	if fm.Polling() {
+		return nil
+	}
 
 	// Find directories containing matching files
 	matchingDirs, err := group.ListMatchingDirectories()
@@ -0,0 +1,34 @@
+//go:build darwin
+
+package filemonitor
+
+import (
+	"strings"
+	"syscall"
+)
+
+// Network and FUSE file systems, kqueue does not report changes made by other hosts on them
+var unwatchableFS = map[string]bool{
+	"nfs":     true,
+	"smbfs":   true,
+	"afpfs":   true,
+	"webdav":  true,
+	"macfuse": true,
+	"osxfuse": true,
+}
+
+// eventsSupported reports whether the file system of dir reports changes to the watcher
+func eventsSupported(dir string) bool {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return true
+	}
+	var b strings.Builder
+	for _, c := range st.Fstypename {
+		if c == 0 {
+			break
+		}
+		b.WriteByte(byte(c))
+	}
+	return !unwatchableFS[b.String()]
+}
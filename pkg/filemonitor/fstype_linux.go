@@ -0,0 +1,24 @@
+//go:build linux
+
+package filemonitor
+
+import "syscall"
+
+// Magic numbers of network and FUSE file systems, inotify does not report changes made by other hosts on them
+var unwatchableFS = map[uint32]bool{
+	0x6969:     true, // NFS
+	0x517B:     true, // SMB
+	0xFF534D42: true, // CIFS
+	0xFE534D42: true, // SMB2
+	0x01021997: true, // 9P, e.g. Windows drives in WSL
+	0x65735546: true, // FUSE, e.g. sshfs and rclone
+}
+
+// eventsSupported reports whether the file system of dir reports changes to the watcher
+func eventsSupported(dir string) bool {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return true
+	}
+	return !unwatchableFS[uint32(st.Type)]
+}
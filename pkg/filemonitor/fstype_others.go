@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package filemonitor
+
+// eventsSupported reports whether the file system of dir reports changes to the watcher
+// Windows reports changes on network shares, unknown file systems fall back to polling only when watching fails
+func eventsSupported(dir string) bool {
+	return true
+}
@@ -0,0 +1,100 @@
+package filemonitor
+
+import (
+	"os"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultPollInterval is the interval between scans when files are polled
+const DefaultPollInterval = 2 * time.Second
+
+// fileState is what a scan remembers of a file to detect changes
+type fileState struct {
+	size    int64
+	modTime int64
+}
+
+// SetPollInterval sets the interval between scans when files are polled, it takes effect on the next Start
+func (fm *FileMonitor) SetPollInterval(interval time.Duration) {
+	fm.stateMutex.Lock()
+	defer fm.stateMutex.Unlock()
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	fm.interval = interval
+}
+
+// Polling returns whether files are polled because they can not be watched
+func (fm *FileMonitor) Polling() bool {
+	fm.stateMutex.RLock()
+	defer fm.stateMutex.RUnlock()
+	return fm.polling
+}
+
+// startPolling starts the poll loop
+func (fm *FileMonitor) startPolling() {
+	fm.stateMutex.Lock()
+	fm.polling = true
+	interval := fm.interval
+	fm.stateMutex.Unlock()
+
+	// Scan before returning so changes right after Start are not missed
+	states := fm.scan()
+	fm.wg.Add(1)
+	go fm.pollLoop(interval, states)
+}
+
+// pollLoop scans the files of all groups and forwards changes as events
+// New files are reported as Create, changed size or modification time as Write, and missing files as Remove
+func (fm *FileMonitor) pollLoop(interval time.Duration, states map[string]fileState) {
+	defer fm.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-fm.stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		current := fm.scan()
+		for path, state := range current {
+			old, ok := states[path]
+			switch {
+			case !ok:
+				fm.forwardEventToGroups(fsnotify.Event{Name: path, Op: fsnotify.Create})
+			case state != old:
+				fm.forwardEventToGroups(fsnotify.Event{Name: path, Op: fsnotify.Write})
+			}
+		}
+		for path := range states {
+			if _, ok := current[path]; !ok {
+				fm.forwardEventToGroups(fsnotify.Event{Name: path, Op: fsnotify.Remove})
+			}
+		}
+		states = current
+	}
+}
+
+// scan returns the state of the files of all groups
+func (fm *FileMonitor) scan() map[string]fileState {
+	states := make(map[string]fileState)
+	for _, group := range fm.GetGroups() {
+		files, err := group.List()
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			info, err := os.Stat(file)
+			if err != nil {
+				continue
+			}
+			states[file] = fileState{size: info.Size(), modTime: info.ModTime().UnixNano()}
+		}
+	}
+	return states
+}
@@ -0,0 +1,66 @@
+package filemonitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestPolling(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "message_0.db")
+	if err := os.WriteFile(existing, []byte("a"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	group, err := NewFileGroup("test", dir, `.*\.db$`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := make(chan fsnotify.Event, 16)
+	group.AddCallback(func(e fsnotify.Event) error {
+		events <- e
+		return nil
+	})
+
+	fm := NewFileMonitor()
+	fm.AddGroup(group)
+	fm.SetPollInterval(10 * time.Millisecond)
+	fm.stopCh = make(chan struct{})
+	fm.isRunning = true
+	fm.startPolling()
+	defer fm.Stop()
+
+	expect := func(name string, op fsnotify.Op) {
+		t.Helper()
+		select {
+		case e := <-events:
+			if e.Name != name || e.Op != op {
+				t.Fatalf("got %s %s, want %s %s", e.Op, e.Name, op, name)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no %s event for %s", op, name)
+		}
+	}
+
+	created := filepath.Join(dir, "message_1.db")
+	os.WriteFile(created, []byte("b"), 0600)
+	expect(created, fsnotify.Create)
+
+	os.WriteFile(existing, []byte("aa"), 0600)
+	expect(existing, fsnotify.Write)
+
+	os.Remove(created)
+	expect(created, fsnotify.Remove)
+
+	// Files outside the group are not reported
+	os.WriteFile(filepath.Join(dir, "message_0.db-wal"), []byte("c"), 0600)
+	select {
+	case e := <-events:
+		t.Fatalf("unexpected event %s %s", e.Op, e.Name)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
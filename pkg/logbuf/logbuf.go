@@ -0,0 +1,79 @@
+package logbuf
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/rs/zerolog"
+)
+
+// DefaultSize is the number of entries kept by Default
+const DefaultSize = 2000
+
+// Default is the buffer receiving the TUI logs
+var Default = New(DefaultSize)
+
+// Entry is a log entry in zerolog JSON format
+type Entry struct {
+	Level zerolog.Level
+	Data  []byte
+}
+
+// Buffer keeps the latest log entries in a ring, it implements zerolog.LevelWriter
+type Buffer struct {
+	mu      sync.Mutex
+	entries []Entry
+	next    int
+	full    bool
+	version atomic.Uint64
+}
+
+// New creates a buffer keeping at most size entries
+func New(size int) *Buffer {
+	return &Buffer{entries: make([]Entry, max(size, 1))}
+}
+
+// Write stores an entry without level
+func (b *Buffer) Write(p []byte) (int, error) {
+	return b.WriteLevel(zerolog.NoLevel, p)
+}
+
+// WriteLevel stores an entry, the oldest entry is dropped when the buffer is full
+func (b *Buffer) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	data := make([]byte, len(p))
+	copy(data, p)
+
+	b.mu.Lock()
+	b.entries[b.next] = Entry{Level: level, Data: data}
+	b.next = (b.next + 1) % len(b.entries)
+	if b.next == 0 {
+		b.full = true
+	}
+	b.mu.Unlock()
+	b.version.Add(1)
+	return len(p), nil
+}
+
+// Entries returns the entries at or above min level from oldest to newest
+// Entries without level are always returned
+func (b *Buffer) Entries(min zerolog.Level) []Entry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ordered := b.entries[:b.next]
+	if b.full {
+		ordered = append(append([]Entry{}, b.entries[b.next:]...), b.entries[:b.next]...)
+	}
+	result := make([]Entry, 0, len(ordered))
+	for _, e := range ordered {
+		if e.Level == zerolog.NoLevel || e.Level >= min {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// Version returns a counter increased on every write, it can be used to detect new entries
+func (b *Buffer) Version() uint64 {
+	return b.version.Load()
+}
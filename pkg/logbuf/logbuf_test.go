@@ -0,0 +1,28 @@
+package logbuf
+
+import (
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestBuffer(t *testing.T) {
+	b := New(3)
+	logger := zerolog.New(b)
+	logger.Debug().Msg("1")
+	logger.Info().Msg("2")
+	logger.Warn().Msg("3")
+	logger.Error().Msg("4")
+
+	if b.Version() != 4 {
+		t.Errorf("version = %d, want 4", b.Version())
+	}
+
+	all := b.Entries(zerolog.DebugLevel)
+	if len(all) != 3 || all[0].Level != zerolog.InfoLevel || all[2].Level != zerolog.ErrorLevel {
+		t.Fatalf("unexpected entries: %+v", all)
+	}
+	if warn := b.Entries(zerolog.WarnLevel); len(warn) != 2 {
+		t.Errorf("got %d entries at warn level, want 2", len(warn))
+	}
+}
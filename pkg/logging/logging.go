@@ -0,0 +1,124 @@
+// Package logging configures the global zerolog logger: level, per-module levels, console or JSON format,
+// and output to stderr or a size rotated file. The stdlib log package is redirected to the same logger.
+package logging
+
+import (
+	"fmt"
+	"io"
+	stdlog "log"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	FormatConsole = "console"
+	FormatJSON    = "json"
+)
+
+// Config is the log configuration, zero values use the defaults
+type Config struct {
+	Level      string            // default level, info when empty
+	Modules    map[string]string // level per module, see Module for how modules are matched
+	Format     string            // console or json, console when empty
+	File       string            // log file, stderr when empty
+	MaxSize    int               // size in MB before the file is rotated, DefaultMaxSize when 0
+	MaxBackups int               // rotated files to keep, DefaultMaxBackups when 0
+}
+
+// Setup creates the writer for c and sets it as the output of the global logger
+func Setup(c Config, stderr io.Writer) error {
+	w, err := NewWriter(c, stderr)
+	if err != nil {
+		return err
+	}
+	return SetupWriter(c, w)
+}
+
+// NewWriter returns the writer for the format and output of c, logs are written to stderr when no file is set
+// File output is never colored
+func NewWriter(c Config, stderr io.Writer) (io.Writer, error) {
+	out, noColor := stderr, false
+	if len(c.File) != 0 {
+		f, err := NewRotateWriter(c.File, c.MaxSize, c.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		out, noColor = f, true
+	}
+	switch c.Format {
+	case "", FormatConsole:
+		return zerolog.ConsoleWriter{Out: out, NoColor: noColor, TimeFormat: time.RFC3339}, nil
+	case FormatJSON:
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q, want console or json", c.Format)
+	}
+}
+
+// SetupWriter sets the levels of c and makes w the output of the global logger and the stdlib log package
+// w receives zerolog JSON events, wrap other writers with NewWriter
+func SetupWriter(c Config, w io.Writer) error {
+	level, err := parseLevel(c.Level, zerolog.InfoLevel)
+	if err != nil {
+		return err
+	}
+	hook, err := newModuleHook(level, c.Modules)
+	if err != nil {
+		return err
+	}
+
+	logger := zerolog.New(w).With().Timestamp().Logger()
+	if hook != nil {
+		// Events are created down to the lowest module level and dropped by the hook
+		level = hook.min
+		logger = logger.Hook(hook)
+	}
+	zerolog.SetGlobalLevel(level)
+	log.Logger = logger
+
+	stdlog.SetFlags(0)
+	stdlog.SetOutput(log.Logger)
+	return nil
+}
+
+// ParseLevels parses a level spec such as "info" or "info,wechat=debug,http=warn"
+// into the default level and the levels per module
+func ParseLevels(spec string) (level string, modules map[string]string, err error) {
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if len(part) == 0 {
+			continue
+		}
+		name, lvl, ok := strings.Cut(part, "=")
+		if !ok {
+			if _, err := parseLevel(part, zerolog.InfoLevel); err != nil {
+				return "", nil, err
+			}
+			level = part
+			continue
+		}
+		if _, err := parseLevel(lvl, zerolog.InfoLevel); err != nil {
+			return "", nil, err
+		}
+		if modules == nil {
+			modules = make(map[string]string)
+		}
+		modules[strings.TrimSpace(name)] = strings.TrimSpace(lvl)
+	}
+	return level, modules, nil
+}
+
+// parseLevel parses a zerolog level name, def is returned for an empty name
+func parseLevel(s string, def zerolog.Level) (zerolog.Level, error) {
+	if len(s) == 0 {
+		return def, nil
+	}
+	level, err := zerolog.ParseLevel(strings.ToLower(s))
+	if err != nil || level == zerolog.NoLevel {
+		return def, fmt.Errorf("unknown log level %q, want trace, debug, info, warn, error or disabled", s)
+	}
+	return level, nil
+}
@@ -0,0 +1,84 @@
+package logging_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/DanielMao1/chatlog/pkg/logging"
+)
+
+func TestParseLevels(t *testing.T) {
+	level, modules, err := logging.ParseLevels("info, wechat=debug,http=warn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if level != "info" || modules["wechat"] != "debug" || modules["http"] != "warn" || len(modules) != 2 {
+		t.Errorf("ParseLevels = %s %v", level, modules)
+	}
+	if _, _, err := logging.ParseLevels("wechat=verbose"); err == nil {
+		t.Error("ParseLevels accepted an unknown level")
+	}
+}
+
+func TestModuleLevels(t *testing.T) {
+	defer zerolog.SetGlobalLevel(zerolog.GlobalLevel())
+	defer func(l zerolog.Logger) { log.Logger = l }(log.Logger)
+
+	var buf bytes.Buffer
+	// 测试代码所在的包为 pkg/logging_test
+	err := logging.SetupWriter(logging.Config{Level: "warn", Modules: map[string]string{"logging_test": "debug", "other": "error"}}, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Debug().Msg("module debug")
+	log.Trace().Msg("module trace")
+	if out := buf.String(); !strings.Contains(out, "module debug") || strings.Contains(out, "module trace") {
+		t.Errorf("output %q", out)
+	}
+
+	buf.Reset()
+	if err := logging.SetupWriter(logging.Config{Level: "debug", Modules: map[string]string{"pkg/logging_test": "error"}}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	log.Warn().Msg("module warn")
+	log.Error().Msg("module error")
+	if out := buf.String(); strings.Contains(out, "module warn") || !strings.Contains(out, "module error") {
+		t.Errorf("output %q", out)
+	}
+}
+
+func TestNewWriter(t *testing.T) {
+	if _, err := logging.NewWriter(logging.Config{Format: "xml"}, os.Stderr); err == nil {
+		t.Error("NewWriter accepted an unknown format")
+	}
+}
+
+func TestRotateWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "server.log")
+	w, err := logging.NewRotateWriter(path, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	line := bytes.Repeat([]byte("x"), 600<<10)
+	for i := 0; i < 5; i++ {
+		if _, err := w.Write(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		if fi, err := os.Stat(name); err != nil || fi.Size() != int64(len(line)) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("kept more than 2 backups: %v", err)
+	}
+}
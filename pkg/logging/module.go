@@ -0,0 +1,116 @@
+package logging
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// moduleHook drops events below the level of the module logging them
+//
+// The module of an event is the package of the function calling Msg, found from the call stack, so call sites
+// keep using the global logger. A module name matches a package when its path segments appear in the package
+// path: "wechat" matches internal/wechat, internal/chatlog/wechat and their subpackages, "chatlog/wechat" only
+// the latter. When several names match, the one with the most segments wins.
+type moduleHook struct {
+	level   zerolog.Level // level of packages matching no module
+	min     zerolog.Level // lowest level of all modules and the default
+	max     zerolog.Level // events at or above the highest level are kept without looking up the module
+	modules []module
+	cache   sync.Map // package path -> zerolog.Level
+}
+
+type module struct {
+	segments []string
+	level    zerolog.Level
+}
+
+// newModuleHook returns nil when no module is configured
+func newModuleHook(level zerolog.Level, modules map[string]string) (*moduleHook, error) {
+	if len(modules) == 0 {
+		return nil, nil
+	}
+	h := &moduleHook{level: level, min: level, max: level}
+	for name, l := range modules {
+		lvl, err := parseLevel(l, level)
+		if err != nil {
+			return nil, err
+		}
+		h.modules = append(h.modules, module{segments: strings.Split(strings.Trim(name, "/"), "/"), level: lvl})
+		h.min, h.max = min(h.min, lvl), max(h.max, lvl)
+	}
+	return h, nil
+}
+
+func (h *moduleHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	if level >= h.max {
+		return
+	}
+	if level < h.levelOf(callerPackage()) {
+		e.Discard()
+	}
+}
+
+// levelOf returns the level of the module matching pkg
+func (h *moduleHook) levelOf(pkg string) zerolog.Level {
+	if v, ok := h.cache.Load(pkg); ok {
+		return v.(zerolog.Level)
+	}
+	level, best := h.level, 0
+	segments := strings.Split(pkg, "/")
+	for _, m := range h.modules {
+		if len(m.segments) > best && containsSegments(segments, m.segments) {
+			level, best = m.level, len(m.segments)
+		}
+	}
+	h.cache.Store(pkg, level)
+	return level
+}
+
+// containsSegments reports whether sub appears in segments as a contiguous run
+func containsSegments(segments, sub []string) bool {
+	for i := 0; i+len(sub) <= len(segments); i++ {
+		match := true
+		for j := range sub {
+			if segments[i+j] != sub[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// callerPackage returns the package of the first function on the stack outside zerolog and this package
+func callerPackage() string {
+	var pcs [16]uintptr
+	n := runtime.Callers(3, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		pkg := funcPackage(frame.Function)
+		if !strings.HasPrefix(pkg, "github.com/rs/zerolog") && pkg != thisPackage {
+			return pkg
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// funcPackage returns the package path of a function name such as example.com/a/b.(*T).M
+func funcPackage(name string) string {
+	slash := strings.LastIndexByte(name, '/')
+	if dot := strings.IndexByte(name[slash+1:], '.'); dot >= 0 {
+		return name[:slash+1+dot]
+	}
+	return name
+}
+
+var thisPackage = reflect.TypeOf(moduleHook{}).PkgPath()
@@ -0,0 +1,185 @@
+package progress
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	StageDecrypt = "decrypt"
+	StageKey     = "key"
+	StageExport  = "export"
+
+	PhaseScan    = "scan"
+	PhaseDerived = "derived"
+
+	// Interval is the minimum interval between two events published by a Tracker
+	Interval = 100 * time.Millisecond
+
+	// BufferSize is the channel buffer size of a subscriber
+	BufferSize = 64
+)
+
+// Event describes the progress of a long running task
+type Event struct {
+	Stage string `json:"stage"`
+	Phase string `json:"phase,omitempty"`
+
+	// Name is the item being processed, e.g. a database file
+	Name string `json:"name,omitempty"`
+	// Done and Size are the processed and total bytes of the current item
+	Done int64 `json:"done,omitempty"`
+	Size int64 `json:"size,omitempty"`
+
+	// Count and Total are the finished and total items, Total is 0 if unknown
+	Count int `json:"count"`
+	Total int `json:"total"`
+
+	// Bytes and TotalBytes are the processed and total bytes of all items
+	Bytes      int64 `json:"bytes,omitempty"`
+	TotalBytes int64 `json:"totalBytes,omitempty"`
+
+	// Found and Expected are the found and expected keys of the key stage
+	Found    int `json:"found,omitempty"`
+	Expected int `json:"expected,omitempty"`
+
+	// Finished is set on the last event of a task
+	Finished bool `json:"finished,omitempty"`
+}
+
+var (
+	mu          sync.RWMutex
+	subscribers = map[chan Event]struct{}{}
+)
+
+// Subscribe returns a channel receiving all published events and a function to unsubscribe
+// Events are dropped instead of blocking publishers when the subscriber falls behind
+func Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, BufferSize)
+	mu.Lock()
+	subscribers[ch] = struct{}{}
+	mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			mu.Lock()
+			delete(subscribers, ch)
+			mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish sends the event to all subscribers without blocking
+func Publish(e Event) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for ch := range subscribers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Tracker aggregates the progress of items processed concurrently and publishes throttled events
+type Tracker struct {
+	mu    sync.Mutex
+	event Event
+	items map[string][2]int64
+	last  time.Time
+}
+
+// NewTracker creates a tracker for total items of totalBytes bytes, both can be 0 if unknown
+func NewTracker(stage, phase string, total int, totalBytes int64) *Tracker {
+	t := &Tracker{
+		event: Event{
+			Stage:      stage,
+			Phase:      phase,
+			Total:      total,
+			TotalBytes: totalBytes,
+		},
+		items: make(map[string][2]int64),
+	}
+	Publish(t.event)
+	return t
+}
+
+// Start marks an item of size bytes as started
+func (t *Tracker) Start(name string, size int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.items[name] = [2]int64{0, size}
+	t.update(name)
+	t.publish(false)
+}
+
+// Add adds n processed bytes to the item
+func (t *Tracker) Add(name string, n int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	item := t.items[name]
+	item[0] += n
+	t.items[name] = item
+	t.event.Bytes += n
+	t.update(name)
+	t.publish(false)
+}
+
+// Restart discards the processed bytes of the item when it is processed again from the beginning
+func (t *Tracker) Restart(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	item := t.items[name]
+	t.event.Bytes -= item[0]
+	item[0] = 0
+	t.items[name] = item
+	t.update(name)
+	t.publish(false)
+}
+
+// Finish marks the item as finished, the remaining bytes of a skipped or failed item are counted as processed
+func (t *Tracker) Finish(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	item := t.items[name]
+	if item[0] < item[1] {
+		t.event.Bytes += item[1] - item[0]
+		item[0] = item[1]
+	}
+	t.items[name] = item
+	t.update(name)
+	delete(t.items, name)
+	t.event.Count++
+	t.publish(true)
+}
+
+// Found sets the found and expected keys
+func (t *Tracker) Found(found, expected int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.event.Found, t.event.Expected = found, expected
+	t.publish(true)
+}
+
+// Done publishes the last event of the task
+func (t *Tracker) Done() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.event.Finished = true
+	Publish(t.event)
+}
+
+func (t *Tracker) update(name string) {
+	item := t.items[name]
+	t.event.Name, t.event.Done, t.event.Size = name, item[0], item[1]
+}
+
+func (t *Tracker) publish(force bool) {
+	if !force && time.Since(t.last) < Interval {
+		return
+	}
+	t.last = time.Now()
+	Publish(t.event)
+}
@@ -0,0 +1,66 @@
+package progress
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestTracker(t *testing.T) {
+	events, cancel := Subscribe()
+	defer cancel()
+
+	tracker := NewTracker(StageDecrypt, "", 3, 300)
+	var wg sync.WaitGroup
+	for _, name := range []string{"a.db", "b.db", "c.db"} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			tracker.Start(name, 100)
+			tracker.Add(name, 40)
+			// 未写满的部分在完成时计入
+			tracker.Finish(name)
+		}(name)
+	}
+	wg.Wait()
+	tracker.Done()
+
+	var last Event
+	for e := range events {
+		last = e
+		if e.Finished {
+			break
+		}
+	}
+	if last.Stage != StageDecrypt || last.Count != 3 || last.Total != 3 {
+		t.Errorf("unexpected count: %+v", last)
+	}
+	if last.Bytes != 300 || last.TotalBytes != 300 {
+		t.Errorf("unexpected bytes: %+v", last)
+	}
+}
+
+func TestTrackerRestart(t *testing.T) {
+	tracker := NewTracker(StageDecrypt, "", 1, 100)
+	tracker.Start("a.db", 100)
+	tracker.Add("a.db", 80)
+	// 重新处理时之前写入的字节不重复计算
+	tracker.Restart("a.db")
+	tracker.Add("a.db", 100)
+	tracker.Finish("a.db")
+	if tracker.event.Bytes != 100 {
+		t.Errorf("Bytes = %d, want 100", tracker.event.Bytes)
+	}
+}
+
+func TestPublishWithoutBlocking(t *testing.T) {
+	events, cancel := Subscribe()
+	for i := 0; i < BufferSize*2; i++ {
+		Publish(Event{Stage: StageKey, Count: i})
+	}
+	if len(events) != BufferSize {
+		t.Errorf("got %d buffered events, want %d", len(events), BufferSize)
+	}
+	cancel()
+	cancel()
+	Publish(Event{Stage: StageKey})
+}
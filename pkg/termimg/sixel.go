@@ -0,0 +1,112 @@
+package termimg
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"io"
+)
+
+// levels is the number of levels per channel of the sixel palette, 6*6*6 = 216 colors
+const levels = 6
+
+// WriteSixel encodes the image as sixel graphics, scaled down to at most maxWidth pixels wide
+func WriteSixel(w io.Writer, img image.Image, maxWidth int) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return fmt.Errorf("empty image")
+	}
+	if maxWidth > 0 && width > maxWidth {
+		height = max(height*maxWidth/width, 1)
+		width = maxWidth
+	}
+
+	// nearest neighbour scaling and quantization to the palette
+	pixels := make([]int, width*height)
+	used := make([]bool, levels*levels*levels)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			sx := bounds.Min.X + x*bounds.Dx()/width
+			sy := bounds.Min.Y + y*bounds.Dy()/height
+			r, g, b, a := img.At(sx, sy).RGBA()
+			if a == 0 {
+				pixels[y*width+x] = -1
+				continue
+			}
+			index := quantize(r)*levels*levels + quantize(g)*levels + quantize(b)
+			pixels[y*width+x] = index
+			used[index] = true
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "\x1bPq\"1;1;%d;%d", width, height)
+	for index, ok := range used {
+		if !ok {
+			continue
+		}
+		r, g, b := index/(levels*levels), index/levels%levels, index%levels
+		fmt.Fprintf(bw, "#%d;2;%d;%d;%d", index, r*100/(levels-1), g*100/(levels-1), b*100/(levels-1))
+	}
+
+	// each band is 6 pixels high, every color of the band is drawn in its own pass
+	row := make([]byte, width)
+	for top := 0; top < height; top += 6 {
+		colors := make(map[int]bool)
+		for y := top; y < min(top+6, height); y++ {
+			for x := 0; x < width; x++ {
+				if index := pixels[y*width+x]; index >= 0 {
+					colors[index] = true
+				}
+			}
+		}
+		first := true
+		for index := range used {
+			if !colors[index] {
+				continue
+			}
+			for x := 0; x < width; x++ {
+				var bits byte
+				for dy := 0; dy < 6 && top+dy < height; dy++ {
+					if pixels[(top+dy)*width+x] == index {
+						bits |= 1 << dy
+					}
+				}
+				row[x] = 63 + bits
+			}
+			if !first {
+				bw.WriteByte('$')
+			}
+			first = false
+			fmt.Fprintf(bw, "#%d", index)
+			writeRLE(bw, row)
+		}
+		bw.WriteByte('-')
+	}
+	bw.WriteString("\x1b\\")
+	return bw.Flush()
+}
+
+// writeRLE writes the sixel row with run-length encoding
+func writeRLE(w *bufio.Writer, row []byte) {
+	for i := 0; i < len(row); {
+		j := i
+		for j < len(row) && row[j] == row[i] {
+			j++
+		}
+		if n := j - i; n > 3 {
+			fmt.Fprintf(w, "!%d%c", n, row[i])
+		} else {
+			for k := 0; k < n; k++ {
+				w.WriteByte(row[i])
+			}
+		}
+		i = j
+	}
+}
+
+// quantize maps a 16-bit color channel to a palette level
+func quantize(c uint32) int {
+	return int((c*(levels-1) + 0x7fff) / 0xffff)
+}
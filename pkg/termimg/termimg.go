@@ -0,0 +1,121 @@
+package termimg
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	ProtocolNone  = ""
+	ProtocolITerm = "iterm2"
+	ProtocolKitty = "kitty"
+	ProtocolSixel = "sixel"
+
+	// EnvProtocol overrides the detected protocol, "none" disables terminal graphics
+	EnvProtocol = "CHATLOG_IMAGE_PROTOCOL"
+
+	// CellWidth is the assumed width of a terminal cell in pixels, used to scale sixel images
+	CellWidth = 10
+
+	// kittyChunkSize is the maximum payload size of a kitty graphics escape sequence
+	kittyChunkSize = 4096
+)
+
+// Detect returns the graphics protocol supported by the current terminal
+// Terminals are detected from environment variables set by the terminal emulator,
+// graphics are disabled inside tmux and screen unless EnvProtocol is set
+func Detect() string {
+	if p := strings.ToLower(os.Getenv(EnvProtocol)); len(p) != 0 {
+		switch p {
+		case ProtocolITerm, ProtocolKitty, ProtocolSixel:
+			return p
+		}
+		return ProtocolNone
+	}
+	if len(os.Getenv("TMUX")) != 0 || strings.HasPrefix(os.Getenv("TERM"), "screen") {
+		return ProtocolNone
+	}
+
+	term := os.Getenv("TERM")
+	program := os.Getenv("TERM_PROGRAM")
+	switch {
+	case len(os.Getenv("KITTY_WINDOW_ID")) != 0, term == "xterm-kitty", term == "xterm-ghostty", program == "ghostty":
+		return ProtocolKitty
+	case program == "iTerm.app", program == "WezTerm", os.Getenv("LC_TERMINAL") == "iTerm2":
+		return ProtocolITerm
+	case strings.Contains(term, "sixel"), strings.HasPrefix(term, "foot"), term == "mlterm", term == "yaft-256color":
+		return ProtocolSixel
+	}
+	return ProtocolNone
+}
+
+// Decode decodes JPEG, PNG and GIF image data
+func Decode(data []byte) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// Write writes the image data to w with the protocol, scaled to at most cols terminal cells wide
+func Write(w io.Writer, protocol string, data []byte, cols int) error {
+	switch protocol {
+	case ProtocolITerm:
+		return writeITerm(w, data, cols)
+	case ProtocolKitty:
+		return writeKitty(w, data, cols)
+	case ProtocolSixel:
+		img, err := Decode(data)
+		if err != nil {
+			return err
+		}
+		return WriteSixel(w, img, cols*CellWidth)
+	}
+	return fmt.Errorf("unsupported image protocol %q", protocol)
+}
+
+// writeITerm uses the iTerm2 inline images protocol, the terminal decodes the image itself
+func writeITerm(w io.Writer, data []byte, cols int) error {
+	_, err := fmt.Fprintf(w, "\x1b]1337;File=inline=1;size=%d;width=%d;preserveAspectRatio=1:%s\a",
+		len(data), cols, base64.StdEncoding.EncodeToString(data))
+	return err
+}
+
+// writeKitty uses the kitty graphics protocol, images are sent as PNG in chunks
+func writeKitty(w io.Writer, data []byte, cols int) error {
+	if !bytes.HasPrefix(data, []byte("\x89PNG")) {
+		img, err := Decode(data)
+		if err != nil {
+			return err
+		}
+		buf := &bytes.Buffer{}
+		if err := png.Encode(buf, img); err != nil {
+			return err
+		}
+		data = buf.Bytes()
+	}
+
+	payload := base64.StdEncoding.EncodeToString(data)
+	for i := 0; i < len(payload); i += kittyChunkSize {
+		end := min(i+kittyChunkSize, len(payload))
+		more := 0
+		if end < len(payload) {
+			more = 1
+		}
+		control := fmt.Sprintf("m=%d", more)
+		if i == 0 {
+			// q=2 suppresses responses, which would otherwise be written to stdin
+			control = fmt.Sprintf("a=T,f=100,q=2,c=%d,m=%d", cols, more)
+		}
+		if _, err := fmt.Fprintf(w, "\x1b_G%s;%s\x1b\\", control, payload[i:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
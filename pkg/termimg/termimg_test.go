@@ -0,0 +1,68 @@
+package termimg
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func testPNG(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDetect(t *testing.T) {
+	for _, env := range []string{EnvProtocol, "TMUX", "TERM", "TERM_PROGRAM", "LC_TERMINAL", "KITTY_WINDOW_ID"} {
+		t.Setenv(env, "")
+	}
+	t.Setenv("TERM", "xterm-kitty")
+	if got := Detect(); got != ProtocolKitty {
+		t.Errorf("Detect() = %q, want kitty", got)
+	}
+	t.Setenv("TMUX", "/tmp/tmux-1000/default,1,0")
+	if got := Detect(); got != ProtocolNone {
+		t.Errorf("Detect() in tmux = %q, want none", got)
+	}
+	t.Setenv(EnvProtocol, "sixel")
+	if got := Detect(); got != ProtocolSixel {
+		t.Errorf("Detect() with override = %q, want sixel", got)
+	}
+}
+
+func TestWriteKitty(t *testing.T) {
+	// large enough to be split into several chunks
+	data := testPNG(t, 200, 200)
+	data = append(data, bytes.Repeat([]byte{0}, kittyChunkSize)...)
+	buf := &bytes.Buffer{}
+	if err := Write(buf, ProtocolKitty, data, 40); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "\x1b_Ga=T,f=100,q=2,c=40,m=1;") || !strings.Contains(out, "\x1b_Gm=0;") {
+		t.Errorf("unexpected kitty output prefix: %q", out[:40])
+	}
+}
+
+func TestWriteSixel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := Write(buf, ProtocolSixel, testPNG(t, 40, 12), 2); err != nil {
+		t.Fatal(err)
+	}
+	// scaled to 20x6: one band of a single red color, 20 full columns
+	want := "\x1bPq\"1;1;20;6#180;2;100;0;0#180!20~-\x1b\\"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteSixel() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,77 @@
+package util
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"unicode/utf16"
+)
+
+// ErrNoClipboard is returned when no clipboard tool is available.
+var ErrNoClipboard = errors.New("no clipboard tool found, install wl-clipboard, xclip or xsel")
+
+// CopyToClipboard copies text to the system clipboard with pbcopy on macOS, clip on Windows,
+// and wl-copy, xclip or xsel on Linux and other Unix systems.
+func CopyToClipboard(text string) error {
+	name, args, err := clipboardCommand()
+	if err != nil {
+		return err
+	}
+	input := []byte(text)
+	if runtime.GOOS == "windows" {
+		// clip reads the console code page unless the input starts with a UTF-16 BOM
+		input = utf16LE(text)
+	}
+
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = bytes.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); len(msg) != 0 {
+			return fmt.Errorf("%s: %w: %s", name, err, msg)
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// clipboardCommand returns the command that reads text from stdin into the clipboard.
+func clipboardCommand() (string, []string, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return "pbcopy", nil, nil
+	case "windows":
+		return "clip", nil, nil
+	}
+
+	candidates := [][]string{
+		{"xclip", "-selection", "clipboard"},
+		{"xsel", "--clipboard", "--input"},
+	}
+	if len(os.Getenv("WAYLAND_DISPLAY")) != 0 {
+		candidates = append([][]string{{"wl-copy"}}, candidates...)
+	}
+	for _, c := range candidates {
+		if _, err := exec.LookPath(c[0]); err == nil {
+			return c[0], c[1:], nil
+		}
+	}
+	return "", nil, ErrNoClipboard
+}
+
+// utf16LE encodes text as UTF-16 little endian with a byte order mark.
+func utf16LE(text string) []byte {
+	units := utf16.Encode([]rune(text))
+	buf := make([]byte, 2+len(units)*2)
+	binary.LittleEndian.PutUint16(buf, 0xfeff)
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(buf[2+i*2:], u)
+	}
+	return buf
+}
@@ -6,6 +6,7 @@ package dat2img
 
 import (
 	"bytes"
+	"context"
 	"crypto/aes"
 	"encoding/binary"
 	"encoding/hex"
@@ -46,6 +47,11 @@ var (
 // Dat2Image converts WeChat dat file data to image data
 // Returns the decoded image data, file extension, and any error encountered
 func Dat2Image(data []byte) ([]byte, string, error) {
+	return Dat2ImageContext(context.Background(), data)
+}
+
+// Dat2ImageContext is like Dat2Image, converting animated images with ffmpeg stops when ctx is done
+func Dat2ImageContext(ctx context.Context, data []byte) ([]byte, string, error) {
 	if len(data) < 4 {
 		return nil, "", fmt.Errorf("data length is too short: %d", len(data))
 	}
@@ -54,7 +60,7 @@ func Dat2Image(data []byte) ([]byte, string, error) {
 	if len(data) >= 6 {
 		for _, format := range V4Formats {
 			if bytes.Equal(data[:4], format.Header) {
-				return Dat2ImageV4(data, format.AesKey)
+				return dat2ImageV4(ctx, data, format.AesKey)
 			}
 		}
 	}
@@ -204,6 +210,10 @@ func SetAesKey(key string) {
 // Dat2ImageV4 processes WeChat v4 dat image files
 // WeChat v4 uses a combination of AES-ECB and XOR encryption
 func Dat2ImageV4(data []byte, aeskey []byte) ([]byte, string, error) {
+	return dat2ImageV4(context.Background(), data, aeskey)
+}
+
+func dat2ImageV4(ctx context.Context, data []byte, aeskey []byte) ([]byte, string, error) {
 	if len(data) < 15 {
 		return nil, "", fmt.Errorf("data length is too short for WeChat v4 format: %d", len(data))
 	}
@@ -275,7 +285,7 @@ func Dat2ImageV4(data []byte, aeskey []byte) ([]byte, string, error) {
 	}
 
 	if imgType == "wxgf" {
-		return Wxam2pic(result)
+		return Wxam2picContext(ctx, result)
 	}
 
 	if imgType == "" {
@@ -2,6 +2,7 @@ package dat2img
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -37,6 +38,11 @@ func init() {
 }
 
 func Wxam2pic(data []byte) ([]byte, string, error) {
+	return Wxam2picContext(context.Background(), data)
+}
+
+// Wxam2picContext is like Wxam2pic, the ffmpeg process is killed when ctx is done
+func Wxam2picContext(ctx context.Context, data []byte) ([]byte, string, error) {
 
 	if len(data) < 15 || !bytes.Equal(data[0:4], WXGF.Header) {
 		return nil, "", fmt.Errorf("invalid wxgf")
@@ -59,7 +65,7 @@ func Wxam2pic(data []byte) ([]byte, string, error) {
 			}
 		}
 		if FFmpegMode {
-			mp4Data, err := ConvertAnime2GIF(animeFrames, maskFrames)
+			mp4Data, err := ConvertAnime2GIF(ctx, animeFrames, maskFrames)
 			if err != nil {
 				return nil, "", err
 			}
@@ -76,7 +82,7 @@ func Wxam2pic(data []byte) ([]byte, string, error) {
 	size := partitions.Partitions[partitions.MaxIndex].Size
 
 	if FFmpegMode {
-		jpgData, err := Convert2JPG(data[offset : offset+size])
+		jpgData, err := Convert2JPG(ctx, data[offset:offset+size])
 		if err != nil {
 			return nil, "", err
 		}
@@ -169,8 +175,8 @@ func findDataPartition(data []byte) (*Partitions, error) {
 	return nil, fmt.Errorf("no partition found")
 }
 
-func Convert2JPG(data []byte) ([]byte, error) {
-	cmd := exec.Command(FFMpegPath,
+func Convert2JPG(ctx context.Context, data []byte) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, FFMpegPath,
 		"-i", "-",
 		"-vframes", "1",
 		"-c:v", "mjpeg",
@@ -213,7 +219,7 @@ func writeTempFile(data [][]byte) (string, error) {
 
 // ConvertAnime2GIF convert anime frames and mask frames to mp4
 // FIXME No longer need to write to temporary files
-func ConvertAnime2GIF(animeFrames [][]byte, maskFrames [][]byte) ([]byte, error) {
+func ConvertAnime2GIF(ctx context.Context, animeFrames [][]byte, maskFrames [][]byte) ([]byte, error) {
 	animeFilePath, err := writeTempFile(animeFrames)
 	if err != nil {
 		return nil, fmt.Errorf("failed to write anime temp file: %w", err)
@@ -226,7 +232,7 @@ func ConvertAnime2GIF(animeFrames [][]byte, maskFrames [][]byte) ([]byte, error)
 	}
 	defer os.Remove(maskFilePath)
 
-	cmd := exec.Command(FFMpegPath,
+	cmd := exec.CommandContext(ctx, FFMpegPath,
 		"-i", animeFilePath,
 		"-i", maskFilePath,
 		"-filter_complex", "[0:v][1:v]alphamerge,split[s0][s1];[s0]palettegen[p];[s1][p]paletteuse",
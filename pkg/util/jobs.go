@@ -0,0 +1,21 @@
+package util
+
+import "runtime"
+
+// MaxJobs 并发任务数的上限，避免大量并发读写拖慢磁盘
+const MaxJobs = 16
+
+// Jobs 返回实际使用的并发任务数
+// n <= 0 时使用 CPU 核数（至少为 2），结果不超过 MaxJobs
+func Jobs(n int) int {
+	if n <= 0 {
+		n = runtime.NumCPU()
+		if n < 2 {
+			n = 2
+		}
+	}
+	if n > MaxJobs {
+		n = MaxJobs
+	}
+	return n
+}
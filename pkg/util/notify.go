@@ -0,0 +1,53 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ErrNoNotifier is returned when no desktop notification tool is available.
+var ErrNoNotifier = errors.New("no desktop notification tool found, install libnotify (notify-send)")
+
+// notifyScripts show a notification with osascript on macOS and a tray balloon on Windows.
+// Title and body are passed through the environment to avoid quoting them in the script.
+const (
+	notifyScriptDarwin  = `display notification (system attribute "CHATLOG_NOTIFY_BODY") with title (system attribute "CHATLOG_NOTIFY_TITLE")`
+	notifyScriptWindows = `Add-Type -AssemblyName System.Windows.Forms; ` +
+		`$n = New-Object System.Windows.Forms.NotifyIcon; ` +
+		`$n.Icon = [System.Drawing.SystemIcons]::Information; $n.Visible = $true; ` +
+		`$n.ShowBalloonTip(5000, $env:CHATLOG_NOTIFY_TITLE, $env:CHATLOG_NOTIFY_BODY, 'None'); ` +
+		`Start-Sleep -Seconds 5; $n.Dispose()`
+)
+
+// DesktopNotify shows a native desktop notification with osascript on macOS, PowerShell on Windows,
+// and notify-send on Linux and other Unix systems. It blocks until the tool exits.
+func DesktopNotify(title, body string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("osascript", "-e", notifyScriptDarwin)
+	case "windows":
+		cmd = exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", notifyScriptWindows)
+	default:
+		if _, err := exec.LookPath("notify-send"); err != nil {
+			return ErrNoNotifier
+		}
+		cmd = exec.Command("notify-send", "--app-name=chatlog", title, body)
+	}
+	cmd.Env = append(os.Environ(), "CHATLOG_NOTIFY_TITLE="+title, "CHATLOG_NOTIFY_BODY="+body)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); len(msg) != 0 {
+			return fmt.Errorf("%s: %w: %s", cmd.Path, err, msg)
+		}
+		return fmt.Errorf("%s: %w", cmd.Path, err)
+	}
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"runtime"
 
 	"github.com/rs/zerolog/log"
+	"github.com/shirou/gopsutil/v4/disk"
 )
 
 // FindFilesWithPatterns 在指定目录下查找匹配多个正则表达式的文件
@@ -115,6 +116,27 @@ func ByteCountSI(b int64) string {
 		float64(b)/float64(div), "kMGTPE"[exp])
 }
 
+// FreeSpace returns the free space of the volume holding path.
+// path may not exist yet, the nearest existing parent is checked and returned as dir.
+func FreeSpace(path string) (free int64, dir string, err error) {
+	dir = path
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	usage, err := disk.Usage(dir)
+	if err != nil {
+		return 0, dir, err
+	}
+	return int64(usage.Free), dir, nil
+}
+
 // PrepareDir ensures that the specified directory path exists.
 // If the directory does not exist, it attempts to create it.
 func PrepareDir(path string) error {
@@ -133,3 +155,10 @@ func PrepareDir(path string) error {
 	}
 	return nil
 }
+
+// IsTerminal reports whether f is connected to a terminal.
+// It returns false for pipes, regular files and /dev/null, e.g. under cron, CI or docker logs.
+func IsTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,54 @@
+package util
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// gb2312Initials GB2312 一级汉字按拼音排序，每个声母对应的起始编码
+var gb2312Initials = []struct {
+	code    int
+	initial byte
+}{
+	{0xB0A1, 'a'}, {0xB0C5, 'b'}, {0xB2C1, 'c'}, {0xB4EE, 'd'}, {0xB6EA, 'e'},
+	{0xB7A2, 'f'}, {0xB8C1, 'g'}, {0xB9FE, 'h'}, {0xBBF7, 'j'}, {0xBFA6, 'k'},
+	{0xC0AC, 'l'}, {0xC2E8, 'm'}, {0xC4C3, 'n'}, {0xC5B6, 'o'}, {0xC5BE, 'p'},
+	{0xC6DA, 'q'}, {0xC8BB, 'r'}, {0xC8F6, 's'}, {0xCBFA, 't'}, {0xCDDA, 'w'},
+	{0xCEF4, 'x'}, {0xD1B9, 'y'}, {0xD4D1, 'z'},
+}
+
+// gb2312Level1End GB2312 一级汉字的结束编码，二级汉字按部首排序，无法得到拼音
+const gb2312Level1End = 0xD7F9
+
+// PinyinInitials 返回字符串的拼音首字母，如 "张三abc" 返回 "zsabc"
+// 字母和数字转为小写保留，常用汉字（GB2312 一级汉字）转为拼音首字母，其余字符忽略
+func PinyinInitials(s string) string {
+	enc := simplifiedchinese.GBK.NewEncoder()
+	buf := strings.Builder{}
+	for _, r := range s {
+		switch {
+		case r < unicode.MaxASCII:
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				buf.WriteRune(unicode.ToLower(r))
+			}
+		case unicode.Is(unicode.Han, r):
+			b, err := enc.Bytes([]byte(string(r)))
+			if err != nil || len(b) != 2 {
+				continue
+			}
+			code := int(b[0])<<8 | int(b[1])
+			if code < gb2312Initials[0].code || code > gb2312Level1End {
+				continue
+			}
+			for i := len(gb2312Initials) - 1; i >= 0; i-- {
+				if code >= gb2312Initials[i].code {
+					buf.WriteByte(gb2312Initials[i].initial)
+					break
+				}
+			}
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,24 @@
+package util
+
+import "testing"
+
+func TestPinyinInitials(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", ""},
+		{"张三", "zs"},
+		{"李四 Li", "lsli"},
+		{"文件传输助手", "wjcszs"},
+		{"阿蔡", "ac"},
+		{"王小明123", "wxm123"},
+		{"Bob_2号", "bob2h"},
+		{"😀群聊", "ql"},
+	}
+	for _, tt := range tests {
+		if got := PinyinInitials(tt.input); got != tt.want {
+			t.Errorf("PinyinInitials(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
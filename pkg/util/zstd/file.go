@@ -0,0 +1,122 @@
+package zstd
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Magic zstd 帧的开头
+var Magic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// IsCompressed 判断文件是否为 zstd 压缩的文件
+func IsCompressed(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, len(Magic))
+	if _, err := io.ReadFull(f, head); err != nil {
+		return false
+	}
+	return bytes.Equal(head, Magic)
+}
+
+// CompressFile 将文件原地压缩，文件名不变，已压缩时不处理
+// 先写入同目录的临时文件再替换，失败时原文件不变
+func CompressFile(path string) error {
+	if IsCompressed(path) {
+		return nil
+	}
+	return rewrite(path, func(dst io.Writer, src io.Reader) error {
+		enc, err := zstd.NewWriter(dst, zstd.WithEncoderLevel(zstd.SpeedDefault))
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(enc, src); err != nil {
+			enc.Close()
+			return err
+		}
+		return enc.Close()
+	})
+}
+
+// InflateFile 将压缩的文件原地解压，需要写入数据库前调用，未压缩时不处理
+func InflateFile(path string) error {
+	if !IsCompressed(path) {
+		return nil
+	}
+	return rewrite(path, decompress)
+}
+
+// DecompressFile 将压缩的文件解压到 dst，解压的文件只有当前用户可以读写
+func DecompressFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tmp := dst + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := decompress(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+func decompress(dst io.Writer, src io.Reader) error {
+	dec, err := zstd.NewReader(src, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return err
+	}
+	defer dec.Close()
+	_, err = io.Copy(dst, dec)
+	return err
+}
+
+// rewrite 用 fn 转换文件内容后替换原文件，保留修改时间
+func rewrite(path string, fn func(dst io.Writer, src io.Reader) error) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := out.Name()
+	if err := fn(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	in.Close()
+	os.Chtimes(tmp, info.ModTime(), info.ModTime())
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package zstd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompressFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "message_0.db")
+	data := bytes.Repeat([]byte("SQLite format 3\x00chatlog "), 4096)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CompressFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if !IsCompressed(path) {
+		t.Fatal("file not compressed")
+	}
+	if info, _ := os.Stat(path); info.Size() >= int64(len(data)) {
+		t.Fatalf("compressed size %d not smaller than %d", info.Size(), len(data))
+	}
+	// 已压缩时不重复压缩
+	if err := CompressFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(dir, "copy.db")
+	if err := DecompressFile(path, dst); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(dst); !bytes.Equal(got, data) {
+		t.Fatal("decompressed copy differs")
+	}
+
+	if err := InflateFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(path); !bytes.Equal(got, data) {
+		t.Fatal("inflated file differs")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Fatalf("temporary files left: %d entries", len(entries))
+	}
+}
@@ -30,3 +30,28 @@ func GetMore(mod bool) string {
 	}
 	return fmt.Sprintf("version %s %s %s/%s\n", Version, runtime.Version(), runtime.GOOS, runtime.GOARCH)
 }
+
+// BuildInfo 结构化的版本信息
+type BuildInfo struct {
+	Version   string            `json:"version"`
+	GoVersion string            `json:"goVersion"`
+	OS        string            `json:"os"`
+	Arch      string            `json:"arch"`
+	Modules   map[string]string `json:"modules,omitempty"`
+}
+
+func Info(mod bool) *BuildInfo {
+	info := &BuildInfo{
+		Version:   Version,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+	if mod {
+		info.Modules = make(map[string]string, len(buildInfo.Deps))
+		for _, dep := range buildInfo.Deps {
+			info.Modules[dep.Path] = dep.Version
+		}
+	}
+	return info
+}